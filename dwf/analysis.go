@@ -0,0 +1,514 @@
+package dwf
+
+import "math"
+
+// FindEdge returns the index of the first sample at or after start where the
+// given DIO line makes a transition matching slope, or -1 if there is none.
+// The returned index is the first sample holding the new level.
+func FindEdge(samples []uint32, line int, slope TriggerSlope, start int) int {
+	if start < 1 {
+		start = 1
+	}
+	mask := uint32(1) << uint(line)
+	for i := start; i < len(samples); i++ {
+		prev := samples[i-1]&mask != 0
+		cur := samples[i]&mask != 0
+		if prev == cur {
+			continue
+		}
+		switch slope {
+		case TriggerSlopeRise:
+			if cur {
+				return i
+			}
+		case TriggerSlopeFall:
+			if !cur {
+				return i
+			}
+		default:
+			return i
+		}
+	}
+	return -1
+}
+
+// LogicRun is a run of consecutive logic samples with the same value.
+type LogicRun struct {
+	// Value is the value of the samples.
+	Value uint16
+	// Count is the number of samples in the run.
+	Count int
+}
+
+// RunLengths run-length encodes logic samples, which are mostly constant,
+// into runs of equal values in sample order.
+func RunLengths(samples []uint16) []LogicRun {
+	var runs []LogicRun
+	for _, v := range samples {
+		if n := len(runs); n > 0 && runs[n-1].Value == v {
+			runs[n-1].Count++
+			continue
+		}
+		runs = append(runs, LogicRun{Value: v, Count: 1})
+	}
+	return runs
+}
+
+// TimingStats summarizes a set of time intervals in seconds.
+type TimingStats struct {
+	// Count is the number of intervals; the other fields are NaN if 0.
+	Count int
+	// Min is the shortest interval.
+	Min float64
+	// Max is the longest interval.
+	Max float64
+	// Mean is the average interval.
+	Mean float64
+}
+
+// timingStats summarizes intervals given in samples at sampleRate Hz.
+func timingStats(intervals []int, sampleRate float64) TimingStats {
+	st := TimingStats{Count: len(intervals), Min: math.NaN(), Max: math.NaN(), Mean: math.NaN()}
+	if len(intervals) == 0 {
+		return st
+	}
+	lo, hi, sum := intervals[0], intervals[0], 0
+	for _, n := range intervals {
+		lo, hi, sum = min(lo, n), max(hi, n), sum+n
+	}
+	st.Min = float64(lo) / sampleRate
+	st.Max = float64(hi) / sampleRate
+	st.Mean = float64(sum) / float64(len(intervals)) / sampleRate
+	return st
+}
+
+// LogicMeasurements holds timing measurements of one logic channel. Only
+// whole pulses and cycles count, so the partial ones at the ends of the
+// capture do not skew the results.
+type LogicMeasurements struct {
+	// RisingEdges is the number of rising edges.
+	RisingEdges int
+	// FallingEdges is the number of falling edges.
+	FallingEdges int
+	// HighTime is the width of the high pulses.
+	HighTime TimingStats
+	// LowTime is the width of the low pulses.
+	LowTime TimingStats
+	// Period is the time from each rising edge to the next.
+	Period TimingStats
+	// Frequency in Hz from the mean period, NaN without a whole cycle.
+	Frequency float64
+	// DutyCycle is the percentage of the whole cycles spent high, NaN
+	// without a whole cycle.
+	DutyCycle float64
+}
+
+// MeasureLogic measures the pulses and cycles of a logic channel in
+// samples taken at sampleRate Hz.
+func MeasureLogic(samples []uint32, sampleRate float64, channel int) LogicMeasurements {
+	m := LogicMeasurements{Frequency: math.NaN(), DutyCycle: math.NaN()}
+	var highs, lows, periods []int
+	lastRise, lastFall := -1, -1
+	firstRise, highInCycles := -1, 0
+	for i := FindEdge(samples, channel, TriggerSlopeEither, 1); i >= 0; i = FindEdge(samples, channel, TriggerSlopeEither, i+1) {
+		if samples[i]&(1<<uint(channel)) != 0 {
+			m.RisingEdges++
+			if lastFall >= 0 {
+				lows = append(lows, i-lastFall)
+			}
+			if lastRise >= 0 {
+				periods = append(periods, i-lastRise)
+			} else {
+				firstRise = i
+			}
+			lastRise = i
+			continue
+		}
+		m.FallingEdges++
+		if lastRise >= 0 {
+			highs = append(highs, i-lastRise)
+			highInCycles += i - lastRise
+		}
+		lastFall = i
+	}
+	m.HighTime = timingStats(highs, sampleRate)
+	m.LowTime = timingStats(lows, sampleRate)
+	m.Period = timingStats(periods, sampleRate)
+	if len(periods) > 0 {
+		m.Frequency = 1 / m.Period.Mean
+		// A high pulse that began at the last rising edge is outside the
+		// whole cycles.
+		if lastFall > lastRise {
+			highInCycles -= lastFall - lastRise
+		}
+		m.DutyCycle = 100 * float64(highInCycles) / float64(lastRise-firstRise)
+	}
+	return m
+}
+
+// MeasureLogicDelay measures the delay from each edge of fromSlope on one
+// logic channel to the next edge of toSlope on another, before the next
+// from edge. Edges without a matching to edge are not counted. On the same
+// channel the from edge itself does not count, and the next from edge
+// does, so that the same edge on both measures the period.
+func MeasureLogicDelay(samples []uint32, sampleRate float64, from int, fromSlope TriggerSlope, to int, toSlope TriggerSlope) TimingStats {
+	var delays []int
+	same := from == to
+	for i := FindEdge(samples, from, fromSlope, 1); i >= 0; {
+		next := FindEdge(samples, from, fromSlope, i+1)
+		start := i
+		if same {
+			start = i + 1
+		}
+		j := FindEdge(samples, to, toSlope, start)
+		if j >= 0 && (next < 0 || j < next || same && j == next) {
+			delays = append(delays, j-i)
+		}
+		i = next
+	}
+	return timingStats(delays, sampleRate)
+}
+
+// WaveformMeasurements holds standard oscilloscope measurements of a capture.
+// Timing values are NaN when they cannot be determined, e.g. for a DC signal
+// or when the capture holds less than one full cycle.
+type WaveformMeasurements struct {
+	// Min is the lowest sample in Volts.
+	Min float64
+	// Max is the highest sample in Volts.
+	Max float64
+	// PeakToPeak is Max - Min in Volts.
+	PeakToPeak float64
+	// Mean is the average voltage.
+	Mean float64
+	// RMS is the root-mean-square voltage.
+	RMS float64
+	// Frequency in Hz, from the average spacing of rising mid-level crossings.
+	Frequency float64
+	// Period in seconds.
+	Period float64
+	// DutyCycle is the percentage of whole cycles spent above the mid level.
+	DutyCycle float64
+	// RiseTime is the average 10 % to 90 % transition time in seconds.
+	RiseTime float64
+	// FallTime is the average 90 % to 10 % transition time in seconds.
+	FallTime float64
+	// Cycles is the number of whole cycles in the capture.
+	Cycles int
+}
+
+// MeasureWaveform computes standard oscilloscope measurements over data
+// sampled at sampleRate Hz. Reference levels are taken from the minimum and
+// maximum samples; edges are detected at the mid level with 10 % hysteresis
+// so that noise does not produce false crossings.
+func MeasureWaveform(data []float64, sampleRate float64) WaveformMeasurements {
+	nan := math.NaN()
+	m := WaveformMeasurements{
+		Min: nan, Max: nan, PeakToPeak: nan, Mean: nan, RMS: nan,
+		Frequency: nan, Period: nan, DutyCycle: nan, RiseTime: nan, FallTime: nan,
+	}
+	if len(data) == 0 {
+		return m
+	}
+
+	m.Min, m.Max = data[0], data[0]
+	var sum, sumSq float64
+	for _, v := range data {
+		m.Min = math.Min(m.Min, v)
+		m.Max = math.Max(m.Max, v)
+		sum += v
+		sumSq += v * v
+	}
+	n := float64(len(data))
+	m.PeakToPeak = m.Max - m.Min
+	m.Mean = sum / n
+	m.RMS = math.Sqrt(sumSq / n)
+
+	if sampleRate <= 0 || m.PeakToPeak <= 0 {
+		return m
+	}
+
+	mid := m.Min + m.PeakToPeak/2
+	low := m.Min + m.PeakToPeak*0.1
+	high := m.Min + m.PeakToPeak*0.9
+	rising, falling := midCrossings(data, mid, m.PeakToPeak*0.1)
+
+	if len(rising) >= 2 {
+		first, last := rising[0], rising[len(rising)-1]
+		m.Cycles = len(rising) - 1
+		m.Period = (last - first) / float64(m.Cycles) / sampleRate
+
+		// High time per cycle runs from each rising crossing to the
+		// falling crossing that follows it.
+		var highTime float64
+		f := 0
+		for k := 0; k < m.Cycles; k++ {
+			for f < len(falling) && falling[f] < rising[k] {
+				f++
+			}
+			if f < len(falling) && falling[f] < rising[k+1] {
+				highTime += falling[f] - rising[k]
+			}
+		}
+		m.DutyCycle = 100 * highTime / (last - first)
+		m.Frequency = 1 / m.Period
+	}
+	m.RiseTime = transitionTime(data, rising, low, high) / sampleRate
+	m.FallTime = transitionTime(data, falling, high, low) / sampleRate
+	return m
+}
+
+// midCrossings returns the fractional sample positions where data crosses
+// mid upwards and downwards. A crossing only counts once the signal has
+// moved hyst beyond mid, which rejects noise around the threshold.
+func midCrossings(data []float64, mid, hyst float64) (rising, falling []float64) {
+	state := 0 // -1 below, +1 above, 0 unknown
+	for i, v := range data {
+		switch {
+		case v >= mid+hyst && state <= 0:
+			if state < 0 {
+				rising = append(rising, levelCrossing(data, i, mid, true))
+			}
+			state = 1
+		case v <= mid-hyst && state >= 0:
+			if state > 0 {
+				falling = append(falling, levelCrossing(data, i, mid, false))
+			}
+			state = -1
+		}
+	}
+	return rising, falling
+}
+
+// levelCrossing searches backwards from i for the sample pair that crosses
+// level in the given direction and returns the interpolated position.
+func levelCrossing(data []float64, i int, level float64, up bool) float64 {
+	for j := i; j > 0; j-- {
+		a, b := data[j-1], data[j]
+		if (up && a < level && b >= level) || (!up && a > level && b <= level) {
+			return float64(j-1) + (level-a)/(b-a)
+		}
+	}
+	return float64(i)
+}
+
+// transitionTime returns the average number of samples the signal takes to
+// go from level "from" to level "to" around each mid-level crossing, or NaN
+// if no complete transition was found.
+func transitionTime(data []float64, crossings []float64, from, to float64) float64 {
+	up := to > from
+	var total float64
+	count := 0
+	for _, c := range crossings {
+		// data[next] is the first sample past the mid level.
+		next := int(c) + 1
+		if next >= len(data) {
+			continue
+		}
+		start, end := -1.0, -1.0
+		for j := next; j > 0; j-- {
+			if (up && data[j-1] <= from) || (!up && data[j-1] >= from) {
+				start = float64(j-1) + (from-data[j-1])/(data[j]-data[j-1])
+				break
+			}
+		}
+		for j := next; j < len(data); j++ {
+			if (up && data[j] >= to) || (!up && data[j] <= to) {
+				end = float64(j-1) + (to-data[j-1])/(data[j]-data[j-1])
+				break
+			}
+		}
+		if start >= 0 && end >= start {
+			total += end - start
+			count++
+		}
+	}
+	if count == 0 {
+		return math.NaN()
+	}
+	return total / float64(count)
+}
+
+// TimebaseMeasurement describes the error of the instrument's sample clock,
+// measured against a reference clock of known frequency. Jitter values
+// include the reference clock's own jitter and the edge interpolation error
+// caused by noise, so they are an upper bound for the instrument jitter.
+type TimebaseMeasurement struct {
+	// Edges is the number of rising reference edges found in the capture.
+	Edges int
+	// Duration is the time in seconds between the first and last edge.
+	Duration float64
+	// MeasuredFrequency is the reference frequency in Hz as seen by the
+	// instrument's time base.
+	MeasuredFrequency float64
+	// ErrorPPM is the time base error in parts per million. Positive values
+	// mean the sample clock runs fast, so measured intervals are too long.
+	ErrorPPM float64
+	// ErrorUncertaintyPPM is the standard error of ErrorPPM from the fit.
+	ErrorUncertaintyPPM float64
+	// CorrectionFactor converts a measured time interval into a true one by
+	// multiplication.
+	CorrectionFactor float64
+	// PeriodJitterRMS is the standard deviation of the individual periods in
+	// seconds.
+	PeriodJitterRMS float64
+	// TIERMS is the RMS time interval error in seconds: the deviation of the
+	// edges from an ideal clock fitted to them.
+	TIERMS float64
+	// TIEPeakToPeak is the peak-to-peak time interval error in seconds.
+	TIEPeakToPeak float64
+}
+
+// MeasureTimebase measures the sample clock error and jitter from data
+// sampled at sampleRate Hz while recording a reference clock of refFreq Hz.
+// Rising mid-level crossings are located with sub-sample interpolation and a
+// straight line is fitted to their positions; its slope gives the time base
+// error and the residuals the time interval error. Values are NaN when the
+// capture holds fewer than three edges.
+func MeasureTimebase(data []float64, sampleRate, refFreq float64) TimebaseMeasurement {
+	nan := math.NaN()
+	t := TimebaseMeasurement{
+		Duration: nan, MeasuredFrequency: nan, ErrorPPM: nan, ErrorUncertaintyPPM: nan,
+		CorrectionFactor: nan, PeriodJitterRMS: nan, TIERMS: nan, TIEPeakToPeak: nan,
+	}
+	if len(data) == 0 || sampleRate <= 0 || refFreq <= 0 {
+		return t
+	}
+	lo, hi := data[0], data[0]
+	for _, v := range data {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	if hi <= lo {
+		return t
+	}
+	rising, _ := midCrossings(data, lo+(hi-lo)/2, (hi-lo)*0.1)
+	t.Edges = len(rising)
+	if len(rising) < 3 {
+		return t
+	}
+
+	// Least-squares fit of edge position (in samples) against edge number.
+	n := float64(len(rising))
+	meanK := (n - 1) / 2
+	var meanX float64
+	for _, x := range rising {
+		meanX += x
+	}
+	meanX /= n
+	var sxy, sxx float64
+	for k, x := range rising {
+		dk := float64(k) - meanK
+		sxy += dk * (x - meanX)
+		sxx += dk * dk
+	}
+	slope := sxy / sxx // samples per reference period
+
+	var sse float64
+	tieMin, tieMax := math.Inf(1), math.Inf(-1)
+	for k, x := range rising {
+		r := x - (meanX + slope*(float64(k)-meanK))
+		sse += r * r
+		tieMin = math.Min(tieMin, r)
+		tieMax = math.Max(tieMax, r)
+	}
+	slopeErr := math.Sqrt(sse/(n-2)/sxx) / slope
+
+	var sumP, sumPSq float64
+	for k := 1; k < len(rising); k++ {
+		p := rising[k] - rising[k-1]
+		sumP += p
+		sumPSq += p * p
+	}
+	periods := n - 1
+	meanP := sumP / periods
+	periodVar := math.Max(sumPSq/periods-meanP*meanP, 0)
+
+	t.Duration = (rising[len(rising)-1] - rising[0]) / sampleRate
+	t.MeasuredFrequency = sampleRate / slope
+	t.CorrectionFactor = t.MeasuredFrequency / refFreq
+	t.ErrorPPM = (refFreq/t.MeasuredFrequency - 1) * 1e6
+	t.ErrorUncertaintyPPM = slopeErr * 1e6
+	t.PeriodJitterRMS = math.Sqrt(periodVar) / sampleRate
+	t.TIERMS = math.Sqrt(sse/n) / sampleRate
+	t.TIEPeakToPeak = (tieMax - tieMin) / sampleRate
+	return t
+}
+
+// XYMeasurements describes the figure two simultaneously sampled signals
+// draw when plotted against each other, such as a Lissajous ellipse or an
+// I-V curve. Values are NaN when a signal is constant.
+type XYMeasurements struct {
+	// XAmplitude is half the peak-to-peak of X.
+	XAmplitude float64
+	// YAmplitude is half the peak-to-peak of Y.
+	YAmplitude float64
+	// Correlation is the correlation coefficient of X and Y, which is the
+	// cosine of the phase shift for two sine waves.
+	Correlation float64
+	// Phase is the phase of Y relative to X in degrees, positive if Y
+	// leads. It assumes sine waves of the same frequency.
+	Phase float64
+	// AmplitudeRatio is the RMS of Y over the RMS of X, both without DC.
+	AmplitudeRatio float64
+	// Slope is the least-squares dY/dX, e.g. the conductance of an I-V trace.
+	Slope float64
+	// Intercept is Y of the fitted line at X = 0.
+	Intercept float64
+}
+
+// MeasureXY analyzes y plotted against x. The phase magnitude comes from
+// the correlation coefficient and its sign from the direction in which the
+// figure is traced: an ellipse drawn clockwise means Y leads X.
+func MeasureXY(x, y []float64) XYMeasurements {
+	nan := math.NaN()
+	m := XYMeasurements{
+		XAmplitude: nan, YAmplitude: nan, Correlation: nan, Phase: nan,
+		AmplitudeRatio: nan, Slope: nan, Intercept: nan,
+	}
+	n := min(len(x), len(y))
+	if n == 0 {
+		return m
+	}
+	x, y = x[:n], y[:n]
+
+	var meanX, meanY float64
+	xMin, xMax, yMin, yMax := x[0], x[0], y[0], y[0]
+	for i := range n {
+		meanX += x[i]
+		meanY += y[i]
+		xMin, xMax = math.Min(xMin, x[i]), math.Max(xMax, x[i])
+		yMin, yMax = math.Min(yMin, y[i]), math.Max(yMax, y[i])
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+	m.XAmplitude = (xMax - xMin) / 2
+	m.YAmplitude = (yMax - yMin) / 2
+
+	// Covariances and the signed area of the traced figure (shoelace).
+	var sxx, syy, sxy, area float64
+	for i := range n {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		sxx += dx * dx
+		syy += dy * dy
+		sxy += dx * dy
+		if i+1 < n {
+			area += dx*(y[i+1]-meanY) - (x[i+1]-meanX)*dy
+		}
+	}
+	if sxx == 0 {
+		return m
+	}
+	m.Slope = sxy / sxx
+	m.Intercept = meanY - m.Slope*meanX
+	if syy == 0 {
+		return m
+	}
+	m.AmplitudeRatio = math.Sqrt(syy / sxx)
+	m.Correlation = math.Max(-1, math.Min(1, sxy/math.Sqrt(sxx*syy)))
+	m.Phase = math.Acos(m.Correlation) * 180 / math.Pi
+	if area > 0 {
+		m.Phase = -m.Phase
+	}
+	return m
+}
@@ -114,6 +114,21 @@ func dwfDeviceClose(hdwf C.HDWF) error {
 	return nil
 }
 
+func dwfDeviceReset(hdwf C.HDWF) error {
+	if C.FDwfDeviceReset(hdwf) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfDeviceAutoConfigureGet(hdwf C.HDWF) (bool, error) {
+	var auto C.int
+	if C.FDwfDeviceAutoConfigureGet(hdwf, &auto) == 0 {
+		return false, lastError()
+	}
+	return auto != 0, nil
+}
+
 // --- Analog Input (Oscilloscope) ---
 
 func dwfAnalogInChannelCount(hdwf C.HDWF) (int, error) {
@@ -165,6 +180,20 @@ func dwfAnalogInChannelRangeSet(hdwf C.HDWF, channel C.int, volts float64) error
 	return nil
 }
 
+func dwfAnalogInChannelBandwidthSet(hdwf C.HDWF, channel C.int, hz float64) error {
+	if C.FDwfAnalogInChannelBandwidthSet(hdwf, channel, C.double(hz)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogInChannelAttenuationSet(hdwf C.HDWF, channel C.int, factor float64) error {
+	if C.FDwfAnalogInChannelAttenuationSet(hdwf, channel, C.double(factor)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
 func dwfAnalogInBufferSizeSet(hdwf C.HDWF, size int) error {
 	if C.FDwfAnalogInBufferSizeSet(hdwf, C.int(size)) == 0 {
 		return lastError()
@@ -179,13 +208,124 @@ func dwfAnalogInFrequencySet(hdwf C.HDWF, freq float64) error {
 	return nil
 }
 
-func dwfAnalogInChannelFilterSet(hdwf C.HDWF, channel, filter C.int) error {
+func dwfAnalogInFrequencyInfo(hdwf C.HDWF) (float64, float64, error) {
+	var lo, hi C.double
+	if C.FDwfAnalogInFrequencyInfo(hdwf, &lo, &hi) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), nil
+}
+
+func dwfAnalogInFrequencyGet(hdwf C.HDWF) (float64, error) {
+	var hz C.double
+	if C.FDwfAnalogInFrequencyGet(hdwf, &hz) == 0 {
+		return 0, lastError()
+	}
+	return float64(hz), nil
+}
+
+func dwfAnalogInBufferSizeGet(hdwf C.HDWF) (int, error) {
+	var size C.int
+	if C.FDwfAnalogInBufferSizeGet(hdwf, &size) == 0 {
+		return 0, lastError()
+	}
+	return int(size), nil
+}
+
+func dwfAnalogInChannelRangeInfo(hdwf C.HDWF) (float64, float64, error) {
+	var lo, hi, steps C.double
+	if C.FDwfAnalogInChannelRangeInfo(hdwf, &lo, &hi, &steps) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), nil
+}
+
+func dwfAnalogInChannelRangeGet(hdwf C.HDWF, channel C.int) (float64, error) {
+	var volts C.double
+	if C.FDwfAnalogInChannelRangeGet(hdwf, channel, &volts) == 0 {
+		return 0, lastError()
+	}
+	return float64(volts), nil
+}
+
+func dwfAnalogInChannelOffsetInfo(hdwf C.HDWF) (float64, float64, error) {
+	var lo, hi, steps C.double
+	if C.FDwfAnalogInChannelOffsetInfo(hdwf, &lo, &hi, &steps) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), nil
+}
+
+func dwfAnalogInChannelOffsetGet(hdwf C.HDWF, channel C.int) (float64, error) {
+	var volts C.double
+	if C.FDwfAnalogInChannelOffsetGet(hdwf, channel, &volts) == 0 {
+		return 0, lastError()
+	}
+	return float64(volts), nil
+}
+
+func dwfAnalogInChannelFilterSet(hdwf C.HDWF, channel C.int, filter C.FILTER) error {
 	if C.FDwfAnalogInChannelFilterSet(hdwf, channel, filter) == 0 {
 		return lastError()
 	}
 	return nil
 }
 
+func dwfAnalogInChannelCouplingInfo(hdwf C.HDWF) (int, error) {
+	var fs C.int
+	if C.FDwfAnalogInChannelCouplingInfo(hdwf, &fs) == 0 {
+		return 0, lastError()
+	}
+	return int(fs), nil
+}
+
+func dwfAnalogInChannelCouplingSet(hdwf C.HDWF, channel C.int, coupling C.DwfAnalogCoupling) error {
+	if C.FDwfAnalogInChannelCouplingSet(hdwf, channel, coupling) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogInChannelEnableGet(hdwf C.HDWF, channel C.int) (bool, error) {
+	var enabled C.int
+	if C.FDwfAnalogInChannelEnableGet(hdwf, channel, &enabled) == 0 {
+		return false, lastError()
+	}
+	return enabled != 0, nil
+}
+
+func dwfAnalogInChannelAttenuationGet(hdwf C.HDWF, channel C.int) (float64, error) {
+	var factor C.double
+	if C.FDwfAnalogInChannelAttenuationGet(hdwf, channel, &factor) == 0 {
+		return 0, lastError()
+	}
+	return float64(factor), nil
+}
+
+func dwfAnalogInChannelBandwidthGet(hdwf C.HDWF, channel C.int) (float64, error) {
+	var hz C.double
+	if C.FDwfAnalogInChannelBandwidthGet(hdwf, channel, &hz) == 0 {
+		return 0, lastError()
+	}
+	return float64(hz), nil
+}
+
+func dwfAnalogInChannelFilterGet(hdwf C.HDWF, channel C.int) (ScopeFilter, error) {
+	var filter C.FILTER
+	if C.FDwfAnalogInChannelFilterGet(hdwf, channel, &filter) == 0 {
+		return 0, lastError()
+	}
+	return ScopeFilter(filter), nil
+}
+
+func dwfAnalogInChannelCouplingGet(hdwf C.HDWF, channel C.int) (AnalogCoupling, error) {
+	var coupling C.DwfAnalogCoupling
+	if C.FDwfAnalogInChannelCouplingGet(hdwf, channel, &coupling) == 0 {
+		return 0, lastError()
+	}
+	return AnalogCoupling(coupling), nil
+}
+
 func dwfAnalogInConfigure(hdwf C.HDWF, reconfigure, start bool) error {
 	var r, s C.int
 	if reconfigure {
@@ -228,6 +368,100 @@ func dwfAnalogInStatusData(hdwf C.HDWF, channel C.int, bufSize int) ([]float64,
 	return buf, nil
 }
 
+func dwfAnalogInStatusData16(hdwf C.HDWF, channel C.int, bufSize int) ([]int16, error) {
+	buf := make([]int16, bufSize)
+	if C.FDwfAnalogInStatusData16(hdwf, channel, (*C.short)(unsafe.Pointer(&buf[0])), 0, C.int(bufSize)) == 0 {
+		return nil, lastError()
+	}
+	return buf, nil
+}
+
+func dwfAnalogInNoiseSizeInfo(hdwf C.HDWF) (int, error) {
+	var maxSize C.int
+	if C.FDwfAnalogInNoiseSizeInfo(hdwf, &maxSize) == 0 {
+		return 0, lastError()
+	}
+	return int(maxSize), nil
+}
+
+func dwfAnalogInNoiseSizeSet(hdwf C.HDWF, size int) error {
+	if C.FDwfAnalogInNoiseSizeSet(hdwf, C.int(size)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogInStatusNoise(hdwf C.HDWF, channel C.int, size int) ([]float64, []float64, error) {
+	lo := make([]float64, size)
+	hi := make([]float64, size)
+	if C.FDwfAnalogInStatusNoise(hdwf, channel, (*C.double)(unsafe.Pointer(&lo[0])), (*C.double)(unsafe.Pointer(&hi[0])), C.int(size)) == 0 {
+		return nil, nil, lastError()
+	}
+	return lo, hi, nil
+}
+
+func dwfAnalogInStatusRecord(hdwf C.HDWF) (int, int, int, error) {
+	var available, lost, corrupt C.int
+	if C.FDwfAnalogInStatusRecord(hdwf, &available, &lost, &corrupt) == 0 {
+		return 0, 0, 0, lastError()
+	}
+	return int(available), int(lost), int(corrupt), nil
+}
+
+func dwfAnalogInAcquisitionModeSet(hdwf C.HDWF, mode C.ACQMODE) error {
+	if C.FDwfAnalogInAcquisitionModeSet(hdwf, mode) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogInAcquisitionModeInfo(hdwf C.HDWF) (int, error) {
+	var modes C.int
+	if C.FDwfAnalogInAcquisitionModeInfo(hdwf, &modes) == 0 {
+		return 0, lastError()
+	}
+	return int(modes), nil
+}
+
+func dwfAnalogInStatusSamplesValid(hdwf C.HDWF) (int, error) {
+	var valid C.int
+	if C.FDwfAnalogInStatusSamplesValid(hdwf, &valid) == 0 {
+		return 0, lastError()
+	}
+	return int(valid), nil
+}
+
+func dwfAnalogInRecordLengthSet(hdwf C.HDWF, seconds float64) error {
+	if C.FDwfAnalogInRecordLengthSet(hdwf, C.double(seconds)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogInCounterInfo(hdwf C.HDWF) (float64, float64, error) {
+	var cntMax, secMax C.double
+	if C.FDwfAnalogInCounterInfo(hdwf, &cntMax, &secMax) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(cntMax), float64(secMax), nil
+}
+
+func dwfAnalogInCounterSet(hdwf C.HDWF, seconds float64) error {
+	if C.FDwfAnalogInCounterSet(hdwf, C.double(seconds)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogInCounterStatus(hdwf C.HDWF) (float64, float64, int, error) {
+	var cnt, freq C.double
+	var tick C.int
+	if C.FDwfAnalogInCounterStatus(hdwf, &cnt, &freq, &tick) == 0 {
+		return 0, 0, 0, lastError()
+	}
+	return float64(cnt), float64(freq), int(tick), nil
+}
+
 func dwfAnalogInReset(hdwf C.HDWF) error {
 	if C.FDwfAnalogInReset(hdwf) == 0 {
 		return lastError()
@@ -272,6 +506,161 @@ func dwfAnalogInTriggerLevelSet(hdwf C.HDWF, level float64) error {
 	return nil
 }
 
+func dwfAnalogInTriggerLengthSet(hdwf C.HDWF, seconds float64) error {
+	if C.FDwfAnalogInTriggerLengthSet(hdwf, C.double(seconds)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogInTriggerLengthConditionSet(hdwf C.HDWF, cond C.TRIGLEN) error {
+	if C.FDwfAnalogInTriggerLengthConditionSet(hdwf, cond) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogInTriggerHysteresisSet(hdwf C.HDWF, volts float64) error {
+	if C.FDwfAnalogInTriggerHysteresisSet(hdwf, C.double(volts)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogInTriggerHoldOffInfo(hdwf C.HDWF) (float64, float64, error) {
+	var lo, hi, steps C.double
+	if C.FDwfAnalogInTriggerHoldOffInfo(hdwf, &lo, &hi, &steps) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), nil
+}
+
+func dwfAnalogInTriggerPositionInfo(hdwf C.HDWF) (float64, float64, error) {
+	var lo, hi, steps C.double
+	if C.FDwfAnalogInTriggerPositionInfo(hdwf, &lo, &hi, &steps) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), nil
+}
+
+func dwfAnalogInTriggerPositionSet(hdwf C.HDWF, seconds float64) error {
+	if C.FDwfAnalogInTriggerPositionSet(hdwf, C.double(seconds)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogInTriggerPositionStatus(hdwf C.HDWF) (float64, error) {
+	var pos C.double
+	if C.FDwfAnalogInTriggerPositionStatus(hdwf, &pos) == 0 {
+		return 0, lastError()
+	}
+	return float64(pos), nil
+}
+
+func dwfAnalogInStatusAutoTriggered(hdwf C.HDWF) (bool, error) {
+	var auto C.int
+	if C.FDwfAnalogInStatusAutoTriggered(hdwf, &auto) == 0 {
+		return false, lastError()
+	}
+	return auto != 0, nil
+}
+
+func dwfAnalogInTriggerSourceGet(hdwf C.HDWF) (TriggerSource, error) {
+	var src C.TRIGSRC
+	if C.FDwfAnalogInTriggerSourceGet(hdwf, &src) == 0 {
+		return 0, lastError()
+	}
+	return TriggerSource(src), nil
+}
+
+func dwfAnalogInTriggerChannelGet(hdwf C.HDWF) (int, error) {
+	var channel C.int
+	if C.FDwfAnalogInTriggerChannelGet(hdwf, &channel) == 0 {
+		return 0, lastError()
+	}
+	return int(channel), nil
+}
+
+func dwfAnalogInTriggerTypeGet(hdwf C.HDWF) (TriggerType, error) {
+	var trigType C.TRIGTYPE
+	if C.FDwfAnalogInTriggerTypeGet(hdwf, &trigType) == 0 {
+		return 0, lastError()
+	}
+	return TriggerType(trigType), nil
+}
+
+func dwfAnalogInTriggerConditionGet(hdwf C.HDWF) (TriggerSlope, error) {
+	var cond C.DwfTriggerSlope
+	if C.FDwfAnalogInTriggerConditionGet(hdwf, &cond) == 0 {
+		return 0, lastError()
+	}
+	return TriggerSlope(cond), nil
+}
+
+func dwfAnalogInTriggerLevelGet(hdwf C.HDWF) (float64, error) {
+	var level C.double
+	if C.FDwfAnalogInTriggerLevelGet(hdwf, &level) == 0 {
+		return 0, lastError()
+	}
+	return float64(level), nil
+}
+
+func dwfAnalogInTriggerHysteresisGet(hdwf C.HDWF) (float64, error) {
+	var volts C.double
+	if C.FDwfAnalogInTriggerHysteresisGet(hdwf, &volts) == 0 {
+		return 0, lastError()
+	}
+	return float64(volts), nil
+}
+
+func dwfAnalogInTriggerPositionGet(hdwf C.HDWF) (float64, error) {
+	var seconds C.double
+	if C.FDwfAnalogInTriggerPositionGet(hdwf, &seconds) == 0 {
+		return 0, lastError()
+	}
+	return float64(seconds), nil
+}
+
+func dwfAnalogInTriggerHoldOffGet(hdwf C.HDWF) (float64, error) {
+	var seconds C.double
+	if C.FDwfAnalogInTriggerHoldOffGet(hdwf, &seconds) == 0 {
+		return 0, lastError()
+	}
+	return float64(seconds), nil
+}
+
+func dwfAnalogInTriggerAutoTimeoutGet(hdwf C.HDWF) (float64, error) {
+	var timeout C.double
+	if C.FDwfAnalogInTriggerAutoTimeoutGet(hdwf, &timeout) == 0 {
+		return 0, lastError()
+	}
+	return float64(timeout), nil
+}
+
+func dwfAnalogInTriggerLengthGet(hdwf C.HDWF) (float64, error) {
+	var seconds C.double
+	if C.FDwfAnalogInTriggerLengthGet(hdwf, &seconds) == 0 {
+		return 0, lastError()
+	}
+	return float64(seconds), nil
+}
+
+func dwfAnalogInTriggerLengthConditionGet(hdwf C.HDWF) (TriggerLengthCondition, error) {
+	var cond C.TRIGLEN
+	if C.FDwfAnalogInTriggerLengthConditionGet(hdwf, &cond) == 0 {
+		return 0, lastError()
+	}
+	return TriggerLengthCondition(cond), nil
+}
+
+func dwfAnalogInTriggerHoldOffSet(hdwf C.HDWF, seconds float64) error {
+	if C.FDwfAnalogInTriggerHoldOffSet(hdwf, C.double(seconds)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
 func dwfAnalogInTriggerConditionSet(hdwf C.HDWF, cond C.DwfTriggerSlope) error {
 	if C.FDwfAnalogInTriggerConditionSet(hdwf, cond) == 0 {
 		return lastError()
@@ -307,6 +696,14 @@ func dwfAnalogOutNodeFunctionSet(hdwf C.HDWF, channel, node C.int, function C.FU
 	return nil
 }
 
+func dwfAnalogOutNodeDataInfo(hdwf C.HDWF, channel, node C.int) (int, int, error) {
+	var minSamples, maxSamples C.int
+	if C.FDwfAnalogOutNodeDataInfo(hdwf, channel, node, &minSamples, &maxSamples) == 0 {
+		return 0, 0, lastError()
+	}
+	return int(minSamples), int(maxSamples), nil
+}
+
 func dwfAnalogOutNodeDataSet(hdwf C.HDWF, channel, node C.int, data []float64) error {
 	if len(data) == 0 {
 		return nil
@@ -345,6 +742,93 @@ func dwfAnalogOutNodeSymmetrySet(hdwf C.HDWF, channel, node C.int, symmetry floa
 	return nil
 }
 
+func dwfAnalogOutNodePhaseSet(hdwf C.HDWF, channel, node C.int, degrees float64) error {
+	if C.FDwfAnalogOutNodePhaseSet(hdwf, channel, node, C.double(degrees)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogOutNodeFrequencyInfo(hdwf C.HDWF, channel, node C.int) (float64, float64, error) {
+	var lo, hi C.double
+	if C.FDwfAnalogOutNodeFrequencyInfo(hdwf, channel, node, &lo, &hi) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), nil
+}
+
+func dwfAnalogOutNodeFrequencyGet(hdwf C.HDWF, channel, node C.int) (float64, error) {
+	var v C.double
+	if C.FDwfAnalogOutNodeFrequencyGet(hdwf, channel, node, &v) == 0 {
+		return 0, lastError()
+	}
+	return float64(v), nil
+}
+
+func dwfAnalogOutNodeAmplitudeInfo(hdwf C.HDWF, channel, node C.int) (float64, float64, error) {
+	var lo, hi C.double
+	if C.FDwfAnalogOutNodeAmplitudeInfo(hdwf, channel, node, &lo, &hi) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), nil
+}
+
+func dwfAnalogOutNodeAmplitudeGet(hdwf C.HDWF, channel, node C.int) (float64, error) {
+	var v C.double
+	if C.FDwfAnalogOutNodeAmplitudeGet(hdwf, channel, node, &v) == 0 {
+		return 0, lastError()
+	}
+	return float64(v), nil
+}
+
+func dwfAnalogOutNodeOffsetInfo(hdwf C.HDWF, channel, node C.int) (float64, float64, error) {
+	var lo, hi C.double
+	if C.FDwfAnalogOutNodeOffsetInfo(hdwf, channel, node, &lo, &hi) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), nil
+}
+
+func dwfAnalogOutNodeOffsetGet(hdwf C.HDWF, channel, node C.int) (float64, error) {
+	var v C.double
+	if C.FDwfAnalogOutNodeOffsetGet(hdwf, channel, node, &v) == 0 {
+		return 0, lastError()
+	}
+	return float64(v), nil
+}
+
+func dwfAnalogOutNodeSymmetryInfo(hdwf C.HDWF, channel, node C.int) (float64, float64, error) {
+	var lo, hi C.double
+	if C.FDwfAnalogOutNodeSymmetryInfo(hdwf, channel, node, &lo, &hi) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), nil
+}
+
+func dwfAnalogOutNodeSymmetryGet(hdwf C.HDWF, channel, node C.int) (float64, error) {
+	var v C.double
+	if C.FDwfAnalogOutNodeSymmetryGet(hdwf, channel, node, &v) == 0 {
+		return 0, lastError()
+	}
+	return float64(v), nil
+}
+
+func dwfAnalogOutNodePhaseInfo(hdwf C.HDWF, channel, node C.int) (float64, float64, error) {
+	var lo, hi C.double
+	if C.FDwfAnalogOutNodePhaseInfo(hdwf, channel, node, &lo, &hi) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), nil
+}
+
+func dwfAnalogOutNodePhaseGet(hdwf C.HDWF, channel, node C.int) (float64, error) {
+	var v C.double
+	if C.FDwfAnalogOutNodePhaseGet(hdwf, channel, node, &v) == 0 {
+		return 0, lastError()
+	}
+	return float64(v), nil
+}
+
 func dwfAnalogOutRunSet(hdwf C.HDWF, channel C.int, runTime float64) error {
 	if C.FDwfAnalogOutRunSet(hdwf, channel, C.double(runTime)) == 0 {
 		return lastError()
@@ -366,6 +850,42 @@ func dwfAnalogOutRepeatSet(hdwf C.HDWF, channel C.int, repeat int) error {
 	return nil
 }
 
+func dwfAnalogOutTriggerSourceSet(hdwf C.HDWF, channel C.int, src C.TRIGSRC) error {
+	if C.FDwfAnalogOutTriggerSourceSet(hdwf, channel, src) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogOutTriggerSlopeSet(hdwf C.HDWF, channel C.int, slope C.DwfTriggerSlope) error {
+	if C.FDwfAnalogOutTriggerSlopeSet(hdwf, channel, slope) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogOutMasterSet(hdwf C.HDWF, channel, master C.int) error {
+	if C.FDwfAnalogOutMasterSet(hdwf, channel, master) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfAnalogOutIdleInfo(hdwf C.HDWF, channel C.int) (int, error) {
+	var fs C.int
+	if C.FDwfAnalogOutIdleInfo(hdwf, channel, &fs) == 0 {
+		return 0, lastError()
+	}
+	return int(fs), nil
+}
+
+func dwfAnalogOutIdleSet(hdwf C.HDWF, channel C.int, idle C.DwfAnalogOutIdle) error {
+	if C.FDwfAnalogOutIdleSet(hdwf, channel, idle) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
 func dwfAnalogOutConfigure(hdwf C.HDWF, channel C.int, start bool) error {
 	var s C.int
 	if start {
@@ -377,6 +897,32 @@ func dwfAnalogOutConfigure(hdwf C.HDWF, channel C.int, start bool) error {
 	return nil
 }
 
+func dwfAnalogOutStatus(hdwf C.HDWF, channel C.int) (byte, error) {
+	var status C.DwfState
+	if C.FDwfAnalogOutStatus(hdwf, channel, &status) == 0 {
+		return 0, lastError()
+	}
+	return byte(status), nil
+}
+
+func dwfAnalogOutNodePlayStatus(hdwf C.HDWF, channel, node C.int) (int, int, int, error) {
+	var free, lost, corrupted C.int
+	if C.FDwfAnalogOutNodePlayStatus(hdwf, channel, node, &free, &lost, &corrupted) == 0 {
+		return 0, 0, 0, lastError()
+	}
+	return int(free), int(lost), int(corrupted), nil
+}
+
+func dwfAnalogOutNodePlayData(hdwf C.HDWF, channel, node C.int, data []float64) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if C.FDwfAnalogOutNodePlayData(hdwf, channel, node, (*C.double)(unsafe.Pointer(&data[0])), C.int(len(data))) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
 func dwfAnalogOutReset(hdwf C.HDWF, channel C.int) error {
 	if C.FDwfAnalogOutReset(hdwf, channel) == 0 {
 		return lastError()
@@ -418,6 +964,24 @@ func dwfAnalogIOChannelNodeName(hdwf C.HDWF, channel, node C.int) (string, strin
 	return C.GoString(&name[0]), C.GoString(&unit[0]), nil
 }
 
+func dwfAnalogIOChannelNodeSetInfo(hdwf C.HDWF, channel, node C.int) (float64, float64, int, error) {
+	var lo, hi C.double
+	var steps C.int
+	if C.FDwfAnalogIOChannelNodeSetInfo(hdwf, channel, node, &lo, &hi, &steps) == 0 {
+		return 0, 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), int(steps), nil
+}
+
+func dwfAnalogIOChannelNodeStatusInfo(hdwf C.HDWF, channel, node C.int) (float64, float64, int, error) {
+	var lo, hi C.double
+	var steps C.int
+	if C.FDwfAnalogIOChannelNodeStatusInfo(hdwf, channel, node, &lo, &hi, &steps) == 0 {
+		return 0, 0, 0, lastError()
+	}
+	return float64(lo), float64(hi), int(steps), nil
+}
+
 func dwfAnalogIOChannelNodeSet(hdwf C.HDWF, channel, node C.int, value float64) error {
 	if C.FDwfAnalogIOChannelNodeSet(hdwf, channel, node, C.double(value)) == 0 {
 		return lastError()
@@ -459,6 +1023,14 @@ func dwfAnalogIOEnableSet(hdwf C.HDWF, enable bool) error {
 	return nil
 }
 
+func dwfAnalogIOEnableGet(hdwf C.HDWF) (bool, error) {
+	var e C.int
+	if C.FDwfAnalogIOEnableGet(hdwf, &e) == 0 {
+		return false, lastError()
+	}
+	return e != 0, nil
+}
+
 func dwfAnalogIOReset(hdwf C.HDWF) error {
 	if C.FDwfAnalogIOReset(hdwf) == 0 {
 		return lastError()
@@ -499,6 +1071,38 @@ func dwfDigitalInDividerSet(hdwf C.HDWF, divider int) error {
 	return nil
 }
 
+func dwfDigitalInDividerGet(hdwf C.HDWF) (int, error) {
+	var divider C.uint
+	if C.FDwfDigitalInDividerGet(hdwf, &divider) == 0 {
+		return 0, lastError()
+	}
+	return int(divider), nil
+}
+
+func dwfDigitalInDividerInfo(hdwf C.HDWF) (int, error) {
+	var maxDivider C.uint
+	if C.FDwfDigitalInDividerInfo(hdwf, &maxDivider) == 0 {
+		return 0, lastError()
+	}
+	return int(maxDivider), nil
+}
+
+func dwfDigitalInSampleFormatGet(hdwf C.HDWF) (int, error) {
+	var bits C.int
+	if C.FDwfDigitalInSampleFormatGet(hdwf, &bits) == 0 {
+		return 0, lastError()
+	}
+	return int(bits), nil
+}
+
+func dwfDigitalInBufferSizeGet(hdwf C.HDWF) (int, error) {
+	var size C.int
+	if C.FDwfDigitalInBufferSizeGet(hdwf, &size) == 0 {
+		return 0, lastError()
+	}
+	return int(size), nil
+}
+
 func dwfDigitalInSampleFormatSet(hdwf C.HDWF, bits int) error {
 	if C.FDwfDigitalInSampleFormatSet(hdwf, C.int(bits)) == 0 {
 		return lastError()
@@ -506,6 +1110,17 @@ func dwfDigitalInSampleFormatSet(hdwf C.HDWF, bits int) error {
 	return nil
 }
 
+func dwfDigitalInInputOrderSet(hdwf C.HDWF, dioFirst bool) error {
+	var v C.int
+	if dioFirst {
+		v = 1
+	}
+	if C.FDwfDigitalInInputOrderSet(hdwf, v) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
 func dwfDigitalInBufferSizeSet(hdwf C.HDWF, size int) error {
 	if C.FDwfDigitalInBufferSizeSet(hdwf, C.int(size)) == 0 {
 		return lastError()
@@ -527,6 +1142,38 @@ func dwfDigitalInConfigure(hdwf C.HDWF, reconfigure, start bool) error {
 	return nil
 }
 
+func dwfDigitalInCounterInfo(hdwf C.HDWF) (float64, float64, error) {
+	var cntMax, secMax C.double
+	if C.FDwfDigitalInCounterInfo(hdwf, &cntMax, &secMax) == 0 {
+		return 0, 0, lastError()
+	}
+	return float64(cntMax), float64(secMax), nil
+}
+
+func dwfDigitalInCounterSet(hdwf C.HDWF, seconds float64) error {
+	if C.FDwfDigitalInCounterSet(hdwf, C.double(seconds)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfDigitalInCounterStatus(hdwf C.HDWF) (float64, float64, int, error) {
+	var cnt, freq C.double
+	var tick C.int
+	if C.FDwfDigitalInCounterStatus(hdwf, &cnt, &freq, &tick) == 0 {
+		return 0, 0, 0, lastError()
+	}
+	return float64(cnt), float64(freq), int(tick), nil
+}
+
+func dwfDigitalInStatusAutoTriggered(hdwf C.HDWF) (bool, error) {
+	var auto C.int
+	if C.FDwfDigitalInStatusAutoTriggered(hdwf, &auto) == 0 {
+		return false, lastError()
+	}
+	return auto != 0, nil
+}
+
 func dwfDigitalInStatus(hdwf C.HDWF, readData bool) (byte, error) {
 	var rd C.int
 	if readData {
@@ -539,8 +1186,8 @@ func dwfDigitalInStatus(hdwf C.HDWF, readData bool) (byte, error) {
 	return byte(status), nil
 }
 
-func dwfDigitalInStatusData(hdwf C.HDWF, buf []uint16) error {
-	if C.FDwfDigitalInStatusData(hdwf, unsafe.Pointer(&buf[0]), C.int(2*len(buf))) == 0 {
+func dwfDigitalInStatusData(hdwf C.HDWF, buf []byte) error {
+	if C.FDwfDigitalInStatusData(hdwf, unsafe.Pointer(&buf[0]), C.int(len(buf))) == 0 {
 		return lastError()
 	}
 	return nil
@@ -629,6 +1276,14 @@ func dwfDigitalOutInternalClockInfo(hdwf C.HDWF) (float64, error) {
 	return float64(freq), nil
 }
 
+func dwfDigitalOutCounterInfo(hdwf C.HDWF, channel C.int) (int, error) {
+	var vMin, vMax C.uint
+	if C.FDwfDigitalOutCounterInfo(hdwf, channel, &vMin, &vMax) == 0 {
+		return 0, lastError()
+	}
+	return int(vMax), nil
+}
+
 func dwfDigitalOutEnableSet(hdwf C.HDWF, channel C.int, enable bool) error {
 	var e C.int
 	if enable {
@@ -640,6 +1295,30 @@ func dwfDigitalOutEnableSet(hdwf C.HDWF, channel C.int, enable bool) error {
 	return nil
 }
 
+func dwfDigitalOutEnableGet(hdwf C.HDWF, channel C.int) (bool, error) {
+	var e C.int
+	if C.FDwfDigitalOutEnableGet(hdwf, channel, &e) == 0 {
+		return false, lastError()
+	}
+	return e != 0, nil
+}
+
+func dwfDigitalOutTypeInfo(hdwf C.HDWF, channel C.int) (int, error) {
+	var types C.int
+	if C.FDwfDigitalOutTypeInfo(hdwf, channel, &types) == 0 {
+		return 0, lastError()
+	}
+	return int(types), nil
+}
+
+func dwfDigitalOutTypeGet(hdwf C.HDWF, channel C.int) (DigitalOutType, error) {
+	var outType C.DwfDigitalOutType
+	if C.FDwfDigitalOutTypeGet(hdwf, channel, &outType) == 0 {
+		return 0, lastError()
+	}
+	return DigitalOutType(outType), nil
+}
+
 func dwfDigitalOutTypeSet(hdwf C.HDWF, channel C.int, outType C.DwfDigitalOutType) error {
 	if C.FDwfDigitalOutTypeSet(hdwf, channel, outType) == 0 {
 		return lastError()
@@ -654,6 +1333,29 @@ func dwfDigitalOutDividerSet(hdwf C.HDWF, channel C.int, divider int) error {
 	return nil
 }
 
+func dwfDigitalOutOutputInfo(hdwf C.HDWF, channel C.int) (int, error) {
+	var outputs C.int
+	if C.FDwfDigitalOutOutputInfo(hdwf, channel, &outputs) == 0 {
+		return 0, lastError()
+	}
+	return int(outputs), nil
+}
+
+func dwfDigitalOutOutputSet(hdwf C.HDWF, channel C.int, output C.DwfDigitalOutOutput) error {
+	if C.FDwfDigitalOutOutputSet(hdwf, channel, output) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfDigitalOutIdleInfo(hdwf C.HDWF, channel C.int) (int, error) {
+	var idles C.int
+	if C.FDwfDigitalOutIdleInfo(hdwf, channel, &idles) == 0 {
+		return 0, lastError()
+	}
+	return int(idles), nil
+}
+
 func dwfDigitalOutIdleSet(hdwf C.HDWF, channel C.int, idle C.DwfDigitalOutIdle) error {
 	if C.FDwfDigitalOutIdleSet(hdwf, channel, idle) == 0 {
 		return lastError()
@@ -682,6 +1384,22 @@ func dwfDigitalOutRepeatSet(hdwf C.HDWF, repeat int) error {
 	return nil
 }
 
+func dwfDigitalOutDividerGet(hdwf C.HDWF, channel C.int) (int, error) {
+	var divider C.uint
+	if C.FDwfDigitalOutDividerGet(hdwf, channel, &divider) == 0 {
+		return 0, lastError()
+	}
+	return int(divider), nil
+}
+
+func dwfDigitalOutCounterGet(hdwf C.HDWF, channel C.int) (int, int, error) {
+	var low, high C.uint
+	if C.FDwfDigitalOutCounterGet(hdwf, channel, &low, &high) == 0 {
+		return 0, 0, lastError()
+	}
+	return int(low), int(high), nil
+}
+
 func dwfDigitalOutCounterSet(hdwf C.HDWF, channel C.int, low, high int) error {
 	if C.FDwfDigitalOutCounterSet(hdwf, channel, C.uint(low), C.uint(high)) == 0 {
 		return lastError()
@@ -689,11 +1407,66 @@ func dwfDigitalOutCounterSet(hdwf C.HDWF, channel C.int, low, high int) error {
 	return nil
 }
 
+func dwfDigitalOutCounterInitSet(hdwf C.HDWF, channel C.int, high bool, count int) error {
+	var h C.int
+	if high {
+		h = 1
+	}
+	if C.FDwfDigitalOutCounterInitSet(hdwf, channel, h, C.uint(count)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfDigitalOutDataInfo(hdwf C.HDWF, channel C.int) (int, error) {
+	var maxBits C.uint
+	if C.FDwfDigitalOutDataInfo(hdwf, channel, &maxBits) == 0 {
+		return 0, lastError()
+	}
+	return int(maxBits), nil
+}
+
+// dwfDigitalOutDataSet packs one bit per element of data, the first bit in
+// the least significant bit of the first byte as the SDK expects.
 func dwfDigitalOutDataSet(hdwf C.HDWF, channel C.int, data []uint16) error {
 	if len(data) == 0 {
 		return nil
 	}
-	if C.FDwfDigitalOutDataSet(hdwf, channel, unsafe.Pointer(&data[0]), C.uint(len(data))) == 0 {
+	packed := make([]byte, (len(data)+7)/8)
+	for i, v := range data {
+		if v != 0 {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	if C.FDwfDigitalOutDataSet(hdwf, channel, unsafe.Pointer(&packed[0]), C.uint(len(data))) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+// dwfDigitalOutPlayDataSet packs samples of bitsPerSample bits each back to
+// back, the first sample in the least significant bits of the first byte.
+func dwfDigitalOutPlayDataSet(hdwf C.HDWF, data []uint16, bitsPerSample int) error {
+	if len(data) == 0 {
+		return nil
+	}
+	packed := make([]byte, (len(data)*bitsPerSample+7)/8)
+	for i, v := range data {
+		for b := range bitsPerSample {
+			if v>>b&1 != 0 {
+				bit := i*bitsPerSample + b
+				packed[bit/8] |= 1 << (bit % 8)
+			}
+		}
+	}
+	if C.FDwfDigitalOutPlayDataSet(hdwf, (*C.uchar)(unsafe.Pointer(&packed[0])), C.uint(bitsPerSample), C.uint(len(data))) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
+func dwfDigitalOutPlayRateSet(hdwf C.HDWF, rate float64) error {
+	if C.FDwfDigitalOutPlayRateSet(hdwf, C.double(rate)) == 0 {
 		return lastError()
 	}
 	return nil
@@ -717,6 +1490,22 @@ func dwfDigitalOutReset(hdwf C.HDWF) error {
 	return nil
 }
 
+func dwfDigitalOutStatus(hdwf C.HDWF) (byte, error) {
+	var status C.DwfState
+	if C.FDwfDigitalOutStatus(hdwf, &status) == 0 {
+		return 0, lastError()
+	}
+	return byte(status), nil
+}
+
+func dwfDigitalOutRepeatStatus(hdwf C.HDWF) (int, error) {
+	var remaining C.uint
+	if C.FDwfDigitalOutRepeatStatus(hdwf, &remaining) == 0 {
+		return 0, lastError()
+	}
+	return int(remaining), nil
+}
+
 func dwfDigitalOutRepeatTriggerSet(hdwf C.HDWF, enable bool) error {
 	var e C.int
 	if enable {
@@ -789,6 +1578,21 @@ func dwfDigitalIOInputStatus(hdwf C.HDWF) (uint32, error) {
 	return uint32(data), nil
 }
 
+func dwfDigitalIOPullGet(hdwf C.HDWF) (uint32, uint32, error) {
+	var up, down C.uint
+	if C.FDwfDigitalIOPullGet(hdwf, &up, &down) == 0 {
+		return 0, 0, lastError()
+	}
+	return uint32(up), uint32(down), nil
+}
+
+func dwfDigitalIOPullSet(hdwf C.HDWF, up, down uint32) error {
+	if C.FDwfDigitalIOPullSet(hdwf, C.uint(up), C.uint(down)) == 0 {
+		return lastError()
+	}
+	return nil
+}
+
 func dwfDigitalIOReset(hdwf C.HDWF) error {
 	if C.FDwfDigitalIOReset(hdwf) == 0 {
 		return lastError()
@@ -1050,6 +1854,9 @@ func dwfDigitalI2cWriteRead(hdwf C.HDWF, address C.int, txData, rxBuf []byte) (i
 // DevHandle is the Go-level alias for the native device handle.
 type DevHandle = C.HDWF
 
+// analogOutNode is the Go-level alias for a wavegen channel node.
+type analogOutNode = C.int
+
 // Go-level constants wrapping C SDK constants
 var (
 	cEnumfilterAll        = C.int(C.enumfilterAll)
@@ -1058,14 +1865,21 @@ var (
 	cDevidDDiscovery      = C.int(C.devidDDiscovery)
 	cDevidADP3X50         = C.int(C.devidADP3X50)
 	cDevidADP5250         = C.int(C.devidADP5250)
-	cFilterDecimate       = C.int(C.filterDecimate)
 	cTrigsrcNone          = C.TRIGSRC(C.trigsrcNone)
 	cTrigsrcDetectorDigIn = C.TRIGSRC(C.trigsrcDetectorDigitalIn)
-	cTrigtypeEdge         = C.int(C.trigtypeEdge)
 	cDwfTriggerSlopeRise  = C.DwfTriggerSlope(C.DwfTriggerSlopeRise)
 	cDwfTriggerSlopeFall  = C.DwfTriggerSlope(C.DwfTriggerSlopeFall)
+	cDwfStateReady        = byte(C.DwfStateReady)
 	cDwfStateDone         = byte(C.DwfStateDone)
+	cDwfStateRunning      = byte(C.DwfStateRunning)
+	cDwfStateWait         = byte(C.DwfStateWait)
+	cDwfStateConfig       = byte(C.DwfStateConfig)
+	cDwfStatePrefill      = byte(C.DwfStatePrefill)
+	cDwfStateArmed        = byte(C.DwfStateArmed)
+	cAcqmodeRecord        = C.ACQMODE(C.acqmodeRecord)
 	cAnalogOutNodeCarrier = C.int(C.AnalogOutNodeCarrier)
+	cAnalogOutNodeFM      = C.int(C.AnalogOutNodeFM)
+	cAnalogOutNodeAM      = C.int(C.AnalogOutNodeAM)
 	cDwfDigitalOutIdleZet = C.DwfDigitalOutIdle(C.DwfDigitalOutIdleZet)
 
 	// DwfEnumConfigInfo constants
@@ -1093,8 +1907,32 @@ func cFunc(v WavegenFunc) C.FUNC { return C.FUNC(v) }
 // cTrigSrc converts Go TriggerSource to C.TRIGSRC
 func cTrigSrc(v TriggerSource) C.TRIGSRC { return C.TRIGSRC(v) }
 
+// cTrigType converts Go TriggerType to the C trigger type
+func cTrigType(v TriggerType) C.int { return C.int(v) }
+
+// cTrigSlope converts Go TriggerSlope to C.DwfTriggerSlope
+func cTrigSlope(v TriggerSlope) C.DwfTriggerSlope { return C.DwfTriggerSlope(v) }
+
+// cTrigLen converts Go TriggerLengthCondition to C.TRIGLEN
+func cTrigLen(v TriggerLengthCondition) C.TRIGLEN { return C.TRIGLEN(v) }
+
+// cFilter converts Go ScopeFilter to C.FILTER
+func cFilter(v ScopeFilter) C.FILTER { return C.FILTER(v) }
+
+// cAcqMode converts Go AcquisitionMode to C.ACQMODE
+func cAcqMode(v AcquisitionMode) C.ACQMODE { return C.ACQMODE(v) }
+
+// cAnalogCoupling converts Go AnalogCoupling to C.DwfAnalogCoupling
+func cAnalogCoupling(v AnalogCoupling) C.DwfAnalogCoupling { return C.DwfAnalogCoupling(v) }
+
 // cDigitalOutType converts Go DigitalOutType to C.DwfDigitalOutType
 func cDigitalOutType(v DigitalOutType) C.DwfDigitalOutType { return C.DwfDigitalOutType(v) }
 
+// cDigitalOutOutput converts Go DigitalOutOutput to C.DwfDigitalOutOutput
+func cDigitalOutOutput(v DigitalOutOutput) C.DwfDigitalOutOutput { return C.DwfDigitalOutOutput(v) }
+
 // cDigitalOutIdle converts Go DigitalOutIdle to C.DwfDigitalOutIdle
 func cDigitalOutIdle(v DigitalOutIdle) C.DwfDigitalOutIdle { return C.DwfDigitalOutIdle(v) }
+
+// cAnalogOutIdle converts Go WavegenIdle to C.DwfAnalogOutIdle
+func cAnalogOutIdle(v WavegenIdle) C.DwfAnalogOutIdle { return C.DwfAnalogOutIdle(v) }
@@ -0,0 +1,185 @@
+package dwf
+
+// SPITransfer is one chip-select framed SPI transaction decoded from a
+// logic analyzer capture.
+type SPITransfer struct {
+	// Start is the sample index where CS was asserted.
+	Start int
+	// End is the sample index where CS was released.
+	End int
+	// MOSI holds the bytes sent by the controller; empty if not sampled.
+	MOSI []byte
+	// MISO holds the bytes sent by the peripheral; empty if not sampled.
+	MISO []byte
+	// Bits is the number of clock edges on which data was sampled.
+	Bits int
+	// Incomplete is set if the capture started or ended inside the transfer
+	// or the last byte has fewer than 8 bits, which are then dropped.
+	Incomplete bool
+}
+
+// DecodeSPI decodes the SPI traffic in a capture of all DIO lines. The
+// lines and the mode come from cfg; a negative CS treats the whole capture
+// as one transfer and a negative MISO or MOSI skips that direction. CS is
+// active low. Data is sampled on the leading clock edge in modes 0 and 2
+// and on the trailing edge in modes 1 and 3.
+func DecodeSPI(samples []uint32, cfg SPIConfig) []SPITransfer {
+	if len(samples) == 0 {
+		return nil
+	}
+	bit := func(s uint32, line int) bool { return line >= 0 && s&(1<<uint(line)) != 0 }
+	cpol := cfg.Mode&2 != 0
+	cpha := cfg.Mode&1 != 0
+	// The sampling edge is rising when CPOL == CPHA.
+	sampleRising := cpol == cpha
+
+	var out []SPITransfer
+	var cur *SPITransfer
+	var mosiByte, misoByte byte
+	var nbits int
+	shift := func(b byte, v bool) byte {
+		var in byte
+		if v {
+			in = 1
+		}
+		if cfg.MSBFirst {
+			return b<<1 | in
+		}
+		return b>>1 | in<<7
+	}
+	finish := func(end int, incomplete bool) {
+		cur.End = end
+		cur.Incomplete = cur.Incomplete || incomplete || nbits != 0
+		out = append(out, *cur)
+		cur = nil
+	}
+	begin := func(start int, incomplete bool) {
+		cur = &SPITransfer{Start: start, Incomplete: incomplete}
+		mosiByte, misoByte, nbits = 0, 0, 0
+	}
+
+	active := func(s uint32) bool { return cfg.CS < 0 || !bit(s, cfg.CS) }
+	if active(samples[0]) {
+		begin(0, cfg.CS >= 0)
+	}
+	for i := 1; i < len(samples); i++ {
+		prev, s := samples[i-1], samples[i]
+		switch {
+		case cur == nil && active(s):
+			begin(i, false)
+			continue
+		case cur != nil && !active(s):
+			finish(i, false)
+			continue
+		case cur == nil:
+			continue
+		}
+		p, c := bit(prev, cfg.SCK), bit(s, cfg.SCK)
+		if p == c || c != sampleRising {
+			continue
+		}
+		cur.Bits++
+		mosiByte = shift(mosiByte, bit(s, cfg.MOSI))
+		misoByte = shift(misoByte, bit(s, cfg.MISO))
+		nbits++
+		if nbits == 8 {
+			if cfg.MOSI >= 0 {
+				cur.MOSI = append(cur.MOSI, mosiByte)
+			}
+			if cfg.MISO >= 0 {
+				cur.MISO = append(cur.MISO, misoByte)
+			}
+			mosiByte, misoByte, nbits = 0, 0, 0
+		}
+	}
+	if cur != nil {
+		finish(len(samples), cfg.CS >= 0)
+	}
+	return out
+}
+
+// UARTFrame is one character decoded from a logic analyzer capture.
+type UARTFrame struct {
+	// Start is the sample index of the falling edge of the start bit.
+	Start int
+	// End is the sample index just past the last stop bit.
+	End int
+	// Data holds the data bits, LSB first on the wire.
+	Data uint16
+	// ParityError is set if the parity bit does not match the data.
+	ParityError bool
+	// FramingError is set if a stop bit was low.
+	FramingError bool
+	// Break is set if the line stayed low for the whole frame.
+	Break bool
+	// Incomplete is set if the capture ended inside the frame; Data then
+	// holds only the bits received.
+	Incomplete bool
+}
+
+// DecodeUART decodes the characters received on cfg.RX in a capture of all
+// DIO lines sampled at sampleRate Hz. The line idles high; each bit is read
+// at its center, timed from the falling edge of the start bit. A start bit
+// that is no longer low at its center is taken as a glitch and skipped.
+// Parity 1 is odd and 2 is even, as in UARTConfig.
+func DecodeUART(samples []uint32, sampleRate float64, cfg UARTConfig) []UARTFrame {
+	if len(samples) == 0 || sampleRate <= 0 || cfg.BaudRate <= 0 || cfg.RX < 0 {
+		return nil
+	}
+	perBit := sampleRate / float64(cfg.BaudRate)
+	parityBits := 0
+	if cfg.Parity != 0 {
+		parityBits = 1
+	}
+	frameBits := 1 + cfg.DataBits + parityBits + cfg.StopBits
+	high := func(i int) bool { return samples[i]&(1<<uint(cfg.RX)) != 0 }
+	// center returns the sample at the middle of bit k of a frame whose
+	// start edge is at sample start.
+	center := func(start, k int) int { return start + int((float64(k)+0.5)*perBit) }
+
+	var out []UARTFrame
+	for i := 1; i < len(samples); i++ {
+		if !high(i-1) || high(i) {
+			continue
+		}
+		if c := center(i, 0); c < len(samples) && high(c) {
+			continue
+		}
+		f := UARTFrame{Start: i, End: i + int(float64(frameBits)*perBit+0.5)}
+		ones, low := 0, true
+		k := 1
+		for ; k < frameBits; k++ {
+			c := center(i, k)
+			if c >= len(samples) {
+				f.Incomplete = true
+				break
+			}
+			v := high(c)
+			low = low && !v
+			switch {
+			case k <= cfg.DataBits:
+				if v {
+					f.Data |= 1 << uint(k-1)
+					ones++
+				}
+			case k <= cfg.DataBits+parityBits:
+				if v {
+					ones++
+				}
+				// Odd parity makes the count of ones odd, even parity even.
+				f.ParityError = (ones%2 == 1) != (cfg.Parity == 1)
+			default:
+				f.FramingError = f.FramingError || !v
+			}
+		}
+		f.Break = !f.Incomplete && low
+		out = append(out, f)
+		if f.Incomplete {
+			break
+		}
+		// Look for the next start bit from the middle of the last stop bit,
+		// which is where a falling edge can first occur.
+		i = center(f.Start, frameBits-1)
+	}
+	return out
+}
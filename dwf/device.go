@@ -1,7 +1,16 @@
 package dwf
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 // deviceNames maps human-readable names to DWF SDK device filter IDs.
@@ -28,10 +37,15 @@ var deviceIDToName = map[int]string{
 type Device struct {
 	handle DevHandle
 	info   *DeviceInfo
+	// config and revision record the configuration and hardware revision of
+	// the open device, for status reports and reprogramming.
+	config   int
+	revision int
 
 	scope    *scopeImpl
 	wavegen  *wavegenImpl
 	supply   *supplyImpl
+	analogIO *analogIOImpl
 	dmm      *dmmImpl
 	logic    *logicImpl
 	pattern  *patternImpl
@@ -39,6 +53,10 @@ type Device struct {
 	uart     *uartImpl
 	spi      *spiImpl
 	i2c      *i2cImpl
+
+	// ioMu serializes the analog I/O calls of the soft-start ramp, which
+	// runs in the background, with those of the tools.
+	ioMu sync.Mutex
 }
 
 // NewDevice creates a new unconnected Device instance.
@@ -47,8 +65,9 @@ func NewDevice() *Device {
 	d.scope = &scopeImpl{dev: d}
 	d.wavegen = &wavegenImpl{dev: d}
 	d.supply = &supplyImpl{dev: d}
+	d.analogIO = &analogIOImpl{dev: d}
 	d.dmm = &dmmImpl{dev: d}
-	d.logic = &logicImpl{dev: d}
+	d.logic = &logicImpl{dev: d, sampleBits: logicSampleBits, triggerIndex: -1}
 	d.pattern = &patternImpl{dev: d}
 	d.staticIO = &staticIOImpl{dev: d}
 	d.uart = &uartImpl{dev: d}
@@ -140,7 +159,8 @@ func (d *Device) EnumConfigs(deviceIndex int) ([]DeviceConfig, error) {
 // Open connects to a Digilent device.
 func (d *Device) Open(device string, config int) (*DeviceInfo, error) {
 	filter := cEnumfilterAll
-	if devID, ok := deviceNames[device]; ok {
+	devID, isProduct := deviceNames[device]
+	if isProduct {
 		filter = cInt(int(devID))
 	}
 
@@ -155,12 +175,28 @@ func (d *Device) Open(device string, config int) (*DeviceInfo, error) {
 		return nil, fmt.Errorf("no %s connected", device)
 	}
 
+	// anything that is not a product name selects a device by serial number
+	candidates := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		if device != "" && !isProduct {
+			if sn, err := dwfEnumSN(cInt(i)); err != nil || sn != device {
+				continue
+			}
+		}
+		candidates = append(candidates, i)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no device with serial number %s connected", device)
+	}
+
 	// attempt to open the first available device
 	var hdwf DevHandle
 	var openErr error
-	for i := 0; i < count; i++ {
+	opened := 0
+	for _, i := range candidates {
 		hdwf, openErr = dwfDeviceConfigOpen(cInt(i), cInt(config))
 		if hdwf != 0 {
+			opened = i
 			break
 		}
 	}
@@ -171,20 +207,23 @@ func (d *Device) Open(device string, config int) (*DeviceInfo, error) {
 		return nil, fmt.Errorf("failed to open device")
 	}
 	d.handle = hdwf
+	d.scope.defaultHysteresis, _ = dwfAnalogInTriggerHysteresisGet(hdwf)
 
 	// detect device type
 	devName := ""
+	devType := -1
 	serialNum := ""
-	if count > 0 {
-		devID, _, err := dwfEnumDeviceType(0)
-		if err == nil {
-			if name, ok := deviceIDToName[devID]; ok {
-				devName = name
-			}
-		}
-		if sn, err := dwfEnumSN(0); err == nil {
-			serialNum = sn
+	d.config = config
+	d.revision = 0
+	if devID, devRev, err := dwfEnumDeviceType(cInt(opened)); err == nil {
+		if name, ok := deviceIDToName[devID]; ok {
+			devName = name
 		}
+		devType = devID
+		d.revision = devRev
+	}
+	if sn, err := dwfEnumSN(cInt(opened)); err == nil {
+		serialNum = sn
 	}
 
 	// get version
@@ -216,13 +255,67 @@ func (d *Device) Open(device string, config int) (*DeviceInfo, error) {
 	if n, err := dwfDigitalOutCount(hdwf); err == nil {
 		info.DigitalOutChannels = n
 	}
+	info.Capabilities = probeCapabilities(hdwf, devType, config)
+	info.Capabilities.ADCBits = info.MaxAnalogInResolution
 
 	d.info = info
 	return info, nil
 }
 
+// probeCapabilities finds the optional features of an opened device from
+// its analog I/O channels and clocks.
+func probeCapabilities(h DevHandle, devType, config int) DeviceCapabilities {
+	caps := DeviceCapabilities{Config: config}
+	if devType == int(cDevidDDiscovery) {
+		caps.FirstDIO = 24
+	}
+	if hz, err := dwfDigitalInInternalClockInfo(h); err == nil {
+		caps.MaxDigitalRate = hz
+	}
+	chCount, err := dwfAnalogIOChannelCount(h)
+	if err != nil {
+		return caps
+	}
+	for ch := 0; ch < chCount; ch++ {
+		_, label, err := dwfAnalogIOChannelName(h, cInt(ch))
+		if err != nil {
+			continue
+		}
+		switch label {
+		case "DMM":
+			caps.HasDMM = true
+		case "V+", "p25V", "VDD", "p6V":
+			caps.HasSupplies = true
+		case "V-", "n25V":
+			caps.HasSuppliesNegative = true
+		case "VIO":
+			nodeCount, err := dwfAnalogIOChannelInfo(h, cInt(ch))
+			if err != nil {
+				continue
+			}
+			for n := 0; n < nodeCount; n++ {
+				if node, _, err := dwfAnalogIOChannelNodeName(h, cInt(ch), cInt(n)); err == nil && node == "Voltage" {
+					caps.VIOAdjustable = true
+				}
+			}
+		}
+	}
+	return caps
+}
+
+// firstDIO returns the number of the open device's first digital line.
+func (d *Device) firstDIO() int {
+	if d.info == nil {
+		return 0
+	}
+	return d.info.Capabilities.FirstDIO
+}
+
 // Close disconnects from the device.
 func (d *Device) Close() error {
+	d.supply.cancelRamp()
+	// The DIO lines of a reopened device are push-pull again.
+	d.staticIO.openDrain, d.staticIO.openSource, d.staticIO.outputs = 0, 0, 0
 	if d.handle != 0 {
 		err := dwfDeviceClose(d.handle)
 		d.handle = 0
@@ -231,8 +324,53 @@ func (d *Device) Close() error {
 	return nil
 }
 
+// Status reports the FPGA configuration of the open device and whether it
+// still answers requests.
+func (d *Device) Status() (FirmwareStatus, error) {
+	if d.handle == 0 || d.info == nil {
+		return FirmwareStatus{}, nil
+	}
+	st := FirmwareStatus{
+		Open:             true,
+		Name:             d.info.Name,
+		SerialNumber:     d.info.SerialNumber,
+		HardwareRevision: d.revision,
+		Config:           d.config,
+		SDKVersion:       d.info.Version,
+	}
+	auto, err := dwfDeviceAutoConfigureGet(d.handle)
+	if err != nil {
+		st.Error = err.Error()
+		return st, nil
+	}
+	st.Responsive = true
+	st.AutoConfigure = auto
+	return st, nil
+}
+
+// Reinitialize recovers a misbehaving device. Without reprogram all
+// instruments are reset to their defaults; with reprogram the device is
+// closed and reopened with the same configuration, which reloads the FPGA.
+func (d *Device) Reinitialize(reprogram bool) (*DeviceInfo, error) {
+	if d.handle == 0 || d.info == nil {
+		return nil, fmt.Errorf("no device open")
+	}
+	if !reprogram {
+		if err := dwfDeviceReset(d.handle); err != nil {
+			return nil, err
+		}
+		return d.info, nil
+	}
+	serial, config := d.info.SerialNumber, d.config
+	// a wedged device may fail to close cleanly; reopening still reloads it
+	_ = d.Close()
+	return d.Open(serial, config)
+}
+
 // Temperature returns the device board temperature in °C.
 func (d *Device) Temperature() (float64, error) {
+	d.ioMu.Lock()
+	defer d.ioMu.Unlock()
 	chCount, err := dwfAnalogIOChannelCount(d.handle)
 	if err != nil {
 		return 0, err
@@ -265,6 +403,7 @@ func (d *Device) Temperature() (float64, error) {
 func (d *Device) Scope() Oscilloscope       { return d.scope }
 func (d *Device) Wavegen() WavegenDriver    { return d.wavegen }
 func (d *Device) Supply() PowerSupply       { return d.supply }
+func (d *Device) AnalogIO() AnalogIO        { return d.analogIO }
 func (d *Device) DMM() DigitalMultimeter    { return d.dmm }
 func (d *Device) Logic() LogicAnalyzer      { return d.logic }
 func (d *Device) Pattern() PatternGenerator { return d.pattern }
@@ -276,20 +415,44 @@ func (d *Device) I2CProtocol() I2C          { return d.i2c }
 // ==================== Oscilloscope ====================
 
 type scopeImpl struct {
-	dev        *Device
-	bufferSize int
-}
-
-func (s *scopeImpl) Open(cfg ScopeConfig) error {
+	dev          *Device
+	bufferSize   int
+	sampleRate   float64
+	triggered    bool
+	triggerIndex int
+	acquiredAt   time.Time
+	mode         AcquisitionMode
+	recordLength float64
+	// scanning is set while a scan mode acquisition runs between records.
+	scanning bool
+	// defaultHysteresis is the trigger hysteresis of the freshly opened
+	// device, which a hysteresis of 0 restores.
+	defaultHysteresis float64
+}
+
+func (s *scopeImpl) Open(cfg ScopeConfig) (ScopeSettings, error) {
 	h := s.dev.handle
 	if err := dwfAnalogInChannelEnableSet(h, -1, true); err != nil {
-		return err
+		return ScopeSettings{}, err
+	}
+	attenuation := cfg.Attenuation
+	if attenuation == 0 {
+		attenuation = 1
+	}
+	if attenuation < 0 {
+		return ScopeSettings{}, fmt.Errorf("attenuation must be positive, got %g", attenuation)
+	}
+	if err := dwfAnalogInChannelAttenuationSet(h, -1, attenuation); err != nil {
+		return ScopeSettings{}, err
 	}
 	if err := dwfAnalogInChannelOffsetSet(h, -1, cfg.OffsetVoltage); err != nil {
-		return err
+		return ScopeSettings{}, err
 	}
 	if err := dwfAnalogInChannelRangeSet(h, -1, cfg.AmplitudeRange); err != nil {
-		return err
+		return ScopeSettings{}, err
+	}
+	if err := s.setCoupling(-1, cfg.Coupling); err != nil {
+		return ScopeSettings{}, err
 	}
 
 	maxBuf := 0
@@ -302,16 +465,114 @@ func (s *scopeImpl) Open(cfg ScopeConfig) error {
 	}
 	s.bufferSize = bufSize
 	if err := dwfAnalogInBufferSizeSet(h, bufSize); err != nil {
-		return err
+		return ScopeSettings{}, err
 	}
 	if err := dwfAnalogInFrequencySet(h, cfg.SamplingFrequency); err != nil {
+		return ScopeSettings{}, err
+	}
+	if cfg.Bandwidth > 0 {
+		if err := dwfAnalogInChannelBandwidthSet(h, -1, cfg.Bandwidth); err != nil {
+			return ScopeSettings{}, err
+		}
+	}
+	if err := dwfAnalogInChannelFilterSet(h, -1, cFilter(cfg.Filter)); err != nil {
+		return ScopeSettings{}, err
+	}
+	if cfg.RecordLength < 0 {
+		return ScopeSettings{}, fmt.Errorf("record length must not be negative, got %g", cfg.RecordLength)
+	}
+	if err := s.setMode(cfg.AcquisitionMode); err != nil {
+		return ScopeSettings{}, err
+	}
+	st, err := s.settings(maxBuf)
+	if err != nil {
+		return ScopeSettings{}, fmt.Errorf("reading back the settings: %w", err)
+	}
+	s.sampleRate = st.SamplingFrequency
+	if s.sampleRate == 0 {
+		s.sampleRate = cfg.SamplingFrequency
+	}
+	s.recordLength = cfg.RecordLength
+	if s.recordLength == 0 && s.sampleRate > 0 {
+		s.recordLength = float64(bufSize) / s.sampleRate
+	}
+	st.AcquisitionMode = s.mode
+	if s.mode == AcqModeRecord {
+		st.RecordLength = s.recordLength
+	}
+	return st, nil
+}
+
+// setMode applies the acquisition mode after checking that the device
+// supports it. Any running scan is stopped.
+func (s *scopeImpl) setMode(mode AcquisitionMode) error {
+	h := s.dev.handle
+	if mode < AcqModeSingle || mode > AcqModeRecord {
+		return fmt.Errorf("invalid acquisition mode %d", mode)
+	}
+	if mode != AcqModeSingle {
+		modes, err := dwfAnalogInAcquisitionModeInfo(h)
+		if err != nil {
+			return err
+		}
+		if modes&(1<<uint(mode)) == 0 {
+			return fmt.Errorf("acquisition mode %d is not supported by this device", mode)
+		}
+	}
+	if s.scanning {
+		_ = dwfAnalogInConfigure(h, false, false)
+		s.scanning = false
+	}
+	if err := dwfAnalogInAcquisitionModeSet(h, cAcqMode(mode)); err != nil {
 		return err
 	}
-	return dwfAnalogInChannelFilterSet(h, -1, cFilterDecimate)
+	s.mode = mode
+	return nil
+}
+
+func (s *scopeImpl) SampleRate() float64 {
+	return s.sampleRate
+}
+
+func (s *scopeImpl) BufferSize() int {
+	return s.bufferSize
+}
+
+// settings reads back the applied configuration of every channel and the
+// device limits. Limits the device does not report are left zero.
+func (s *scopeImpl) settings(maxBuf int) (ScopeSettings, error) {
+	h := s.dev.handle
+	st := ScopeSettings{MaxBufferSize: maxBuf}
+	var err error
+	if st.SamplingFrequency, err = dwfAnalogInFrequencyGet(h); err != nil {
+		return ScopeSettings{}, err
+	}
+	if st.BufferSize, err = dwfAnalogInBufferSizeGet(h); err != nil {
+		return ScopeSettings{}, err
+	}
+	count, err := dwfAnalogInChannelCount(h)
+	if err != nil {
+		return ScopeSettings{}, err
+	}
+	for i := range count {
+		ch := ScopeChannelSettings{Channel: i + 1}
+		if ch.OffsetVoltage, err = dwfAnalogInChannelOffsetGet(h, cInt(i)); err != nil {
+			return ScopeSettings{}, err
+		}
+		if ch.AmplitudeRange, err = dwfAnalogInChannelRangeGet(h, cInt(i)); err != nil {
+			return ScopeSettings{}, err
+		}
+		st.Channels = append(st.Channels, ch)
+	}
+	st.FrequencyLimits.Min, st.FrequencyLimits.Max, _ = dwfAnalogInFrequencyInfo(h)
+	st.RangeLimits.Min, st.RangeLimits.Max, _ = dwfAnalogInChannelRangeInfo(h)
+	st.OffsetLimits.Min, st.OffsetLimits.Max, _ = dwfAnalogInChannelOffsetInfo(h)
+	return st, nil
 }
 
 func (s *scopeImpl) Measure(channel int) (float64, error) {
 	h := s.dev.handle
+	s.scanning = false
 	if err := dwfAnalogInConfigure(h, false, false); err != nil {
 		return 0, err
 	}
@@ -321,8 +582,141 @@ func (s *scopeImpl) Measure(channel int) (float64, error) {
 	return dwfAnalogInStatusSample(h, cInt(channel-1))
 }
 
+func (s *scopeImpl) SetAttenuation(channel int, factor float64) error {
+	if factor <= 0 {
+		return fmt.Errorf("attenuation must be positive, got %g", factor)
+	}
+	return dwfAnalogInChannelAttenuationSet(s.dev.handle, cInt(channel-1), factor)
+}
+
+func (s *scopeImpl) Config() (ScopeReadback, error) {
+	h := s.dev.handle
+	var rb ScopeReadback
+	var err error
+	if rb.SamplingFrequency, err = dwfAnalogInFrequencyGet(h); err != nil {
+		return ScopeReadback{}, err
+	}
+	if rb.BufferSize, err = dwfAnalogInBufferSizeGet(h); err != nil {
+		return ScopeReadback{}, err
+	}
+	count, err := dwfAnalogInChannelCount(h)
+	if err != nil {
+		return ScopeReadback{}, err
+	}
+	for i := range count {
+		ch, err := s.channelConfig(i)
+		if err != nil {
+			return ScopeReadback{}, err
+		}
+		rb.Channels = append(rb.Channels, ch)
+	}
+	if rb.Trigger, err = s.triggerConfig(); err != nil {
+		return ScopeReadback{}, err
+	}
+	return rb, nil
+}
+
+// channelConfig reads back the settings of a 0-based channel index.
+// Coupling and bandwidth are not available on every device and read as DC
+// and 0 there.
+func (s *scopeImpl) channelConfig(idx int) (ScopeChannelReadback, error) {
+	h := s.dev.handle
+	ch := ScopeChannelReadback{Channel: idx + 1}
+	var err error
+	if ch.Enabled, err = dwfAnalogInChannelEnableGet(h, cInt(idx)); err != nil {
+		return ch, err
+	}
+	if ch.Range, err = dwfAnalogInChannelRangeGet(h, cInt(idx)); err != nil {
+		return ch, err
+	}
+	if ch.Offset, err = dwfAnalogInChannelOffsetGet(h, cInt(idx)); err != nil {
+		return ch, err
+	}
+	if ch.Attenuation, err = dwfAnalogInChannelAttenuationGet(h, cInt(idx)); err != nil {
+		return ch, err
+	}
+	if ch.Filter, err = dwfAnalogInChannelFilterGet(h, cInt(idx)); err != nil {
+		return ch, err
+	}
+	if coupling, err := dwfAnalogInChannelCouplingGet(h, cInt(idx)); err == nil {
+		ch.Coupling = coupling
+	}
+	if hz, err := dwfAnalogInChannelBandwidthGet(h, cInt(idx)); err == nil {
+		ch.Bandwidth = hz
+	}
+	return ch, nil
+}
+
+// triggerConfig reads back the trigger settings.
+func (s *scopeImpl) triggerConfig() (TriggerReadback, error) {
+	h := s.dev.handle
+	var t TriggerReadback
+	var err error
+	if t.Source, err = dwfAnalogInTriggerSourceGet(h); err != nil {
+		return t, err
+	}
+	if t.Channel, err = dwfAnalogInTriggerChannelGet(h); err != nil {
+		return t, err
+	}
+	t.Channel++
+	if t.Type, err = dwfAnalogInTriggerTypeGet(h); err != nil {
+		return t, err
+	}
+	if t.Condition, err = dwfAnalogInTriggerConditionGet(h); err != nil {
+		return t, err
+	}
+	if t.Level, err = dwfAnalogInTriggerLevelGet(h); err != nil {
+		return t, err
+	}
+	if t.Hysteresis, err = dwfAnalogInTriggerHysteresisGet(h); err != nil {
+		return t, err
+	}
+	if t.Position, err = dwfAnalogInTriggerPositionGet(h); err != nil {
+		return t, err
+	}
+	if t.HoldOff, err = dwfAnalogInTriggerHoldOffGet(h); err != nil {
+		return t, err
+	}
+	if t.AutoTimeout, err = dwfAnalogInTriggerAutoTimeoutGet(h); err != nil {
+		return t, err
+	}
+	if t.Length, err = dwfAnalogInTriggerLengthGet(h); err != nil {
+		return t, err
+	}
+	if t.LengthCondition, err = dwfAnalogInTriggerLengthConditionGet(h); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+func (s *scopeImpl) SetCoupling(channel int, coupling AnalogCoupling) error {
+	return s.setCoupling(channel-1, coupling)
+}
+
+// setCoupling applies coupling to a 0-based channel index (-1 for all).
+// Devices without selectable coupling are DC only; requesting DC on them is a no-op.
+func (s *scopeImpl) setCoupling(idx int, coupling AnalogCoupling) error {
+	h := s.dev.handle
+	supported, err := dwfAnalogInChannelCouplingInfo(h)
+	if err != nil {
+		supported = 1 << uint(CouplingDC)
+	}
+	if supported&(1<<uint(coupling)) == 0 {
+		name := "DC"
+		if coupling == CouplingAC {
+			name = "AC"
+		}
+		return fmt.Errorf("%s coupling not supported on this device", name)
+	}
+	if supported == 1<<uint(CouplingDC) {
+		return nil
+	}
+	return dwfAnalogInChannelCouplingSet(h, cInt(idx), cAnalogCoupling(coupling))
+}
+
 func (s *scopeImpl) SetTrigger(cfg TriggerConfig) error {
 	h := s.dev.handle
+	s.triggered = cfg.Enable && cfg.Source != TrigSrcNone
 	if cfg.Enable && cfg.Source != TrigSrcNone {
 		if err := dwfAnalogInTriggerAutoTimeoutSet(h, cfg.Timeout); err != nil {
 			return err
@@ -337,12 +731,40 @@ func (s *scopeImpl) SetTrigger(cfg TriggerConfig) error {
 		if err := dwfAnalogInTriggerChannelSet(h, cInt(ch)); err != nil {
 			return err
 		}
-		if err := dwfAnalogInTriggerTypeSet(h, cTrigtypeEdge); err != nil {
+		if err := dwfAnalogInTriggerTypeSet(h, cTrigType(cfg.Type)); err != nil {
 			return err
 		}
-		if err := dwfAnalogInTriggerLevelSet(h, cfg.Level); err != nil {
+		if cfg.Type == TriggerTypePulse {
+			if cfg.Length <= 0 {
+				return fmt.Errorf("pulse trigger requires a positive length, got %g s", cfg.Length)
+			}
+			if err := dwfAnalogInTriggerLengthSet(h, cfg.Length); err != nil {
+				return err
+			}
+			if err := dwfAnalogInTriggerLengthConditionSet(h, cTrigLen(cfg.LengthCondition)); err != nil {
+				return err
+			}
+		}
+		if cfg.Type == TriggerTypeWindow {
+			if err := s.setWindow(cfg.WindowLow, cfg.WindowHigh); err != nil {
+				return err
+			}
+		} else {
+			if err := dwfAnalogInTriggerLevelSet(h, cfg.Level); err != nil {
+				return err
+			}
+			if err := s.setHysteresis(cfg); err != nil {
+				return err
+			}
+		}
+		if err := s.setHoldOff(cfg.HoldOff); err != nil {
 			return err
 		}
+		if cfg.Position != nil {
+			if err := s.setPosition(*cfg.Position); err != nil {
+				return err
+			}
+		}
 		if cfg.EdgeRising {
 			return dwfAnalogInTriggerConditionSet(h, cDwfTriggerSlopeRise)
 		}
@@ -351,468 +773,2496 @@ func (s *scopeImpl) SetTrigger(cfg TriggerConfig) error {
 	return dwfAnalogInTriggerSourceSet(h, cTrigsrcNone)
 }
 
-func (s *scopeImpl) Record(channel int) ([]float64, error) {
+// autoHysteresisFraction is the share of the input range used as trigger
+// hysteresis in TriggerHysteresisAuto mode.
+const autoHysteresisFraction = 0.02
+
+// setHysteresis applies the trigger hysteresis, resolving the auto mode from
+// the range of the analog trigger channel and 0 to the device default.
+func (s *scopeImpl) setHysteresis(cfg TriggerConfig) error {
 	h := s.dev.handle
-	if err := dwfAnalogInConfigure(h, false, true); err != nil {
-		return nil, err
-	}
-	for {
-		status, err := dwfAnalogInStatus(h, true)
-		if err != nil {
-			return nil, err
+	volts := cfg.Hysteresis
+	switch {
+	case volts == TriggerHysteresisKeep:
+		return nil
+	case volts == 0:
+		volts = s.defaultHysteresis
+	case volts == TriggerHysteresisAuto:
+		if cfg.Source != TrigSrcDetectorAnalogIn {
+			return nil
 		}
-		if status == cDwfStateDone {
-			break
+		rng, err := dwfAnalogInChannelRangeGet(h, cInt(cfg.Channel-1))
+		if err != nil {
+			return err
 		}
+		volts = rng * autoHysteresisFraction
+	case volts < 0:
+		return fmt.Errorf("trigger hysteresis must not be negative, got %g V", volts)
 	}
-	return dwfAnalogInStatusData(h, cInt(channel-1), s.bufferSize)
+	return dwfAnalogInTriggerHysteresisSet(h, volts)
 }
 
-func (s *scopeImpl) Close() error {
-	return dwfAnalogInReset(s.dev.handle)
+// setWindow configures a window trigger. The device describes the window as
+// the trigger level plus or minus the hysteresis.
+func (s *scopeImpl) setWindow(low, high float64) error {
+	h := s.dev.handle
+	if high <= low {
+		return fmt.Errorf("window trigger requires high > low, got %g V to %g V", low, high)
+	}
+	if err := dwfAnalogInTriggerLevelSet(h, (low+high)/2); err != nil {
+		return err
+	}
+	return dwfAnalogInTriggerHysteresisSet(h, (high-low)/2)
 }
 
-// ==================== Wavegen ====================
-
-type wavegenImpl struct {
-	dev *Device
+// setHoldOff sets the trigger holdoff after checking the device range.
+func (s *scopeImpl) setHoldOff(seconds float64) error {
+	h := s.dev.handle
+	if seconds < 0 {
+		return fmt.Errorf("trigger holdoff must not be negative, got %g s", seconds)
+	}
+	lo, hi, err := dwfAnalogInTriggerHoldOffInfo(h)
+	if err != nil || hi <= 0 {
+		if seconds > 0 {
+			return fmt.Errorf("trigger holdoff is not supported by this device")
+		}
+		return nil
+	}
+	if seconds > 0 && (seconds < lo || seconds > hi) {
+		return fmt.Errorf("trigger holdoff %g s out of range [%g, %g] s", seconds, lo, hi)
+	}
+	return dwfAnalogInTriggerHoldOffSet(h, seconds)
 }
 
-func (w *wavegenImpl) Generate(cfg WavegenConfig) error {
-	h := w.dev.handle
-	ch := cInt(cfg.Channel - 1)
-	node := cAnalogOutNodeCarrier
-
-	if err := dwfAnalogOutNodeEnableSet(h, ch, node, true); err != nil {
-		return err
+// setPosition sets the horizontal trigger position after checking the
+// device range.
+func (s *scopeImpl) setPosition(seconds float64) error {
+	h := s.dev.handle
+	lo, hi, err := dwfAnalogInTriggerPositionInfo(h)
+	if err == nil && hi > lo && (seconds < lo || seconds > hi) {
+		return fmt.Errorf("trigger position %g s out of range [%g, %g] s", seconds, lo, hi)
 	}
-	if err := dwfAnalogOutNodeFunctionSet(h, ch, node, cFunc(cfg.Function)); err != nil {
+	return dwfAnalogInTriggerPositionSet(h, seconds)
+}
+
+// acquire starts a single acquisition and waits until it is done.
+func (s *scopeImpl) acquire(ctx context.Context) error {
+	h := s.dev.handle
+	if err := dwfAnalogInConfigure(h, false, true); err != nil {
 		return err
 	}
-	if cfg.Function == FuncCustom && len(cfg.CustomData) > 0 {
-		if err := dwfAnalogOutNodeDataSet(h, ch, node, cfg.CustomData); err != nil {
+	for {
+		if err := ctx.Err(); err != nil {
+			_ = dwfAnalogInConfigure(h, false, false)
+			return fmt.Errorf("acquisition aborted: %w", err)
+		}
+		status, err := dwfAnalogInStatus(h, true)
+		if err != nil {
 			return err
 		}
+		if status == cDwfStateDone {
+			s.triggerIndex = s.locateTrigger()
+			s.acquiredAt = time.Now()
+			return nil
+		}
 	}
-	if err := dwfAnalogOutNodeFrequencySet(h, ch, node, cfg.Frequency); err != nil {
-		return err
-	}
-	if err := dwfAnalogOutNodeAmplitudeSet(h, ch, node, cfg.Amplitude); err != nil {
-		return err
-	}
-	if err := dwfAnalogOutNodeOffsetSet(h, ch, node, cfg.Offset); err != nil {
-		return err
-	}
-	if err := dwfAnalogOutNodeSymmetrySet(h, ch, node, cfg.Symmetry); err != nil {
-		return err
+}
+
+// locateTrigger returns the buffer index of the trigger event of the last
+// acquisition, or -1 if it was not triggered or the trigger lies outside
+// the buffer. The device reports the trigger position in seconds from the
+// middle of the buffer.
+func (s *scopeImpl) locateTrigger() int {
+	h := s.dev.handle
+	if !s.triggered || s.sampleRate <= 0 {
+		return -1
 	}
-	if err := dwfAnalogOutRunSet(h, ch, cfg.RunTime); err != nil {
-		return err
+	if auto, err := dwfAnalogInStatusAutoTriggered(h); err != nil || auto {
+		return -1
 	}
-	if err := dwfAnalogOutWaitSet(h, ch, cfg.Wait); err != nil {
-		return err
+	pos, err := dwfAnalogInTriggerPositionStatus(h)
+	if err != nil {
+		return -1
 	}
-	if err := dwfAnalogOutRepeatSet(h, ch, cfg.Repeat); err != nil {
-		return err
+	index := s.bufferSize/2 - int(math.Round(pos*s.sampleRate))
+	if index < 0 || index >= s.bufferSize {
+		return -1
 	}
-	return dwfAnalogOutConfigure(h, ch, true)
-}
-
-func (w *wavegenImpl) Enable(channel int) error {
-	return dwfAnalogOutConfigure(w.dev.handle, cInt(channel-1), true)
+	return index
 }
 
-func (w *wavegenImpl) Disable(channel int) error {
-	return dwfAnalogOutConfigure(w.dev.handle, cInt(channel-1), false)
+func (s *scopeImpl) Timing() AcquisitionTiming {
+	return AcquisitionTiming{SampleRate: s.sampleRate, TriggerIndex: s.triggerIndex, Time: s.acquiredAt}
 }
 
-func (w *wavegenImpl) Close(channel int) error {
-	return dwfAnalogOutReset(w.dev.handle, cInt(channel-1))
+func (s *scopeImpl) Record(ctx context.Context, channel int) ([]float64, error) {
+	switch s.mode {
+	case AcqModeScanShift, AcqModeScanScreen:
+		data, err := s.recordScan(ctx, []int{channel})
+		if err != nil {
+			return nil, err
+		}
+		return data[0], nil
+	case AcqModeRecord:
+		return s.recordLong(ctx, channel)
+	}
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return dwfAnalogInStatusData(s.dev.handle, cInt(channel-1), s.bufferSize)
 }
 
-// ==================== Power Supply ====================
-
-type supplyImpl struct {
-	dev *Device
+func (s *scopeImpl) RecordChannels(ctx context.Context, channels []int) ([][]float64, error) {
+	switch s.mode {
+	case AcqModeScanShift, AcqModeScanScreen:
+		return s.recordScan(ctx, channels)
+	case AcqModeRecord:
+		return nil, fmt.Errorf("the record acquisition mode captures one channel at a time")
+	}
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	out := make([][]float64, len(channels))
+	for i, ch := range channels {
+		data, err := dwfAnalogInStatusData(s.dev.handle, cInt(ch-1), s.bufferSize)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = data
+	}
+	return out, nil
 }
 
-func (s *supplyImpl) findChannelNode(label, nodeName string) (int, int, bool) {
+// recordScan returns the samples of channels a scan mode acquisition holds
+// so far, starting the scan on first use. The scan keeps running between records,
+// so repeated calls follow the signal like a roll mode display. In scan
+// screen mode the samples are in buffer order, wrapping at the write
+// position.
+func (s *scopeImpl) recordScan(ctx context.Context, channels []int) ([][]float64, error) {
 	h := s.dev.handle
-	chCount, err := dwfAnalogIOChannelCount(h)
-	if err != nil {
-		return -1, -1, false
-	}
+	if !s.scanning {
+		if err := dwfAnalogInConfigure(h, false, true); err != nil {
+			return nil, err
+		}
+		s.scanning = true
+	}
+	s.triggerIndex = -1
+	for {
+		if err := ctx.Err(); err != nil {
+			_ = dwfAnalogInConfigure(h, false, false)
+			s.scanning = false
+			return nil, fmt.Errorf("acquisition aborted: %w", err)
+		}
+		if _, err := dwfAnalogInStatus(h, true); err != nil {
+			return nil, err
+		}
+		valid, err := dwfAnalogInStatusSamplesValid(h)
+		if err != nil {
+			return nil, err
+		}
+		if valid == 0 {
+			continue
+		}
+		out := make([][]float64, len(channels))
+		for i, ch := range channels {
+			if out[i], err = dwfAnalogInStatusData(h, cInt(ch-1), min(valid, s.bufferSize)); err != nil {
+				return nil, err
+			}
+		}
+		s.acquiredAt = time.Now()
+		return out, nil
+	}
+}
+
+// recordLong streams a record of the configured length to the host, which
+// allows records longer than the device buffer. Samples lost because the
+// host could not keep up fail the record rather than leave silent gaps.
+func (s *scopeImpl) recordLong(ctx context.Context, channel int) ([]float64, error) {
+	var data []float64
+	stats, err := s.stream(ctx, channel, s.recordLength, func(chunk []float64) error {
+		data = append(data, chunk...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stats.Lost > 0 || stats.Corrupt > 0 {
+		return nil, fmt.Errorf("record lost %d and corrupted %d samples; lower the sampling frequency", stats.Lost, stats.Corrupt)
+	}
+	s.triggerIndex = -1
+	s.acquiredAt = time.Now()
+	return data, nil
+}
+
+func (s *scopeImpl) RecordPeak(ctx context.Context, channel int) (PeakRecord, error) {
+	h := s.dev.handle
+	maxNoise, err := dwfAnalogInNoiseSizeInfo(h)
+	if err != nil {
+		return PeakRecord{}, err
+	}
+	if maxNoise <= 0 || s.bufferSize <= 0 {
+		return PeakRecord{}, fmt.Errorf("peak detection is not supported by this device")
+	}
+	if s.mode != AcqModeSingle {
+		return PeakRecord{}, fmt.Errorf("peak detection needs the single acquisition mode")
+	}
+	size := min(maxNoise, s.bufferSize)
+	if err := dwfAnalogInNoiseSizeSet(h, size); err != nil {
+		return PeakRecord{}, err
+	}
+	// The noise buffer takes device memory, so release it afterwards.
+	defer dwfAnalogInNoiseSizeSet(h, 0)
+
+	if err := s.acquire(ctx); err != nil {
+		return PeakRecord{}, err
+	}
+	lo, hi, err := dwfAnalogInStatusNoise(h, cInt(channel-1), size)
+	if err != nil {
+		return PeakRecord{}, err
+	}
+	data, err := dwfAnalogInStatusData(h, cInt(channel-1), s.bufferSize)
+	if err != nil {
+		return PeakRecord{}, err
+	}
+	return PeakRecord{Min: lo, Max: hi, SamplesPerPoint: float64(s.bufferSize) / float64(size), Data: data}, nil
+}
+
+func (s *scopeImpl) RecordRaw(ctx context.Context, channel int) (RawRecord, error) {
+	h := s.dev.handle
+	bits, err := dwfAnalogInBitsInfo(h)
+	if err != nil || bits <= 0 || bits > 16 || s.bufferSize <= 0 {
+		return RawRecord{}, fmt.Errorf("raw ADC samples are not supported by this device")
+	}
+	if s.mode != AcqModeSingle {
+		return RawRecord{}, fmt.Errorf("raw ADC samples need the single acquisition mode")
+	}
+	if err := s.acquire(ctx); err != nil {
+		return RawRecord{}, err
+	}
+	ch := cInt(channel - 1)
+	codes, err := dwfAnalogInStatusData16(h, ch, s.bufferSize)
+	if err != nil {
+		return RawRecord{}, err
+	}
+	// The codes span the input range, centered on the offset.
+	rng, err := dwfAnalogInChannelRangeGet(h, ch)
+	if err != nil {
+		return RawRecord{}, err
+	}
+	offset, err := dwfAnalogInChannelOffsetGet(h, ch)
+	if err != nil {
+		return RawRecord{}, err
+	}
+	return RawRecord{Codes: codes, Bits: bits, VoltsPerCode: rng / 65536, Offset: offset}, nil
+}
+
+func (s *scopeImpl) RecordAverage(ctx context.Context, channel int, count int) (AveragedRecord, error) {
+	if count < 1 {
+		return AveragedRecord{}, fmt.Errorf("average count must be at least 1, got %d", count)
+	}
+	// Welford's running mean/variance keeps the sums numerically stable.
+	var mean, m2 []float64
+	for n := 1; n <= count; n++ {
+		data, err := s.Record(ctx, channel)
+		if err != nil {
+			return AveragedRecord{}, fmt.Errorf("acquisition %d of %d: %w", n, count, err)
+		}
+		if mean == nil {
+			mean = make([]float64, len(data))
+			m2 = make([]float64, len(data))
+		}
+		if len(data) != len(mean) {
+			return AveragedRecord{}, fmt.Errorf("acquisition %d returned %d samples, expected %d", n, len(data), len(mean))
+		}
+		for i, v := range data {
+			d := v - mean[i]
+			mean[i] += d / float64(n)
+			m2[i] += d * (v - mean[i])
+		}
+	}
+	std := make([]float64, len(mean))
+	if count > 1 {
+		for i := range m2 {
+			std[i] = math.Sqrt(m2[i] / float64(count-1))
+		}
+	}
+	return AveragedRecord{Count: count, Mean: mean, StdDev: std}, nil
+}
+
+func (s *scopeImpl) CounterLimits() (Limits, error) {
+	_, secMax, err := dwfAnalogInCounterInfo(s.dev.handle)
+	if err != nil {
+		// Devices without a counter reject the call.
+		return Limits{}, nil
+	}
+	return Limits{Max: secMax}, nil
+}
+
+func (s *scopeImpl) Count(ctx context.Context, cfg CounterConfig) (CounterReading, error) {
+	h := s.dev.handle
+	limits, _ := s.CounterLimits()
+	if limits.Max <= 0 {
+		return CounterReading{}, fmt.Errorf("the analog-in frequency counter is not supported by this device")
+	}
+	if cfg.GateTime <= 0 || cfg.GateTime > limits.Max {
+		return CounterReading{}, fmt.Errorf("gate time must be between 0 and %g s, got %g", limits.Max, cfg.GateTime)
+	}
+	// The counter counts trigger events, so it borrows the trigger.
+	prev, err := s.triggerConfig()
+	if err != nil {
+		return CounterReading{}, err
+	}
+	defer s.restoreTrigger(prev)
+	if err := dwfAnalogInCounterSet(h, cfg.GateTime); err != nil {
+		return CounterReading{}, err
+	}
+	defer dwfAnalogInCounterSet(h, 0)
+	if err := s.SetTrigger(TriggerConfig{
+		Enable:     true,
+		Source:     TrigSrcDetectorAnalogIn,
+		Channel:    cfg.Channel,
+		Type:       TriggerTypeEdge,
+		EdgeRising: true,
+		Level:      cfg.Level,
+		Hysteresis: cfg.Hysteresis,
+	}); err != nil {
+		return CounterReading{}, err
+	}
+	s.scanning = false
+	if err := dwfAnalogInConfigure(h, false, true); err != nil {
+		return CounterReading{}, err
+	}
+	defer dwfAnalogInConfigure(h, false, false)
+	start := -1
+	for {
+		if err := ctx.Err(); err != nil {
+			return CounterReading{}, fmt.Errorf("count aborted: %w", err)
+		}
+		if _, err := dwfAnalogInStatus(h, false); err != nil {
+			return CounterReading{}, err
+		}
+		count, freq, tick, err := dwfAnalogInCounterStatus(h)
+		if err != nil {
+			return CounterReading{}, err
+		}
+		// The tick advances at the end of each gate; wait for the first one
+		// that ends after the counter started.
+		if start < 0 {
+			start = tick
+		} else if tick != start {
+			return CounterReading{Count: count, Frequency: freq, GateTime: cfg.GateTime}, nil
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// restoreTrigger reapplies the trigger settings that Count changes.
+func (s *scopeImpl) restoreTrigger(t TriggerReadback) {
+	h := s.dev.handle
+	s.triggered = t.Source != TrigSrcNone
+	_ = dwfAnalogInTriggerSourceSet(h, cTrigSrc(t.Source))
+	_ = dwfAnalogInTriggerChannelSet(h, cInt(t.Channel-1))
+	_ = dwfAnalogInTriggerTypeSet(h, cTrigType(t.Type))
+	_ = dwfAnalogInTriggerConditionSet(h, cTrigSlope(t.Condition))
+	_ = dwfAnalogInTriggerLevelSet(h, t.Level)
+	_ = dwfAnalogInTriggerHysteresisSet(h, t.Hysteresis)
+	_ = dwfAnalogInTriggerPositionSet(h, t.Position)
+	_ = dwfAnalogInTriggerHoldOffSet(h, t.HoldOff)
+	_ = dwfAnalogInTriggerAutoTimeoutSet(h, t.AutoTimeout)
+	_ = dwfAnalogInTriggerLengthSet(h, t.Length)
+	_ = dwfAnalogInTriggerLengthConditionSet(h, cTrigLen(t.LengthCondition))
+}
+
+func (s *scopeImpl) Stream(ctx context.Context, channel int, duration float64, onChunk func(chunk []float64) error) (StreamStats, error) {
+	return s.stream(ctx, channel, duration, onChunk)
+}
+
+// stream runs a record mode acquisition of duration seconds, passing the
+// samples to onChunk as they arrive, and restores the opened acquisition
+// mode afterwards.
+func (s *scopeImpl) stream(ctx context.Context, channel int, duration float64, onChunk func(chunk []float64) error) (StreamStats, error) {
+	h := s.dev.handle
+	var stats StreamStats
+	s.scanning = false
+	if err := dwfAnalogInAcquisitionModeSet(h, cAcqmodeRecord); err != nil {
+		return stats, err
+	}
+	defer dwfAnalogInAcquisitionModeSet(h, cAcqMode(s.mode))
+
+	if err := dwfAnalogInRecordLengthSet(h, duration); err != nil {
+		return stats, err
+	}
+	if err := dwfAnalogInConfigure(h, false, true); err != nil {
+		return stats, err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			_ = dwfAnalogInConfigure(h, false, false)
+			return stats, fmt.Errorf("acquisition aborted: %w", err)
+		}
+		status, err := dwfAnalogInStatus(h, true)
+		if err != nil {
+			return stats, err
+		}
+		// nothing to read until the acquisition has actually started
+		if stats.Samples == 0 && (status == cDwfStateConfig || status == cDwfStatePrefill || status == cDwfStateArmed) {
+			continue
+		}
+		available, lost, corrupt, err := dwfAnalogInStatusRecord(h)
+		if err != nil {
+			return stats, err
+		}
+		stats.Lost += lost
+		stats.Corrupt += corrupt
+		if available > 0 {
+			chunk, err := dwfAnalogInStatusData(h, cInt(channel-1), available)
+			if err != nil {
+				return stats, err
+			}
+			stats.Samples += available
+			if err := onChunk(chunk); err != nil {
+				_ = dwfAnalogInConfigure(h, false, false)
+				return stats, err
+			}
+		}
+		if status == cDwfStateDone {
+			return stats, nil
+		}
+	}
+}
+
+func (s *scopeImpl) Close() error {
+	s.mode, s.scanning = AcqModeSingle, false
+	s.sampleRate, s.bufferSize, s.recordLength = 0, 0, 0
+	return dwfAnalogInReset(s.dev.handle)
+}
+
+// ==================== Wavegen ====================
+
+type wavegenImpl struct {
+	dev *Device
+}
+
+func (w *wavegenImpl) Generate(cfg WavegenConfig) (WavegenSettings, error) {
+	h := w.dev.handle
+	ch := cInt(cfg.Channel - 1)
+	node := cAnalogOutNodeCarrier
+
+	if err := dwfAnalogOutNodeEnableSet(h, ch, node, true); err != nil {
+		return WavegenSettings{}, err
+	}
+	if err := dwfAnalogOutNodeFunctionSet(h, ch, node, cFunc(cfg.Function)); err != nil {
+		return WavegenSettings{}, err
+	}
+	if cfg.Function == FuncCustom && len(cfg.CustomData) > 0 {
+		if err := dwfAnalogOutNodeDataSet(h, ch, node, cfg.CustomData); err != nil {
+			return WavegenSettings{}, err
+		}
+	}
+	if err := dwfAnalogOutNodeFrequencySet(h, ch, node, cfg.Frequency); err != nil {
+		return WavegenSettings{}, err
+	}
+	if err := dwfAnalogOutNodeAmplitudeSet(h, ch, node, cfg.Amplitude); err != nil {
+		return WavegenSettings{}, err
+	}
+	if err := dwfAnalogOutNodeOffsetSet(h, ch, node, cfg.Offset); err != nil {
+		return WavegenSettings{}, err
+	}
+	if err := dwfAnalogOutNodeSymmetrySet(h, ch, node, cfg.Symmetry); err != nil {
+		return WavegenSettings{}, err
+	}
+	// Devices without phase control fail the call; that only matters when a
+	// phase was asked for.
+	if err := dwfAnalogOutNodePhaseSet(h, ch, node, cfg.Phase); err != nil && cfg.Phase != 0 {
+		return WavegenSettings{}, err
+	}
+	if err := setModulation(h, cfg.Channel, cAnalogOutNodeAM, cfg.AM); err != nil {
+		return WavegenSettings{}, fmt.Errorf("AM: %w", err)
+	}
+	if err := setModulation(h, cfg.Channel, cAnalogOutNodeFM, cfg.FM); err != nil {
+		return WavegenSettings{}, fmt.Errorf("FM: %w", err)
+	}
+	if err := dwfAnalogOutRunSet(h, ch, cfg.RunTime); err != nil {
+		return WavegenSettings{}, err
+	}
+	if err := dwfAnalogOutWaitSet(h, ch, cfg.Wait); err != nil {
+		return WavegenSettings{}, err
+	}
+	if err := dwfAnalogOutRepeatSet(h, ch, cfg.Repeat); err != nil {
+		return WavegenSettings{}, err
+	}
+	if err := dwfAnalogOutTriggerSourceSet(h, ch, cTrigSrc(cfg.TriggerSource)); err != nil {
+		return WavegenSettings{}, err
+	}
+	if cfg.TriggerSource != TrigSrcNone {
+		if err := dwfAnalogOutTriggerSlopeSet(h, ch, cTrigSlope(cfg.TriggerSlope)); err != nil {
+			return WavegenSettings{}, err
+		}
+	}
+	if err := dwfAnalogOutConfigure(h, ch, true); err != nil {
+		return WavegenSettings{}, err
+	}
+
+	// Read back what the device applied. Limits the device does not report
+	// are left zero.
+	var st WavegenSettings
+	var err error
+	if st.Frequency, err = dwfAnalogOutNodeFrequencyGet(h, ch, node); err != nil {
+		return WavegenSettings{}, fmt.Errorf("reading back the frequency: %w", err)
+	}
+	if st.Amplitude, err = dwfAnalogOutNodeAmplitudeGet(h, ch, node); err != nil {
+		return WavegenSettings{}, fmt.Errorf("reading back the amplitude: %w", err)
+	}
+	if st.Offset, err = dwfAnalogOutNodeOffsetGet(h, ch, node); err != nil {
+		return WavegenSettings{}, fmt.Errorf("reading back the offset: %w", err)
+	}
+	if st.Symmetry, err = dwfAnalogOutNodeSymmetryGet(h, ch, node); err != nil {
+		return WavegenSettings{}, fmt.Errorf("reading back the symmetry: %w", err)
+	}
+	// As when setting it, only a requested phase needs phase control.
+	if st.Phase, err = dwfAnalogOutNodePhaseGet(h, ch, node); err != nil && cfg.Phase != 0 {
+		return WavegenSettings{}, fmt.Errorf("reading back the phase: %w", err)
+	}
+	st.FrequencyLimits.Min, st.FrequencyLimits.Max, _ = dwfAnalogOutNodeFrequencyInfo(h, ch, node)
+	st.AmplitudeLimits.Min, st.AmplitudeLimits.Max, _ = dwfAnalogOutNodeAmplitudeInfo(h, ch, node)
+	st.OffsetLimits.Min, st.OffsetLimits.Max, _ = dwfAnalogOutNodeOffsetInfo(h, ch, node)
+	st.SymmetryLimits.Min, st.SymmetryLimits.Max, _ = dwfAnalogOutNodeSymmetryInfo(h, ch, node)
+	st.PhaseLimits.Min, st.PhaseLimits.Max, _ = dwfAnalogOutNodePhaseInfo(h, ch, node)
+	st.AM = appliedModulation(h, cfg.Channel, cAnalogOutNodeAM, cfg.AM)
+	st.FM = appliedModulation(h, cfg.Channel, cAnalogOutNodeFM, cfg.FM)
+	return st, nil
+}
+
+// setModulation configures a modulation node of a channel, or disables it
+// when m is nil. The node's amplitude is the depth in percent.
+func setModulation(h DevHandle, channel int, node analogOutNode, m *WavegenModulation) error {
+	ch := cInt(channel - 1)
+	if m == nil {
+		// devices without the node have nothing to disable
+		_ = dwfAnalogOutNodeEnableSet(h, ch, node, false)
+		return nil
+	}
+	if err := dwfAnalogOutNodeEnableSet(h, ch, node, true); err != nil {
+		return err
+	}
+	if err := dwfAnalogOutNodeFunctionSet(h, ch, node, cFunc(m.Function)); err != nil {
+		return err
+	}
+	if err := dwfAnalogOutNodeFrequencySet(h, ch, node, m.Frequency); err != nil {
+		return err
+	}
+	return dwfAnalogOutNodeAmplitudeSet(h, ch, node, m.Depth)
+}
+
+// appliedModulation reads back the modulation the device applied, keeping
+// the requested values where a read fails.
+func appliedModulation(h DevHandle, channel int, node analogOutNode, m *WavegenModulation) *WavegenModulation {
+	if m == nil {
+		return nil
+	}
+	ch := cInt(channel - 1)
+	applied := *m
+	if v, err := dwfAnalogOutNodeFrequencyGet(h, ch, node); err == nil {
+		applied.Frequency = v
+	}
+	if v, err := dwfAnalogOutNodeAmplitudeGet(h, ch, node); err == nil {
+		applied.Depth = v
+	}
+	return &applied
+}
+
+func (w *wavegenImpl) Play(ctx context.Context, cfg PlayConfig) (StreamStats, error) {
+	h := w.dev.handle
+	ch := cInt(cfg.Channel - 1)
+	node := cAnalogOutNodeCarrier
+	var stats StreamStats
+	if len(cfg.Data) == 0 || cfg.SampleRate <= 0 {
+		return stats, fmt.Errorf("play needs samples and a positive sample rate")
+	}
+	_, bufferSize, err := dwfAnalogOutNodeDataInfo(h, ch, node)
+	if err != nil {
+		return stats, err
+	}
+
+	if err := dwfAnalogOutNodeEnableSet(h, ch, node, true); err != nil {
+		return stats, err
+	}
+	if err := dwfAnalogOutNodeFunctionSet(h, ch, node, cFunc(FuncPlay)); err != nil {
+		return stats, err
+	}
+	// in play mode the node frequency is the sample rate
+	if err := dwfAnalogOutNodeFrequencySet(h, ch, node, cfg.SampleRate); err != nil {
+		return stats, err
+	}
+	if err := dwfAnalogOutNodeAmplitudeSet(h, ch, node, cfg.Amplitude); err != nil {
+		return stats, err
+	}
+	if err := dwfAnalogOutNodeOffsetSet(h, ch, node, cfg.Offset); err != nil {
+		return stats, err
+	}
+	if err := dwfAnalogOutRunSet(h, ch, float64(len(cfg.Data))/cfg.SampleRate); err != nil {
+		return stats, err
+	}
+	if err := dwfAnalogOutRepeatSet(h, ch, 1); err != nil {
+		return stats, err
+	}
+	// prefill the device buffer, then top it up while it plays
+	sent := min(len(cfg.Data), bufferSize)
+	if err := dwfAnalogOutNodeDataSet(h, ch, node, cfg.Data[:sent]); err != nil {
+		return stats, err
+	}
+	if err := dwfAnalogOutConfigure(h, ch, true); err != nil {
+		return stats, err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			_ = dwfAnalogOutConfigure(h, ch, false)
+			stats.Samples = sent
+			return stats, fmt.Errorf("playback aborted: %w", err)
+		}
+		status, err := dwfAnalogOutStatus(h, ch)
+		if err != nil {
+			return stats, err
+		}
+		if sent < len(cfg.Data) {
+			free, lost, corrupt, err := dwfAnalogOutNodePlayStatus(h, ch, node)
+			if err != nil {
+				return stats, err
+			}
+			stats.Lost += lost
+			stats.Corrupt += corrupt
+			if free > 0 {
+				n := min(free, len(cfg.Data)-sent)
+				if err := dwfAnalogOutNodePlayData(h, ch, node, cfg.Data[sent:sent+n]); err != nil {
+					return stats, err
+				}
+				sent += n
+				continue
+			}
+		}
+		if status == cDwfStateDone {
+			stats.Samples = sent
+			return stats, nil
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (w *wavegenImpl) DataLimits(channel int) (Limits, error) {
+	lo, hi, err := dwfAnalogOutNodeDataInfo(w.dev.handle, cInt(channel-1), cAnalogOutNodeCarrier)
+	if err != nil {
+		return Limits{}, err
+	}
+	return Limits{Min: float64(lo), Max: float64(hi)}, nil
+}
+
+func (w *wavegenImpl) Enable(channel int) error {
+	return dwfAnalogOutConfigure(w.dev.handle, cInt(channel-1), true)
+}
+
+func (w *wavegenImpl) Disable(channel int) error {
+	return dwfAnalogOutConfigure(w.dev.handle, cInt(channel-1), false)
+}
+
+func (w *wavegenImpl) SetIdle(channel int, idle WavegenIdle) error {
+	h := w.dev.handle
+	ch := cInt(channel - 1)
+	// Devices that do not report the idle modes are assumed to support all.
+	if supported, err := dwfAnalogOutIdleInfo(h, ch); err == nil && supported != 0 && supported&(1<<uint(idle)) == 0 {
+		return fmt.Errorf("idle mode %d not supported on channel %d", idle, channel)
+	}
+	return dwfAnalogOutIdleSet(h, ch, cAnalogOutIdle(idle))
+}
+
+func (w *wavegenImpl) SetMaster(channel, master int) error {
+	return dwfAnalogOutMasterSet(w.dev.handle, cInt(channel-1), cInt(master-1))
+}
+
+func (w *wavegenImpl) Close(channel int) error {
+	return dwfAnalogOutReset(w.dev.handle, cInt(channel-1))
+}
+
+// ==================== Power Supply ====================
+
+type supplyImpl struct {
+	dev *Device
+
+	// mu guards stopRamp.
+	mu sync.Mutex
+	// stopRamp ends the soft-start ramp in progress and waits for it.
+	stopRamp func()
+}
+
+// Soft-start ramps step the voltage every rampStepInterval, in at most
+// maxRampSteps steps.
+const (
+	rampStepInterval = 10 * time.Millisecond
+	maxRampSteps     = 1000
+)
+
+// supplyTarget is the voltage a rail ramps to, and the voltage it starts
+// from.
+type supplyTarget struct {
+	labels   []string
+	from, to float64
+}
+
+// cancelRamp stops the soft-start ramp in progress, if any, leaving the
+// rails at the voltage they reached.
+func (s *supplyImpl) cancelRamp() {
+	s.mu.Lock()
+	stop := s.stopRamp
+	s.stopRamp = nil
+	s.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// startRamp steps the rails to their targets over d in the background.
+func (s *supplyImpl) startRamp(d time.Duration, targets []supplyTarget) {
+	steps := min(maxRampSteps, max(1, int(d/rampStepInterval)))
+	quit, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(d / time.Duration(steps))
+		defer ticker.Stop()
+		for i := 1; i <= steps; i++ {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+			s.dev.ioMu.Lock()
+			for _, t := range targets {
+				s.setNode(t.labels, "Voltage", t.from+(t.to-t.from)*float64(i)/float64(steps))
+			}
+			s.dev.ioMu.Unlock()
+		}
+	}()
+	s.mu.Lock()
+	s.stopRamp = func() {
+		close(quit)
+		<-done
+	}
+	s.mu.Unlock()
+}
+
+func (s *supplyImpl) findChannelNode(label, nodeName string) (int, int, bool) {
+	h := s.dev.handle
+	chCount, err := dwfAnalogIOChannelCount(h)
+	if err != nil {
+		return -1, -1, false
+	}
 	for ch := 0; ch < chCount; ch++ {
 		_, lbl, err := dwfAnalogIOChannelName(h, cInt(ch))
 		if err != nil || lbl != label {
 			continue
 		}
-		nodeCount, err := dwfAnalogIOChannelInfo(h, cInt(ch))
+		nodeCount, err := dwfAnalogIOChannelInfo(h, cInt(ch))
+		if err != nil {
+			continue
+		}
+		for n := 0; n < nodeCount; n++ {
+			name, _, err := dwfAnalogIOChannelNodeName(h, cInt(ch), cInt(n))
+			if err != nil {
+				continue
+			}
+			if name == nodeName {
+				return ch, n, true
+			}
+		}
+	}
+	return -1, -1, false
+}
+
+func (s *supplyImpl) setNode(labels []string, nodeName string, value float64) {
+	h := s.dev.handle
+	for _, label := range labels {
+		if ch, node, ok := s.findChannelNode(label, nodeName); ok {
+			_ = dwfAnalogIOChannelNodeSet(h, cInt(ch), cInt(node), value)
+			return
+		}
+	}
+}
+
+// railPowered reports whether the rail of the given labels is switched on,
+// with the master enable on, and the voltage it reads back. The caller
+// refreshes the status first.
+func (s *supplyImpl) railPowered(labels []string, master bool) (bool, float64) {
+	h := s.dev.handle
+	for _, label := range labels {
+		ch, enable, ok := s.findChannelNode(label, "Enable")
+		if !ok {
+			continue
+		}
+		on, _ := dwfAnalogIOChannelNodeGet(h, cInt(ch), cInt(enable))
+		var v float64
+		if _, node, ok := s.findChannelNode(label, "Voltage"); ok {
+			v, _ = dwfAnalogIOChannelNodeStatus(h, cInt(ch), cInt(node))
+		}
+		return master && on != 0, v
+	}
+	return false, 0
+}
+
+// supplyRails maps the AnalogIO labels of the supplies to the rails of
+// SuppliesConfig.
+var supplyRails = map[string]string{
+	"V+":   "positive",
+	"p25V": "positive",
+	"V-":   "negative",
+	"n25V": "negative",
+	"VDD":  "digital",
+	"p6V":  "digital",
+}
+
+func (s *supplyImpl) Rails() ([]SupplyRail, error) {
+	s.dev.ioMu.Lock()
+	defer s.dev.ioMu.Unlock()
+	h := s.dev.handle
+	chCount, err := dwfAnalogIOChannelCount(h)
+	if err != nil {
+		return nil, err
+	}
+	var rails []SupplyRail
+	for ch := 0; ch < chCount; ch++ {
+		name, label, err := dwfAnalogIOChannelName(h, cInt(ch))
+		if err != nil || supplyRails[label] == "" {
+			continue
+		}
+		rail := SupplyRail{Rail: supplyRails[label], Name: name, Label: label}
+		nodeCount, err := dwfAnalogIOChannelInfo(h, cInt(ch))
+		if err != nil {
+			return nil, err
+		}
+		for n := 0; n < nodeCount; n++ {
+			node, units, err := dwfAnalogIOChannelNodeName(h, cInt(ch), cInt(n))
+			if err != nil {
+				return nil, err
+			}
+			lo, hi, steps, err := dwfAnalogIOChannelNodeSetInfo(h, cInt(ch), cInt(n))
+			if err != nil || steps == 0 {
+				// Read-only nodes, such as measured values, cannot be set.
+				continue
+			}
+			rail.Nodes = append(rail.Nodes, SupplyNode{Name: node, Units: units, Min: lo, Max: hi, Steps: steps})
+		}
+		rails = append(rails, rail)
+	}
+	return rails, nil
+}
+
+// isProtectionFlag reports whether a node name reads as a protection flag,
+// such as "Limitation" or "Overtemp".
+func isProtectionFlag(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"limit", "fault", "protect", "shutdown", "overtemp", "overcurrent"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *supplyImpl) Status() (SupplyStatus, error) {
+	s.dev.ioMu.Lock()
+	defer s.dev.ioMu.Unlock()
+	h := s.dev.handle
+	if err := dwfAnalogIOStatus(h); err != nil {
+		return SupplyStatus{}, err
+	}
+	// A rail is only on while the master enable is.
+	master, err := dwfAnalogIOEnableGet(h)
+	if err != nil {
+		return SupplyStatus{}, err
+	}
+	chCount, err := dwfAnalogIOChannelCount(h)
+	if err != nil {
+		return SupplyStatus{}, err
+	}
+	st := SupplyStatus{Faults: []string{}}
+	for ch := 0; ch < chCount; ch++ {
+		_, label, err := dwfAnalogIOChannelName(h, cInt(ch))
+		if err != nil {
+			continue
+		}
+		nodeCount, err := dwfAnalogIOChannelInfo(h, cInt(ch))
+		if err != nil {
+			continue
+		}
+		rail := SupplyRailStatus{Rail: supplyRails[label], Label: label, Readings: map[string]float64{}}
+		for n := 0; n < nodeCount; n++ {
+			name, _, err := dwfAnalogIOChannelNodeName(h, cInt(ch), cInt(n))
+			if err != nil {
+				continue
+			}
+			value, err := dwfAnalogIOChannelNodeStatus(h, cInt(ch), cInt(n))
+			if err != nil {
+				continue
+			}
+			rail.Readings[name] = value
+			switch name {
+			case "Enable":
+				set, _ := dwfAnalogIOChannelNodeGet(h, cInt(ch), cInt(n))
+				rail.Enabled = master && set != 0
+			case "Voltage":
+				rail.SetVoltage, _ = dwfAnalogIOChannelNodeGet(h, cInt(ch), cInt(n))
+				rail.Voltage = value
+			}
+			// Settable nodes such as a current limit are not flags.
+			if _, _, steps, err := dwfAnalogIOChannelNodeSetInfo(h, cInt(ch), cInt(n)); err == nil && steps > 0 {
+				continue
+			}
+			if !isProtectionFlag(name) || value == 0 {
+				continue
+			}
+			// No current flows to limit while the master enable is off.
+			limit := strings.Contains(strings.ToLower(name), "limit")
+			if limit && !master {
+				continue
+			}
+			st.Faults = append(st.Faults, label+" "+name)
+			rail.Limiting = rail.Limiting || limit
+		}
+		if rail.Rail == "" {
+			continue
+		}
+		if rail.Enabled && rail.SetVoltage != 0 && math.Abs(rail.Voltage) < 0.9*math.Abs(rail.SetVoltage) {
+			rail.Limiting = true
+		}
+		st.Rails = append(st.Rails, rail)
+	}
+	return st, nil
+}
+
+// railSwitch is the setting Switch applies to one rail.
+type railSwitch struct {
+	labels  []string
+	on      bool
+	voltage float64
+	current float64
+}
+
+// railState is the state of a rail before Switch writes to it.
+type railState struct {
+	powered bool
+	voltage float64
+}
+
+// rampTargets returns the soft-start ramps for switches, given the state of
+// each rail before switching, and the voltage to set each rail to: a rail
+// switched on that was not powered starts at the voltage it read back and
+// ramps up from there, any other rail is set directly.
+func rampTargets(switches []railSwitch, prior []railState) ([]float64, []supplyTarget) {
+	start := make([]float64, len(switches))
+	var ramp []supplyTarget
+	for i, sw := range switches {
+		start[i] = sw.voltage
+		if sw.on && !prior[i].powered {
+			start[i] = prior[i].voltage
+			ramp = append(ramp, supplyTarget{labels: sw.labels, from: prior[i].voltage, to: sw.voltage})
+		}
+	}
+	return start, ramp
+}
+
+func (s *supplyImpl) Switch(cfg SuppliesConfig) error {
+	if err := s.validate(cfg); err != nil {
+		return err
+	}
+	s.cancelRamp()
+	s.dev.ioMu.Lock()
+	defer s.dev.ioMu.Unlock()
+	switches := []railSwitch{
+		{[]string{"V+", "p25V"}, cfg.PositiveState, cfg.PositiveVoltage, cfg.PositiveCurrent},
+		{[]string{"V-", "n25V"}, cfg.NegativeState, cfg.NegativeVoltage, cfg.NegativeCurrent},
+		{[]string{"VDD", "p6V"}, cfg.State, cfg.Voltage, cfg.Current},
+	}
+
+	// The rails to ramp are decided from their state before anything is
+	// written: once the Enable node is set, a rail switched on from off
+	// reads as powered.
+	start := make([]float64, len(switches))
+	for i, sw := range switches {
+		start[i] = sw.voltage
+	}
+	var ramp []supplyTarget
+	if cfg.RampTime > 0 && cfg.MasterState {
+		master, err := dwfAnalogIOEnableGet(s.dev.handle)
+		if err != nil {
+			return err
+		}
+		if err := dwfAnalogIOStatus(s.dev.handle); err != nil {
+			return err
+		}
+		prior := make([]railState, len(switches))
+		for i, sw := range switches {
+			prior[i].powered, prior[i].voltage = s.railPowered(sw.labels, master)
+		}
+		start, ramp = rampTargets(switches, prior)
+	}
+
+	for i, sw := range switches {
+		enableVal := 0.0
+		if sw.on {
+			enableVal = 1.0
+		}
+		s.setNode(sw.labels, "Enable", enableVal)
+		s.setNode(sw.labels, "Voltage", start[i])
+		s.setNode(sw.labels, "Current", sw.current)
+	}
+
+	// master enable
+	if err := dwfAnalogIOEnableSet(s.dev.handle, cfg.MasterState); err != nil {
+		return err
+	}
+	if len(ramp) > 0 {
+		s.startRamp(time.Duration(cfg.RampTime*float64(time.Second)), ramp)
+	}
+	return nil
+}
+
+func (s *supplyImpl) Config() (SuppliesReadback, error) {
+	s.dev.ioMu.Lock()
+	defer s.dev.ioMu.Unlock()
+	h := s.dev.handle
+	var rb SuppliesReadback
+	var err error
+	if rb.MasterState, err = dwfAnalogIOEnableGet(h); err != nil {
+		return SuppliesReadback{}, err
+	}
+	chCount, err := dwfAnalogIOChannelCount(h)
+	if err != nil {
+		return SuppliesReadback{}, err
+	}
+	for ch := 0; ch < chCount; ch++ {
+		_, label, err := dwfAnalogIOChannelName(h, cInt(ch))
+		if err != nil || supplyRails[label] == "" {
+			continue
+		}
+		rail := SupplyRailReadback{Rail: supplyRails[label], Label: label}
+		nodeCount, err := dwfAnalogIOChannelInfo(h, cInt(ch))
+		if err != nil {
+			return SuppliesReadback{}, err
+		}
+		for n := 0; n < nodeCount; n++ {
+			name, _, err := dwfAnalogIOChannelNodeName(h, cInt(ch), cInt(n))
+			if err != nil {
+				return SuppliesReadback{}, err
+			}
+			if name != "Enable" && name != "Voltage" && name != "Current" {
+				continue
+			}
+			v, err := dwfAnalogIOChannelNodeGet(h, cInt(ch), cInt(n))
+			if err != nil {
+				return SuppliesReadback{}, err
+			}
+			switch name {
+			case "Enable":
+				rail.Enabled = v != 0
+			case "Voltage":
+				rail.Voltage = v
+			case "Current":
+				// Only a settable node is a limit; others report a reading.
+				if _, ok := s.dev.nodeRange(ch, n); ok {
+					rail.Current = &v
+				}
+			}
+		}
+		rb.Rails = append(rb.Rails, rail)
+	}
+	return rb, nil
+}
+
+func (s *supplyImpl) Close() error {
+	s.cancelRamp()
+	s.dev.ioMu.Lock()
+	defer s.dev.ioMu.Unlock()
+	return dwfAnalogIOReset(s.dev.handle)
+}
+
+// ==================== AnalogIO ====================
+
+type analogIOImpl struct {
+	dev *Device
+}
+
+func (a *analogIOImpl) Channels() ([]AnalogIOChannel, error) {
+	a.dev.ioMu.Lock()
+	defer a.dev.ioMu.Unlock()
+	h := a.dev.handle
+	chCount, err := dwfAnalogIOChannelCount(h)
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]AnalogIOChannel, 0, chCount)
+	for ch := 0; ch < chCount; ch++ {
+		name, label, err := dwfAnalogIOChannelName(h, cInt(ch))
+		if err != nil {
+			return nil, err
+		}
+		nodeCount, err := dwfAnalogIOChannelInfo(h, cInt(ch))
+		if err != nil {
+			return nil, err
+		}
+		c := AnalogIOChannel{Index: ch, Name: name, Label: label, Nodes: make([]AnalogIONode, 0, nodeCount)}
+		for n := 0; n < nodeCount; n++ {
+			nodeName, units, err := dwfAnalogIOChannelNodeName(h, cInt(ch), cInt(n))
+			if err != nil {
+				return nil, err
+			}
+			node := AnalogIONode{Index: n, Name: nodeName, Units: units}
+			if lo, hi, steps, err := dwfAnalogIOChannelNodeSetInfo(h, cInt(ch), cInt(n)); err == nil && steps > 0 {
+				node.Set = &AnalogIORange{Min: lo, Max: hi, Steps: steps}
+			}
+			if lo, hi, steps, err := dwfAnalogIOChannelNodeStatusInfo(h, cInt(ch), cInt(n)); err == nil && steps > 0 {
+				node.Status = &AnalogIORange{Min: lo, Max: hi, Steps: steps}
+			}
+			c.Nodes = append(c.Nodes, node)
+		}
+		channels = append(channels, c)
+	}
+	return channels, nil
+}
+
+func (a *analogIOImpl) Read(channel, node int) (AnalogIOReading, error) {
+	a.dev.ioMu.Lock()
+	defer a.dev.ioMu.Unlock()
+	h := a.dev.handle
+	if err := dwfAnalogIOStatus(h); err != nil {
+		return AnalogIOReading{}, err
+	}
+	var r AnalogIOReading
+	if v, err := dwfAnalogIOChannelNodeGet(h, cInt(channel), cInt(node)); err == nil {
+		r.Set = &v
+	}
+	if v, err := dwfAnalogIOChannelNodeStatus(h, cInt(channel), cInt(node)); err == nil {
+		r.Status = &v
+	}
+	if r.Set == nil && r.Status == nil {
+		return AnalogIOReading{}, fmt.Errorf("channel %d node %d has no value to read", channel, node)
+	}
+	return r, nil
+}
+
+func (a *analogIOImpl) ReadStatus(nodes []AnalogIONodeRef) ([]float64, error) {
+	a.dev.ioMu.Lock()
+	defer a.dev.ioMu.Unlock()
+	h := a.dev.handle
+	if err := dwfAnalogIOStatus(h); err != nil {
+		return nil, err
+	}
+	values := make([]float64, len(nodes))
+	for i, n := range nodes {
+		v, err := dwfAnalogIOChannelNodeStatus(h, cInt(n.Channel), cInt(n.Node))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (a *analogIOImpl) Set(channel, node int, value float64) (float64, error) {
+	a.dev.ioMu.Lock()
+	defer a.dev.ioMu.Unlock()
+	h := a.dev.handle
+	if err := dwfAnalogIOChannelNodeSet(h, cInt(channel), cInt(node), value); err != nil {
+		return 0, err
+	}
+	return dwfAnalogIOChannelNodeGet(h, cInt(channel), cInt(node))
+}
+
+// ==================== DMM ====================
+
+type dmmImpl struct {
+	dev     *Device
+	channel int
+	nodes   struct {
+		enable int
+		mode   int
+		rangN  int
+		meas   int
+		input  int
+		// aux are the other nodes that report a status.
+		aux []dmmAuxNode
+	}
+}
+
+// dmmAuxNode is a DMM status node outside the ones Measure configures.
+type dmmAuxNode struct {
+	index       int
+	name, units string
+}
+
+func (m *dmmImpl) Open() error {
+	m.dev.ioMu.Lock()
+	defer m.dev.ioMu.Unlock()
+	h := m.dev.handle
+	m.channel = -1
+	m.nodes.enable = -1
+	m.nodes.mode = -1
+	m.nodes.rangN = -1
+	m.nodes.meas = -1
+	m.nodes.input = -1
+	m.nodes.aux = nil
+
+	chCount, err := dwfAnalogIOChannelCount(h)
+	if err != nil {
+		return err
+	}
+	for ch := 0; ch < chCount; ch++ {
+		_, label, err := dwfAnalogIOChannelName(h, cInt(ch))
+		if err != nil || label != "DMM" {
+			continue
+		}
+		m.channel = ch
+		break
+	}
+	if m.channel < 0 {
+		return fmt.Errorf("DMM not available on this device")
+	}
+
+	nodeCount, err := dwfAnalogIOChannelInfo(h, cInt(m.channel))
+	if err != nil {
+		return err
+	}
+	for n := 0; n < nodeCount; n++ {
+		name, units, err := dwfAnalogIOChannelNodeName(h, cInt(m.channel), cInt(n))
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "Enable":
+			m.nodes.enable = n
+		case "Mode":
+			m.nodes.mode = n
+		case "Range":
+			m.nodes.rangN = n
+		case "Meas":
+			m.nodes.meas = n
+		case "Input":
+			m.nodes.input = n
+		default:
+			if _, _, steps, err := dwfAnalogIOChannelNodeStatusInfo(h, cInt(m.channel), cInt(n)); err == nil && steps > 0 {
+				m.nodes.aux = append(m.nodes.aux, dmmAuxNode{index: n, name: name, units: units})
+			}
+		}
+	}
+
+	if m.nodes.enable >= 0 {
+		return dwfAnalogIOChannelNodeSet(h, cInt(m.channel), cInt(m.nodes.enable), 1.0)
+	}
+	return nil
+}
+
+func (m *dmmImpl) Measure(mode DMMMode, range_ float64, highImpedance bool) (DMMReading, error) {
+	m.dev.ioMu.Lock()
+	defer m.dev.ioMu.Unlock()
+	h := m.dev.handle
+	if err := m.validateMode(mode); err != nil {
+		return DMMReading{}, err
+	}
+	// The input impedance only applies to DC voltage; the current modes
+	// pick their shunt from the mode alone.
+	if m.nodes.input >= 0 && mode == DMMModeDCVoltage {
+		inputVal := 0.0
+		if highImpedance {
+			inputVal = 1.0
+		}
+		if err := dwfAnalogIOChannelNodeSet(h, cInt(m.channel), cInt(m.nodes.input), inputVal); err != nil {
+			return DMMReading{}, err
+		}
+	}
+	if m.nodes.mode >= 0 {
+		if err := dwfAnalogIOChannelNodeSet(h, cInt(m.channel), cInt(m.nodes.mode), float64(mode)); err != nil {
+			return DMMReading{}, err
+		}
+	}
+	if m.nodes.rangN >= 0 {
+		// The ranges depend on the mode, so they are checked once it is set.
+		if err := m.validateRange(mode, range_); err != nil {
+			return DMMReading{}, err
+		}
+		if err := dwfAnalogIOChannelNodeSet(h, cInt(m.channel), cInt(m.nodes.rangN), range_); err != nil {
+			return DMMReading{}, err
+		}
+	}
+	if err := dwfAnalogIOStatus(h); err != nil {
+		return DMMReading{}, err
+	}
+	if m.nodes.meas < 0 {
+		return DMMReading{}, fmt.Errorf("DMM measurement node not found")
+	}
+	value, err := dwfAnalogIOChannelNodeStatus(h, cInt(m.channel), cInt(m.nodes.meas))
+	if err != nil {
+		return DMMReading{}, err
+	}
+	r := DMMReading{Value: value, Mode: mode, Range: range_}
+	if _, units, err := dwfAnalogIOChannelNodeName(h, cInt(m.channel), cInt(m.nodes.meas)); err == nil {
+		r.Units = units
+	}
+	if m.nodes.mode >= 0 {
+		if v, err := dwfAnalogIOChannelNodeGet(h, cInt(m.channel), cInt(m.nodes.mode)); err == nil {
+			r.Mode = DMMMode(v)
+		}
+	}
+	// The status of the range node is the range auto-ranging settled on;
+	// devices without one report the configured range.
+	if m.nodes.rangN >= 0 {
+		if v, err := dwfAnalogIOChannelNodeStatus(h, cInt(m.channel), cInt(m.nodes.rangN)); err == nil {
+			r.Range = v
+		} else if v, err := dwfAnalogIOChannelNodeGet(h, cInt(m.channel), cInt(m.nodes.rangN)); err == nil {
+			r.Range = v
+		}
+	}
+	for _, n := range m.nodes.aux {
+		v, err := dwfAnalogIOChannelNodeStatus(h, cInt(m.channel), cInt(n.index))
+		if err != nil {
+			continue
+		}
+		r.Aux = append(r.Aux, DMMAuxReading{Name: n.name, Value: v, Units: n.units})
+	}
+	// Some devices report the thermocouple voltage on the measurement node
+	// and the compensated temperature on a node of its own.
+	if r.Mode == DMMModeTemperature && !isTemperatureUnit(r.Units) {
+		for _, a := range r.Aux {
+			if isTemperatureUnit(a.Units) {
+				r.Aux = append(r.Aux, DMMAuxReading{Name: "Meas", Value: r.Value, Units: r.Units})
+				r.Value, r.Units = a.Value, a.Units
+				break
+			}
+		}
+	}
+	return r, nil
+}
+
+// isTemperatureUnit reports whether units are degrees.
+func isTemperatureUnit(units string) bool {
+	switch strings.TrimSpace(units) {
+	case "°C", "degC", "°F", "K":
+		return true
+	}
+	return false
+}
+
+func (m *dmmImpl) Close() error {
+	m.dev.ioMu.Lock()
+	defer m.dev.ioMu.Unlock()
+	h := m.dev.handle
+	if m.nodes.enable >= 0 {
+		_ = dwfAnalogIOChannelNodeSet(h, cInt(m.channel), cInt(m.nodes.enable), 0)
+	}
+	return dwfAnalogIOReset(h)
+}
+
+// ==================== Logic Analyzer ====================
+
+type logicImpl struct {
+	dev        *Device
+	bufferSize int
+	sampleRate float64
+	// channels is the DIO line of each sample bit, nil for the identity.
+	channels []int
+	// sampleBits is the width of the raw samples the device returns.
+	sampleBits int
+	// dioFirst is set when the raw samples hold the DIO lines before the
+	// input-only lines, which puts DIO 32-39 of the Digital Discovery in
+	// reach of a 32-bit sample.
+	dioFirst bool
+	// sync is set while a logic channel clocks the samples.
+	sync bool
+	// triggered is set while the trigger is enabled, with prefill samples
+	// recorded ahead of the trigger event.
+	triggered    bool
+	prefill      int
+	triggerIndex int
+	acquiredAt   time.Time
+	// trigger is the last trigger configuration, which Count restores.
+	trigger LogicTriggerConfig
+}
+
+// logicSyncDivider is the divider that makes the trigger detector clock the
+// samples instead of the internal clock.
+const logicSyncDivider = math.MaxUint32
+
+// logicSampleBits is the sample width the logic analyzer is opened with
+// unless the mapped lines need another.
+const logicSampleBits = 16
+
+// logicSampleFormats are the raw sample widths the SDK supports.
+var logicSampleFormats = []int{8, 16, 32}
+
+// digitalDiscoveryDIO is the number of DIO lines of the Digital Discovery,
+// which follow its input-only lines from FirstDIO.
+const digitalDiscoveryDIO = 16
+
+func (l *logicImpl) Open(cfg LogicConfig) error {
+	h := l.dev.handle
+	maxLine := 31
+	if first := l.dev.firstDIO(); first > 0 {
+		maxLine = first + digitalDiscoveryDIO - 1
+	}
+	if len(cfg.Channels) > 32 {
+		return fmt.Errorf("at most 32 channels can be mapped, got %d", len(cfg.Channels))
+	}
+	seen := map[int]bool{}
+	for _, line := range cfg.Channels {
+		if line < 0 || line > maxLine {
+			return fmt.Errorf("DIO line %d out of range 0-%d", line, maxLine)
+		}
+		if seen[line] {
+			return fmt.Errorf("DIO line %d is mapped twice", line)
+		}
+		seen[line] = true
+	}
+	if cfg.SampleBits != 0 && !slices.Contains(logicSampleFormats, cfg.SampleBits) {
+		return fmt.Errorf("sample format must be 8, 16 or 32 bits, got %d", cfg.SampleBits)
+	}
+	if cfg.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative, got %g", cfg.Threshold)
+	}
+	if cfg.Threshold > 0 {
+		if err := l.setThreshold(cfg.Threshold); err != nil {
+			return err
+		}
+	}
+	l.channels = slices.Clone(cfg.Channels)
+	l.dioFirst = l.dev.firstDIO() > 0 && slices.ContainsFunc(cfg.Channels, func(line int) bool { return line >= 32 })
+	bits, err := l.sampleFormat(cfg.SampleBits)
+	if err != nil {
+		return err
+	}
+	l.sampleBits = bits
+
+	maxBuf, _ := dwfDigitalInBufferSizeInfo(h)
+	l.bufferSize = cfg.BufferSize
+	if l.bufferSize == 0 || l.bufferSize > maxBuf {
+		l.bufferSize = maxBuf
+	}
+
+	l.sync = cfg.Sync
+	if cfg.Sync {
+		if err := l.syncClock(cfg.ClockChannel, cfg.ClockEdge); err != nil {
+			return err
+		}
+		l.sampleRate = 0
+	} else if err := l.setDivider(cfg.SamplingFrequency); err != nil {
+		return err
+	}
+	if l.dev.firstDIO() > 0 {
+		if err := dwfDigitalInInputOrderSet(h, l.dioFirst); err != nil {
+			return err
+		}
+	}
+	if err := dwfDigitalInSampleFormatSet(h, l.sampleBits); err != nil {
+		return err
+	}
+	return dwfDigitalInBufferSizeSet(h, l.bufferSize)
+}
+
+// setDivider divides the internal clock down to the sampling frequency.
+func (l *logicImpl) setDivider(freq float64) error {
+	h := l.dev.handle
+	internalFreq, err := dwfDigitalInInternalClockInfo(h)
+	if err != nil {
+		return err
+	}
+	// Truncating the divider keeps the rate at or above the requested one.
+	divider := int(internalFreq / freq)
+	if divider < 1 {
+		divider = 1
+	}
+	if maxDivider, err := dwfDigitalInDividerInfo(h); err == nil && maxDivider > 0 {
+		divider = min(divider, maxDivider)
+	}
+	if err := dwfDigitalInDividerSet(h, divider); err != nil {
+		return err
+	}
+	l.sampleRate = internalFreq / float64(divider)
+	return nil
+}
+
+// syncClock takes samples on edges of a logic channel: with the sync
+// divider, the edges the trigger detector matches are the sample clock.
+func (l *logicImpl) syncClock(channel int, edge TriggerSlope) error {
+	h := l.dev.handle
+	bit, err := l.triggerBit(channel)
+	if err != nil {
+		return err
+	}
+	rise, fall, err := edgeMasks(bit, edge)
+	if err != nil {
+		return err
+	}
+	if err := dwfDigitalInDividerSet(h, logicSyncDivider); err != nil {
+		return err
+	}
+	return dwfDigitalInTriggerSet(h, 0, 0, cUint(rise), cUint(fall))
+}
+
+// sampleFormat picks the raw sample width: the requested one, or else the
+// narrowest that holds every mapped line. Without a mapping it stays at
+// logicSampleBits, so that channel N remains DIO line N.
+func (l *logicImpl) sampleFormat(requested int) (int, error) {
+	needed := 0
+	for bit := range l.channels {
+		needed = max(needed, l.rawBit(bit)+1)
+	}
+	bits := requested
+	if bits == 0 {
+		bits = logicSampleBits
+		if len(l.channels) > 0 {
+			bits = logicSampleFormats[len(logicSampleFormats)-1]
+			for _, f := range logicSampleFormats {
+				if f >= needed {
+					bits = f
+					break
+				}
+			}
+		}
+	}
+	if bits < needed {
+		return 0, fmt.Errorf("%d-bit samples cannot hold the mapped DIO lines, which need %d bits", bits, needed)
+	}
+	// A format wider than the narrowest one holding all of the device's
+	// lines only wastes buffer.
+	if l.dev.info != nil && l.dev.info.DigitalInChannels > 0 {
+		lines := l.dev.info.DigitalInChannels
+		for _, f := range logicSampleFormats {
+			if f >= lines {
+				if bits > f {
+					return 0, fmt.Errorf("the %s samples %d lines; use a sample format of at most %d bits", l.dev.model(), lines, f)
+				}
+				break
+			}
+		}
+	}
+	return bits, nil
+}
+
+// edgeMasks returns the rising and falling edge masks of the trigger
+// detector that match edge on the trigger bit.
+func edgeMasks(bit uint32, edge TriggerSlope) (rise, fall uint32, err error) {
+	switch edge {
+	case TriggerSlopeRise:
+		return bit, 0, nil
+	case TriggerSlopeFall:
+		return 0, bit, nil
+	case TriggerSlopeEither:
+		return bit, bit, nil
+	}
+	return 0, 0, fmt.Errorf("invalid edge %d", edge)
+}
+
+func (l *logicImpl) Config() (LogicReadback, error) {
+	h := l.dev.handle
+	rb := LogicReadback{Channels: slices.Clone(l.channels)}
+	var err error
+	if rb.InternalClock, err = dwfDigitalInInternalClockInfo(h); err != nil {
+		return LogicReadback{}, err
+	}
+	if rb.Divider, err = dwfDigitalInDividerGet(h); err != nil {
+		return LogicReadback{}, err
+	}
+	if rb.Divider == logicSyncDivider {
+		rb.Sync = true
+	} else if rb.Divider > 0 {
+		rb.SampleRate = rb.InternalClock / float64(rb.Divider)
+	}
+	if rb.BufferSize, err = dwfDigitalInBufferSizeGet(h); err != nil {
+		return LogicReadback{}, err
+	}
+	if rb.SampleBits, err = dwfDigitalInSampleFormatGet(h); err != nil {
+		return LogicReadback{}, err
+	}
+	return rb, nil
+}
+
+// setThreshold sets the digital input threshold through the analog I/O
+// node that devices such as the Analog Discovery Pro 3X50 expose for it.
+func (l *logicImpl) setThreshold(volts float64) error {
+	l.dev.ioMu.Lock()
+	defer l.dev.ioMu.Unlock()
+	ch, node, ok := l.dev.thresholdNode()
+	if !ok {
+		return fmt.Errorf("the digital input threshold is not adjustable on this device")
+	}
+	h := l.dev.handle
+	if err := dwfAnalogIOChannelNodeSet(h, cInt(ch), cInt(node), volts); err != nil {
+		return err
+	}
+	return dwfAnalogIOEnableSet(h, true)
+}
+
+// thresholdNode finds the analog I/O channel and node of the digital input
+// threshold.
+func (d *Device) thresholdNode() (int, int, bool) {
+	for _, label := range []string{"DIO", "Digital", "VIO"} {
+		if ch, node, ok := d.supply.findChannelNode(label, "Threshold"); ok {
+			return ch, node, true
+		}
+	}
+	return -1, -1, false
+}
+
+// line returns the DIO line sampled into bit.
+func (l *logicImpl) line(bit int) int {
+	if bit >= 0 && bit < len(l.channels) {
+		return l.channels[bit]
+	}
+	return bit
+}
+
+// rawBit returns the bit of a raw sample that holds logic channel bit.
+func (l *logicImpl) rawBit(bit int) int {
+	line := l.line(bit)
+	if !l.dioFirst {
+		return line
+	}
+	if first := l.dev.firstDIO(); line >= first {
+		return line - first
+	}
+	return line + digitalDiscoveryDIO
+}
+
+// unpack widens raw samples to words and reorders their bits according to
+// the channel mapping.
+func (l *logicImpl) unpack(raw []byte) []uint32 {
+	size := l.sampleBits / 8
+	samples := make([]uint32, len(raw)/size)
+	rawBits := make([]int, len(l.channels))
+	for bit := range rawBits {
+		rawBits[bit] = l.rawBit(bit)
+	}
+	for i := range samples {
+		var w uint32
+		switch size {
+		case 1:
+			w = uint32(raw[i])
+		case 2:
+			w = uint32(binary.LittleEndian.Uint16(raw[2*i:]))
+		default:
+			w = binary.LittleEndian.Uint32(raw[4*i:])
+		}
+		if len(rawBits) > 0 {
+			var v uint32
+			for bit, rb := range rawBits {
+				v |= (w >> rb & 1) << bit
+			}
+			w = v
+		}
+		samples[i] = w
+	}
+	return samples
+}
+
+func (l *logicImpl) SetTrigger(cfg LogicTriggerConfig) error {
+	h := l.dev.handle
+	if cfg.Enable && l.sync {
+		return fmt.Errorf("the trigger detector clocks the samples in sync mode; reopen the logic analyzer without a sample clock to trigger")
+	}
+	l.triggered, l.trigger = false, cfg
+	if cfg.Enable {
+		if err := dwfDigitalInTriggerSourceSet(h, cTrigsrcDetectorDigIn); err != nil {
+			return err
+		}
+	} else {
+		return dwfDigitalInTriggerSourceSet(h, cTrigsrcNone)
+	}
+
+	pos := cfg.Position
+	if pos < 0 {
+		pos = 0
+	}
+	if pos > l.bufferSize {
+		pos = l.bufferSize
+	}
+	if err := dwfDigitalInTriggerPositionSet(h, l.bufferSize-pos); err != nil {
+		return err
+	}
+	if err := dwfDigitalInTriggerPrefillSet(h, pos); err != nil {
+		return err
+	}
+	l.triggered, l.prefill = true, pos
+
+	if cfg.Mask != 0 {
+		low, high, err := l.patternLevels(cfg.Mask, cfg.Value)
+		if err != nil {
+			return err
+		}
+		var rise, fall uint32
+		if cfg.Qualified {
+			bit, err := l.triggerBit(cfg.Channel)
+			if err != nil {
+				return err
+			}
+			if cfg.RisingEdge {
+				rise = bit
+			} else {
+				fall = bit
+			}
+		}
+		if err := dwfDigitalInTriggerSet(h, cUint(low), cUint(high), cUint(rise), cUint(fall)); err != nil {
+			return err
+		}
+		if err := dwfDigitalInTriggerResetSet(h, 0, 0, 0, 0); err != nil {
+			return err
+		}
+	} else if err := l.edgeTrigger(cfg.Channel, cfg.RisingEdge); err != nil {
+		return err
+	}
+
+	if err := dwfDigitalInTriggerAutoTimeoutSet(h, cfg.Timeout); err != nil {
+		return err
+	}
+	if err := dwfDigitalInTriggerLengthSet(h, cfg.LengthMin, cfg.LengthMax, 0); err != nil {
+		return err
+	}
+	return dwfDigitalInTriggerCountSet(h, cInt(cfg.Count), 0)
+}
+
+// triggerBit returns the trigger mask bit of a logic channel. The trigger
+// masks are 32 bits wide and ordered by DIO line.
+func (l *logicImpl) triggerBit(channel int) (uint32, error) {
+	line := l.line(channel)
+	if line < 0 || line >= 32 {
+		return 0, fmt.Errorf("DIO line %d cannot trigger the logic analyzer", line)
+	}
+	return 1 << line, nil
+}
+
+// patternLevels converts a value and mask over logic channels into the
+// low and high level masks of the trigger.
+func (l *logicImpl) patternLevels(mask, value uint32) (low, high uint32, err error) {
+	if value&^mask != 0 {
+		return 0, 0, fmt.Errorf("trigger value %#x has bits outside the mask %#x", value, mask)
+	}
+	for ch := range 32 {
+		if mask&(1<<ch) == 0 {
+			continue
+		}
+		bit, err := l.triggerBit(ch)
+		if err != nil {
+			return 0, 0, err
+		}
+		if value&(1<<ch) != 0 {
+			high |= bit
+		} else {
+			low |= bit
+		}
+	}
+	return low, high, nil
+}
+
+// edgeTrigger triggers on an edge of one logic channel; the opposite edge
+// resets the trigger for the length condition.
+func (l *logicImpl) edgeTrigger(channel int, rising bool) error {
+	h := l.dev.handle
+	bit, err := l.triggerBit(channel)
+	if err != nil {
+		return err
+	}
+	chBit := cUint(bit)
+	if rising {
+		if err := dwfDigitalInTriggerSet(h, 0, chBit, 0, 0); err != nil {
+			return err
+		}
+		return dwfDigitalInTriggerResetSet(h, 0, 0, chBit, 0)
+	}
+	if err := dwfDigitalInTriggerSet(h, chBit, 0, 0, 0); err != nil {
+		return err
+	}
+	return dwfDigitalInTriggerResetSet(h, 0, 0, 0, chBit)
+}
+
+func (l *logicImpl) Record(ctx context.Context, channel int) ([]uint16, error) {
+	samples, err := l.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]uint16, len(samples))
+	for i, v := range samples {
+		data[i] = uint16(v >> channel & 1)
+	}
+	return data, nil
+}
+
+func (l *logicImpl) Capture(ctx context.Context) (LogicCapture, error) {
+	samples, err := l.acquire(ctx)
+	if err != nil {
+		return LogicCapture{}, err
+	}
+	return LogicCapture{SampleRate: l.sampleRate, SampleBits: l.sampleBits, Sync: l.sync, Samples: samples}, nil
+}
+
+// acquire runs a single acquisition and returns the sample words after the
+// channel mapping.
+func (l *logicImpl) acquire(ctx context.Context) ([]uint32, error) {
+	h := l.dev.handle
+	if err := dwfDigitalInConfigure(h, false, true); err != nil {
+		return nil, err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			_ = dwfDigitalInConfigure(h, false, false)
+			return nil, fmt.Errorf("acquisition aborted: %w", err)
+		}
+		status, err := dwfDigitalInStatus(h, true)
 		if err != nil {
-			continue
+			return nil, err
 		}
-		for n := 0; n < nodeCount; n++ {
-			name, _, err := dwfAnalogIOChannelNodeName(h, cInt(ch), cInt(n))
-			if err != nil {
-				continue
-			}
-			if name == nodeName {
-				return ch, n, true
-			}
+		if status == cDwfStateDone {
+			break
 		}
 	}
-	return -1, -1, false
+	l.triggerIndex = l.locateTrigger()
+	l.acquiredAt = time.Now()
+	raw := make([]byte, l.bufferSize*l.sampleBits/8)
+	if err := dwfDigitalInStatusData(h, raw); err != nil {
+		return nil, err
+	}
+	return l.unpack(raw), nil
 }
 
-func (s *supplyImpl) setNode(labels []string, nodeName string, value float64) {
-	h := s.dev.handle
-	for _, label := range labels {
-		if ch, node, ok := s.findChannelNode(label, nodeName); ok {
-			_ = dwfAnalogIOChannelNodeSet(h, cInt(ch), cInt(node), value)
-			return
+func (l *logicImpl) CounterLimits() (Limits, error) {
+	_, secMax, err := dwfDigitalInCounterInfo(l.dev.handle)
+	if err != nil {
+		// Devices without a counter reject the call.
+		return Limits{}, nil
+	}
+	return Limits{Max: secMax}, nil
+}
+
+func (l *logicImpl) Count(ctx context.Context, cfg LogicCounterConfig) (CounterReading, error) {
+	h := l.dev.handle
+	limits, _ := l.CounterLimits()
+	if limits.Max <= 0 {
+		return CounterReading{}, fmt.Errorf("the digital-in frequency counter is not supported by this device")
+	}
+	if cfg.GateTime <= 0 || cfg.GateTime > limits.Max {
+		return CounterReading{}, fmt.Errorf("gate time must be between 0 and %g s, got %g", limits.Max, cfg.GateTime)
+	}
+	if l.sync {
+		return CounterReading{}, fmt.Errorf("the trigger detector clocks the samples in sync mode; reopen the logic analyzer without a sample clock to count")
+	}
+	bit, err := l.triggerBit(cfg.Channel)
+	if err != nil {
+		return CounterReading{}, err
+	}
+	rise, fall, err := edgeMasks(bit, cfg.Edge)
+	if err != nil {
+		return CounterReading{}, err
+	}
+	// The counter counts trigger events, so it borrows the trigger.
+	prev := l.trigger
+	defer func() {
+		_ = dwfDigitalInCounterSet(h, 0)
+		_ = l.SetTrigger(prev)
+	}()
+	if err := dwfDigitalInCounterSet(h, cfg.GateTime); err != nil {
+		return CounterReading{}, err
+	}
+	if err := dwfDigitalInTriggerSourceSet(h, cTrigsrcDetectorDigIn); err != nil {
+		return CounterReading{}, err
+	}
+	if err := dwfDigitalInTriggerSet(h, 0, 0, cUint(rise), cUint(fall)); err != nil {
+		return CounterReading{}, err
+	}
+	if err := dwfDigitalInConfigure(h, false, true); err != nil {
+		return CounterReading{}, err
+	}
+	defer dwfDigitalInConfigure(h, false, false)
+	start := -1
+	for {
+		if err := ctx.Err(); err != nil {
+			return CounterReading{}, fmt.Errorf("count aborted: %w", err)
+		}
+		if _, err := dwfDigitalInStatus(h, false); err != nil {
+			return CounterReading{}, err
+		}
+		count, freq, tick, err := dwfDigitalInCounterStatus(h)
+		if err != nil {
+			return CounterReading{}, err
 		}
+		// The tick advances at the end of each gate; wait for the first one
+		// that ends after the counter started.
+		if start < 0 {
+			start = tick
+		} else if tick != start {
+			return CounterReading{Count: count, Frequency: freq, GateTime: cfg.GateTime}, nil
+		}
+		time.Sleep(time.Millisecond)
 	}
 }
 
-func (s *supplyImpl) Switch(cfg SuppliesConfig) error {
-	// positive supply
-	posLabels := []string{"V+", "p25V"}
-	enableVal := 0.0
-	if cfg.PositiveState {
-		enableVal = 1.0
-	}
-	s.setNode(posLabels, "Enable", enableVal)
-	s.setNode(posLabels, "Voltage", cfg.PositiveVoltage)
-	s.setNode(posLabels, "Current", cfg.PositiveCurrent)
-
-	// negative supply
-	negLabels := []string{"V-", "n25V"}
-	enableVal = 0.0
-	if cfg.NegativeState {
-		enableVal = 1.0
-	}
-	s.setNode(negLabels, "Enable", enableVal)
-	s.setNode(negLabels, "Voltage", cfg.NegativeVoltage)
-	s.setNode(negLabels, "Current", cfg.NegativeCurrent)
-
-	// digital/6V supply
-	digLabels := []string{"VDD", "p6V"}
-	enableVal = 0.0
-	if cfg.State {
-		enableVal = 1.0
-	}
-	s.setNode(digLabels, "Enable", enableVal)
-	s.setNode(digLabels, "Voltage", cfg.Voltage)
-	s.setNode(digLabels, "Current", cfg.Current)
+// locateTrigger returns the buffer index of the trigger event of the last
+// acquisition, or -1 if it was not triggered. A triggered acquisition
+// starts with the prefill samples.
+func (l *logicImpl) locateTrigger() int {
+	if !l.triggered || l.prefill >= l.bufferSize {
+		return -1
+	}
+	if auto, err := dwfDigitalInStatusAutoTriggered(l.dev.handle); err != nil || auto {
+		return -1
+	}
+	return l.prefill
+}
 
-	// master enable
-	return dwfAnalogIOEnableSet(s.dev.handle, cfg.MasterState)
+func (l *logicImpl) Timing() AcquisitionTiming {
+	return AcquisitionTiming{SampleRate: l.sampleRate, TriggerIndex: l.triggerIndex, Time: l.acquiredAt}
 }
 
-func (s *supplyImpl) Close() error {
-	return dwfAnalogIOReset(s.dev.handle)
+func (l *logicImpl) Close() error {
+	l.sync, l.triggered, l.trigger = false, false, LogicTriggerConfig{}
+	return dwfDigitalInReset(l.dev.handle)
 }
 
-// ==================== DMM ====================
+// ==================== Pattern Generator ====================
 
-type dmmImpl struct {
-	dev     *Device
-	channel int
-	nodes   struct {
-		enable int
-		mode   int
-		rangN  int
-		meas   int
-		input  int
-	}
+type patternImpl struct {
+	dev *Device
+	// run is the global run control applied by the last Generate.
+	run PatternRun
+	// channels holds the run control each enabled channel was generated
+	// with, to detect when a later Generate overrides it.
+	channels map[int]PatternRun
 }
 
-func (m *dmmImpl) Open() error {
-	h := m.dev.handle
-	m.channel = -1
-	m.nodes.enable = -1
-	m.nodes.mode = -1
-	m.nodes.rangN = -1
-	m.nodes.meas = -1
-	m.nodes.input = -1
+func (p *patternImpl) Generate(cfg PatternConfig) (PatternSettings, error) {
+	h := p.dev.handle
+	ch := cInt(cfg.Channel - p.dev.firstDIO())
 
-	chCount, err := dwfAnalogIOChannelCount(h)
+	internalFreq, err := dwfDigitalOutInternalClockInfo(h)
 	if err != nil {
-		return err
+		return PatternSettings{}, err
 	}
-	for ch := 0; ch < chCount; ch++ {
-		_, label, err := dwfAnalogIOChannelName(h, cInt(ch))
-		if err != nil || label != "DMM" {
-			continue
+
+	if cfg.Function == DigitalOutTypeCustom {
+		if len(cfg.Data) == 0 {
+			return PatternSettings{}, fmt.Errorf("a custom pattern needs data")
+		}
+		if maxBits, err := dwfDigitalOutDataInfo(h, ch); err == nil && len(cfg.Data) > maxBits {
+			return PatternSettings{}, fmt.Errorf("pattern of %d bits exceeds the %d-bit buffer of DIO %d", len(cfg.Data), maxBits, cfg.Channel)
 		}
-		m.channel = ch
-		break
 	}
-	if m.channel < 0 {
-		return fmt.Errorf("DMM not available on this device")
+	// Devices that do not report the idle states are assumed to support all.
+	if supported, err := dwfDigitalOutIdleInfo(h, ch); err == nil && supported != 0 && supported&(1<<uint(cfg.IdleState)) == 0 {
+		return PatternSettings{}, fmt.Errorf("idle state %d not supported on DIO %d", cfg.IdleState, cfg.Channel)
 	}
-
-	nodeCount, err := dwfAnalogIOChannelInfo(h, cInt(m.channel))
-	if err != nil {
-		return err
+	if err := dwfDigitalOutEnableSet(h, ch, true); err != nil {
+		return PatternSettings{}, err
 	}
-	for n := 0; n < nodeCount; n++ {
-		name, _, err := dwfAnalogIOChannelNodeName(h, cInt(m.channel), cInt(n))
+	if err := dwfDigitalOutTypeSet(h, ch, cDigitalOutType(cfg.Function)); err != nil {
+		return PatternSettings{}, err
+	}
+
+	divider := int(internalFreq / cfg.Frequency)
+	var low, high int
+	if cfg.Function == DigitalOutTypePulse {
+		counterMax, err := dwfDigitalOutCounterInfo(h, ch)
 		if err != nil {
-			continue
-		}
-		switch name {
-		case "Enable":
-			m.nodes.enable = n
-		case "Mode":
-			m.nodes.mode = n
-		case "Range":
-			m.nodes.rangN = n
-		case "Meas":
-			m.nodes.meas = n
-		case "Input":
-			m.nodes.input = n
+			return PatternSettings{}, err
 		}
+		divider, low, high = pulseCounts(internalFreq/cfg.Frequency, cfg.DutyCycle, counterMax)
+	}
+	if err := dwfDigitalOutDividerSet(h, ch, divider); err != nil {
+		return PatternSettings{}, err
+	}
+	if err := dwfDigitalOutIdleSet(h, ch, cDigitalOutIdle(cfg.IdleState)); err != nil {
+		return PatternSettings{}, err
+	}
+	if err := p.setOutput(cfg.Channel, cfg.Output); err != nil {
+		return PatternSettings{}, err
 	}
 
-	if m.nodes.enable >= 0 {
-		return dwfAnalogIOChannelNodeSet(h, cInt(m.channel), cInt(m.nodes.enable), 1.0)
+	runTime := float64(cfg.RunTime)
+	if runTime < 0 && len(cfg.Data) > 0 {
+		// One pass of the pattern, which is usually well under a second.
+		runTime = float64(len(cfg.Data)) / cfg.Frequency
+	}
+	run := PatternRun{Wait: cfg.Wait, Repeat: cfg.Repeat, RunTime: runTime}
+	if cfg.TriggerEnabled {
+		run.TriggerEnabled = true
+		run.TriggerSource = cfg.TriggerSource
+		run.TriggerEdgeRising = cfg.TriggerEdgeRising
+	}
+	if err := setPatternRun(h, run); err != nil {
+		return PatternSettings{}, err
 	}
-	return nil
-}
 
-func (m *dmmImpl) Measure(mode DMMMode, range_ float64, highImpedance bool) (float64, error) {
-	h := m.dev.handle
-	if m.nodes.input >= 0 {
-		inputVal := 0.0
-		if highImpedance {
-			inputVal = 1.0
+	if cfg.Function == DigitalOutTypePulse {
+		if err := dwfDigitalOutCounterSet(h, ch, low, high); err != nil {
+			return PatternSettings{}, err
 		}
-		if err := dwfAnalogIOChannelNodeSet(h, cInt(m.channel), cInt(m.nodes.input), inputVal); err != nil {
-			return 0, err
+		// Clear a phase left by Clocks.
+		if err := dwfDigitalOutCounterInitSet(h, ch, false, 0); err != nil {
+			return PatternSettings{}, err
 		}
-	}
-	if m.nodes.mode >= 0 {
-		if err := dwfAnalogIOChannelNodeSet(h, cInt(m.channel), cInt(m.nodes.mode), float64(mode)); err != nil {
-			return 0, err
+	} else if cfg.Function == DigitalOutTypeCustom {
+		if err := dwfDigitalOutDataSet(h, ch, cfg.Data); err != nil {
+			return PatternSettings{}, err
 		}
 	}
-	if m.nodes.rangN >= 0 {
-		if err := dwfAnalogIOChannelNodeSet(h, cInt(m.channel), cInt(m.nodes.rangN), range_); err != nil {
-			return 0, err
-		}
+
+	if err := dwfDigitalOutConfigure(h, true); err != nil {
+		return PatternSettings{}, err
 	}
-	if err := dwfAnalogIOStatus(h); err != nil {
-		return -1, err
+	return p.apply(cfg.Channel, run), nil
+}
+
+// outputNames names the drive types in errors.
+var outputNames = map[DigitalOutOutput]string{
+	DigitalOutOutputPushPull:   "push-pull",
+	DigitalOutOutputOpenDrain:  "open-drain",
+	DigitalOutOutputOpenSource: "open-source",
+}
+
+// setOutput sets the drive type of a DIO channel. Devices that do not
+// report their drive types are assumed to support all.
+func (p *patternImpl) setOutput(channel int, output DigitalOutOutput) error {
+	h := p.dev.handle
+	ch := cInt(channel - p.dev.firstDIO())
+	name, ok := outputNames[output]
+	if !ok {
+		return fmt.Errorf("invalid output type %d", output)
 	}
-	if m.nodes.meas >= 0 {
-		return dwfAnalogIOChannelNodeStatus(h, cInt(m.channel), cInt(m.nodes.meas))
+	if supported, err := dwfDigitalOutOutputInfo(h, ch); err == nil && supported != 0 && supported&(1<<output) == 0 {
+		return fmt.Errorf("DIO %d does not support %s output on the %s", channel, name, p.dev.model())
 	}
-	return -1, fmt.Errorf("DMM measurement node not found")
+	return dwfDigitalOutOutputSet(h, ch, cDigitalOutOutput(output))
 }
 
-func (m *dmmImpl) Close() error {
-	h := m.dev.handle
-	if m.nodes.enable >= 0 {
-		_ = dwfAnalogIOChannelNodeSet(h, cInt(m.channel), cInt(m.nodes.enable), 0)
-	}
-	return dwfAnalogIOReset(h)
+func (p *patternImpl) MaxData(channel int) (int, error) {
+	return dwfDigitalOutDataInfo(p.dev.handle, cInt(channel-p.dev.firstDIO()))
 }
 
-// ==================== Logic Analyzer ====================
+func (p *patternImpl) Toggle(cfg ToggleConfig) (ToggleSettings, error) {
+	if cfg.Frequency <= 0 {
+		return ToggleSettings{}, fmt.Errorf("toggle frequency must be positive")
+	}
+	if cfg.Count < 0 {
+		return ToggleSettings{}, fmt.Errorf("toggle count must not be negative")
+	}
+	h := p.dev.handle
+	ch := cInt(cfg.Channel - p.dev.firstDIO())
+	internalFreq, err := dwfDigitalOutInternalClockInfo(h)
+	if err != nil {
+		return ToggleSettings{}, err
+	}
+	counterMax, err := dwfDigitalOutCounterInfo(h, ch)
+	if err != nil {
+		return ToggleSettings{}, err
+	}
+	divider, low, high := pulseCounts(internalFreq/cfg.Frequency, 50, counterMax)
+	settings := ToggleSettings{
+		Frequency: internalFreq / float64(divider*(low+high)),
+		Divider:   divider,
+	}
+	run := PatternRun{}
+	if cfg.Count > 0 {
+		settings.RunTime = float64(cfg.Count) / settings.Frequency
+		run = PatternRun{Repeat: 1, RunTime: settings.RunTime}
+	}
 
-type logicImpl struct {
-	dev        *Device
-	bufferSize int
+	if err := dwfDigitalOutEnableSet(h, ch, true); err != nil {
+		return ToggleSettings{}, err
+	}
+	if err := dwfDigitalOutTypeSet(h, ch, cDigitalOutType(DigitalOutTypePulse)); err != nil {
+		return ToggleSettings{}, err
+	}
+	if err := dwfDigitalOutIdleSet(h, ch, cDigitalOutIdle(DigitalOutIdleLow)); err != nil {
+		return ToggleSettings{}, err
+	}
+	if err := p.setOutput(cfg.Channel, cfg.Output); err != nil {
+		return ToggleSettings{}, err
+	}
+	if err := dwfDigitalOutDividerSet(h, ch, divider); err != nil {
+		return ToggleSettings{}, err
+	}
+	if err := dwfDigitalOutCounterSet(h, ch, low, high); err != nil {
+		return ToggleSettings{}, err
+	}
+	if err := dwfDigitalOutCounterInitSet(h, ch, false, 0); err != nil {
+		return ToggleSettings{}, err
+	}
+	if err := setPatternRun(h, run); err != nil {
+		return ToggleSettings{}, err
+	}
+	if err := dwfDigitalOutConfigure(h, true); err != nil {
+		return ToggleSettings{}, err
+	}
+	settings.Pattern = p.apply(cfg.Channel, run)
+	return settings, nil
 }
 
-func (l *logicImpl) Open(cfg LogicConfig) error {
-	h := l.dev.handle
-	maxBuf, _ := dwfDigitalInBufferSizeInfo(h)
-	l.bufferSize = cfg.BufferSize
-	if l.bufferSize == 0 || l.bufferSize > maxBuf {
-		l.bufferSize = maxBuf
+// setPatternRun applies the global run control of the pattern generator.
+func setPatternRun(h DevHandle, run PatternRun) error {
+	if err := dwfDigitalOutRunSet(h, run.RunTime); err != nil {
+		return err
 	}
-
-	internalFreq, err := dwfDigitalInInternalClockInfo(h)
-	if err != nil {
+	if err := dwfDigitalOutWaitSet(h, run.Wait); err != nil {
 		return err
 	}
-	divider := int(internalFreq / cfg.SamplingFrequency)
-	if err := dwfDigitalInDividerSet(h, divider); err != nil {
+	if err := dwfDigitalOutRepeatSet(h, run.Repeat); err != nil {
 		return err
 	}
-	if err := dwfDigitalInSampleFormatSet(h, 16); err != nil {
+	if err := dwfDigitalOutRepeatTriggerSet(h, run.TriggerEnabled); err != nil {
 		return err
 	}
-	return dwfDigitalInBufferSizeSet(h, l.bufferSize)
+	// Clear the source of an earlier triggered pattern, which would
+	// otherwise hold back the start.
+	if !run.TriggerEnabled {
+		return dwfDigitalOutTriggerSourceSet(h, cTrigSrc(TrigSrcNone))
+	}
+	if err := dwfDigitalOutTriggerSourceSet(h, cTrigSrc(run.TriggerSource)); err != nil {
+		return err
+	}
+	if run.TriggerEdgeRising {
+		return dwfDigitalOutTriggerSlopeSet(h, cDwfTriggerSlopeRise)
+	}
+	return dwfDigitalOutTriggerSlopeSet(h, cDwfTriggerSlopeFall)
 }
 
-func (l *logicImpl) SetTrigger(cfg LogicTriggerConfig) error {
-	h := l.dev.handle
-	if cfg.Enable {
-		if err := dwfDigitalInTriggerSourceSet(h, cTrigsrcDetectorDigIn); err != nil {
-			return err
-		}
-	} else {
-		return dwfDigitalInTriggerSourceSet(h, cTrigsrcNone)
-	}
+// pulseCounts splits a period of cycles internal clock cycles into a clock
+// divider and low and high counter values that fit in counterMax, keeping
+// the divider as small as possible for the best frequency and duty cycle
+// resolution. Both phases get at least one count.
+func pulseCounts(cycles, dutyCycle float64, counterMax int) (divider, low, high int) {
+	divider = max(1, int(math.Ceil(cycles/float64(counterMax))))
+	steps := max(2, int(math.Round(cycles/float64(divider))))
+	high = min(steps-1, max(1, int(math.Round(float64(steps)*dutyCycle/100))))
+	return divider, steps - high, high
+}
 
-	pos := cfg.Position
-	if pos < 0 {
-		pos = 0
-	}
-	if pos > l.bufferSize {
-		pos = l.bufferSize
+func (p *patternImpl) Clocks(cfgs []ClockConfig) (ClockSettings, error) {
+	if len(cfgs) == 0 {
+		return ClockSettings{}, fmt.Errorf("no clocks given")
 	}
-	if err := dwfDigitalInTriggerPositionSet(h, l.bufferSize-pos); err != nil {
-		return err
+	seen := map[int]bool{}
+	for _, c := range cfgs {
+		if c.Frequency <= 0 {
+			return ClockSettings{}, fmt.Errorf("DIO %d: clock frequency must be positive", c.Channel)
+		}
+		if c.DutyCycle <= 0 || c.DutyCycle >= 100 {
+			return ClockSettings{}, fmt.Errorf("DIO %d: duty cycle must be between 0 and 100%%, got %g", c.Channel, c.DutyCycle)
+		}
+		if seen[c.Channel] {
+			return ClockSettings{}, fmt.Errorf("DIO %d is given twice", c.Channel)
+		}
+		seen[c.Channel] = true
 	}
-	if err := dwfDigitalInTriggerPrefillSet(h, pos); err != nil {
-		return err
+	h := p.dev.handle
+	internalFreq, err := dwfDigitalOutInternalClockInfo(h)
+	if err != nil {
+		return ClockSettings{}, err
 	}
+	var settings ClockSettings
+	for _, c := range cfgs {
+		ch := cInt(c.Channel - p.dev.firstDIO())
+		counterMax, err := dwfDigitalOutCounterInfo(h, ch)
+		if err != nil {
+			return ClockSettings{}, err
+		}
+		divider, low, high := pulseCounts(internalFreq/c.Frequency, c.DutyCycle, counterMax)
+		steps := low + high
+		delay := int(math.Round(math.Mod(math.Mod(c.Phase, 360)+360, 360) / 360 * float64(steps)))
+		startHigh, count := clockInit(low, high, delay%steps)
 
-	chBit := cUint(1 << cfg.Channel)
-	if cfg.RisingEdge {
-		if err := dwfDigitalInTriggerSet(h, 0, chBit, 0, 0); err != nil {
-			return err
+		if err := dwfDigitalOutEnableSet(h, ch, true); err != nil {
+			return ClockSettings{}, err
 		}
-		if err := dwfDigitalInTriggerResetSet(h, 0, 0, chBit, 0); err != nil {
-			return err
+		if err := dwfDigitalOutTypeSet(h, ch, cDigitalOutType(DigitalOutTypePulse)); err != nil {
+			return ClockSettings{}, err
 		}
-	} else {
-		if err := dwfDigitalInTriggerSet(h, chBit, 0, 0, 0); err != nil {
-			return err
+		if err := dwfDigitalOutIdleSet(h, ch, cDigitalOutIdle(DigitalOutIdleLow)); err != nil {
+			return ClockSettings{}, err
 		}
-		if err := dwfDigitalInTriggerResetSet(h, 0, 0, 0, chBit); err != nil {
-			return err
+		if err := dwfDigitalOutDividerSet(h, ch, divider); err != nil {
+			return ClockSettings{}, err
+		}
+		if err := dwfDigitalOutCounterSet(h, ch, low, high); err != nil {
+			return ClockSettings{}, err
+		}
+		if err := dwfDigitalOutCounterInitSet(h, ch, startHigh, count); err != nil {
+			return ClockSettings{}, err
+		}
+		settings.Clocks = append(settings.Clocks, ClockOutput{
+			Channel:   c.Channel,
+			Frequency: internalFreq / float64(divider*steps),
+			DutyCycle: 100 * float64(high) / float64(steps),
+			Phase:     360 * float64(delay%steps) / float64(steps),
+			Divider:   divider,
+		})
+	}
+	// The clocks run until stopped, and start together so their phases hold.
+	run := PatternRun{}
+	if err := setPatternRun(h, run); err != nil {
+		return ClockSettings{}, err
+	}
+	if err := dwfDigitalOutConfigure(h, true); err != nil {
+		return ClockSettings{}, err
+	}
+	for _, c := range cfgs {
+		settings.Pattern = p.apply(c.Channel, run)
+	}
+	return settings, nil
+}
+
+// clockInit returns the initial level and count of a pulse counter that
+// makes a clock of low and high steps, rising at step 0, start delay steps
+// late: the clock starts at the point of its cycle it would reach delay
+// steps before the end, with the steps that remain of that level.
+func clockInit(low, high, delay int) (startHigh bool, count int) {
+	pos := (low + high - delay) % (low + high)
+	if pos < high {
+		return true, high - pos
+	}
+	return false, low + high - pos
+}
+
+// apply records run as the global run control and as the intent of
+// channel, and reports the other enabled channels it overrides.
+func (p *patternImpl) apply(channel int, run PatternRun) PatternSettings {
+	if p.channels == nil {
+		p.channels = map[int]PatternRun{}
+	}
+	p.run = run
+	p.channels[channel] = run
+	settings := PatternSettings{Run: run}
+	for ch := range p.channels {
+		settings.Channels = append(settings.Channels, ch)
+	}
+	sort.Ints(settings.Channels)
+	for _, ch := range settings.Channels {
+		for _, d := range runDiff(p.channels[ch], run) {
+			settings.Conflicts = append(settings.Conflicts, fmt.Sprintf("DIO %d: %s", ch, d))
 		}
 	}
-
-	if err := dwfDigitalInTriggerAutoTimeoutSet(h, cfg.Timeout); err != nil {
-		return err
-	}
-	if err := dwfDigitalInTriggerLengthSet(h, cfg.LengthMin, cfg.LengthMax, 0); err != nil {
-		return err
-	}
-	return dwfDigitalInTriggerCountSet(h, cInt(cfg.Count), 0)
+	return settings
 }
 
-func (l *logicImpl) Record(channel int) ([]uint16, error) {
-	h := l.dev.handle
-	if err := dwfDigitalInConfigure(h, false, true); err != nil {
-		return nil, err
+// runDiff lists the run control fields that differ between the requested
+// and the effective settings.
+func runDiff(want, got PatternRun) []string {
+	var out []string
+	if want.Wait != got.Wait {
+		out = append(out, fmt.Sprintf("wait %gs -> %gs", want.Wait, got.Wait))
 	}
-	for {
-		status, err := dwfDigitalInStatus(h, true)
-		if err != nil {
-			return nil, err
-		}
-		if status == cDwfStateDone {
-			break
-		}
+	if want.Repeat != got.Repeat {
+		out = append(out, fmt.Sprintf("repeat %d -> %d", want.Repeat, got.Repeat))
 	}
-	buffer := make([]uint16, l.bufferSize)
-	if err := dwfDigitalInStatusData(h, buffer); err != nil {
-		return nil, err
+	if want.RunTime != got.RunTime {
+		out = append(out, fmt.Sprintf("run time %gs -> %gs", want.RunTime, got.RunTime))
 	}
-	for i := range buffer {
-		buffer[i] = (buffer[i] & (1 << channel)) >> channel
+	if want.TriggerEnabled != got.TriggerEnabled || want.TriggerSource != got.TriggerSource || want.TriggerEdgeRising != got.TriggerEdgeRising {
+		out = append(out, fmt.Sprintf("trigger %s -> %s", triggerDesc(want), triggerDesc(got)))
 	}
-	return buffer, nil
+	return out
 }
 
-func (l *logicImpl) Close() error {
-	return dwfDigitalInReset(l.dev.handle)
+func triggerDesc(r PatternRun) string {
+	if !r.TriggerEnabled {
+		return "none"
+	}
+	edge := "falling"
+	if r.TriggerEdgeRising {
+		edge = "rising"
+	}
+	return fmt.Sprintf("source %d %s", r.TriggerSource, edge)
 }
 
-// ==================== Pattern Generator ====================
-
-type patternImpl struct {
-	dev *Device
-}
+// maxROMInputs bounds the inputs of ROM logic, whose table doubles with each.
+const maxROMInputs = 16
 
-func (p *patternImpl) Generate(cfg PatternConfig) error {
+func (p *patternImpl) ROM(cfg ROMConfig) (ROMSettings, error) {
+	if len(cfg.Inputs) == 0 || len(cfg.Inputs) > maxROMInputs {
+		return ROMSettings{}, fmt.Errorf("ROM logic needs 1 to %d inputs, got %d", maxROMInputs, len(cfg.Inputs))
+	}
+	if len(cfg.Outputs) == 0 || len(cfg.Outputs) > 32 {
+		return ROMSettings{}, fmt.Errorf("ROM logic needs 1 to 32 outputs, got %d", len(cfg.Outputs))
+	}
+	if len(cfg.Table) != 1<<len(cfg.Inputs) {
+		return ROMSettings{}, fmt.Errorf("a table of %d inputs needs %d entries, got %d", len(cfg.Inputs), 1<<len(cfg.Inputs), len(cfg.Table))
+	}
+	seen := map[int]bool{}
+	for _, line := range slices.Concat(cfg.Inputs, cfg.Outputs) {
+		if seen[line] {
+			return ROMSettings{}, fmt.Errorf("DIO %d is used twice", line)
+		}
+		seen[line] = true
+	}
 	h := p.dev.handle
-	ch := cInt(cfg.Channel)
-	if p.dev.info != nil && p.dev.info.Name == "Digital Discovery" {
-		ch = cInt(cfg.Channel - 24)
+	first := p.dev.firstDIO()
+	var settings ROMSettings
+	for k, out := range cfg.Outputs {
+		ch := cInt(out - first)
+		if types, err := dwfDigitalOutTypeInfo(h, ch); err != nil {
+			return ROMSettings{}, err
+		} else if types&(1<<DigitalOutTypeROM) == 0 {
+			return ROMSettings{}, fmt.Errorf("DIO %d does not support ROM logic", out)
+		}
+		if err := dwfDigitalOutEnableSet(h, ch, true); err != nil {
+			return ROMSettings{}, err
+		}
+		if err := dwfDigitalOutTypeSet(h, ch, cDigitalOutType(DigitalOutTypeROM)); err != nil {
+			return ROMSettings{}, err
+		}
+		// The table is indexed by the state of the lowest DIO lines, as many
+		// as its size allows.
+		maxBits, err := dwfDigitalOutDataInfo(h, ch)
+		if err != nil {
+			return ROMSettings{}, err
+		}
+		settings.AddressLines = bits.Len(uint(maxBits)) - 1
+		if settings.AddressLines < 1 {
+			return ROMSettings{}, fmt.Errorf("DIO %d reports no ROM table", out)
+		}
+		for _, in := range cfg.Inputs {
+			if in-first < 0 || in-first >= settings.AddressLines {
+				return ROMSettings{}, fmt.Errorf("DIO %d cannot be a ROM input; the %s addresses its tables with DIO %d-%d", in, p.dev.model(), first, first+settings.AddressLines-1)
+			}
+		}
+		data := make([]uint16, 1<<settings.AddressLines)
+		for addr := range data {
+			index := 0
+			for i, in := range cfg.Inputs {
+				index |= (addr >> (in - first) & 1) << i
+			}
+			data[addr] = uint16(cfg.Table[index] >> k & 1)
+		}
+		if err := dwfDigitalOutDataSet(h, ch, data); err != nil {
+			return ROMSettings{}, err
+		}
 	}
-
-	internalFreq, err := dwfDigitalOutInternalClockInfo(h)
-	if err != nil {
-		return err
+	run := PatternRun{}
+	if err := setPatternRun(h, run); err != nil {
+		return ROMSettings{}, err
 	}
-
-	if err := dwfDigitalOutEnableSet(h, ch, true); err != nil {
-		return err
+	if err := dwfDigitalOutConfigure(h, true); err != nil {
+		return ROMSettings{}, err
 	}
-	if err := dwfDigitalOutTypeSet(h, ch, cDigitalOutType(cfg.Function)); err != nil {
-		return err
+	for _, out := range cfg.Outputs {
+		settings.Pattern = p.apply(out, run)
 	}
+	return settings, nil
+}
 
-	divider := int(internalFreq / cfg.Frequency)
-	if err := dwfDigitalOutDividerSet(h, ch, divider); err != nil {
-		return err
+// playWidths are the sample widths play mode streams, in bits.
+var playWidths = []int{1, 2, 4, 8, 16}
+
+func (p *patternImpl) Play(ctx context.Context, cfg PatternPlayConfig) (PatternPlaySettings, error) {
+	if len(cfg.Data) == 0 || cfg.SampleRate <= 0 {
+		return PatternPlaySettings{}, fmt.Errorf("play needs samples and a positive sample rate")
 	}
-	if err := dwfDigitalOutIdleSet(h, ch, cDigitalOutIdle(cfg.IdleState)); err != nil {
-		return err
+	if !slices.Contains(playWidths, cfg.Lines) {
+		return PatternPlaySettings{}, fmt.Errorf("play mode drives 1, 2, 4, 8 or 16 lines, got %d", cfg.Lines)
 	}
-
-	runTime := cfg.RunTime
-	if runTime < 0 && len(cfg.Data) > 0 {
-		runTime = int(float64(len(cfg.Data)) / cfg.Frequency)
+	h := p.dev.handle
+	first := p.dev.firstDIO()
+	for i := range cfg.Lines {
+		ch := cInt(i)
+		if types, err := dwfDigitalOutTypeInfo(h, ch); err != nil {
+			return PatternPlaySettings{}, err
+		} else if types&(1<<DigitalOutTypePlay) == 0 {
+			return PatternPlaySettings{}, fmt.Errorf("DIO %d does not support play mode on the %s", first+i, p.dev.model())
+		}
+		if err := dwfDigitalOutEnableSet(h, ch, true); err != nil {
+			return PatternPlaySettings{}, err
+		}
+		if err := dwfDigitalOutTypeSet(h, ch, cDigitalOutType(DigitalOutTypePlay)); err != nil {
+			return PatternPlaySettings{}, err
+		}
 	}
-	if err := dwfDigitalOutRunSet(h, float64(runTime)); err != nil {
-		return err
+	if err := dwfDigitalOutPlayRateSet(h, cfg.SampleRate); err != nil {
+		return PatternPlaySettings{}, err
 	}
-	if err := dwfDigitalOutWaitSet(h, cfg.Wait); err != nil {
-		return err
+	// The SDK keeps the data and streams it to the device as it drains.
+	if err := dwfDigitalOutPlayDataSet(h, cfg.Data, cfg.Lines); err != nil {
+		return PatternPlaySettings{}, err
 	}
-	if err := dwfDigitalOutRepeatSet(h, cfg.Repeat); err != nil {
-		return err
+	run := PatternRun{Repeat: 1, RunTime: float64(len(cfg.Data)) / cfg.SampleRate}
+	if err := setPatternRun(h, run); err != nil {
+		return PatternPlaySettings{}, err
 	}
-
-	if err := dwfDigitalOutRepeatTriggerSet(h, cfg.TriggerEnabled); err != nil {
-		return err
+	if err := dwfDigitalOutConfigure(h, true); err != nil {
+		return PatternPlaySettings{}, err
 	}
-	if cfg.TriggerEnabled {
-		if err := dwfDigitalOutTriggerSourceSet(h, cTrigSrc(cfg.TriggerSource)); err != nil {
-			return err
+	var settings PatternPlaySettings
+	for i := range cfg.Lines {
+		settings.Pattern = p.apply(first+i, run)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			_ = dwfDigitalOutConfigure(h, false)
+			return settings, fmt.Errorf("playback aborted: %w", err)
 		}
-		if cfg.TriggerEdgeRising {
-			if err := dwfDigitalOutTriggerSlopeSet(h, cDwfTriggerSlopeRise); err != nil {
-				return err
-			}
-		} else {
-			if err := dwfDigitalOutTriggerSlopeSet(h, cDwfTriggerSlopeFall); err != nil {
-				return err
-			}
+		status, err := dwfDigitalOutStatus(h)
+		if err != nil {
+			return settings, err
+		}
+		if status == cDwfStateDone {
+			settings.Samples = len(cfg.Data)
+			return settings, nil
 		}
+		time.Sleep(time.Millisecond)
 	}
+}
 
-	if cfg.Function == DigitalOutTypePulse {
-		steps := int(internalFreq/cfg.Frequency) / divider
-		high := int(float64(steps) * cfg.DutyCycle / 100)
-		low := steps - high
-		if err := dwfDigitalOutCounterSet(h, ch, low, high); err != nil {
-			return err
-		}
-	} else if cfg.Function == DigitalOutTypeCustom && len(cfg.Data) > 0 {
-		if err := dwfDigitalOutDataSet(h, ch, cfg.Data); err != nil {
-			return err
+func (p *patternImpl) SetDuty(channel int, dutyCycle float64) (DutySettings, error) {
+	if dutyCycle < 0 || dutyCycle > 100 {
+		return DutySettings{}, fmt.Errorf("duty cycle must be between 0 and 100%%, got %g", dutyCycle)
+	}
+	h := p.dev.handle
+	ch := cInt(channel - p.dev.firstDIO())
+	if on, err := dwfDigitalOutEnableGet(h, ch); err != nil {
+		return DutySettings{}, err
+	} else if !on {
+		return DutySettings{}, fmt.Errorf("DIO %d is not generating a pattern", channel)
+	}
+	if t, err := dwfDigitalOutTypeGet(h, ch); err != nil {
+		return DutySettings{}, err
+	} else if t != DigitalOutTypePulse {
+		return DutySettings{}, fmt.Errorf("DIO %d is not generating pulses", channel)
+	}
+	low, high, err := dwfDigitalOutCounterGet(h, ch)
+	if err != nil {
+		return DutySettings{}, err
+	}
+	divider, err := dwfDigitalOutDividerGet(h, ch)
+	if err != nil {
+		return DutySettings{}, err
+	}
+	internalFreq, err := dwfDigitalOutInternalClockInfo(h)
+	if err != nil {
+		return DutySettings{}, err
+	}
+	// Keeping the period in counter steps keeps the frequency; the line
+	// must still toggle, so the duty cycle stops one step short of 0 and
+	// 100%.
+	steps := low + high
+	high = min(steps-1, max(1, int(math.Round(float64(steps)*dutyCycle/100))))
+	if err := dwfDigitalOutCounterSet(h, ch, steps-high, high); err != nil {
+		return DutySettings{}, err
+	}
+	settings := DutySettings{
+		DutyCycle: 100 * float64(high) / float64(steps),
+		Frequency: internalFreq / float64(max(divider, 1)*steps),
+	}
+	// With auto-configure the device takes the counter while running.
+	if auto, err := dwfDeviceAutoConfigureGet(h); err != nil || !auto {
+		if err := dwfDigitalOutConfigure(h, true); err != nil {
+			return DutySettings{}, err
 		}
+		settings.Restarted = true
 	}
-
-	return dwfDigitalOutConfigure(h, true)
+	return settings, nil
 }
 
 func (p *patternImpl) Enable(channel int) error {
 	h := p.dev.handle
-	ch := cInt(channel)
-	if p.dev.info != nil && p.dev.info.Name == "Digital Discovery" {
-		ch = cInt(channel - 24)
-	}
+	ch := cInt(channel - p.dev.firstDIO())
 	if err := dwfDigitalOutEnableSet(h, ch, true); err != nil {
 		return err
 	}
@@ -821,24 +3271,84 @@ func (p *patternImpl) Enable(channel int) error {
 
 func (p *patternImpl) Disable(channel int) error {
 	h := p.dev.handle
-	ch := cInt(channel)
-	if p.dev.info != nil && p.dev.info.Name == "Digital Discovery" {
-		ch = cInt(channel - 24)
-	}
+	ch := cInt(channel - p.dev.firstDIO())
 	if err := dwfDigitalOutEnableSet(h, ch, false); err != nil {
 		return err
 	}
+	delete(p.channels, channel)
 	return dwfDigitalOutConfigure(h, true)
 }
 
+func (p *patternImpl) Status() (PatternStatus, error) {
+	h := p.dev.handle
+	state, err := dwfDigitalOutStatus(h)
+	if err != nil {
+		return PatternStatus{}, err
+	}
+	remaining, err := dwfDigitalOutRepeatStatus(h)
+	if err != nil {
+		return PatternStatus{}, err
+	}
+	status := PatternStatus{
+		State:             stateName(state),
+		WaitingForTrigger: state == cDwfStateArmed,
+		TriggerEnabled:    p.run.TriggerEnabled,
+		Repeat:            p.run.Repeat,
+		RepeatsRemaining:  remaining,
+		Enabled:           []int{},
+	}
+	count, err := dwfDigitalOutCount(h)
+	if err != nil {
+		return PatternStatus{}, err
+	}
+	for ch := range count {
+		on, err := dwfDigitalOutEnableGet(h, cInt(ch))
+		if err != nil {
+			return PatternStatus{}, err
+		}
+		if on {
+			status.Enabled = append(status.Enabled, ch+p.dev.firstDIO())
+		}
+	}
+	return status, nil
+}
+
 func (p *patternImpl) Close() error {
+	p.run, p.channels = PatternRun{}, nil
 	return dwfDigitalOutReset(p.dev.handle)
 }
 
+// stateName names an instrument state returned by the status functions.
+func stateName(state byte) string {
+	switch state {
+	case cDwfStateReady:
+		return "ready"
+	case cDwfStateArmed:
+		return "armed"
+	case cDwfStateDone:
+		return "done"
+	case cDwfStateRunning:
+		return "running"
+	case cDwfStateConfig:
+		return "config"
+	case cDwfStatePrefill:
+		return "prefill"
+	case cDwfStateWait:
+		return "wait"
+	}
+	return fmt.Sprintf("unknown (%d)", state)
+}
+
 // ==================== Static I/O ====================
 
 type staticIOImpl struct {
 	dev *Device
+	// openDrain and openSource are the masks of the lines whose drive
+	// type SetOutput emulates.
+	openDrain, openSource uint32
+	// outputs holds the direction of the emulated lines, a bit set for
+	// each output, since their output enable follows the level instead.
+	outputs uint32
 }
 
 func (s *staticIOImpl) channelCount() int {
@@ -854,10 +3364,25 @@ func (s *staticIOImpl) channelCount() int {
 }
 
 func (s *staticIOImpl) adjustChannel(channel int) int {
-	if s.dev.info != nil && s.dev.info.Name == "Digital Discovery" {
-		return channel - 24
+	return channel - s.dev.firstDIO()
+}
+
+// bits returns the mask of the given DIO lines in the static I/O
+// registers.
+func (s *staticIOImpl) bits(channels ...int) uint32 {
+	var mask uint32
+	for _, channel := range channels {
+		mask |= uint32(1) << uint32(s.adjustChannel(channel))
 	}
-	return channel
+	return mask
+}
+
+// release makes the lines of mask push-pull inputs again in the emulation
+// state, after another instrument has taken them over.
+func (s *staticIOImpl) release(mask uint32) {
+	s.openDrain &^= mask
+	s.openSource &^= mask
+	s.outputs &^= mask
 }
 
 func rotateLeft(number, position, size uint32) uint32 {
@@ -868,6 +3393,14 @@ func (s *staticIOImpl) SetMode(channel int, output bool) error {
 	h := s.dev.handle
 	ch := s.adjustChannel(channel)
 	count := uint32(s.channelCount())
+	if bit := uint32(1) << uint32(ch); s.emulated()&bit != 0 {
+		var oe uint32
+		if output {
+			oe = bit
+		}
+		_, err := s.WriteMasks(DigitalIOWrite{Mask: bit, OutputEnable: &oe})
+		return err
+	}
 
 	mask, err := dwfDigitalIOOutputEnableGet(h)
 	if err != nil {
@@ -900,6 +3433,14 @@ func (s *staticIOImpl) SetState(channel int, value bool) error {
 	h := s.dev.handle
 	ch := s.adjustChannel(channel)
 	count := uint32(s.channelCount())
+	if bit := uint32(1) << uint32(ch); s.emulated()&bit != 0 {
+		var level uint32
+		if value {
+			level = bit
+		}
+		_, err := s.WriteMasks(DigitalIOWrite{Mask: bit, Output: &level})
+		return err
+	}
 
 	mask, err := dwfDigitalIOOutputGet(h)
 	if err != nil {
@@ -915,38 +3456,222 @@ func (s *staticIOImpl) SetState(channel int, value bool) error {
 }
 
 func (s *staticIOImpl) SetCurrent(current float64) error {
+	s.dev.ioMu.Lock()
+	defer s.dev.ioMu.Unlock()
+	ch, node, ok := s.dev.supply.findChannelNode("VDD", "Drive")
+	if !ok {
+		return fmt.Errorf("the DIO drive current is not adjustable on the %s", s.dev.model())
+	}
+	if err := s.validateDrive(ch, node, current); err != nil {
+		return err
+	}
+	return dwfAnalogIOChannelNodeSet(s.dev.handle, cInt(ch), cInt(node), current)
+}
+
+// emulated returns the mask of the open-drain and open-source lines.
+func (s *staticIOImpl) emulated() uint32 {
+	return s.openDrain | s.openSource
+}
+
+// emulatedEnable returns the output enable of the open-drain and
+// open-source lines at the given levels: each is driven at its active
+// level only.
+func (s *staticIOImpl) emulatedEnable(levels uint32) uint32 {
+	return ^levels&s.openDrain | levels&s.openSource
+}
+
+func (s *staticIOImpl) SetOutput(channel int, output DigitalOutOutput) error {
+	ch := s.adjustChannel(channel)
+	if ch < 0 || ch >= s.channelCount() {
+		return fmt.Errorf("DIO %d is not a static I/O line of the %s", channel, s.dev.model())
+	}
+	if _, ok := outputNames[output]; !ok {
+		return fmt.Errorf("invalid output type %d", output)
+	}
+	bit := uint32(1) << uint32(ch)
+	// Keep the line's direction across the change: an output now follows
+	// its level, or drives again as push-pull; an input stays released.
+	masks, err := s.ReadMasks()
+	if err != nil {
+		return err
+	}
+	dir := masks.OutputEnable & bit
+	s.openDrain &^= bit
+	s.openSource &^= bit
+	switch output {
+	case DigitalOutOutputOpenDrain:
+		s.openDrain |= bit
+	case DigitalOutOutputOpenSource:
+		s.openSource |= bit
+	}
+	_, err = s.WriteMasks(DigitalIOWrite{Mask: bit, OutputEnable: &dir})
+	return err
+}
+
+func (s *staticIOImpl) SetPull(channel int, direction PullDirection) error {
 	h := s.dev.handle
-	chCount, err := dwfAnalogIOChannelCount(h)
+	up, down, err := dwfDigitalIOPullGet(h)
 	if err != nil {
 		return err
 	}
-	for ch := 0; ch < chCount; ch++ {
-		_, label, err := dwfAnalogIOChannelName(h, cInt(ch))
-		if err != nil || label != "VDD" {
-			continue
+	var mask uint32
+	if channel < 0 {
+		mask = uint32(1)<<uint32(s.channelCount()) - 1
+	} else {
+		mask = uint32(1) << uint32(s.adjustChannel(channel))
+	}
+	up &^= mask
+	down &^= mask
+	switch direction {
+	case PullUp:
+		up |= mask
+	case PullDown:
+		down |= mask
+	}
+	return dwfDigitalIOPullSet(h, up, down)
+}
+
+func (s *staticIOImpl) SetVIO(volts float64) error {
+	s.dev.ioMu.Lock()
+	defer s.dev.ioMu.Unlock()
+	ch, node, ok := s.dev.supply.findChannelNode("VIO", "Voltage")
+	if !ok {
+		return fmt.Errorf("VIO is not adjustable on this device")
+	}
+	h := s.dev.handle
+	if err := dwfAnalogIOChannelNodeSet(h, cInt(ch), cInt(node), volts); err != nil {
+		return err
+	}
+	return dwfAnalogIOEnableSet(h, true)
+}
+
+func (s *staticIOImpl) SetThreshold(volts float64) error {
+	return s.dev.logic.setThreshold(volts)
+}
+
+func (s *staticIOImpl) Levels() (DigitalIOLevels, error) {
+	s.dev.ioMu.Lock()
+	defer s.dev.ioMu.Unlock()
+	h := s.dev.handle
+	// read returns the value and settable range of a node.
+	read := func(ch, node int) (*float64, *AnalogIORange, error) {
+		v, err := dwfAnalogIOChannelNodeGet(h, cInt(ch), cInt(node))
+		if err != nil {
+			return nil, nil, err
 		}
-		nodeCount, err := dwfAnalogIOChannelInfo(h, cInt(ch))
+		lo, hi, steps, err := dwfAnalogIOChannelNodeSetInfo(h, cInt(ch), cInt(node))
 		if err != nil {
-			continue
+			return &v, nil, nil
 		}
-		for n := 0; n < nodeCount; n++ {
-			name, _, err := dwfAnalogIOChannelNodeName(h, cInt(ch), cInt(n))
-			if err != nil || name != "Drive" {
-				continue
-			}
-			return dwfAnalogIOChannelNodeSet(h, cInt(ch), cInt(n), current)
+		return &v, &AnalogIORange{Min: lo, Max: hi, Steps: steps}, nil
+	}
+	var levels DigitalIOLevels
+	var err error
+	if ch, node, ok := s.dev.supply.findChannelNode("VIO", "Voltage"); ok {
+		if levels.VIO, levels.VIORange, err = read(ch, node); err != nil {
+			return DigitalIOLevels{}, err
 		}
 	}
-	return fmt.Errorf("drive current node not found")
+	if ch, node, ok := s.dev.thresholdNode(); ok {
+		if levels.Threshold, levels.ThresholdRange, err = read(ch, node); err != nil {
+			return DigitalIOLevels{}, err
+		}
+	}
+	if ch, node, ok := s.dev.supply.findChannelNode("VDD", "Drive"); ok {
+		if levels.Drive, _, err = read(ch, node); err != nil {
+			return DigitalIOLevels{}, err
+		}
+	}
+	return levels, nil
 }
 
-func (s *staticIOImpl) SetPull(channel int, direction PullDirection) error {
-	_ = channel
-	_ = direction
-	return fmt.Errorf("SetPull: not yet implemented for this device")
+// lineMask returns the mask of all DIO lines.
+func (s *staticIOImpl) lineMask() uint32 {
+	if n := s.channelCount(); n < 32 {
+		return 1<<n - 1
+	}
+	return math.MaxUint32
+}
+
+func (s *staticIOImpl) ReadMasks() (DigitalIOMasks, error) {
+	h := s.dev.handle
+	m := DigitalIOMasks{Lines: s.channelCount(), FirstDIO: s.dev.firstDIO()}
+	var err error
+	if m.OutputEnable, err = dwfDigitalIOOutputEnableGet(h); err != nil {
+		return DigitalIOMasks{}, err
+	}
+	if m.Output, err = dwfDigitalIOOutputGet(h); err != nil {
+		return DigitalIOMasks{}, err
+	}
+	if err := dwfDigitalIOStatus(h); err != nil {
+		return DigitalIOMasks{}, err
+	}
+	if m.Input, err = dwfDigitalIOInputStatus(h); err != nil {
+		return DigitalIOMasks{}, err
+	}
+	all := s.lineMask()
+	m.OutputEnable &= all
+	m.Output &= all
+	m.Input &= all
+	// An emulated line reports its direction, not the output enable that
+	// follows its level.
+	em := s.emulated()
+	m.OutputEnable = m.OutputEnable&^em | s.outputs&em
+	m.OpenDrain, m.OpenSource = s.openDrain, s.openSource
+	return m, nil
+}
+
+func (s *staticIOImpl) WriteMasks(w DigitalIOWrite) (DigitalIOMasks, error) {
+	if extra := w.Mask &^ s.lineMask(); extra != 0 {
+		return DigitalIOMasks{}, fmt.Errorf("mask %#x selects lines beyond the %d DIO lines of the %s", w.Mask, s.channelCount(), s.dev.model())
+	}
+	h := s.dev.handle
+	out, err := dwfDigitalIOOutputGet(h)
+	if err != nil {
+		return DigitalIOMasks{}, err
+	}
+	oe, err := dwfDigitalIOOutputEnableGet(h)
+	if err != nil {
+		return DigitalIOMasks{}, err
+	}
+	// dir is the direction of each line: the output enable, or for the
+	// open-drain and open-source lines the direction kept apart from it.
+	em := s.emulated()
+	dir := oe&^em | s.outputs&em
+	newOut := out
+	if w.Output != nil {
+		newOut = out&^w.Mask | *w.Output&w.Mask
+	}
+	if w.OutputEnable != nil {
+		dir = dir&^w.Mask | *w.OutputEnable&w.Mask
+	}
+	s.outputs = dir & em
+	// Open-drain and open-source outputs are driven at their active level
+	// and released otherwise; their inputs stay released.
+	newOE := dir&^em | s.emulatedEnable(newOut)&dir&em
+	// Outputs are disabled first and enabled last, so that a line turned
+	// into an output starts at its new level and an open-drain line is
+	// released before its level rises.
+	if released := oe & newOE; released != oe {
+		if err := dwfDigitalIOOutputEnableSet(h, released); err != nil {
+			return DigitalIOMasks{}, err
+		}
+	}
+	if newOut != out {
+		if err := dwfDigitalIOOutputSet(h, newOut); err != nil {
+			return DigitalIOMasks{}, err
+		}
+	}
+	if newOE != oe&newOE {
+		if err := dwfDigitalIOOutputEnableSet(h, newOE); err != nil {
+			return DigitalIOMasks{}, err
+		}
+	}
+	return s.ReadMasks()
 }
 
 func (s *staticIOImpl) Close() error {
+	s.openDrain, s.openSource, s.outputs = 0, 0, 0
 	return dwfDigitalIOReset(s.dev.handle)
 }
 
@@ -954,18 +3679,27 @@ func (s *staticIOImpl) Close() error {
 
 type uartImpl struct {
 	dev *Device
+	// rx and tx are the lines of the last Open, -1 if unassigned.
+	rx, tx int
 }
 
 func (u *uartImpl) Open(cfg UARTConfig) error {
+	if cfg.RX < 0 && cfg.TX < 0 {
+		return fmt.Errorf("UART needs an RX or a TX line")
+	}
 	h := u.dev.handle
 	if err := dwfDigitalUartRateSet(h, float64(cfg.BaudRate)); err != nil {
 		return err
 	}
-	if err := dwfDigitalUartTxSet(h, cInt(cfg.TX)); err != nil {
-		return err
+	if cfg.TX >= 0 {
+		if err := dwfDigitalUartTxSet(h, cInt(cfg.TX)); err != nil {
+			return err
+		}
 	}
-	if err := dwfDigitalUartRxSet(h, cInt(cfg.RX)); err != nil {
-		return err
+	if cfg.RX >= 0 {
+		if err := dwfDigitalUartRxSet(h, cInt(cfg.RX)); err != nil {
+			return err
+		}
 	}
 	if err := dwfDigitalUartBitsSet(h, cInt(cfg.DataBits)); err != nil {
 		return err
@@ -976,12 +3710,20 @@ func (u *uartImpl) Open(cfg UARTConfig) error {
 	if err := dwfDigitalUartStopSet(h, float64(cfg.StopBits)); err != nil {
 		return err
 	}
-	_ = dwfDigitalUartTx(h, nil)
-	_, _, _ = dwfDigitalUartRx(h, 0)
+	if cfg.TX >= 0 {
+		_ = dwfDigitalUartTx(h, nil)
+	}
+	if cfg.RX >= 0 {
+		_, _, _ = dwfDigitalUartRx(h, 0)
+	}
+	u.rx, u.tx = cfg.RX, cfg.TX
 	return nil
 }
 
 func (u *uartImpl) Read() ([]byte, error) {
+	if u.rx < 0 {
+		return nil, fmt.Errorf("UART was opened without an RX line")
+	}
 	h := u.dev.handle
 	maxBuf := 8192
 	if u.dev.info != nil && u.dev.info.MaxAnalogInBufferSize > 0 {
@@ -1002,6 +3744,9 @@ func (u *uartImpl) Read() ([]byte, error) {
 }
 
 func (u *uartImpl) Write(data []byte) error {
+	if u.tx < 0 {
+		return fmt.Errorf("UART was opened without a TX line")
+	}
 	return dwfDigitalUartTx(u.dev.handle, data)
 }
 
@@ -1196,5 +3941,122 @@ func (ic *i2cImpl) Close() error {
 	return dwfDigitalI2cReset(ic.dev.handle)
 }
 
+// i2cRecoveryPulses is the number of SCL pulses that lets any target finish
+// the byte it is sending: 8 data bits plus the acknowledge bit.
+const i2cRecoveryPulses = 9
+
+// i2cStretchTimeout bounds how long a target may hold SCL low during
+// recovery before the bus is considered unrecoverable.
+const i2cStretchTimeout = 10 * time.Millisecond
+
+func (ic *i2cImpl) Recover(cfg I2CConfig) (I2CRecovery, error) {
+	h := ic.dev.handle
+	var rec I2CRecovery
+	if err := dwfDigitalI2cReset(h); err != nil {
+		return rec, err
+	}
+	scl, sda := ic.dev.staticIO.bits(cfg.SCL), ic.dev.staticIO.bits(cfg.SDA)
+	rate := cfg.ClockRate
+	if rate <= 0 {
+		rate = 100e3
+	}
+	half := time.Duration(float64(time.Second) / rate / 2)
+
+	// Emulate open-drain lines: the output latch stays low and a line is
+	// driven by enabling its output, released by disabling it.
+	enable, err := dwfDigitalIOOutputEnableGet(h)
+	if err != nil {
+		return rec, err
+	}
+	out, err := dwfDigitalIOOutputGet(h)
+	if err != nil {
+		return rec, err
+	}
+	enable &^= scl | sda
+	if err := dwfDigitalIOOutputEnableSet(h, enable); err != nil {
+		return rec, err
+	}
+	if err := dwfDigitalIOOutputSet(h, out&^(scl|sda)); err != nil {
+		return rec, err
+	}
+	// Release both lines again whatever happens. They are no longer
+	// emulated open-drain or open-source static I/O lines.
+	ic.dev.staticIO.release(scl | sda)
+	defer dwfDigitalIOOutputEnableSet(h, enable)
+
+	drive := func(lines uint32) error {
+		return dwfDigitalIOOutputEnableSet(h, enable|lines)
+	}
+	read := func() (uint32, error) {
+		if err := dwfDigitalIOStatus(h); err != nil {
+			return 0, err
+		}
+		return dwfDigitalIOInputStatus(h)
+	}
+	// releaseSCL lets SCL go high, waiting for a target that stretches it.
+	releaseSCL := func(held uint32) (uint32, error) {
+		if err := drive(held); err != nil {
+			return 0, err
+		}
+		deadline := time.Now().Add(i2cStretchTimeout)
+		for {
+			time.Sleep(half)
+			in, err := read()
+			if err != nil || in&scl != 0 {
+				return in, err
+			}
+			if time.Now().After(deadline) {
+				return in, fmt.Errorf("SCL is held low by a target; the bus cannot be recovered by clocking, power-cycle the target")
+			}
+		}
+	}
+
+	in, err := read()
+	if err != nil {
+		return rec, err
+	}
+	if in&scl == 0 {
+		if in, err = releaseSCL(0); err != nil {
+			return rec, err
+		}
+	}
+	rec.SDAStuck = in&sda == 0
+
+	for rec.Pulses < i2cRecoveryPulses && in&sda == 0 {
+		if err := drive(scl); err != nil {
+			return rec, err
+		}
+		time.Sleep(half)
+		if in, err = releaseSCL(0); err != nil {
+			return rec, err
+		}
+		rec.Pulses++
+	}
+
+	// STOP: SDA rises while SCL is high.
+	if err := drive(scl); err != nil {
+		return rec, err
+	}
+	time.Sleep(half)
+	if err := drive(scl | sda); err != nil {
+		return rec, err
+	}
+	time.Sleep(half)
+	if _, err := releaseSCL(sda); err != nil {
+		return rec, err
+	}
+	if err := drive(0); err != nil {
+		return rec, err
+	}
+	time.Sleep(half)
+	if in, err = read(); err != nil {
+		return rec, err
+	}
+	if in&sda == 0 {
+		return rec, fmt.Errorf("SDA still held low after %d clock pulses and a STOP", rec.Pulses)
+	}
+	return rec, ic.Open(cfg)
+}
+
 // Compile-time interface checks
 var _ DiscoveryDevice = (*Device)(nil)
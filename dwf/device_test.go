@@ -0,0 +1,45 @@
+package dwf
+
+import "testing"
+
+func TestRampTargets(t *testing.T) {
+	// The master enable is already on: the positive rail is switched on
+	// from off, the negative rail is on already and the digital rail stays
+	// off.
+	switches := []railSwitch{
+		{labels: []string{"V+", "p25V"}, on: true, voltage: 5},
+		{labels: []string{"V-", "n25V"}, on: true, voltage: -5},
+		{labels: []string{"VDD", "p6V"}, on: false, voltage: 3.3},
+	}
+	prior := []railState{
+		{powered: false, voltage: 0.2},
+		{powered: true, voltage: -3},
+		{powered: false, voltage: 0},
+	}
+	start, ramp := rampTargets(switches, prior)
+	if len(ramp) != 1 {
+		t.Fatalf("ramps = %+v, want one for the positive rail", ramp)
+	}
+	if r := ramp[0]; r.labels[0] != "V+" || r.from != 0.2 || r.to != 5 {
+		t.Errorf("ramp = %+v, want V+ from 0.2 to 5", r)
+	}
+	if want := []float64{0.2, -5, 3.3}; start[0] != want[0] || start[1] != want[1] || start[2] != want[2] {
+		t.Errorf("start = %v, want %v", start, want)
+	}
+}
+
+func TestStaticIOBits(t *testing.T) {
+	d := NewDevice()
+	d.info = &DeviceInfo{Capabilities: DeviceCapabilities{FirstDIO: 24}}
+	s := d.staticIO
+	if got := s.bits(24, 25); got != 0b11 {
+		t.Errorf("bits(24, 25) = %#b, want bits 0 and 1", got)
+	}
+
+	s.openDrain, s.openSource, s.outputs = 0b0101, 0b1010, 0b1111
+	s.release(s.bits(24, 25))
+	if s.openDrain != 0b0100 || s.openSource != 0b1000 || s.outputs != 0b1100 {
+		t.Errorf("after release: open-drain %#b, open-source %#b, outputs %#b; want DIO 24 and 25 cleared",
+			s.openDrain, s.openSource, s.outputs)
+	}
+}
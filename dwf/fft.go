@@ -0,0 +1,150 @@
+package dwf
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// FFTWindow enumerates window functions applied before a spectrum is computed.
+type FFTWindow int
+
+const (
+	WindowRectangular FFTWindow = 0
+	WindowHann        FFTWindow = 1
+	WindowFlatTop     FFTWindow = 2
+)
+
+// Spectrum is a single-sided magnitude spectrum.
+type Spectrum struct {
+	// BinWidth is the frequency spacing between bins in Hz.
+	BinWidth float64
+	// DC is the mean of the analyzed samples in Volts.
+	DC float64
+	// Magnitudes holds the peak amplitude in Volts of bin k at k*BinWidth Hz.
+	Magnitudes []float64
+}
+
+// SpectrumPeak is a local maximum of a spectrum.
+type SpectrumPeak struct {
+	// Frequency in Hz, refined by interpolating between neighboring bins.
+	Frequency float64
+	// Magnitude is the peak amplitude in Volts.
+	Magnitude float64
+}
+
+// windowCoefficients returns the window of length n.
+func windowCoefficients(window FFTWindow, n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		x := 2 * math.Pi * float64(i) / float64(n)
+		switch window {
+		case WindowHann:
+			w[i] = 0.5 - 0.5*math.Cos(x)
+		case WindowFlatTop:
+			w[i] = 0.21557895 - 0.41663158*math.Cos(x) + 0.277263158*math.Cos(2*x) -
+				0.083578947*math.Cos(3*x) + 0.006947368*math.Cos(4*x)
+		default:
+			w[i] = 1
+		}
+	}
+	return w
+}
+
+// ComputeSpectrum returns the magnitude spectrum of data sampled at
+// sampleRate Hz. The capture is truncated to the largest power of two, the
+// window is applied, and magnitudes are corrected for the window gain so a
+// sine of amplitude A shows a peak of about A (exactly A with flat-top).
+// The mean is removed before windowing so DC leakage does not mask
+// low-frequency components; bin 0 holds its magnitude.
+func ComputeSpectrum(data []float64, sampleRate float64, window FFTWindow) Spectrum {
+	n := 1
+	for n*2 <= len(data) {
+		n *= 2
+	}
+	if len(data) < 2 || sampleRate <= 0 {
+		return Spectrum{}
+	}
+
+	var mean float64
+	for _, v := range data[:n] {
+		mean += v
+	}
+	mean /= float64(n)
+
+	w := windowCoefficients(window, n)
+	var gain float64
+	buf := make([]complex128, n)
+	for i := 0; i < n; i++ {
+		buf[i] = complex((data[i]-mean)*w[i], 0)
+		gain += w[i]
+	}
+	fft(buf)
+
+	mags := make([]float64, n/2+1)
+	for k := range mags {
+		scale := 2 / gain
+		if k == 0 || k == n/2 {
+			scale = 1 / gain
+		}
+		mags[k] = cmplx.Abs(buf[k]) * scale
+	}
+	mags[0] = math.Abs(mean)
+	return Spectrum{BinWidth: sampleRate / float64(n), DC: mean, Magnitudes: mags}
+}
+
+// Peaks returns up to count local maxima with a magnitude of at least
+// minMagnitude, largest first. The DC bin is ignored.
+func (s Spectrum) Peaks(count int, minMagnitude float64) []SpectrumPeak {
+	m := s.Magnitudes
+	var peaks []SpectrumPeak
+	for k := 1; k < len(m)-1; k++ {
+		if m[k] < minMagnitude || m[k] < m[k-1] || m[k] <= m[k+1] {
+			continue
+		}
+		// Parabolic interpolation on the log magnitude locates the true
+		// peak between bins.
+		offset := 0.0
+		a, b, c := math.Log(m[k-1]), math.Log(m[k]), math.Log(m[k+1])
+		if d := a - 2*b + c; d < 0 && !math.IsInf(a, 0) && !math.IsInf(c, 0) {
+			offset = 0.5 * (a - c) / d
+		}
+		peaks = append(peaks, SpectrumPeak{
+			Frequency: (float64(k) + offset) * s.BinWidth,
+			Magnitude: m[k],
+		})
+	}
+	sort.Slice(peaks, func(i, j int) bool { return peaks[i].Magnitude > peaks[j].Magnitude })
+	if len(peaks) > count {
+		peaks = peaks[:count]
+	}
+	return peaks
+}
+
+// fft computes an in-place radix-2 FFT; len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				u := x[start+k]
+				v := x[start+k+size/2] * w
+				x[start+k] = u + v
+				x[start+k+size/2] = u - v
+				w *= step
+			}
+		}
+	}
+}
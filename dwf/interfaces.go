@@ -1,5 +1,7 @@
 package dwf
 
+import "context"
+
 // DeviceController manages device lifecycle and info.
 type DeviceController interface {
 	// EnumDevices discovers all connected Digilent devices and returns their info.
@@ -11,7 +13,8 @@ type DeviceController interface {
 	EnumConfigs(deviceIndex int) ([]DeviceConfig, error)
 
 	// Open connects to a Digilent device.
-	// device can be "" (first available), "Analog Discovery 2", "Digital Discovery", etc.
+	// device can be "" (first available), "Analog Discovery 2", "Digital Discovery", etc.,
+	// or a serial number to select one specific device.
 	// config selects the device configuration index (0 for default).
 	Open(device string, config int) (*DeviceInfo, error)
 
@@ -20,22 +23,86 @@ type DeviceController interface {
 
 	// Temperature returns the board temperature in °C.
 	Temperature() (float64, error)
+
+	// Status reports the FPGA configuration of the open device and whether it responds.
+	Status() (FirmwareStatus, error)
+
+	// Reinitialize resets all instruments or, with reprogram, closes and reopens
+	// the device with the same configuration to reload the FPGA.
+	Reinitialize(reprogram bool) (*DeviceInfo, error)
 }
 
 // Oscilloscope controls the analog input (scope) instrument.
 type Oscilloscope interface {
-	// Open initializes the oscilloscope with the given configuration.
-	Open(cfg ScopeConfig) error
+	// Open initializes the oscilloscope with the given configuration and
+	// returns the settings the device actually applied.
+	Open(cfg ScopeConfig) (ScopeSettings, error)
 
 	// Measure reads a single voltage sample from the specified channel (1-based).
 	Measure(channel int) (float64, error)
 
+	// SampleRate returns the sampling rate applied by the last Open in Hz,
+	// or 0 if the oscilloscope is not open.
+	SampleRate() float64
+
+	// BufferSize returns the buffer size applied by the last Open in
+	// samples, or 0 if the oscilloscope is not open.
+	BufferSize() int
+
+	// Config reads back the configuration the device actually applied.
+	Config() (ScopeReadback, error)
+
+	// Timing describes the time axis of the last acquisition made by
+	// Record, RecordPeak or RecordAverage.
+	Timing() AcquisitionTiming
+
 	// SetTrigger configures the oscilloscope trigger.
 	SetTrigger(cfg TriggerConfig) error
 
+	// SetCoupling selects AC or DC input coupling for the specified channel (1-based).
+	SetCoupling(channel int, coupling AnalogCoupling) error
+
+	// SetAttenuation sets the probe attenuation factor for the specified channel
+	// (1-based) so that samples are reported in probe-tip volts.
+	SetAttenuation(channel int, factor float64) error
+
 	// Record captures a buffer of samples from the specified channel (1-based).
-	// Returns the recorded voltage samples.
-	Record(channel int) ([]float64, error)
+	// Returns the recorded voltage samples. The acquisition is stopped and an
+	// error wrapping ctx.Err() is returned if ctx ends before it completes.
+	Record(ctx context.Context, channel int) ([]float64, error)
+
+	// RecordChannels captures one buffer and returns the samples of each of
+	// the given channels (1-based), so they share the same trigger and
+	// time axis.
+	RecordChannels(ctx context.Context, channels []int) ([][]float64, error)
+
+	// RecordPeak captures a buffer from the specified channel (1-based) and
+	// returns the min/max of the ADC samples behind each decimated point,
+	// along with the decimated samples.
+	RecordPeak(ctx context.Context, channel int) (PeakRecord, error)
+
+	// RecordRaw captures a buffer from the specified channel (1-based) and
+	// returns the ADC codes with the factors that convert them to volts.
+	// It needs the single acquisition mode.
+	RecordRaw(ctx context.Context, channel int) (RawRecord, error)
+
+	// RecordAverage performs count triggered acquisitions on the specified
+	// channel (1-based) and returns their point-wise average.
+	RecordAverage(ctx context.Context, channel int, count int) (AveragedRecord, error)
+
+	// CounterLimits returns the gate times in seconds the analog-in
+	// frequency counter accepts. Max is 0 on devices without a counter.
+	CounterLimits() (Limits, error)
+
+	// Count runs the analog-in frequency counter for one gate and returns
+	// the reading. The trigger settings it uses are restored afterwards.
+	Count(ctx context.Context, cfg CounterConfig) (CounterReading, error)
+
+	// Stream acquires duration seconds from the specified channel (1-based)
+	// in record mode, passing each block of new samples to onChunk as soon
+	// as it is available. Returning an error from onChunk, or the end of
+	// ctx, stops the acquisition.
+	Stream(ctx context.Context, channel int, duration float64, onChunk func(chunk []float64) error) (StreamStats, error)
 
 	// Close resets the oscilloscope.
 	Close() error
@@ -43,8 +110,19 @@ type Oscilloscope interface {
 
 // WavegenDriver controls the analog output (wavegen) instrument.
 type WavegenDriver interface {
-	// Generate starts an analog waveform on the specified channel.
-	Generate(cfg WavegenConfig) error
+	// Generate starts an analog waveform on the specified channel and returns
+	// the parameters the device actually applied.
+	Generate(cfg WavegenConfig) (WavegenSettings, error)
+
+	// DataLimits returns the smallest and largest custom waveform, in
+	// samples, the given channel (1-based) accepts.
+	DataLimits(channel int) (Limits, error)
+
+	// Play streams cfg.Data once, refilling the device buffer from the host
+	// as it drains, and returns when the last sample has been output. Lost
+	// counts the samples the device ran out of because the host fell
+	// behind. Canceling ctx stops the output.
+	Play(ctx context.Context, cfg PlayConfig) (StreamStats, error)
 
 	// Enable starts output on the given channel (1-based).
 	Enable(channel int) error
@@ -52,19 +130,62 @@ type WavegenDriver interface {
 	// Disable stops output on the given channel (1-based).
 	Disable(channel int) error
 
+	// SetIdle sets what the given channel (1-based) outputs before and
+	// after a run, including after Disable.
+	SetIdle(channel int, idle WavegenIdle) error
+
+	// SetMaster slaves the given channel (1-based) to the state machine of
+	// master, so that it starts, runs and repeats with it. A master equal to
+	// channel makes the channel independent again.
+	SetMaster(channel, master int) error
+
 	// Close resets the wavegen for the given channel (1-based).
 	Close(channel int) error
 }
 
 // PowerSupply controls the onboard power supplies.
 type PowerSupply interface {
-	// Switch configures and enables/disables the power supplies.
+	// Switch configures and enables/disables the power supplies. With a
+	// RampTime it returns once the rails are on at 0 V while their voltage
+	// keeps rising; the next Switch or Close stops the ramp. A voltage or
+	// current limit the model does not accept is rejected with the valid
+	// range before anything is set.
 	Switch(cfg SuppliesConfig) error
 
+	// Config reads back the programmed master enable and the enable, set
+	// voltage and current limit of each rail.
+	Config() (SuppliesReadback, error)
+
+	// Rails reports the programmable supply rails of the device with the
+	// range of each settable quantity.
+	Rails() ([]SupplyRail, error)
+
+	// Status reads the supply rails and the protection flags of the device.
+	Status() (SupplyStatus, error)
+
 	// Close resets the power supply instrument.
 	Close() error
 }
 
+// AnalogIO gives raw access to the channels and nodes of the AnalogIO
+// instrument, which hold device-specific settings and monitors.
+type AnalogIO interface {
+	// Channels lists every channel with its nodes and their ranges.
+	Channels() ([]AnalogIOChannel, error)
+
+	// Read returns the configured and reported value of a node; channel and
+	// node are 0-based indexes.
+	Read(channel, node int) (AnalogIOReading, error)
+
+	// ReadStatus returns the reported values of several nodes from a single
+	// status update, so that they are sampled together.
+	ReadStatus(nodes []AnalogIONodeRef) ([]float64, error)
+
+	// Set writes the value of a node and returns the value the device
+	// applied; channel and node are 0-based indexes.
+	Set(channel, node int, value float64) (float64, error)
+}
+
 // DigitalMultimeter controls the DMM instrument (available on some devices).
 type DigitalMultimeter interface {
 	// Open initializes the DMM.
@@ -72,8 +193,14 @@ type DigitalMultimeter interface {
 
 	// Measure performs a measurement in the given mode.
 	// range_ specifies the measurement range (0 = auto).
-	// highImpedance sets 10GΩ input (true) vs 10MΩ (false) for DC voltage.
-	Measure(mode DMMMode, range_ float64, highImpedance bool) (float64, error)
+	// highImpedance sets 10GΩ input (true) vs 10MΩ (false) for DC voltage
+	// and is ignored in other modes. The mode and a non-zero range are
+	// checked against those the device reports before anything is set.
+	// The reading carries the mode, range and units read back afterwards,
+	// and the readings of the DMM's other status nodes. In temperature mode
+	// a node reporting degrees supplies the value if the measurement node
+	// does not.
+	Measure(mode DMMMode, range_ float64, highImpedance bool) (DMMReading, error)
 
 	// Close resets the DMM.
 	Close() error
@@ -81,15 +208,40 @@ type DigitalMultimeter interface {
 
 // LogicAnalyzer controls the digital input (logic analyzer) instrument.
 type LogicAnalyzer interface {
-	// Open initializes the logic analyzer with the given configuration.
+	// Open initializes the logic analyzer with the given configuration and
+	// selects the sample format that holds the requested lines.
 	Open(cfg LogicConfig) error
 
+	// Config reads back the sampling settings in effect, which differ from
+	// the requested ones where the device coerced them, such as the sample
+	// rate set by an integer clock divider.
+	Config() (LogicReadback, error)
+
+	// Timing describes the time axis of the last acquisition made by
+	// Record or Capture.
+	Timing() AcquisitionTiming
+
+	// CounterLimits returns the gate times in seconds the digital-in
+	// frequency counter accepts. Max is 0 on devices without a counter.
+	CounterLimits() (Limits, error)
+
+	// Count runs the digital-in frequency counter for one gate and returns
+	// the reading. The trigger it borrows is restored afterwards.
+	Count(ctx context.Context, cfg LogicCounterConfig) (CounterReading, error)
+
 	// SetTrigger configures the logic analyzer trigger.
 	SetTrigger(cfg LogicTriggerConfig) error
 
-	// Record captures digital samples from the specified DIO channel.
-	// Returns the recorded logic values.
-	Record(channel int) ([]uint16, error)
+	// Record captures digital samples from the specified channel, after the
+	// channel mapping of Open.
+	// Returns the recorded logic values. The acquisition is stopped and an
+	// error wrapping ctx.Err() is returned if ctx ends before it completes.
+	Record(ctx context.Context, channel int) ([]uint16, error)
+
+	// Capture acquires one buffer and returns the samples of all logic
+	// channels, so that timing between lines can be compared within a
+	// single acquisition.
+	Capture(ctx context.Context) (LogicCapture, error)
 
 	// Close resets the logic analyzer.
 	Close() error
@@ -97,8 +249,35 @@ type LogicAnalyzer interface {
 
 // PatternGenerator controls the digital output (pattern generator) instrument.
 type PatternGenerator interface {
-	// Generate starts a digital pattern on the configured channel.
-	Generate(cfg PatternConfig) error
+	// Generate starts a digital pattern on the configured channel and
+	// reports the run control it now shares with the other channels.
+	Generate(cfg PatternConfig) (PatternSettings, error)
+
+	// MaxData returns the most bits a custom pattern of the DIO channel
+	// holds.
+	MaxData(channel int) (int, error)
+
+	// Toggle outputs a square wave on one DIO channel, working out the
+	// clock divider and counter values for the requested frequency.
+	Toggle(cfg ToggleConfig) (ToggleSettings, error)
+
+	// Clocks starts free-running clocks on several DIO channels at once,
+	// each with its own frequency, duty cycle and phase, so that their
+	// edges keep a fixed relation.
+	Clocks(cfgs []ClockConfig) (ClockSettings, error)
+
+	// ROM drives output channels from a lookup table addressed by input
+	// lines, so the device acts as glue logic without the host.
+	ROM(cfg ROMConfig) (ROMSettings, error)
+
+	// Play streams cfg.Data once on the lowest DIO lines, the SDK refilling
+	// the device buffer from the host, and returns when playback has
+	// finished or ctx ends.
+	Play(ctx context.Context, cfg PatternPlayConfig) (PatternPlaySettings, error)
+
+	// SetDuty changes the duty cycle of a running pulse channel at its
+	// current frequency, without resetting the pattern generator.
+	SetDuty(channel int, dutyCycle float64) (DutySettings, error)
 
 	// Enable starts output on the given DIO channel.
 	Enable(channel int) error
@@ -106,6 +285,9 @@ type PatternGenerator interface {
 	// Disable stops output on the given DIO channel.
 	Disable(channel int) error
 
+	// Status reports the run state and the remaining repeat count.
+	Status() (PatternStatus, error)
+
 	// Close resets the pattern generator.
 	Close() error
 }
@@ -122,12 +304,42 @@ type StaticIO interface {
 	SetState(channel int, value bool) error
 
 	// SetCurrent limits the DIO output current in mA.
-	// Valid values: 2, 4, 6, 8, 12, 16 mA.
+	// Valid values on the Digital Discovery: 2, 4, 6, 8, 12, 16 mA, or 0 to
+	// follow VIO. Other values are rejected with the allowed set.
 	SetCurrent(current float64) error
 
-	// SetPull configures pull-up/pull-down for a DIO channel.
+	// SetPull configures pull-up/pull-down for a DIO channel, or for all
+	// channels when channel is -1.
 	SetPull(channel int, direction PullDirection) error
 
+	// SetVIO sets the digital I/O supply voltage in Volts (Digital Discovery).
+	// Input thresholds and output levels follow VIO.
+	SetVIO(volts float64) error
+
+	// SetThreshold sets the digital input threshold in Volts on devices
+	// where it is adjustable independently of VIO.
+	SetThreshold(volts float64) error
+
+	// Levels reads the VIO and input threshold with their ranges, and the
+	// drive current.
+	Levels() (DigitalIOLevels, error)
+
+	// SetOutput sets the drive type of a DIO line. Static I/O has no native
+	// open-drain or open-source drive, so those are emulated: an output
+	// line is driven only at its active level (low for open-drain, high
+	// for open-source) and released otherwise. The line keeps its
+	// direction: SetMode(channel, false) releases it at any level, and
+	// SetState on an input only sets the level it drives once an output.
+	SetOutput(channel int, output DigitalOutOutput) error
+
+	// ReadMasks reads the output enable, output and input state of all
+	// DIO lines at once.
+	ReadMasks() (DigitalIOMasks, error)
+
+	// WriteMasks changes the output enable and output state of the lines
+	// selected by w.Mask together, and returns the state that results.
+	WriteMasks(w DigitalIOWrite) (DigitalIOMasks, error)
+
 	// Close resets the static I/O.
 	Close() error
 }
@@ -185,6 +397,11 @@ type I2C interface {
 	// Exchange sends txData then receives rxCount bytes from the given address.
 	Exchange(txData []byte, rxCount int, address int) ([]byte, error)
 
+	// Recover frees a bus on which a target holds SDA low: it clocks SCL up
+	// to nine times until SDA is released, generates a STOP and then
+	// re-initializes the I2C engine with cfg.
+	Recover(cfg I2CConfig) (I2CRecovery, error)
+
 	// Close resets the I2C interface.
 	Close() error
 }
@@ -195,6 +412,7 @@ type DiscoveryDevice interface {
 	Scope() Oscilloscope
 	Wavegen() WavegenDriver
 	Supply() PowerSupply
+	AnalogIO() AnalogIO
 	DMM() DigitalMultimeter
 	Logic() LogicAnalyzer
 	Pattern() PatternGenerator
@@ -0,0 +1,187 @@
+package dwf
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// modelLimits lists the values a device model accepts for settings the SDK
+// would otherwise silently round or clamp. Settings a model does not list
+// are checked against the ranges its AnalogIO nodes report.
+type modelLimits struct {
+	// driveCurrents are the valid DIO drive strengths in mA; 0 lets the
+	// device choose one from VIO.
+	driveCurrents []float64
+	// supplyVoltage bounds the voltage of each supply rail, by AnalogIO
+	// label.
+	supplyVoltage map[string]valueRange
+}
+
+// valueRange is a closed interval of valid values.
+type valueRange struct {
+	min, max float64
+}
+
+var ad2Limits = modelLimits{
+	supplyVoltage: map[string]valueRange{"V+": {0.5, 5}, "V-": {-5, -0.5}},
+}
+
+// modelLimitsByName holds the limits of the models that need more than
+// their reported ranges, by device name.
+var modelLimitsByName = map[string]modelLimits{
+	"Analog Discovery 2":      ad2Limits,
+	"Analog Discovery Studio": ad2Limits,
+	"Digital Discovery": {
+		driveCurrents: []float64{0, 2, 4, 6, 8, 12, 16},
+	},
+	"Analog Discovery Pro 5250": {
+		supplyVoltage: map[string]valueRange{"p25V": {0, 25}, "n25V": {-25, 0}, "p6V": {0, 6}},
+	},
+}
+
+// model returns the name of the open device, for error messages.
+func (d *Device) model() string {
+	if d.info == nil || d.info.Name == "" {
+		return "device"
+	}
+	return d.info.Name
+}
+
+// limits returns the limits of the open device's model.
+func (d *Device) limits() modelLimits {
+	if d.info == nil {
+		return modelLimits{}
+	}
+	return modelLimitsByName[d.info.Name]
+}
+
+// check returns a descriptive error if v lies outside r.
+func (r valueRange) check(what string, v float64, units, model string) error {
+	if v < r.min || v > r.max {
+		return fmt.Errorf("%s of %g %s is outside the %g to %g %s range of the %s", what, v, units, r.min, r.max, units, model)
+	}
+	return nil
+}
+
+// oneOf returns a descriptive error if v is not one of the allowed values.
+func oneOf(what string, v float64, allowed []float64, units, model string) error {
+	if slices.Contains(allowed, v) {
+		return nil
+	}
+	values := make([]string, len(allowed))
+	for i, a := range allowed {
+		values[i] = strconv.FormatFloat(a, 'g', -1, 64)
+	}
+	return fmt.Errorf("%s of %g %s is not supported by the %s; use one of %s %s", what, v, units, model, strings.Join(values, ", "), units)
+}
+
+// nodeRange returns the settable range of a node, if the device reports one.
+func (d *Device) nodeRange(ch, node int) (valueRange, bool) {
+	lo, hi, steps, err := dwfAnalogIOChannelNodeSetInfo(d.handle, cInt(ch), cInt(node))
+	if err != nil || steps == 0 {
+		return valueRange{}, false
+	}
+	return valueRange{lo, hi}, true
+}
+
+// validate checks the rails that cfg switches on against the limits of the
+// model, or the ranges the device reports, so that a voltage or current
+// limit the SDK would clamp is rejected with the accepted range instead.
+func (s *supplyImpl) validate(cfg SuppliesConfig) error {
+	limits := s.dev.limits()
+	for _, r := range []struct {
+		name             string
+		labels           []string
+		on               bool
+		voltage, current float64
+	}{
+		{"positive supply", []string{"V+", "p25V"}, cfg.PositiveState, cfg.PositiveVoltage, cfg.PositiveCurrent},
+		{"negative supply", []string{"V-", "n25V"}, cfg.NegativeState, cfg.NegativeVoltage, cfg.NegativeCurrent},
+		{"digital supply", []string{"VDD", "p6V"}, cfg.State, cfg.Voltage, cfg.Current},
+	} {
+		if !r.on {
+			continue
+		}
+		label, ch, node, ok := s.findRailNode(r.labels, "Voltage")
+		if !ok {
+			return fmt.Errorf("the %s has no programmable %s", s.dev.model(), r.name)
+		}
+		if rng, ok := limits.supplyVoltage[label]; ok {
+			if err := rng.check(r.name+" voltage", r.voltage, "V", s.dev.model()); err != nil {
+				return err
+			}
+		} else if rng, ok := s.dev.nodeRange(ch, node); ok {
+			if err := rng.check(r.name+" voltage", r.voltage, "V", s.dev.model()); err != nil {
+				return err
+			}
+		}
+		// A zero current limit leaves the device default.
+		if r.current == 0 {
+			continue
+		}
+		_, ch, node, ok = s.findRailNode(r.labels, "Current")
+		if !ok {
+			return fmt.Errorf("the %s of the %s has no adjustable current limit", r.name, s.dev.model())
+		}
+		if rng, ok := s.dev.nodeRange(ch, node); ok {
+			if err := rng.check(r.name+" current limit", r.current, "A", s.dev.model()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findRailNode finds a node of the first of labels the device has, and
+// returns that label with the channel and node indexes.
+func (s *supplyImpl) findRailNode(labels []string, nodeName string) (string, int, int, bool) {
+	for _, label := range labels {
+		if ch, node, ok := s.findChannelNode(label, nodeName); ok {
+			return label, ch, node, true
+		}
+	}
+	return "", -1, -1, false
+}
+
+// validateDrive checks a DIO drive current in mA against the strengths of
+// the model, or the range the device reports.
+func (s *staticIOImpl) validateDrive(ch, node int, current float64) error {
+	if allowed := s.dev.limits().driveCurrents; allowed != nil {
+		return oneOf("drive current", current, allowed, "mA", s.dev.model())
+	}
+	if rng, ok := s.dev.nodeRange(ch, node); ok {
+		return rng.check("drive current", current, "mA", s.dev.model())
+	}
+	return nil
+}
+
+// validateMode checks a DMM mode against the modes the device reports, so
+// that the low- and high-current modes fail on a DMM without those inputs.
+func (m *dmmImpl) validateMode(mode DMMMode) error {
+	if m.nodes.mode < 0 {
+		return nil
+	}
+	if rng, ok := m.dev.nodeRange(m.channel, m.nodes.mode); ok && (float64(mode) < rng.min || float64(mode) > rng.max) {
+		return fmt.Errorf("DMM mode %d is not supported by the %s; use a mode from %g to %g", mode, m.dev.model(), rng.min, rng.max)
+	}
+	return nil
+}
+
+// validateRange checks a DMM range against the ranges the device reports
+// for the current mode. Auto-range (0) is always accepted.
+func (m *dmmImpl) validateRange(mode DMMMode, range_ float64) error {
+	if range_ == 0 {
+		return nil
+	}
+	rng, ok := m.dev.nodeRange(m.channel, m.nodes.rangN)
+	if !ok {
+		return nil
+	}
+	units := ""
+	if m.nodes.meas >= 0 {
+		_, units, _ = dwfAnalogIOChannelNodeName(m.dev.handle, cInt(m.channel), cInt(m.nodes.meas))
+	}
+	return rng.check(fmt.Sprintf("DMM range in mode %d", mode), range_, units, m.dev.model())
+}
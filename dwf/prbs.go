@@ -0,0 +1,34 @@
+package dwf
+
+import "fmt"
+
+// prbsTaps holds the second tap of the standard PRBS polynomials
+// x^n + x^tap + 1, by order n.
+var prbsTaps = map[int]int{7: 6, 9: 5, 11: 9, 15: 14, 20: 3, 23: 18, 31: 28}
+
+// PRBSOrders lists the supported PRBS orders.
+var PRBSOrders = []int{7, 9, 11, 15, 20, 23, 31}
+
+// PRBS returns the first n bits of the pseudo-random binary sequence of
+// the given order, one element per bit, from a Fibonacci LFSR started at
+// seed. The sequence repeats every 2^order - 1 bits, and the same order and
+// seed always give the same bits. Only the low order bits of seed are used
+// and they must not all be zero.
+func PRBS(order int, seed uint32, n int) ([]uint16, error) {
+	tap, ok := prbsTaps[order]
+	if !ok {
+		return nil, fmt.Errorf("unsupported PRBS order %d; use one of %v", order, PRBSOrders)
+	}
+	mask := uint32(1)<<order - 1
+	state := seed & mask
+	if state == 0 {
+		return nil, fmt.Errorf("PRBS%d seed must have a nonzero bit in its low %d bits", order, order)
+	}
+	bits := make([]uint16, n)
+	for i := range bits {
+		bit := (state>>(order-1) ^ state>>(tap-1)) & 1
+		state = (state<<1 | bit) & mask
+		bits[i] = uint16(bit)
+	}
+	return bits, nil
+}
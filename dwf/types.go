@@ -3,6 +3,8 @@
 // via CGo bindings to libdwf.
 package dwf
 
+import "time"
+
 // WavegenFunc enumerates analog waveform generator function types.
 type WavegenFunc int
 
@@ -18,6 +20,7 @@ const (
 	FuncTrapezium WavegenFunc = 8
 	FuncSinePower WavegenFunc = 9
 	FuncCustom    WavegenFunc = 30
+	FuncPlay      WavegenFunc = 31
 )
 
 // TriggerSource enumerates trigger source types.
@@ -41,6 +44,28 @@ const (
 	TrigSrcExternal4         TriggerSource = 14
 )
 
+// TriggerType enumerates the analog trigger detector types.
+type TriggerType int
+
+const (
+	TriggerTypeEdge   TriggerType = 0
+	TriggerTypePulse  TriggerType = 1
+	TriggerTypeWindow TriggerType = 3
+)
+
+// TriggerLengthCondition enumerates how a pulse trigger compares the pulse
+// width with TriggerConfig.Length.
+type TriggerLengthCondition int
+
+const (
+	// TriggerLengthLess triggers at the end of a pulse shorter than Length.
+	TriggerLengthLess TriggerLengthCondition = 0
+	// TriggerLengthTimeout triggers once a pulse has lasted Length.
+	TriggerLengthTimeout TriggerLengthCondition = 1
+	// TriggerLengthMore triggers at the end of a pulse longer than Length.
+	TriggerLengthMore TriggerLengthCondition = 2
+)
+
 // DMMMode enumerates digital multimeter measurement modes.
 type DMMMode int
 
@@ -59,6 +84,31 @@ const (
 	DMMModeDCHighCurrent DMMMode = 11
 )
 
+// DMMReading is a DMM measurement with the settings the device resolved.
+type DMMReading struct {
+	// Value is the measured value.
+	Value float64
+	// Mode is the mode the device measured in.
+	Mode DMMMode
+	// Range is the range the device used, resolved when auto-ranging.
+	Range float64
+	// Units are the units the device reports for Value, e.g. "V".
+	Units string
+	// Aux are the other readings the DMM reports with the measurement,
+	// such as the raw value behind it.
+	Aux []DMMAuxReading
+}
+
+// DMMAuxReading is an auxiliary DMM reading.
+type DMMAuxReading struct {
+	// Name is the name of the DMM node, e.g. "Raw".
+	Name string
+	// Value is the reading.
+	Value float64
+	// Units are the units of the reading, empty if the device names none.
+	Units string
+}
+
 // DigitalOutType enumerates pattern generator output types.
 type DigitalOutType int
 
@@ -66,6 +116,19 @@ const (
 	DigitalOutTypePulse  DigitalOutType = 0
 	DigitalOutTypeCustom DigitalOutType = 1
 	DigitalOutTypeRandom DigitalOutType = 2
+	DigitalOutTypeROM    DigitalOutType = 3
+	DigitalOutTypePlay   DigitalOutType = 5
+)
+
+// DigitalOutOutput enumerates the drive types of digital outputs. Open-drain
+// drives low and floats high, for wired-AND lines like I2C with a pull-up;
+// open-source drives high and floats low.
+type DigitalOutOutput int
+
+const (
+	DigitalOutOutputPushPull   DigitalOutOutput = 0
+	DigitalOutOutputOpenDrain  DigitalOutOutput = 1
+	DigitalOutOutputOpenSource DigitalOutOutput = 2
 )
 
 // DigitalOutIdle enumerates idle states for digital outputs.
@@ -78,6 +141,20 @@ const (
 	DigitalOutIdleZet  DigitalOutIdle = 3
 )
 
+// WavegenIdle enumerates what a wavegen output drives while not running.
+type WavegenIdle int
+
+const (
+	// WavegenIdleDisable turns the output off.
+	WavegenIdleDisable WavegenIdle = 0
+	// WavegenIdleOffset holds the configured offset voltage.
+	WavegenIdleOffset WavegenIdle = 1
+	// WavegenIdleInitial holds the first value of the waveform.
+	WavegenIdleInitial WavegenIdle = 2
+	// WavegenIdleHold holds the last value the waveform output.
+	WavegenIdleHold WavegenIdle = 3
+)
+
 // TriggerSlope enumerates trigger edge types.
 type TriggerSlope int
 
@@ -87,6 +164,41 @@ const (
 	TriggerSlopeEither TriggerSlope = 2
 )
 
+// ScopeFilter enumerates oscilloscope acquisition filters, which decide how
+// ADC conversions are reduced to samples when sampling below the ADC rate.
+type ScopeFilter int
+
+const (
+	FilterDecimate ScopeFilter = 0
+	FilterAverage  ScopeFilter = 1
+	FilterMinMax   ScopeFilter = 2
+)
+
+// AcquisitionMode enumerates how the oscilloscope fills its buffer.
+type AcquisitionMode int
+
+const (
+	// AcqModeSingle fills the buffer once per acquisition, around a trigger.
+	AcqModeSingle AcquisitionMode = 0
+	// AcqModeScanShift runs continuously and shifts new samples into the
+	// buffer like a chart recorder ("roll mode").
+	AcqModeScanShift AcquisitionMode = 1
+	// AcqModeScanScreen runs continuously and overwrites the buffer from
+	// the start once it is full, like a sweeping screen.
+	AcqModeScanScreen AcquisitionMode = 2
+	// AcqModeRecord streams samples to the host for records longer than
+	// the device buffer.
+	AcqModeRecord AcquisitionMode = 3
+)
+
+// AnalogCoupling enumerates oscilloscope input coupling modes.
+type AnalogCoupling int
+
+const (
+	CouplingDC AnalogCoupling = 0
+	CouplingAC AnalogCoupling = 1
+)
+
 // PullDirection enumerates pull-up/pull-down directions for Static I/O.
 type PullDirection int
 
@@ -118,6 +230,54 @@ type DeviceInfo struct {
 	MaxAnalogInBufferSize int
 	// MaxAnalogInResolution is the ADC bit resolution.
 	MaxAnalogInResolution int
+	// Capabilities lists the optional features of the device.
+	Capabilities DeviceCapabilities `json:"capabilities"`
+}
+
+// DeviceCapabilities tells which optional features an open device has in
+// its loaded configuration, so callers can branch on them rather than on
+// product names.
+type DeviceCapabilities struct {
+	// Config is the loaded device configuration.
+	Config int `json:"config"`
+	// HasDMM reports whether the device has a digital multimeter.
+	HasDMM bool `json:"has_dmm"`
+	// HasSupplies reports whether the device has a positive or fixed
+	// programmable supply.
+	HasSupplies bool `json:"has_supplies"`
+	// HasSuppliesNegative reports whether the device has a negative supply.
+	HasSuppliesNegative bool `json:"has_supplies_negative"`
+	// VIOAdjustable reports whether the digital I/O voltage can be set.
+	VIOAdjustable bool `json:"vio_adjustable"`
+	// MaxDigitalRate is the logic analyzer base clock in Hz, or 0 if unknown.
+	MaxDigitalRate float64 `json:"max_digital_rate"`
+	// ADCBits is the oscilloscope resolution in the loaded configuration.
+	ADCBits int `json:"adc_bits"`
+	// FirstDIO is the number of the first digital line; the Digital
+	// Discovery numbers its DIO pins from 24.
+	FirstDIO int `json:"first_dio"`
+}
+
+// FirmwareStatus describes the FPGA configuration of the open device.
+type FirmwareStatus struct {
+	// Open reports whether a device is open; the other fields are only set if so.
+	Open bool
+	// Name is the product name.
+	Name string
+	// SerialNumber is the device serial.
+	SerialNumber string
+	// HardwareRevision is the board revision reported by enumeration.
+	HardwareRevision int
+	// Config is the device configuration (FPGA image) index loaded at open.
+	Config int
+	// SDKVersion is the DWF SDK version.
+	SDKVersion string
+	// AutoConfigure reports whether setting changes are sent to the device immediately.
+	AutoConfigure bool
+	// Responsive reports whether the device answered a status request.
+	Responsive bool
+	// Error is the SDK error returned when the device did not answer.
+	Error string
 }
 
 // DeviceConfig holds information about one device configuration preset.
@@ -169,6 +329,221 @@ type ScopeConfig struct {
 	OffsetVoltage float64
 	// AmplitudeRange in Volts (default ±5 V).
 	AmplitudeRange float64
+	// Coupling is the input coupling applied to all channels (default DC).
+	Coupling AnalogCoupling
+	// Attenuation is the probe attenuation factor for all channels (e.g. 10
+	// for a 10x probe); 0 means 1x. Samples are scaled to probe-tip volts.
+	Attenuation float64
+	// Filter is the acquisition filter for all channels (default decimate).
+	Filter ScopeFilter
+	// Bandwidth is the input bandwidth limit in Hz; 0 keeps the device default.
+	Bandwidth float64
+	// AcquisitionMode decides how records are acquired (default single).
+	AcquisitionMode AcquisitionMode
+	// RecordLength is the duration of a record in seconds in record mode;
+	// 0 means one buffer's worth of samples.
+	RecordLength float64
+}
+
+// Limits is the range of values a device setting accepts.
+type Limits struct {
+	// Min is the smallest accepted value.
+	Min float64
+	// Max is the largest accepted value.
+	Max float64
+}
+
+// ScopeSettings reports the oscilloscope configuration actually applied by
+// the device after Open, together with the hardware limits.
+type ScopeSettings struct {
+	// SamplingFrequency is the achieved sampling rate in Hz.
+	SamplingFrequency float64
+	// BufferSize is the applied buffer size in samples.
+	BufferSize int
+	// Channels holds the applied settings of each channel.
+	Channels []ScopeChannelSettings
+	// FrequencyLimits is the supported sampling rate range in Hz.
+	FrequencyLimits Limits
+	// MaxBufferSize is the largest supported buffer in samples.
+	MaxBufferSize int
+	// RangeLimits is the supported input range in Volts.
+	RangeLimits Limits
+	// OffsetLimits is the supported offset range in Volts.
+	OffsetLimits Limits
+	// AcquisitionMode is the applied acquisition mode.
+	AcquisitionMode AcquisitionMode
+	// RecordLength is the record duration in seconds in record mode.
+	RecordLength float64
+}
+
+// ScopeChannelSettings holds the settings applied to one oscilloscope
+// channel.
+type ScopeChannelSettings struct {
+	// Channel is the 1-based channel number.
+	Channel int
+	// OffsetVoltage is the applied offset in Volts.
+	OffsetVoltage float64
+	// AmplitudeRange is the applied input range in Volts.
+	AmplitudeRange float64
+}
+
+// ScopeReadback holds the oscilloscope configuration read back from the
+// device, which coerces requested values to what the hardware supports.
+type ScopeReadback struct {
+	// SamplingFrequency is the applied sampling rate in Hz.
+	SamplingFrequency float64
+	// BufferSize is the applied buffer size in samples.
+	BufferSize int
+	// Channels holds the settings of each analog input channel.
+	Channels []ScopeChannelReadback
+	// Trigger holds the applied trigger settings.
+	Trigger TriggerReadback
+}
+
+// ScopeChannelReadback holds the settings applied to one analog input.
+type ScopeChannelReadback struct {
+	// Channel is the 1-based channel number.
+	Channel int
+	// Enabled reports whether the channel is acquired.
+	Enabled bool
+	// Range is the input range in Volts.
+	Range float64
+	// Offset is the input offset in Volts.
+	Offset float64
+	// Attenuation is the probe attenuation factor.
+	Attenuation float64
+	// Coupling is the input coupling; DC on devices without a choice.
+	Coupling AnalogCoupling
+	// Filter is the acquisition filter.
+	Filter ScopeFilter
+	// Bandwidth is the input bandwidth in Hz, 0 if not adjustable.
+	Bandwidth float64
+}
+
+// TriggerReadback holds the oscilloscope trigger settings applied by the
+// device.
+type TriggerReadback struct {
+	// Source is the trigger source.
+	Source TriggerSource
+	// Channel is the 1-based trigger channel.
+	Channel int
+	// Type is the trigger detector.
+	Type TriggerType
+	// Condition is the edge or pulse polarity.
+	Condition TriggerSlope
+	// Level is the trigger level in Volts.
+	Level float64
+	// Hysteresis is the trigger hysteresis in Volts.
+	Hysteresis float64
+	// Position is the horizontal position in seconds from the buffer middle.
+	Position float64
+	// HoldOff is the trigger holdoff in seconds.
+	HoldOff float64
+	// AutoTimeout is the auto-trigger timeout in seconds; 0 is disabled.
+	AutoTimeout float64
+	// Length is the pulse width in seconds of a pulse trigger.
+	Length float64
+	// LengthCondition is the pulse width comparison of a pulse trigger.
+	LengthCondition TriggerLengthCondition
+}
+
+// AveragedRecord is the point-wise average of several triggered acquisitions.
+type AveragedRecord struct {
+	// Count is the number of acquisitions averaged.
+	Count int
+	// Mean holds the averaged voltage of each sample point.
+	Mean []float64
+	// StdDev holds the standard deviation of each sample point across the
+	// acquisitions.
+	StdDev []float64
+}
+
+// PeakRecord holds a peak-detect acquisition: the minimum and maximum of the
+// raw ADC samples within each interval, so glitches shorter than the sample
+// period remain visible.
+type PeakRecord struct {
+	// Min holds the lowest voltage seen in each interval.
+	Min []float64
+	// Max holds the highest voltage seen in each interval.
+	Max []float64
+	// SamplesPerPoint is the number of buffer samples each interval spans.
+	SamplesPerPoint float64
+	// Data holds the decimated samples of the same acquisition, so the
+	// envelope can be shown around each of them.
+	Data []float64
+}
+
+// RawRecord holds an acquisition as ADC codes rather than volts, for
+// metrology work where the caller does or checks the conversion.
+type RawRecord struct {
+	// Codes holds the samples as signed 16-bit codes. The ADC result is
+	// left-aligned, so one ADC step is 2^(16-Bits) codes.
+	Codes []int16
+	// Bits is the ADC resolution.
+	Bits int
+	// VoltsPerCode converts codes to volts: v = code*VoltsPerCode + Offset.
+	VoltsPerCode float64
+	// Offset is the channel offset in Volts.
+	Offset float64
+}
+
+// CounterConfig configures the frequency counter of an analog input.
+type CounterConfig struct {
+	// Channel is the 1-based input channel.
+	Channel int
+	// GateTime is the counting interval in seconds.
+	GateTime float64
+	// Level is the threshold in Volts that the signal crosses rising once
+	// per period.
+	Level float64
+	// Hysteresis in Volts the signal must fall back below the level before
+	// the next edge counts; 0 selects the device default.
+	Hysteresis float64
+}
+
+// CounterReading is the result of one gate of the analog-in or digital-in
+// frequency counter.
+type CounterReading struct {
+	// Count is the number of counted edges during the gate.
+	Count float64
+	// Frequency in Hz as measured by the device.
+	Frequency float64
+	// GateTime is the gate time in seconds.
+	GateTime float64
+}
+
+// LogicCounterConfig configures one gate of the digital-in frequency
+// counter.
+type LogicCounterConfig struct {
+	// Channel is the logic channel whose edges are counted.
+	Channel int
+	// GateTime is the counting interval in seconds.
+	GateTime float64
+	// Edge selects the edges that count.
+	Edge TriggerSlope
+}
+
+// AcquisitionTiming describes the time axis of an acquisition: sample i was
+// taken (i - TriggerIndex) / SampleRate seconds after the trigger event.
+type AcquisitionTiming struct {
+	// SampleRate is the sampling rate in Hz.
+	SampleRate float64
+	// TriggerIndex is the sample index of the trigger event, or -1 if the
+	// acquisition was not triggered (trigger disabled or auto-triggered).
+	TriggerIndex int
+	// Time is the host time at which the acquisition completed, zero
+	// before the first one.
+	Time time.Time
+}
+
+// StreamStats summarizes a streamed (record mode) acquisition.
+type StreamStats struct {
+	// Samples is the total number of samples delivered.
+	Samples int
+	// Lost is the number of samples dropped because the host fell behind.
+	Lost int
+	// Corrupt is the number of samples that may be corrupted.
+	Corrupt int
 }
 
 // TriggerConfig configures the oscilloscope trigger.
@@ -181,12 +556,46 @@ type TriggerConfig struct {
 	Channel int
 	// Timeout is the auto-trigger timeout in seconds; 0 disables.
 	Timeout float64
-	// EdgeRising selects rising (true) or falling (false) edge.
+	// Type selects the trigger detector; the zero value is an edge trigger.
+	Type TriggerType
+	// EdgeRising selects rising (true) or falling (false) edge. For pulse
+	// triggers it selects a positive (true) or negative (false) pulse, for
+	// window triggers entering (true) or leaving (false) the window.
 	EdgeRising bool
-	// Level is the trigger level in Volts.
+	// Level is the trigger level in Volts. Window triggers ignore it.
 	Level float64
+	// WindowLow is the lower bound in Volts of a window trigger.
+	WindowLow float64
+	// WindowHigh is the upper bound in Volts of a window trigger.
+	WindowHigh float64
+	// Length is the pulse width in seconds a pulse trigger compares against.
+	Length float64
+	// LengthCondition selects how a pulse trigger compares the pulse width.
+	LengthCondition TriggerLengthCondition
+	// HoldOff is the time in seconds after a trigger during which further
+	// trigger events are ignored; 0 disables.
+	HoldOff float64
+	// Position is the horizontal trigger position in seconds, measured from
+	// the middle of the buffer as the device does: positive values move the
+	// trigger towards the start of the capture and keep less history, and 0
+	// centres the trigger. nil keeps the current position.
+	Position *float64
+	// Hysteresis in Volts the signal must move back across the level before
+	// the trigger re-arms; 0 restores the device default,
+	// TriggerHysteresisAuto derives it from the channel range and
+	// TriggerHysteresisKeep leaves the current setting. Window triggers
+	// derive it from the window bounds instead.
+	Hysteresis float64
 }
 
+const (
+	// TriggerHysteresisAuto selects a trigger hysteresis of a fixed
+	// fraction of the trigger channel's input range.
+	TriggerHysteresisAuto = -1
+	// TriggerHysteresisKeep leaves the trigger hysteresis as it is.
+	TriggerHysteresisKeep = -2
+)
+
 // WavegenConfig configures waveform generation on an analog output channel.
 type WavegenConfig struct {
 	// Channel is the wavegen channel (1 or 2).
@@ -199,8 +608,11 @@ type WavegenConfig struct {
 	Frequency float64
 	// Amplitude in Volts.
 	Amplitude float64
-	// Symmetry as percentage (0-100).
+	// Symmetry as percentage (0-100). For FuncSinePower it is the power,
+	// from -100 to 100, where 0 is a plain sine.
 	Symmetry float64
+	// Phase in degrees (0-360) at which the waveform starts.
+	Phase float64
 	// Wait time before start in seconds.
 	Wait float64
 	// RunTime in seconds; 0 means infinite.
@@ -209,6 +621,226 @@ type WavegenConfig struct {
 	Repeat int
 	// CustomData holds voltages when Function=FuncCustom.
 	CustomData []float64
+	// AM modulates the amplitude of the waveform; nil disables it.
+	AM *WavegenModulation
+	// FM modulates the frequency of the waveform; nil disables it.
+	FM *WavegenModulation
+	// TriggerSource starts the output; TrigSrcNone starts it at once and
+	// TrigSrcDetectorAnalogIn with the scope trigger.
+	TriggerSource TriggerSource
+	// TriggerSlope is the edge of the trigger source that starts the output.
+	TriggerSlope TriggerSlope
+}
+
+// PlayConfig configures the streaming of a waveform longer than the
+// wavegen buffer.
+type PlayConfig struct {
+	// Channel is the wavegen channel (1 or 2).
+	Channel int
+	// SampleRate is the output rate in samples per second.
+	SampleRate float64
+	// Amplitude in Volts scales the samples.
+	Amplitude float64
+	// Offset in Volts.
+	Offset float64
+	// Data holds the samples in the normalized range ±1, played once.
+	Data []float64
+}
+
+// WavegenModulation configures the AM or FM node of a wavegen channel.
+type WavegenModulation struct {
+	// Function is the modulating waveform.
+	Function WavegenFunc
+	// Frequency of the modulating waveform in Hz.
+	Frequency float64
+	// Depth is the modulation index for AM and the frequency deviation for
+	// FM, in percent of the carrier amplitude or frequency.
+	Depth float64
+}
+
+// WavegenSettings reports the waveform parameters actually applied by the
+// device after Generate, together with the hardware limits.
+type WavegenSettings struct {
+	// Frequency is the achieved frequency in Hz.
+	Frequency float64
+	// Amplitude is the applied amplitude in Volts.
+	Amplitude float64
+	// Offset is the applied offset in Volts.
+	Offset float64
+	// Symmetry is the applied symmetry in percent.
+	Symmetry float64
+	// Phase is the applied phase in degrees.
+	Phase float64
+	// FrequencyLimits is the supported frequency range in Hz.
+	FrequencyLimits Limits
+	// AmplitudeLimits is the supported amplitude range in Volts.
+	AmplitudeLimits Limits
+	// OffsetLimits is the supported offset range in Volts.
+	OffsetLimits Limits
+	// SymmetryLimits is the supported symmetry range in percent.
+	SymmetryLimits Limits
+	// PhaseLimits is the supported phase range in degrees.
+	PhaseLimits Limits
+	// AM is the applied amplitude modulation, nil when disabled.
+	AM *WavegenModulation
+	// FM is the applied frequency modulation, nil when disabled.
+	FM *WavegenModulation
+}
+
+// DigitalIOLevels are the I/O supply voltage, input threshold and drive
+// current of the digital lines, on devices where they are adjustable.
+type DigitalIOLevels struct {
+	// VIO is the I/O supply voltage in Volts, nil if it is not adjustable.
+	VIO *float64 `json:"vio,omitempty"`
+	// VIORange bounds the values VIO can be set to.
+	VIORange *AnalogIORange `json:"vio_range,omitempty"`
+	// Threshold is the input logic threshold in Volts, nil if it is not
+	// adjustable.
+	Threshold *float64 `json:"threshold,omitempty"`
+	// ThresholdRange bounds the values Threshold can be set to.
+	ThresholdRange *AnalogIORange `json:"threshold_range,omitempty"`
+	// Drive is the DIO drive current in mA, nil if it is not adjustable.
+	Drive *float64 `json:"drive,omitempty"`
+}
+
+// DigitalIOMasks holds the state of all DIO lines as bit masks, bit 0 for
+// the first DIO line.
+type DigitalIOMasks struct {
+	// Lines is the number of DIO lines the masks cover.
+	Lines int
+	// FirstDIO is the DIO line number of bit 0.
+	FirstDIO int
+	// OutputEnable has a bit set for each line configured as an output,
+	// including open-drain and open-source lines that are released.
+	OutputEnable uint32
+	// Output holds the level driven on each output line.
+	Output uint32
+	// Input holds the level read on each line.
+	Input uint32
+	// OpenDrain has a bit set for each line set to open-drain with
+	// SetOutput.
+	OpenDrain uint32
+	// OpenSource has a bit set for each line set to open-source with
+	// SetOutput.
+	OpenSource uint32
+}
+
+// DigitalIOWrite updates several DIO lines in one write per mask.
+type DigitalIOWrite struct {
+	// Mask selects the lines to change; the others keep their settings.
+	Mask uint32
+	// OutputEnable, if set, makes each selected line with a 1 bit an
+	// output and each with a 0 bit an input.
+	OutputEnable *uint32
+	// Output, if set, drives each selected line high or low.
+	Output *uint32
+}
+
+// AnalogIOChannel is a channel of the AnalogIO instrument, such as a supply
+// rail, the VIO reference or a system monitor.
+type AnalogIOChannel struct {
+	// Index is the 0-based channel index.
+	Index int `json:"index"`
+	// Name is the device's name for the channel, e.g. "Positive Supply".
+	Name string `json:"name"`
+	// Label is the device's short label, e.g. "V+".
+	Label string `json:"label"`
+	// Nodes are the quantities of the channel.
+	Nodes []AnalogIONode `json:"nodes"`
+}
+
+// AnalogIONode is a quantity of an AnalogIO channel.
+type AnalogIONode struct {
+	// Index is the 0-based node index within the channel.
+	Index int `json:"index"`
+	// Name is the quantity, e.g. "Enable", "Voltage" or "Temp".
+	Name string `json:"name"`
+	// Units of the values, e.g. "V".
+	Units string `json:"units"`
+	// Set bounds the values that can be written; nil if the node is read-only.
+	Set *AnalogIORange `json:"set,omitempty"`
+	// Status bounds the values read back; nil if the node cannot be read.
+	Status *AnalogIORange `json:"status,omitempty"`
+}
+
+// AnalogIORange bounds the values of an AnalogIO node.
+type AnalogIORange struct {
+	// Min and Max are the lowest and highest value.
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	// Steps is the number of distinct values between Min and Max.
+	Steps int `json:"steps"`
+}
+
+// AnalogIONodeRef identifies a node of an AnalogIO channel.
+type AnalogIONodeRef struct {
+	// Channel and Node are 0-based indexes.
+	Channel int `json:"channel"`
+	Node    int `json:"node"`
+}
+
+// AnalogIOReading is the value of an AnalogIO node.
+type AnalogIOReading struct {
+	// Set is the configured value, nil if the node is read-only.
+	Set *float64 `json:"set,omitempty"`
+	// Status is the value the device reports, nil if it reports none.
+	Status *float64 `json:"status,omitempty"`
+}
+
+// SupplyRail describes a programmable supply rail of the open device.
+type SupplyRail struct {
+	// Rail names the rail as SuppliesConfig does: positive, negative or
+	// digital.
+	Rail string `json:"rail"`
+	// Name is the device's name for the rail, e.g. "Positive Supply".
+	Name string `json:"name"`
+	// Label is the device's short label, e.g. "V+".
+	Label string `json:"label"`
+	// Nodes are the settable quantities of the rail.
+	Nodes []SupplyNode `json:"nodes"`
+}
+
+// SupplyNode is a settable quantity of a supply rail, such as its voltage.
+type SupplyNode struct {
+	// Name is the quantity, e.g. "Voltage" or "Current".
+	Name string `json:"name"`
+	// Units of Min and Max, e.g. "V".
+	Units string `json:"units"`
+	// Min and Max bound the value that can be set.
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	// Steps is the number of distinct values between Min and Max.
+	Steps int `json:"steps"`
+}
+
+// SupplyStatus is a reading of the supply rails together with the
+// protection flags the device raises.
+type SupplyStatus struct {
+	// Rails holds one entry per programmable supply rail.
+	Rails []SupplyRailStatus `json:"rails"`
+	// Faults lists the read-only protection flags that are set, such as
+	// current limiting or thermal shutdown, as "<label> <node>".
+	Faults []string `json:"faults"`
+}
+
+// SupplyRailStatus is the state of one supply rail.
+type SupplyRailStatus struct {
+	// Rail names the rail as SuppliesConfig does.
+	Rail string `json:"rail"`
+	// Label is the device's short label, e.g. "V+".
+	Label string `json:"label"`
+	// Enabled reports whether the rail is switched on, with the master
+	// enable on.
+	Enabled bool `json:"enabled"`
+	// SetVoltage is the configured voltage in Volts.
+	SetVoltage float64 `json:"set_voltage"`
+	// Voltage is the voltage the device reads back in Volts.
+	Voltage float64 `json:"voltage"`
+	// Limiting reports that the rail is current limited: the device flags
+	// it, or the enabled rail sags below 90% of its set voltage.
+	Limiting bool `json:"limiting"`
+	// Readings holds the status of every node of the rail by name.
+	Readings map[string]float64 `json:"readings"`
 }
 
 // SuppliesConfig configures the power supply voltages and states.
@@ -233,6 +865,33 @@ type SuppliesConfig struct {
 	NegativeCurrent float64
 	// Current limit for the digital/6V rail in Amps.
 	Current float64
+	// RampTime in seconds soft-starts the rails being switched on, stepping
+	// their voltage from 0 to the target in the background; 0 sets it at
+	// once.
+	RampTime float64
+}
+
+// SuppliesReadback holds the supply configuration read back from the
+// device, which keeps the settings of earlier Switch calls.
+type SuppliesReadback struct {
+	// MasterState is the master enable of all supplies.
+	MasterState bool
+	// Rails holds the programmed settings of each supply rail.
+	Rails []SupplyRailReadback
+}
+
+// SupplyRailReadback holds the programmed settings of one supply rail.
+type SupplyRailReadback struct {
+	// Rail is "positive", "negative" or "digital", as in SuppliesConfig.
+	Rail string
+	// Label is the device's label for the rail, e.g. "V+".
+	Label string
+	// Enabled is the rail's own enable.
+	Enabled bool
+	// Voltage is the set voltage in Volts.
+	Voltage float64
+	// Current is the current limit in Amps, nil if it is not adjustable.
+	Current *float64
 }
 
 // LogicConfig configures the logic analyzer before acquisition.
@@ -241,6 +900,62 @@ type LogicConfig struct {
 	SamplingFrequency float64
 	// BufferSize in samples; 0 means maximum.
 	BufferSize int
+	// Threshold is the input logic threshold in Volts on devices with an
+	// adjustable threshold, such as the Analog Discovery Pro 3X50; 0 keeps
+	// the device default.
+	Threshold float64
+	// Channels maps sample bits to DIO lines: bit N of every sample, and
+	// channel N in Record and SetTrigger, is DIO line Channels[N]. Empty
+	// keeps bit N on line N.
+	Channels []int
+	// SampleBits is the width of the samples the device records: 8, 16 or
+	// 32. 0 picks the narrowest that holds the mapped lines, or 16 without
+	// a mapping. On the Digital Discovery DIO 24-31 need 32 bits and DIO
+	// 32-39 a channel mapping.
+	SampleBits int
+	// Sync takes one sample per edge of ClockChannel instead of sampling
+	// at SamplingFrequency, so that a synchronous bus is captured at its
+	// clock edges without oversampling. The trigger detector is then busy
+	// clocking the samples.
+	Sync bool
+	// ClockChannel is the logic channel whose edges take the samples in
+	// sync mode.
+	ClockChannel int
+	// ClockEdge selects the clock edges that take a sample in sync mode.
+	ClockEdge TriggerSlope
+}
+
+// LogicCapture holds one raw logic analyzer acquisition.
+type LogicCapture struct {
+	// SampleRate is the effective sampling rate in Hz.
+	SampleRate float64
+	// SampleBits is the width of the samples the device recorded.
+	SampleBits int
+	// Sync is set when the samples were taken on clock edges; they then
+	// have no time base and SampleRate is 0.
+	Sync bool
+	// Samples holds one word per sample; bit N is the state of logic
+	// channel N, which is DIO line N without a channel mapping.
+	Samples []uint32
+}
+
+// LogicReadback holds the logic analyzer settings in effect on the device.
+type LogicReadback struct {
+	// InternalClock is the frequency of the sample clock in Hz.
+	InternalClock float64
+	// Divider is the applied divider of the internal clock.
+	Divider int
+	// SampleRate is the achieved sampling rate in Hz, 0 in sync mode.
+	SampleRate float64
+	// Sync is set when samples are taken on the edges of a logic channel.
+	Sync bool
+	// BufferSize is the applied buffer size in samples.
+	BufferSize int
+	// SampleBits is the applied sample width.
+	SampleBits int
+	// Channels is the DIO line of each logic channel, empty for the
+	// identity.
+	Channels []int
 }
 
 // LogicTriggerConfig configures the logic analyzer trigger.
@@ -261,6 +976,17 @@ type LogicTriggerConfig struct {
 	LengthMax float64
 	// Count is the trigger event counter.
 	Count int
+	// Mask selects the logic channels of a pattern trigger; bit N is
+	// channel N. When it is non-zero the trigger fires while the masked
+	// channels equal Value, and Channel and RisingEdge are ignored unless
+	// Qualified is set.
+	Mask uint32
+	// Value is the level of each masked channel for a pattern trigger.
+	Value uint32
+	// Qualified makes a pattern trigger fire on the edge of Channel
+	// selected by RisingEdge while the pattern holds, e.g. SDA falling
+	// while SCL is high for an I2C start condition.
+	Qualified bool
 }
 
 // PatternConfig configures the digital pattern generator.
@@ -273,7 +999,8 @@ type PatternConfig struct {
 	Frequency float64
 	// DutyCycle as percentage (for Pulse function).
 	DutyCycle float64
-	// Data is the custom bit pattern (for Custom function).
+	// Data is the custom bit pattern (for Custom function), one element
+	// per bit in output order; any nonzero element is a high bit.
 	Data []uint16
 	// Wait time before start in seconds.
 	Wait float64
@@ -283,6 +1010,8 @@ type PatternConfig struct {
 	RunTime int
 	// IdleState for the output when not active.
 	IdleState DigitalOutIdle
+	// Output is the drive type; the zero value is push-pull.
+	Output DigitalOutOutput
 	// TriggerEnabled includes trigger in repeat cycle.
 	TriggerEnabled bool
 	// TriggerSource for the pattern generator.
@@ -291,11 +1020,176 @@ type PatternConfig struct {
 	TriggerEdgeRising bool
 }
 
+// PatternRun is the run control of the pattern generator. The device
+// applies one run control to all channels, so generating a pattern on one
+// channel changes the timing of every other enabled channel.
+type PatternRun struct {
+	// Wait is the time before start in seconds.
+	Wait float64
+	// Repeat count; 0 means infinite.
+	Repeat int
+	// RunTime in seconds; 0 means infinite.
+	RunTime float64
+	// TriggerEnabled includes the trigger in the repeat cycle.
+	TriggerEnabled bool
+	// TriggerSource starts each repeat when TriggerEnabled is set.
+	TriggerSource TriggerSource
+	// TriggerEdgeRising selects the rising (true) or falling (false) edge.
+	TriggerEdgeRising bool
+}
+
+// PatternSettings reports the effective run control after Generate.
+type PatternSettings struct {
+	// Run is the run control now shared by all enabled channels.
+	Run PatternRun
+	// Channels lists the enabled channels, in ascending order.
+	Channels []int
+	// Conflicts describes how the run control requested for other enabled
+	// channels was overridden, e.g. "DIO 1: repeat 5 -> 0".
+	Conflicts []string
+}
+
+// ToggleConfig configures a square wave on one DIO line.
+type ToggleConfig struct {
+	// Channel is the DIO line.
+	Channel int
+	// Frequency is the square wave frequency in Hz; the line goes high and
+	// low once per period.
+	Frequency float64
+	// Count is the number of periods to output; 0 toggles until stopped.
+	Count int
+	// Output is the drive type; the zero value is push-pull.
+	Output DigitalOutOutput
+}
+
+// ToggleSettings reports the square wave started by Toggle.
+type ToggleSettings struct {
+	// Frequency is the achieved frequency in Hz.
+	Frequency float64
+	// Divider is the clock divider applied to the channel.
+	Divider int
+	// RunTime is the output duration in seconds; 0 means until stopped.
+	RunTime float64
+	// Pattern is the run control now shared by all enabled channels.
+	Pattern PatternSettings
+}
+
+// ClockConfig configures one clock output of Clocks.
+type ClockConfig struct {
+	// Channel is the DIO line.
+	Channel int
+	// Frequency is the clock frequency in Hz.
+	Frequency float64
+	// DutyCycle is the high time as a percentage of the period.
+	DutyCycle float64
+	// Phase delays the clock by this many degrees of its period, relative
+	// to a clock that rises when the outputs start.
+	Phase float64
+}
+
+// ClockOutput reports one clock started by Clocks.
+type ClockOutput struct {
+	// Channel is the DIO line.
+	Channel int
+	// Frequency is the achieved frequency in Hz.
+	Frequency float64
+	// DutyCycle is the achieved duty cycle in percent.
+	DutyCycle float64
+	// Phase is the achieved phase delay in degrees.
+	Phase float64
+	// Divider is the clock divider applied to the channel.
+	Divider int
+}
+
+// ClockSettings reports the clocks started by Clocks.
+type ClockSettings struct {
+	// Clocks holds one output per requested clock, in order.
+	Clocks []ClockOutput
+	// Pattern is the run control now shared by all enabled channels.
+	Pattern PatternSettings
+}
+
+// ROMConfig configures ROM logic: outputs driven from a lookup table
+// addressed by the state of input lines, like a small combinational logic
+// block.
+type ROMConfig struct {
+	// Inputs are the DIO lines of the table index, least significant
+	// bit first.
+	Inputs []int
+	// Outputs are the DIO lines driven from the table; bit k of each
+	// entry drives Outputs[k].
+	Outputs []int
+	// Table holds the output word of each input value, 2^len(Inputs)
+	// entries.
+	Table []uint32
+}
+
+// ROMSettings reports the ROM logic started by ROM.
+type ROMSettings struct {
+	// AddressLines is the number of DIO lines, from the first, that
+	// address the device's lookup tables; inputs must be among them.
+	AddressLines int
+	// Pattern is the run control now shared by all enabled channels.
+	Pattern PatternSettings
+}
+
+// PatternPlayConfig configures the streaming of a digital pattern longer
+// than the pattern generator buffer.
+type PatternPlayConfig struct {
+	// Lines is the number of DIO lines driven, from the first: 1, 2, 4, 8
+	// or 16. It is also the width of each sample in bits.
+	Lines int
+	// SampleRate is the output rate in samples per second.
+	SampleRate float64
+	// Data holds the samples, played once; bit k of each drives the k-th
+	// DIO line.
+	Data []uint16
+}
+
+// PatternPlaySettings reports a pattern streamed by Play.
+type PatternPlaySettings struct {
+	// Samples is the number of samples played.
+	Samples int
+	// Pattern is the run control that was shared by all enabled channels
+	// while playing.
+	Pattern PatternSettings
+}
+
+// DutySettings reports the duty cycle applied by SetDuty.
+type DutySettings struct {
+	// DutyCycle is the achieved duty cycle in percent.
+	DutyCycle float64
+	// Frequency is the pulse frequency in Hz, which SetDuty keeps.
+	Frequency float64
+	// Restarted is set when the device only takes new settings on
+	// reconfiguration, which restarted the pattern.
+	Restarted bool
+}
+
+// PatternStatus reports the run state of the pattern generator.
+type PatternStatus struct {
+	// State is ready, armed, wait, running, done, config or prefill.
+	State string
+	// WaitingForTrigger is set while the generator is armed and waits for
+	// its trigger to start the next repeat.
+	WaitingForTrigger bool
+	// TriggerEnabled reports whether each repeat waits for the trigger.
+	TriggerEnabled bool
+	// Repeat is the configured repeat count; 0 means infinite.
+	Repeat int
+	// RepeatsRemaining is the number of repeats still to run; only
+	// meaningful when Repeat is not 0.
+	RepeatsRemaining int
+	// Enabled lists the DIO lines whose output is enabled, in ascending
+	// order.
+	Enabled []int
+}
+
 // UARTConfig configures UART communication.
 type UARTConfig struct {
-	// RX is the DIO line for receiving data.
+	// RX is the DIO line for receiving data (-1 for transmit-only).
 	RX int
-	// TX is the DIO line for transmitting data.
+	// TX is the DIO line for transmitting data (-1 for receive-only).
 	TX int
 	// BaudRate in bits/s (default 9600).
 	BaudRate int
@@ -336,3 +1230,11 @@ type I2CConfig struct {
 	// Stretching enables/disables clock stretching.
 	Stretching bool
 }
+
+// I2CRecovery reports what a bus recovery had to do.
+type I2CRecovery struct {
+	// SDAStuck is true if a target held SDA low when recovery started.
+	SDAStuck bool
+	// Pulses is the number of SCL pulses clocked until SDA was released.
+	Pulses int
+}
@@ -31,14 +31,58 @@ func main() {
 	port := flag.String("port", "8080", "Listen port for sse/http transport")
 	host := flag.String("host", "0.0.0.0", "Listen host/address for sse/http transport")
 	check := flag.Bool("check", false, "Check device connectivity and print device info, then exit")
+	captureStore := flag.String("capture-store", "memory", "Where large captures are kept: memory, a directory, or s3://bucket/prefix")
+	expect := flag.String("expect", "", "Required hardware, e.g. \"model=Analog Discovery 2,analog_in=2,strict\" (overrides the config file)")
+	configFile := flag.String("config", "", "JSON configuration file (device naming rules)")
+	safety := flag.String("safety", "", "Voltage limits, e.g. \"wavegen_amplitude=2,wavegen_offset=1,supply_voltage=3.3\" (overrides the config file)")
+	usageFile := flag.String("usage-file", server.DefaultUsagePath(), "File that persists device usage statistics (empty to keep them in memory)")
 	flag.Parse()
 
+	var cfg *server.Config
+	if *configFile != "" {
+		var err error
+		if cfg, err = server.LoadConfig(*configFile); err != nil {
+			log.Fatalf("Loading config: %v", err)
+		}
+	}
+
 	if *check {
-		checkDevice()
+		checkDevice(cfg)
 		return
 	}
 
 	s := server.New()
+	if err := s.SetConfig(cfg); err != nil {
+		log.Fatalf("Applying config: %v", err)
+	}
+	if *expect != "" {
+		e, err := server.ParseExpectation(*expect)
+		if err != nil {
+			log.Fatalf("Invalid --expect: %v", err)
+		}
+		s.SetExpectation(e)
+	}
+	if *safety != "" {
+		l, err := server.ParseSafetyLimits(*safety)
+		if err != nil {
+			log.Fatalf("Invalid --safety: %v", err)
+		}
+		s.SetSafetyLimits(l)
+	}
+	if err := s.ValidateHardware(); err != nil {
+		if s.StrictHardware() {
+			log.Fatalf("Hardware check failed, refusing to serve: %v", err)
+		}
+		log.Printf("Hardware check failed, running in degraded mode: %v", err)
+	}
+	if err := s.LoadUsage(*usageFile); err != nil {
+		log.Fatalf("Loading usage statistics: %v", err)
+	}
+	store, err := server.NewCaptureStore(*captureStore)
+	if err != nil {
+		log.Fatalf("Capture store: %v", err)
+	}
+	s.SetCaptureStore(store)
 
 	switch *transport {
 	case "stdio":
@@ -99,7 +143,7 @@ func main() {
 	}
 }
 
-func checkDevice() {
+func checkDevice(cfg *server.Config) {
 	// Enumerate connected devices
 	dev := dwf.NewDevice()
 	devices, err := dev.EnumDevices()
@@ -122,6 +166,9 @@ func checkDevice() {
 		if d.UserName != "" && d.UserName != d.DeviceName {
 			name += " (" + d.UserName + ")"
 		}
+		if friendly := cfg.DeviceName(d.SerialNumber, d.DeviceName); friendly != "" {
+			name += " [" + friendly + "]"
+		}
 		fmt.Printf("  %d) %-35s  %-20s  %s\n", d.Index, name, d.SerialNumber, status)
 	}
 
@@ -159,6 +206,13 @@ func checkDevice() {
 	fmt.Printf("  Digital Out Channels:%d\n", info.DigitalOutChannels)
 	fmt.Printf("  Max Buffer Size:     %d\n", info.MaxAnalogInBufferSize)
 	fmt.Printf("  ADC Resolution:      %d bits\n", info.MaxAnalogInResolution)
+	caps := info.Capabilities
+	fmt.Printf("  DMM:                 %v\n", caps.HasDMM)
+	fmt.Printf("  Supplies:            %v (negative: %v)\n", caps.HasSupplies, caps.HasSuppliesNegative)
+	fmt.Printf("  Adjustable VIO:      %v\n", caps.VIOAdjustable)
+	if caps.MaxDigitalRate > 0 {
+		fmt.Printf("  Max Digital Rate:    %g MHz\n", caps.MaxDigitalRate/1e6)
+	}
 
 	// Print board temperature if available
 	temp, err := dev.Temperature()
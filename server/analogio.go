@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// findAnalogIONode resolves the channel and node arguments of the AnalogIO
+// tools. A channel is its 0-based index, name or label and a node its
+// 0-based index or name; names match without regard to case.
+func findAnalogIONode(channels []dwf.AnalogIOChannel, args map[string]any) (dwf.AnalogIOChannel, dwf.AnalogIONode, error) {
+	chArg, nodeArg := argKey(args["channel"]), argKey(args["node"])
+	if chArg == "" || nodeArg == "" {
+		return dwf.AnalogIOChannel{}, dwf.AnalogIONode{}, fmt.Errorf("channel and node are required")
+	}
+	for _, ch := range channels {
+		if chArg != strconv.Itoa(ch.Index) && !strings.EqualFold(chArg, ch.Name) && !strings.EqualFold(chArg, ch.Label) {
+			continue
+		}
+		for _, n := range ch.Nodes {
+			if nodeArg == strconv.Itoa(n.Index) || strings.EqualFold(nodeArg, n.Name) {
+				return ch, n, nil
+			}
+		}
+		return ch, dwf.AnalogIONode{}, fmt.Errorf("channel %q has no node %q", ch.Label, nodeArg)
+	}
+	return dwf.AnalogIOChannel{}, dwf.AnalogIONode{}, fmt.Errorf("no AnalogIO channel %q; discovery_analogio_list shows the channels", chArg)
+}
+
+// argKey returns a string or whole number argument as a string, "" if it is
+// missing or neither.
+func argKey(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		if v == float64(int(v)) {
+			return strconv.Itoa(int(v))
+		}
+	}
+	return ""
+}
+
+func (s *DiscoveryMCPServer) handleAnalogIOList(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	channels, err := s.device.AnalogIO().Channels()
+	if err != nil {
+		return errResult(err), nil
+	}
+	if channels == nil {
+		channels = []dwf.AnalogIOChannel{}
+	}
+	return jsonResult(map[string]interface{}{"channels": channels}), nil
+}
+
+func (s *DiscoveryMCPServer) handleAnalogIOGet(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	channels, err := s.device.AnalogIO().Channels()
+	if err != nil {
+		return errResult(err), nil
+	}
+	ch, node, err := findAnalogIONode(channels, argsMap(req.Params.Arguments))
+	if err != nil {
+		return errResult(err), nil
+	}
+	r, err := s.device.AnalogIO().Read(ch.Index, node.Index)
+	if err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(map[string]interface{}{
+		"channel": ch.Index,
+		"label":   ch.Label,
+		"node":    node.Name,
+		"units":   node.Units,
+		"set":     r.Set,
+		"status":  r.Status,
+	}), nil
+}
+
+func (s *DiscoveryMCPServer) handleAnalogIOSet(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	if _, ok := args["value"]; !ok {
+		return errResult(fmt.Errorf("value is required")), nil
+	}
+	value := getFloat(args, "value", 0)
+	channels, err := s.device.AnalogIO().Channels()
+	if err != nil {
+		return errResult(err), nil
+	}
+	ch, node, err := findAnalogIONode(channels, args)
+	if err != nil {
+		return errResult(err), nil
+	}
+	if node.Set == nil {
+		return errResult(fmt.Errorf("%s %s is read-only", ch.Label, node.Name)), nil
+	}
+	if value < node.Set.Min || value > node.Set.Max {
+		return errResult(fmt.Errorf("%s %s must be between %g and %g %s, got %g", ch.Label, node.Name, node.Set.Min, node.Set.Max, node.Units, value)), nil
+	}
+	// A voltage node may drive a supply rail, so the supply limit applies.
+	if s.safety != nil && strings.EqualFold(node.Name, "Voltage") {
+		if err := exceeds(ch.Label+" voltage", value, s.safety.SupplyVoltage); err != nil {
+			return errResult(err), nil
+		}
+	}
+	applied, err := s.device.AnalogIO().Set(ch.Index, node.Index, value)
+	if err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(map[string]interface{}{
+		"channel":   ch.Index,
+		"label":     ch.Label,
+		"node":      node.Name,
+		"units":     node.Units,
+		"requested": value,
+		"applied":   applied,
+	}), nil
+}
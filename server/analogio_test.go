@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func testAnalogIOChannels() []dwf.AnalogIOChannel {
+	return []dwf.AnalogIOChannel{
+		{Index: 0, Name: "Positive Supply", Label: "V+", Nodes: []dwf.AnalogIONode{
+			{Index: 0, Name: "Enable", Units: "", Set: &dwf.AnalogIORange{Min: 0, Max: 1, Steps: 2}},
+			{Index: 1, Name: "Voltage", Units: "V", Set: &dwf.AnalogIORange{Min: 0, Max: 5, Steps: 256}},
+		}},
+		{Index: 1, Name: "System", Label: "USB", Nodes: []dwf.AnalogIONode{
+			{Index: 0, Name: "Voltage", Units: "V", Status: &dwf.AnalogIORange{Min: 0, Max: 6, Steps: 4096}},
+		}},
+	}
+}
+
+func TestFindAnalogIONode(t *testing.T) {
+	channels := testAnalogIOChannels()
+	for _, tc := range []struct {
+		args    map[string]any
+		channel int
+		node    string
+		ok      bool
+	}{
+		{map[string]any{"channel": 0.0, "node": 1.0}, 0, "Voltage", true},
+		{map[string]any{"channel": "v+", "node": "enable"}, 0, "Enable", true},
+		{map[string]any{"channel": "System", "node": "0"}, 1, "Voltage", true},
+		{map[string]any{"channel": "V-", "node": "Voltage"}, 0, "", false},
+		{map[string]any{"channel": "V+", "node": "Current"}, 0, "", false},
+		{map[string]any{"channel": 0.5, "node": 0.0}, 0, "", false},
+		{map[string]any{"node": 0.0}, 0, "", false},
+	} {
+		ch, node, err := findAnalogIONode(channels, tc.args)
+		if (err == nil) != tc.ok {
+			t.Errorf("%v: err = %v, want ok %v", tc.args, err, tc.ok)
+			continue
+		}
+		if tc.ok && (ch.Index != tc.channel || node.Name != tc.node) {
+			t.Errorf("%v: got channel %d node %q, want %d %q", tc.args, ch.Index, node.Name, tc.channel, tc.node)
+		}
+	}
+}
+
+func TestHandleAnalogIOList(t *testing.T) {
+	s, dev := newTestServer()
+	dev.analogIO.channels = testAnalogIOChannels()
+
+	result, _ := s.handleAnalogIOList(context.Background(), makeReq(nil))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content[0].(mcp.TextContent).Text)
+	}
+	var got struct {
+		Channels []dwf.AnalogIOChannel `json:"channels"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Channels) != 2 || got.Channels[1].Nodes[0].Status == nil || got.Channels[1].Nodes[0].Set != nil {
+		t.Errorf("unexpected channels: %+v", got.Channels)
+	}
+}
+
+func TestHandleAnalogIOGet(t *testing.T) {
+	s, dev := newTestServer()
+	dev.analogIO.channels = testAnalogIOChannels()
+	status := 5.02
+	dev.analogIO.reading = dwf.AnalogIOReading{Status: &status}
+
+	result, _ := s.handleAnalogIOGet(context.Background(), makeReq(map[string]interface{}{"channel": "USB", "node": "Voltage"}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content[0].(mcp.TextContent).Text)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["status"] != 5.02 || got["set"] != nil || got["units"] != "V" {
+		t.Errorf("unexpected result: %v", got)
+	}
+}
+
+func TestHandleAnalogIOSet(t *testing.T) {
+	s, dev := newTestServer()
+	dev.analogIO.channels = testAnalogIOChannels()
+
+	result, _ := s.handleAnalogIOSet(context.Background(), makeReq(map[string]interface{}{"channel": "V+", "node": "Voltage", "value": 3.3}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content[0].(mcp.TextContent).Text)
+	}
+	if dev.analogIO.setChannel != 0 || dev.analogIO.setNode != 1 || dev.analogIO.setValue != 3.3 {
+		t.Errorf("Set(%d, %d, %g), want Set(0, 1, 3.3)", dev.analogIO.setChannel, dev.analogIO.setNode, dev.analogIO.setValue)
+	}
+
+	s.SetSafetyLimits(&SafetyLimits{SupplyVoltage: 3})
+	for _, tc := range []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"channel": "V+", "node": "Voltage"}, "value is required"},
+		{map[string]interface{}{"channel": "USB", "node": "Voltage", "value": 5.0}, "read-only"},
+		{map[string]interface{}{"channel": "V+", "node": "Voltage", "value": 6.0}, "between 0 and 5"},
+		{map[string]interface{}{"channel": "V+", "node": "Voltage", "value": 3.3}, "safety limit"},
+	} {
+		dev.analogIO.setValue = 0
+		result, _ := s.handleAnalogIOSet(context.Background(), makeReq(tc.args))
+		if !result.IsError {
+			t.Errorf("%v: expected error", tc.args)
+			continue
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, tc.want) {
+			t.Errorf("%v: got %q, want %q", tc.args, text, tc.want)
+		}
+		if dev.analogIO.setValue != 0 {
+			t.Errorf("%v: rejected value reached the device", tc.args)
+		}
+	}
+}
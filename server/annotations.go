@@ -0,0 +1,300 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// captureIndexName is the capture store object that holds the annotations,
+// so they live next to the captures they describe.
+const captureIndexName = "annotations.json"
+
+// captureNote is a free-text annotation of a capture.
+type captureNote struct {
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+// captureAnalysis is the outcome of running an analysis pipeline over a
+// capture.
+type captureAnalysis struct {
+	Time     time.Time                `json:"time"`
+	Pipeline string                   `json:"pipeline"`
+	Array    string                   `json:"array"`
+	Results  []map[string]interface{} `json:"results"`
+}
+
+// captureEntry describes a stored capture with its tags, notes and
+// analysis results.
+type captureEntry struct {
+	Name     string            `json:"name"`
+	Created  time.Time         `json:"created,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Notes    []captureNote     `json:"notes,omitempty"`
+	Analyses []captureAnalysis `json:"analyses,omitempty"`
+}
+
+// matches reports whether the entry carries all tags (case-insensitive) and
+// contains text in its name or notes.
+func (e *captureEntry) matches(tags []string, text string) bool {
+	for _, t := range tags {
+		if !slices.ContainsFunc(e.Tags, func(have string) bool { return strings.EqualFold(have, t) }) {
+			return false
+		}
+	}
+	if text == "" {
+		return true
+	}
+	text = strings.ToLower(text)
+	if strings.Contains(strings.ToLower(e.Name), text) {
+		return true
+	}
+	for _, n := range e.Notes {
+		if strings.Contains(strings.ToLower(n.Text), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureIndex tracks the captures saved during the session and those
+// annotated by the client. It is loaded from the capture store on first use
+// and saved back after every change.
+type captureIndex struct {
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]*captureEntry
+	now     func() time.Time
+}
+
+func newCaptureIndex() *captureIndex {
+	return &captureIndex{entries: map[string]*captureEntry{}, now: time.Now}
+}
+
+// load reads the index from store unless it already has. A missing index
+// starts empty; any other failure is returned and retried on next use, so
+// that a save never replaces an index that could not be read.
+func (ci *captureIndex) load(ctx context.Context, store CaptureStore) error {
+	if ci.loaded {
+		return nil
+	}
+	data, err := store.Get(ctx, captureIndexName)
+	if isCaptureNotFound(err) {
+		ci.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading capture annotations: %w", err)
+	}
+	var entries []*captureEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("loading capture annotations: %w", err)
+	}
+	for _, e := range entries {
+		ci.entries[e.Name] = e
+	}
+	ci.loaded = true
+	return nil
+}
+
+// ready loads the index, so that a capture is stored only if it can be
+// indexed.
+func (ci *captureIndex) ready(ctx context.Context, store CaptureStore) error {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	return ci.load(ctx, store)
+}
+
+func (ci *captureIndex) save(ctx context.Context, store CaptureStore) error {
+	data, err := json.Marshal(ci.sorted())
+	if err != nil {
+		return err
+	}
+	if err := store.Put(ctx, captureIndexName, data); err != nil {
+		return fmt.Errorf("saving capture annotations: %w", err)
+	}
+	return nil
+}
+
+// sorted returns the entries oldest first.
+func (ci *captureIndex) sorted() []*captureEntry {
+	out := make([]*captureEntry, 0, len(ci.entries))
+	for _, e := range ci.entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Created.Equal(out[j].Created) {
+			return out[i].Created.Before(out[j].Created)
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// added records a capture just saved to the store.
+func (ci *captureIndex) added(ctx context.Context, store CaptureStore, name string) error {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	if err := ci.load(ctx, store); err != nil {
+		return err
+	}
+	if _, ok := ci.entries[name]; !ok {
+		ci.entries[name] = &captureEntry{Name: name, Created: ci.now().UTC()}
+	}
+	return ci.save(ctx, store)
+}
+
+// annotate adds a note and tags to a capture, which must exist in store.
+func (ci *captureIndex) annotate(ctx context.Context, store CaptureStore, name, note string, tags []string) (captureEntry, error) {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	if err := ci.load(ctx, store); err != nil {
+		return captureEntry{}, err
+	}
+	e, ok := ci.entries[name]
+	if !ok {
+		if _, err := store.Get(ctx, name); err != nil {
+			return captureEntry{}, fmt.Errorf("capture %q not found", name)
+		}
+		e = &captureEntry{Name: name}
+		ci.entries[name] = e
+	}
+	if note != "" {
+		e.Notes = append(e.Notes, captureNote{Time: ci.now().UTC(), Text: note})
+	}
+	for _, t := range tags {
+		if !slices.ContainsFunc(e.Tags, func(have string) bool { return strings.EqualFold(have, t) }) {
+			e.Tags = append(e.Tags, t)
+		}
+	}
+	return *e, ci.save(ctx, store)
+}
+
+// analyzed attaches pipeline results to a capture, keeping the latest
+// maxCaptureAnalyses.
+func (ci *captureIndex) analyzed(ctx context.Context, store CaptureStore, name string, a captureAnalysis) error {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	if err := ci.load(ctx, store); err != nil {
+		return err
+	}
+	e, ok := ci.entries[name]
+	if !ok {
+		e = &captureEntry{Name: name}
+		ci.entries[name] = e
+	}
+	a.Time = ci.now().UTC()
+	e.Analyses = append(e.Analyses, a)
+	if n := len(e.Analyses) - maxCaptureAnalyses; n > 0 {
+		e.Analyses = slices.Delete(e.Analyses, 0, n)
+	}
+	return ci.save(ctx, store)
+}
+
+// search returns the captures that match tags and text, oldest first.
+func (ci *captureIndex) search(ctx context.Context, store CaptureStore, tags []string, text string) ([]captureEntry, error) {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	if err := ci.load(ctx, store); err != nil {
+		return nil, err
+	}
+	var out []captureEntry
+	for _, e := range ci.sorted() {
+		if e.matches(tags, text) {
+			out = append(out, *e)
+		}
+	}
+	return out, nil
+}
+
+// captureName accepts a capture name or its captures:// URI.
+func captureName(ref string) string {
+	return strings.TrimPrefix(strings.TrimSpace(ref), capturesURIPrefix)
+}
+
+// parseTags reads a tag list given as an array of strings or a
+// comma-separated string.
+func parseTags(v any) []string {
+	var raw []string
+	switch t := v.(type) {
+	case string:
+		raw = strings.Split(t, ",")
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+	var tags []string
+	for _, t := range raw {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// captureEntryResult formats an entry for a tool result.
+func captureEntryResult(e captureEntry) map[string]interface{} {
+	result := map[string]interface{}{
+		"name":  e.Name,
+		"uri":   capturesURIPrefix + e.Name,
+		"tags":  e.Tags,
+		"notes": e.Notes,
+	}
+	if e.Tags == nil {
+		result["tags"] = []string{}
+	}
+	if e.Notes == nil {
+		result["notes"] = []captureNote{}
+	}
+	if !e.Created.IsZero() {
+		result["created"] = e.Created
+	}
+	if len(e.Analyses) > 0 {
+		result["analyses"] = e.Analyses
+	}
+	return result
+}
+
+func (s *DiscoveryMCPServer) handleCaptureAnnotate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	name := captureName(getString(args, "capture", ""))
+	if err := validCaptureName(name); err != nil || name == captureIndexName {
+		return errResult(fmt.Errorf("invalid capture %q", getString(args, "capture", ""))), nil
+	}
+	note := strings.TrimSpace(getString(args, "note", ""))
+	tags := parseTags(args["tags"])
+	if note == "" && len(tags) == 0 {
+		return errResult(fmt.Errorf("give a note, tags or both")), nil
+	}
+	e, err := s.annotations.annotate(ctx, s.captures, name, note, tags)
+	if err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(captureEntryResult(e)), nil
+}
+
+func (s *DiscoveryMCPServer) handleCaptureSearch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	entries, err := s.annotations.search(ctx, s.captures, parseTags(args["tags"]), strings.TrimSpace(getString(args, "text", "")))
+	if err != nil {
+		return errResult(err), nil
+	}
+	captures := make([]map[string]interface{}, 0, len(entries))
+	for _, e := range entries {
+		captures = append(captures, captureEntryResult(e))
+	}
+	return jsonResult(map[string]interface{}{
+		"captures": captures,
+	}), nil
+}
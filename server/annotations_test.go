@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCaptureAnnotations(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.recordData = make([]float64, 8)
+
+	var uris []string
+	for range 2 {
+		result, _ := s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+			"channel":         float64(1),
+			"preview_samples": float64(2),
+		}))
+		var got struct {
+			URI string `json:"uri"`
+		}
+		json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got)
+		uris = append(uris, got.URI)
+	}
+
+	annotate := func(args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		result, err := s.handleCaptureAnnotate(context.Background(), makeReq(args))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result
+	}
+	if r := annotate(map[string]any{"capture": uris[0], "note": "VOUT ripple", "tags": []any{"before rework"}}); r.IsError {
+		t.Fatalf("annotate: %v", r.Content)
+	}
+	if r := annotate(map[string]any{"capture": uris[1], "tags": "after rework, ripple"}); r.IsError {
+		t.Fatalf("annotate: %v", r.Content)
+	}
+	if r := annotate(map[string]any{"capture": "missing.json", "note": "x"}); !r.IsError {
+		t.Error("expected error for unknown capture")
+	}
+	if r := annotate(map[string]any{"capture": uris[0]}); !r.IsError {
+		t.Error("expected error without note or tags")
+	}
+
+	search := func(args map[string]any) []string {
+		t.Helper()
+		result, _ := s.handleCaptureSearch(context.Background(), makeReq(args))
+		var got struct {
+			Captures []struct {
+				URI string `json:"uri"`
+			} `json:"captures"`
+		}
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		var out []string
+		for _, c := range got.Captures {
+			out = append(out, c.URI)
+		}
+		return out
+	}
+	if got := search(map[string]any{}); len(got) != 2 {
+		t.Errorf("all captures = %v, want 2", got)
+	}
+	if got := search(map[string]any{"tags": []any{"After Rework"}}); len(got) != 1 || got[0] != uris[1] {
+		t.Errorf("tag search = %v, want %s", got, uris[1])
+	}
+	if got := search(map[string]any{"text": "ripple"}); len(got) != 1 || got[0] != uris[0] {
+		t.Errorf("text search = %v, want %s", got, uris[0])
+	}
+
+	// The annotations are kept in the capture store and survive a restart.
+	reloaded := newCaptureIndex()
+	if got, err := reloaded.search(context.Background(), s.captures, []string{"before rework"}, ""); err != nil || len(got) != 1 || got[0].Notes[0].Text != "VOUT ripple" {
+		t.Errorf("reloaded annotations = %+v, %v", got, err)
+	}
+}
+
+// unreadableStore fails to read the annotation index, as a store that is
+// briefly unreachable would.
+type unreadableStore struct {
+	CaptureStore
+	fail bool
+}
+
+func (u *unreadableStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if u.fail && name == captureIndexName {
+		return nil, errors.New("connection refused")
+	}
+	return u.CaptureStore.Get(ctx, name)
+}
+
+func TestCaptureAnnotationsUnreadableIndex(t *testing.T) {
+	ctx := context.Background()
+	store := &unreadableStore{CaptureStore: newMemoryStore()}
+	s, _ := newTestServer()
+	s.SetCaptureStore(store)
+	if err := s.storeCapture(ctx, "a.json", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.annotations.annotate(ctx, s.captures, "a.json", "keep me", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted server that cannot read the index neither stores nor
+	// annotates, so the saved index is not replaced by an empty one.
+	store.fail = true
+	s.annotations = newCaptureIndex()
+	if err := s.storeCapture(ctx, "b.json", map[string]interface{}{}); err == nil {
+		t.Error("expected error storing a capture")
+	}
+	if _, err := store.Get(ctx, "b.json"); err == nil {
+		t.Error("capture stored although it could not be indexed")
+	}
+	result, _ := s.handleCaptureAnnotate(ctx, makeReq(map[string]any{"capture": "a.json", "note": "x"}))
+	if !result.IsError {
+		t.Error("expected error annotating")
+	}
+	if result, _ := s.handleCaptureSearch(ctx, makeReq(map[string]any{})); !result.IsError {
+		t.Error("expected error searching")
+	}
+
+	store.fail = false
+	if got, err := s.annotations.search(ctx, s.captures, nil, "keep me"); err != nil || len(got) != 1 {
+		t.Errorf("annotations after recovery = %+v, %v", got, err)
+	}
+}
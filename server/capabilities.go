@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// Instruments whose channels and buffers depend on the device configuration.
+const (
+	instrumentScope    = "scope"
+	instrumentWavegen  = "wavegen"
+	instrumentLogic    = "logic"
+	instrumentPattern  = "pattern"
+	instrumentAnalogIO = "analog_io"
+)
+
+// instrumentNeed tells what an instrument takes from a device configuration.
+type instrumentNeed struct {
+	// name is the instrument as written in messages.
+	name     string
+	channels func(dwf.DeviceConfig) int
+	// buffer is nil for instruments without a sample buffer.
+	buffer func(dwf.DeviceConfig) int
+}
+
+var instrumentNeeds = map[string]instrumentNeed{
+	instrumentScope: {
+		name:     "oscilloscope",
+		channels: func(c dwf.DeviceConfig) int { return c.AnalogInChannels },
+		buffer:   func(c dwf.DeviceConfig) int { return c.AnalogInBufferSize },
+	},
+	instrumentWavegen: {
+		name:     "waveform generator",
+		channels: func(c dwf.DeviceConfig) int { return c.AnalogOutChannels },
+		buffer:   func(c dwf.DeviceConfig) int { return c.AnalogOutBufferSize },
+	},
+	instrumentLogic: {
+		name:     "logic analyzer",
+		channels: func(c dwf.DeviceConfig) int { return c.DigitalInChannels },
+		buffer:   func(c dwf.DeviceConfig) int { return c.DigitalInBufferSize },
+	},
+	instrumentPattern: {
+		name:     "pattern generator",
+		channels: func(c dwf.DeviceConfig) int { return c.DigitalOutChannels },
+		buffer:   func(c dwf.DeviceConfig) int { return c.DigitalOutBufferSize },
+	},
+	instrumentAnalogIO: {
+		name:     "analog I/O (supplies and DMM)",
+		channels: func(c dwf.DeviceConfig) int { return c.AnalogIOChannels },
+	},
+}
+
+// deviceConfigs remembers the configurations of the open device and which
+// of them is loaded, so tools can tell when the loaded one lacks their
+// instrument.
+type deviceConfigs struct {
+	mu      sync.Mutex
+	current int
+	// configs is nil when no device is open or its configurations are unknown.
+	configs []dwf.DeviceConfig
+}
+
+func (dc *deviceConfigs) set(current int, configs []dwf.DeviceConfig) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.current, dc.configs = current, nil
+	if current >= 0 && current < len(configs) {
+		dc.configs = configs
+	}
+}
+
+// limitation reports how the loaded configuration lacks instrument, or nil
+// if it does not or nothing is known. The result names the configurations
+// that have the instrument, largest buffer first.
+func (dc *deviceConfigs) limitation(instrument string) map[string]interface{} {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	need, ok := instrumentNeeds[instrument]
+	if !ok || dc.configs == nil {
+		return nil
+	}
+	buffer := func(c dwf.DeviceConfig) int {
+		if need.buffer == nil {
+			return 0
+		}
+		return need.buffer(c)
+	}
+	usable := func(c dwf.DeviceConfig) bool {
+		return need.channels(c) > 0 && (need.buffer == nil || need.buffer(c) > 0)
+	}
+	cur := dc.configs[dc.current]
+	if usable(cur) {
+		return nil
+	}
+	lacks := "channels"
+	if need.channels(cur) > 0 {
+		lacks = "sample buffer"
+	}
+
+	var usableIdx []int
+	for i, c := range dc.configs {
+		if usable(c) {
+			usableIdx = append(usableIdx, i)
+		}
+	}
+	sort.SliceStable(usableIdx, func(i, j int) bool {
+		return buffer(dc.configs[usableIdx[i]]) > buffer(dc.configs[usableIdx[j]])
+	})
+	alternatives := make([]map[string]interface{}, 0, len(usableIdx))
+	for _, i := range usableIdx {
+		alt := map[string]interface{}{"config": i, "channels": need.channels(dc.configs[i])}
+		if need.buffer != nil {
+			alt["buffer_size"] = buffer(dc.configs[i])
+		}
+		alternatives = append(alternatives, alt)
+	}
+
+	msg := fmt.Sprintf("device configuration %d has no %s %s", dc.current, need.name, lacks)
+	result := map[string]interface{}{
+		"instrument": instrument,
+		"config":     dc.current,
+		"limitation": "no " + lacks,
+	}
+	if len(usableIdx) > 0 {
+		best := usableIdx[0]
+		msg += fmt.Sprintf("; reopen the device with discovery_device_open config=%d to use it", best)
+		result["suggested_config"] = best
+		result["alternatives"] = alternatives
+	} else {
+		msg += "; no configuration of this device has one"
+	}
+	result["error"] = msg
+	return result
+}
+
+// loadConfigs records the configurations of the device just opened with
+// the given configuration index. Without enumeration data the checks are
+// skipped.
+func (s *DiscoveryMCPServer) loadConfigs(serial string, config int) {
+	s.configs.set(-1, nil)
+	devices, err := s.device.EnumDevices()
+	if err != nil {
+		return
+	}
+	for _, d := range devices {
+		if d.SerialNumber != serial {
+			continue
+		}
+		if configs, err := s.device.EnumConfigs(d.Index); err == nil {
+			s.configs.set(config, configs)
+		}
+		return
+	}
+}
+
+// requires wraps the handler of a tool that needs instrument, so the tool
+// fails with a structured error when the loaded device configuration lacks
+// it.
+func (s *DiscoveryMCPServer) requires(instrument string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if limitation := s.configs.limitation(instrument); limitation != nil {
+			result := jsonResult(limitation)
+			result.IsError = true
+			return result, nil
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func TestRequiresInstrument(t *testing.T) {
+	s, dev := newTestServer()
+	dev.openInfo = &dwf.DeviceInfo{SerialNumber: "SN:1"}
+	dev.enumDevices = []dwf.EnumDevice{{Index: 0, SerialNumber: "SN:0"}, {Index: 1, SerialNumber: "SN:1"}}
+	dev.enumConfigs = []dwf.DeviceConfig{
+		{AnalogInChannels: 2, AnalogInBufferSize: 8192, DigitalInChannels: 16, DigitalInBufferSize: 4096},
+		{AnalogInChannels: 2, AnalogInBufferSize: 16384},
+		{AnalogInChannels: 2, DigitalInChannels: 16, DigitalInBufferSize: 16384},
+	}
+	called := 0
+	handler := s.requires(instrumentScope, func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		called++
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	// Nothing is known before a device is opened.
+	if result, _ := handler(context.Background(), makeReq(nil)); result.IsError || called != 1 {
+		t.Fatalf("unexpected result before open: %v", result.Content)
+	}
+
+	if result, _ := s.handleDeviceOpen(context.Background(), makeReq(map[string]any{"config": float64(2)})); result.IsError {
+		t.Fatalf("open: %v", result.Content)
+	}
+	if dev.configsIndex != 1 {
+		t.Errorf("configurations enumerated for device %d, want 1", dev.configsIndex)
+	}
+	result, _ := handler(context.Background(), makeReq(nil))
+	if !result.IsError || called != 1 {
+		t.Fatalf("expected a structured error, got %v", result.Content)
+	}
+	var got struct {
+		Error           string `json:"error"`
+		Limitation      string `json:"limitation"`
+		Config          int    `json:"config"`
+		SuggestedConfig int    `json:"suggested_config"`
+		Alternatives    []struct {
+			Config     int `json:"config"`
+			BufferSize int `json:"buffer_size"`
+		} `json:"alternatives"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Config != 2 || got.Limitation != "no sample buffer" || got.SuggestedConfig != 1 || len(got.Alternatives) != 2 || got.Alternatives[1].Config != 0 {
+		t.Errorf("unexpected limitation %+v", got)
+	}
+	if !strings.Contains(got.Error, "oscilloscope") || !strings.Contains(got.Error, "config=1") {
+		t.Errorf("unexpected message %q", got.Error)
+	}
+
+	// The logic analyzer has what it needs, and closing forgets the device.
+	if limitation := s.configs.limitation(instrumentLogic); limitation != nil {
+		t.Errorf("unexpected logic limitation %v", limitation)
+	}
+	s.handleDeviceClose(context.Background(), makeReq(nil))
+	if result, _ := handler(context.Background(), makeReq(nil)); result.IsError || called != 2 {
+		t.Errorf("unexpected result after close: %v", result.Content)
+	}
+}
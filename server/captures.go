@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// capturesURIPrefix is the scheme of the resources that serve stored captures.
+const capturesURIPrefix = "captures://"
+
+// CaptureStore persists captures that are too large to return inline, so
+// tools can hand the client a captures:// resource URI instead.
+type CaptureStore interface {
+	// Put stores data under name, replacing any previous capture.
+	Put(ctx context.Context, name string, data []byte) error
+	// Get returns the capture stored under name.
+	Get(ctx context.Context, name string) ([]byte, error)
+	// Location describes where the capture is kept (path or URL), for logs
+	// and tool results.
+	Location(name string) string
+}
+
+// NewCaptureStore creates a store from a specification:
+//
+//	"" or "memory"       keep captures in memory
+//	"s3://bucket/prefix" S3 or MinIO, configured by the AWS_* environment
+//	any other value      directory on the local disk
+func NewCaptureStore(spec string) (CaptureStore, error) {
+	switch {
+	case spec == "" || spec == "memory":
+		return newMemoryStore(), nil
+	case strings.HasPrefix(spec, "s3://"):
+		return newS3StoreFromEnv(spec)
+	}
+	if err := os.MkdirAll(spec, 0o755); err != nil {
+		return nil, fmt.Errorf("creating capture directory: %w", err)
+	}
+	return &dirStore{dir: spec}, nil
+}
+
+// validCaptureName rejects names that could escape the store's namespace.
+func validCaptureName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("invalid capture name %q", name)
+	}
+	return nil
+}
+
+// errCaptureNotFound is wrapped by the stores when a capture does not exist.
+var errCaptureNotFound = errors.New("not found")
+
+// isCaptureNotFound reports whether err is a store's error for a missing
+// capture.
+func isCaptureNotFound(err error) bool {
+	return errors.Is(err, errCaptureNotFound) || errors.Is(err, fs.ErrNotExist)
+}
+
+// defaultMemoryStoreBytes caps the captures kept by the memory store.
+const defaultMemoryStoreBytes = 256 << 20
+
+// memoryStore keeps captures in process memory. Once they exceed maxBytes
+// the oldest are evicted; the annotation index is always kept.
+type memoryStore struct {
+	mu       sync.Mutex
+	captures map[string][]byte
+	// order lists the capture names, oldest first.
+	order    []string
+	size     int
+	maxBytes int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{captures: map[string][]byte{}, maxBytes: defaultMemoryStoreBytes}
+}
+
+func (m *memoryStore) Put(_ context.Context, name string, data []byte) error {
+	if err := validCaptureName(name); err != nil {
+		return err
+	}
+	if len(data) > m.maxBytes {
+		return fmt.Errorf("capture of %d bytes exceeds the %d bytes kept in memory; use a directory or S3 capture store", len(data), m.maxBytes)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.captures[name]; ok {
+		m.size -= len(old)
+		m.order = slices.DeleteFunc(m.order, func(n string) bool { return n == name })
+	}
+	m.captures[name] = data
+	m.order = append(m.order, name)
+	m.size += len(data)
+	for i := 0; m.size > m.maxBytes && i < len(m.order); {
+		evict := m.order[i]
+		if evict == name || evict == captureIndexName {
+			i++
+			continue
+		}
+		m.size -= len(m.captures[evict])
+		delete(m.captures, evict)
+		m.order = slices.Delete(m.order, i, i+1)
+	}
+	return nil
+}
+
+func (m *memoryStore) Get(_ context.Context, name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.captures[name]
+	if !ok {
+		return nil, fmt.Errorf("capture %q %w", name, errCaptureNotFound)
+	}
+	return data, nil
+}
+
+func (m *memoryStore) Location(name string) string {
+	return "memory:" + name
+}
+
+// dirStore keeps captures as files in a local directory.
+type dirStore struct {
+	dir string
+}
+
+func (d *dirStore) Put(_ context.Context, name string, data []byte) error {
+	if err := validCaptureName(name); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(d.dir, name), data, 0o644)
+}
+
+func (d *dirStore) Get(_ context.Context, name string) ([]byte, error) {
+	if err := validCaptureName(name); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(d.dir, name))
+}
+
+func (d *dirStore) Location(name string) string {
+	return filepath.Join(d.dir, name)
+}
+
+// captureSeq makes capture names unique within one server run.
+var captureSeq atomic.Int64
+
+// newCaptureName returns a unique name for a capture of the given kind.
+func newCaptureName(kind string) string {
+	return fmt.Sprintf("%s-%s-%d.json", kind, time.Now().UTC().Format("20060102T150405Z"), captureSeq.Add(1))
+}
+
+// storeCapture saves a capture as JSON, with non-finite numbers as null.
+func (s *DiscoveryMCPServer) storeCapture(ctx context.Context, name string, capture map[string]interface{}) error {
+	data, err := json.Marshal(finiteValue(capture, "", map[string]bool{}))
+	if err != nil {
+		return fmt.Errorf("encoding capture: %w", err)
+	}
+	// An index that cannot be read fails the capture before it is stored.
+	if err := s.annotations.ready(ctx, s.captures); err != nil {
+		return err
+	}
+	if err := s.captures.Put(ctx, name, data); err != nil {
+		return fmt.Errorf("storing capture: %w", err)
+	}
+	// The capture is stored at this point. An index that fails to save
+	// keeps the entry in memory, and the next change of the index saves it.
+	_ = s.annotations.added(ctx, s.captures, name)
+	return nil
+}
+
+// SetCaptureStore replaces the store that holds large captures.
+func (s *DiscoveryMCPServer) SetCaptureStore(store CaptureStore) {
+	s.captures = store
+	s.annotations = newCaptureIndex()
+}
+
+func (s *DiscoveryMCPServer) handleCaptureResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	name := strings.TrimPrefix(req.Params.URI, capturesURIPrefix)
+	data, err := s.captures.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      req.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(data),
+		},
+	}, nil
+}
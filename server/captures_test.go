@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCaptureStores(t *testing.T) {
+	dir, err := NewCaptureStore(filepath.Join(t.TempDir(), "captures"))
+	if err != nil {
+		t.Fatalf("creating dir store: %v", err)
+	}
+	mem, err := NewCaptureStore("")
+	if err != nil {
+		t.Fatalf("creating memory store: %v", err)
+	}
+
+	for name, store := range map[string]CaptureStore{"dir": dir, "memory": mem} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if err := store.Put(ctx, "a.json", []byte(`{"x":1}`)); err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			got, err := store.Get(ctx, "a.json")
+			if err != nil || string(got) != `{"x":1}` {
+				t.Errorf("get = %q, %v", got, err)
+			}
+			if _, err := store.Get(ctx, "missing.json"); err == nil {
+				t.Error("expected error for missing capture")
+			}
+			for _, bad := range []string{"", "..", "../x", `a\b`} {
+				if err := store.Put(ctx, bad, nil); err == nil {
+					t.Errorf("put %q: expected error", bad)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	m := newMemoryStore()
+	m.maxBytes = 12
+	for _, name := range []string{captureIndexName, "a.json", "b.json"} {
+		if err := m.Put(ctx, name, []byte("xxxx")); err != nil {
+			t.Fatalf("put %s: %v", name, err)
+		}
+	}
+	// Replacing a capture makes it the newest, so b goes first.
+	if err := m.Put(ctx, "a.json", []byte("yy")); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if err := m.Put(ctx, "c.json", []byte("xxxx")); err != nil {
+		t.Fatalf("put c.json: %v", err)
+	}
+	for name, kept := range map[string]bool{captureIndexName: true, "a.json": true, "b.json": false, "c.json": true} {
+		if _, err := m.Get(ctx, name); (err == nil) != kept {
+			t.Errorf("%s kept = %v, want %v", name, err == nil, kept)
+		}
+	}
+	if m.size != 10 {
+		t.Errorf("size = %d, want 10", m.size)
+	}
+	if err := m.Put(ctx, "big.json", make([]byte, 13)); err == nil {
+		t.Error("expected error for a capture larger than the store")
+	}
+}
+
+func TestHandleScopeStreamStore(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.sampleRate = 1000
+	dev.scope.streamData = [][]float64{{0.1, 0.2}, {0.3}}
+
+	result, err := s.handleScopeStream(context.Background(), makeReq(map[string]any{
+		"channel":  float64(1),
+		"duration": float64(1),
+		"store":    true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !strings.HasPrefix(got.URI, capturesURIPrefix) {
+		t.Fatalf("uri = %q, want %s prefix", got.URI, capturesURIPrefix)
+	}
+
+	var req mcp.ReadResourceRequest
+	req.Params.URI = got.URI
+	contents, err := s.handleCaptureResource(context.Background(), req)
+	if err != nil {
+		t.Fatalf("reading capture: %v", err)
+	}
+	var capture struct {
+		SampleRate float64   `json:"sample_rate"`
+		Data       []float64 `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &capture); err != nil {
+		t.Fatalf("invalid capture JSON: %v", err)
+	}
+	if capture.SampleRate != 1000 || len(capture.Data) != 3 || capture.Data[2] != 0.3 {
+		t.Errorf("unexpected capture %+v", capture)
+	}
+}
+
+func TestHandleScopeRecordPreview(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.sampleRate = 1000
+	dev.scope.triggerIndex = 4
+	dev.scope.recordData = []float64{0, 1, 0, 0, 0, -2, 0, 0}
+
+	result, _ := s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+		"channel":         float64(1),
+		"preview_samples": float64(2),
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Samples         int       `json:"samples"`
+		SamplesPerPoint float64   `json:"samples_per_point"`
+		Min             []float64 `json:"min"`
+		Max             []float64 `json:"max"`
+		SampleRate      float64   `json:"sample_rate"`
+		TriggerIndex    int       `json:"trigger_index"`
+		URI             string    `json:"uri"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Samples != 8 || got.SamplesPerPoint != 4 || got.SampleRate != 250 || got.TriggerIndex != 1 {
+		t.Errorf("unexpected preview %+v", got)
+	}
+	if len(got.Min) != 2 || got.Min[0] != 0 || got.Max[0] != 1 || got.Min[1] != -2 || got.Max[1] != 0 {
+		t.Errorf("envelope = %v / %v", got.Min, got.Max)
+	}
+
+	var req mcp.ReadResourceRequest
+	req.Params.URI = got.URI
+	contents, err := s.handleCaptureResource(context.Background(), req)
+	if err != nil {
+		t.Fatalf("reading capture: %v", err)
+	}
+	var capture struct {
+		SampleRate   float64   `json:"sample_rate"`
+		TriggerIndex int       `json:"trigger_index"`
+		Data         []float64 `json:"data"`
+	}
+	json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &capture)
+	if capture.SampleRate != 1000 || capture.TriggerIndex != 4 || len(capture.Data) != 8 {
+		t.Errorf("unexpected capture %+v", capture)
+	}
+
+	result, _ = s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+		"channel":         float64(1),
+		"preview_samples": float64(100),
+	}))
+	if text := result.Content[0].(mcp.TextContent).Text; strings.Contains(text, "uri") {
+		t.Errorf("short capture should be returned in full, got %s", text)
+	}
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// Config is the server configuration loaded with --config.
+type Config struct {
+	// Devices assigns stable friendly names to devices by serial number.
+	// Rules are checked in order and the first match wins.
+	Devices []DeviceRule `json:"devices"`
+	// Expect describes the hardware the server requires.
+	Expect *DeviceExpectation `json:"expect,omitempty"`
+	// Probes defines named probe points available from startup.
+	Probes []ProbePoint `json:"probes,omitempty"`
+	// Pins names DIO lines after the signals wired to them, e.g.
+	// {"SDA": 0, "RESET_N": 5}.
+	Pins map[string]int `json:"pins,omitempty"`
+	// Pipelines defines named capture analysis pipelines.
+	Pipelines []PipelineDef `json:"pipelines,omitempty"`
+	// Safety caps the wavegen and supply voltages the tools may apply.
+	Safety *SafetyLimits `json:"safety,omitempty"`
+}
+
+// DeviceRule maps devices to a friendly name, similar to a udev rule.
+type DeviceRule struct {
+	// Serial is the serial number to match; shell-style wildcards (*, ?, [...])
+	// are allowed.
+	Serial string `json:"serial"`
+	// Product optionally restricts the rule to one product, e.g. "Analog Discovery 2".
+	Product string `json:"product,omitempty"`
+	// Name is the friendly name, e.g. "bench-left".
+	Name string `json:"name"`
+}
+
+// LoadConfig reads and validates a JSON configuration file.
+func LoadConfig(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &cfg, nil
+}
+
+// Validate checks that every rule has a serial pattern and a unique name and
+// that the probe points, pins, pipelines and safety limits are consistent.
+func (c *Config) Validate() error {
+	names := map[string]bool{}
+	for i, r := range c.Devices {
+		if r.Serial == "" || r.Name == "" {
+			return fmt.Errorf("device rule %d: serial and name are required", i)
+		}
+		if _, err := path.Match(r.Serial, ""); err != nil {
+			return fmt.Errorf("device rule %d: invalid serial pattern %q", i, r.Serial)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("device rule %d: duplicate name %q", i, r.Name)
+		}
+		names[r.Name] = true
+	}
+	if c.Expect != nil {
+		if err := c.Expect.validate(); err != nil {
+			return fmt.Errorf("expect: %w", err)
+		}
+	}
+	probes := map[string]bool{}
+	for i := range c.Probes {
+		if err := c.Probes[i].normalize(); err != nil {
+			return fmt.Errorf("probe %d: %w", i, err)
+		}
+		if probes[c.Probes[i].Name] {
+			return fmt.Errorf("probe %d: duplicate name %q", i, c.Probes[i].Name)
+		}
+		probes[c.Probes[i].Name] = true
+	}
+	pins := map[string]string{}
+	for name, line := range c.Pins {
+		if err := validatePin(strings.TrimSpace(name), line); err != nil {
+			return err
+		}
+		key := strings.ToLower(strings.TrimSpace(name))
+		if other, ok := pins[key]; ok {
+			return fmt.Errorf("pins %q and %q differ only in case", other, name)
+		}
+		pins[key] = name
+	}
+	if c.Safety != nil {
+		if err := c.Safety.validate(); err != nil {
+			return fmt.Errorf("safety: %w", err)
+		}
+	}
+	pipelines := map[string]bool{}
+	for i, p := range c.Pipelines {
+		if p.Name == "" {
+			return fmt.Errorf("pipeline %d: name is required", i)
+		}
+		if pipelines[p.Name] {
+			return fmt.Errorf("pipeline %d: duplicate name %q", i, p.Name)
+		}
+		pipelines[p.Name] = true
+		if _, err := parsePipeline(p.Stages); err != nil {
+			return fmt.Errorf("pipeline %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// DeviceName returns the friendly name of a device, or "" if no rule matches.
+func (c *Config) DeviceName(serial, product string) string {
+	if c == nil {
+		return ""
+	}
+	for _, r := range c.Devices {
+		if r.Product != "" && r.Product != product {
+			continue
+		}
+		if ok, _ := path.Match(r.Serial, serial); ok {
+			return r.Name
+		}
+	}
+	return ""
+}
+
+// hasDeviceName reports whether name is a friendly name defined by a rule.
+func (c *Config) hasDeviceName(name string) bool {
+	if c == nil {
+		return false
+	}
+	for _, r := range c.Devices {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetConfig applies a configuration loaded with LoadConfig. It fails if a
+// probe or pin of the configuration cannot be defined.
+func (s *DiscoveryMCPServer) SetConfig(cfg *Config) error {
+	s.config = cfg
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Expect != nil {
+		s.expect = cfg.Expect
+	}
+	if cfg.Safety != nil {
+		s.safety = cfg.Safety
+	}
+	for _, p := range cfg.Probes {
+		if err := s.probes.define(p); err != nil {
+			return fmt.Errorf("probe %q: %w", p.Name, err)
+		}
+	}
+	for name, line := range cfg.Pins {
+		if err := s.pins.define(Pin{Name: name, Line: line}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// namedDevice is an enumeration entry together with its friendly name.
+type namedDevice struct {
+	dwf.EnumDevice
+	FriendlyName string `json:",omitempty"`
+}
+
+// enumNamed enumerates the connected devices and applies the naming rules.
+func (s *DiscoveryMCPServer) enumNamed() ([]namedDevice, error) {
+	devices, err := s.device.EnumDevices()
+	if err != nil || devices == nil {
+		return nil, err
+	}
+	named := make([]namedDevice, len(devices))
+	for i, d := range devices {
+		named[i] = namedDevice{EnumDevice: d, FriendlyName: s.config.DeviceName(d.SerialNumber, d.DeviceName)}
+	}
+	return named, nil
+}
+
+// resolveDevice translates a friendly device name into the connected device
+// it refers to. Other values are returned unchanged with index -1.
+func (s *DiscoveryMCPServer) resolveDevice(device string) (serial string, index int, err error) {
+	if !s.config.hasDeviceName(device) {
+		return device, -1, nil
+	}
+	devices, err := s.enumNamed()
+	if err != nil {
+		return "", -1, err
+	}
+	for _, d := range devices {
+		if d.FriendlyName == device {
+			return d.SerialNumber, d.Index, nil
+		}
+	}
+	return "", -1, fmt.Errorf("no device named %q is connected", device)
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"devices":[{"serial":"SN:210321A*","name":"bench-left"},{"serial":"SN:1","name":"thermal-rig"}]}`, false},
+		{"missing name", `{"devices":[{"serial":"SN:1"}]}`, true},
+		{"duplicate name", `{"devices":[{"serial":"SN:1","name":"a"},{"serial":"SN:2","name":"a"}]}`, true},
+		{"bad pattern", `{"devices":[{"serial":"SN:[","name":"a"}]}`, true},
+		{"bad JSON", `{"devices":`, true},
+		{"pipeline", `{"pipelines":[{"name":"ripple","stages":[{"stage":"filter","window":8},{"stage":"measure"}]}]}`, false},
+		{"unknown stage", `{"pipelines":[{"name":"ripple","stages":[{"stage":"fft"}]}]}`, true},
+		{"duplicate pipeline", `{"pipelines":[{"name":"a","stages":[{"stage":"measure"}]},{"name":"a","stages":[{"stage":"measure"}]}]}`, true},
+		{"safety", `{"safety":{"wavegen_amplitude":2,"supply_voltage":3.3}}`, false},
+		{"negative safety limit", `{"safety":{"wavegen_offset":-1}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigDeviceName(t *testing.T) {
+	cfg := &Config{Devices: []DeviceRule{
+		{Serial: "SN:210321A1B2C3", Name: "bench-left"},
+		{Serial: "SN:2103*", Product: "Digital Discovery", Name: "logic-rig"},
+		{Serial: "SN:2103*", Name: "thermal-rig"},
+	}}
+	tests := []struct {
+		serial, product, want string
+	}{
+		{"SN:210321A1B2C3", "Analog Discovery 2", "bench-left"},
+		{"SN:2103FFFF", "Digital Discovery", "logic-rig"},
+		{"SN:2103FFFF", "Analog Discovery 2", "thermal-rig"},
+		{"SN:9999", "Analog Discovery 2", ""},
+	}
+	for _, tt := range tests {
+		if got := cfg.DeviceName(tt.serial, tt.product); got != tt.want {
+			t.Errorf("DeviceName(%q, %q) = %q, want %q", tt.serial, tt.product, got, tt.want)
+		}
+	}
+	var none *Config
+	if got := none.DeviceName("SN:1", ""); got != "" {
+		t.Errorf("nil config DeviceName = %q, want empty", got)
+	}
+}
+
+func newNamedTestServer() (*DiscoveryMCPServer, *mockDevice) {
+	s, dev := newTestServer()
+	s.SetConfig(&Config{Devices: []DeviceRule{
+		{Serial: "SN:AAA", Name: "bench-left"},
+		{Serial: "SN:BBB", Name: "thermal-rig"},
+	}})
+	dev.enumDevices = []dwf.EnumDevice{
+		{Index: 0, DeviceName: "Analog Discovery 2", SerialNumber: "SN:BBB"},
+		{Index: 1, DeviceName: "Analog Discovery 2", SerialNumber: "SN:CCC"},
+	}
+	return s, dev
+}
+
+func TestEnumerateFriendlyNames(t *testing.T) {
+	s, _ := newNamedTestServer()
+	result, err := s.handleEnumerate(context.Background(), makeReq(map[string]any{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got[0]["FriendlyName"] != "thermal-rig" {
+		t.Errorf("device 0 friendly name = %v, want thermal-rig", got[0]["FriendlyName"])
+	}
+	if _, ok := got[1]["FriendlyName"]; ok {
+		t.Errorf("unnamed device should not have a friendly name: %v", got[1])
+	}
+}
+
+func TestDeviceOpenByFriendlyName(t *testing.T) {
+	s, dev := newNamedTestServer()
+	dev.openInfo = &dwf.DeviceInfo{Name: "Analog Discovery 2", SerialNumber: "SN:BBB"}
+	result, err := s.handleDeviceOpen(context.Background(), makeReq(map[string]any{
+		"device": "thermal-rig",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %v", result.Content)
+	}
+	if dev.openDevice != "SN:BBB" {
+		t.Errorf("opened %q, want serial SN:BBB", dev.openDevice)
+	}
+	var got map[string]any
+	json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got)
+	if got["FriendlyName"] != "thermal-rig" {
+		t.Errorf("FriendlyName = %v, want thermal-rig", got["FriendlyName"])
+	}
+
+	// A configured name whose device is unplugged is an error, not a fallback.
+	result, _ = s.handleDeviceOpen(context.Background(), makeReq(map[string]any{
+		"device": "bench-left",
+	}))
+	if !result.IsError {
+		t.Error("expected error for disconnected named device")
+	}
+
+	// Product names pass through unchanged.
+	s.handleDeviceOpen(context.Background(), makeReq(map[string]any{
+		"device": "Analog Discovery 2",
+	}))
+	if dev.openDevice != "Analog Discovery 2" {
+		t.Errorf("opened %q, want product name", dev.openDevice)
+	}
+}
+
+func TestDeviceGetConfigsByFriendlyName(t *testing.T) {
+	s, dev := newNamedTestServer()
+	dev.enumDevices[0].SerialNumber = "SN:CCC"
+	dev.enumDevices[1].SerialNumber = "SN:BBB"
+	result, _ := s.handleDeviceGetConfigs(context.Background(), makeReq(map[string]any{
+		"device": "thermal-rig",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %v", result.Content)
+	}
+	if dev.configsIndex != 1 {
+		t.Errorf("configs index = %d, want 1", dev.configsIndex)
+	}
+
+	result, _ = s.handleDeviceGetConfigs(context.Background(), makeReq(map[string]any{
+		"device": "nope",
+	}))
+	if !result.IsError {
+		t.Error("expected error for unknown device name")
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Sample encodings accepted by the record tools. The binary encodings are
+// little-endian and base64 encoded, which takes a fraction of the tokens of
+// a JSON number array.
+const (
+	encodingJSON = "json"
+	encodingF32  = "base64_f32"
+	encodingI16  = "base64_i16"
+)
+
+// parseSampleEncoding reads the encoding argument, defaulting to json.
+func parseSampleEncoding(args any) (string, error) {
+	enc := strings.ToLower(getString(args, "encoding", encodingJSON))
+	switch enc {
+	case encodingJSON, encodingF32, encodingI16:
+		return enc, nil
+	}
+	return "", fmt.Errorf("unknown encoding %q (expected json, base64_f32 or base64_i16)", enc)
+}
+
+// encodeSamples stores data in result under key using the given encoding.
+// With base64_i16 each value is stored as round((v - offset) / scale) and
+// the scale and offset are added as key_scale and key_offset, so the client
+// recovers v = raw * scale + offset to within scale/2. Integer data that
+// fits in 16 bits is stored exactly with scale 1 and offset 0; otherwise the
+// scale spreads the data range over ±32767. Every raw value is a sample:
+// non-finite samples are stored as 0 and their indices listed in
+// key_invalid.
+func encodeSamples(result map[string]interface{}, key string, data []float64, enc string) {
+	switch enc {
+	case encodingF32:
+		buf := make([]byte, 4*len(data))
+		for i, v := range data {
+			binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(v)))
+		}
+		result[key] = base64.StdEncoding.EncodeToString(buf)
+	case encodingI16:
+		scale, offset := i16Scale(data)
+		buf := make([]byte, 2*len(data))
+		var invalid []int
+		for i, v := range data {
+			var raw int16
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				invalid = append(invalid, i)
+			} else {
+				raw = int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round((v-offset)/scale))))
+			}
+			binary.LittleEndian.PutUint16(buf[2*i:], uint16(raw))
+		}
+		result[key] = base64.StdEncoding.EncodeToString(buf)
+		result[key+"_scale"] = scale
+		result[key+"_offset"] = offset
+		if len(invalid) > 0 {
+			result[key+"_invalid"] = invalid
+		}
+	default:
+		result[key] = data
+		return
+	}
+	result["encoding"] = enc
+}
+
+// decodeSamples reverses encodeSamples for a base64 encoding. scale and
+// offset apply to base64_i16 as they do when encoding; every raw value,
+// -32768 included, is a sample.
+func decodeSamples(text, enc string, scale, offset float64) ([]float64, error) {
+	buf, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	var out []float64
+	switch enc {
+	case encodingF32:
+		if len(buf)%4 != 0 {
+			return nil, fmt.Errorf("base64_f32 data is %d bytes, not a multiple of 4", len(buf))
+		}
+		out = make([]float64, len(buf)/4)
+		for i := range out {
+			out[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:])))
+		}
+	case encodingI16:
+		if len(buf)%2 != 0 {
+			return nil, fmt.Errorf("base64_i16 data is %d bytes, not a multiple of 2", len(buf))
+		}
+		out = make([]float64, len(buf)/2)
+		for i := range out {
+			out[i] = float64(int16(binary.LittleEndian.Uint16(buf[2*i:])))*scale + offset
+		}
+	default:
+		return nil, fmt.Errorf("cannot decode encoding %q (expected base64_f32 or base64_i16)", enc)
+	}
+	return out, nil
+}
+
+// i16Scale picks the scale and offset that map the finite values of data
+// onto the 16-bit range.
+func i16Scale(data []float64) (scale, offset float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	integers := true
+	for _, v := range data {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+		integers = integers && v == math.Trunc(v)
+	}
+	if lo > hi {
+		return 1, 0
+	}
+	if integers && lo >= math.MinInt16 && hi <= math.MaxInt16 {
+		return 1, 0
+	}
+	offset = (lo + hi) / 2
+	if hi == lo {
+		return 1, offset
+	}
+	return (hi - lo) / (2 * math.MaxInt16), offset
+}
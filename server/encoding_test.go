@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestEncodeSamples(t *testing.T) {
+	data := []float64{-1.5, 0, 0.25, 2.5}
+
+	result := map[string]interface{}{}
+	encodeSamples(result, "data", data, encodingF32)
+	raw, err := base64.StdEncoding.DecodeString(result["data"].(string))
+	if err != nil || len(raw) != 16 {
+		t.Fatalf("base64_f32 = %v, %v", result["data"], err)
+	}
+	for i, want := range data {
+		if got := math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:])); float64(got) != want {
+			t.Errorf("f32 sample %d = %g, want %g", i, got, want)
+		}
+	}
+
+	result = map[string]interface{}{}
+	encodeSamples(result, "data", append(data, math.NaN()), encodingI16)
+	raw, _ = base64.StdEncoding.DecodeString(result["data"].(string))
+	scale, offset := result["data_scale"].(float64), result["data_offset"].(float64)
+	for i, want := range data {
+		got := float64(int16(binary.LittleEndian.Uint16(raw[2*i:])))*scale + offset
+		if math.Abs(got-want) > scale {
+			t.Errorf("i16 sample %d = %g, want %g", i, got, want)
+		}
+	}
+	if invalid, _ := result["data_invalid"].([]int); !slices.Equal(invalid, []int{4}) {
+		t.Errorf("data_invalid = %v, want [4]", result["data_invalid"])
+	}
+	if result["encoding"] != encodingI16 {
+		t.Errorf("encoding = %v", result["encoding"])
+	}
+
+	result = map[string]interface{}{}
+	encodeSamples(result, "data", []float64{-32768, 1, 1, 0}, encodingI16)
+	if result["data_scale"] != 1.0 || result["data_offset"] != 0.0 {
+		t.Errorf("integer data scale/offset = %v/%v, want exact", result["data_scale"], result["data_offset"])
+	}
+	if _, ok := result["data_invalid"]; ok {
+		t.Error("data_invalid set without non-finite samples")
+	}
+}
+
+func TestDecodeSamples(t *testing.T) {
+	data := []float64{-1.5, 0, 0.25, 2.5}
+	for _, enc := range []string{encodingF32, encodingI16} {
+		result := map[string]interface{}{}
+		encodeSamples(result, "data", data, enc)
+		scale, offset := 1.0, 0.0
+		if enc == encodingI16 {
+			scale, offset = result["data_scale"].(float64), result["data_offset"].(float64)
+		}
+		got, err := decodeSamples(result["data"].(string), enc, scale, offset)
+		if err != nil || len(got) != len(data) {
+			t.Fatalf("%s: decoded %v, %v", enc, got, err)
+		}
+		for i, v := range data {
+			if math.Abs(got[i]-v) > 1e-4 {
+				t.Errorf("%s: sample %d = %g, want %g", enc, i, got[i], v)
+			}
+		}
+	}
+	// The most negative int16 is a sample like any other.
+	if got, err := decodeSamples("AIA=", encodingI16, 0.5, 1); err != nil || len(got) != 1 || got[0] != -16383 {
+		t.Errorf("-32768 decoded as %v, %v; want -16383", got, err)
+	}
+	for _, bad := range []struct{ text, enc string }{
+		{"not base64!", encodingF32},
+		{"AAA=", encodingF32},
+		{"AA==", encodingI16},
+		{"AAAAAA==", encodingJSON},
+	} {
+		if _, err := decodeSamples(bad.text, bad.enc, 1, 0); err == nil {
+			t.Errorf("%q as %s: expected error", bad.text, bad.enc)
+		}
+	}
+}
+
+func TestHandleRecordEncoding(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.recordData = []float64{0.5, -0.5}
+	dev.logic.recordData = []uint16{0, 1, 1}
+
+	result, _ := s.handleScopeRecord(context.Background(), makeReq(map[string]any{"channel": float64(1), "encoding": "base64_f32"}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var scope struct {
+		Data     string `json:"data"`
+		Encoding string `json:"encoding"`
+		Samples  int    `json:"samples"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &scope); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if raw, _ := base64.StdEncoding.DecodeString(scope.Data); len(raw) != 8 || scope.Encoding != "base64_f32" || scope.Samples != 2 {
+		t.Errorf("unexpected scope result %+v", scope)
+	}
+
+	result, _ = s.handleLogicRecord(context.Background(), makeReq(map[string]any{"channel": float64(0), "encoding": "base64_i16"}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var logic struct {
+		Data  string  `json:"data"`
+		Scale float64 `json:"data_scale"`
+	}
+	json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &logic)
+	raw, _ := base64.StdEncoding.DecodeString(logic.Data)
+	if len(raw) != 6 || raw[2] != 1 || logic.Scale != 1 {
+		t.Errorf("unexpected logic result %+v", logic)
+	}
+
+	result, _ = s.handleScopeRecord(context.Background(), makeReq(map[string]any{"channel": float64(1), "encoding": "hex"}))
+	if !result.IsError {
+		t.Error("expected error for unknown encoding")
+	}
+}
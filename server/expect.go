@@ -0,0 +1,193 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// DeviceExpectation describes the hardware a deployment requires, so an
+// agent never runs a test plan against the wrong board.
+type DeviceExpectation struct {
+	// Model is the required product name, e.g. "Analog Discovery 2".
+	Model string `json:"model,omitempty"`
+	// Serial is the required serial number; shell-style wildcards are allowed.
+	Serial string `json:"serial,omitempty"`
+	// MinAnalogIn is the minimum number of oscilloscope channels.
+	MinAnalogIn int `json:"min_analog_in,omitempty"`
+	// MinAnalogOut is the minimum number of wavegen channels.
+	MinAnalogOut int `json:"min_analog_out,omitempty"`
+	// MinDigitalIn is the minimum number of logic analyzer lines.
+	MinDigitalIn int `json:"min_digital_in,omitempty"`
+	// MinDigitalOut is the minimum number of pattern generator lines.
+	MinDigitalOut int `json:"min_digital_out,omitempty"`
+	// Strict refuses to serve (at startup) or to open a mismatching device.
+	// Otherwise the server runs in degraded mode and reports the mismatches.
+	Strict bool `json:"strict,omitempty"`
+}
+
+// ParseExpectation parses the --expect flag, a comma-separated list of
+// key=value pairs: model, serial, analog_in, analog_out, digital_in,
+// digital_out, plus the bare word strict. For example:
+//
+//	model=Analog Discovery 2,analog_in=2,strict
+func ParseExpectation(spec string) (*DeviceExpectation, error) {
+	e := &DeviceExpectation{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if item == "strict" {
+			e.Strict = true
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid expectation %q: expected key=value", item)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		counts := map[string]*int{
+			"analog_in":   &e.MinAnalogIn,
+			"analog_out":  &e.MinAnalogOut,
+			"digital_in":  &e.MinDigitalIn,
+			"digital_out": &e.MinDigitalOut,
+		}
+		switch key {
+		case "model":
+			e.Model = value
+		case "serial":
+			e.Serial = value
+		default:
+			dst, ok := counts[key]
+			if !ok {
+				return nil, fmt.Errorf("unknown expectation %q", key)
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s count %q", key, value)
+			}
+			*dst = n
+		}
+	}
+	return e, e.validate()
+}
+
+func (e *DeviceExpectation) validate() error {
+	if _, err := path.Match(e.Serial, ""); err != nil {
+		return fmt.Errorf("invalid serial pattern %q", e.Serial)
+	}
+	return nil
+}
+
+// matchesIdentity reports whether a device has the expected model and serial.
+func (e *DeviceExpectation) matchesIdentity(model, serial string) bool {
+	if e.Model != "" && !strings.EqualFold(e.Model, model) {
+		return false
+	}
+	if e.Serial != "" {
+		if ok, _ := path.Match(e.Serial, serial); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// mismatches lists how an opened device differs from the expectation.
+func (e *DeviceExpectation) mismatches(info *dwf.DeviceInfo) []string {
+	var out []string
+	if e.Model != "" && !strings.EqualFold(e.Model, info.Name) {
+		out = append(out, fmt.Sprintf("model is %q, expected %q", info.Name, e.Model))
+	}
+	if e.Serial != "" {
+		if ok, _ := path.Match(e.Serial, info.SerialNumber); !ok {
+			out = append(out, fmt.Sprintf("serial is %q, expected %q", info.SerialNumber, e.Serial))
+		}
+	}
+	counts := []struct {
+		what      string
+		have, min int
+	}{
+		{"analog in channels", info.AnalogInChannels, e.MinAnalogIn},
+		{"analog out channels", info.AnalogOutChannels, e.MinAnalogOut},
+		{"digital in channels", info.DigitalInChannels, e.MinDigitalIn},
+		{"digital out channels", info.DigitalOutChannels, e.MinDigitalOut},
+	}
+	for _, c := range counts {
+		if c.have < c.min {
+			out = append(out, fmt.Sprintf("%d %s, expected at least %d", c.have, c.what, c.min))
+		}
+	}
+	return out
+}
+
+// SetExpectation sets the required hardware, replacing any expect section of
+// the configuration file.
+func (s *DiscoveryMCPServer) SetExpectation(e *DeviceExpectation) {
+	s.expect = e
+}
+
+// StrictHardware reports whether a hardware mismatch must stop the server.
+func (s *DiscoveryMCPServer) StrictHardware() bool {
+	return s.expect != nil && s.expect.Strict
+}
+
+// ValidateHardware checks at startup that a device matching the expected
+// model and serial is attached. Channel counts are checked when the device
+// is opened. Without an expectation it always succeeds. A failure puts the
+// server in degraded mode; callers should refuse to serve if Strict is set.
+func (s *DiscoveryMCPServer) ValidateHardware() error {
+	if s.expect == nil {
+		return nil
+	}
+	devices, err := s.device.EnumDevices()
+	if err != nil {
+		s.setDegraded([]string{err.Error()})
+		return fmt.Errorf("enumerating devices: %w", err)
+	}
+	for _, d := range devices {
+		if s.expect.matchesIdentity(d.DeviceName, d.SerialNumber) {
+			s.setDegraded(nil)
+			return nil
+		}
+	}
+	reason := "no attached device matches the expected hardware"
+	if s.expect.Model != "" || s.expect.Serial != "" {
+		reason = fmt.Sprintf("no attached device matches model %q serial %q", s.expect.Model, s.expect.Serial)
+	}
+	s.setDegraded([]string{reason})
+	return errors.New(reason)
+}
+
+// checkOpened validates a freshly opened device. It returns an error if the
+// device must be rejected, and otherwise records any mismatches as degraded
+// mode.
+func (s *DiscoveryMCPServer) checkOpened(info *dwf.DeviceInfo) error {
+	if s.expect == nil {
+		return nil
+	}
+	problems := s.expect.mismatches(info)
+	if len(problems) > 0 && s.expect.Strict {
+		return fmt.Errorf("device does not match the expected hardware: %s", strings.Join(problems, "; "))
+	}
+	s.setDegraded(problems)
+	return nil
+}
+
+func (s *DiscoveryMCPServer) setDegraded(problems []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.degraded = problems
+}
+
+// degradedReasons returns why the hardware does not match the expectation,
+// nil when it does.
+func (s *DiscoveryMCPServer) degradedReasons() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.degraded
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func TestParseExpectation(t *testing.T) {
+	e, err := ParseExpectation("model=Analog Discovery 2, serial=SN:2103*, analog_in=2, digital_in=16, strict")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := DeviceExpectation{Model: "Analog Discovery 2", Serial: "SN:2103*", MinAnalogIn: 2, MinDigitalIn: 16, Strict: true}
+	if *e != want {
+		t.Errorf("got %+v, want %+v", *e, want)
+	}
+
+	for _, bad := range []string{"model", "colour=red", "analog_in=two", "analog_in=-1", "serial=SN:["} {
+		if _, err := ParseExpectation(bad); err == nil {
+			t.Errorf("ParseExpectation(%q): expected error", bad)
+		}
+	}
+}
+
+func TestValidateHardware(t *testing.T) {
+	s, dev := newTestServer()
+	if err := s.ValidateHardware(); err != nil {
+		t.Errorf("no expectation: unexpected error %v", err)
+	}
+
+	dev.enumDevices = []dwf.EnumDevice{{DeviceName: "Analog Discovery 2", SerialNumber: "SN:1"}}
+	s.SetExpectation(&DeviceExpectation{Model: "Digital Discovery", Strict: true})
+	if err := s.ValidateHardware(); err == nil {
+		t.Error("expected error for missing model")
+	}
+	if !s.StrictHardware() {
+		t.Error("expected strict hardware check")
+	}
+	if len(s.degradedReasons()) == 0 {
+		t.Error("expected degraded mode after failed check")
+	}
+
+	s.SetExpectation(&DeviceExpectation{Model: "analog discovery 2", Serial: "SN:*"})
+	if err := s.ValidateHardware(); err != nil {
+		t.Errorf("matching device: unexpected error %v", err)
+	}
+	if d := s.degradedReasons(); d != nil {
+		t.Errorf("degraded = %v, want nil", d)
+	}
+}
+
+func TestDeviceOpenExpectation(t *testing.T) {
+	info := &dwf.DeviceInfo{Name: "Analog Discovery 2", SerialNumber: "SN:1", AnalogInChannels: 2, DigitalInChannels: 16}
+
+	t.Run("strict rejects", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.openInfo = info
+		s.SetExpectation(&DeviceExpectation{MinAnalogIn: 4, Strict: true})
+		result, _ := s.handleDeviceOpen(context.Background(), makeReq(map[string]any{}))
+		if !result.IsError {
+			t.Fatal("expected error for too few analog channels")
+		}
+	})
+
+	t.Run("degraded reports mismatches", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.openInfo = info
+		s.SetExpectation(&DeviceExpectation{Model: "Analog Discovery 3", MinAnalogIn: 2})
+		result, _ := s.handleDeviceOpen(context.Background(), makeReq(map[string]any{}))
+		if result.IsError {
+			t.Fatalf("unexpected tool error: %v", result.Content)
+		}
+		var got struct {
+			ExpectationMismatches []string
+		}
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if len(got.ExpectationMismatches) != 1 {
+			t.Errorf("mismatches = %v, want only the model", got.ExpectationMismatches)
+		}
+	})
+
+	t.Run("match", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.openInfo = info
+		s.SetExpectation(&DeviceExpectation{Model: "Analog Discovery 2", MinAnalogIn: 2, Strict: true})
+		result, _ := s.handleDeviceOpen(context.Background(), makeReq(map[string]any{}))
+		if result.IsError {
+			t.Fatalf("unexpected tool error: %v", result.Content)
+		}
+	})
+}
+
+func TestConfigExpectSection(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `{"expect":{"model":"Analog Discovery 2","min_analog_in":2,"strict":true}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, _ := newTestServer()
+	if err := s.SetConfig(cfg); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	if s.expect == nil || s.expect.MinAnalogIn != 2 || !s.StrictHardware() {
+		t.Errorf("expect section not applied: %+v", s.expect)
+	}
+}
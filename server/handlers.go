@@ -2,15 +2,37 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/molejar/discovery-mcp/dwf"
 )
 
+// scopeDataNotification is the method of the notifications that carry
+// streamed oscilloscope samples to the client.
+const scopeDataNotification = "notifications/discovery/scope_data"
+
+// logNotification is the MCP logging notification, used to warn the client
+// of events it did not poll for.
+const logNotification = "notifications/message"
+
+// maxScopeAverages bounds the acquisitions averaged by one scope_record call.
+const maxScopeAverages = 1000
+
 // Helper functions for parameter extraction
 
 func argsMap(args any) map[string]interface{} {
@@ -51,19 +73,461 @@ func getString(args any, key, def string) string {
 	return def
 }
 
+// getFloatList reads an array of numbers; a missing key gives nil.
+func getFloatList(args any, key string) ([]float64, error) {
+	v, ok := argsMap(args)[key]
+	if !ok {
+		return nil, nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of numbers", key)
+	}
+	out := make([]float64, 0, len(items))
+	for _, item := range items {
+		f, ok := item.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of numbers", key)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// getIntList reads an array of integers; a missing key gives nil.
+func getIntList(args any, key string) ([]int, error) {
+	values, err := getFloatList(args, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an array of integers", key)
+	}
+	if values == nil {
+		return nil, nil
+	}
+	out := make([]int, 0, len(values))
+	for _, f := range values {
+		if f != math.Trunc(f) {
+			return nil, fmt.Errorf("%s must be an array of integers", key)
+		}
+		out = append(out, int(f))
+	}
+	return out, nil
+}
+
+func parseSlope(name string) (dwf.TriggerSlope, error) {
+	switch name {
+	case "rising":
+		return dwf.TriggerSlopeRise, nil
+	case "falling":
+		return dwf.TriggerSlopeFall, nil
+	case "either":
+		return dwf.TriggerSlopeEither, nil
+	}
+	return 0, fmt.Errorf("invalid edge %q: expected rising, falling or either", name)
+}
+
+func parseWavegenIdle(name string) (dwf.WavegenIdle, error) {
+	switch strings.ToLower(name) {
+	case "disable":
+		return dwf.WavegenIdleDisable, nil
+	case "offset":
+		return dwf.WavegenIdleOffset, nil
+	case "initial":
+		return dwf.WavegenIdleInitial, nil
+	case "hold":
+		return dwf.WavegenIdleHold, nil
+	}
+	return 0, fmt.Errorf("invalid idle %q: expected disable, offset, initial or hold", name)
+}
+
+func parseOutput(name string) (dwf.DigitalOutOutput, error) {
+	switch strings.ToLower(name) {
+	case "push-pull":
+		return dwf.DigitalOutOutputPushPull, nil
+	case "open-drain":
+		return dwf.DigitalOutOutputOpenDrain, nil
+	case "open-source":
+		return dwf.DigitalOutOutputOpenSource, nil
+	}
+	return 0, fmt.Errorf("invalid output %q: expected push-pull, open-drain or open-source", name)
+}
+
+func parseDigitalIdle(name string) (dwf.DigitalOutIdle, error) {
+	switch strings.ToLower(name) {
+	case "init":
+		return dwf.DigitalOutIdleInit, nil
+	case "low":
+		return dwf.DigitalOutIdleLow, nil
+	case "high":
+		return dwf.DigitalOutIdleHigh, nil
+	case "z":
+		return dwf.DigitalOutIdleZet, nil
+	}
+	return 0, fmt.Errorf("invalid idle state %q: expected init, low, high or z", name)
+}
+
+func parseCoupling(name string) (dwf.AnalogCoupling, error) {
+	switch strings.ToLower(name) {
+	case "dc":
+		return dwf.CouplingDC, nil
+	case "ac":
+		return dwf.CouplingAC, nil
+	}
+	return 0, fmt.Errorf("invalid coupling %q: expected dc or ac", name)
+}
+
+func parseFilter(name string) (dwf.ScopeFilter, error) {
+	switch strings.ToLower(name) {
+	case "decimate":
+		return dwf.FilterDecimate, nil
+	case "average":
+		return dwf.FilterAverage, nil
+	case "minmax":
+		return dwf.FilterMinMax, nil
+	}
+	return 0, fmt.Errorf("invalid filter %q: expected decimate, average or minmax", name)
+}
+
+func parseAcquisitionMode(name string) (dwf.AcquisitionMode, error) {
+	switch strings.ToLower(name) {
+	case "single":
+		return dwf.AcqModeSingle, nil
+	case "scan_shift":
+		return dwf.AcqModeScanShift, nil
+	case "scan_screen":
+		return dwf.AcqModeScanScreen, nil
+	case "record":
+		return dwf.AcqModeRecord, nil
+	}
+	return 0, fmt.Errorf("invalid acquisition mode %q: expected single, scan_shift, scan_screen or record", name)
+}
+
+var acquisitionModeNames = map[dwf.AcquisitionMode]string{
+	dwf.AcqModeSingle:     "single",
+	dwf.AcqModeScanShift:  "scan_shift",
+	dwf.AcqModeScanScreen: "scan_screen",
+	dwf.AcqModeRecord:     "record",
+}
+
+func parseTriggerType(name string) (dwf.TriggerType, error) {
+	switch strings.ToLower(name) {
+	case "edge":
+		return dwf.TriggerTypeEdge, nil
+	case "pulse":
+		return dwf.TriggerTypePulse, nil
+	case "window":
+		return dwf.TriggerTypeWindow, nil
+	}
+	return 0, fmt.Errorf("invalid trigger type %q: expected edge, pulse or window", name)
+}
+
+func parseLengthCondition(name string) (dwf.TriggerLengthCondition, error) {
+	switch strings.ToLower(name) {
+	case "more":
+		return dwf.TriggerLengthMore, nil
+	case "less":
+		return dwf.TriggerLengthLess, nil
+	case "timeout":
+		return dwf.TriggerLengthTimeout, nil
+	}
+	return 0, fmt.Errorf("invalid length condition %q: expected more, less or timeout", name)
+}
+
+// Names of device enums in tool results, matching the parse functions.
+var (
+	slopeNames = map[dwf.TriggerSlope]string{
+		dwf.TriggerSlopeRise:   "rising",
+		dwf.TriggerSlopeFall:   "falling",
+		dwf.TriggerSlopeEither: "either",
+	}
+	couplingNames = map[dwf.AnalogCoupling]string{
+		dwf.CouplingDC: "dc",
+		dwf.CouplingAC: "ac",
+	}
+	filterNames = map[dwf.ScopeFilter]string{
+		dwf.FilterDecimate: "decimate",
+		dwf.FilterAverage:  "average",
+		dwf.FilterMinMax:   "minmax",
+	}
+	triggerTypeNames = map[dwf.TriggerType]string{
+		dwf.TriggerTypeEdge:   "edge",
+		dwf.TriggerTypePulse:  "pulse",
+		dwf.TriggerTypeWindow: "window",
+	}
+	digitalIdleNames = map[dwf.DigitalOutIdle]string{
+		dwf.DigitalOutIdleInit: "init",
+		dwf.DigitalOutIdleLow:  "low",
+		dwf.DigitalOutIdleHigh: "high",
+		dwf.DigitalOutIdleZet:  "z",
+	}
+	outputNames = map[dwf.DigitalOutOutput]string{
+		dwf.DigitalOutOutputPushPull:   "push-pull",
+		dwf.DigitalOutOutputOpenDrain:  "open-drain",
+		dwf.DigitalOutOutputOpenSource: "open-source",
+	}
+	lengthConditionNames = map[dwf.TriggerLengthCondition]string{
+		dwf.TriggerLengthMore:    "more",
+		dwf.TriggerLengthLess:    "less",
+		dwf.TriggerLengthTimeout: "timeout",
+	}
+)
+
+// enumName returns the name of v, or its number if it has none.
+func enumName[T ~int](names map[T]string, v T) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return strconv.Itoa(int(v))
+}
+
+// parseHysteresis accepts a voltage as a number or numeric string, or "auto".
+// An omitted hysteresis keeps the current setting.
+func parseHysteresis(v any) (float64, error) {
+	switch h := v.(type) {
+	case nil:
+		return dwf.TriggerHysteresisKeep, nil
+	case float64:
+		if h < 0 {
+			return 0, fmt.Errorf("hysteresis must not be negative")
+		}
+		return h, nil
+	case string:
+		if strings.EqualFold(h, "auto") {
+			return dwf.TriggerHysteresisAuto, nil
+		}
+		f, err := strconv.ParseFloat(h, 64)
+		if err != nil || f < 0 {
+			return 0, fmt.Errorf("invalid hysteresis %q: expected Volts or \"auto\"", h)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("invalid hysteresis %v: expected Volts or \"auto\"", v)
+}
+
+// parsePreTrigger accepts the signal history to keep before the trigger as
+// seconds (number or numeric string) or as a percentage of the buffer, e.g.
+// "25%", and converts it to a device trigger position for a buffer of
+// bufferTime seconds.
+func parsePreTrigger(v any, bufferTime float64) (float64, error) {
+	if bufferTime <= 0 {
+		return 0, fmt.Errorf("unknown buffer length; open the oscilloscope before setting the trigger position")
+	}
+	var pre float64
+	switch p := v.(type) {
+	case float64:
+		pre = p
+	case string:
+		p = strings.TrimSpace(p)
+		if percent, ok := strings.CutSuffix(p, "%"); ok {
+			f, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+			if err != nil || f < 0 || f > 100 {
+				return 0, fmt.Errorf("invalid position %q: expected 0%% to 100%%", p)
+			}
+			pre = f / 100 * bufferTime
+			break
+		}
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid position %q: expected seconds or a percentage", p)
+		}
+		pre = f
+	default:
+		return 0, fmt.Errorf("invalid position %v: expected seconds or a percentage", v)
+	}
+	if pre < 0 || pre > bufferTime {
+		return 0, fmt.Errorf("position %g s outside the %g s buffer", pre, bufferTime)
+	}
+	return bufferTime/2 - pre, nil
+}
+
+func parseWindow(name string) (dwf.FFTWindow, error) {
+	switch strings.ToLower(name) {
+	case "rectangular":
+		return dwf.WindowRectangular, nil
+	case "hann":
+		return dwf.WindowHann, nil
+	case "flattop":
+		return dwf.WindowFlatTop, nil
+	}
+	return 0, fmt.Errorf("invalid window %q: expected hann, flattop or rectangular", name)
+}
+
+// peakHold reduces a spectrum to at most n points, keeping the largest
+// magnitude (and its frequency) of each group of bins so peaks survive.
+func peakHold(spec dwf.Spectrum, n int) ([]float64, []float64) {
+	m := spec.Magnitudes
+	group := (len(m) + n - 1) / n
+	freqs := make([]float64, 0, n)
+	mags := make([]float64, 0, n)
+	for start := 0; start < len(m); start += group {
+		best := start
+		for k := start + 1; k < start+group && k < len(m); k++ {
+			if m[k] > m[best] {
+				best = k
+			}
+		}
+		freqs = append(freqs, float64(best)*spec.BinWidth)
+		mags = append(mags, m[best])
+	}
+	return freqs, mags
+}
+
+// defaultAcquisitionTimeout is how long, in seconds, a tool waits for each
+// triggered acquisition unless the call sets a timeout.
+const defaultAcquisitionTimeout = 10.0
+
+// acquisitionContext bounds count triggered acquisitions by the "timeout"
+// argument, in seconds per acquisition. A timeout of 0 waits indefinitely.
+func acquisitionContext(ctx context.Context, args any, count int) (context.Context, context.CancelFunc, error) {
+	timeout := getFloat(args, "timeout", defaultAcquisitionTimeout)
+	if timeout < 0 {
+		return nil, nil, fmt.Errorf("timeout must not be negative")
+	}
+	if timeout == 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout*float64(count)*float64(time.Second)))
+	return ctx, cancel, nil
+}
+
+// acquisitionError turns an expired acquisition deadline into an actionable
+// message and passes other errors through.
+func acquisitionError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errors.New("acquisition timed out waiting for the trigger; check the trigger settings and signal, or increase timeout")
+	}
+	return err
+}
+
+// jsonResult encodes v as the text of a tool result. NaN and ±Inf values,
+// which JSON cannot represent, are reported as null; when v is a map the
+// result is flagged with "quality": "non_finite" and the affected fields.
+// Only the maps and slices the handlers build are searched, so results that
+// carry measurements are built as maps.
 func jsonResult(v interface{}) *mcp.CallToolResult {
-	data, _ := json.Marshal(v)
+	bad := map[string]bool{}
+	v = finiteValue(v, "", bad)
+	if m, ok := v.(map[string]interface{}); ok && len(bad) > 0 {
+		fields := make([]string, 0, len(bad))
+		for f := range bad {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		m["quality"] = "non_finite"
+		m["non_finite_fields"] = fields
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errResult(fmt.Errorf("encoding result: %w", err))
+	}
 	return mcp.NewToolResultText(string(data))
 }
 
+// finiteValue returns v with NaN and ±Inf numbers replaced by nil, recording
+// the path of each replaced value in bad. Array indices are not part of the
+// path, so a data array with several bad samples is reported once. Maps are
+// copied rather than modified.
+func finiteValue(v interface{}, path string, bad map[string]bool) interface{} {
+	isBad := func(f float64) bool { return math.IsNaN(f) || math.IsInf(f, 0) }
+	mark := func() {
+		if path == "" {
+			bad["value"] = true
+		} else {
+			bad[path] = true
+		}
+	}
+	switch x := v.(type) {
+	case float64:
+		if isBad(x) {
+			mark()
+			return nil
+		}
+	case float32:
+		if isBad(float64(x)) {
+			mark()
+			return nil
+		}
+	case *float64:
+		if x != nil && isBad(*x) {
+			mark()
+			return nil
+		}
+	case []float64:
+		for _, f := range x {
+			if isBad(f) {
+				out := make([]interface{}, len(x))
+				for i, f := range x {
+					out[i] = finiteValue(f, path, bad)
+				}
+				return out
+			}
+		}
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = finiteValue(e, path, bad)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			out[k] = finiteValue(e, p, bad)
+		}
+		return out
+	case []map[string]interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = finiteValue(e, path, bad)
+		}
+		return out
+	}
+	return v
+}
+
 func errResult(err error) *mcp.CallToolResult {
 	return mcp.NewToolResultError(err.Error())
 }
 
+func limitsMap(l dwf.Limits) map[string]interface{} {
+	return map[string]interface{}{"min": l.Min, "max": l.Max}
+}
+
+// adjustedSettings compares requested and applied values (given as pairs)
+// and returns the ones the device changed, e.g. by rounding to the nearest
+// achievable frequency or clamping to a hardware limit.
+func adjustedSettings(pairs map[string][2]float64) map[string]interface{} {
+	adjusted := map[string]interface{}{}
+	for name, p := range pairs {
+		requested, applied := p[0], p[1]
+		tolerance := 1e-9 * math.Max(math.Abs(requested), math.Abs(applied))
+		if math.Abs(requested-applied) > tolerance {
+			adjusted[name] = map[string]interface{}{
+				"requested": requested,
+				"applied":   applied,
+			}
+		}
+	}
+	return adjusted
+}
+
+// modulationMap describes an applied modulation, naming its depth as AM
+// and FM call it.
+func modulationMap(m *dwf.WavegenModulation, depth string) map[string]interface{} {
+	return map[string]interface{}{
+		"function":  int(m.Function),
+		"frequency": m.Frequency,
+		depth:       m.Depth,
+	}
+}
+
 // ==================== Device Handlers ====================
 
 func (s *DiscoveryMCPServer) handleEnumerate(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	devices, err := s.device.EnumDevices()
+	devices, err := s.enumNamed()
 	if err != nil {
 		return errResult(err), nil
 	}
@@ -72,6 +536,16 @@ func (s *DiscoveryMCPServer) handleEnumerate(_ context.Context, _ mcp.CallToolRe
 
 func (s *DiscoveryMCPServer) handleDeviceGetConfigs(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	idx := getInt(req.Params.Arguments, "device_index", 0)
+	if name := getString(req.Params.Arguments, "device", ""); name != "" {
+		_, i, err := s.resolveDevice(name)
+		if err != nil {
+			return errResult(err), nil
+		}
+		if i < 0 {
+			return errResult(fmt.Errorf("unknown device name %q", name)), nil
+		}
+		idx = i
+	}
 	configs, err := s.device.EnumConfigs(idx)
 	if err != nil {
 		return errResult(err), nil
@@ -83,41 +557,212 @@ func (s *DiscoveryMCPServer) handleDeviceOpen(_ context.Context, req mcp.CallToo
 	device := getString(req.Params.Arguments, "device", "")
 	config := getInt(req.Params.Arguments, "config", 0)
 
-	info, err := s.device.Open(device, config)
+	target, _, err := s.resolveDevice(device)
+	if err != nil {
+		return errResult(err), nil
+	}
+	info, err := s.device.Open(target, config)
 	if err != nil {
 		return errResult(err), nil
 	}
-	return jsonResult(info), nil
+	if info == nil {
+		info = &dwf.DeviceInfo{}
+	}
+	if err := s.checkOpened(info); err != nil {
+		_ = s.device.Close()
+		return errResult(err), nil
+	}
+	s.usage.opened(info.SerialNumber)
+	s.loadConfigs(info.SerialNumber, config)
+	name := s.config.DeviceName(info.SerialNumber, info.Name)
+	label := name
+	if label == "" {
+		label = info.SerialNumber
+	}
+	log.Printf("Opened device %s (%s, %s)", label, info.Name, info.SerialNumber)
+	degraded := s.degradedReasons()
+	for _, problem := range degraded {
+		log.Printf("Degraded mode: %s", problem)
+	}
+	return jsonResult(struct {
+		*dwf.DeviceInfo
+		FriendlyName          string   `json:",omitempty"`
+		ExpectationMismatches []string `json:",omitempty"`
+	}{info, name, degraded}), nil
 }
 
 func (s *DiscoveryMCPServer) handleDeviceClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if err := s.device.Close(); err != nil {
 		return errResult(err), nil
 	}
+	s.watchdog.disarm()
+	s.usage.closed()
+	s.configs.set(-1, nil)
 	return mcp.NewToolResultText("Device closed"), nil
 }
 
+func (s *DiscoveryMCPServer) handleDeviceStatus(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	status, err := s.device.Status()
+	if err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(status), nil
+}
+
+func (s *DiscoveryMCPServer) handleDeviceReinitialize(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	reprogram := getBool(req.Params.Arguments, "reprogram", false)
+	info, err := s.device.Reinitialize(reprogram)
+	if err != nil {
+		return errResult(err), nil
+	}
+	// both a reset and a reload switch the supplies off
+	s.usage.supply(false)
+	if reprogram {
+		log.Printf("Device %s reprogrammed", info.SerialNumber)
+		if err := s.checkOpened(info); err != nil {
+			_ = s.device.Close()
+			return errResult(err), nil
+		}
+		return jsonResult(info), nil
+	}
+	return mcp.NewToolResultText("All instruments reset to defaults; reopen instruments before use"), nil
+}
+
 func (s *DiscoveryMCPServer) handleDeviceTemperature(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	temp, err := s.device.Temperature()
 	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("%.2f °C", temp)), nil
+	return mcp.NewToolResultText(s.format.quantity(temp, "°C", "%.2f °C")), nil
 }
 
 // ==================== Oscilloscope Handlers ====================
 
 func (s *DiscoveryMCPServer) handleScopeOpen(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	coupling, err := parseCoupling(getString(req.Params.Arguments, "coupling", "dc"))
+	if err != nil {
+		return errResult(err), nil
+	}
+	filter, err := parseFilter(getString(req.Params.Arguments, "filter", "decimate"))
+	if err != nil {
+		return errResult(err), nil
+	}
+	mode, err := parseAcquisitionMode(getString(req.Params.Arguments, "acquisition_mode", "single"))
+	if err != nil {
+		return errResult(err), nil
+	}
+	recordLength := getFloat(req.Params.Arguments, "record_length", 0)
+	if recordLength != 0 && mode != dwf.AcqModeRecord {
+		return errResult(fmt.Errorf("record_length applies to the record acquisition mode only")), nil
+	}
 	cfg := dwf.ScopeConfig{
 		SamplingFrequency: getFloat(req.Params.Arguments, "sampling_frequency", 20e6),
 		BufferSize:        getInt(req.Params.Arguments, "buffer_size", 0),
 		OffsetVoltage:     getFloat(req.Params.Arguments, "offset_voltage", 0),
 		AmplitudeRange:    getFloat(req.Params.Arguments, "amplitude_range", 5),
+		Coupling:          coupling,
+		Attenuation:       getFloat(req.Params.Arguments, "attenuation", 1),
+		Filter:            filter,
+		Bandwidth:         getFloat(req.Params.Arguments, "bandwidth", 0),
+		AcquisitionMode:   mode,
+		RecordLength:      recordLength,
+	}
+	st, err := s.device.Scope().Open(cfg)
+	if err != nil {
+		return errResult(err), nil
+	}
+
+	channels := make([]map[string]interface{}, 0, len(st.Channels))
+	for _, ch := range st.Channels {
+		channels = append(channels, map[string]interface{}{
+			"channel":         ch.Channel,
+			"offset_voltage":  ch.OffsetVoltage,
+			"amplitude_range": ch.AmplitudeRange,
+		})
+	}
+	applied := map[string]interface{}{
+		"sampling_frequency": st.SamplingFrequency,
+		"buffer_size":        st.BufferSize,
+		"channels":           channels,
+	}
+	if st.AcquisitionMode == dwf.AcqModeRecord {
+		applied["record_length"] = st.RecordLength
+	}
+	result := map[string]interface{}{
+		"message":          "Oscilloscope initialized",
+		"acquisition_mode": enumName(acquisitionModeNames, st.AcquisitionMode),
+		"applied":          applied,
+		"limits": map[string]interface{}{
+			"sampling_frequency": limitsMap(st.FrequencyLimits),
+			"buffer_size":        map[string]interface{}{"max": st.MaxBufferSize},
+			"offset_voltage":     limitsMap(st.OffsetLimits),
+			"amplitude_range":    limitsMap(st.RangeLimits),
+		},
 	}
-	if err := s.device.Scope().Open(cfg); err != nil {
+	requested := map[string][2]float64{
+		"sampling_frequency": {cfg.SamplingFrequency, st.SamplingFrequency},
+	}
+	for _, ch := range st.Channels {
+		requested[fmt.Sprintf("channel%d_offset_voltage", ch.Channel)] = [2]float64{cfg.OffsetVoltage, ch.OffsetVoltage}
+		requested[fmt.Sprintf("channel%d_amplitude_range", ch.Channel)] = [2]float64{cfg.AmplitudeRange, ch.AmplitudeRange}
+	}
+	if cfg.BufferSize != 0 {
+		requested["buffer_size"] = [2]float64{float64(cfg.BufferSize), float64(st.BufferSize)}
+	}
+	if adjusted := adjustedSettings(requested); len(adjusted) > 0 {
+		result["adjusted"] = adjusted
+	}
+	return jsonResult(result), nil
+}
+
+func (s *DiscoveryMCPServer) handleScopeGetConfig(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	rb, err := s.device.Scope().Config()
+	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText("Oscilloscope initialized"), nil
+	channels := make([]map[string]interface{}, 0, len(rb.Channels))
+	for _, ch := range rb.Channels {
+		channels = append(channels, map[string]interface{}{
+			"channel":     ch.Channel,
+			"enabled":     ch.Enabled,
+			"range":       ch.Range,
+			"offset":      ch.Offset,
+			"attenuation": ch.Attenuation,
+			"coupling":    enumName(couplingNames, ch.Coupling),
+			"filter":      enumName(filterNames, ch.Filter),
+			"bandwidth":   ch.Bandwidth,
+		})
+	}
+	t := rb.Trigger
+	trigger := map[string]interface{}{
+		"source":       int(t.Source),
+		"channel":      t.Channel,
+		"type":         enumName(triggerTypeNames, t.Type),
+		"condition":    enumName(slopeNames, t.Condition),
+		"level":        t.Level,
+		"hysteresis":   t.Hysteresis,
+		"position":     t.Position,
+		"holdoff":      t.HoldOff,
+		"auto_timeout": t.AutoTimeout,
+	}
+	if t.Type == dwf.TriggerTypePulse {
+		trigger["length"] = t.Length
+		trigger["length_condition"] = enumName(lengthConditionNames, t.LengthCondition)
+	}
+	if t.Type == dwf.TriggerTypeWindow {
+		trigger["window_low"] = t.Level - t.Hysteresis
+		trigger["window_high"] = t.Level + t.Hysteresis
+	}
+	result := map[string]interface{}{
+		"sampling_frequency": rb.SamplingFrequency,
+		"buffer_size":        rb.BufferSize,
+		"channels":           channels,
+		"trigger":            trigger,
+	}
+	if rb.SamplingFrequency > 0 {
+		result["buffer_time"] = float64(rb.BufferSize) / rb.SamplingFrequency
+	}
+	return jsonResult(result), nil
 }
 
 func (s *DiscoveryMCPServer) handleScopeMeasure(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -126,7 +771,30 @@ func (s *DiscoveryMCPServer) handleScopeMeasure(_ context.Context, req mcp.CallT
 	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("%.6f V", voltage)), nil
+	return mcp.NewToolResultText(s.format.quantity(voltage, "V", "%.6f V")), nil
+}
+
+func (s *DiscoveryMCPServer) handleScopeCoupling(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	name := getString(req.Params.Arguments, "coupling", "dc")
+	coupling, err := parseCoupling(name)
+	if err != nil {
+		return errResult(err), nil
+	}
+	if err := s.device.Scope().SetCoupling(ch, coupling); err != nil {
+		return errResult(err), nil
+	}
+	s.usage.relay(fmt.Sprintf("scope.ch%d.coupling", ch), int(coupling))
+	return mcp.NewToolResultText(fmt.Sprintf("Channel %d coupling set to %s", ch, strings.ToUpper(name))), nil
+}
+
+func (s *DiscoveryMCPServer) handleScopeAttenuation(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	factor := getFloat(req.Params.Arguments, "attenuation", 1)
+	if err := s.device.Scope().SetAttenuation(ch, factor); err != nil {
+		return errResult(err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Channel %d attenuation set to %gx", ch, factor)), nil
 }
 
 func (s *DiscoveryMCPServer) handleScopeTrigger(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -137,199 +805,2276 @@ func (s *DiscoveryMCPServer) handleScopeTrigger(_ context.Context, req mcp.CallT
 		Timeout:    getFloat(req.Params.Arguments, "timeout", 0),
 		EdgeRising: getBool(req.Params.Arguments, "edge_rising", true),
 		Level:      getFloat(req.Params.Arguments, "level", 0),
+		HoldOff:    getFloat(req.Params.Arguments, "holdoff", 0),
+		Length:     getFloat(req.Params.Arguments, "length", 0),
+	}
+	var err error
+	if cfg.Type, err = parseTriggerType(getString(req.Params.Arguments, "type", "edge")); err != nil {
+		return errResult(err), nil
+	}
+	if cfg.LengthCondition, err = parseLengthCondition(getString(req.Params.Arguments, "length_condition", "more")); err != nil {
+		return errResult(err), nil
 	}
-	if err := s.device.Scope().SetTrigger(cfg); err != nil {
+	if cfg.Type == dwf.TriggerTypePulse && cfg.Length <= 0 {
+		return errResult(fmt.Errorf("pulse trigger requires a positive length in seconds")), nil
+	}
+	if cfg.Type == dwf.TriggerTypeWindow {
+		args := argsMap(req.Params.Arguments)
+		if args["window_low"] == nil || args["window_high"] == nil {
+			return errResult(fmt.Errorf("window trigger requires window_low and window_high")), nil
+		}
+		cfg.WindowLow = getFloat(req.Params.Arguments, "window_low", 0)
+		cfg.WindowHigh = getFloat(req.Params.Arguments, "window_high", 0)
+		if cfg.WindowHigh <= cfg.WindowLow {
+			return errResult(fmt.Errorf("window_high must be above window_low")), nil
+		}
+		if args["hysteresis"] != nil {
+			return errResult(fmt.Errorf("hysteresis cannot be combined with a window trigger")), nil
+		}
+		switch cond := getString(req.Params.Arguments, "window_condition", "exit"); cond {
+		case "enter":
+			cfg.EdgeRising = true
+		case "exit":
+			cfg.EdgeRising = false
+		default:
+			return errResult(fmt.Errorf("invalid window condition %q: expected enter or exit", cond)), nil
+		}
+	}
+	if cfg.HoldOff < 0 {
+		return errResult(fmt.Errorf("holdoff must not be negative")), nil
+	}
+	hysteresis, err := parseHysteresis(argsMap(req.Params.Arguments)["hysteresis"])
+	if err != nil {
+		return errResult(err), nil
+	}
+	cfg.Hysteresis = hysteresis
+	scope := s.device.Scope()
+	var bufferTime float64
+	if rate := scope.SampleRate(); rate > 0 {
+		bufferTime = float64(scope.BufferSize()) / rate
+	}
+	if v := argsMap(req.Params.Arguments)["position"]; v != nil {
+		position, err := parsePreTrigger(v, bufferTime)
+		if err != nil {
+			return errResult(err), nil
+		}
+		cfg.Position = &position
+	}
+	if err := scope.SetTrigger(cfg); err != nil {
 		return errResult(err), nil
 	}
 	return mcp.NewToolResultText("Trigger configured"), nil
 }
 
-func (s *DiscoveryMCPServer) handleScopeRecord(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (s *DiscoveryMCPServer) handleScopeRecord(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	averages := getInt(req.Params.Arguments, "averages", 1)
+	if averages < 1 || averages > maxScopeAverages {
+		return errResult(fmt.Errorf("averages must be between 1 and %d", maxScopeAverages)), nil
+	}
+	enc, err := parseSampleEncoding(req.Params.Arguments)
+	if err != nil {
+		return errResult(err), nil
+	}
+	preview := getInt(req.Params.Arguments, "preview_samples", 0)
+	if preview < 0 {
+		return errResult(fmt.Errorf("preview_samples must not be negative")), nil
+	}
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, averages)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+
+	// arrays holds the recorded sample arrays in result order; lo and hi
+	// are the arrays a preview envelope is computed from.
+	var arrays []namedSamples
+	var lo, hi []float64
+	samplesPerPoint := 1.0
+	result := map[string]interface{}{"channel": ch}
+	peakDetect := getBool(req.Params.Arguments, "peak_detect", false)
+	noise := getBool(req.Params.Arguments, "noise", false)
+	if (peakDetect || noise) && averages > 1 {
+		return errResult(fmt.Errorf("peak_detect and noise cannot be combined with averages")), nil
+	}
+	if peakDetect && noise {
+		return errResult(fmt.Errorf("peak_detect already returns the noise envelope; use only one of peak_detect and noise")), nil
+	}
+	switch {
+	case noise:
+		peak, err := s.device.Scope().RecordPeak(ctx, ch)
+		if err != nil {
+			return errResult(acquisitionError(err)), nil
+		}
+		s.usage.captured("scope", 1)
+		result["noise_samples_per_point"] = peak.SamplesPerPoint
+		arrays = []namedSamples{{"data", peak.Data}, {"noise_min", peak.Min}, {"noise_max", peak.Max}}
+		lo, hi = peak.Data, peak.Data
+	case peakDetect:
+		peak, err := s.device.Scope().RecordPeak(ctx, ch)
+		if err != nil {
+			return errResult(acquisitionError(err)), nil
+		}
+		s.usage.captured("scope", 1)
+		samplesPerPoint = peak.SamplesPerPoint
+		result["samples_per_point"] = peak.SamplesPerPoint
+		arrays = []namedSamples{{"min", peak.Min}, {"max", peak.Max}}
+		lo, hi = peak.Min, peak.Max
+	case averages > 1:
+		avg, err := s.device.Scope().RecordAverage(ctx, ch, averages)
+		if err != nil {
+			return errResult(acquisitionError(err)), nil
+		}
+		s.usage.captured("scope", avg.Count)
+		result["averages"] = avg.Count
+		arrays = []namedSamples{{"data", avg.Mean}}
+		if getBool(req.Params.Arguments, "stddev", false) {
+			arrays = append(arrays, namedSamples{"stddev", avg.StdDev})
+		}
+		lo, hi = avg.Mean, avg.Mean
+	default:
+		data, err := s.device.Scope().Record(ctx, ch)
+		if err != nil {
+			return errResult(acquisitionError(err)), nil
+		}
+		s.usage.captured("scope", 1)
+		arrays = []namedSamples{{"data", data}}
+		lo, hi = data, data
+	}
+	n := len(lo)
+	result["samples"] = n
+	timing := s.device.Scope().Timing()
+
+	if preview == 0 || preview >= n {
+		for _, a := range arrays {
+			encodeSamples(result, a.name, a.data, enc)
+		}
+		return jsonResult(timeAxis(result, timing, n, samplesPerPoint)), nil
+	}
+
+	// Store the full capture and return a min/max envelope in its place.
+	name := newCaptureName("scope")
+	full := timeAxis(map[string]interface{}{}, timing, n, samplesPerPoint)
+	for k, v := range result {
+		full[k] = v
+	}
+	for _, a := range arrays {
+		full[a.name] = a.data
+	}
+	if err := s.storeCapture(ctx, name, full); err != nil {
+		return errResult(err), nil
+	}
+	pmin, pmax := envelope(lo, hi, preview)
+	perPreview := samplesPerPoint * float64(n) / float64(preview)
+	result["preview_samples"] = preview
+	result["samples_per_point"] = perPreview
+	encodeSamples(result, "min", pmin, enc)
+	encodeSamples(result, "max", pmax, enc)
+	result["uri"] = capturesURIPrefix + name
+	result["location"] = s.captures.Location(name)
+	return jsonResult(timeAxis(result, timing, preview, perPreview)), nil
+}
+
+func (s *DiscoveryMCPServer) handleScopeRecordRaw(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	enc, err := parseSampleEncoding(req.Params.Arguments)
+	if err != nil {
+		return errResult(err), nil
+	}
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+	raw, err := s.device.Scope().RecordRaw(ctx, ch)
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	s.usage.captured("scope", 1)
+
+	codes := make([]float64, len(raw.Codes))
+	for i, c := range raw.Codes {
+		codes[i] = float64(c)
+	}
+	result := map[string]interface{}{
+		"channel":        ch,
+		"samples":        len(codes),
+		"adc_bits":       raw.Bits,
+		"codes_per_step": 1 << (16 - raw.Bits),
+		"volts_per_code": raw.VoltsPerCode,
+		"offset":         raw.Offset,
+	}
+	encodeSamples(result, "codes", codes, enc)
+	return jsonResult(timeAxis(result, s.device.Scope().Timing(), len(codes), 1)), nil
+}
+
+func (s *DiscoveryMCPServer) handleScopeRecordXY(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	xCh := getInt(req.Params.Arguments, "x_channel", 1)
+	yCh := getInt(req.Params.Arguments, "y_channel", 2)
+	if xCh == yCh {
+		return errResult(fmt.Errorf("x_channel and y_channel must differ")), nil
+	}
+	enc, err := parseSampleEncoding(req.Params.Arguments)
+	if err != nil {
+		return errResult(err), nil
+	}
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+	data, err := s.device.Scope().RecordChannels(ctx, []int{xCh, yCh})
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	s.usage.captured("scope", 1)
+
+	x, y := data[0], data[1]
+	m := dwf.MeasureXY(x, y)
+	result := map[string]interface{}{
+		"x_channel":       xCh,
+		"y_channel":       yCh,
+		"samples":         len(x),
+		"x_amplitude":     m.XAmplitude,
+		"y_amplitude":     m.YAmplitude,
+		"correlation":     m.Correlation,
+		"phase":           m.Phase,
+		"amplitude_ratio": m.AmplitudeRatio,
+		"slope":           m.Slope,
+		"intercept":       m.Intercept,
+	}
+	encodeSamples(result, "x", x, enc)
+	encodeSamples(result, "y", y, enc)
+	return jsonResult(timeAxis(result, s.device.Scope().Timing(), len(x), 1)), nil
+}
+
+// namedSamples is a sample array and the result key it is returned under.
+type namedSamples struct {
+	name string
+	data []float64
+}
+
+// envelope reduces lo and hi to n points, each the minimum of lo and the
+// maximum of hi over an equal share of the samples, so peaks survive the
+// decimation.
+func envelope(lo, hi []float64, n int) (mins, maxs []float64) {
+	mins = make([]float64, n)
+	maxs = make([]float64, n)
+	for i := range n {
+		start, end := i*len(lo)/n, (i+1)*len(lo)/n
+		mins[i], maxs[i] = math.Inf(1), math.Inf(-1)
+		for j := start; j < end; j++ {
+			mins[i] = math.Min(mins[i], lo[j])
+			maxs[i] = math.Max(maxs[i], hi[j])
+		}
+	}
+	return mins, maxs
+}
+
+// timeAxis adds what a client needs to reconstruct the time axis of n
+// recorded points to result: sample rate, sample period, duration, the
+// trigger index (-1 if not triggered) and the host time the acquisition
+// completed. Each point spans samplesPerPoint
+// samples of the acquisition.
+func timeAxis(result map[string]interface{}, timing dwf.AcquisitionTiming, n int, samplesPerPoint float64) map[string]interface{} {
+	if samplesPerPoint <= 0 {
+		samplesPerPoint = 1
+	}
+	rate := timing.SampleRate / samplesPerPoint
+	result["sample_rate"] = rate
+	if rate > 0 {
+		result["sample_period"] = 1 / rate
+		result["duration"] = float64(n) / rate
+	}
+	trigger := -1
+	if timing.TriggerIndex >= 0 {
+		trigger = int(float64(timing.TriggerIndex) / samplesPerPoint)
+	}
+	result["trigger_index"] = trigger
+	if !timing.Time.IsZero() {
+		result["timestamp"] = timing.Time.UTC().Format(time.RFC3339Nano)
+	}
+	return result
+}
+
+func (s *DiscoveryMCPServer) handleScopeAnalyze(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	ch := getInt(req.Params.Arguments, "channel", 1)
-	data, err := s.device.Scope().Record(ch)
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
 	if err != nil {
 		return errResult(err), nil
 	}
+	defer cancel()
+	data, err := s.device.Scope().Record(ctx, ch)
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	s.usage.captured("scope", 1)
+	rate := s.device.Scope().SampleRate()
+	m := dwf.MeasureWaveform(data, rate)
 	return jsonResult(map[string]interface{}{
-		"channel": ch,
-		"samples": len(data),
-		"data":    data,
+		"channel":      ch,
+		"samples":      len(data),
+		"sample_rate":  rate,
+		"min":          m.Min,
+		"max":          m.Max,
+		"peak_to_peak": m.PeakToPeak,
+		"mean":         m.Mean,
+		"rms":          m.RMS,
+		"frequency":    m.Frequency,
+		"period":       m.Period,
+		"duty_cycle":   m.DutyCycle,
+		"rise_time":    m.RiseTime,
+		"fall_time":    m.FallTime,
+		"cycles":       m.Cycles,
 	}), nil
 }
 
-func (s *DiscoveryMCPServer) handleScopeClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	if err := s.device.Scope().Close(); err != nil {
+// waveformStatFields are the measurements discovery_scope_stats aggregates.
+var waveformStatFields = []struct {
+	name  string
+	value func(dwf.WaveformMeasurements) float64
+}{
+	{"min", func(m dwf.WaveformMeasurements) float64 { return m.Min }},
+	{"max", func(m dwf.WaveformMeasurements) float64 { return m.Max }},
+	{"peak_to_peak", func(m dwf.WaveformMeasurements) float64 { return m.PeakToPeak }},
+	{"mean", func(m dwf.WaveformMeasurements) float64 { return m.Mean }},
+	{"rms", func(m dwf.WaveformMeasurements) float64 { return m.RMS }},
+	{"frequency", func(m dwf.WaveformMeasurements) float64 { return m.Frequency }},
+	{"period", func(m dwf.WaveformMeasurements) float64 { return m.Period }},
+	{"duty_cycle", func(m dwf.WaveformMeasurements) float64 { return m.DutyCycle }},
+	{"rise_time", func(m dwf.WaveformMeasurements) float64 { return m.RiseTime }},
+	{"fall_time", func(m dwf.WaveformMeasurements) float64 { return m.FallTime }},
+}
+
+// sampleStats summarizes the finite values of one measurement over
+// repeated acquisitions. Statistics of fewer than one value are NaN, as is
+// the standard deviation of a single value.
+func sampleStats(values []float64) map[string]interface{} {
+	var n int
+	var mean, m2 float64
+	lo, hi := math.NaN(), math.NaN()
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		n++
+		if n == 1 {
+			lo, hi = v, v
+		}
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+		d := v - mean
+		mean += d / float64(n)
+		m2 += d * (v - mean)
+	}
+	stats := map[string]interface{}{
+		"count":  n,
+		"mean":   math.NaN(),
+		"min":    lo,
+		"max":    hi,
+		"stddev": math.NaN(),
+	}
+	if n > 0 {
+		stats["mean"] = mean
+	}
+	if n > 1 {
+		stats["stddev"] = math.Sqrt(m2 / float64(n-1))
+	}
+	return stats
+}
+
+func (s *DiscoveryMCPServer) handleScopeStats(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	count := getInt(req.Params.Arguments, "count", 10)
+	if count < 1 || count > maxScopeAverages {
+		return errResult(fmt.Errorf("count must be between 1 and %d", maxScopeAverages)), nil
+	}
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, count)
+	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText("Oscilloscope reset"), nil
+	defer cancel()
+
+	rate := s.device.Scope().SampleRate()
+	values := make([][]float64, len(waveformStatFields))
+	for n := 1; n <= count; n++ {
+		data, err := s.device.Scope().Record(ctx, ch)
+		if err != nil {
+			return errResult(fmt.Errorf("acquisition %d of %d: %w", n, count, acquisitionError(err))), nil
+		}
+		s.usage.captured("scope", 1)
+		m := dwf.MeasureWaveform(data, rate)
+		for i, f := range waveformStatFields {
+			values[i] = append(values[i], f.value(m))
+		}
+	}
+
+	measurements := make(map[string]interface{}, len(waveformStatFields))
+	for i, f := range waveformStatFields {
+		measurements[f.name] = sampleStats(values[i])
+	}
+	return jsonResult(map[string]interface{}{
+		"channel":      ch,
+		"acquisitions": count,
+		"sample_rate":  rate,
+		"measurements": measurements,
+	}), nil
+}
+
+func (s *DiscoveryMCPServer) handleScopeFrequency(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	ch := getInt(args, "channel", 1)
+	method := strings.ToLower(getString(args, "method", "auto"))
+	gate := getFloat(args, "gate_time", 1)
+	rate := s.device.Scope().SampleRate()
+	if rate <= 0 {
+		return errResult(fmt.Errorf("unknown sample rate; open the oscilloscope first")), nil
+	}
+	limits, err := s.device.Scope().CounterLimits()
+	if err != nil {
+		return errResult(err), nil
+	}
+	switch method {
+	case "auto":
+		method = "software"
+		if limits.Max > 0 {
+			method = "counter"
+		}
+	case "counter":
+		if limits.Max <= 0 {
+			return errResult(fmt.Errorf("this device has no analog-in frequency counter; use method software")), nil
+		}
+	case "software":
+	default:
+		return errResult(fmt.Errorf("unknown method %q (expected auto, counter or software)", method)), nil
+	}
+	if method == "counter" && (gate <= 0 || gate > limits.Max) {
+		return errResult(fmt.Errorf("gate_time must be between 0 and %g s, got %g", limits.Max, gate)), nil
+	}
+
+	result := map[string]interface{}{"channel": ch, "method": method}
+	_, hasLevel := args["level"]
+	level := getFloat(args, "level", 0)
+	if method == "software" || !hasLevel {
+		actx, cancel, err := acquisitionContext(ctx, args, 1)
+		if err != nil {
+			return errResult(err), nil
+		}
+		defer cancel()
+		data, err := s.device.Scope().Record(actx, ch)
+		if err != nil {
+			return errResult(acquisitionError(err)), nil
+		}
+		s.usage.captured("scope", 1)
+		m := dwf.MeasureWaveform(data, rate)
+		if method == "software" {
+			result["frequency"] = m.Frequency
+			result["period"] = m.Period
+			result["count"] = m.Cycles
+			result["gate_time"] = float64(len(data)) / rate
+			return jsonResult(result), nil
+		}
+		// Count at the middle of the signal's swing.
+		level = (m.Min + m.Max) / 2
+	}
+
+	cfg := dwf.CounterConfig{
+		Channel:    ch,
+		GateTime:   gate,
+		Level:      level,
+		Hysteresis: getFloat(args, "hysteresis", 0),
+	}
+	// A gate always ends, signal or not; the deadline only guards against a
+	// device that stops responding.
+	cctx, cancel := context.WithTimeout(ctx, time.Duration((2*gate+1)*float64(time.Second)))
+	defer cancel()
+	r, err := s.device.Scope().Count(cctx, cfg)
+	if err != nil {
+		return errResult(err), nil
+	}
+	result["frequency"] = r.Frequency
+	result["period"] = math.NaN()
+	if r.Frequency > 0 {
+		result["period"] = 1 / r.Frequency
+	}
+	result["count"] = r.Count
+	result["gate_time"] = r.GateTime
+	result["level"] = cfg.Level
+	return jsonResult(result), nil
+}
+
+func (s *DiscoveryMCPServer) handleScopeFFT(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	window, err := parseWindow(getString(req.Params.Arguments, "window", "hann"))
+	if err != nil {
+		return errResult(err), nil
+	}
+	peakCount := getInt(req.Params.Arguments, "peaks", 5)
+	maxBins := getInt(req.Params.Arguments, "bins", 256)
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+
+	data, err := s.device.Scope().Record(ctx, ch)
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	rate := s.device.Scope().SampleRate()
+	if rate <= 0 {
+		return errResult(fmt.Errorf("unknown sample rate; open the oscilloscope first")), nil
+	}
+	s.usage.captured("scope", 1)
+	spec := dwf.ComputeSpectrum(data, rate, window)
+	if len(spec.Magnitudes) == 0 {
+		return errResult(fmt.Errorf("not enough samples for a spectrum")), nil
+	}
+
+	// Report peaks down to -60 dB below the strongest non-DC bin.
+	var strongest float64
+	for _, m := range spec.Magnitudes[1:] {
+		strongest = math.Max(strongest, m)
+	}
+	peaks := make([]map[string]interface{}, 0, peakCount)
+	for _, p := range spec.Peaks(peakCount, strongest*1e-3) {
+		peaks = append(peaks, map[string]interface{}{
+			"frequency": p.Frequency,
+			"magnitude": p.Magnitude,
+		})
+	}
+
+	result := map[string]interface{}{
+		"channel":   ch,
+		"samples":   2 * (len(spec.Magnitudes) - 1),
+		"bin_width": spec.BinWidth,
+		"dc":        spec.DC,
+		"peaks":     peaks,
+	}
+	if maxBins > 0 {
+		freqs, mags := peakHold(spec, maxBins)
+		result["frequencies"] = freqs
+		result["magnitudes"] = mags
+	}
+	return jsonResult(result), nil
+}
+
+// maxTimebaseErrorPPM is the largest time base error accepted before the
+// capture is assumed not to show the reference clock at all.
+const maxTimebaseErrorPPM = 1e4
+
+func (s *DiscoveryMCPServer) handleScopeTimebase(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	ref := getFloat(req.Params.Arguments, "reference_frequency", 0)
+	if ref <= 0 {
+		return errResult(fmt.Errorf("reference_frequency must be positive")), nil
+	}
+	rate := s.device.Scope().SampleRate()
+	if rate <= 0 {
+		return errResult(fmt.Errorf("unknown sample rate; open the oscilloscope first")), nil
+	}
+	if ref*4 > rate {
+		return errResult(fmt.Errorf("reference %g Hz is too fast for %g Hz sampling; use a sample rate of at least %g Hz", ref, rate, ref*4)), nil
+	}
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+
+	data, err := s.device.Scope().Record(ctx, ch)
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	s.usage.captured("scope", 1)
+	tb := dwf.MeasureTimebase(data, rate, ref)
+	if tb.Edges < 3 {
+		return errResult(fmt.Errorf("found %d reference edges, need at least 3; check the connection or lengthen the capture", tb.Edges)), nil
+	}
+	if math.Abs(tb.ErrorPPM) > maxTimebaseErrorPPM {
+		return errResult(fmt.Errorf("measured %g Hz, too far from the %g Hz reference; check the reference frequency and connection", tb.MeasuredFrequency, ref)), nil
+	}
+	return jsonResult(map[string]interface{}{
+		"channel":               ch,
+		"samples":               len(data),
+		"sample_rate":           rate,
+		"reference_frequency":   ref,
+		"measured_frequency":    tb.MeasuredFrequency,
+		"edges":                 tb.Edges,
+		"duration":              tb.Duration,
+		"error_ppm":             tb.ErrorPPM,
+		"error_uncertainty_ppm": tb.ErrorUncertaintyPPM,
+		"correction_factor":     tb.CorrectionFactor,
+		"period_jitter_rms":     tb.PeriodJitterRMS,
+		"tie_rms":               tb.TIERMS,
+		"tie_peak_to_peak":      tb.TIEPeakToPeak,
+	}), nil
+}
+
+func (s *DiscoveryMCPServer) handleScopeStream(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	duration := getFloat(req.Params.Arguments, "duration", 1)
+	if duration <= 0 {
+		return errResult(fmt.Errorf("duration must be positive")), nil
+	}
+	// The recording itself takes duration, on top of the wait for the
+	// trigger.
+	timeout := getFloat(req.Params.Arguments, "timeout", defaultAcquisitionTimeout)
+	if timeout < 0 {
+		return errResult(fmt.Errorf("timeout must not be negative")), nil
+	}
+	var cancel context.CancelFunc
+	if timeout == 0 {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration((duration+timeout)*float64(time.Second)))
+	}
+	defer cancel()
+
+	var progressToken mcp.ProgressToken
+	if req.Params.Meta != nil {
+		progressToken = req.Params.Meta.ProgressToken
+	}
+	srv := server.ServerFromContext(ctx)
+	store := getBool(req.Params.Arguments, "store", false)
+
+	chunks := 0
+	minV, maxV := math.Inf(1), math.Inf(-1)
+	var recorded []float64
+	stats, err := s.device.Scope().Stream(ctx, ch, duration, func(chunk []float64) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if store {
+			recorded = append(recorded, chunk...)
+		}
+		for _, v := range chunk {
+			minV = math.Min(minV, v)
+			maxV = math.Max(maxV, v)
+		}
+		if srv != nil {
+			_ = srv.SendNotificationToClient(ctx, scopeDataNotification, map[string]interface{}{
+				"channel": ch,
+				"chunk":   chunks,
+				"data":    chunk,
+			})
+			if progressToken != nil {
+				_ = srv.SendNotificationToClient(ctx, "notifications/progress", map[string]interface{}{
+					"progressToken": progressToken,
+					"progress":      chunks + 1,
+					"message":       fmt.Sprintf("%d chunks streamed", chunks+1),
+				})
+			}
+		}
+		chunks++
+		return nil
+	})
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	s.usage.captured("scope", 1)
+
+	result := map[string]interface{}{
+		"channel": ch,
+		"samples": stats.Samples,
+		"chunks":  chunks,
+		"lost":    stats.Lost,
+		"corrupt": stats.Corrupt,
+	}
+	if stats.Samples > 0 {
+		result["min"] = minV
+		result["max"] = maxV
+	}
+	if store {
+		name := newCaptureName("scope")
+		if err := s.storeCapture(ctx, name, map[string]interface{}{
+			"channel":     ch,
+			"sample_rate": s.device.Scope().SampleRate(),
+			"samples":     len(recorded),
+			"lost":        stats.Lost,
+			"corrupt":     stats.Corrupt,
+			"data":        recorded,
+		}); err != nil {
+			return errResult(err), nil
+		}
+		result["uri"] = capturesURIPrefix + name
+		result["location"] = s.captures.Location(name)
+	}
+	return jsonResult(result), nil
+}
+
+func (s *DiscoveryMCPServer) handleScopeClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := s.device.Scope().Close(); err != nil {
+		return errResult(err), nil
+	}
+	return mcp.NewToolResultText("Oscilloscope reset"), nil
+}
+
+// ==================== Wavegen Handlers ====================
+
+func (s *DiscoveryMCPServer) handleWavegenGenerate(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := dwf.WavegenConfig{
+		Channel:       getInt(req.Params.Arguments, "channel", 1),
+		Function:      dwf.WavegenFunc(getInt(req.Params.Arguments, "function", 1)),
+		Offset:        getFloat(req.Params.Arguments, "offset", 0),
+		Frequency:     getFloat(req.Params.Arguments, "frequency", 1000),
+		Amplitude:     getFloat(req.Params.Arguments, "amplitude", 1),
+		Symmetry:      getFloat(req.Params.Arguments, "symmetry", 50),
+		Phase:         math.Mod(getFloat(req.Params.Arguments, "phase", 0), 360),
+		Wait:          getFloat(req.Params.Arguments, "wait", 0),
+		RunTime:       getFloat(req.Params.Arguments, "run_time", 0),
+		Repeat:        getInt(req.Params.Arguments, "repeat", 0),
+		TriggerSource: dwf.TriggerSource(getInt(req.Params.Arguments, "trigger_source", 0)),
+	}
+	custom, err := getFloatList(req.Params.Arguments, "custom_data")
+	if err != nil {
+		return errResult(err), nil
+	}
+	if cfg.TriggerSlope, err = parseSlope(getString(req.Params.Arguments, "trigger_slope", "rising")); err != nil {
+		return errResult(err), nil
+	}
+	if cfg.Phase < 0 {
+		cfg.Phase += 360
+	}
+	if _, ok := argsMap(req.Params.Arguments)["power"]; ok && cfg.Function != dwf.FuncSinePower {
+		return errResult(fmt.Errorf("power needs function %d (sine power)", dwf.FuncSinePower)), nil
+	}
+	if cfg.Function == dwf.FuncSinePower {
+		// The device takes the power of a sine power waveform as its
+		// symmetry; 0 is a plain sine.
+		cfg.Symmetry = getFloat(req.Params.Arguments, "power", 0)
+		if cfg.Symmetry < -100 || cfg.Symmetry > 100 {
+			return errResult(fmt.Errorf("power must be between -100 and 100, got %g", cfg.Symmetry)), nil
+		}
+	}
+	if cfg.AM, err = modulationArgs(argsMap(req.Params.Arguments), "am", "am_depth", 50); err != nil {
+		return errResult(err), nil
+	}
+	if cfg.FM, err = modulationArgs(argsMap(req.Params.Arguments), "fm", "fm_deviation", 10); err != nil {
+		return errResult(err), nil
+	}
+	var warnings []string
+	if custom != nil {
+		if cfg.Function != dwf.FuncCustom {
+			return errResult(fmt.Errorf("custom_data needs function %d (custom)", dwf.FuncCustom)), nil
+		}
+		limits, err := s.device.Wavegen().DataLimits(cfg.Channel)
+		if err != nil {
+			return errResult(err), nil
+		}
+		cfg.CustomData, cfg.Amplitude, warnings, err = fitCustomWaveform(custom, cfg.Amplitude, limits, s.format)
+		if err != nil {
+			return errResult(err), nil
+		}
+	}
+	if err := s.safety.checkWavegenConfig(cfg); err != nil {
+		return errResult(err), nil
+	}
+	st, err := s.device.Wavegen().Generate(cfg)
+	if err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(wavegenResult(cfg, st, warnings)), nil
+}
+
+// modulationArgs reads the AM or FM parameters starting with prefix, or
+// returns nil if the modulation frequency is not given. depthKey names the
+// depth parameter, in percent of the carrier.
+func modulationArgs(args map[string]any, prefix, depthKey string, defaultDepth float64) (*dwf.WavegenModulation, error) {
+	if _, ok := args[prefix+"_frequency"]; !ok {
+		return nil, nil
+	}
+	m := &dwf.WavegenModulation{
+		Function:  dwf.WavegenFunc(getInt(args, prefix+"_function", int(dwf.FuncSine))),
+		Frequency: getFloat(args, prefix+"_frequency", 0),
+		Depth:     getFloat(args, depthKey, defaultDepth),
+	}
+	if m.Frequency <= 0 {
+		return nil, fmt.Errorf("%s_frequency must be positive, got %g", prefix, m.Frequency)
+	}
+	if m.Depth < 0 || m.Depth > 100 {
+		return nil, fmt.Errorf("%s must be between 0 and 100 percent, got %g", depthKey, m.Depth)
+	}
+	return m, nil
+}
+
+// wavegenResult describes a started waveform: the applied settings, their
+// limits, those the device adjusted and any warnings.
+func wavegenResult(cfg dwf.WavegenConfig, st dwf.WavegenSettings, warnings []string) map[string]interface{} {
+	result := map[string]interface{}{
+		"message": fmt.Sprintf("Generating waveform on channel %d", cfg.Channel),
+		"applied": map[string]interface{}{
+			"frequency": st.Frequency,
+			"amplitude": st.Amplitude,
+			"offset":    st.Offset,
+			"symmetry":  st.Symmetry,
+			"phase":     st.Phase,
+		},
+		"limits": map[string]interface{}{
+			"frequency": limitsMap(st.FrequencyLimits),
+			"amplitude": limitsMap(st.AmplitudeLimits),
+			"offset":    limitsMap(st.OffsetLimits),
+			"symmetry":  limitsMap(st.SymmetryLimits),
+			"phase":     limitsMap(st.PhaseLimits),
+		},
+	}
+	pairs := map[string][2]float64{
+		"frequency": {cfg.Frequency, st.Frequency},
+		"amplitude": {cfg.Amplitude, st.Amplitude},
+		"offset":    {cfg.Offset, st.Offset},
+		"symmetry":  {cfg.Symmetry, st.Symmetry},
+		"phase":     {cfg.Phase, st.Phase},
+	}
+	applied := result["applied"].(map[string]interface{})
+	if cfg.Function == dwf.FuncSinePower {
+		// Report the power under its own name rather than as symmetry.
+		applied["power"] = applied["symmetry"]
+		delete(applied, "symmetry")
+		pairs["power"] = pairs["symmetry"]
+		delete(pairs, "symmetry")
+	}
+	if cfg.AM != nil && st.AM != nil {
+		applied["am"] = modulationMap(st.AM, "depth")
+		pairs["am_frequency"] = [2]float64{cfg.AM.Frequency, st.AM.Frequency}
+		pairs["am_depth"] = [2]float64{cfg.AM.Depth, st.AM.Depth}
+	}
+	if cfg.FM != nil && st.FM != nil {
+		applied["fm"] = modulationMap(st.FM, "deviation")
+		pairs["fm_frequency"] = [2]float64{cfg.FM.Frequency, st.FM.Frequency}
+		pairs["fm_deviation"] = [2]float64{cfg.FM.Depth, st.FM.Depth}
+	}
+	if adjusted := adjustedSettings(pairs); len(adjusted) > 0 {
+		result["adjusted"] = adjusted
+	}
+	if cfg.CustomData != nil {
+		result["custom_samples"] = len(cfg.CustomData)
+	}
+	if cfg.TriggerSource != dwf.TrigSrcNone {
+		result["message"] = fmt.Sprintf("Channel %d armed; the waveform starts on the trigger", cfg.Channel)
+		result["trigger"] = map[string]interface{}{
+			"source": int(cfg.TriggerSource),
+			"slope":  enumName(slopeNames, cfg.TriggerSlope),
+		}
+	}
+	if len(warnings) > 0 {
+		result["warning"] = strings.Join(warnings, " ")
+	}
+	return result
+}
+
+func (s *DiscoveryMCPServer) handleWavegenEnable(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	if err := s.device.Wavegen().Enable(ch); err != nil {
+		return errResult(err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Wavegen channel %d enabled", ch)), nil
+}
+
+func (s *DiscoveryMCPServer) handleWavegenDisable(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	if err := s.device.Wavegen().Disable(ch); err != nil {
+		return errResult(err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Wavegen channel %d disabled", ch)), nil
+}
+
+func (s *DiscoveryMCPServer) handleWavegenIdle(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	name := getString(req.Params.Arguments, "idle", "")
+	idle, err := parseWavegenIdle(name)
+	if err != nil {
+		return errResult(err), nil
+	}
+	if err := s.device.Wavegen().SetIdle(ch, idle); err != nil {
+		return errResult(err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Wavegen channel %d idles at %s", ch, strings.ToLower(name))), nil
+}
+
+func (s *DiscoveryMCPServer) handleWavegenMaster(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 2)
+	master := getInt(req.Params.Arguments, "master", 1)
+	if master == 0 {
+		master = ch
+	}
+	if ch < 1 || master < 1 {
+		return errResult(fmt.Errorf("channel and master must be 1-based channel numbers")), nil
+	}
+	if err := s.device.Wavegen().SetMaster(ch, master); err != nil {
+		return errResult(err), nil
+	}
+	if master == ch {
+		return mcp.NewToolResultText(fmt.Sprintf("Wavegen channel %d runs independently", ch)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Wavegen channel %d follows channel %d; generate on channel %d first, then on channel %d to start both together", ch, master, ch, master)), nil
+}
+
+func (s *DiscoveryMCPServer) handleWavegenClose(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 1)
+	if err := s.device.Wavegen().Close(ch); err != nil {
+		return errResult(err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Wavegen channel %d reset", ch)), nil
+}
+
+// ==================== Power Supply Handlers ====================
+
+func (s *DiscoveryMCPServer) handleSuppliesSwitch(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := dwf.SuppliesConfig{
+		MasterState:     getBool(req.Params.Arguments, "master_state", false),
+		PositiveState:   getBool(req.Params.Arguments, "positive_state", false),
+		NegativeState:   getBool(req.Params.Arguments, "negative_state", false),
+		State:           getBool(req.Params.Arguments, "state", false),
+		PositiveVoltage: getFloat(req.Params.Arguments, "positive_voltage", 0),
+		NegativeVoltage: getFloat(req.Params.Arguments, "negative_voltage", 0),
+		Voltage:         getFloat(req.Params.Arguments, "voltage", 0),
+		PositiveCurrent: getFloat(req.Params.Arguments, "positive_current", 0),
+		NegativeCurrent: getFloat(req.Params.Arguments, "negative_current", 0),
+		Current:         getFloat(req.Params.Arguments, "current", 0),
+		RampTime:        getFloat(req.Params.Arguments, "ramp_time", 0),
+	}
+	if cfg.RampTime < 0 || cfg.RampTime > 60 {
+		return errResult(fmt.Errorf("ramp_time must be between 0 and 60 seconds, got %g", cfg.RampTime)), nil
+	}
+	maxOn := getFloat(req.Params.Arguments, "max_on_time", 0)
+	if maxOn < 0 || maxOn > maxSupplyOnTime {
+		return errResult(fmt.Errorf("max_on_time must be between 0 and %d seconds, got %g", maxSupplyOnTime, maxOn)), nil
+	}
+	if err := s.safety.checkSupplies(cfg); err != nil {
+		return errResult(err), nil
+	}
+	if err := s.device.Supply().Switch(cfg); err != nil {
+		return errResult(err), nil
+	}
+	s.usage.supply(cfg.MasterState)
+	msg := "Power supplies configured"
+	if cfg.RampTime > 0 && cfg.MasterState {
+		msg += "; the voltage ramps up over " + s.format.quantity(cfg.RampTime, "s", "%g s")
+	}
+	if maxOn > 0 && cfg.MasterState {
+		s.watchdog.arm(time.Duration(maxOn * float64(time.Second)))
+		msg += "; they switch off after " + s.format.quantity(maxOn, "s", "%g s") + " without a tool call"
+	} else {
+		s.watchdog.disarm()
+	}
+	return mcp.NewToolResultText(msg), nil
+}
+
+func (s *DiscoveryMCPServer) handleSuppliesGetConfig(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	rb, err := s.device.Supply().Config()
+	if err != nil {
+		return errResult(err), nil
+	}
+	rails := make([]map[string]interface{}, 0, len(rb.Rails))
+	for _, r := range rb.Rails {
+		rail := map[string]interface{}{
+			"rail":    r.Rail,
+			"label":   r.Label,
+			"enabled": r.Enabled,
+			"output":  r.Enabled && rb.MasterState,
+			"voltage": r.Voltage,
+		}
+		if r.Current != nil {
+			rail["current"] = *r.Current
+		}
+		rails = append(rails, rail)
+	}
+	result := map[string]interface{}{
+		"master_state": rb.MasterState,
+		"rails":        rails,
+	}
+	if left, ok := s.watchdog.remaining(); ok {
+		result["auto_off_in"] = left.Seconds()
+	}
+	return jsonResult(result), nil
+}
+
+func (s *DiscoveryMCPServer) handleSuppliesInfo(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	rails, err := s.device.Supply().Rails()
+	if err != nil {
+		return errResult(err), nil
+	}
+	if rails == nil {
+		rails = []dwf.SupplyRail{}
+	}
+	result := map[string]interface{}{"rails": rails}
+	if s.safety != nil && s.safety.SupplyVoltage > 0 {
+		result["safety_limit"] = s.safety.SupplyVoltage
+	}
+	return jsonResult(result), nil
+}
+
+// supplyTrips lists the tripped protections of a supply status: the flags
+// the device raises and the rails in current limit it does not flag.
+func supplyTrips(st dwf.SupplyStatus) []string {
+	trips := append([]string{}, st.Faults...)
+	for _, r := range st.Rails {
+		flagged := slices.ContainsFunc(st.Faults, func(f string) bool { return strings.HasPrefix(f, r.Label+" ") })
+		if r.Limiting && !flagged {
+			trips = append(trips, r.Label+" current limiting")
+		}
+	}
+	return trips
+}
+
+func (s *DiscoveryMCPServer) handleSuppliesStatus(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	monitor := getFloat(req.Params.Arguments, "monitor", 0)
+	if monitor < 0 || monitor > 3600 {
+		return errResult(fmt.Errorf("monitor must be between 0 and 3600 seconds, got %g", monitor)), nil
+	}
+	interval := getFloat(req.Params.Arguments, "interval", 0.5)
+	if interval < 0.05 {
+		return errResult(fmt.Errorf("interval must be at least 0.05 seconds, got %g", interval)), nil
+	}
+	st, err := s.device.Supply().Status()
+	if err != nil {
+		return errResult(err), nil
+	}
+	trips := supplyTrips(st)
+	result := map[string]interface{}{
+		"rails":   st.Rails,
+		"faults":  st.Faults,
+		"tripped": len(trips) > 0,
+	}
+	if monitor == 0 {
+		return jsonResult(result), nil
+	}
+
+	// Poll for the monitoring time and warn the client of every protection
+	// that trips, including those already tripped at the start.
+	srv := server.ServerFromContext(ctx)
+	start := time.Now()
+	seen := map[string]bool{}
+	events := []map[string]interface{}{}
+	for {
+		elapsed := time.Since(start).Seconds()
+		for _, trip := range trips {
+			if seen[trip] {
+				continue
+			}
+			seen[trip] = true
+			events = append(events, map[string]interface{}{"time": elapsed, "fault": trip})
+			if srv != nil {
+				_ = srv.SendNotificationToClient(ctx, logNotification, map[string]interface{}{
+					"level":  "warning",
+					"logger": "discovery_supplies",
+					"data":   fmt.Sprintf("supply protection tripped after %s: %s", s.format.quantity(elapsed, "s", "%.1f s"), trip),
+				})
+			}
+		}
+		if elapsed >= monitor {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return errResult(fmt.Errorf("monitoring aborted: %w", ctx.Err())), nil
+		case <-time.After(time.Duration(math.Min(interval, monitor-elapsed) * float64(time.Second))):
+		}
+		if st, err = s.device.Supply().Status(); err != nil {
+			return errResult(err), nil
+		}
+		trips = supplyTrips(st)
+	}
+	result["rails"], result["faults"] = st.Rails, st.Faults
+	result["tripped"] = len(events) > 0
+	result["monitored"] = monitor
+	result["events"] = events
+	return jsonResult(result), nil
+}
+
+func (s *DiscoveryMCPServer) handleSuppliesClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.watchdog.disarm()
+	if err := s.device.Supply().Close(); err != nil {
+		return errResult(err), nil
+	}
+	s.usage.supply(false)
+	return mcp.NewToolResultText("Power supplies reset"), nil
+}
+
+// ==================== DMM Handlers ====================
+
+func (s *DiscoveryMCPServer) handleDMMOpen(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := s.device.DMM().Open(); err != nil {
+		return errResult(err), nil
+	}
+	return mcp.NewToolResultText("DMM initialized"), nil
+}
+
+func (s *DiscoveryMCPServer) handleDMMMeasure(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	mode := dwf.DMMMode(getInt(req.Params.Arguments, "mode", 1))
+	if _, ok := dmmModeNames[mode]; !ok {
+		return errResult(fmt.Errorf("invalid DMM mode %d: expected 0 to 11", mode)), nil
+	}
+	range_ := getFloat(req.Params.Arguments, "range", 0)
+	if range_ < 0 {
+		return errResult(fmt.Errorf("range must be positive or 0 for auto-range, got %g", range_)), nil
+	}
+	highZ := getBool(req.Params.Arguments, "high_impedance", false)
+	samples := getInt(req.Params.Arguments, "samples", 1)
+	if samples < 1 || samples > 1000 {
+		return errResult(fmt.Errorf("samples must be between 1 and 1000, got %d", samples)), nil
+	}
+
+	values := make([]float64, 0, samples)
+	var reading dwf.DMMReading
+	for range samples {
+		if err := ctx.Err(); err != nil {
+			return errResult(fmt.Errorf("measurement aborted after %d samples: %w", len(values), err)), nil
+		}
+		r, err := s.device.DMM().Measure(mode, range_, highZ)
+		if err != nil {
+			return errResult(err), nil
+		}
+		reading = r
+		values = append(values, r.Value)
+	}
+	s.usage.relay("dmm.mode", int(mode))
+	// Devices that do not name the units of the reading fall back to the
+	// units of the mode.
+	units := reading.Units
+	if units == "" {
+		units = dmmModeUnits[reading.Mode]
+	}
+	result := map[string]interface{}{
+		"unit":      units,
+		"range":     reading.Range,
+		"mode":      int(reading.Mode),
+		"mode_name": enumName(dmmModeNames, reading.Mode),
+	}
+	if len(reading.Aux) > 0 {
+		aux := make([]map[string]interface{}, len(reading.Aux))
+		for i, a := range reading.Aux {
+			aux[i] = map[string]interface{}{"name": a.Name, "value": a.Value, "unit": a.Units}
+		}
+		result["aux"] = aux
+	}
+	if samples == 1 {
+		result["value"] = reading.Value
+		result["text"] = s.format.quantity(reading.Value, units, "%.6f")
+		return jsonResult(result), nil
+	}
+	for k, v := range sampleStats(values) {
+		result[k] = v
+	}
+	return jsonResult(result), nil
+}
+
+func (s *DiscoveryMCPServer) handleDMMClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := s.device.DMM().Close(); err != nil {
+		return errResult(err), nil
+	}
+	return mcp.NewToolResultText("DMM reset"), nil
+}
+
+// ==================== Logic Analyzer Handlers ====================
+
+func (s *DiscoveryMCPServer) handleLogicOpen(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	channels, err := getIntList(req.Params.Arguments, "channels")
+	if err != nil {
+		return errResult(err), nil
+	}
+	cfg := dwf.LogicConfig{
+		SamplingFrequency: getFloat(req.Params.Arguments, "sampling_frequency", 100e6),
+		BufferSize:        getInt(req.Params.Arguments, "buffer_size", 0),
+		Threshold:         getFloat(req.Params.Arguments, "threshold", 0),
+		Channels:          channels,
+		SampleBits:        getInt(req.Params.Arguments, "sample_bits", 0),
+	}
+	if !slices.Contains([]int{0, 8, 16, 32}, cfg.SampleBits) {
+		return errResult(fmt.Errorf("sample_bits must be 8, 16 or 32, got %d", cfg.SampleBits)), nil
+	}
+	if _, ok := argsMap(req.Params.Arguments)["clock_channel"]; ok {
+		cfg.Sync = true
+		cfg.ClockChannel = getInt(req.Params.Arguments, "clock_channel", 0)
+		if cfg.ClockEdge, err = parseSlope(getString(req.Params.Arguments, "clock_edge", "either")); err != nil {
+			return errResult(err), nil
+		}
+	}
+	if err := s.device.Logic().Open(cfg); err != nil {
+		return errResult(err), nil
+	}
+	msg := "Logic analyzer initialized"
+	if cfg.Threshold > 0 {
+		msg += ", input threshold " + s.format.quantity(cfg.Threshold, "V", "%g V")
+	}
+	if len(channels) > 0 {
+		lines := make([]string, len(channels))
+		for i, line := range channels {
+			lines[i] = fmt.Sprintf("%d=DIO%d", i, line)
+		}
+		msg += ", channels " + strings.Join(lines, " ")
+	}
+	if cfg.SampleBits > 0 {
+		msg += fmt.Sprintf(", %d-bit samples", cfg.SampleBits)
+	}
+	if cfg.Sync {
+		msg += fmt.Sprintf(", sampling on %s edges of channel %d", enumName(slopeNames, cfg.ClockEdge), cfg.ClockChannel)
+	}
+	// The clock divider is an integer, so the rate is rarely the one asked for.
+	if rb, err := s.device.Logic().Config(); err == nil && rb.SampleRate > 0 {
+		msg += fmt.Sprintf(", sampling at %s (requested %s)",
+			s.format.quantity(rb.SampleRate, "Hz", "%.6g Hz"), s.format.quantity(cfg.SamplingFrequency, "Hz", "%.6g Hz"))
+	}
+	return mcp.NewToolResultText(msg), nil
+}
+
+func (s *DiscoveryMCPServer) handleLogicGetConfig(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	rb, err := s.device.Logic().Config()
+	if err != nil {
+		return errResult(err), nil
+	}
+	result := map[string]interface{}{
+		"internal_clock": rb.InternalClock,
+		"divider":        rb.Divider,
+		"sample_rate":    rb.SampleRate,
+		"buffer_size":    rb.BufferSize,
+		"sample_bits":    rb.SampleBits,
+	}
+	if rb.Sync {
+		result["sync"] = true
+	}
+	if rb.SampleRate > 0 {
+		result["buffer_time"] = float64(rb.BufferSize) / rb.SampleRate
+	}
+	if len(rb.Channels) > 0 {
+		result["channels"] = rb.Channels
+	}
+	return jsonResult(result), nil
+}
+
+// logicTimeBase rejects a capture whose samples cannot be converted to
+// times, so that the timing tools fail instead of dividing by zero.
+func logicTimeBase(capture dwf.LogicCapture) error {
+	if capture.Sync {
+		return fmt.Errorf("samples taken on clock edges have no time base; reopen the logic analyzer without clock_channel to measure timing")
+	}
+	if capture.SampleRate <= 0 {
+		return fmt.Errorf("unknown sample rate; open the logic analyzer first")
+	}
+	return nil
+}
+
+func (s *DiscoveryMCPServer) handleLogicTrigger(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := dwf.LogicTriggerConfig{
+		Enable:     getBool(req.Params.Arguments, "enable", true),
+		Channel:    getInt(req.Params.Arguments, "channel", 0),
+		Position:   getInt(req.Params.Arguments, "position", 0),
+		Timeout:    getFloat(req.Params.Arguments, "timeout", 0),
+		RisingEdge: getBool(req.Params.Arguments, "rising_edge", true),
+		LengthMin:  getFloat(req.Params.Arguments, "length_min", 0),
+		LengthMax:  getFloat(req.Params.Arguments, "length_max", 20),
+		Count:      getInt(req.Params.Arguments, "count", 1),
+	}
+	args := argsMap(req.Params.Arguments)
+	_, withPattern := args["pattern"]
+	mode := "edge"
+	if withPattern {
+		mode = "pattern"
+	}
+	mode = strings.ToLower(getString(args, "mode", mode))
+	switch {
+	case mode == "glitch":
+		if withPattern {
+			return errResult(fmt.Errorf("a glitch trigger takes no pattern")), nil
+		}
+		if err := glitchTrigger(args, &cfg); err != nil {
+			return errResult(err), nil
+		}
+	case protocolTriggers[mode] != "":
+		if withPattern {
+			return errResult(fmt.Errorf("a %s trigger takes no pattern", mode)), nil
+		}
+		if err := protocolTrigger(mode, args, &cfg); err != nil {
+			return errResult(err), nil
+		}
+	case mode == "pattern" && !withPattern:
+		return errResult(fmt.Errorf("mode pattern needs a pattern")), nil
+	case mode != "edge" && mode != "pattern":
+		return errResult(fmt.Errorf("invalid trigger mode %q: expected edge, pattern, glitch, i2c_start, i2c_stop, uart_start or spi_select", mode)), nil
+	}
+	if withPattern {
+		value, err := parseBits(args["pattern"])
+		if err != nil {
+			return errResult(fmt.Errorf("pattern: %w", err)), nil
+		}
+		mask := byteMask(value)
+		if m, ok := args["mask"]; ok {
+			if mask, err = parseBits(m); err != nil {
+				return errResult(fmt.Errorf("mask: %w", err)), nil
+			}
+		}
+		if mask == 0 {
+			return errResult(fmt.Errorf("mask selects no channels")), nil
+		}
+		if value&^mask != 0 {
+			return errResult(fmt.Errorf("pattern %#x has bits outside the mask %#x", value, mask)), nil
+		}
+		cfg.Mask, cfg.Value = mask, value
+	} else if _, ok := args["mask"]; ok {
+		return errResult(fmt.Errorf("mask needs a pattern")), nil
+	}
+	if err := s.device.Logic().SetTrigger(cfg); err != nil {
+		return errResult(err), nil
+	}
+	if desc := protocolTriggers[mode]; desc != "" {
+		return mcp.NewToolResultText("Logic trigger configured on the next " + desc), nil
+	}
+	switch mode {
+	case "pattern":
+		return mcp.NewToolResultText(fmt.Sprintf("Logic trigger configured on pattern %#x, mask %#x", cfg.Value, cfg.Mask)), nil
+	case "glitch":
+		polarity := "negative"
+		if cfg.RisingEdge {
+			polarity = "positive"
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Logic trigger configured on %s pulses on channel %d from %s to %s wide",
+			polarity, cfg.Channel, s.format.quantity(cfg.LengthMin, "s", "%g s"), s.format.quantity(cfg.LengthMax, "s", "%g s"))), nil
+	}
+	return mcp.NewToolResultText("Logic trigger configured"), nil
+}
+
+// protocolTriggers describes the protocol event of each trigger preset.
+var protocolTriggers = map[string]string{
+	"i2c_start":  "I2C start condition (SDA falling while SCL is high)",
+	"i2c_stop":   "I2C stop condition (SDA rising while SCL is high)",
+	"uart_start": "UART start bit (RX falling from idle)",
+	"spi_select": "SPI chip select (CS falling)",
+}
+
+// protocolTrigger sets up cfg for a protocol trigger preset from the lines
+// of the protocol in args: sda and scl for I2C, rx for UART and cs for SPI.
+func protocolTrigger(mode string, args map[string]any, cfg *dwf.LogicTriggerConfig) error {
+	line := func(name string) (int, error) {
+		v := getInt(args, name, -1)
+		if v < 0 || v > 31 {
+			return 0, fmt.Errorf("a %s trigger needs %s as a logic channel from 0 to 31", mode, name)
+		}
+		return v, nil
+	}
+	switch mode {
+	case "i2c_start", "i2c_stop":
+		sda, err := line("sda")
+		if err != nil {
+			return err
+		}
+		scl, err := line("scl")
+		if err != nil {
+			return err
+		}
+		if sda == scl {
+			return fmt.Errorf("sda and scl must be different channels")
+		}
+		cfg.Mask, cfg.Value, cfg.Qualified = 1<<scl, 1<<scl, true
+		cfg.Channel, cfg.RisingEdge = sda, mode == "i2c_stop"
+	case "uart_start":
+		rx, err := line("rx")
+		if err != nil {
+			return err
+		}
+		cfg.Channel, cfg.RisingEdge = rx, false
+	case "spi_select":
+		cs, err := line("cs")
+		if err != nil {
+			return err
+		}
+		cfg.Channel, cfg.RisingEdge = cs, false
+	}
+	return nil
+}
+
+// glitchTrigger sets up cfg to trigger on a pulse of the given polarity
+// whose width lies between min_width and max_width. The pulse starts on the
+// trigger edge and ends on the reset edge, and the trigger length bounds
+// the time between them.
+func glitchTrigger(args map[string]any, cfg *dwf.LogicTriggerConfig) error {
+	switch polarity := strings.ToLower(getString(args, "polarity", "positive")); polarity {
+	case "positive":
+		cfg.RisingEdge = true
+	case "negative":
+		cfg.RisingEdge = false
+	default:
+		return fmt.Errorf("invalid polarity %q: expected positive or negative", polarity)
+	}
+	maxWidth := getFloat(args, "max_width", 0)
+	if maxWidth <= 0 {
+		return fmt.Errorf("a glitch trigger needs a positive max_width in seconds")
+	}
+	minWidth := getFloat(args, "min_width", 0)
+	if minWidth < 0 || minWidth >= maxWidth {
+		return fmt.Errorf("min_width must be at least 0 and below max_width, got %g", minWidth)
+	}
+	cfg.LengthMin, cfg.LengthMax = minWidth, maxWidth
+	return nil
+}
+
+// parseBits reads a bit field given as a whole number or as a string in
+// decimal, hex (0x) or binary (0b), such as "0xA5" or "0b1010_0101".
+func parseBits(v any) (uint32, error) {
+	switch v := v.(type) {
+	case float64:
+		if v >= 0 && v <= math.MaxUint32 && v == math.Trunc(v) {
+			return uint32(v), nil
+		}
+	case string:
+		if n, err := strconv.ParseUint(strings.TrimSpace(v), 0, 32); err == nil {
+			return uint32(n), nil
+		}
+	}
+	return 0, fmt.Errorf("expected a 32-bit number such as 165, \"0xA5\" or \"0b10100101\", got %v", v)
+}
+
+// byteMask returns the mask of the whole bytes up to the highest set bit of
+// v, at least one byte.
+func byteMask(v uint32) uint32 {
+	mask := uint32(0xFF)
+	for v&^mask != 0 {
+		mask = mask<<8 | 0xFF
+	}
+	return mask
+}
+
+func (s *DiscoveryMCPServer) handleLogicRecord(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ch := getInt(req.Params.Arguments, "channel", 0)
+	enc, err := parseSampleEncoding(req.Params.Arguments)
+	if err != nil {
+		return errResult(err), nil
+	}
+	rle := getBool(req.Params.Arguments, "rle", false)
+	if rle && enc != encodingJSON {
+		return errResult(fmt.Errorf("rle returns runs instead of a sample array and cannot be combined with encoding %s", enc)), nil
+	}
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+	data, err := s.device.Logic().Record(ctx, ch)
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	s.usage.captured("logic", 1)
+	result := timeAxis(map[string]interface{}{
+		"channel": ch,
+		"samples": len(data),
+		"data":    data,
+	}, s.device.Logic().Timing(), len(data), 1)
+	if rle {
+		runs := dwf.RunLengths(data)
+		pairs := make([][2]int, len(runs))
+		for i, r := range runs {
+			pairs[i] = [2]int{int(r.Value), r.Count}
+		}
+		delete(result, "data")
+		result["runs"] = pairs
+	}
+	if enc != encodingJSON {
+		values := make([]float64, len(data))
+		for i, v := range data {
+			values[i] = float64(v)
+		}
+		encodeSamples(result, "data", values, enc)
+	}
+	return jsonResult(result), nil
+}
+
+func (s *DiscoveryMCPServer) handleLogicDelta(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	fromCh := getInt(req.Params.Arguments, "from_channel", 0)
+	toCh := getInt(req.Params.Arguments, "to_channel", 0)
+	if fromCh < 0 || fromCh > 31 {
+		return errResult(fmt.Errorf("from_channel must be a logic channel from 0 to 31, got %d", fromCh)), nil
+	}
+	if toCh < 0 || toCh > 31 {
+		return errResult(fmt.Errorf("to_channel must be a logic channel from 0 to 31, got %d", toCh)), nil
+	}
+	fromName := getString(req.Params.Arguments, "from_edge", "rising")
+	toName := getString(req.Params.Arguments, "to_edge", "rising")
+	fromEdge, err := parseSlope(fromName)
+	if err != nil {
+		return errResult(err), nil
+	}
+	toEdge, err := parseSlope(toName)
+	if err != nil {
+		return errResult(err), nil
+	}
+
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+	capture, err := s.device.Logic().Capture(ctx)
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	if err := logicTimeBase(capture); err != nil {
+		return errResult(err), nil
+	}
+	s.usage.captured("logic", 1)
+
+	from := dwf.FindEdge(capture.Samples, fromCh, fromEdge, 0)
+	if from < 0 {
+		return errResult(fmt.Errorf("no %s edge found on DIO %d", fromName, fromCh)), nil
+	}
+	// On another line an edge in the same sample is a delay of zero; on the
+	// same line the from edge itself is not the edge it waits for.
+	start := from
+	if toCh == fromCh {
+		start = from + 1
+	}
+	to := dwf.FindEdge(capture.Samples, toCh, toEdge, start)
+	if to < 0 {
+		return errResult(fmt.Errorf("no %s edge found on DIO %d after sample %d", toName, toCh, from)), nil
+	}
+
+	// Each edge is only known to within one sample period, so the
+	// difference between them is uncertain by up to one period either way.
+	period := 1 / capture.SampleRate
+	return jsonResult(map[string]interface{}{
+		"from_channel": fromCh,
+		"to_channel":   toCh,
+		"from_sample":  from,
+		"to_sample":    to,
+		"sample_rate":  capture.SampleRate,
+		"delay":        float64(to-from) * period,
+		"uncertainty":  period,
+	}), nil
+}
+
+// timingResult returns timing statistics for a result, nil without any
+// intervals.
+func timingResult(st dwf.TimingStats) map[string]interface{} {
+	if st.Count == 0 {
+		return nil
+	}
+	return map[string]interface{}{"count": st.Count, "min": st.Min, "max": st.Max, "mean": st.Mean}
+}
+
+func (s *DiscoveryMCPServer) handleLogicMeasure(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	ch := getInt(args, "channel", -1)
+	if ch < 0 || ch > 31 {
+		return errResult(fmt.Errorf("channel must be a logic channel from 0 to 31, got %d", ch)), nil
+	}
+	_, withRef := args["ref_channel"]
+	ref := getInt(args, "ref_channel", -1)
+	if withRef && (ref < 0 || ref > 31) {
+		return errResult(fmt.Errorf("ref_channel must be a logic channel from 0 to 31, got %d", ref)), nil
+	}
+	refName := getString(args, "ref_edge", "rising")
+	refEdge, err := parseSlope(refName)
+	if err != nil {
+		return errResult(err), nil
+	}
+	edgeName := getString(args, "edge", "rising")
+	edge, err := parseSlope(edgeName)
+	if err != nil {
+		return errResult(err), nil
+	}
+
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+	capture, err := s.device.Logic().Capture(ctx)
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	if err := logicTimeBase(capture); err != nil {
+		return errResult(err), nil
+	}
+	s.usage.captured("logic", 1)
+
+	m := dwf.MeasureLogic(capture.Samples, capture.SampleRate, ch)
+	result := map[string]interface{}{
+		"channel":       ch,
+		"sample_rate":   capture.SampleRate,
+		"samples":       len(capture.Samples),
+		"resolution":    1 / capture.SampleRate,
+		"rising_edges":  m.RisingEdges,
+		"falling_edges": m.FallingEdges,
+		"frequency":     m.Frequency,
+		"duty_cycle":    m.DutyCycle,
+	}
+	for key, st := range map[string]dwf.TimingStats{"high_time": m.HighTime, "low_time": m.LowTime, "period": m.Period} {
+		if r := timingResult(st); r != nil {
+			result[key] = r
+		}
+	}
+	if m.RisingEdges+m.FallingEdges == 0 {
+		level := 0
+		if len(capture.Samples) > 0 && capture.Samples[0]&(1<<uint(ch)) != 0 {
+			level = 1
+		}
+		result["level"] = level
+	}
+	if withRef {
+		delay := dwf.MeasureLogicDelay(capture.Samples, capture.SampleRate, ref, refEdge, ch, edge)
+		d := timingResult(delay)
+		if d == nil {
+			d = map[string]interface{}{"count": 0}
+		}
+		d["ref_channel"], d["ref_edge"], d["edge"] = ref, refName, edgeName
+		result["delay"] = d
+	}
+	return jsonResult(result), nil
+}
+
+func (s *DiscoveryMCPServer) handleLogicCount(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	if _, ok := args["channel"]; !ok {
+		return errResult(fmt.Errorf("channel is required")), nil
+	}
+	ch := getInt(args, "channel", 0)
+	if ch < 0 || ch > 31 {
+		return errResult(fmt.Errorf("channel must be a logic channel from 0 to 31, got %d", ch)), nil
+	}
+	edgeName := getString(args, "edge", "rising")
+	edge, err := parseSlope(edgeName)
+	if err != nil {
+		return errResult(err), nil
+	}
+	method := strings.ToLower(getString(args, "method", "auto"))
+	_, withGate := args["gate_time"]
+	gate := getFloat(args, "gate_time", 1)
+	limits, err := s.device.Logic().CounterLimits()
+	if err != nil {
+		return errResult(err), nil
+	}
+	switch method {
+	case "auto":
+		method = "software"
+		if limits.Max > 0 {
+			method = "counter"
+		}
+	case "counter":
+		if limits.Max <= 0 {
+			return errResult(fmt.Errorf("this device has no digital-in frequency counter; use method software")), nil
+		}
+	case "software":
+	default:
+		return errResult(fmt.Errorf("unknown method %q (expected auto, counter or software)", method)), nil
+	}
+
+	if method == "software" && withGate {
+		return errResult(fmt.Errorf("gate_time applies to the counter only; software counting uses the capture length, set by the logic analyzer's buffer size and sampling rate")), nil
+	}
+
+	result := map[string]interface{}{"channel": ch, "method": method, "edge": edgeName}
+	if method == "software" {
+		actx, cancel, err := acquisitionContext(ctx, args, 1)
+		if err != nil {
+			return errResult(err), nil
+		}
+		defer cancel()
+		capture, err := s.device.Logic().Capture(actx)
+		if err != nil {
+			return errResult(acquisitionError(err)), nil
+		}
+		if err := logicTimeBase(capture); err != nil {
+			return errResult(err), nil
+		}
+		s.usage.captured("logic", 1)
+		m := dwf.MeasureLogic(capture.Samples, capture.SampleRate, ch)
+		count := m.RisingEdges
+		switch edge {
+		case dwf.TriggerSlopeFall:
+			count = m.FallingEdges
+		case dwf.TriggerSlopeEither:
+			count += m.FallingEdges
+		}
+		result["count"] = count
+		result["frequency"] = m.Frequency
+		result["gate_time"] = float64(len(capture.Samples)) / capture.SampleRate
+	} else {
+		if gate <= 0 || gate > limits.Max {
+			return errResult(fmt.Errorf("gate_time must be between 0 and %g s, got %g", limits.Max, gate)), nil
+		}
+		// A gate always ends, signal or not; the deadline only guards
+		// against a device that stops responding.
+		cctx, cancel := context.WithTimeout(ctx, time.Duration((2*gate+1)*float64(time.Second)))
+		defer cancel()
+		r, err := s.device.Logic().Count(cctx, dwf.LogicCounterConfig{Channel: ch, GateTime: gate, Edge: edge})
+		if err != nil {
+			return errResult(err), nil
+		}
+		result["count"] = r.Count
+		result["frequency"] = r.Frequency
+		result["gate_time"] = r.GateTime
+	}
+	result["period"] = math.NaN()
+	if f := result["frequency"].(float64); f > 0 {
+		result["period"] = 1 / f
+	}
+	return jsonResult(result), nil
+}
+
+func (s *DiscoveryMCPServer) handleLogicDecodeUART(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := dwf.UARTConfig{
+		RX:       getInt(req.Params.Arguments, "rx", -1),
+		TX:       -1,
+		BaudRate: getInt(req.Params.Arguments, "baud_rate", 9600),
+		Parity:   getInt(req.Params.Arguments, "parity", 0),
+		DataBits: getInt(req.Params.Arguments, "data_bits", 8),
+		StopBits: getInt(req.Params.Arguments, "stop_bits", 1),
+	}
+	if cfg.RX < 0 || cfg.RX > 31 {
+		return errResult(fmt.Errorf("rx must be a logic channel from 0 to 31, got %d", cfg.RX)), nil
+	}
+	if cfg.BaudRate <= 0 {
+		return errResult(fmt.Errorf("baud_rate must be positive, got %d", cfg.BaudRate)), nil
+	}
+	if cfg.Parity < 0 || cfg.Parity > 2 {
+		return errResult(fmt.Errorf("parity must be 0 (none), 1 (odd) or 2 (even), got %d", cfg.Parity)), nil
+	}
+	if cfg.DataBits < 5 || cfg.DataBits > 9 {
+		return errResult(fmt.Errorf("data_bits must be between 5 and 9, got %d", cfg.DataBits)), nil
+	}
+	if cfg.StopBits < 1 || cfg.StopBits > 2 {
+		return errResult(fmt.Errorf("stop_bits must be 1 or 2, got %d", cfg.StopBits)), nil
+	}
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+	capture, err := s.device.Logic().Capture(ctx)
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	if err := logicTimeBase(capture); err != nil {
+		return errResult(err), nil
+	}
+	s.usage.captured("logic", 1)
+	// Reading each bit at its center needs a few samples per bit.
+	if capture.SampleRate < 4*float64(cfg.BaudRate) {
+		return errResult(fmt.Errorf("a sample rate of %g Hz is too low for %d baud; open the logic analyzer with at least %d Hz", capture.SampleRate, cfg.BaudRate, 4*cfg.BaudRate)), nil
+	}
+
+	decoded := dwf.DecodeUART(capture.Samples, capture.SampleRate, cfg)
+	frames := make([]map[string]interface{}, 0, len(decoded))
+	var data []byte
+	bad := 0
+	for _, f := range decoded {
+		frame := map[string]interface{}{
+			"time":  float64(f.Start) / capture.SampleRate,
+			"value": f.Data,
+		}
+		if f.ParityError {
+			frame["parity_error"] = true
+		}
+		if f.FramingError {
+			frame["framing_error"] = true
+		}
+		if f.Break {
+			frame["break"] = true
+		}
+		if f.Incomplete {
+			frame["incomplete"] = true
+		}
+		if f.ParityError || f.FramingError || f.Incomplete {
+			bad++
+		} else if cfg.DataBits <= 8 {
+			data = append(data, byte(f.Data))
+		}
+		frames = append(frames, frame)
+	}
+	result := map[string]interface{}{
+		"sample_rate": capture.SampleRate,
+		"samples":     len(capture.Samples),
+		"frames":      frames,
+		"errors":      bad,
+	}
+	if cfg.DataBits <= 8 {
+		result["data"] = fmt.Sprintf("%x", data)
+		result["text"] = string(data)
+	}
+	return jsonResult(result), nil
+}
+
+func (s *DiscoveryMCPServer) handleLogicClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := s.device.Logic().Close(); err != nil {
+		return errResult(err), nil
+	}
+	return mcp.NewToolResultText("Logic analyzer reset"), nil
 }
 
-// ==================== Wavegen Handlers ====================
+// ==================== Pattern Generator Handlers ====================
 
-func (s *DiscoveryMCPServer) handleWavegenGenerate(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	cfg := dwf.WavegenConfig{
-		Channel:   getInt(req.Params.Arguments, "channel", 1),
-		Function:  dwf.WavegenFunc(getInt(req.Params.Arguments, "function", 1)),
-		Offset:    getFloat(req.Params.Arguments, "offset", 0),
+// parsePatternData decodes the data of a custom pattern into one element per
+// bit in output order. A bit string lists the bits as 0 and 1, ignoring
+// spaces and underscores; hex and base64 data are sent most significant bit
+// first, so each hex digit gives four bits and each base64 byte eight.
+func parsePatternData(format, data string) ([]uint16, error) {
+	var raw []byte
+	switch format {
+	case "bits":
+		var bits []uint16
+		for _, c := range data {
+			switch c {
+			case '0', '1':
+				bits = append(bits, uint16(c-'0'))
+			case ' ', '_':
+			default:
+				return nil, fmt.Errorf("invalid bit %q in pattern data", c)
+			}
+		}
+		return bits, nil
+	case "hex":
+		data = strings.TrimPrefix(strings.ReplaceAll(data, " ", ""), "0x")
+		bits := make([]uint16, 0, 4*len(data))
+		for _, c := range data {
+			v, err := strconv.ParseUint(string(c), 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hex digit %q in pattern data", c)
+			}
+			for b := 3; b >= 0; b-- {
+				bits = append(bits, uint16(v>>b&1))
+			}
+		}
+		return bits, nil
+	case "base64":
+		var err error
+		if raw, err = base64.StdEncoding.DecodeString(data); err != nil {
+			return nil, fmt.Errorf("invalid base64 pattern data: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown data_format %q (expected bits, hex or base64)", format)
+	}
+	bits := make([]uint16, 0, 8*len(raw))
+	for _, v := range raw {
+		for b := 7; b >= 0; b-- {
+			bits = append(bits, uint16(v>>b&1))
+		}
+	}
+	return bits, nil
+}
+
+func (s *DiscoveryMCPServer) handlePatternGenerate(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := dwf.PatternConfig{
+		Channel:   getInt(req.Params.Arguments, "channel", 0),
+		Function:  dwf.DigitalOutType(getInt(req.Params.Arguments, "function", 0)),
 		Frequency: getFloat(req.Params.Arguments, "frequency", 1000),
-		Amplitude: getFloat(req.Params.Arguments, "amplitude", 1),
-		Symmetry:  getFloat(req.Params.Arguments, "symmetry", 50),
+		DutyCycle: getFloat(req.Params.Arguments, "duty_cycle", 50),
 		Wait:      getFloat(req.Params.Arguments, "wait", 0),
-		RunTime:   getFloat(req.Params.Arguments, "run_time", 0),
 		Repeat:    getInt(req.Params.Arguments, "repeat", 0),
+		RunTime:   getInt(req.Params.Arguments, "run_time", 0),
+
+		TriggerSource:     dwf.TriggerSource(getInt(req.Params.Arguments, "trigger_source", 0)),
+		TriggerEdgeRising: getBool(req.Params.Arguments, "trigger_edge_rising", true),
 	}
-	if err := s.device.Wavegen().Generate(cfg); err != nil {
-		return errResult(err), nil
+	if cfg.TriggerSource < dwf.TrigSrcNone || cfg.TriggerSource > dwf.TrigSrcExternal4 {
+		return errResult(fmt.Errorf("trigger_source must be between %d and %d, got %d", dwf.TrigSrcNone, dwf.TrigSrcExternal4, cfg.TriggerSource)), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("Generating waveform on channel %d", cfg.Channel)), nil
-}
-
-func (s *DiscoveryMCPServer) handleWavegenEnable(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	ch := getInt(req.Params.Arguments, "channel", 1)
-	if err := s.device.Wavegen().Enable(ch); err != nil {
+	cfg.TriggerEnabled = cfg.TriggerSource != dwf.TrigSrcNone
+	if _, ok := argsMap(req.Params.Arguments)["trigger_edge_rising"]; ok && !cfg.TriggerEnabled {
+		return errResult(fmt.Errorf("trigger_edge_rising needs a trigger_source")), nil
+	}
+	var err error
+	if cfg.IdleState, err = parseDigitalIdle(getString(req.Params.Arguments, "idle_state", "init")); err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("Wavegen channel %d enabled", ch)), nil
-}
-
-func (s *DiscoveryMCPServer) handleWavegenDisable(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	ch := getInt(req.Params.Arguments, "channel", 1)
-	if err := s.device.Wavegen().Disable(ch); err != nil {
+	if cfg.Output, err = parseOutput(getString(req.Params.Arguments, "output", "push-pull")); err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("Wavegen channel %d disabled", ch)), nil
-}
-
-func (s *DiscoveryMCPServer) handleWavegenClose(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	ch := getInt(req.Params.Arguments, "channel", 1)
-	if err := s.device.Wavegen().Close(ch); err != nil {
+	if data := getString(req.Params.Arguments, "data", ""); data != "" {
+		if cfg.Function != dwf.DigitalOutTypeCustom {
+			return errResult(fmt.Errorf("data applies to the custom function (1) only")), nil
+		}
+		if cfg.Data, err = parsePatternData(getString(req.Params.Arguments, "data_format", "bits"), data); err != nil {
+			return errResult(err), nil
+		}
+	}
+	prbs, err := s.prbsPattern(argsMap(req.Params.Arguments), &cfg)
+	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("Wavegen channel %d reset", ch)), nil
-}
-
-// ==================== Power Supply Handlers ====================
-
-func (s *DiscoveryMCPServer) handleSuppliesSwitch(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	cfg := dwf.SuppliesConfig{
-		MasterState:     getBool(req.Params.Arguments, "master_state", false),
-		PositiveState:   getBool(req.Params.Arguments, "positive_state", false),
-		NegativeState:   getBool(req.Params.Arguments, "negative_state", false),
-		State:           getBool(req.Params.Arguments, "state", false),
-		PositiveVoltage: getFloat(req.Params.Arguments, "positive_voltage", 0),
-		NegativeVoltage: getFloat(req.Params.Arguments, "negative_voltage", 0),
-		Voltage:         getFloat(req.Params.Arguments, "voltage", 0),
-		PositiveCurrent: getFloat(req.Params.Arguments, "positive_current", 0),
-		NegativeCurrent: getFloat(req.Params.Arguments, "negative_current", 0),
-		Current:         getFloat(req.Params.Arguments, "current", 0),
+	if cfg.Function == dwf.DigitalOutTypeCustom && len(cfg.Data) == 0 {
+		return errResult(fmt.Errorf("the custom function needs data")), nil
 	}
-	if err := s.device.Supply().Switch(cfg); err != nil {
+	st, err := s.device.Pattern().Generate(cfg)
+	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText("Power supplies configured"), nil
+	result := map[string]interface{}{
+		"message": fmt.Sprintf("Pattern generated on DIO %d", cfg.Channel),
+		"output":  enumName(outputNames, cfg.Output),
+		"idle":    enumName(digitalIdleNames, cfg.IdleState),
+	}
+	if len(cfg.Data) > 0 {
+		result["bits"] = len(cfg.Data)
+	}
+	if prbs != nil {
+		result["prbs"] = prbs
+	}
+	return jsonResult(patternRunResult(result, st)), nil
 }
 
-func (s *DiscoveryMCPServer) handleSuppliesClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	if err := s.device.Supply().Close(); err != nil {
-		return errResult(err), nil
+// prbsPattern replaces the device's unseeded random function by a
+// reproducible PRBS loaded as a custom pattern when prbs is given. It
+// returns the order, seed and period of the sequence for the result, nil
+// without prbs.
+func (s *DiscoveryMCPServer) prbsPattern(args map[string]any, cfg *dwf.PatternConfig) (map[string]interface{}, error) {
+	if _, ok := args["prbs"]; !ok {
+		if _, ok := args["seed"]; ok {
+			return nil, fmt.Errorf("seed applies to a prbs pattern only")
+		}
+		return nil, nil
 	}
-	return mcp.NewToolResultText("Power supplies reset"), nil
+	if cfg.Function != dwf.DigitalOutTypeRandom {
+		return nil, fmt.Errorf("prbs applies to the random function (2) only")
+	}
+	if cfg.Data != nil {
+		return nil, fmt.Errorf("give either prbs or data, not both")
+	}
+	order := getInt(args, "prbs", 0)
+	if !slices.Contains(dwf.PRBSOrders, order) {
+		return nil, fmt.Errorf("prbs must be one of %v, got %d", dwf.PRBSOrders, order)
+	}
+	seed := uint32(1)<<order - 1
+	if v, ok := args["seed"]; ok {
+		var err error
+		if seed, err = parseBits(v); err != nil {
+			return nil, fmt.Errorf("seed: %w", err)
+		}
+	}
+	maxBits, err := s.device.Pattern().MaxData(cfg.Channel)
+	if err != nil {
+		return nil, err
+	}
+	period := 1<<order - 1
+	length := getInt(args, "length", min(period, maxBits))
+	if length <= 0 || length > maxBits {
+		return nil, fmt.Errorf("length must be between 1 and the %d-bit buffer of DIO %d, got %d", maxBits, cfg.Channel, length)
+	}
+	if cfg.Data, err = dwf.PRBS(order, seed, length); err != nil {
+		return nil, err
+	}
+	cfg.Function = dwf.DigitalOutTypeCustom
+	// A buffer shorter than the period repeats from the seed, which a
+	// bit-error-rate receiver must expect.
+	return map[string]interface{}{
+		"order":     order,
+		"seed":      seed,
+		"period":    period,
+		"truncated": length < period,
+	}, nil
 }
 
-// ==================== DMM Handlers ====================
+// patternRunResult adds the shared run control of the pattern generator and
+// any channels whose run control it overrides to result.
+func patternRunResult(result map[string]interface{}, st dwf.PatternSettings) map[string]interface{} {
+	run := map[string]interface{}{
+		"wait":     st.Run.Wait,
+		"repeat":   st.Run.Repeat,
+		"run_time": st.Run.RunTime,
+	}
+	if st.Run.TriggerEnabled {
+		run["trigger_source"] = int(st.Run.TriggerSource)
+		run["trigger_edge_rising"] = st.Run.TriggerEdgeRising
+	}
+	result["run"] = run
+	result["channels"] = st.Channels
+	if len(st.Conflicts) > 0 {
+		result["conflicts"] = st.Conflicts
+		result["warning"] = "wait, repeat, run time and trigger are shared by all pattern channels; the settings of the other channels listed in conflicts were overridden"
+	}
+	return result
+}
 
-func (s *DiscoveryMCPServer) handleDMMOpen(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	if err := s.device.DMM().Open(); err != nil {
+func (s *DiscoveryMCPServer) handleDIOToggle(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	cfg := dwf.ToggleConfig{
+		Channel:   getInt(args, "channel", 0),
+		Frequency: getFloat(args, "frequency", 0),
+		Count:     getInt(args, "count", 0),
+	}
+	if cfg.Frequency <= 0 {
+		return errResult(fmt.Errorf("frequency must be positive")), nil
+	}
+	if cfg.Count < 0 {
+		return errResult(fmt.Errorf("count must not be negative")), nil
+	}
+	var err error
+	if cfg.Output, err = parseOutput(getString(args, "output", "push-pull")); err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText("DMM initialized"), nil
-}
-
-func (s *DiscoveryMCPServer) handleDMMMeasure(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	mode := dwf.DMMMode(getInt(req.Params.Arguments, "mode", 1))
-	range_ := getFloat(req.Params.Arguments, "range", 0)
-	highZ := getBool(req.Params.Arguments, "high_impedance", false)
-
-	value, err := s.device.DMM().Measure(mode, range_, highZ)
+	if args["duration"] != nil {
+		if args["count"] != nil {
+			return errResult(fmt.Errorf("give either count or duration, not both")), nil
+		}
+		duration := getFloat(args, "duration", 0)
+		if duration <= 0 {
+			return errResult(fmt.Errorf("duration must be positive")), nil
+		}
+		cfg.Count = max(1, int(math.Round(duration*cfg.Frequency)))
+	}
+	st, err := s.device.Pattern().Toggle(cfg)
 	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("%.6f", value)), nil
+	result := map[string]interface{}{
+		"message":             fmt.Sprintf("Toggling DIO %d at %s", cfg.Channel, s.format.quantity(st.Frequency, "Hz", "%g Hz")),
+		"channel":             cfg.Channel,
+		"frequency":           st.Frequency,
+		"requested_frequency": cfg.Frequency,
+		"divider":             st.Divider,
+		"output":              enumName(outputNames, cfg.Output),
+	}
+	if cfg.Count > 0 {
+		result["count"] = cfg.Count
+		result["duration"] = st.RunTime
+	}
+	return jsonResult(patternRunResult(result, st.Pattern)), nil
 }
 
-func (s *DiscoveryMCPServer) handleDMMClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	if err := s.device.DMM().Close(); err != nil {
+func (s *DiscoveryMCPServer) handlePatternClock(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	list, ok := argsMap(req.Params.Arguments)["clocks"].([]interface{})
+	if !ok || len(list) == 0 {
+		return errResult(fmt.Errorf("clocks must be a non-empty array")), nil
+	}
+	cfgs := make([]dwf.ClockConfig, len(list))
+	for i, item := range list {
+		args, ok := item.(map[string]interface{})
+		if !ok {
+			return errResult(fmt.Errorf("clock %d: must be an object", i)), nil
+		}
+		if _, ok := args["channel"]; !ok {
+			return errResult(fmt.Errorf("clock %d: channel is required", i)), nil
+		}
+		cfgs[i] = dwf.ClockConfig{
+			Channel:   getInt(args, "channel", 0),
+			Frequency: getFloat(args, "frequency", 0),
+			DutyCycle: getFloat(args, "duty_cycle", 50),
+			Phase:     getFloat(args, "phase", 0),
+		}
+		if cfgs[i].Frequency <= 0 {
+			return errResult(fmt.Errorf("clock %d: frequency must be positive", i)), nil
+		}
+	}
+	st, err := s.device.Pattern().Clocks(cfgs)
+	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText("DMM reset"), nil
+	clocks := make([]map[string]interface{}, len(st.Clocks))
+	lines := make([]string, len(st.Clocks))
+	for i, c := range st.Clocks {
+		clocks[i] = map[string]interface{}{
+			"channel":             c.Channel,
+			"frequency":           c.Frequency,
+			"requested_frequency": cfgs[i].Frequency,
+			"duty_cycle":          c.DutyCycle,
+			"phase":               c.Phase,
+			"divider":             c.Divider,
+		}
+		lines[i] = fmt.Sprintf("DIO %d", c.Channel)
+	}
+	return jsonResult(patternRunResult(map[string]interface{}{
+		"message": "Clocks running on " + strings.Join(lines, ", "),
+		"clocks":  clocks,
+	}, st.Pattern)), nil
 }
 
-// ==================== Logic Analyzer Handlers ====================
-
-func (s *DiscoveryMCPServer) handleLogicOpen(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	cfg := dwf.LogicConfig{
-		SamplingFrequency: getFloat(req.Params.Arguments, "sampling_frequency", 100e6),
-		BufferSize:        getInt(req.Params.Arguments, "buffer_size", 0),
+func (s *DiscoveryMCPServer) handlePatternROM(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	inputs, err := getIntList(req.Params.Arguments, "inputs")
+	if err != nil {
+		return errResult(err), nil
 	}
-	if err := s.device.Logic().Open(cfg); err != nil {
+	outputs, err := getIntList(req.Params.Arguments, "outputs")
+	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText("Logic analyzer initialized"), nil
-}
-
-func (s *DiscoveryMCPServer) handleLogicTrigger(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	cfg := dwf.LogicTriggerConfig{
-		Enable:     getBool(req.Params.Arguments, "enable", true),
-		Channel:    getInt(req.Params.Arguments, "channel", 0),
-		Position:   getInt(req.Params.Arguments, "position", 0),
-		Timeout:    getFloat(req.Params.Arguments, "timeout", 0),
-		RisingEdge: getBool(req.Params.Arguments, "rising_edge", true),
-		LengthMin:  getFloat(req.Params.Arguments, "length_min", 0),
-		LengthMax:  getFloat(req.Params.Arguments, "length_max", 20),
-		Count:      getInt(req.Params.Arguments, "count", 1),
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return errResult(fmt.Errorf("inputs and outputs are required")), nil
 	}
-	if err := s.device.Logic().SetTrigger(cfg); err != nil {
+	list, ok := argsMap(req.Params.Arguments)["table"].([]interface{})
+	if !ok {
+		return errResult(fmt.Errorf("table must be an array")), nil
+	}
+	cfg := dwf.ROMConfig{Inputs: inputs, Outputs: outputs, Table: make([]uint32, len(list))}
+	for i, v := range list {
+		if cfg.Table[i], err = parseBits(v); err != nil {
+			return errResult(fmt.Errorf("table[%d]: %w", i, err)), nil
+		}
+		if cfg.Table[i]>>len(outputs) != 0 {
+			return errResult(fmt.Errorf("table[%d] = %#x has bits beyond the %d outputs", i, cfg.Table[i], len(outputs))), nil
+		}
+	}
+	st, err := s.device.Pattern().ROM(cfg)
+	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText("Logic trigger configured"), nil
+	return jsonResult(patternRunResult(map[string]interface{}{
+		"message":       fmt.Sprintf("ROM logic driving %d outputs from %d inputs", len(outputs), len(inputs)),
+		"inputs":        inputs,
+		"outputs":       outputs,
+		"address_lines": st.AddressLines,
+	}, st.Pattern)), nil
 }
 
-func (s *DiscoveryMCPServer) handleLogicRecord(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	ch := getInt(req.Params.Arguments, "channel", 0)
-	data, err := s.device.Logic().Record(ch)
+func (s *DiscoveryMCPServer) handlePatternSetDuty(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	if _, ok := args["duty_cycle"]; !ok {
+		return errResult(fmt.Errorf("duty_cycle is required")), nil
+	}
+	ch := getInt(args, "channel", 0)
+	duty := getFloat(args, "duty_cycle", 0)
+	if duty < 0 || duty > 100 {
+		return errResult(fmt.Errorf("duty_cycle must be between 0 and 100, got %g", duty)), nil
+	}
+	st, err := s.device.Pattern().SetDuty(ch, duty)
 	if err != nil {
 		return errResult(err), nil
 	}
-	return jsonResult(map[string]interface{}{
-		"channel": ch,
-		"samples": len(data),
-		"data":    data,
-	}), nil
+	result := map[string]interface{}{
+		"channel":              ch,
+		"duty_cycle":           st.DutyCycle,
+		"requested_duty_cycle": duty,
+		"frequency":            st.Frequency,
+	}
+	if st.Restarted {
+		result["warning"] = "the device applies settings only on reconfiguration, so the pattern restarted"
+	}
+	return jsonResult(result), nil
 }
 
-func (s *DiscoveryMCPServer) handleLogicClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	if err := s.device.Logic().Close(); err != nil {
-		return errResult(err), nil
+// maxPatternPlaySamples bounds a streamed digital pattern, a 4 MiB file
+// of one-bit samples.
+const maxPatternPlaySamples = 1 << 25
+
+// maxPatternPlayTextBytes bounds a text play file, eight characters per
+// sample such as "0xFFFF, ".
+const maxPatternPlayTextBytes = 8 * maxPatternPlaySamples
+
+// parsePlaySamples decodes a play file into samples of lines bits. Binary
+// files hold the samples back to back as the SDK takes them, the first in
+// the least significant bits of the first byte; text files list one number
+// per sample, separated by spaces, commas or newlines, in decimal or with a
+// 0x or 0b prefix.
+func parsePlaySamples(raw []byte, format string, lines int) ([]uint16, error) {
+	switch format {
+	case "binary":
+		data := make([]uint16, len(raw)*8/lines)
+		for i := range data {
+			for b := range lines {
+				bit := i*lines + b
+				data[i] |= uint16(raw[bit/8]>>(bit%8)&1) << b
+			}
+		}
+		return data, nil
+	case "text":
+		fields := strings.FieldsFunc(string(raw), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+		})
+		data := make([]uint16, len(fields))
+		for i, f := range fields {
+			v, err := strconv.ParseUint(f, 0, lines)
+			if err != nil {
+				return nil, fmt.Errorf("sample %d: %q is not a %d-bit value", i, f, lines)
+			}
+			data[i] = uint16(v)
+		}
+		return data, nil
 	}
-	return mcp.NewToolResultText("Logic analyzer reset"), nil
+	return nil, fmt.Errorf("unknown format %q (expected binary or text)", format)
 }
 
-// ==================== Pattern Generator Handlers ====================
+// playCaptureSamples reads the samples of a stored capture array, which
+// must be whole numbers of at most lines bits.
+func (s *DiscoveryMCPServer) playCaptureSamples(ctx context.Context, ref, array string, lines int) ([]uint16, float64, error) {
+	values, rate, err := s.loadCaptureArray(ctx, captureName(ref), array)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]uint16, len(values))
+	for i, v := range values {
+		if v != math.Trunc(v) || v < 0 || v >= float64(int(1)<<lines) {
+			return nil, 0, fmt.Errorf("sample %d of %g is not a %d-bit value", i, v, lines)
+		}
+		data[i] = uint16(v)
+	}
+	return data, rate, nil
+}
 
-func (s *DiscoveryMCPServer) handlePatternGenerate(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	cfg := dwf.PatternConfig{
-		Channel:   getInt(req.Params.Arguments, "channel", 0),
-		Function:  dwf.DigitalOutType(getInt(req.Params.Arguments, "function", 0)),
-		Frequency: getFloat(req.Params.Arguments, "frequency", 1000),
-		DutyCycle: getFloat(req.Params.Arguments, "duty_cycle", 50),
-		Wait:      getFloat(req.Params.Arguments, "wait", 0),
-		Repeat:    getInt(req.Params.Arguments, "repeat", 0),
-		RunTime:   getInt(req.Params.Arguments, "run_time", 0),
+func (s *DiscoveryMCPServer) handlePatternPlay(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	lines := getInt(args, "lines", 8)
+	if !slices.Contains([]int{1, 2, 4, 8, 16}, lines) {
+		return errResult(fmt.Errorf("lines must be 1, 2, 4, 8 or 16, got %d", lines)), nil
+	}
+	rate := getFloat(args, "sample_rate", 0)
+	margin := getFloat(args, "timeout", 10)
+	if margin < 0 {
+		return errResult(fmt.Errorf("timeout must not be negative")), nil
+	}
+	ref := getString(args, "capture", "")
+	file := getString(args, "file", "")
+	var data []uint16
+	var source string
+	switch {
+	case ref != "" && file != "":
+		return errResult(fmt.Errorf("give either capture or file, not both")), nil
+	case ref != "":
+		var captureRate float64
+		var err error
+		data, captureRate, err = s.playCaptureSamples(ctx, ref, getString(args, "array", "data"), lines)
+		if err != nil {
+			return errResult(err), nil
+		}
+		if _, ok := args["sample_rate"]; !ok {
+			rate = captureRate
+		}
+		source = capturesURIPrefix + captureName(ref)
+	case file != "":
+		format := strings.ToLower(getString(args, "format", "binary"))
+		info, err := os.Stat(file)
+		if err != nil {
+			return errResult(err), nil
+		}
+		switch format {
+		case "binary":
+			if info.Size()*8/int64(lines) > maxPatternPlaySamples {
+				return errResult(fmt.Errorf("%s holds more than %d samples of %d bits", file, maxPatternPlaySamples, lines)), nil
+			}
+		case "text":
+			if info.Size() > maxPatternPlayTextBytes {
+				return errResult(fmt.Errorf("%s is larger than the %d bytes accepted for a text file", file, maxPatternPlayTextBytes)), nil
+			}
+		default:
+			return errResult(fmt.Errorf("unknown format %q (expected binary or text)", format)), nil
+		}
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return errResult(err), nil
+		}
+		if data, err = parsePlaySamples(raw, format, lines); err != nil {
+			return errResult(fmt.Errorf("%s: %w", file, err)), nil
+		}
+		source = file
+	default:
+		return errResult(fmt.Errorf("give the samples as a capture or a file")), nil
 	}
-	if err := s.device.Pattern().Generate(cfg); err != nil {
+	if rate <= 0 {
+		return errResult(fmt.Errorf("sample_rate must be positive; captures without a sample_rate need it given")), nil
+	}
+	if len(data) == 0 || len(data) > maxPatternPlaySamples {
+		return errResult(fmt.Errorf("need 1 to %d samples, got %d", maxPatternPlaySamples, len(data))), nil
+	}
+
+	duration := float64(len(data)) / rate
+	ctx, cancel := context.WithTimeout(ctx, time.Duration((duration+margin)*float64(time.Second)))
+	defer cancel()
+	st, err := s.device.Pattern().Play(ctx, dwf.PatternPlayConfig{Lines: lines, SampleRate: rate, Data: data})
+	if err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("Pattern generated on DIO %d", cfg.Channel)), nil
+	return jsonResult(patternRunResult(map[string]interface{}{
+		"message":     fmt.Sprintf("Played %d samples on %d lines", st.Samples, lines),
+		"source":      source,
+		"lines":       lines,
+		"samples":     st.Samples,
+		"sample_rate": rate,
+		"duration":    duration,
+	}, st.Pattern)), nil
 }
 
 func (s *DiscoveryMCPServer) handlePatternEnable(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -348,6 +3093,47 @@ func (s *DiscoveryMCPServer) handlePatternDisable(_ context.Context, req mcp.Cal
 	return mcp.NewToolResultText(fmt.Sprintf("Pattern DIO %d disabled", ch)), nil
 }
 
+// maxPatternWait bounds the wait of discovery_pattern_status.
+const maxPatternWait = 3600
+
+func (s *DiscoveryMCPServer) handlePatternStatus(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	wait := getFloat(req.Params.Arguments, "wait", 0)
+	if wait < 0 || wait > maxPatternWait {
+		return errResult(fmt.Errorf("wait must be between 0 and %d seconds, got %g", maxPatternWait, wait)), nil
+	}
+	st, err := s.device.Pattern().Status()
+	if err != nil {
+		return errResult(err), nil
+	}
+	// Poll until a finite pattern has finished, e.g. before sampling what
+	// a burst produced.
+	deadline := time.Now().Add(time.Duration(wait * float64(time.Second)))
+	for wait > 0 && st.State != "done" && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return errResult(fmt.Errorf("waiting for the pattern aborted: %w", ctx.Err())), nil
+		case <-time.After(10 * time.Millisecond):
+		}
+		if st, err = s.device.Pattern().Status(); err != nil {
+			return errResult(err), nil
+		}
+	}
+	result := map[string]interface{}{
+		"state":               st.State,
+		"waiting_for_trigger": st.WaitingForTrigger,
+		"trigger_enabled":     st.TriggerEnabled,
+		"repeat":              st.Repeat,
+		"enabled":             st.Enabled,
+	}
+	if st.Repeat > 0 {
+		result["repeats_remaining"] = st.RepeatsRemaining
+	}
+	if wait > 0 {
+		result["done"] = st.State == "done"
+	}
+	return jsonResult(result), nil
+}
+
 func (s *DiscoveryMCPServer) handlePatternClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if err := s.device.Pattern().Close(); err != nil {
 		return errResult(err), nil
@@ -360,6 +3146,19 @@ func (s *DiscoveryMCPServer) handlePatternClose(_ context.Context, _ mcp.CallToo
 func (s *DiscoveryMCPServer) handleStaticSetMode(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	ch := getInt(req.Params.Arguments, "channel", 0)
 	output := getBool(req.Params.Arguments, "output", false)
+	outputType := getString(req.Params.Arguments, "output_type", "")
+	if outputType != "" {
+		drive, err := parseOutput(outputType)
+		if err != nil {
+			return errResult(err), nil
+		}
+		// The drive type goes first, so that an open-drain line never
+		// drives high on becoming an output.
+		if err := s.device.Static().SetOutput(ch, drive); err != nil {
+			return errResult(err), nil
+		}
+		outputType = outputNames[drive]
+	}
 	if err := s.device.Static().SetMode(ch, output); err != nil {
 		return errResult(err), nil
 	}
@@ -367,6 +3166,9 @@ func (s *DiscoveryMCPServer) handleStaticSetMode(_ context.Context, req mcp.Call
 	if output {
 		mode = "output"
 	}
+	if outputType != "" {
+		mode += " (" + outputType + ")"
+	}
 	return mcp.NewToolResultText(fmt.Sprintf("DIO %d set to %s", ch, mode)), nil
 }
 
@@ -396,6 +3198,81 @@ func (s *DiscoveryMCPServer) handleStaticSetState(_ context.Context, req mcp.Cal
 	return mcp.NewToolResultText(fmt.Sprintf("DIO %d set to %s", ch, stateStr)), nil
 }
 
+// hexMask formats a DIO mask as hex with one digit per four lines.
+func hexMask(v uint32, lines int) string {
+	return fmt.Sprintf("0x%0*X", (lines+3)/4, v)
+}
+
+// dioMasksResult reports the state of all DIO lines as hex masks.
+func dioMasksResult(m dwf.DigitalIOMasks) map[string]interface{} {
+	result := map[string]interface{}{
+		"lines":         m.Lines,
+		"first_dio":     m.FirstDIO,
+		"output_enable": hexMask(m.OutputEnable, m.Lines),
+		"output":        hexMask(m.Output, m.Lines),
+		"input":         hexMask(m.Input, m.Lines),
+	}
+	if m.OpenDrain != 0 {
+		result["open_drain"] = hexMask(m.OpenDrain, m.Lines)
+	}
+	if m.OpenSource != 0 {
+		result["open_source"] = hexMask(m.OpenSource, m.Lines)
+	}
+	return result
+}
+
+func (s *DiscoveryMCPServer) handleStaticReadMask(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	m, err := s.device.Static().ReadMasks()
+	if err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(dioMasksResult(m)), nil
+}
+
+func (s *DiscoveryMCPServer) handleStaticWriteMask(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	var w dwf.DigitalIOWrite
+	for _, p := range []struct {
+		key string
+		dst **uint32
+	}{{"output", &w.Output}, {"output_enable", &w.OutputEnable}} {
+		v, ok := args[p.key]
+		if !ok {
+			continue
+		}
+		bits, err := parseBits(v)
+		if err != nil {
+			return errResult(fmt.Errorf("%s: %w", p.key, err)), nil
+		}
+		*p.dst = &bits
+	}
+	if w.Output == nil && w.OutputEnable == nil {
+		return errResult(fmt.Errorf("give output, output_enable or both")), nil
+	}
+	if v, ok := args["mask"]; ok {
+		var err error
+		if w.Mask, err = parseBits(v); err != nil {
+			return errResult(fmt.Errorf("mask: %w", err)), nil
+		}
+	} else {
+		m, err := s.device.Static().ReadMasks()
+		if err != nil {
+			return errResult(err), nil
+		}
+		w.Mask = uint32(1<<m.Lines - 1)
+	}
+	for _, v := range []*uint32{w.Output, w.OutputEnable} {
+		if v != nil && *v&^w.Mask != 0 {
+			return errResult(fmt.Errorf("%#x has bits outside mask %#x", *v, w.Mask)), nil
+		}
+	}
+	m, err := s.device.Static().WriteMasks(w)
+	if err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(dioMasksResult(m)), nil
+}
+
 func (s *DiscoveryMCPServer) handleStaticClose(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	if err := s.device.Static().Close(); err != nil {
 		return errResult(err), nil
@@ -414,10 +3291,21 @@ func (s *DiscoveryMCPServer) handleUARTOpen(_ context.Context, req mcp.CallToolR
 		DataBits: getInt(req.Params.Arguments, "data_bits", 8),
 		StopBits: getInt(req.Params.Arguments, "stop_bits", 1),
 	}
+	if cfg.RX < 0 && cfg.TX < 0 {
+		return errResult(fmt.Errorf("at least one of rx and tx must be a DIO line")), nil
+	}
 	if err := s.device.UARTProtocol().Open(cfg); err != nil {
 		return errResult(err), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("UART initialized: %d baud, RX=DIO%d, TX=DIO%d", cfg.BaudRate, cfg.RX, cfg.TX)), nil
+	return mcp.NewToolResultText(fmt.Sprintf("UART initialized: %d baud, RX=%s, TX=%s", cfg.BaudRate, uartLine(cfg.RX), uartLine(cfg.TX))), nil
+}
+
+// uartLine names a UART line for messages; -1 means unassigned.
+func uartLine(line int) string {
+	if line < 0 {
+		return "unused"
+	}
+	return fmt.Sprintf("DIO%d", line)
 }
 
 func (s *DiscoveryMCPServer) handleUARTRead(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -498,6 +3386,66 @@ func (s *DiscoveryMCPServer) handleSPIClose(_ context.Context, _ mcp.CallToolReq
 	return mcp.NewToolResultText("SPI reset"), nil
 }
 
+func (s *DiscoveryMCPServer) handleSPIMonitor(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := dwf.SPIConfig{
+		CS:       getInt(req.Params.Arguments, "cs", -1),
+		SCK:      getInt(req.Params.Arguments, "sck", 1),
+		MISO:     getInt(req.Params.Arguments, "miso", -1),
+		MOSI:     getInt(req.Params.Arguments, "mosi", -1),
+		Mode:     getInt(req.Params.Arguments, "mode", 0),
+		MSBFirst: getBool(req.Params.Arguments, "msb_first", true),
+	}
+	if cfg.Mode < 0 || cfg.Mode > 3 {
+		return errResult(fmt.Errorf("mode must be 0-3")), nil
+	}
+	if cfg.MISO < 0 && cfg.MOSI < 0 {
+		return errResult(fmt.Errorf("at least one of miso and mosi is required")), nil
+	}
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+	capture, err := s.device.Logic().Capture(ctx)
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	if capture.SampleRate <= 0 && !capture.Sync {
+		return errResult(fmt.Errorf("unknown sample rate; open the logic analyzer first")), nil
+	}
+	s.usage.captured("logic", 1)
+
+	decoded := dwf.DecodeSPI(capture.Samples, cfg)
+	transfers := make([]map[string]interface{}, 0, len(decoded))
+	for _, t := range decoded {
+		transfer := map[string]interface{}{"bits": t.Bits}
+		// Samples taken on clock edges have no time base.
+		if !capture.Sync {
+			transfer["start"] = float64(t.Start) / capture.SampleRate
+			transfer["end"] = float64(t.End) / capture.SampleRate
+		}
+		if cfg.MOSI >= 0 {
+			transfer["mosi"] = fmt.Sprintf("%x", t.MOSI)
+		}
+		if cfg.MISO >= 0 {
+			transfer["miso"] = fmt.Sprintf("%x", t.MISO)
+		}
+		if t.Incomplete {
+			transfer["incomplete"] = true
+		}
+		transfers = append(transfers, transfer)
+	}
+	result := map[string]interface{}{
+		"sample_rate": capture.SampleRate,
+		"samples":     len(capture.Samples),
+		"transfers":   transfers,
+	}
+	if capture.Sync {
+		result["sync"] = true
+	}
+	return jsonResult(result), nil
+}
+
 // ==================== I2C Handlers ====================
 
 func (s *DiscoveryMCPServer) handleI2COpen(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -513,6 +3461,24 @@ func (s *DiscoveryMCPServer) handleI2COpen(_ context.Context, req mcp.CallToolRe
 	return mcp.NewToolResultText("I2C initialized"), nil
 }
 
+func (s *DiscoveryMCPServer) handleI2CRecover(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := dwf.I2CConfig{
+		SDA:        getInt(req.Params.Arguments, "sda", 0),
+		SCL:        getInt(req.Params.Arguments, "scl", 1),
+		ClockRate:  getFloat(req.Params.Arguments, "clock_rate", 100e3),
+		Stretching: getBool(req.Params.Arguments, "stretching", false),
+	}
+	rec, err := s.device.I2CProtocol().Recover(cfg)
+	if err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(map[string]interface{}{
+		"sda_stuck":   rec.SDAStuck,
+		"pulses":      rec.Pulses,
+		"initialized": true,
+	}), nil
+}
+
 func (s *DiscoveryMCPServer) handleI2CScan(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	addresses, err := s.device.I2CProtocol().Scan()
 	if err != nil {
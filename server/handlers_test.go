@@ -5,8 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 
@@ -17,111 +22,354 @@ import (
 
 // mockScope implements dwf.Oscilloscope for testing.
 type mockScope struct {
-	openCfg    dwf.ScopeConfig
-	openErr    error
-	measureVal float64
-	measureErr error
-	triggerCfg dwf.TriggerConfig
-	triggerErr error
-	recordData []float64
-	recordErr  error
-	closeErr   error
+	openCfg      dwf.ScopeConfig
+	openSettings dwf.ScopeSettings
+	readback     dwf.ScopeReadback
+	sampleRate   float64
+	bufferSize   int
+	triggerIndex int
+	openErr      error
+	measureVal   float64
+	measureErr   error
+	triggerCfg   dwf.TriggerConfig
+	triggerErr   error
+	coupling     map[int]dwf.AnalogCoupling
+	couplingErr  error
+	attenuation  map[int]float64
+	attenErr     error
+	recordData   []float64
+	recordErr    error
+	recordBlock  bool
+	recordStdDev []float64
+	peakRecord   dwf.PeakRecord
+	peakErr      error
+	rawRecord    dwf.RawRecord
+	channelData  map[int][]float64
+	averageCount int
+	streamData   [][]float64
+	streamErr    error
+	streamCtx    context.Context
+	closeErr     error
+	closed       int
+	counterMax   float64
+	counterCfg   dwf.CounterConfig
+	counter      dwf.CounterReading
+	counterErr   error
 }
 
-func (m *mockScope) Open(cfg dwf.ScopeConfig) error {
+func (m *mockScope) Open(cfg dwf.ScopeConfig) (dwf.ScopeSettings, error) {
 	m.openCfg = cfg
-	return m.openErr
+	return m.openSettings, m.openErr
 }
+func (m *mockScope) SampleRate() float64                  { return m.sampleRate }
+func (m *mockScope) BufferSize() int                      { return m.bufferSize }
 func (m *mockScope) Measure(channel int) (float64, error) { return m.measureVal, m.measureErr }
+func (m *mockScope) Timing() dwf.AcquisitionTiming {
+	return dwf.AcquisitionTiming{SampleRate: m.sampleRate, TriggerIndex: m.triggerIndex}
+}
+func (m *mockScope) Config() (dwf.ScopeReadback, error) { return m.readback, nil }
 func (m *mockScope) SetTrigger(cfg dwf.TriggerConfig) error {
 	m.triggerCfg = cfg
 	return m.triggerErr
 }
-func (m *mockScope) Record(channel int) ([]float64, error) { return m.recordData, m.recordErr }
-func (m *mockScope) Close() error                          { return m.closeErr }
+func (m *mockScope) SetCoupling(channel int, coupling dwf.AnalogCoupling) error {
+	if m.coupling == nil {
+		m.coupling = map[int]dwf.AnalogCoupling{}
+	}
+	m.coupling[channel] = coupling
+	return m.couplingErr
+}
+func (m *mockScope) SetAttenuation(channel int, factor float64) error {
+	if m.attenuation == nil {
+		m.attenuation = map[int]float64{}
+	}
+	m.attenuation[channel] = factor
+	return m.attenErr
+}
+func (m *mockScope) Record(ctx context.Context, channel int) ([]float64, error) {
+	if m.recordBlock {
+		// Wait for a trigger that never comes.
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return m.recordData, m.recordErr
+}
+func (m *mockScope) RecordPeak(ctx context.Context, channel int) (dwf.PeakRecord, error) {
+	return m.peakRecord, m.peakErr
+}
+func (m *mockScope) RecordChannels(ctx context.Context, channels []int) ([][]float64, error) {
+	out := make([][]float64, len(channels))
+	for i, ch := range channels {
+		out[i] = m.channelData[ch]
+	}
+	return out, m.recordErr
+}
+func (m *mockScope) RecordRaw(ctx context.Context, channel int) (dwf.RawRecord, error) {
+	return m.rawRecord, m.recordErr
+}
+func (m *mockScope) RecordAverage(ctx context.Context, channel int, count int) (dwf.AveragedRecord, error) {
+	m.averageCount = count
+	if m.recordErr != nil {
+		return dwf.AveragedRecord{}, m.recordErr
+	}
+	return dwf.AveragedRecord{Count: count, Mean: m.recordData, StdDev: m.recordStdDev}, nil
+}
+func (m *mockScope) CounterLimits() (dwf.Limits, error) { return dwf.Limits{Max: m.counterMax}, nil }
+func (m *mockScope) Count(ctx context.Context, cfg dwf.CounterConfig) (dwf.CounterReading, error) {
+	m.counterCfg = cfg
+	return m.counter, m.counterErr
+}
+func (m *mockScope) Stream(ctx context.Context, channel int, duration float64, onChunk func(chunk []float64) error) (dwf.StreamStats, error) {
+	m.streamCtx = ctx
+	var stats dwf.StreamStats
+	for _, chunk := range m.streamData {
+		stats.Samples += len(chunk)
+		if err := onChunk(chunk); err != nil {
+			return stats, err
+		}
+	}
+	return stats, m.streamErr
+}
+func (m *mockScope) Close() error {
+	m.closed++
+	return m.closeErr
+}
 
 // mockWavegen implements dwf.WavegenDriver for testing.
 type mockWavegen struct {
-	generateCfg dwf.WavegenConfig
-	generateErr error
-	enableErr   error
-	disableErr  error
-	closeErr    error
+	generateCfg      dwf.WavegenConfig
+	generateSettings dwf.WavegenSettings
+	generateErr      error
+	dataLimits       dwf.Limits
+	playCfg          dwf.PlayConfig
+	playStats        dwf.StreamStats
+	playErr          error
+	enableErr        error
+	disableErr       error
+	idle             map[int]dwf.WavegenIdle
+	idleErr          error
+	master           map[int]int
+	masterErr        error
+	closeErr         error
 }
 
-func (m *mockWavegen) Generate(cfg dwf.WavegenConfig) error {
+func (m *mockWavegen) Generate(cfg dwf.WavegenConfig) (dwf.WavegenSettings, error) {
 	m.generateCfg = cfg
-	return m.generateErr
+	return m.generateSettings, m.generateErr
+}
+func (m *mockWavegen) DataLimits(channel int) (dwf.Limits, error) { return m.dataLimits, nil }
+func (m *mockWavegen) Enable(channel int) error                   { return m.enableErr }
+func (m *mockWavegen) Disable(channel int) error                  { return m.disableErr }
+func (m *mockWavegen) Close(channel int) error                    { return m.closeErr }
+func (m *mockWavegen) SetIdle(channel int, idle dwf.WavegenIdle) error {
+	if m.idle == nil {
+		m.idle = map[int]dwf.WavegenIdle{}
+	}
+	m.idle[channel] = idle
+	return m.idleErr
+}
+func (m *mockWavegen) SetMaster(channel, master int) error {
+	if m.master == nil {
+		m.master = map[int]int{}
+	}
+	m.master[channel] = master
+	return m.masterErr
+}
+func (m *mockWavegen) Play(ctx context.Context, cfg dwf.PlayConfig) (dwf.StreamStats, error) {
+	m.playCfg = cfg
+	stats := m.playStats
+	stats.Samples = len(cfg.Data)
+	return stats, m.playErr
 }
-func (m *mockWavegen) Enable(channel int) error  { return m.enableErr }
-func (m *mockWavegen) Disable(channel int) error { return m.disableErr }
-func (m *mockWavegen) Close(channel int) error   { return m.closeErr }
 
 // mockSupply implements dwf.PowerSupply for testing.
 type mockSupply struct {
 	switchCfg dwf.SuppliesConfig
 	switchErr error
-	closeErr  error
+	rails     []dwf.SupplyRail
+	readback  dwf.SuppliesReadback
+	// statuses are returned in turn by Status, repeating the last.
+	statuses    []dwf.SupplyStatus
+	statusCalls int
+	closeErr    error
 }
 
 func (m *mockSupply) Switch(cfg dwf.SuppliesConfig) error {
 	m.switchCfg = cfg
 	return m.switchErr
 }
-func (m *mockSupply) Close() error { return m.closeErr }
+func (m *mockSupply) Rails() ([]dwf.SupplyRail, error) { return m.rails, nil }
+func (m *mockSupply) Close() error                     { return m.closeErr }
+func (m *mockSupply) Config() (dwf.SuppliesReadback, error) {
+	return m.readback, nil
+}
+func (m *mockSupply) Status() (dwf.SupplyStatus, error) {
+	m.statusCalls++
+	if len(m.statuses) == 0 {
+		return dwf.SupplyStatus{Faults: []string{}}, nil
+	}
+	return m.statuses[min(m.statusCalls, len(m.statuses))-1], nil
+}
+
+// mockAnalogIO implements dwf.AnalogIO for testing.
+type mockAnalogIO struct {
+	channels []dwf.AnalogIOChannel
+	reading  dwf.AnalogIOReading
+	readErr  error
+	// set records the last Set call, which applies value.
+	setChannel, setNode int
+	setValue            float64
+	setErr              error
+	// status holds the values ReadStatus reports; statusCalls counts calls.
+	status      map[dwf.AnalogIONodeRef]float64
+	statusErr   error
+	statusCalls int
+}
+
+func (m *mockAnalogIO) Channels() ([]dwf.AnalogIOChannel, error) { return m.channels, nil }
+func (m *mockAnalogIO) Read(channel, node int) (dwf.AnalogIOReading, error) {
+	return m.reading, m.readErr
+}
+func (m *mockAnalogIO) Set(channel, node int, value float64) (float64, error) {
+	m.setChannel, m.setNode, m.setValue = channel, node, value
+	return value, m.setErr
+}
+func (m *mockAnalogIO) ReadStatus(nodes []dwf.AnalogIONodeRef) ([]float64, error) {
+	m.statusCalls++
+	values := make([]float64, len(nodes))
+	for i, n := range nodes {
+		values[i] = m.status[n]
+	}
+	return values, m.statusErr
+}
 
 // mockDMM implements dwf.DigitalMultimeter for testing.
 type mockDMM struct {
 	openErr    error
 	measureVal float64
-	measureErr error
-	closeErr   error
+	// measureVals, when set, are returned in turn by Measure.
+	measureVals  []float64
+	measureCalls int
+	measureErr   error
+	// resolved, when set, is the range, units and auxiliary readings the
+	// device reports.
+	resolved dwf.DMMReading
+	closeErr error
 }
 
 func (m *mockDMM) Open() error { return m.openErr }
-func (m *mockDMM) Measure(mode dwf.DMMMode, range_ float64, highImpedance bool) (float64, error) {
-	return m.measureVal, m.measureErr
+func (m *mockDMM) Measure(mode dwf.DMMMode, range_ float64, highImpedance bool) (dwf.DMMReading, error) {
+	m.measureCalls++
+	r := dwf.DMMReading{Value: m.measureVal, Mode: mode, Range: range_, Units: m.resolved.Units, Aux: m.resolved.Aux}
+	if m.resolved.Range != 0 {
+		r.Range = m.resolved.Range
+	}
+	if len(m.measureVals) > 0 {
+		r.Value = m.measureVals[(m.measureCalls-1)%len(m.measureVals)]
+	}
+	return r, m.measureErr
 }
 func (m *mockDMM) Close() error { return m.closeErr }
 
 // mockLogic implements dwf.LogicAnalyzer for testing.
 type mockLogic struct {
-	openCfg    dwf.LogicConfig
-	openErr    error
-	triggerCfg dwf.LogicTriggerConfig
-	triggerErr error
-	recordData []uint16
-	recordErr  error
-	closeErr   error
+	openCfg     dwf.LogicConfig
+	openErr     error
+	triggerCfg  dwf.LogicTriggerConfig
+	triggerErr  error
+	recordData  []uint16
+	recordErr   error
+	captureData dwf.LogicCapture
+	captureErr  error
+	closeErr    error
+	readback    dwf.LogicReadback
+	timing      dwf.AcquisitionTiming
+	counterMax  float64
+	counterCfg  dwf.LogicCounterConfig
+	counter     dwf.CounterReading
 }
 
 func (m *mockLogic) Open(cfg dwf.LogicConfig) error {
 	m.openCfg = cfg
 	return m.openErr
 }
+func (m *mockLogic) Config() (dwf.LogicReadback, error) {
+	return m.readback, nil
+}
+func (m *mockLogic) Timing() dwf.AcquisitionTiming { return m.timing }
+func (m *mockLogic) CounterLimits() (dwf.Limits, error) {
+	return dwf.Limits{Max: m.counterMax}, nil
+}
+func (m *mockLogic) Count(ctx context.Context, cfg dwf.LogicCounterConfig) (dwf.CounterReading, error) {
+	m.counterCfg = cfg
+	return m.counter, nil
+}
 func (m *mockLogic) SetTrigger(cfg dwf.LogicTriggerConfig) error {
 	m.triggerCfg = cfg
 	return m.triggerErr
 }
-func (m *mockLogic) Record(channel int) ([]uint16, error) { return m.recordData, m.recordErr }
-func (m *mockLogic) Close() error                         { return m.closeErr }
+func (m *mockLogic) Record(ctx context.Context, channel int) ([]uint16, error) {
+	return m.recordData, m.recordErr
+}
+func (m *mockLogic) Capture(ctx context.Context) (dwf.LogicCapture, error) {
+	return m.captureData, m.captureErr
+}
+func (m *mockLogic) Close() error { return m.closeErr }
 
 // mockPattern implements dwf.PatternGenerator for testing.
 type mockPattern struct {
 	generateCfg dwf.PatternConfig
+	generated   dwf.PatternSettings
 	generateErr error
+	toggleCfg   dwf.ToggleConfig
+	toggled     dwf.ToggleSettings
+	toggleErr   error
+	clockCfgs   []dwf.ClockConfig
+	clocks      dwf.ClockSettings
+	romCfg      dwf.ROMConfig
+	playCfg     dwf.PatternPlayConfig
+	maxData     int
+	dutyChannel int
+	duty        float64
+	dutySet     dwf.DutySettings
 	enableErr   error
 	disableErr  error
+	status      dwf.PatternStatus
+	statusErr   error
 	closeErr    error
 }
 
-func (m *mockPattern) Generate(cfg dwf.PatternConfig) error {
+func (m *mockPattern) Generate(cfg dwf.PatternConfig) (dwf.PatternSettings, error) {
 	m.generateCfg = cfg
-	return m.generateErr
+	return m.generated, m.generateErr
 }
 func (m *mockPattern) Enable(channel int) error  { return m.enableErr }
 func (m *mockPattern) Disable(channel int) error { return m.disableErr }
 func (m *mockPattern) Close() error              { return m.closeErr }
+func (m *mockPattern) Toggle(cfg dwf.ToggleConfig) (dwf.ToggleSettings, error) {
+	m.toggleCfg = cfg
+	return m.toggled, m.toggleErr
+}
+func (m *mockPattern) MaxData(channel int) (int, error) { return m.maxData, nil }
+func (m *mockPattern) ROM(cfg dwf.ROMConfig) (dwf.ROMSettings, error) {
+	m.romCfg = cfg
+	return dwf.ROMSettings{AddressLines: 8}, nil
+}
+func (m *mockPattern) Play(_ context.Context, cfg dwf.PatternPlayConfig) (dwf.PatternPlaySettings, error) {
+	m.playCfg = cfg
+	return dwf.PatternPlaySettings{Samples: len(cfg.Data)}, nil
+}
+func (m *mockPattern) SetDuty(channel int, dutyCycle float64) (dwf.DutySettings, error) {
+	m.dutyChannel, m.duty = channel, dutyCycle
+	return m.dutySet, nil
+}
+func (m *mockPattern) Clocks(cfgs []dwf.ClockConfig) (dwf.ClockSettings, error) {
+	m.clockCfgs = cfgs
+	return m.clocks, nil
+}
+func (m *mockPattern) Status() (dwf.PatternStatus, error) {
+	return m.status, m.statusErr
+}
 
 // mockStaticIO implements dwf.StaticIO for testing.
 type mockStaticIO struct {
@@ -131,16 +379,72 @@ type mockStaticIO struct {
 	setStateErr   error
 	setCurrentErr error
 	setPullErr    error
+	setVIOErr     error
 	closeErr      error
+	calls         []string
+	// levels is returned by Levels and updated by SetVIO, SetThreshold and
+	// SetCurrent.
+	levels dwf.DigitalIOLevels
+	// masks is returned by ReadMasks and updated by WriteMasks.
+	masks dwf.DigitalIOMasks
+	write dwf.DigitalIOWrite
 }
 
-func (m *mockStaticIO) SetMode(channel int, output bool) error { return m.setModeErr }
-func (m *mockStaticIO) GetState(channel int) (bool, error)     { return m.getStateVal, m.getStateErr }
-func (m *mockStaticIO) SetState(channel int, value bool) error { return m.setStateErr }
-func (m *mockStaticIO) SetCurrent(current float64) error       { return m.setCurrentErr }
+func (m *mockStaticIO) SetMode(channel int, output bool) error {
+	m.calls = append(m.calls, fmt.Sprintf("mode %d %v", channel, output))
+	return m.setModeErr
+}
+func (m *mockStaticIO) GetState(channel int) (bool, error) {
+	m.calls = append(m.calls, fmt.Sprintf("get %d", channel))
+	return m.getStateVal, m.getStateErr
+}
+func (m *mockStaticIO) SetState(channel int, value bool) error {
+	m.calls = append(m.calls, fmt.Sprintf("set %d %v", channel, value))
+	return m.setStateErr
+}
+func (m *mockStaticIO) SetCurrent(current float64) error {
+	m.calls = append(m.calls, fmt.Sprintf("drive %g", current))
+	if m.setCurrentErr == nil && m.levels.Drive != nil {
+		m.levels.Drive = &current
+	}
+	return m.setCurrentErr
+}
+func (m *mockStaticIO) SetOutput(channel int, output dwf.DigitalOutOutput) error {
+	m.calls = append(m.calls, fmt.Sprintf("output %d %d", channel, output))
+	return nil
+}
 func (m *mockStaticIO) SetPull(channel int, direction dwf.PullDirection) error {
+	m.calls = append(m.calls, fmt.Sprintf("pull %d %d", channel, direction))
 	return m.setPullErr
 }
+func (m *mockStaticIO) SetVIO(volts float64) error {
+	m.calls = append(m.calls, fmt.Sprintf("vio %g", volts))
+	if m.setVIOErr == nil && m.levels.VIO != nil {
+		m.levels.VIO = &volts
+	}
+	return m.setVIOErr
+}
+func (m *mockStaticIO) SetThreshold(volts float64) error {
+	m.calls = append(m.calls, fmt.Sprintf("threshold %g", volts))
+	m.levels.Threshold = &volts
+	return nil
+}
+func (m *mockStaticIO) Levels() (dwf.DigitalIOLevels, error) {
+	return m.levels, nil
+}
+func (m *mockStaticIO) ReadMasks() (dwf.DigitalIOMasks, error) {
+	return m.masks, nil
+}
+func (m *mockStaticIO) WriteMasks(w dwf.DigitalIOWrite) (dwf.DigitalIOMasks, error) {
+	m.write = w
+	if w.Output != nil {
+		m.masks.Output = m.masks.Output&^w.Mask | *w.Output
+	}
+	if w.OutputEnable != nil {
+		m.masks.OutputEnable = m.masks.OutputEnable&^w.Mask | *w.OutputEnable
+	}
+	return m.masks, nil
+}
 func (m *mockStaticIO) Close() error { return m.closeErr }
 
 // mockUART implements dwf.UART for testing.
@@ -151,15 +455,32 @@ type mockUART struct {
 	readErr  error
 	writeErr error
 	closeErr error
+	// loopbackRate, if set, makes written data come back on Read at baud
+	// rates up to it and garbled above.
+	loopbackRate int
 }
 
 func (m *mockUART) Open(cfg dwf.UARTConfig) error {
 	m.openCfg = cfg
 	return m.openErr
 }
-func (m *mockUART) Read() ([]byte, error)   { return m.readData, m.readErr }
-func (m *mockUART) Write(data []byte) error { return m.writeErr }
-func (m *mockUART) Close() error            { return m.closeErr }
+func (m *mockUART) Read() ([]byte, error) {
+	data := m.readData
+	if m.loopbackRate > 0 {
+		m.readData = nil
+	}
+	return data, m.readErr
+}
+func (m *mockUART) Write(data []byte) error {
+	if m.loopbackRate > 0 {
+		m.readData = slices.Clone(data)
+		if m.openCfg.BaudRate > m.loopbackRate {
+			m.readData[0] ^= 0x01
+		}
+	}
+	return m.writeErr
+}
+func (m *mockUART) Close() error { return m.closeErr }
 
 // mockSPI implements dwf.SPI for testing.
 type mockSPI struct {
@@ -171,12 +492,18 @@ type mockSPI struct {
 	exchangeData []byte
 	exchangeErr  error
 	closeErr     error
+	// loopbackRate, if set, makes Exchange return the sent data at clock
+	// frequencies up to it.
+	loopbackRate float64
 }
 
 func (m *mockSPI) Open(cfg dwf.SPIConfig) error           { m.openCfg = cfg; return m.openErr }
 func (m *mockSPI) Read(count int, cs int) ([]byte, error) { return m.readData, m.readErr }
 func (m *mockSPI) Write(data []byte, cs int) error        { return m.writeErr }
 func (m *mockSPI) Exchange(txData []byte, rxCount int, cs int) ([]byte, error) {
+	if m.loopbackRate > 0 && m.openCfg.ClockFrequency <= m.loopbackRate {
+		return slices.Clone(txData), m.exchangeErr
+	}
 	return m.exchangeData, m.exchangeErr
 }
 func (m *mockSPI) Close() error { return m.closeErr }
@@ -192,6 +519,9 @@ type mockI2C struct {
 	writeErr     error
 	exchangeData []byte
 	exchangeErr  error
+	recovery     dwf.I2CRecovery
+	recoverCfg   dwf.I2CConfig
+	recoverErr   error
 	closeErr     error
 }
 
@@ -202,6 +532,10 @@ func (m *mockI2C) Write(data []byte, address int) error        { return m.writeE
 func (m *mockI2C) Exchange(txData []byte, rxCount int, address int) ([]byte, error) {
 	return m.exchangeData, m.exchangeErr
 }
+func (m *mockI2C) Recover(cfg dwf.I2CConfig) (dwf.I2CRecovery, error) {
+	m.recoverCfg = cfg
+	return m.recovery, m.recoverErr
+}
 func (m *mockI2C) Close() error { return m.closeErr }
 
 // mockDevice implements dwf.DiscoveryDevice, aggregating all mock instruments.
@@ -210,14 +544,20 @@ type mockDevice struct {
 	enumDevicesErr error
 	enumConfigs    []dwf.DeviceConfig
 	enumConfigsErr error
+	configsIndex   int
+	openDevice     string
 	openInfo       *dwf.DeviceInfo
 	openErr        error
+	status         dwf.FirmwareStatus
+	reinitErr      error
+	reprogrammed   bool
 	closeErr       error
 	temperature    float64
 	tempErr        error
 	scope          *mockScope
 	wavegen        *mockWavegen
 	supply         *mockSupply
+	analogIO       *mockAnalogIO
 	dmm            *mockDMM
 	logic          *mockLogic
 	pattern        *mockPattern
@@ -231,9 +571,11 @@ func (d *mockDevice) EnumDevices() ([]dwf.EnumDevice, error) {
 	return d.enumDevices, d.enumDevicesErr
 }
 func (d *mockDevice) EnumConfigs(deviceIndex int) ([]dwf.DeviceConfig, error) {
+	d.configsIndex = deviceIndex
 	return d.enumConfigs, d.enumConfigsErr
 }
 func (d *mockDevice) Open(device string, config int) (*dwf.DeviceInfo, error) {
+	d.openDevice = device
 	return d.openInfo, d.openErr
 }
 func (d *mockDevice) Close() error                  { return d.closeErr }
@@ -241,6 +583,7 @@ func (d *mockDevice) Temperature() (float64, error) { return d.temperature, d.te
 func (d *mockDevice) Scope() dwf.Oscilloscope       { return d.scope }
 func (d *mockDevice) Wavegen() dwf.WavegenDriver    { return d.wavegen }
 func (d *mockDevice) Supply() dwf.PowerSupply       { return d.supply }
+func (d *mockDevice) AnalogIO() dwf.AnalogIO        { return d.analogIO }
 func (d *mockDevice) DMM() dwf.DigitalMultimeter    { return d.dmm }
 func (d *mockDevice) Logic() dwf.LogicAnalyzer      { return d.logic }
 func (d *mockDevice) Pattern() dwf.PatternGenerator { return d.pattern }
@@ -248,6 +591,13 @@ func (d *mockDevice) Static() dwf.StaticIO          { return d.staticIO }
 func (d *mockDevice) UARTProtocol() dwf.UART        { return d.uart }
 func (d *mockDevice) SPIProtocol() dwf.SPI          { return d.spi }
 func (d *mockDevice) I2CProtocol() dwf.I2C          { return d.i2c }
+func (d *mockDevice) Status() (dwf.FirmwareStatus, error) {
+	return d.status, nil
+}
+func (d *mockDevice) Reinitialize(reprogram bool) (*dwf.DeviceInfo, error) {
+	d.reprogrammed = reprogram
+	return d.openInfo, d.reinitErr
+}
 
 // newTestServer creates a DiscoveryMCPServer with a fully mocked device.
 func newTestServer() (*DiscoveryMCPServer, *mockDevice) {
@@ -255,6 +605,7 @@ func newTestServer() (*DiscoveryMCPServer, *mockDevice) {
 		scope:    &mockScope{},
 		wavegen:  &mockWavegen{},
 		supply:   &mockSupply{},
+		analogIO: &mockAnalogIO{},
 		dmm:      &mockDMM{},
 		logic:    &mockLogic{},
 		pattern:  &mockPattern{},
@@ -371,6 +722,46 @@ func TestJsonResult(t *testing.T) {
 	}
 }
 
+func TestJsonResultNonFinite(t *testing.T) {
+	overrange := math.Inf(1)
+	result := jsonResult(map[string]interface{}{
+		"reading": &overrange,
+		"min":     math.Inf(-1),
+		"max":     1.5,
+		"data":    []float64{0, math.NaN(), 1.5},
+		"nested": map[string]interface{}{
+			"mean": math.NaN(),
+		},
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error result: %v", result.Content)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+		t.Fatalf("invalid JSON %q: %v", text, err)
+	}
+	if parsed["min"] != nil || parsed["max"] != 1.5 {
+		t.Errorf("expected min=null max=1.5, got %q", text)
+	}
+	if !strings.Contains(text, `"data":[0,null,1.5]`) {
+		t.Errorf("expected NaN sample as null, got %q", text)
+	}
+	if parsed["quality"] != "non_finite" {
+		t.Errorf("expected quality flag, got %q", text)
+	}
+	if !strings.Contains(text, `"non_finite_fields":["data","min","nested.mean","reading"]`) {
+		t.Errorf("expected affected fields, got %q", text)
+	}
+}
+
+func TestJsonResultMarshalError(t *testing.T) {
+	result := jsonResult(map[string]interface{}{"ch": make(chan int)})
+	if !result.IsError {
+		t.Error("expected error result for unencodable value")
+	}
+}
+
 func TestErrResult(t *testing.T) {
 	result := errResult(errors.New("test error"))
 	if result == nil {
@@ -480,7 +871,8 @@ func TestHandleDeviceGetConfigs(t *testing.T) {
 func TestHandleDeviceOpen(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		s, dev := newTestServer()
-		dev.openInfo = &dwf.DeviceInfo{Name: "Analog Discovery 2", Handle: 1}
+		dev.openInfo = &dwf.DeviceInfo{Name: "Analog Discovery 2", Handle: 1,
+			Capabilities: dwf.DeviceCapabilities{HasSupplies: true, HasSuppliesNegative: true, MaxDigitalRate: 100e6, ADCBits: 14}}
 		result, err := s.handleDeviceOpen(context.Background(), makeReq(map[string]interface{}{
 			"device": "Analog Discovery 2",
 			"config": float64(0),
@@ -492,6 +884,13 @@ func TestHandleDeviceOpen(t *testing.T) {
 		if !strings.Contains(text, "Analog Discovery 2") {
 			t.Errorf("expected device name in result, got %q", text)
 		}
+		var got struct {
+			Capabilities map[string]any `json:"capabilities"`
+		}
+		json.Unmarshal([]byte(text), &got)
+		if got.Capabilities["has_supplies_negative"] != true || got.Capabilities["has_dmm"] != false || got.Capabilities["adc_bits"] != float64(14) {
+			t.Errorf("unexpected capabilities %v", got.Capabilities)
+		}
 	})
 
 	t.Run("error", func(t *testing.T) {
@@ -533,6 +932,55 @@ func TestHandleDeviceClose(t *testing.T) {
 	})
 }
 
+func TestHandleDeviceStatus(t *testing.T) {
+	s, dev := newTestServer()
+	dev.status = dwf.FirmwareStatus{Open: true, SerialNumber: "SN:1", Config: 2, HardwareRevision: 3, Responsive: true}
+	result, err := s.handleDeviceStatus(context.Background(), makeReq(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, `"Config":2`) || !strings.Contains(text, `"Responsive":true`) {
+		t.Errorf("unexpected status %q", text)
+	}
+}
+
+func TestHandleDeviceReinitialize(t *testing.T) {
+	t.Run("reset", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, err := s.handleDeviceReinitialize(context.Background(), makeReq(nil))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.IsError || dev.reprogrammed {
+			t.Errorf("expected plain reset, got %v (reprogrammed %v)", result.Content, dev.reprogrammed)
+		}
+	})
+
+	t.Run("reprogram", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.openInfo = &dwf.DeviceInfo{Name: "Analog Discovery 2", SerialNumber: "SN:1"}
+		result, _ := s.handleDeviceReinitialize(context.Background(), makeReq(map[string]any{
+			"reprogram": true,
+		}))
+		if result.IsError || !dev.reprogrammed {
+			t.Fatalf("expected reprogram, got %v", result.Content)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "SN:1") {
+			t.Errorf("expected device info, got %q", text)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.reinitErr = errors.New("no device open")
+		result, _ := s.handleDeviceReinitialize(context.Background(), makeReq(nil))
+		if !result.IsError {
+			t.Error("expected error")
+		}
+	})
+}
+
 func TestHandleDeviceTemperature(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		s, dev := newTestServer()
@@ -578,6 +1026,129 @@ func TestHandleScopeOpen(t *testing.T) {
 		}
 	})
 
+	t.Run("ac coupling", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, _ := s.handleScopeOpen(context.Background(), makeReq(map[string]interface{}{
+			"coupling": "ac",
+		}))
+		if result.IsError {
+			t.Fatalf("unexpected error result: %v", result.Content)
+		}
+		if dev.scope.openCfg.Coupling != dwf.CouplingAC {
+			t.Errorf("expected AC coupling, got %v", dev.scope.openCfg.Coupling)
+		}
+	})
+
+	t.Run("applied settings", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.openSettings = dwf.ScopeSettings{
+			SamplingFrequency: 33.333333e6,
+			BufferSize:        8192,
+			Channels: []dwf.ScopeChannelSettings{
+				{Channel: 1, AmplitudeRange: 5},
+				{Channel: 2, AmplitudeRange: 50},
+			},
+			FrequencyLimits: dwf.Limits{Min: 1, Max: 100e6},
+			MaxBufferSize:   8192,
+		}
+		result, _ := s.handleScopeOpen(context.Background(), makeReq(map[string]interface{}{
+			"sampling_frequency": 30e6,
+			"amplitude_range":    5.0,
+		}))
+		text := result.Content[0].(mcp.TextContent).Text
+		var parsed struct {
+			Applied  map[string]any                                  `json:"applied"`
+			Limits   map[string]map[string]float64                   `json:"limits"`
+			Adjusted map[string]struct{ Requested, Applied float64 } `json:"adjusted"`
+		}
+		if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+			t.Fatalf("invalid JSON %q: %v", text, err)
+		}
+		if parsed.Applied["sampling_frequency"] != 33.333333e6 || parsed.Limits["sampling_frequency"]["max"] != 100e6 {
+			t.Errorf("expected applied rate and limits, got %q", text)
+		}
+		adj, ok := parsed.Adjusted["sampling_frequency"]
+		if !ok || adj.Requested != 30e6 {
+			t.Errorf("expected sampling_frequency adjustment, got %q", text)
+		}
+		if _, ok := parsed.Adjusted["channel1_amplitude_range"]; ok {
+			t.Errorf("amplitude_range was honored and should not be reported as adjusted: %q", text)
+		}
+		if adj := parsed.Adjusted["channel2_amplitude_range"]; adj.Applied != 50 {
+			t.Errorf("expected the channel 2 range adjustment, got %q", text)
+		}
+		if chs, _ := parsed.Applied["channels"].([]any); len(chs) != 2 {
+			t.Errorf("expected the settings of both channels, got %q", text)
+		}
+		if _, ok := parsed.Adjusted["buffer_size"]; ok {
+			t.Errorf("buffer_size 0 means maximum and should not be reported as adjusted: %q", text)
+		}
+	})
+
+	t.Run("filter and bandwidth", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, _ := s.handleScopeOpen(context.Background(), makeReq(map[string]interface{}{
+			"filter":    "average",
+			"bandwidth": 20e6,
+		}))
+		if result.IsError {
+			t.Fatalf("unexpected error result: %v", result.Content)
+		}
+		if dev.scope.openCfg.Filter != dwf.FilterAverage || dev.scope.openCfg.Bandwidth != 20e6 {
+			t.Errorf("expected average filter at 20 MHz, got %+v", dev.scope.openCfg)
+		}
+	})
+
+	t.Run("acquisition mode", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.openSettings = dwf.ScopeSettings{AcquisitionMode: dwf.AcqModeRecord, RecordLength: 2}
+		result, _ := s.handleScopeOpen(context.Background(), makeReq(map[string]interface{}{
+			"acquisition_mode": "record",
+			"record_length":    2.0,
+		}))
+		if result.IsError {
+			t.Fatalf("unexpected error result: %v", result.Content)
+		}
+		if dev.scope.openCfg.AcquisitionMode != dwf.AcqModeRecord || dev.scope.openCfg.RecordLength != 2 {
+			t.Errorf("expected a 2 s record mode, got %+v", dev.scope.openCfg)
+		}
+		var parsed struct {
+			Mode    string         `json:"acquisition_mode"`
+			Applied map[string]any `json:"applied"`
+		}
+		json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &parsed)
+		if parsed.Mode != "record" || parsed.Applied["record_length"] != 2.0 {
+			t.Errorf("unexpected result %+v", parsed)
+		}
+
+		for _, args := range []map[string]interface{}{
+			{"acquisition_mode": "roll"},
+			{"acquisition_mode": "scan_shift", "record_length": 1.0},
+		} {
+			if result, _ := s.handleScopeOpen(context.Background(), makeReq(args)); !result.IsError {
+				t.Errorf("expected error result for %v", args)
+			}
+		}
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		s, _ := newTestServer()
+		result, _ := s.handleScopeOpen(context.Background(), makeReq(map[string]interface{}{
+			"filter": "median",
+		}))
+		if !result.IsError {
+			t.Error("expected error result for invalid filter")
+		}
+	})
+
+	t.Run("default attenuation", func(t *testing.T) {
+		s, dev := newTestServer()
+		_, _ = s.handleScopeOpen(context.Background(), makeReq(nil))
+		if dev.scope.openCfg.Attenuation != 1 {
+			t.Errorf("expected 1x attenuation by default, got %v", dev.scope.openCfg.Attenuation)
+		}
+	})
+
 	t.Run("error", func(t *testing.T) {
 		s, dev := newTestServer()
 		dev.scope.openErr = errors.New("scope fail")
@@ -588,6 +1159,115 @@ func TestHandleScopeOpen(t *testing.T) {
 	})
 }
 
+func TestHandleScopeAttenuation(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, err := s.handleScopeAttenuation(context.Background(), makeReq(map[string]any{
+			"channel":     float64(1),
+			"attenuation": 10.0,
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, "10x") {
+			t.Errorf("expected '10x', got %q", text)
+		}
+		if dev.scope.attenuation[1] != 10 {
+			t.Errorf("expected channel 1 at 10x, got %v", dev.scope.attenuation)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.attenErr = errors.New("attenuation must be positive")
+		result, _ := s.handleScopeAttenuation(context.Background(), makeReq(map[string]any{
+			"channel":     float64(1),
+			"attenuation": -1.0,
+		}))
+		if !result.IsError {
+			t.Error("expected error result")
+		}
+	})
+}
+
+func TestHandleScopeCoupling(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, err := s.handleScopeCoupling(context.Background(), makeReq(map[string]any{
+			"channel":  float64(2),
+			"coupling": "ac",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, "AC") {
+			t.Errorf("expected 'AC', got %q", text)
+		}
+		if dev.scope.coupling[2] != dwf.CouplingAC {
+			t.Errorf("expected channel 2 AC, got %v", dev.scope.coupling)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		s, _ := newTestServer()
+		result, _ := s.handleScopeCoupling(context.Background(), makeReq(map[string]any{
+			"channel":  float64(1),
+			"coupling": "gnd",
+		}))
+		if !result.IsError {
+			t.Error("expected error result for invalid coupling")
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.couplingErr = errors.New("AC coupling not supported on this device")
+		result, _ := s.handleScopeCoupling(context.Background(), makeReq(map[string]any{
+			"channel":  float64(1),
+			"coupling": "ac",
+		}))
+		if !result.IsError {
+			t.Error("expected error result")
+		}
+	})
+}
+
+func TestHandleScopeGetConfig(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.readback = dwf.ScopeReadback{
+		SamplingFrequency: 1e6,
+		BufferSize:        8192,
+		Channels: []dwf.ScopeChannelReadback{
+			{Channel: 1, Enabled: true, Range: 5, Attenuation: 10, Coupling: dwf.CouplingAC, Filter: dwf.FilterAverage},
+		},
+		Trigger: dwf.TriggerReadback{Channel: 1, Type: dwf.TriggerTypeWindow, Condition: dwf.TriggerSlopeFall, Level: 1, Hysteresis: 0.5},
+	}
+	result, err := s.handleScopeGetConfig(context.Background(), makeReq(nil))
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v %v", err, result.Content)
+	}
+	var got struct {
+		BufferTime float64          `json:"buffer_time"`
+		Channels   []map[string]any `json:"channels"`
+		Trigger    map[string]any   `json:"trigger"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if math.Abs(got.BufferTime-8.192e-3) > 1e-12 {
+		t.Errorf("buffer_time = %g", got.BufferTime)
+	}
+	if len(got.Channels) != 1 || got.Channels[0]["coupling"] != "ac" || got.Channels[0]["filter"] != "average" {
+		t.Errorf("unexpected channels %v", got.Channels)
+	}
+	tr := got.Trigger
+	if tr["type"] != "window" || tr["condition"] != "falling" || tr["window_low"] != 0.5 || tr["window_high"] != 1.5 {
+		t.Errorf("unexpected trigger %v", tr)
+	}
+}
+
 func TestHandleScopeMeasure(t *testing.T) {
 	s, dev := newTestServer()
 	dev.scope.measureVal = 1.234567
@@ -621,115 +1301,1811 @@ func TestHandleScopeTrigger(t *testing.T) {
 	}
 }
 
-func TestHandleScopeRecord(t *testing.T) {
+func TestHandleScopeTriggerHoldOff(t *testing.T) {
 	s, dev := newTestServer()
-	dev.scope.recordData = []float64{0.1, 0.2, 0.3}
-	result, err := s.handleScopeRecord(context.Background(), makeReq(map[string]interface{}{
-		"channel": float64(1),
+	result, err := s.handleScopeTrigger(context.Background(), makeReq(map[string]any{
+		"source":  float64(2),
+		"holdoff": 0.002,
 	}))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, `"samples":3`) {
-		t.Errorf("expected samples count, got %q", text)
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %v", result.Content)
+	}
+	if dev.scope.triggerCfg.HoldOff != 0.002 {
+		t.Errorf("holdoff = %g, want 0.002", dev.scope.triggerCfg.HoldOff)
+	}
+
+	result, _ = s.handleScopeTrigger(context.Background(), makeReq(map[string]any{
+		"holdoff": -1.0,
+	}))
+	if !result.IsError {
+		t.Error("expected error for negative holdoff")
 	}
 }
 
-func TestHandleScopeClose(t *testing.T) {
-	s, _ := newTestServer()
-	result, err := s.handleScopeClose(context.Background(), makeReq(nil))
+func TestHandleScopeTriggerPulse(t *testing.T) {
+	s, dev := newTestServer()
+	result, err := s.handleScopeTrigger(context.Background(), makeReq(map[string]any{
+		"type":             "pulse",
+		"edge_rising":      false,
+		"length":           1e-6,
+		"length_condition": "less",
+	}))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "reset") {
-		t.Errorf("expected 'reset', got %q", text)
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %v", result.Content)
+	}
+	cfg := dev.scope.triggerCfg
+	if cfg.Type != dwf.TriggerTypePulse || cfg.Length != 1e-6 || cfg.LengthCondition != dwf.TriggerLengthLess || cfg.EdgeRising {
+		t.Errorf("unexpected trigger config %+v", cfg)
 	}
-}
+
+	for name, args := range map[string]map[string]any{
+		"missing length": {"type": "pulse"},
+		"bad type":       {"type": "runt"},
+		"bad condition":  {"type": "pulse", "length": 1e-6, "length_condition": "equal"},
+	} {
+		result, _ := s.handleScopeTrigger(context.Background(), makeReq(args))
+		if !result.IsError {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestHandleScopeTriggerPosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    float64
+		wantErr bool
+	}{
+		{"seconds", 0.002, 0.003, false},
+		{"numeric string", "0.005", 0, false},
+		{"percent", "10%", 0.004, false},
+		{"all history", "100%", -0.005, false},
+		{"too long", 0.02, 0, true},
+		{"bad percent", "150%", 0, true},
+		{"garbage", "early", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, dev := newTestServer()
+			// 10 ms buffer: 1000 samples at 100 kHz.
+			dev.scope.sampleRate = 100e3
+			dev.scope.bufferSize = 1000
+			args := map[string]any{"source": float64(2)}
+			if tt.value != nil {
+				args["position"] = tt.value
+			}
+			result, _ := s.handleScopeTrigger(context.Background(), makeReq(args))
+			if result.IsError != tt.wantErr {
+				t.Fatalf("IsError = %v, want %v: %v", result.IsError, tt.wantErr, result.Content)
+			}
+			if got := dev.scope.triggerCfg.Position; !tt.wantErr && (got == nil || math.Abs(*got-tt.want) > 1e-12) {
+				t.Errorf("position = %v, want %g", got, tt.want)
+			}
+		})
+	}
+	t.Run("omitted", func(t *testing.T) {
+		s, dev := newTestServer()
+		if result, _ := s.handleScopeTrigger(context.Background(), makeReq(map[string]any{"source": float64(2)})); result.IsError {
+			t.Fatalf("unexpected error: %v", result.Content)
+		}
+		if got := dev.scope.triggerCfg.Position; got != nil {
+			t.Errorf("position = %g, want the current position kept", *got)
+		}
+	})
+
+	s, _ := newTestServer()
+	result, _ := s.handleScopeTrigger(context.Background(), makeReq(map[string]any{"position": "10%"}))
+	if !result.IsError {
+		t.Error("expected error before the oscilloscope is opened")
+	}
+}
+
+func TestHandleScopeTriggerWindow(t *testing.T) {
+	s, dev := newTestServer()
+	result, err := s.handleScopeTrigger(context.Background(), makeReq(map[string]any{
+		"type":        "window",
+		"window_low":  3.1,
+		"window_high": 3.5,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %v", result.Content)
+	}
+	cfg := dev.scope.triggerCfg
+	if cfg.Type != dwf.TriggerTypeWindow || cfg.WindowLow != 3.1 || cfg.WindowHigh != 3.5 || cfg.EdgeRising {
+		t.Errorf("unexpected trigger config %+v", cfg)
+	}
+
+	s.handleScopeTrigger(context.Background(), makeReq(map[string]any{
+		"type": "window", "window_low": -1.0, "window_high": 1.0, "window_condition": "enter",
+	}))
+	if !dev.scope.triggerCfg.EdgeRising {
+		t.Error("enter condition should select entering the window")
+	}
+
+	for name, args := range map[string]map[string]any{
+		"missing bounds": {"type": "window", "window_low": 1.0},
+		"inverted":       {"type": "window", "window_low": 2.0, "window_high": 1.0},
+		"hysteresis":     {"type": "window", "window_low": 1.0, "window_high": 2.0, "hysteresis": 0.1},
+		"bad condition":  {"type": "window", "window_low": 1.0, "window_high": 2.0, "window_condition": "inside"},
+	} {
+		result, _ := s.handleScopeTrigger(context.Background(), makeReq(args))
+		if !result.IsError {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestHandleScopeTriggerHysteresis(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    float64
+		wantErr bool
+	}{
+		{"omitted", nil, dwf.TriggerHysteresisKeep, false},
+		{"zero", 0.0, 0, false},
+		{"number", 0.05, 0.05, false},
+		{"numeric string", "0.1", 0.1, false},
+		{"auto", "auto", dwf.TriggerHysteresisAuto, false},
+		{"negative", -0.1, 0, true},
+		{"garbage", "lots", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, dev := newTestServer()
+			args := map[string]any{"source": float64(2)}
+			if tt.value != nil {
+				args["hysteresis"] = tt.value
+			}
+			result, err := s.handleScopeTrigger(context.Background(), makeReq(args))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.IsError != tt.wantErr {
+				t.Fatalf("IsError = %v, want %v: %v", result.IsError, tt.wantErr, result.Content)
+			}
+			if !tt.wantErr && dev.scope.triggerCfg.Hysteresis != tt.want {
+				t.Errorf("hysteresis = %g, want %g", dev.scope.triggerCfg.Hysteresis, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleScopeRecord(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.recordData = []float64{0.1, 0.2, 0.3}
+	result, err := s.handleScopeRecord(context.Background(), makeReq(map[string]interface{}{
+		"channel": float64(1),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, `"samples":3`) {
+		t.Errorf("expected samples count, got %q", text)
+	}
+}
+
+func TestHandleScopeRecordTimeAxis(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.recordData = make([]float64, 1000)
+	dev.scope.sampleRate = 1e6
+	dev.scope.triggerIndex = 500
+	result, _ := s.handleScopeRecord(context.Background(), makeReq(map[string]any{"channel": float64(1)}))
+	var got map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := map[string]float64{"sample_rate": 1e6, "sample_period": 1e-6, "duration": 1e-3, "trigger_index": 500}
+	for key, v := range want {
+		if got[key] != v {
+			t.Errorf("%s = %v, want %g", key, got[key], v)
+		}
+	}
+
+	// Peak detect points each span several samples.
+	dev.scope.peakRecord = dwf.PeakRecord{Min: make([]float64, 100), Max: make([]float64, 100), SamplesPerPoint: 10}
+	dev.scope.triggerIndex = -1
+	result, _ = s.handleScopeRecord(context.Background(), makeReq(map[string]any{"channel": float64(1), "peak_detect": true}))
+	got = nil
+	json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got)
+	if got["sample_rate"] != 1e5 || got["duration"] != 1e-3 || got["trigger_index"] != -1.0 {
+		t.Errorf("unexpected peak time axis %v", got)
+	}
+}
+
+func TestHandleScopeRecordTimeout(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.recordBlock = true
+	start := time.Now()
+	result, err := s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+		"channel": float64(1),
+		"timeout": 0.05,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected timeout error")
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "timed out") {
+		t.Errorf("expected a timeout message, got %q", text)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("record took %v despite a 50 ms timeout", elapsed)
+	}
+
+	result, _ = s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+		"channel": float64(1),
+		"timeout": -1.0,
+	}))
+	if !result.IsError {
+		t.Error("expected error for negative timeout")
+	}
+}
+
+func TestHandleScopeRecordAverage(t *testing.T) {
+	t.Run("with stddev", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.recordData = []float64{0.5, 1.5}
+		dev.scope.recordStdDev = []float64{0.01, 0.02}
+		result, err := s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+			"channel":  float64(2),
+			"averages": float64(16),
+			"stddev":   true,
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("unexpected tool error: %v", result.Content)
+		}
+		if dev.scope.averageCount != 16 {
+			t.Errorf("average count = %d, want 16", dev.scope.averageCount)
+		}
+		var got struct {
+			Averages int       `json:"averages"`
+			Data     []float64 `json:"data"`
+			StdDev   []float64 `json:"stddev"`
+		}
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if got.Averages != 16 || len(got.Data) != 2 || len(got.StdDev) != 2 {
+			t.Errorf("unexpected result %+v", got)
+		}
+	})
+
+	t.Run("stddev omitted by default", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.recordData = []float64{0.5}
+		result, _ := s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+			"channel":  float64(1),
+			"averages": float64(4),
+		}))
+		if text := result.Content[0].(mcp.TextContent).Text; strings.Contains(text, "stddev") {
+			t.Errorf("stddev should be omitted, got %q", text)
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, _ := s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+			"channel":  float64(1),
+			"averages": float64(0),
+		}))
+		if !result.IsError {
+			t.Error("expected error for averages = 0")
+		}
+		if dev.scope.averageCount != 0 {
+			t.Error("RecordAverage should not be called")
+		}
+	})
+}
+
+func TestHandleScopeStats(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.sampleRate = 1e6
+	dev.scope.recordData = []float64{0, 1, 0, -1, 0, 1, 0, -1, 0}
+	result, _ := s.handleScopeStats(context.Background(), makeReq(map[string]any{"channel": float64(1), "count": float64(3)}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Acquisitions int `json:"acquisitions"`
+		Measurements map[string]struct {
+			Count  int      `json:"count"`
+			Mean   *float64 `json:"mean"`
+			StdDev *float64 `json:"stddev"`
+		} `json:"measurements"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	pp := got.Measurements["peak_to_peak"]
+	if got.Acquisitions != 3 || pp.Count != 3 || pp.Mean == nil || *pp.Mean != 2 || pp.StdDev == nil || *pp.StdDev != 0 {
+		t.Errorf("unexpected peak_to_peak stats %+v", pp)
+	}
+	if len(got.Measurements) != len(waveformStatFields) {
+		t.Errorf("got %d measurements, want %d", len(got.Measurements), len(waveformStatFields))
+	}
+
+	if result, _ := s.handleScopeStats(context.Background(), makeReq(map[string]any{"channel": float64(1), "count": float64(0)})); !result.IsError {
+		t.Error("expected error for count 0")
+	}
+	dev.scope.recordErr = errors.New("boom")
+	if result, _ := s.handleScopeStats(context.Background(), makeReq(map[string]any{"channel": float64(1)})); !result.IsError {
+		t.Error("expected error result")
+	}
+}
+
+func TestHandleScopeFrequency(t *testing.T) {
+	s, dev := newTestServer()
+	frequency := func(args map[string]any) map[string]any {
+		t.Helper()
+		args["channel"] = float64(1)
+		result, _ := s.handleScopeFrequency(context.Background(), makeReq(args))
+		if result.IsError {
+			t.Fatalf("unexpected error: %v", result.Content)
+		}
+		var got map[string]any
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return got
+	}
+
+	if result, _ := s.handleScopeFrequency(context.Background(), makeReq(map[string]any{"channel": float64(1)})); !result.IsError {
+		t.Error("expected error before the oscilloscope is opened")
+	}
+	dev.scope.sampleRate = 1000
+	dev.scope.recordData = []float64{0, 0, 2, 2, 0, 0, 2, 2, 0, 0, 2, 2, 0}
+
+	// Without a counter the capture is measured in software.
+	got := frequency(map[string]any{})
+	if got["method"] != "software" || got["frequency"] != 250.0 || got["count"] != 2.0 || got["gate_time"] != 0.013 {
+		t.Errorf("unexpected software result %v", got)
+	}
+	if result, _ := s.handleScopeFrequency(context.Background(), makeReq(map[string]any{"channel": float64(1), "method": "counter"})); !result.IsError {
+		t.Error("expected error for counter method without a counter")
+	}
+
+	// With a counter the threshold defaults to the middle of the swing.
+	dev.scope.counterMax = 10
+	dev.scope.counter = dwf.CounterReading{Count: 1000, Frequency: 1000, GateTime: 1}
+	got = frequency(map[string]any{})
+	if got["method"] != "counter" || got["frequency"] != 1000.0 || got["period"] != 0.001 || dev.scope.counterCfg.Level != 1 {
+		t.Errorf("unexpected counter result %v with %+v", got, dev.scope.counterCfg)
+	}
+	frequency(map[string]any{"level": 0.5, "gate_time": 0.1})
+	if dev.scope.counterCfg.Level != 0.5 || dev.scope.counterCfg.GateTime != 0.1 {
+		t.Errorf("unexpected counter config %+v", dev.scope.counterCfg)
+	}
+	if result, _ := s.handleScopeFrequency(context.Background(), makeReq(map[string]any{"channel": float64(1), "gate_time": float64(20)})); !result.IsError {
+		t.Error("expected error for a gate time beyond the counter's limit")
+	}
+}
+
+func TestSampleStats(t *testing.T) {
+	stats := sampleStats([]float64{1, math.NaN(), 3})
+	if stats["count"] != 2 || stats["mean"] != 2.0 || stats["min"] != 1.0 || stats["max"] != 3.0 || math.Abs(stats["stddev"].(float64)-math.Sqrt2) > 1e-12 {
+		t.Errorf("unexpected stats %v", stats)
+	}
+	if stats := sampleStats([]float64{math.NaN()}); stats["count"] != 0 || !math.IsNaN(stats["mean"].(float64)) {
+		t.Errorf("unexpected stats of no values %v", stats)
+	}
+}
+
+func TestHandleScopeRecordXY(t *testing.T) {
+	s, dev := newTestServer()
+	const n = 1000
+	x, y := make([]float64, n), make([]float64, n)
+	for i := range n {
+		t := 2 * math.Pi * 4 * float64(i) / n
+		x[i] = math.Sin(t)
+		y[i] = 0.5 * math.Sin(t+math.Pi/6)
+	}
+	dev.scope.channelData = map[int][]float64{1: x, 2: y}
+	result, _ := s.handleScopeRecordXY(context.Background(), makeReq(map[string]any{}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		X     []float64 `json:"x"`
+		Y     []float64 `json:"y"`
+		Phase float64   `json:"phase"`
+		Ratio float64   `json:"amplitude_ratio"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got.X) != n || len(got.Y) != n {
+		t.Errorf("got %d/%d samples, want %d", len(got.X), len(got.Y), n)
+	}
+	if math.Abs(got.Phase-30) > 0.5 || math.Abs(got.Ratio-0.5) > 0.01 {
+		t.Errorf("phase %g, ratio %g; want 30 and 0.5", got.Phase, got.Ratio)
+	}
+
+	// Swapping the axes makes X lead.
+	result, _ = s.handleScopeRecordXY(context.Background(), makeReq(map[string]any{"x_channel": float64(2), "y_channel": float64(1)}))
+	json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got)
+	if math.Abs(got.Phase+30) > 0.5 {
+		t.Errorf("swapped phase %g, want -30", got.Phase)
+	}
+
+	result, _ = s.handleScopeRecordXY(context.Background(), makeReq(map[string]any{"x_channel": float64(1), "y_channel": float64(1)}))
+	if !result.IsError {
+		t.Error("expected error for the same channel on both axes")
+	}
+}
+
+func TestHandleScopeRecordRaw(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.rawRecord = dwf.RawRecord{Codes: []int16{-16, 0, 16}, Bits: 12, VoltsPerCode: 10.0 / 65536, Offset: 0.5}
+	result, _ := s.handleScopeRecordRaw(context.Background(), makeReq(map[string]any{"channel": float64(1)}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Codes        []int   `json:"codes"`
+		Bits         int     `json:"adc_bits"`
+		CodesPerStep int     `json:"codes_per_step"`
+		VoltsPerCode float64 `json:"volts_per_code"`
+		Offset       float64 `json:"offset"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !slices.Equal(got.Codes, []int{-16, 0, 16}) || got.Bits != 12 || got.CodesPerStep != 16 || got.Offset != 0.5 {
+		t.Errorf("unexpected result %+v", got)
+	}
+
+	dev.scope.recordErr = errors.New("raw ADC samples are not supported by this device")
+	if result, _ := s.handleScopeRecordRaw(context.Background(), makeReq(map[string]any{"channel": float64(1)})); !result.IsError {
+		t.Error("expected error result")
+	}
+}
+
+func TestHandleScopeRecordPeakDetect(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.peakRecord = dwf.PeakRecord{
+		Min:             []float64{0, -0.1, 0},
+		Max:             []float64{0.1, 3.2, 0.1},
+		SamplesPerPoint: 8,
+	}
+	result, err := s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+		"channel":     float64(1),
+		"peak_detect": true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got struct {
+		Samples         int       `json:"samples"`
+		SamplesPerPoint float64   `json:"samples_per_point"`
+		Min             []float64 `json:"min"`
+		Max             []float64 `json:"max"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Samples != 3 || got.SamplesPerPoint != 8 || got.Max[1] != 3.2 || got.Min[1] != -0.1 {
+		t.Errorf("unexpected result %+v", got)
+	}
+
+	result, _ = s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+		"channel":     float64(1),
+		"peak_detect": true,
+		"averages":    float64(4),
+	}))
+	if !result.IsError {
+		t.Error("expected error when combining peak_detect with averages")
+	}
+}
+
+func TestHandleScopeRecordNoise(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.sampleRate = 1000
+	dev.scope.peakRecord = dwf.PeakRecord{
+		Min:             []float64{0.9, 1.8},
+		Max:             []float64{1.1, 2.6},
+		SamplesPerPoint: 2,
+		Data:            []float64{1, 1, 2, 2},
+	}
+	result, _ := s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+		"channel": float64(1),
+		"noise":   true,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Samples              int       `json:"samples"`
+		SampleRate           float64   `json:"sample_rate"`
+		Data                 []float64 `json:"data"`
+		NoiseMin             []float64 `json:"noise_min"`
+		NoiseMax             []float64 `json:"noise_max"`
+		NoiseSamplesPerPoint float64   `json:"noise_samples_per_point"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Samples != 4 || got.SampleRate != 1000 || len(got.Data) != 4 || got.NoiseSamplesPerPoint != 2 {
+		t.Errorf("unexpected result %+v", got)
+	}
+	if len(got.NoiseMin) != 2 || got.NoiseMax[1] != 2.6 {
+		t.Errorf("noise envelope = %v / %v", got.NoiseMin, got.NoiseMax)
+	}
+
+	result, _ = s.handleScopeRecord(context.Background(), makeReq(map[string]any{
+		"channel":     float64(1),
+		"noise":       true,
+		"peak_detect": true,
+	}))
+	if !result.IsError {
+		t.Error("expected error when combining noise with peak_detect")
+	}
+}
+
+func TestHandleScopeAnalyze(t *testing.T) {
+	t.Run("trapezoid wave", func(t *testing.T) {
+		// 100-sample period at 1 MHz: 10-sample rising ramp, 30 samples
+		// high, 10-sample falling ramp, 50 samples low.
+		var data []float64
+		for c := 0; c < 5; c++ {
+			for i := 0; i < 100; i++ {
+				switch {
+				case i < 10:
+					data = append(data, float64(i)/10*3.3)
+				case i < 40:
+					data = append(data, 3.3)
+				case i < 50:
+					data = append(data, float64(50-i)/10*3.3)
+				default:
+					data = append(data, 0)
+				}
+			}
+		}
+		s, dev := newTestServer()
+		dev.scope.recordData = data
+		dev.scope.sampleRate = 1e6
+		result, err := s.handleScopeAnalyze(context.Background(), makeReq(map[string]any{
+			"channel": float64(1),
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var m map[string]float64
+		text := result.Content[0].(mcp.TextContent).Text
+		if err := json.Unmarshal([]byte(text), &m); err != nil {
+			t.Fatalf("invalid JSON %q: %v", text, err)
+		}
+		checks := map[string]float64{
+			"peak_to_peak": 3.3,
+			"frequency":    10e3,
+			"period":       100e-6,
+			"duty_cycle":   40,
+			"rise_time":    8e-6,
+			"fall_time":    8e-6,
+			"cycles":       4,
+		}
+		for key, want := range checks {
+			if got := m[key]; math.Abs(got-want) > want*0.01 {
+				t.Errorf("%s = %g, want %g", key, got, want)
+			}
+		}
+	})
+
+	t.Run("dc signal", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.recordData = []float64{1.2, 1.2, 1.2}
+		dev.scope.sampleRate = 1e6
+		result, _ := s.handleScopeAnalyze(context.Background(), makeReq(map[string]any{
+			"channel": float64(1),
+		}))
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, `"mean":1.2`) || !strings.Contains(text, `"frequency":null`) {
+			t.Errorf("expected mean 1.2 and no frequency, got %q", text)
+		}
+		if !strings.Contains(text, `"quality":"non_finite"`) {
+			t.Errorf("expected quality flag for undetermined timing, got %q", text)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.recordErr = errors.New("scope fail")
+		result, _ := s.handleScopeAnalyze(context.Background(), makeReq(map[string]any{
+			"channel": float64(1),
+		}))
+		if !result.IsError {
+			t.Error("expected error result")
+		}
+	})
+}
+
+func TestHandleScopeTimebase(t *testing.T) {
+	// A 10 kHz reference recorded by a sample clock running 100 ppm fast
+	// appears slightly slower than nominal.
+	const ref, rate = 10e3, 1e6
+	data := make([]float64, 8192)
+	for i := range data {
+		data[i] = math.Sin(2 * math.Pi * ref / (1 + 100e-6) * float64(i) / rate)
+	}
+
+	t.Run("fast clock", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.recordData = data
+		dev.scope.sampleRate = rate
+		result, err := s.handleScopeTimebase(context.Background(), makeReq(map[string]any{
+			"channel":             float64(1),
+			"reference_frequency": ref,
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("unexpected tool error: %v", result.Content)
+		}
+		var m map[string]float64
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &m); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if math.Abs(m["error_ppm"]-100) > 1 {
+			t.Errorf("error_ppm = %g, want 100", m["error_ppm"])
+		}
+		if math.Abs(m["correction_factor"]*(1+100e-6)-1) > 1e-6 {
+			t.Errorf("correction_factor = %g", m["correction_factor"])
+		}
+		if m["edges"] != 81 || m["tie_rms"] > 1e-9 {
+			t.Errorf("edges = %g, tie_rms = %g", m["edges"], m["tie_rms"])
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		ref  float64
+		data []float64
+	}{
+		"no reference":    {0, data},
+		"too fast":        {400e3, data},
+		"dc signal":       {ref, []float64{1, 1, 1}},
+		"wrong frequency": {12e3, data},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, dev := newTestServer()
+			dev.scope.recordData = tc.data
+			dev.scope.sampleRate = rate
+			result, _ := s.handleScopeTimebase(context.Background(), makeReq(map[string]any{
+				"channel":             float64(1),
+				"reference_frequency": tc.ref,
+			}))
+			if !result.IsError {
+				t.Error("expected error result")
+			}
+		})
+	}
+}
+
+func TestHandleScopeFFT(t *testing.T) {
+	// 1030 Hz at 2 V plus 5 kHz at 0.5 V, sampled at 64 kHz.
+	data := make([]float64, 4096)
+	for i := range data {
+		tm := float64(i) / 64e3
+		data[i] = 0.3 + 2*math.Sin(2*math.Pi*1030*tm) + 0.5*math.Sin(2*math.Pi*5000*tm)
+	}
+
+	t.Run("flattop peaks", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.recordData = data
+		dev.scope.sampleRate = 64e3
+		result, err := s.handleScopeFFT(context.Background(), makeReq(map[string]any{
+			"channel": float64(1),
+			"window":  "flattop",
+			"peaks":   float64(2),
+			"bins":    float64(64),
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		var parsed struct {
+			DC          float64 `json:"dc"`
+			Peaks       []struct{ Frequency, Magnitude float64 }
+			Frequencies []float64 `json:"frequencies"`
+		}
+		if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+			t.Fatalf("invalid JSON %q: %v", text, err)
+		}
+		if len(parsed.Peaks) != 2 {
+			t.Fatalf("expected 2 peaks, got %q", text)
+		}
+		want := []struct{ f, a float64 }{{1030, 2}, {5000, 0.5}}
+		for i, w := range want {
+			p := parsed.Peaks[i]
+			if math.Abs(p.Frequency-w.f) > 5 || math.Abs(p.Magnitude-w.a) > w.a*0.01 {
+				t.Errorf("peak %d = %+v, want %g Hz at %g V", i, p, w.f, w.a)
+			}
+		}
+		if math.Abs(parsed.DC-0.3) > 0.01 {
+			t.Errorf("dc = %g, want 0.3", parsed.DC)
+		}
+		if len(parsed.Frequencies) > 64 {
+			t.Errorf("expected at most 64 spectrum points, got %d", len(parsed.Frequencies))
+		}
+	})
+
+	t.Run("invalid window", func(t *testing.T) {
+		s, _ := newTestServer()
+		result, _ := s.handleScopeFFT(context.Background(), makeReq(map[string]any{
+			"channel": float64(1),
+			"window":  "blackman",
+		}))
+		if !result.IsError {
+			t.Error("expected error result")
+		}
+	})
+
+	t.Run("not opened", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.recordData = data
+		result, _ := s.handleScopeFFT(context.Background(), makeReq(map[string]any{
+			"channel": float64(1),
+		}))
+		if !result.IsError {
+			t.Error("expected error result without a sample rate")
+		}
+	})
+}
+
+func TestHandleScopeStream(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.streamData = [][]float64{{0.1, 0.2}, {-0.3, 0.4, 0.5}}
+		result, err := s.handleScopeStream(context.Background(), makeReq(map[string]any{
+			"channel":  float64(1),
+			"duration": 2.0,
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, `"samples":5`) || !strings.Contains(text, `"chunks":2`) {
+			t.Errorf("expected 5 samples in 2 chunks, got %q", text)
+		}
+		if !strings.Contains(text, `"min":-0.3`) || !strings.Contains(text, `"max":0.5`) {
+			t.Errorf("expected min/max, got %q", text)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.streamData = [][]float64{{0.1}}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		result, _ := s.handleScopeStream(ctx, makeReq(map[string]any{
+			"channel":  float64(1),
+			"duration": 1.0,
+		}))
+		if !result.IsError {
+			t.Error("expected error result for cancelled context")
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.streamErr = errors.New("device lost")
+		result, _ := s.handleScopeStream(context.Background(), makeReq(map[string]any{
+			"channel":  float64(1),
+			"duration": 2.0,
+		}))
+		if !result.IsError {
+			t.Fatal("expected error result")
+		}
+		if usage := s.usage.snapshot(); len(usage) != 0 {
+			t.Errorf("failed stream counted as a capture: %v", usage)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.streamErr = context.DeadlineExceeded
+		result, _ := s.handleScopeStream(context.Background(), makeReq(map[string]any{
+			"channel":  float64(1),
+			"duration": 2.0,
+			"timeout":  3.0,
+		}))
+		if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "timed out") {
+			t.Errorf("expected a timeout error, got %q", text)
+		}
+		deadline, ok := dev.scope.streamCtx.Deadline()
+		if left := time.Until(deadline); !ok || left < 4*time.Second || left > 5*time.Second {
+			t.Errorf("stream deadline in %v, want duration plus timeout", left)
+		}
+	})
+
+	t.Run("canceled request", func(t *testing.T) {
+		s, dev := newTestServer()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s.handleScopeStream(ctx, makeReq(map[string]any{
+			"channel":  float64(1),
+			"duration": 2.0,
+		}))
+		if dev.scope.streamCtx == nil || dev.scope.streamCtx.Err() == nil {
+			t.Error("expected the request context to reach the stream")
+		}
+	})
+
+	t.Run("invalid duration", func(t *testing.T) {
+		s, _ := newTestServer()
+		result, _ := s.handleScopeStream(context.Background(), makeReq(map[string]any{
+			"channel":  float64(1),
+			"duration": 0.0,
+		}))
+		if !result.IsError {
+			t.Error("expected error result for zero duration")
+		}
+	})
+}
+
+func TestHandleScopeClose(t *testing.T) {
+	s, _ := newTestServer()
+	result, err := s.handleScopeClose(context.Background(), makeReq(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "reset") {
+		t.Errorf("expected 'reset', got %q", text)
+	}
+}
 
 // ============================= Wavegen Handlers =============================
 
-func TestHandleWavegenGenerate(t *testing.T) {
+func TestHandleWavegenGenerate(t *testing.T) {
+	s, _ := newTestServer()
+	result, err := s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{
+		"channel":   float64(1),
+		"function":  float64(1),
+		"frequency": 1000.0,
+		"amplitude": 2.5,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "channel 1") {
+		t.Errorf("expected 'channel 1', got %q", text)
+	}
+}
+
+func TestHandleWavegenGenerateClamped(t *testing.T) {
+	s, dev := newTestServer()
+	dev.wavegen.generateSettings = dwf.WavegenSettings{
+		Frequency:       1000,
+		Amplitude:       5,
+		Symmetry:        50,
+		AmplitudeLimits: dwf.Limits{Min: 0, Max: 5},
+	}
+	result, _ := s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{
+		"channel":   float64(1),
+		"function":  float64(1),
+		"frequency": 1000.0,
+		"amplitude": 12.0,
+	}))
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, `"adjusted":{"amplitude":{"applied":5,"requested":12}}`) {
+		t.Errorf("expected only amplitude to be reported as clamped, got %q", text)
+	}
+	if !strings.Contains(text, `"amplitude":{"max":5,"min":0}`) {
+		t.Errorf("expected amplitude limits, got %q", text)
+	}
+}
+
+func TestHandleWavegenGenerateModulation(t *testing.T) {
+	s, dev := newTestServer()
+	dev.wavegen.generateSettings = dwf.WavegenSettings{
+		Frequency: 10000,
+		Amplitude: 1,
+		Symmetry:  50,
+		AM:        &dwf.WavegenModulation{Function: dwf.FuncSine, Frequency: 100, Depth: 40},
+	}
+	result, _ := s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{
+		"channel":      float64(1),
+		"function":     float64(1),
+		"frequency":    10000.0,
+		"amplitude":    1.0,
+		"am_frequency": 100.0,
+		"am_depth":     50.0,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	cfg := dev.wavegen.generateCfg
+	if cfg.AM == nil || *cfg.AM != (dwf.WavegenModulation{Function: dwf.FuncSine, Frequency: 100, Depth: 50}) || cfg.FM != nil {
+		t.Errorf("unexpected modulation AM %+v FM %+v", cfg.AM, cfg.FM)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, `"am":{"depth":40,"frequency":100,"function":1}`) || !strings.Contains(text, `"am_depth":{"applied":40,"requested":50}`) {
+		t.Errorf("expected the applied and adjusted AM depth, got %q", text)
+	}
+
+	for _, args := range []map[string]any{
+		{"fm_frequency": 0.0},
+		{"fm_frequency": 10.0, "fm_deviation": 150.0},
+		{"am_frequency": 10.0, "am_depth": -1.0},
+	} {
+		args["channel"], args["function"] = float64(1), float64(1)
+		if result, _ := s.handleWavegenGenerate(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestHandleWavegenGenerateShape(t *testing.T) {
+	s, dev := newTestServer()
+	dev.wavegen.generateSettings = dwf.WavegenSettings{Frequency: 1000, Amplitude: 1, Symmetry: 40, Phase: 270}
+	result, _ := s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{
+		"channel":  float64(1),
+		"function": float64(dwf.FuncSinePower),
+		"power":    40.0,
+		"phase":    -90.0,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	cfg := dev.wavegen.generateCfg
+	if cfg.Symmetry != 40 || cfg.Phase != 270 {
+		t.Errorf("expected power 40 as symmetry and phase 270, got %g and %g", cfg.Symmetry, cfg.Phase)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, `"power":40`) || strings.Contains(text, `"symmetry":40`) || !strings.Contains(text, `"phase":270`) {
+		t.Errorf("expected the applied power and phase, got %q", text)
+	}
+
+	s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{"channel": float64(1), "function": float64(dwf.FuncSinePower)}))
+	if cfg := dev.wavegen.generateCfg; cfg.Symmetry != 0 {
+		t.Errorf("expected a plain sine power by default, got power %g", cfg.Symmetry)
+	}
+	for _, args := range []map[string]any{
+		{"function": float64(dwf.FuncSine), "power": 10.0},
+		{"function": float64(dwf.FuncSinePower), "power": 150.0},
+	} {
+		args["channel"] = float64(1)
+		if result, _ := s.handleWavegenGenerate(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestHandleWavegenGenerateTrigger(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{
+		"channel":        float64(2),
+		"function":       float64(1),
+		"trigger_source": float64(dwf.TrigSrcExternal1),
+		"trigger_slope":  "falling",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	cfg := dev.wavegen.generateCfg
+	if cfg.TriggerSource != dwf.TrigSrcExternal1 || cfg.TriggerSlope != dwf.TriggerSlopeFall {
+		t.Errorf("expected external trigger 1 on the falling edge, got source %d slope %d", cfg.TriggerSource, cfg.TriggerSlope)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, `"trigger":{"slope":"falling","source":11}`) {
+		t.Errorf("expected the trigger in the result, got %q", text)
+	}
+
+	result, _ = s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{"channel": float64(1), "function": float64(1)}))
+	if cfg := dev.wavegen.generateCfg; cfg.TriggerSource != dwf.TrigSrcNone || strings.Contains(result.Content[0].(mcp.TextContent).Text, `"trigger"`) {
+		t.Errorf("expected no trigger by default, got source %d", cfg.TriggerSource)
+	}
+	result, _ = s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{"channel": float64(1), "function": float64(1), "trigger_slope": "up"}))
+	if !result.IsError {
+		t.Error("expected error for an invalid trigger_slope")
+	}
+}
+
+func TestHandleWavegenEnable(t *testing.T) {
+	s, _ := newTestServer()
+	result, err := s.handleWavegenEnable(context.Background(), makeReq(map[string]any{
+		"channel": float64(2),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "enabled") {
+		t.Errorf("expected 'enabled', got %q", text)
+	}
+}
+
+func TestHandleWavegenDisable(t *testing.T) {
+	s, _ := newTestServer()
+	result, err := s.handleWavegenDisable(context.Background(), makeReq(map[string]any{
+		"channel": float64(1),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "disabled") {
+		t.Errorf("expected 'disabled', got %q", text)
+	}
+}
+
+func TestHandleWavegenIdle(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handleWavegenIdle(context.Background(), makeReq(map[string]any{
+		"channel": float64(2),
+		"idle":    "Hold",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if got := dev.wavegen.idle[2]; got != dwf.WavegenIdleHold {
+		t.Errorf("expected hold on channel 2, got %d", got)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "hold") {
+		t.Errorf("expected 'hold', got %q", text)
+	}
+
+	result, _ = s.handleWavegenIdle(context.Background(), makeReq(map[string]any{"channel": float64(1), "idle": "float"}))
+	if !result.IsError {
+		t.Error("expected error for an unknown idle mode")
+	}
+	dev.wavegen.idleErr = errors.New("idle mode 3 not supported on channel 1")
+	result, _ = s.handleWavegenIdle(context.Background(), makeReq(map[string]any{"channel": float64(1), "idle": "hold"}))
+	if !result.IsError {
+		t.Error("expected the device error")
+	}
+}
+
+func TestHandleWavegenMaster(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handleWavegenMaster(context.Background(), makeReq(map[string]any{}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if got := dev.wavegen.master[2]; got != 1 {
+		t.Errorf("expected channel 2 to follow channel 1 by default, got %d", got)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "follows channel 1") {
+		t.Errorf("expected 'follows channel 1', got %q", text)
+	}
+
+	result, _ = s.handleWavegenMaster(context.Background(), makeReq(map[string]any{"channel": float64(2), "master": float64(0)}))
+	if got := dev.wavegen.master[2]; result.IsError || got != 2 {
+		t.Errorf("expected master 0 to make channel 2 independent, got master %d", got)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "independently") {
+		t.Errorf("expected 'independently', got %q", text)
+	}
+
+	result, _ = s.handleWavegenMaster(context.Background(), makeReq(map[string]any{"channel": float64(-1)}))
+	if !result.IsError {
+		t.Error("expected error for an invalid channel")
+	}
+}
+
+func TestHandleWavegenClose(t *testing.T) {
+	s, _ := newTestServer()
+	result, err := s.handleWavegenClose(context.Background(), makeReq(map[string]any{
+		"channel": float64(1),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "reset") {
+		t.Errorf("expected 'reset', got %q", text)
+	}
+}
+
+// ============================= Supply Handlers =============================
+
+func TestHandleSuppliesSwitch(t *testing.T) {
+	s, _ := newTestServer()
+	result, err := s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{
+		"master_state":     true,
+		"positive_state":   true,
+		"positive_voltage": 5.0,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "configured") {
+		t.Errorf("expected 'configured', got %q", text)
+	}
+}
+
+func TestHandleSuppliesSwitchRamp(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{
+		"master_state":     true,
+		"positive_state":   true,
+		"positive_voltage": 5.0,
+		"ramp_time":        0.5,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if dev.supply.switchCfg.RampTime != 0.5 {
+		t.Errorf("expected a 0.5 s ramp, got %g", dev.supply.switchCfg.RampTime)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "ramps up over 0.5 s") {
+		t.Errorf("expected the ramp in the message, got %q", text)
+	}
+	result, _ = s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{"master_state": true, "ramp_time": 120.0}))
+	if !result.IsError {
+		t.Error("expected error for a ramp_time over 60 s")
+	}
+}
+
+func TestHandleSuppliesGetConfig(t *testing.T) {
+	s, dev := newTestServer()
+	limit := 0.5
+	dev.supply.readback = dwf.SuppliesReadback{
+		MasterState: true,
+		Rails: []dwf.SupplyRailReadback{
+			{Rail: "positive", Label: "V+", Enabled: true, Voltage: 3.3, Current: &limit},
+			{Rail: "negative", Label: "V-", Voltage: -5},
+		},
+	}
+	result, _ := s.handleSuppliesGetConfig(context.Background(), makeReq(nil))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		MasterState bool             `json:"master_state"`
+		Rails       []map[string]any `json:"rails"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.MasterState || len(got.Rails) != 2 {
+		t.Fatalf("unexpected config: %+v", got)
+	}
+	if pos := got.Rails[0]; pos["output"] != true || pos["voltage"] != 3.3 || pos["current"] != 0.5 {
+		t.Errorf("unexpected positive rail: %v", pos)
+	}
+	if neg := got.Rails[1]; neg["output"] != false || neg["current"] != nil {
+		t.Errorf("unexpected negative rail: %v", neg)
+	}
+}
+
+func TestHandleSuppliesInfo(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handleSuppliesInfo(context.Background(), makeReq(nil))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"rails":[]`) {
+		t.Errorf("expected no rails, got %q", text)
+	}
+
+	dev.supply.rails = []dwf.SupplyRail{{
+		Rail:  "positive",
+		Name:  "Positive Supply",
+		Label: "V+",
+		Nodes: []dwf.SupplyNode{{Name: "Voltage", Units: "V", Min: 0.5, Max: 5, Steps: 4096}},
+	}}
+	s.SetSafetyLimits(&SafetyLimits{SupplyVoltage: 3.3})
+	result, _ = s.handleSuppliesInfo(context.Background(), makeReq(nil))
+	text := result.Content[0].(mcp.TextContent).Text
+	for _, want := range []string{`"rail":"positive"`, `"label":"V+"`, `"min":0.5`, `"max":5`, `"steps":4096`, `"safety_limit":3.3`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %s in %q", want, text)
+		}
+	}
+}
+
+func TestSupplyTrips(t *testing.T) {
+	st := dwf.SupplyStatus{
+		Rails: []dwf.SupplyRailStatus{
+			{Label: "V+", Limiting: true},
+			{Label: "V-", Limiting: true},
+			{Label: "VDD"},
+		},
+		Faults: []string{"V- Limitation", "System Overtemp"},
+	}
+	got := supplyTrips(st)
+	want := []string{"V- Limitation", "System Overtemp", "V+ current limiting"}
+	if !slices.Equal(got, want) {
+		t.Errorf("supplyTrips = %v, want %v", got, want)
+	}
+}
+
+func TestHandleSuppliesStatus(t *testing.T) {
+	s, dev := newTestServer()
+	ok := dwf.SupplyStatus{Rails: []dwf.SupplyRailStatus{{Rail: "positive", Label: "V+", Enabled: true, SetVoltage: 5, Voltage: 5}}, Faults: []string{}}
+	limited := ok
+	limited.Rails = []dwf.SupplyRailStatus{{Rail: "positive", Label: "V+", Enabled: true, SetVoltage: 5, Voltage: 3.1, Limiting: true}}
+	dev.supply.statuses = []dwf.SupplyStatus{ok}
+
+	result, _ := s.handleSuppliesStatus(context.Background(), makeReq(nil))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, `"tripped":false`) || strings.Contains(text, `"events"`) {
+		t.Errorf("expected a single untripped read, got %q", text)
+	}
+
+	dev.supply.statuses = []dwf.SupplyStatus{ok, ok, limited}
+	dev.supply.statusCalls = 0
+	result, _ = s.handleSuppliesStatus(context.Background(), makeReq(map[string]any{"monitor": 0.3, "interval": 0.05}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Tripped bool
+		Events  []struct {
+			Time  float64
+			Fault string
+		}
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Tripped || len(got.Events) != 1 || got.Events[0].Fault != "V+ current limiting" || got.Events[0].Time <= 0 {
+		t.Errorf("expected one current limiting event after the start, got %+v", got)
+	}
+
+	for _, args := range []map[string]any{{"monitor": -1.0}, {"monitor": 1.0, "interval": 0.0}} {
+		if result, _ := s.handleSuppliesStatus(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestHandleSuppliesClose(t *testing.T) {
+	s, _ := newTestServer()
+	result, err := s.handleSuppliesClose(context.Background(), makeReq(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "reset") {
+		t.Errorf("expected 'reset', got %q", text)
+	}
+}
+
+// ============================= DMM Handlers =============================
+
+func TestHandleDMMOpen(t *testing.T) {
+	s, _ := newTestServer()
+	result, err := s.handleDMMOpen(context.Background(), makeReq(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "initialized") {
+		t.Errorf("expected 'initialized', got %q", text)
+	}
+}
+
+func TestHandleDMMMeasure(t *testing.T) {
+	s, dev := newTestServer()
+	dev.dmm.measureVal = 12.345678
+	result, err := s.handleDMMMeasure(context.Background(), makeReq(map[string]any{
+		"mode":           float64(1),
+		"range":          0.0,
+		"high_impedance": true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "12.345678") {
+		t.Errorf("expected measurement value, got %q", text)
+	}
+}
+
+func TestHandleDMMMeasureResolvedRange(t *testing.T) {
+	s, dev := newTestServer()
+	dev.dmm.measureVal = 0.0123
+	dev.dmm.resolved = dwf.DMMReading{Range: 0.1, Units: "A"}
+	result, _ := s.handleDMMMeasure(context.Background(), makeReq(map[string]any{
+		"mode":  float64(dwf.DMMModeDCCurrent),
+		"range": 0.0,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["value"] != 0.0123 || got["range"] != 0.1 || got["unit"] != "A" || got["mode_name"] != "dc_current" {
+		t.Errorf("unexpected result: %v", got)
+	}
+
+	if _, ok := got["aux"]; ok {
+		t.Errorf("unexpected aux readings: %v", got["aux"])
+	}
+
+	// Without device units the units of the mode are reported.
+	dev.dmm.resolved = dwf.DMMReading{}
+	result, _ = s.handleDMMMeasure(context.Background(), makeReq(map[string]any{"mode": float64(dwf.DMMModeResistance)}))
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"unit":"Ω"`) {
+		t.Errorf("expected Ω units, got %q", text)
+	}
+}
+
+func TestHandleDMMMeasureAux(t *testing.T) {
+	s, dev := newTestServer()
+	dev.dmm.measureVal = 23.5
+	dev.dmm.resolved = dwf.DMMReading{Units: "°C", Aux: []dwf.DMMAuxReading{{Name: "Raw", Value: 0.00094, Units: "V"}}}
+	result, _ := s.handleDMMMeasure(context.Background(), makeReq(map[string]any{"mode": float64(dwf.DMMModeTemperature)}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Value float64 `json:"value"`
+		Unit  string  `json:"unit"`
+		Aux   []struct {
+			Name  string  `json:"name"`
+			Value float64 `json:"value"`
+			Unit  string  `json:"unit"`
+		} `json:"aux"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Value != 23.5 || got.Unit != "°C" || len(got.Aux) != 1 || got.Aux[0].Name != "Raw" || got.Aux[0].Value != 0.00094 || got.Aux[0].Unit != "V" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+}
+
+func TestHandleDMMMeasureSamples(t *testing.T) {
+	s, dev := newTestServer()
+	dev.dmm.measureVals = []float64{1, 2, 3, 4}
+	result, _ := s.handleDMMMeasure(context.Background(), makeReq(map[string]any{
+		"mode":    float64(dwf.DMMModeDCVoltage),
+		"samples": float64(4),
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if dev.dmm.measureCalls != 4 {
+		t.Errorf("expected 4 readings, got %d", dev.dmm.measureCalls)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["count"] != 4.0 || got["mean"] != 2.5 || got["min"] != 1.0 || got["max"] != 4.0 || got["unit"] != "V" {
+		t.Errorf("unexpected statistics: %v", got)
+	}
+	if sd := got["stddev"].(float64); math.Abs(sd-math.Sqrt(5.0/3)) > 1e-12 {
+		t.Errorf("stddev = %g, want %g", sd, math.Sqrt(5.0/3))
+	}
+
+	result, _ = s.handleDMMMeasure(context.Background(), makeReq(map[string]any{"mode": float64(1), "samples": float64(0)}))
+	if !result.IsError {
+		t.Error("expected error for samples 0")
+	}
+	for _, args := range []map[string]any{
+		{"mode": float64(12)},
+		{"mode": float64(-1)},
+		{"mode": float64(dwf.DMMModeDCHighCurrent), "range": -10.0},
+	} {
+		dev.dmm.measureCalls = 0
+		result, _ = s.handleDMMMeasure(context.Background(), makeReq(args))
+		if !result.IsError || dev.dmm.measureCalls != 0 {
+			t.Errorf("%v: expected error before measuring", args)
+		}
+	}
+}
+
+func TestHandleDMMClose(t *testing.T) {
 	s, _ := newTestServer()
-	result, err := s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{
-		"channel":   float64(1),
-		"function":  float64(1),
-		"frequency": 1000.0,
-		"amplitude": 2.5,
-	}))
+	result, err := s.handleDMMClose(context.Background(), makeReq(nil))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "channel 1") {
-		t.Errorf("expected 'channel 1', got %q", text)
+	if !strings.Contains(text, "reset") {
+		t.Errorf("expected 'reset', got %q", text)
 	}
 }
 
-func TestHandleWavegenEnable(t *testing.T) {
+// ============================= Logic Handlers =============================
+
+func TestHandleLogicOpen(t *testing.T) {
 	s, _ := newTestServer()
-	result, err := s.handleWavegenEnable(context.Background(), makeReq(map[string]any{
-		"channel": float64(2),
+	result, err := s.handleLogicOpen(context.Background(), makeReq(map[string]any{
+		"sampling_frequency": 100e6,
 	}))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "enabled") {
-		t.Errorf("expected 'enabled', got %q", text)
+	if !strings.Contains(text, "initialized") {
+		t.Errorf("expected 'initialized', got %q", text)
 	}
 }
 
-func TestHandleWavegenDisable(t *testing.T) {
-	s, _ := newTestServer()
-	result, err := s.handleWavegenDisable(context.Background(), makeReq(map[string]any{
-		"channel": float64(1),
+func TestHandleLogicOpenThresholdAndChannels(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handleLogicOpen(context.Background(), makeReq(map[string]any{
+		"threshold": 1.5,
+		"channels":  []any{float64(8), float64(9)},
 	}))
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if cfg := dev.logic.openCfg; cfg.Threshold != 1.5 || !slices.Equal(cfg.Channels, []int{8, 9}) {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "1=DIO9") {
+		t.Errorf("expected the channel mapping in %q", text)
+	}
+
+	result, _ = s.handleLogicOpen(context.Background(), makeReq(map[string]any{"channels": []any{1.5}}))
+	if !result.IsError {
+		t.Error("expected error for a non-integer channel")
+	}
+
+	result, _ = s.handleLogicOpen(context.Background(), makeReq(map[string]any{"channels": []any{float64(24), float64(39)}, "sample_bits": float64(32)}))
+	if result.IsError || dev.logic.openCfg.SampleBits != 32 {
+		t.Errorf("expected 32-bit samples, got %+v", dev.logic.openCfg)
+	}
+	result, _ = s.handleLogicOpen(context.Background(), makeReq(map[string]any{"sample_bits": float64(12)}))
+	if !result.IsError {
+		t.Error("expected error for 12-bit samples")
+	}
+}
+
+func TestHandleLogicOpenSync(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handleLogicOpen(context.Background(), makeReq(map[string]any{"clock_channel": float64(1)}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if cfg := dev.logic.openCfg; !cfg.Sync || cfg.ClockChannel != 1 || cfg.ClockEdge != dwf.TriggerSlopeEither {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "either edges of channel 1") {
+		t.Errorf("expected the sample clock in %q", text)
+	}
+	result, _ = s.handleLogicOpen(context.Background(), makeReq(map[string]any{"clock_channel": float64(1), "clock_edge": "up"}))
+	if !result.IsError {
+		t.Error("expected error for an invalid clock edge")
+	}
+
+	// One sample per SCK edge still decodes, without times.
+	var samples []uint32
+	for i, v := range spiCapture([]byte{0x9F}, []byte{0xFF}) {
+		if i%2 == 0 {
+			samples = append(samples, v)
+		}
+	}
+	dev.logic.captureData = dwf.LogicCapture{Sync: true, Samples: samples}
+	result, _ = s.handleSPIMonitor(context.Background(), makeReq(map[string]any{"sck": float64(0), "mosi": float64(1), "cs": float64(3)}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
 	}
 	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "disabled") {
-		t.Errorf("expected 'disabled', got %q", text)
+	if !strings.Contains(text, `"mosi":"9f"`) || strings.Contains(text, `"start"`) {
+		t.Errorf("unexpected result %s", text)
+	}
+	result, _ = s.handleLogicMeasure(context.Background(), makeReq(map[string]any{"channel": float64(0)}))
+	if !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "no time base") {
+		t.Errorf("expected a time base error, got %v", result.Content)
 	}
 }
 
-func TestHandleWavegenClose(t *testing.T) {
+func TestHandleLogicGetConfig(t *testing.T) {
+	s, dev := newTestServer()
+	dev.logic.readback = dwf.LogicReadback{InternalClock: 100e6, Divider: 3, SampleRate: 100e6 / 3, BufferSize: 4096, SampleBits: 16, Channels: []int{8, 9}}
+
+	result, _ := s.handleLogicOpen(context.Background(), makeReq(map[string]any{"sampling_frequency": 30e6}))
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "sampling at 3.33333e+07 Hz (requested 3e+07 Hz)") {
+		t.Errorf("expected the achieved rate in %q", text)
+	}
+
+	result, _ = s.handleLogicGetConfig(context.Background(), makeReq(nil))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["divider"] != 3.0 || got["buffer_size"] != 4096.0 || got["sample_bits"] != 16.0 || got["sample_rate"] != 100e6/3 {
+		t.Errorf("unexpected config: %v", got)
+	}
+	if bt := got["buffer_time"].(float64); math.Abs(bt-4096*3/100e6) > 1e-15 {
+		t.Errorf("buffer_time = %g", bt)
+	}
+}
+
+func TestHandleLogicTrigger(t *testing.T) {
 	s, _ := newTestServer()
-	result, err := s.handleWavegenClose(context.Background(), makeReq(map[string]any{
-		"channel": float64(1),
+	result, err := s.handleLogicTrigger(context.Background(), makeReq(map[string]any{
+		"enable":      true,
+		"channel":     float64(0),
+		"rising_edge": true,
 	}))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "reset") {
-		t.Errorf("expected 'reset', got %q", text)
+	if !strings.Contains(text, "configured") {
+		t.Errorf("expected 'configured', got %q", text)
 	}
 }
 
-// ============================= Supply Handlers =============================
+func TestHandleLogicTriggerPattern(t *testing.T) {
+	s, dev := newTestServer()
+	for _, tc := range []struct {
+		args        map[string]any
+		mask, value uint32
+	}{
+		{map[string]any{"pattern": "0xA5"}, 0xFF, 0xA5},
+		{map[string]any{"pattern": "0b1010_0000", "mask": "0xF0"}, 0xF0, 0xA0},
+		{map[string]any{"pattern": float64(0x1234)}, 0xFFFF, 0x1234},
+		{map[string]any{"pattern": "0", "mask": float64(1)}, 1, 0},
+	} {
+		result, _ := s.handleLogicTrigger(context.Background(), makeReq(tc.args))
+		if result.IsError {
+			t.Errorf("%v: unexpected error: %v", tc.args, result.Content)
+			continue
+		}
+		if cfg := dev.logic.triggerCfg; cfg.Mask != tc.mask || cfg.Value != tc.value {
+			t.Errorf("%v: mask %#x value %#x, want %#x %#x", tc.args, cfg.Mask, cfg.Value, tc.mask, tc.value)
+		}
+	}
+	for _, args := range []map[string]any{
+		{"pattern": "0xZZ"},
+		{"pattern": -1.0},
+		{"pattern": "0x1", "mask": "0"},
+		{"pattern": "0xA5", "mask": "0xF0"},
+		{"mask": "0xFF"},
+	} {
+		if result, _ := s.handleLogicTrigger(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
 
-func TestHandleSuppliesSwitch(t *testing.T) {
-	s, _ := newTestServer()
-	result, err := s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{
-		"master_state":     true,
-		"positive_state":   true,
-		"positive_voltage": 5.0,
+func TestHandleLogicTriggerGlitch(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handleLogicTrigger(context.Background(), makeReq(map[string]any{
+		"mode":      "glitch",
+		"channel":   float64(3),
+		"polarity":  "negative",
+		"max_width": 50e-9,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if cfg := dev.logic.triggerCfg; cfg.Channel != 3 || cfg.RisingEdge || cfg.LengthMin != 0 || cfg.LengthMax != 50e-9 || cfg.Mask != 0 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "negative pulses on channel 3") {
+		t.Errorf("unexpected message %q", text)
+	}
+	for _, args := range []map[string]any{
+		{"mode": "glitch"},
+		{"mode": "glitch", "max_width": 1e-8, "min_width": 2e-8},
+		{"mode": "glitch", "max_width": 1e-8, "polarity": "up"},
+		{"mode": "glitch", "max_width": 1e-8, "pattern": "0x1"},
+		{"mode": "pattern"},
+		{"mode": "runt"},
+	} {
+		if result, _ := s.handleLogicTrigger(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestHandleLogicTriggerProtocol(t *testing.T) {
+	s, dev := newTestServer()
+	for _, tc := range []struct {
+		args    map[string]any
+		channel int
+		rising  bool
+		mask    uint32
+	}{
+		{map[string]any{"mode": "i2c_start", "sda": float64(0), "scl": float64(1)}, 0, false, 2},
+		{map[string]any{"mode": "i2c_stop", "sda": float64(4), "scl": float64(5)}, 4, true, 1 << 5},
+		{map[string]any{"mode": "uart_start", "rx": float64(7)}, 7, false, 0},
+		{map[string]any{"mode": "spi_select", "cs": float64(3)}, 3, false, 0},
+	} {
+		result, _ := s.handleLogicTrigger(context.Background(), makeReq(tc.args))
+		if result.IsError {
+			t.Errorf("%v: unexpected error: %v", tc.args, result.Content)
+			continue
+		}
+		cfg := dev.logic.triggerCfg
+		if cfg.Channel != tc.channel || cfg.RisingEdge != tc.rising || cfg.Mask != tc.mask || cfg.Value != tc.mask || cfg.Qualified != (tc.mask != 0) {
+			t.Errorf("%v: unexpected config %+v", tc.args, cfg)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "next") {
+			t.Errorf("%v: unexpected message %q", tc.args, text)
+		}
+	}
+	for _, args := range []map[string]any{
+		{"mode": "i2c_start", "sda": float64(0)},
+		{"mode": "i2c_start", "sda": float64(1), "scl": float64(1)},
+		{"mode": "uart_start"},
+		{"mode": "spi_select", "cs": float64(40)},
+		{"mode": "spi_select", "cs": float64(3), "pattern": "0x1"},
+	} {
+		if result, _ := s.handleLogicTrigger(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestHandleLogicRecord(t *testing.T) {
+	s, dev := newTestServer()
+	dev.logic.recordData = []uint16{0, 1, 0, 1}
+	result, err := s.handleLogicRecord(context.Background(), makeReq(map[string]any{
+		"channel": float64(0),
 	}))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "configured") {
-		t.Errorf("expected 'configured', got %q", text)
+	if !strings.Contains(text, `"samples":4`) {
+		t.Errorf("expected samples count, got %q", text)
 	}
 }
 
-func TestHandleSuppliesClose(t *testing.T) {
+func TestHandleLogicRecordTiming(t *testing.T) {
+	s, dev := newTestServer()
+	dev.logic.recordData = []uint16{0, 1, 0, 1}
+	dev.logic.timing = dwf.AcquisitionTiming{SampleRate: 1e6, TriggerIndex: 2, Time: time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC)}
+	result, _ := s.handleLogicRecord(context.Background(), makeReq(map[string]any{"channel": float64(0)}))
+	var got struct {
+		SamplePeriod float64 `json:"sample_period"`
+		Duration     float64 `json:"duration"`
+		TriggerIndex int     `json:"trigger_index"`
+		Timestamp    string  `json:"timestamp"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SamplePeriod != 1e-6 || got.Duration != 4e-6 || got.TriggerIndex != 2 || got.Timestamp != "2026-01-02T03:04:05.000006Z" {
+		t.Errorf("unexpected time axis %+v", got)
+	}
+}
+
+func TestHandleLogicRecordRLE(t *testing.T) {
+	s, dev := newTestServer()
+	dev.logic.recordData = []uint16{0, 0, 0, 1, 1, 0}
+	result, _ := s.handleLogicRecord(context.Background(), makeReq(map[string]any{"channel": float64(0), "rle": true}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Samples int      `json:"samples"`
+		Runs    [][2]int `json:"runs"`
+		Data    []int    `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]int{{0, 3}, {1, 2}, {0, 1}}
+	if got.Samples != 6 || !slices.Equal(got.Runs, want) || got.Data != nil {
+		t.Errorf("got %+v, want runs %v", got, want)
+	}
+
+	result, _ = s.handleLogicRecord(context.Background(), makeReq(map[string]any{"channel": float64(0), "rle": true, "encoding": "base64_i16"}))
+	if !result.IsError {
+		t.Error("expected error for rle with a base64 encoding")
+	}
+}
+
+func TestHandleLogicDelta(t *testing.T) {
+	// DIO0 rises at sample 2, DIO1 rises at sample 5.
+	samples := []uint32{0, 0, 1, 1, 1, 3, 3, 2}
+
+	t.Run("success", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.logic.captureData = dwf.LogicCapture{SampleRate: 1e6, Samples: samples}
+		result, err := s.handleLogicDelta(context.Background(), makeReq(map[string]any{
+			"from_channel": float64(0),
+			"to_channel":   float64(1),
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, `"from_sample":2`) || !strings.Contains(text, `"to_sample":5`) {
+			t.Errorf("expected edges at 2 and 5, got %q", text)
+		}
+		if !strings.Contains(text, `"delay":0.000003`) || !strings.Contains(text, `"uncertainty":0.000001`) {
+			t.Errorf("expected 3us delay with 1us uncertainty, got %q", text)
+		}
+	})
+
+	t.Run("falling edge", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.logic.captureData = dwf.LogicCapture{SampleRate: 1e6, Samples: samples}
+		result, _ := s.handleLogicDelta(context.Background(), makeReq(map[string]any{
+			"from_channel": float64(1),
+			"to_channel":   float64(0),
+			"to_edge":      "falling",
+		}))
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, `"to_sample":7`) {
+			t.Errorf("expected falling edge at 7, got %q", text)
+		}
+	})
+
+	t.Run("same line", func(t *testing.T) {
+		s, dev := newTestServer()
+		// DIO0 rises at samples 1 and 4.
+		dev.logic.captureData = dwf.LogicCapture{SampleRate: 1e6, Samples: []uint32{0, 1, 1, 0, 1}}
+		result, _ := s.handleLogicDelta(context.Background(), makeReq(map[string]any{
+			"from_channel": float64(0),
+			"to_channel":   float64(0),
+		}))
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, `"from_sample":1`) || !strings.Contains(text, `"to_sample":4`) {
+			t.Errorf("expected the period from 1 to 4, got %q", text)
+		}
+	})
+
+	t.Run("channel out of range", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.logic.captureData = dwf.LogicCapture{SampleRate: 1e6, Samples: samples}
+		for _, args := range []map[string]any{
+			{"from_channel": float64(32), "to_channel": float64(1)},
+			{"from_channel": float64(0), "to_channel": float64(-1)},
+		} {
+			if result, _ := s.handleLogicDelta(context.Background(), makeReq(args)); !result.IsError {
+				t.Errorf("%v: expected error result", args)
+			}
+		}
+	})
+
+	t.Run("no edge", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.logic.captureData = dwf.LogicCapture{SampleRate: 1e6, Samples: samples}
+		result, _ := s.handleLogicDelta(context.Background(), makeReq(map[string]any{
+			"from_channel": float64(2),
+			"to_channel":   float64(1),
+		}))
+		if !result.IsError {
+			t.Error("expected error result when the start edge is missing")
+		}
+	})
+
+	t.Run("invalid edge", func(t *testing.T) {
+		s, _ := newTestServer()
+		result, _ := s.handleLogicDelta(context.Background(), makeReq(map[string]any{
+			"from_channel": float64(0),
+			"to_channel":   float64(1),
+			"from_edge":    "up",
+		}))
+		if !result.IsError {
+			t.Error("expected error result for invalid edge name")
+		}
+	})
+}
+
+func TestHandleLogicClose(t *testing.T) {
 	s, _ := newTestServer()
-	result, err := s.handleSuppliesClose(context.Background(), makeReq(nil))
+	result, err := s.handleLogicClose(context.Background(), makeReq(nil))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -739,123 +3115,371 @@ func TestHandleSuppliesClose(t *testing.T) {
 	}
 }
 
-// ============================= DMM Handlers =============================
+// ============================= Pattern Handlers =============================
 
-func TestHandleDMMOpen(t *testing.T) {
+func TestHandlePatternGenerate(t *testing.T) {
 	s, _ := newTestServer()
-	result, err := s.handleDMMOpen(context.Background(), makeReq(nil))
+	result, err := s.handlePatternGenerate(context.Background(), makeReq(map[string]any{
+		"channel":   float64(0),
+		"function":  float64(0),
+		"frequency": 1000.0,
+	}))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "initialized") {
-		t.Errorf("expected 'initialized', got %q", text)
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "DIO 0") {
+		t.Errorf("expected 'DIO 0', got %q", text)
+	}
+}
+
+func TestHandlePatternGenerateOutput(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handlePatternGenerate(context.Background(), makeReq(map[string]any{
+		"channel":   float64(2),
+		"function":  float64(1),
+		"frequency": 1000.0,
+		"data":      "1101",
+		"output":    "open-drain",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if dev.pattern.generateCfg.Output != dwf.DigitalOutOutputOpenDrain {
+		t.Errorf("output = %d, want open-drain", dev.pattern.generateCfg.Output)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"output":"open-drain"`) {
+		t.Errorf("unexpected result %s", text)
+	}
+
+	result, _ = s.handleDIOToggle(context.Background(), makeReq(map[string]any{"channel": float64(2), "frequency": 1.0, "output": "open-source"}))
+	if result.IsError || dev.pattern.toggleCfg.Output != dwf.DigitalOutOutputOpenSource {
+		t.Errorf("toggle: %v with output %d", result.Content, dev.pattern.toggleCfg.Output)
+	}
+
+	for _, args := range []map[string]any{
+		{"channel": float64(2), "function": float64(0), "output": "tristate"},
+		{"channel": float64(2), "function": float64(0), "idle_state": "floating"},
+		{"channel": float64(2), "function": float64(0), "trigger_source": float64(15)},
+		{"channel": float64(2), "function": float64(0), "trigger_edge_rising": false},
+	} {
+		if result, _ := s.handlePatternGenerate(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestHandlePatternGenerateIdleTrigger(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handlePatternGenerate(context.Background(), makeReq(map[string]any{
+		"channel":             float64(1),
+		"function":            float64(0),
+		"frequency":           1000.0,
+		"idle_state":          "z",
+		"trigger_source":      float64(11),
+		"trigger_edge_rising": false,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	cfg := dev.pattern.generateCfg
+	if cfg.IdleState != dwf.DigitalOutIdleZet || !cfg.TriggerEnabled || cfg.TriggerSource != dwf.TrigSrcExternal1 || cfg.TriggerEdgeRising {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"idle":"z"`) {
+		t.Errorf("unexpected result %s", text)
+	}
+}
+
+func TestHandlePatternGenerateData(t *testing.T) {
+	s, dev := newTestServer()
+	want := []uint16{1, 0, 1, 1, 0, 0, 1, 0}
+	for _, args := range []map[string]any{
+		{"data": "1011 0010"},
+		{"data": "0xB2", "data_format": "hex"},
+		{"data": "sg==", "data_format": "base64"},
+	} {
+		args["channel"], args["function"], args["frequency"] = float64(2), float64(1), 1000.0
+		result, _ := s.handlePatternGenerate(context.Background(), makeReq(args))
+		if result.IsError {
+			t.Fatalf("%v: unexpected error: %v", args, result.Content)
+		}
+		if got := dev.pattern.generateCfg.Data; !slices.Equal(got, want) {
+			t.Errorf("%v: data = %v, want %v", args, got, want)
+		}
+	}
+
+	for _, args := range []map[string]any{
+		{"function": float64(1)},
+		{"function": float64(1), "data": "102"},
+		{"function": float64(1), "data": "xyz", "data_format": "hex"},
+		{"function": float64(1), "data": "10", "data_format": "octal"},
+		{"function": float64(0), "data": "10"},
+	} {
+		args["channel"], args["frequency"] = float64(2), 1000.0
+		if result, _ := s.handlePatternGenerate(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestHandlePatternGeneratePRBS(t *testing.T) {
+	s, dev := newTestServer()
+	dev.pattern.maxData = 1024
+	args := map[string]any{"channel": float64(0), "function": float64(2), "frequency": 1e6, "prbs": float64(7), "seed": "0x5A"}
+	result, _ := s.handlePatternGenerate(context.Background(), makeReq(args))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	cfg := dev.pattern.generateCfg
+	want, _ := dwf.PRBS(7, 0x5A, 127)
+	if cfg.Function != dwf.DigitalOutTypeCustom || !slices.Equal(cfg.Data, want) {
+		t.Errorf("expected one PRBS7 period as a custom pattern, got %v %v", cfg.Function, cfg.Data)
+	}
+	// A maximal-length sequence has one more 1 than 0 per period.
+	ones := 0
+	for _, b := range cfg.Data {
+		ones += int(b)
+	}
+	if ones != 64 {
+		t.Errorf("PRBS7 period has %d ones, want 64", ones)
+	}
+	result, _ = s.handlePatternGenerate(context.Background(), makeReq(args))
+	if !slices.Equal(dev.pattern.generateCfg.Data, want) {
+		t.Error("the same seed gave a different sequence")
+	}
+
+	args["prbs"] = float64(15)
+	result, _ = s.handlePatternGenerate(context.Background(), makeReq(args))
+	if len(dev.pattern.generateCfg.Data) != 1024 || !strings.Contains(result.Content[0].(mcp.TextContent).Text, `"truncated":true`) {
+		t.Errorf("expected PRBS15 truncated to the buffer, got %d bits", len(dev.pattern.generateCfg.Data))
+	}
+
+	for _, bad := range []map[string]any{
+		{"function": float64(2), "prbs": float64(8)},
+		{"function": float64(0), "prbs": float64(7)},
+		{"function": float64(2), "prbs": float64(7), "seed": "0x80"},
+		{"function": float64(2), "prbs": float64(7), "length": float64(2000)},
+		{"function": float64(2), "seed": float64(1)},
+	} {
+		bad["channel"], bad["frequency"] = float64(0), 1e6
+		if result, _ := s.handlePatternGenerate(context.Background(), makeReq(bad)); !result.IsError {
+			t.Errorf("%v: expected error", bad)
+		}
+	}
+}
+
+func TestHandlePatternClock(t *testing.T) {
+	s, dev := newTestServer()
+	dev.pattern.clocks = dwf.ClockSettings{Clocks: []dwf.ClockOutput{
+		{Channel: 0, Frequency: 1e6, DutyCycle: 50, Divider: 1},
+		{Channel: 1, Frequency: 1e6, DutyCycle: 50, Phase: 90, Divider: 1},
+	}, Pattern: dwf.PatternSettings{Channels: []int{0, 1}}}
+	result, _ := s.handlePatternClock(context.Background(), makeReq(map[string]any{"clocks": []any{
+		map[string]any{"channel": float64(0), "frequency": 1e6},
+		map[string]any{"channel": float64(1), "frequency": 1e6, "phase": 90.0},
+	}}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	want := []dwf.ClockConfig{{Channel: 0, Frequency: 1e6, DutyCycle: 50}, {Channel: 1, Frequency: 1e6, DutyCycle: 50, Phase: 90}}
+	if !slices.Equal(dev.pattern.clockCfgs, want) {
+		t.Errorf("clocks = %+v, want %+v", dev.pattern.clockCfgs, want)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"phase":90`) || !strings.Contains(text, "DIO 0, DIO 1") {
+		t.Errorf("unexpected result %s", text)
+	}
+
+	for _, args := range []map[string]any{
+		{},
+		{"clocks": []any{}},
+		{"clocks": []any{"DIO0"}},
+		{"clocks": []any{map[string]any{"frequency": 1e6}}},
+		{"clocks": []any{map[string]any{"channel": float64(0)}}},
+	} {
+		if result, _ := s.handlePatternClock(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
 	}
 }
 
-func TestHandleDMMMeasure(t *testing.T) {
+func TestHandlePatternROM(t *testing.T) {
 	s, dev := newTestServer()
-	dev.dmm.measureVal = 12.345678
-	result, err := s.handleDMMMeasure(context.Background(), makeReq(map[string]any{
-		"mode":           float64(1),
-		"range":          0.0,
-		"high_impedance": true,
+	result, _ := s.handlePatternROM(context.Background(), makeReq(map[string]any{
+		"inputs":  []any{float64(0), float64(1)},
+		"outputs": []any{float64(2), float64(3)},
+		"table":   []any{float64(0), "0b01", "0b01", "0x2"},
 	}))
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
 	}
-	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "12.345678") {
-		t.Errorf("expected measurement value, got %q", text)
+	if cfg := dev.pattern.romCfg; !slices.Equal(cfg.Table, []uint32{0, 1, 1, 2}) || !slices.Equal(cfg.Outputs, []int{2, 3}) {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"address_lines":8`) {
+		t.Errorf("unexpected result %s", text)
+	}
+
+	for _, args := range []map[string]any{
+		{"outputs": []any{float64(2)}, "table": []any{float64(0), float64(1)}},
+		{"inputs": []any{float64(0)}, "outputs": []any{float64(2)}},
+		{"inputs": []any{float64(0)}, "outputs": []any{float64(2)}, "table": []any{float64(0), float64(2)}},
+		{"inputs": []any{float64(0)}, "outputs": []any{float64(2)}, "table": []any{float64(0), "high"}},
+	} {
+		if result, _ := s.handlePatternROM(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
 	}
 }
 
-func TestHandleDMMClose(t *testing.T) {
-	s, _ := newTestServer()
-	result, err := s.handleDMMClose(context.Background(), makeReq(nil))
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+func TestHandlePatternPlay(t *testing.T) {
+	s, dev := newTestServer()
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "stim.bin")
+	// Two-bit samples 0, 1, 2, 3, least significant first.
+	if err := os.WriteFile(bin, []byte{0b11100100}, 0o644); err != nil {
+		t.Fatal(err)
 	}
-	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "reset") {
-		t.Errorf("expected 'reset', got %q", text)
+	result, _ := s.handlePatternPlay(context.Background(), makeReq(map[string]any{"file": bin, "lines": float64(2), "sample_rate": 1e6}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if cfg := dev.pattern.playCfg; cfg.Lines != 2 || cfg.SampleRate != 1e6 || !slices.Equal(cfg.Data, []uint16{0, 1, 2, 3}) {
+		t.Errorf("unexpected config %+v", cfg)
 	}
-}
 
-// ============================= Logic Handlers =============================
+	text := filepath.Join(dir, "stim.txt")
+	if err := os.WriteFile(text, []byte("0x10, 7\n0b11 255"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, _ = s.handlePatternPlay(context.Background(), makeReq(map[string]any{"file": text, "format": "text", "sample_rate": 1e3}))
+	if result.IsError || !slices.Equal(dev.pattern.playCfg.Data, []uint16{16, 7, 3, 255}) {
+		t.Errorf("text file: %v, data %v", result.Content, dev.pattern.playCfg.Data)
+	}
 
-func TestHandleLogicOpen(t *testing.T) {
-	s, _ := newTestServer()
-	result, err := s.handleLogicOpen(context.Background(), makeReq(map[string]any{
-		"sampling_frequency": 100e6,
-	}))
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	// A stored capture plays at its own sample rate unless one is given.
+	if err := s.storeCapture(context.Background(), "logic-1.json", map[string]interface{}{
+		"sample_rate": 5e5,
+		"data":        []float64{1, 0, 1, 1},
+	}); err != nil {
+		t.Fatal(err)
 	}
-	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "initialized") {
-		t.Errorf("expected 'initialized', got %q", text)
+	result, _ = s.handlePatternPlay(context.Background(), makeReq(map[string]any{"capture": "captures://logic-1.json", "lines": float64(1)}))
+	if result.IsError || dev.pattern.playCfg.SampleRate != 5e5 || !strings.Contains(result.Content[0].(mcp.TextContent).Text, `"samples":4`) {
+		t.Errorf("capture playback: %v at %g Hz", result.Content, dev.pattern.playCfg.SampleRate)
+	}
+
+	for _, args := range []map[string]any{
+		{"file": bin, "lines": float64(3), "sample_rate": 1e6},
+		{"file": bin},
+		{"file": bin, "capture": "logic-1.json", "sample_rate": 1e6},
+		{"sample_rate": 1e6},
+		{"file": text, "format": "text", "lines": float64(4), "sample_rate": 1e6},
+		{"file": filepath.Join(dir, "missing.bin"), "sample_rate": 1e6},
+		{"file": bin, "format": "csv", "sample_rate": 1e6},
+		{"file": bin, "lines": float64(2), "sample_rate": 1e6, "timeout": -1.0},
+		{"capture": "logic-1.json", "lines": float64(1), "array": "time"},
+	} {
+		if result, _ := s.handlePatternPlay(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
 	}
 }
 
-func TestHandleLogicTrigger(t *testing.T) {
-	s, _ := newTestServer()
-	result, err := s.handleLogicTrigger(context.Background(), makeReq(map[string]any{
-		"enable":      true,
-		"channel":     float64(0),
-		"rising_edge": true,
-	}))
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+func TestHandlePatternSetDuty(t *testing.T) {
+	s, dev := newTestServer()
+	dev.pattern.dutySet = dwf.DutySettings{DutyCycle: 25, Frequency: 50}
+	result, _ := s.handlePatternSetDuty(context.Background(), makeReq(map[string]any{"channel": float64(4), "duty_cycle": 24.9}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if dev.pattern.dutyChannel != 4 || dev.pattern.duty != 24.9 {
+		t.Errorf("SetDuty(%d, %g), want SetDuty(4, 24.9)", dev.pattern.dutyChannel, dev.pattern.duty)
 	}
 	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "configured") {
-		t.Errorf("expected 'configured', got %q", text)
+	if !strings.Contains(text, `"duty_cycle":25`) || strings.Contains(text, "warning") {
+		t.Errorf("unexpected result %s", text)
+	}
+
+	dev.pattern.dutySet.Restarted = true
+	result, _ = s.handlePatternSetDuty(context.Background(), makeReq(map[string]any{"channel": float64(4), "duty_cycle": 30.0}))
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "restarted") {
+		t.Errorf("expected a restart warning, got %s", text)
+	}
+
+	for _, args := range []map[string]any{
+		{"channel": float64(4)},
+		{"channel": float64(4), "duty_cycle": 101.0},
+	} {
+		if result, _ := s.handlePatternSetDuty(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
 	}
 }
 
-func TestHandleLogicRecord(t *testing.T) {
+func TestHandlePatternGenerateConflicts(t *testing.T) {
 	s, dev := newTestServer()
-	dev.logic.recordData = []uint16{0, 1, 0, 1}
-	result, err := s.handleLogicRecord(context.Background(), makeReq(map[string]any{
-		"channel": float64(0),
+	dev.pattern.generated = dwf.PatternSettings{
+		Run:       dwf.PatternRun{Repeat: 1, TriggerEnabled: true, TriggerSource: dwf.TrigSrcExternal1, TriggerEdgeRising: true},
+		Channels:  []int{0, 1},
+		Conflicts: []string{"DIO 0: repeat 0 -> 1"},
+	}
+	result, _ := s.handlePatternGenerate(context.Background(), makeReq(map[string]any{
+		"channel":   float64(1),
+		"function":  float64(0),
+		"frequency": 1000.0,
 	}))
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
 	}
-	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, `"samples":4`) {
-		t.Errorf("expected samples count, got %q", text)
+	var got struct {
+		Run       map[string]any `json:"run"`
+		Channels  []int          `json:"channels"`
+		Conflicts []string       `json:"conflicts"`
+		Warning   string         `json:"warning"`
 	}
-}
-
-func TestHandleLogicClose(t *testing.T) {
-	s, _ := newTestServer()
-	result, err := s.handleLogicClose(context.Background(), makeReq(nil))
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
 	}
-	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "reset") {
-		t.Errorf("expected 'reset', got %q", text)
+	if got.Run["repeat"] != float64(1) || got.Run["trigger_source"] != float64(11) || len(got.Channels) != 2 {
+		t.Errorf("unexpected run settings %+v", got)
+	}
+	if len(got.Conflicts) != 1 || got.Warning == "" {
+		t.Errorf("expected conflict warning, got %+v", got)
 	}
 }
 
-// ============================= Pattern Handlers =============================
+func TestHandleDIOToggle(t *testing.T) {
+	s, dev := newTestServer()
+	dev.pattern.toggled = dwf.ToggleSettings{Frequency: 2, Divider: 1000, RunTime: 3}
 
-func TestHandlePatternGenerate(t *testing.T) {
-	s, _ := newTestServer()
-	result, err := s.handlePatternGenerate(context.Background(), makeReq(map[string]any{
-		"channel":   float64(0),
-		"function":  float64(0),
-		"frequency": 1000.0,
+	result, _ := s.handleDIOToggle(context.Background(), makeReq(map[string]any{
+		"channel":   float64(4),
+		"frequency": float64(2),
+		"duration":  1.5,
 	}))
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
 	}
-	text := result.Content[0].(mcp.TextContent).Text
-	if !strings.Contains(text, "DIO 0") {
-		t.Errorf("expected 'DIO 0', got %q", text)
+	if cfg := dev.pattern.toggleCfg; cfg.Channel != 4 || cfg.Frequency != 2 || cfg.Count != 3 {
+		t.Errorf("toggle cfg = %+v, want 3 periods on DIO 4", cfg)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got["count"] != float64(3) || got["duration"] != float64(3) || got["divider"] != float64(1000) {
+		t.Errorf("unexpected result %v", got)
+	}
+
+	for _, args := range []map[string]any{
+		{"channel": float64(0)},
+		{"channel": float64(0), "frequency": float64(1), "count": float64(2), "duration": float64(1)},
+		{"channel": float64(0), "frequency": float64(1), "duration": float64(-1)},
+	} {
+		if result, _ := s.handleDIOToggle(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("expected error for %v", args)
+		}
 	}
 }
 
@@ -887,6 +3511,60 @@ func TestHandlePatternDisable(t *testing.T) {
 	}
 }
 
+func TestHandlePatternStatus(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handlePatternGenerate(context.Background(), makeReq(map[string]any{
+		"channel":        float64(2),
+		"function":       float64(0),
+		"frequency":      float64(1000),
+		"repeat":         float64(5),
+		"trigger_source": float64(11),
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if cfg := dev.pattern.generateCfg; !cfg.TriggerEnabled || cfg.TriggerSource != dwf.TrigSrcExternal1 || !cfg.TriggerEdgeRising {
+		t.Errorf("trigger not configured: %+v", cfg)
+	}
+
+	dev.pattern.status = dwf.PatternStatus{State: "armed", WaitingForTrigger: true, TriggerEnabled: true, Repeat: 5, RepeatsRemaining: 3}
+	result, err := s.handlePatternStatus(context.Background(), makeReq(nil))
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v %v", err, result.Content)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got["state"] != "armed" || got["waiting_for_trigger"] != true || got["repeats_remaining"] != float64(3) {
+		t.Errorf("unexpected status %v", got)
+	}
+
+	dev.pattern.status = dwf.PatternStatus{State: "running", Enabled: []int{2, 5}}
+	result, _ = s.handlePatternStatus(context.Background(), makeReq(nil))
+	text := result.Content[0].(mcp.TextContent).Text
+	if strings.Contains(text, "repeats_remaining") {
+		t.Errorf("infinite repeat should not report remaining count: %s", text)
+	}
+	if !strings.Contains(text, `"enabled":[2,5]`) || strings.Contains(text, `"done"`) {
+		t.Errorf("unexpected status %s", text)
+	}
+
+	// A pattern that does not finish in time is reported as not done.
+	result, _ = s.handlePatternStatus(context.Background(), makeReq(map[string]any{"wait": 0.05}))
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"done":false`) {
+		t.Errorf("expected done false, got %s", text)
+	}
+	dev.pattern.status.State = "done"
+	result, _ = s.handlePatternStatus(context.Background(), makeReq(map[string]any{"wait": 10.0}))
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"done":true`) {
+		t.Errorf("expected done true, got %s", text)
+	}
+	if result, _ := s.handlePatternStatus(context.Background(), makeReq(map[string]any{"wait": -1.0})); !result.IsError {
+		t.Error("expected an error for a negative wait")
+	}
+}
+
 func TestHandlePatternClose(t *testing.T) {
 	s, _ := newTestServer()
 	result, err := s.handlePatternClose(context.Background(), makeReq(nil))
@@ -931,6 +3609,36 @@ func TestHandleStaticSetMode(t *testing.T) {
 			t.Errorf("expected 'input', got %q", text)
 		}
 	})
+
+	t.Run("open-drain", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, _ := s.handleStaticSetMode(context.Background(), makeReq(map[string]any{
+			"channel":     float64(3),
+			"output":      true,
+			"output_type": "open-drain",
+		}))
+		if result.IsError {
+			t.Fatalf("unexpected error: %v", result.Content)
+		}
+		if got := strings.Join(dev.staticIO.calls, ","); got != "output 3 1,mode 3 true" {
+			t.Errorf("calls = %q, want the drive type before the mode", got)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "output (open-drain)") {
+			t.Errorf("expected the drive type in %q", text)
+		}
+	})
+
+	t.Run("invalid output type", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, _ := s.handleStaticSetMode(context.Background(), makeReq(map[string]any{
+			"channel":     float64(3),
+			"output":      true,
+			"output_type": "tristate",
+		}))
+		if !result.IsError || len(dev.staticIO.calls) != 0 {
+			t.Errorf("expected an error before any device call, got %v", dev.staticIO.calls)
+		}
+	})
 }
 
 func TestHandleStaticGetState(t *testing.T) {
@@ -980,6 +3688,45 @@ func TestHandleStaticSetState(t *testing.T) {
 	}
 }
 
+func TestHandleStaticMask(t *testing.T) {
+	s, dev := newTestServer()
+	dev.staticIO.masks = dwf.DigitalIOMasks{Lines: 16, FirstDIO: 24, OutputEnable: 0xF000, Output: 0x1000, Input: 0x1234, OpenDrain: 0x0003}
+
+	result, _ := s.handleStaticReadMask(context.Background(), makeReq(nil))
+	if text := result.Content[0].(mcp.TextContent).Text; result.IsError || !strings.Contains(text, `"input":"0x1234"`) || !strings.Contains(text, `"first_dio":24`) ||
+		!strings.Contains(text, `"open_drain":"0x0003"`) || strings.Contains(text, "open_source") {
+		t.Errorf("unexpected result %s", text)
+	}
+
+	result, _ = s.handleStaticWriteMask(context.Background(), makeReq(map[string]any{"output": "0xA5", "output_enable": "0xFF", "mask": "0x00FF"}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if w := dev.staticIO.write; w.Mask != 0xFF || *w.Output != 0xA5 || *w.OutputEnable != 0xFF {
+		t.Errorf("unexpected write %+v", w)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"output":"0x10A5"`) || !strings.Contains(text, `"output_enable":"0xF0FF"`) {
+		t.Errorf("unexpected result %s", text)
+	}
+
+	// Without a mask all lines are written.
+	result, _ = s.handleStaticWriteMask(context.Background(), makeReq(map[string]any{"output": float64(0)}))
+	if result.IsError || dev.staticIO.write.Mask != 0xFFFF || dev.staticIO.write.OutputEnable != nil {
+		t.Errorf("unexpected write %+v: %v", dev.staticIO.write, result.Content)
+	}
+
+	for _, args := range []map[string]any{
+		{},
+		{"mask": "0xFF"},
+		{"output": "0x100", "mask": "0xFF"},
+		{"output": "high"},
+	} {
+		if result, _ := s.handleStaticWriteMask(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
 func TestHandleStaticClose(t *testing.T) {
 	s, _ := newTestServer()
 	result, err := s.handleStaticClose(context.Background(), makeReq(nil))
@@ -1010,6 +3757,31 @@ func TestHandleUARTOpen(t *testing.T) {
 	}
 }
 
+func TestHandleUARTOpenReceiveOnly(t *testing.T) {
+	s, dev := newTestServer()
+	result, _ := s.handleUARTOpen(context.Background(), makeReq(map[string]any{
+		"rx": float64(3),
+		"tx": float64(-1),
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if dev.uart.openCfg.RX != 3 || dev.uart.openCfg.TX != -1 {
+		t.Errorf("open cfg = %+v", dev.uart.openCfg)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "TX=unused") {
+		t.Errorf("expected unused TX in result, got %q", text)
+	}
+
+	result, _ = s.handleUARTOpen(context.Background(), makeReq(map[string]any{
+		"rx": float64(-1),
+		"tx": float64(-1),
+	}))
+	if !result.IsError {
+		t.Error("expected error with neither rx nor tx")
+	}
+}
+
 func TestHandleUARTRead(t *testing.T) {
 	s, dev := newTestServer()
 	dev.uart.readData = []byte("hello")
@@ -1125,6 +3897,241 @@ func TestHandleSPIClose(t *testing.T) {
 	}
 }
 
+// spiCapture builds logic samples of a mode 0, MSB-first SPI transfer with
+// SCK on DIO0, MOSI on DIO1, MISO on DIO2 and CS on DIO3.
+func spiCapture(mosi, miso []byte) []uint32 {
+	const sck, mosiBit, misoBit, cs = 1, 2, 4, 8
+	samples := []uint32{cs, cs, 0}
+	for i := range mosi {
+		for b := 7; b >= 0; b-- {
+			var data uint32
+			if mosi[i]&(1<<b) != 0 {
+				data |= mosiBit
+			}
+			if miso[i]&(1<<b) != 0 {
+				data |= misoBit
+			}
+			samples = append(samples, data, data, data|sck, data|sck)
+		}
+	}
+	return append(samples, 0, cs, cs)
+}
+
+func TestHandleLogicCount(t *testing.T) {
+	s, dev := newTestServer()
+	// Without a counter, edges are counted in one capture: a 250 kHz clock.
+	dev.logic.captureData = dwf.LogicCapture{SampleRate: 1e6, Samples: []uint32{0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0}}
+	result, _ := s.handleLogicCount(context.Background(), makeReq(map[string]any{"channel": float64(0), "edge": "either"}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["method"] != "software" || got["count"] != 6.0 || got["frequency"] != 250e3 || got["period"] != 4e-6 {
+		t.Errorf("unexpected software count %v", got)
+	}
+
+	dev.logic.counterMax = 10
+	dev.logic.counter = dwf.CounterReading{Count: 1000, Frequency: 1000, GateTime: 1}
+	result, _ = s.handleLogicCount(context.Background(), makeReq(map[string]any{"channel": float64(3), "edge": "falling"}))
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["method"] != "counter" || got["count"] != 1000.0 || got["period"] != 1e-3 {
+		t.Errorf("unexpected counter reading %v", got)
+	}
+	if cfg := dev.logic.counterCfg; cfg.Channel != 3 || cfg.GateTime != 1 || cfg.Edge != dwf.TriggerSlopeFall {
+		t.Errorf("unexpected counter config %+v", cfg)
+	}
+
+	for _, args := range []map[string]any{
+		{"edge": "rising"},
+		{"channel": float64(0), "gate_time": 11.0},
+		{"channel": float64(0), "edge": "up"},
+		{"channel": float64(0), "method": "fast"},
+		{"channel": float64(32)},
+		{"channel": float64(-1)},
+		{"channel": float64(0), "method": "software", "gate_time": 0.5},
+	} {
+		if result, _ := s.handleLogicCount(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestHandleLogicMeasure(t *testing.T) {
+	s, dev := newTestServer()
+	// DIO0 is a clock with a period of 4 samples, high for 1; DIO1 pulses
+	// 2 samples after its second and third rising edges.
+	samples := []uint32{0, 1, 0, 0, 0, 1, 0, 2, 0, 1, 0, 2, 0, 1, 0, 0}
+	dev.logic.captureData = dwf.LogicCapture{SampleRate: 1e6, Samples: samples}
+	result, _ := s.handleLogicMeasure(context.Background(), makeReq(map[string]any{"channel": float64(0)}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		RisingEdges int                `json:"rising_edges"`
+		Frequency   float64            `json:"frequency"`
+		DutyCycle   float64            `json:"duty_cycle"`
+		HighTime    map[string]float64 `json:"high_time"`
+		Period      map[string]float64 `json:"period"`
+		Delay       map[string]any     `json:"delay"`
+		Level       *int               `json:"level"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.RisingEdges != 4 || got.Period["count"] != 3 || got.Period["mean"] != 4e-6 || got.HighTime["max"] != 1e-6 {
+		t.Errorf("unexpected result: %+v", got)
+	}
+	if math.Abs(got.Frequency-250e3) > 1e-6 || got.DutyCycle != 25 || got.Delay != nil || got.Level != nil {
+		t.Errorf("unexpected frequency, duty cycle or delay: %+v", got)
+	}
+
+	result, _ = s.handleLogicMeasure(context.Background(), makeReq(map[string]any{"channel": float64(1), "ref_channel": float64(0)}))
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Delay["count"] != 2.0 || got.Delay["mean"] != 2e-6 {
+		t.Errorf("unexpected delay: %v", got.Delay)
+	}
+
+	// The same edge of the same channel is a period away, not simultaneous.
+	result, _ = s.handleLogicMeasure(context.Background(), makeReq(map[string]any{"channel": float64(0), "ref_channel": float64(0)}))
+	got.Delay = nil
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Delay["count"] != 3.0 || got.Delay["mean"] != 4e-6 {
+		t.Errorf("unexpected same-channel delay: %v", got.Delay)
+	}
+
+	result, _ = s.handleLogicMeasure(context.Background(), makeReq(map[string]any{"channel": float64(2)}))
+	got.Level = nil
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Level == nil || *got.Level != 0 {
+		t.Errorf("expected level 0 for a line without edges, got %+v", got)
+	}
+}
+
+// uartCapture builds logic samples of 8N1 characters on DIO2 at 4 samples
+// per bit, with idle time around each; a nil entry is a break.
+func uartCapture(chars []*byte) []uint32 {
+	const rx = 4
+	var samples []uint32
+	idle := func(n int) {
+		for range n {
+			samples = append(samples, rx)
+		}
+	}
+	bit := func(high bool) {
+		for range 4 {
+			if high {
+				samples = append(samples, rx)
+			} else {
+				samples = append(samples, 0)
+			}
+		}
+	}
+	idle(6)
+	for _, c := range chars {
+		bit(false)
+		for b := range 8 {
+			bit(c != nil && *c&(1<<b) != 0)
+		}
+		bit(c != nil)
+		idle(5)
+	}
+	return samples
+}
+
+func TestHandleLogicDecodeUART(t *testing.T) {
+	s, dev := newTestServer()
+	h, i := byte('H'), byte('i')
+	dev.logic.captureData = dwf.LogicCapture{SampleRate: 4 * 9600, Samples: uartCapture([]*byte{&h, &i, nil})}
+	result, _ := s.handleLogicDecodeUART(context.Background(), makeReq(map[string]any{"rx": float64(2)}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Frames []struct {
+			Time         float64 `json:"time"`
+			Value        int     `json:"value"`
+			FramingError bool    `json:"framing_error"`
+			Break        bool    `json:"break"`
+		} `json:"frames"`
+		Errors int    `json:"errors"`
+		Text   string `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Frames) != 3 || got.Text != "Hi" || got.Errors != 1 {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+	if got.Frames[0].Value != 'H' || got.Frames[0].Time != 6/(4*9600.0) {
+		t.Errorf("first frame = %+v", got.Frames[0])
+	}
+	if f := got.Frames[2]; !f.Break || !f.FramingError {
+		t.Errorf("expected a break, got %+v", f)
+	}
+
+	dev.logic.captureData.SampleRate = 2 * 9600
+	if result, _ := s.handleLogicDecodeUART(context.Background(), makeReq(map[string]any{"rx": float64(2)})); !result.IsError {
+		t.Error("expected error for a sample rate below 4x the baud rate")
+	}
+	if result, _ := s.handleLogicDecodeUART(context.Background(), makeReq(map[string]any{"rx": float64(2), "parity": float64(3)})); !result.IsError {
+		t.Error("expected error for parity 3")
+	}
+}
+
+func TestHandleSPIMonitor(t *testing.T) {
+	s, dev := newTestServer()
+	dev.logic.captureData = dwf.LogicCapture{SampleRate: 1e6, Samples: spiCapture([]byte{0x9F, 0x00}, []byte{0xFF, 0xEF})}
+	result, err := s.handleSPIMonitor(context.Background(), makeReq(map[string]any{
+		"sck":  float64(0),
+		"mosi": float64(1),
+		"miso": float64(2),
+		"cs":   float64(3),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got struct {
+		Transfers []struct {
+			Start      float64 `json:"start"`
+			MOSI       string  `json:"mosi"`
+			MISO       string  `json:"miso"`
+			Bits       int     `json:"bits"`
+			Incomplete bool    `json:"incomplete"`
+		} `json:"transfers"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got.Transfers) != 1 {
+		t.Fatalf("transfers = %+v, want one", got.Transfers)
+	}
+	tr := got.Transfers[0]
+	if tr.MOSI != "9f00" || tr.MISO != "ffef" || tr.Bits != 16 || tr.Incomplete || tr.Start != 2e-6 {
+		t.Errorf("unexpected transfer %+v", tr)
+	}
+
+	for name, args := range map[string]map[string]any{
+		"no data lines": {"sck": float64(0), "cs": float64(3)},
+		"bad mode":      {"sck": float64(0), "mosi": float64(1), "mode": float64(4)},
+	} {
+		result, _ := s.handleSPIMonitor(context.Background(), makeReq(args))
+		if !result.IsError {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
 // ============================= I2C Handlers =============================
 
 func TestHandleI2COpen(t *testing.T) {
@@ -1142,6 +4149,32 @@ func TestHandleI2COpen(t *testing.T) {
 	}
 }
 
+func TestHandleI2CRecover(t *testing.T) {
+	s, dev := newTestServer()
+	dev.i2c.recovery = dwf.I2CRecovery{SDAStuck: true, Pulses: 3}
+	result, err := s.handleI2CRecover(context.Background(), makeReq(map[string]any{
+		"sda":        float64(2),
+		"scl":        float64(3),
+		"clock_rate": float64(10e3),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, `"sda_stuck":true`) || !strings.Contains(text, `"pulses":3`) {
+		t.Errorf("unexpected result %q", text)
+	}
+	if cfg := dev.i2c.recoverCfg; cfg.SDA != 2 || cfg.SCL != 3 || cfg.ClockRate != 10e3 {
+		t.Errorf("recover config = %+v", cfg)
+	}
+
+	dev.i2c.recoverErr = errors.New("SCL is held low by a target")
+	result, _ = s.handleI2CRecover(context.Background(), makeReq(map[string]any{"sda": float64(0), "scl": float64(1)}))
+	if !result.IsError {
+		t.Error("expected error result")
+	}
+}
+
 func TestHandleI2CScan(t *testing.T) {
 	t.Run("found devices", func(t *testing.T) {
 		s, dev := newTestServer()
@@ -1291,6 +4324,13 @@ func TestHandlerErrorPropagation(t *testing.T) {
 				return s.handleLogicRecord
 			},
 		},
+		{
+			name:  "logic capture error",
+			setup: func(d *mockDevice) { d.logic.captureErr = errors.New("logic fail") },
+			handler: func(s *DiscoveryMCPServer) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return s.handleLogicDelta
+			},
+		},
 		{
 			name:  "pattern generate error",
 			setup: func(d *mockDevice) { d.pattern.generateErr = errors.New("pattern fail") },
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// Limits that keep a single macro call from monopolizing the device.
+const (
+	maxMacroOps      = 10000
+	maxMacroDepth    = 4
+	maxMacroWait     = time.Second
+	maxMacroDuration = 10 * time.Second
+)
+
+// macroStep is one parsed static I/O macro operation.
+type macroStep struct {
+	op      string
+	channel int
+	value   bool
+	wait    time.Duration
+	count   int
+	steps   []macroStep
+}
+
+// macroRead records the result of a read operation.
+type macroRead struct {
+	Channel int  `json:"channel"`
+	Value   bool `json:"value"`
+	// At is the time since the macro started, in microseconds.
+	At int64 `json:"at_us"`
+}
+
+// parseMacroSteps converts the raw JSON step list into macro steps.
+func parseMacroSteps(raw any, depth int) ([]macroStep, error) {
+	if depth > maxMacroDepth {
+		return nil, fmt.Errorf("loops nested deeper than %d levels", maxMacroDepth)
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("steps must be an array")
+	}
+	steps := make([]macroStep, 0, len(list))
+	for i, item := range list {
+		args, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("step %d: must be an object", i)
+		}
+		step := macroStep{
+			op:      getString(args, "op", ""),
+			channel: getInt(args, "channel", -1),
+		}
+		switch step.op {
+		case "set", "mode":
+			if step.channel < 0 {
+				return nil, fmt.Errorf("step %d: %s requires a channel", i, step.op)
+			}
+			key := "value"
+			if step.op == "mode" {
+				key = "output"
+			}
+			v, ok := args[key].(bool)
+			if !ok {
+				return nil, fmt.Errorf("step %d: %s requires boolean %q", i, step.op, key)
+			}
+			step.value = v
+		case "read":
+			if step.channel < 0 {
+				return nil, fmt.Errorf("step %d: read requires a channel", i)
+			}
+		case "wait":
+			us := getFloat(args, "us", -1)
+			if us < 0 {
+				return nil, fmt.Errorf("step %d: wait requires a non-negative \"us\"", i)
+			}
+			step.wait = time.Duration(us * float64(time.Microsecond))
+			if step.wait > maxMacroWait {
+				return nil, fmt.Errorf("step %d: wait exceeds %v", i, maxMacroWait)
+			}
+		case "loop":
+			step.count = getInt(args, "count", 0)
+			if step.count < 1 || step.count > maxMacroOps {
+				return nil, fmt.Errorf("step %d: loop count must be between 1 and %d", i, maxMacroOps)
+			}
+			body, err := parseMacroSteps(args["steps"], depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %w", i, err)
+			}
+			step.steps = body
+		default:
+			return nil, fmt.Errorf("step %d: unknown op %q (expected set, mode, read, wait or loop)", i, step.op)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// macroWaitTotal returns the seconds the waits of steps add up to, loops
+// included.
+func macroWaitTotal(steps []macroStep) float64 {
+	total := 0.0
+	for _, step := range steps {
+		switch step.op {
+		case "wait":
+			total += step.wait.Seconds()
+		case "loop":
+			total += float64(step.count) * macroWaitTotal(step.steps)
+		}
+	}
+	return total
+}
+
+// macroRunner executes macro steps against the static I/O instrument.
+type macroRunner struct {
+	ctx   context.Context
+	io    dwf.StaticIO
+	start time.Time
+	// ops counts the I/O and wait operations, steps those and the loop
+	// iterations, so that loops without operations are bounded too.
+	ops, steps int
+	reads      []macroRead
+}
+
+// next accounts for one more step, failing once the macro exceeds its
+// step or time limit or its context ends.
+func (r *macroRunner) next() error {
+	r.steps++
+	if r.steps > maxMacroOps {
+		return fmt.Errorf("macro exceeds %d operations and loop iterations", maxMacroOps)
+	}
+	if time.Since(r.start) > maxMacroDuration {
+		return fmt.Errorf("macro exceeds %v", maxMacroDuration)
+	}
+	return r.ctx.Err()
+}
+
+func (r *macroRunner) run(steps []macroStep) error {
+	for _, step := range steps {
+		if step.op == "loop" {
+			for i := 0; i < step.count; i++ {
+				if err := r.next(); err != nil {
+					return err
+				}
+				if err := r.run(step.steps); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := r.next(); err != nil {
+			return err
+		}
+		r.ops++
+
+		switch step.op {
+		case "set":
+			if err := r.io.SetState(step.channel, step.value); err != nil {
+				return err
+			}
+		case "mode":
+			if err := r.io.SetMode(step.channel, step.value); err != nil {
+				return err
+			}
+		case "read":
+			v, err := r.io.GetState(step.channel)
+			if err != nil {
+				return err
+			}
+			r.reads = append(r.reads, macroRead{
+				Channel: step.channel,
+				Value:   v,
+				At:      time.Since(r.start).Microseconds(),
+			})
+		case "wait":
+			if time.Since(r.start)+step.wait > maxMacroDuration {
+				return fmt.Errorf("macro exceeds %v", maxMacroDuration)
+			}
+			if err := macroWait(r.ctx, step.wait); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// macroWait delays for d, or until ctx ends. Short delays spin, since the
+// scheduler cannot reliably sleep for less than a millisecond.
+func macroWait(ctx context.Context, d time.Duration) error {
+	if d >= time.Millisecond {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	start := time.Now()
+	for time.Since(start) < d {
+	}
+	return nil
+}
+
+func (s *DiscoveryMCPServer) handleStaticMacro(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	steps, err := parseMacroSteps(argsMap(req.Params.Arguments)["steps"], 0)
+	if err != nil {
+		return errResult(fmt.Errorf("invalid macro: %w", err)), nil
+	}
+	if total := macroWaitTotal(steps); total > maxMacroDuration.Seconds() {
+		return errResult(fmt.Errorf("invalid macro: its waits add up to %g s, more than %v", total, maxMacroDuration)), nil
+	}
+
+	r := &macroRunner{ctx: ctx, io: s.device.Static(), start: time.Now()}
+	if err := r.run(steps); err != nil {
+		return errResult(fmt.Errorf("macro stopped after %d operations: %w", r.ops, err)), nil
+	}
+	reads := r.reads
+	if reads == nil {
+		reads = []macroRead{}
+	}
+	return jsonResult(map[string]interface{}{
+		"operations": r.ops,
+		"elapsed_us": time.Since(r.start).Microseconds(),
+		"reads":      reads,
+	}), nil
+}
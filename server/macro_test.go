@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestHandleStaticMacro(t *testing.T) {
+	t.Run("strobe with loop", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.staticIO.getStateVal = true
+		result, err := s.handleStaticMacro(context.Background(), makeReq(map[string]any{
+			"steps": []any{
+				map[string]any{"op": "mode", "channel": float64(0), "output": true},
+				map[string]any{"op": "loop", "count": float64(2), "steps": []any{
+					map[string]any{"op": "set", "channel": float64(0), "value": true},
+					map[string]any{"op": "wait", "us": float64(5)},
+					map[string]any{"op": "set", "channel": float64(0), "value": false},
+				}},
+				map[string]any{"op": "read", "channel": float64(3)},
+			},
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("unexpected error result: %v", result.Content)
+		}
+		want := "mode 0 true,set 0 true,set 0 false,set 0 true,set 0 false,get 3"
+		if got := strings.Join(dev.staticIO.calls, ","); got != want {
+			t.Errorf("calls = %q, want %q", got, want)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, `"operations":8`) {
+			t.Errorf("expected 8 operations, got %q", text)
+		}
+		if !strings.Contains(text, `"channel":3,"value":true`) {
+			t.Errorf("expected read result, got %q", text)
+		}
+	})
+
+	t.Run("invalid step", func(t *testing.T) {
+		tests := []struct {
+			name string
+			step map[string]any
+		}{
+			{"unknown op", map[string]any{"op": "toggle", "channel": float64(0)}},
+			{"missing channel", map[string]any{"op": "set", "value": true}},
+			{"missing value", map[string]any{"op": "set", "channel": float64(0)}},
+			{"negative wait", map[string]any{"op": "wait", "us": float64(-1)}},
+			{"long wait", map[string]any{"op": "wait", "us": float64(5e6)}},
+			{"empty loop", map[string]any{"op": "loop", "count": float64(0), "steps": []any{}}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s, dev := newTestServer()
+				result, _ := s.handleStaticMacro(context.Background(), makeReq(map[string]any{
+					"steps": []any{tt.step},
+				}))
+				if !result.IsError {
+					t.Error("expected error result")
+				}
+				if len(dev.staticIO.calls) != 0 {
+					t.Errorf("expected no I/O before validation passes, got %v", dev.staticIO.calls)
+				}
+			})
+		}
+	})
+
+	t.Run("operation limit", func(t *testing.T) {
+		s, _ := newTestServer()
+		result, _ := s.handleStaticMacro(context.Background(), makeReq(map[string]any{
+			"steps": []any{
+				map[string]any{"op": "loop", "count": float64(maxMacroOps + 1), "steps": []any{
+					map[string]any{"op": "set", "channel": float64(0), "value": true},
+				}},
+			},
+		}))
+		if !result.IsError {
+			t.Error("expected error result when exceeding the operation limit")
+		}
+	})
+
+	t.Run("device error", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.staticIO.setStateErr = errors.New("static fail")
+		result, _ := s.handleStaticMacro(context.Background(), makeReq(map[string]any{
+			"steps": []any{
+				map[string]any{"op": "set", "channel": float64(0), "value": true},
+				map[string]any{"op": "read", "channel": float64(0)},
+			},
+		}))
+		if !result.IsError {
+			t.Error("expected error result")
+		}
+		if len(dev.staticIO.calls) != 1 {
+			t.Errorf("expected macro to stop at the failing step, got %v", dev.staticIO.calls)
+		}
+	})
+}
+
+func TestStaticMacroLimits(t *testing.T) {
+	// Loops without operations count against the limit too.
+	s, _ := newTestServer()
+	loop := map[string]any{"op": "loop", "count": float64(maxMacroOps), "steps": []any{}}
+	for i := 1; i < maxMacroDepth; i++ {
+		loop = map[string]any{"op": "loop", "count": float64(maxMacroOps), "steps": []any{loop}}
+	}
+	result, _ := s.handleStaticMacro(context.Background(), makeReq(map[string]any{"steps": []any{loop}}))
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "loop iterations") {
+		t.Errorf("expected the step limit, got %q", text)
+	}
+
+	result, _ = s.handleStaticMacro(context.Background(), makeReq(map[string]any{"steps": []any{
+		map[string]any{"op": "loop", "count": float64(maxMacroOps + 1), "steps": []any{}},
+	}}))
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "invalid macro") {
+		t.Errorf("expected a rejected loop count, got %q", text)
+	}
+
+	// A wait ends with the context.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, _ = s.handleStaticMacro(ctx, makeReq(map[string]any{"steps": []any{
+		map[string]any{"op": "wait", "us": float64(1e6)},
+	}}))
+	if !result.IsError || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected the cancelled wait to stop early, took %v: %v", time.Since(start), result.Content)
+	}
+
+	// Waits beyond the total duration are refused before sleeping.
+	start = time.Now()
+	result, _ = s.handleStaticMacro(context.Background(), makeReq(map[string]any{"steps": []any{
+		map[string]any{"op": "loop", "count": float64(20), "steps": []any{map[string]any{"op": "wait", "us": float64(1e6)}}},
+	}}))
+	if text := result.Content[0].(mcp.TextContent).Text; !result.IsError || !strings.Contains(text, "add up to 20 s") || time.Since(start) > time.Second {
+		t.Errorf("expected the duration limit, got %q", text)
+	}
+}
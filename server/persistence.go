@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// persistenceRamp draws the share of acquisitions that hit a cell of a
+// persistence map, from none to all.
+const persistenceRamp = " .:-=+*#%@"
+
+// maxPersistenceReport caps the rare cells and anomalous acquisitions
+// listed in a persistence result.
+const maxPersistenceReport = 20
+
+// persistenceMap accumulates acquisitions of one channel in a grid of time
+// bins by amplitude bins, counting for each cell how many acquisitions
+// passed through it.
+type persistenceMap struct {
+	timeBins, ampBins int
+	vMin, vMax        float64
+
+	// hits counts the acquisitions per cell, row by row from vMin up.
+	hits []int
+	// histogram counts the samples per amplitude bin.
+	histogram []int
+	// below and above count the samples outside [vMin, vMax].
+	below, above int
+	// touched lists the cells each acquisition passed through.
+	touched [][]int
+	seen    []bool
+}
+
+func newPersistenceMap(timeBins, ampBins int, vMin, vMax float64) *persistenceMap {
+	return &persistenceMap{
+		timeBins:  timeBins,
+		ampBins:   ampBins,
+		vMin:      vMin,
+		vMax:      vMax,
+		hits:      make([]int, timeBins*ampBins),
+		histogram: make([]int, ampBins),
+		seen:      make([]bool, timeBins*ampBins),
+	}
+}
+
+// add accumulates one acquisition, spreading its samples evenly over the
+// time bins.
+func (pm *persistenceMap) add(data []float64) {
+	var cells []int
+	step := (pm.vMax - pm.vMin) / float64(pm.ampBins)
+	for i, v := range data {
+		switch {
+		case math.IsNaN(v):
+			continue
+		case v < pm.vMin:
+			pm.below++
+			continue
+		case v > pm.vMax:
+			pm.above++
+			continue
+		}
+		a := min(int((v-pm.vMin)/step), pm.ampBins-1)
+		pm.histogram[a]++
+		cell := a*pm.timeBins + i*pm.timeBins/len(data)
+		if !pm.seen[cell] {
+			pm.seen[cell] = true
+			pm.hits[cell]++
+			cells = append(cells, cell)
+		}
+	}
+	for _, cell := range cells {
+		pm.seen[cell] = false
+	}
+	pm.touched = append(pm.touched, cells)
+}
+
+// rows draws the map with one string per amplitude bin, highest voltage
+// first, and one character per time bin.
+func (pm *persistenceMap) rows() []string {
+	n := len(pm.touched)
+	out := make([]string, pm.ampBins)
+	var b strings.Builder
+	for a := range pm.ampBins {
+		b.Reset()
+		for t := range pm.timeBins {
+			hits := pm.hits[a*pm.timeBins+t]
+			c := 0
+			if hits > 0 {
+				c = 1 + (hits*(len(persistenceRamp)-2))/n
+			}
+			b.WriteByte(persistenceRamp[c])
+		}
+		out[pm.ampBins-1-a] = b.String()
+	}
+	return out
+}
+
+// center returns the time bin and voltage at the middle of a cell.
+func (pm *persistenceMap) center(cell int) (int, float64) {
+	step := (pm.vMax - pm.vMin) / float64(pm.ampBins)
+	return cell % pm.timeBins, pm.vMin + (float64(cell/pm.timeBins)+0.5)*step
+}
+
+// rare reports the cells hit by no more than limit acquisitions, rarest
+// first, and the acquisitions (1-based) that passed through any of them
+// with the number of rare cells each touched.
+func (pm *persistenceMap) rare(limit int) (cells []int, acquisitions map[int]int) {
+	for cell, hits := range pm.hits {
+		if hits > 0 && hits <= limit {
+			cells = append(cells, cell)
+		}
+	}
+	sort.SliceStable(cells, func(i, j int) bool { return pm.hits[cells[i]] < pm.hits[cells[j]] })
+	acquisitions = map[int]int{}
+	for i, touched := range pm.touched {
+		for _, cell := range touched {
+			if pm.hits[cell] <= limit {
+				acquisitions[i+1]++
+			}
+		}
+	}
+	return cells, acquisitions
+}
+
+func (s *DiscoveryMCPServer) handlePersistence(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	ch := getInt(args, "channel", 1)
+	count := getInt(args, "count", 100)
+	if count < 1 || count > maxScopeAverages {
+		return errResult(fmt.Errorf("count must be between 1 and %d", maxScopeAverages)), nil
+	}
+	timeBins := getInt(args, "time_bins", 80)
+	ampBins := getInt(args, "amplitude_bins", 32)
+	if timeBins < 1 || timeBins > 1000 || ampBins < 2 || ampBins > 256 {
+		return errResult(fmt.Errorf("time_bins must be between 1 and 1000 and amplitude_bins between 2 and 256")), nil
+	}
+	rareFraction := getFloat(args, "rare_fraction", 0.05)
+	if rareFraction <= 0 || rareFraction >= 1 {
+		return errResult(fmt.Errorf("rare_fraction must be between 0 and 1, got %g", rareFraction)), nil
+	}
+	_, hasMin := args["v_min"]
+	_, hasMax := args["v_max"]
+	if hasMin != hasMax {
+		return errResult(fmt.Errorf("give both v_min and v_max or neither")), nil
+	}
+	vMin, vMax := getFloat(args, "v_min", 0), getFloat(args, "v_max", 0)
+	if hasMin && vMax <= vMin {
+		return errResult(fmt.Errorf("v_max must be above v_min")), nil
+	}
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, count)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+
+	var pm *persistenceMap
+	var samples int
+	for n := 1; n <= count; n++ {
+		data, err := s.device.Scope().Record(ctx, ch)
+		if err != nil {
+			return errResult(fmt.Errorf("acquisition %d of %d: %w", n, count, acquisitionError(err))), nil
+		}
+		if len(data) == 0 {
+			return errResult(fmt.Errorf("acquisition %d of %d returned no samples", n, count)), nil
+		}
+		s.usage.captured("scope", 1)
+		if pm == nil {
+			if !hasMin {
+				// Without a range, frame the first acquisition with half its
+				// span to spare on each side for what later ones do.
+				lo, _ := sampleStat(data, "min")
+				hi, _ := sampleStat(data, "max")
+				margin := max((hi-lo)/2, 0.01)
+				vMin, vMax = lo-margin, hi+margin
+			}
+			pm = newPersistenceMap(timeBins, ampBins, vMin, vMax)
+			samples = len(data)
+		}
+		pm.add(data)
+	}
+
+	limit := max(1, int(rareFraction*float64(count)))
+	cells, acqs := pm.rare(limit)
+	rare := make([]map[string]interface{}, 0, min(len(cells), maxPersistenceReport))
+	for _, cell := range cells[:min(len(cells), maxPersistenceReport)] {
+		t, v := pm.center(cell)
+		rare = append(rare, map[string]interface{}{
+			"time_bin":     t,
+			"voltage":      v,
+			"acquisitions": pm.hits[cell],
+		})
+	}
+	anomalous := make([]int, 0, len(acqs))
+	for n := range acqs {
+		anomalous = append(anomalous, n)
+	}
+	sort.Slice(anomalous, func(i, j int) bool {
+		if acqs[anomalous[i]] != acqs[anomalous[j]] {
+			return acqs[anomalous[i]] > acqs[anomalous[j]]
+		}
+		return anomalous[i] < anomalous[j]
+	})
+	worst := make([]map[string]interface{}, 0, min(len(anomalous), maxPersistenceReport))
+	for _, n := range anomalous[:min(len(anomalous), maxPersistenceReport)] {
+		worst = append(worst, map[string]interface{}{"acquisition": n, "rare_cells": acqs[n]})
+	}
+
+	result := map[string]interface{}{
+		"channel":                ch,
+		"acquisitions":           count,
+		"time_bins":              timeBins,
+		"amplitude_bins":         ampBins,
+		"v_min":                  vMin,
+		"v_max":                  vMax,
+		"volts_per_bin":          (vMax - vMin) / float64(ampBins),
+		"map":                    pm.rows(),
+		"histogram":              pm.histogram,
+		"below_range":            pm.below,
+		"above_range":            pm.above,
+		"rare_limit":             limit,
+		"rare_cells":             len(cells),
+		"rarest":                 rare,
+		"anomalous_acquisitions": worst,
+		"anomalous_count":        len(anomalous),
+	}
+	return jsonResult(timeAxis(result, s.device.Scope().Timing(), timeBins, float64(samples)/float64(timeBins))), nil
+}
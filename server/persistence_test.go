@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestPersistenceMap(t *testing.T) {
+	pm := newPersistenceMap(4, 4, 0, 4)
+	pulse := []float64{0.5, 3.5, 0.5, 0.5}
+	for range 19 {
+		pm.add(pulse)
+	}
+	// One acquisition has a double pulse and one sample out of range.
+	pm.add([]float64{0.5, 3.5, 3.5, 5})
+
+	rows := pm.rows()
+	if want := []string{" @. ", "    ", "    ", "@ %%"}; strings.Join(rows, "|") != strings.Join(want, "|") {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+	if pm.above != 1 || pm.histogram[0] != 19*3+1 || pm.histogram[3] != 19+2 {
+		t.Errorf("histogram %v, above %d", pm.histogram, pm.above)
+	}
+	cells, acqs := pm.rare(1)
+	if len(cells) != 1 || len(acqs) != 1 || acqs[20] != 1 {
+		t.Fatalf("rare cells %v, acquisitions %v", cells, acqs)
+	}
+	if tb, v := pm.center(cells[0]); tb != 2 || v != 3.5 {
+		t.Errorf("rare cell at bin %d, %g V", tb, v)
+	}
+}
+
+func TestHandlePersistence(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.sampleRate = 1e6
+	dev.scope.recordData = []float64{0, 1, 0, 1, 0, 1, 0, 1}
+	result, _ := s.handlePersistence(context.Background(), makeReq(map[string]any{
+		"channel": float64(1), "count": float64(5), "time_bins": float64(8), "amplitude_bins": float64(4),
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Map       []string `json:"map"`
+		Histogram []int    `json:"histogram"`
+		VMin      float64  `json:"v_min"`
+		VMax      float64  `json:"v_max"`
+		RareCells int      `json:"rare_cells"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got.Map) != 4 || got.Map[0] != " @ @ @ @" || got.Map[2] != "@ @ @ @ " || got.Map[3] != "        " {
+		t.Errorf("unexpected map %q", got.Map)
+	}
+	if got.VMin != -0.5 || got.VMax != 1.5 || got.RareCells != 0 {
+		t.Errorf("unexpected range %g..%g or rare cells %d", got.VMin, got.VMax, got.RareCells)
+	}
+
+	for _, args := range []map[string]any{
+		{"count": float64(0)},
+		{"v_min": float64(1)},
+		{"v_min": float64(1), "v_max": float64(0)},
+		{"rare_fraction": float64(1)},
+	} {
+		if result, _ := s.handlePersistence(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
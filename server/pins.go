@@ -0,0 +1,365 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Pin names a DIO line after the signal wired to it, so a test plan can
+// drive "RESET_N" instead of remembering that it is DIO 5.
+type Pin struct {
+	// Name is the signal name, e.g. "SDA"; it matches without regard to case.
+	Name string `json:"name"`
+	// Line is the DIO line the signal is wired to.
+	Line int `json:"line"`
+}
+
+// validatePin checks that a pin name cannot be mistaken for a line number.
+func validatePin(name string, line int) error {
+	if name == "" {
+		return fmt.Errorf("pin name is required")
+	}
+	if _, ok := dioLine(name); ok {
+		return fmt.Errorf("pin name %q reads as a DIO line number; choose the name of the signal", name)
+	}
+	if line < 0 {
+		return fmt.Errorf("pin %q: line must be 0 or greater, got %d", name, line)
+	}
+	return nil
+}
+
+// dioLine parses a line number written as "5" or "DIO5".
+func dioLine(s string) (int, bool) {
+	if len(s) > 3 && strings.EqualFold(s[:3], "dio") {
+		s = s[3:]
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil && n >= 0
+}
+
+// pinSet holds the pin names from the configuration file and those defined
+// by the client.
+type pinSet struct {
+	mu   sync.Mutex
+	pins map[string]Pin
+}
+
+func newPinSet() *pinSet {
+	return &pinSet{pins: map[string]Pin{}}
+}
+
+// define adds or replaces a pin.
+func (ps *pinSet) define(p Pin) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if err := validatePin(p.Name, p.Line); err != nil {
+		return err
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.pins[strings.ToLower(p.Name)] = p
+	return nil
+}
+
+// remove deletes a pin, reporting whether it was defined.
+func (ps *pinSet) remove(name string) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	key := strings.ToLower(strings.TrimSpace(name))
+	_, ok := ps.pins[key]
+	delete(ps.pins, key)
+	return ok
+}
+
+func (ps *pinSet) get(name string) (Pin, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p, ok := ps.pins[strings.ToLower(strings.TrimSpace(name))]
+	return p, ok
+}
+
+// list returns all pins sorted by line, then name.
+func (ps *pinSet) list() []Pin {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	out := make([]Pin, 0, len(ps.pins))
+	for _, p := range ps.pins {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Line != out[j].Line {
+			return out[i].Line < out[j].Line
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// pinArgKey marks the arguments in a tool schema that take a DIO line or a
+// logic channel. allowPinNames collects the marked arguments into the
+// pin arguments of each tool and removes the mark.
+const pinArgKey = "x-pin-arg"
+
+// pinArg is the mark of an argument that takes pin names.
+type pinArg struct {
+	// logic marks a logic analyzer channel rather than a DIO line.
+	logic bool
+	// field is set for an array of objects: it names the field of each
+	// object that takes the line.
+	field string
+}
+
+// takesDIOLine marks a number or number array argument as taking DIO
+// lines, where a pin name is accepted instead.
+func takesDIOLine() mcp.PropertyOption {
+	return func(prop map[string]any) {
+		prop[pinArgKey] = pinArg{}
+	}
+}
+
+// takesDIOLineIn marks an array of objects whose field takes a DIO line,
+// as in the clocks of discovery_pattern_clock. Objects nested under the
+// argument in those objects are resolved too, as in the loops of a macro.
+func takesDIOLineIn(field string) mcp.PropertyOption {
+	return func(prop map[string]any) {
+		prop[pinArgKey] = pinArg{field: field}
+	}
+}
+
+// takesLogicChannel marks an argument as taking a logic analyzer channel. A
+// pin name there stands for the channel that reads the pin's line, as
+// mapped by the channels of discovery_logic_open.
+func takesLogicChannel() mcp.PropertyOption {
+	return func(prop map[string]any) {
+		prop[pinArgKey] = pinArg{logic: true}
+	}
+}
+
+// pinLine resolves a DIO line argument. A number passes unchanged; a string
+// is a line number, "DIO5" or a pin name. named reports whether v named a
+// line rather than numbering it.
+func (ps *pinSet) pinLine(v any) (line any, named bool, err error) {
+	s, ok := v.(string)
+	if !ok {
+		return v, false, nil
+	}
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return float64(n), false, nil
+	}
+	if p, ok := ps.get(s); ok {
+		return float64(p.Line), true, nil
+	}
+	if n, ok := dioLine(s); ok {
+		return float64(n), true, nil
+	}
+	return nil, false, fmt.Errorf("unknown pin %q; discovery_pin_list shows the defined pins", s)
+}
+
+// resolveKey replaces the pin names under key, which may be "a.b" for a
+// field of array objects, by lines. mapLine converts the line of a name
+// for a logic channel argument; it is nil for DIO line arguments.
+func (ps *pinSet) resolveKey(args map[string]any, key string, mapLine func(int) (int, error)) error {
+	arr, field, nested := strings.Cut(key, ".")
+	v, ok := args[arr]
+	if !ok {
+		return nil
+	}
+	list, isList := v.([]any)
+	if !isList {
+		if nested {
+			return nil
+		}
+		r, err := ps.resolveValue(v, mapLine)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		args[key] = r
+		return nil
+	}
+	out := make([]any, len(list))
+	for i, item := range list {
+		if !nested {
+			r, err := ps.resolveValue(item, mapLine)
+			if err != nil {
+				return fmt.Errorf("%s[%d]: %w", key, i, err)
+			}
+			out[i] = r
+			continue
+		}
+		obj, isObj := item.(map[string]any)
+		if !isObj {
+			out[i] = item
+			continue
+		}
+		c := make(map[string]any, len(obj))
+		for k, v := range obj {
+			c[k] = v
+		}
+		if err := ps.resolveKey(c, field, mapLine); err != nil {
+			return fmt.Errorf("%s[%d].%w", arr, i, err)
+		}
+		if err := ps.resolveKey(c, key, mapLine); err != nil {
+			return fmt.Errorf("%s[%d].%w", arr, i, err)
+		}
+		out[i] = c
+	}
+	args[arr] = out
+	return nil
+}
+
+func (ps *pinSet) resolveValue(v any, mapLine func(int) (int, error)) (any, error) {
+	line, named, err := ps.pinLine(v)
+	if err != nil || !named || mapLine == nil {
+		return line, err
+	}
+	ch, err := mapLine(int(line.(float64)))
+	return float64(ch), err
+}
+
+// logicChannelOf returns the logic channel that reads a DIO line under the
+// channel map channels, empty for the identity.
+func logicChannelOf(channels []int, line int) (int, error) {
+	if len(channels) == 0 {
+		return line, nil
+	}
+	for ch, l := range channels {
+		if l == line {
+			return ch, nil
+		}
+	}
+	return 0, fmt.Errorf("DIO %d is not mapped to a logic channel; add it to the channels of discovery_logic_open", line)
+}
+
+// resolvePinArgs rewrites the pin names in the arguments of a tool call to
+// the line numbers, or logic channels, the handlers expect.
+func (s *DiscoveryMCPServer) resolvePinArgs(tool string, args map[string]any) (map[string]any, error) {
+	keys, logicKeys := s.pinArgs[tool], s.logicPinArgs[tool]
+	if len(keys) == 0 && len(logicKeys) == 0 {
+		return args, nil
+	}
+	// The channel of a quick measurement is a scope channel unless it
+	// checks a DIO pin.
+	if tool == "discovery_quick_measure" && getString(args, "check", "") != "dio" {
+		return args, nil
+	}
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		out[k] = v
+	}
+	for _, key := range keys {
+		if err := s.pins.resolveKey(out, key, nil); err != nil {
+			return nil, err
+		}
+	}
+	var channels []int
+	loaded := false
+	mapLine := func(line int) (int, error) {
+		if !loaded {
+			// A logic_open call maps the clock channel through its own
+			// channels, resolved above.
+			if tool == "discovery_logic_open" {
+				channels, _ = getIntList(out, "channels")
+			} else {
+				rb, err := s.device.Logic().Config()
+				if err != nil {
+					return 0, err
+				}
+				channels = rb.Channels
+			}
+			loaded = true
+		}
+		return logicChannelOf(channels, line)
+	}
+	for _, key := range logicKeys {
+		if err := s.pins.resolveKey(out, key, mapLine); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// resolvePins is the tool middleware that accepts pin names wherever a DIO
+// line is expected.
+func (s *DiscoveryMCPServer) resolvePins(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, ok := req.Params.Arguments.(map[string]any)
+		if !ok {
+			return next(ctx, req)
+		}
+		args, err := s.resolvePinArgs(req.Params.Name, args)
+		if err != nil {
+			return errResult(err), nil
+		}
+		req.Params.Arguments = args
+		return next(ctx, req)
+	}
+}
+
+// allowPinNames collects the arguments marked as taking pins, with the keys
+// resolvePinArgs expects: "a.b" is field b of each object of array a. It
+// widens their schemas to strings, so that clients which validate
+// arguments let pin names through.
+func (s *DiscoveryMCPServer) allowPinNames() {
+	s.pinArgs, s.logicPinArgs = map[string][]string{}, map[string][]string{}
+	for name, t := range s.mcpServer.ListTools() {
+		for arg, p := range t.Tool.InputSchema.Properties {
+			prop, _ := p.(map[string]any)
+			mark, ok := prop[pinArgKey].(pinArg)
+			if !ok {
+				continue
+			}
+			delete(prop, pinArgKey)
+			key := arg
+			if mark.field != "" {
+				key += "." + mark.field
+				items, _ := prop["items"].(map[string]any)
+				props, _ := items["properties"].(map[string]any)
+				prop, _ = props[mark.field].(map[string]any)
+			} else if items, ok := prop["items"].(map[string]any); ok {
+				prop = items
+			}
+			if mark.logic {
+				s.logicPinArgs[name] = append(s.logicPinArgs[name], key)
+			} else {
+				s.pinArgs[name] = append(s.pinArgs[name], key)
+			}
+			if prop != nil && prop["type"] == "number" {
+				prop["type"] = []string{"number", "string"}
+				if d, ok := prop["description"].(string); ok {
+					prop["description"] = d + ", or a pin name from discovery_pin_list"
+				}
+			}
+		}
+	}
+}
+
+func (s *DiscoveryMCPServer) handlePinDefine(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := strings.TrimSpace(getString(req.Params.Arguments, "name", ""))
+	if getBool(req.Params.Arguments, "remove", false) {
+		if !s.pins.remove(name) {
+			return errResult(fmt.Errorf("no pin named %q", name)), nil
+		}
+		return jsonResult(map[string]interface{}{"removed": name}), nil
+	}
+	if _, ok := argsMap(req.Params.Arguments)["line"]; !ok {
+		return errResult(fmt.Errorf("line is required")), nil
+	}
+	p := Pin{Name: name, Line: getInt(req.Params.Arguments, "line", -1)}
+	if err := s.pins.define(p); err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(p), nil
+}
+
+func (s *DiscoveryMCPServer) handlePinList(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return jsonResult(map[string]interface{}{
+		"pins": s.pins.list(),
+	}), nil
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestHandlePinDefine(t *testing.T) {
+	s, _ := newTestServer()
+	for _, args := range []map[string]any{
+		{"name": "SDA", "line": 0.0},
+		{"name": "RESET_N", "line": 5.0},
+		{"name": "sda", "line": 2.0},
+	} {
+		result, _ := s.handlePinDefine(context.Background(), makeReq(args))
+		if result.IsError {
+			t.Fatalf("define %v: %s", args, result.Content[0].(mcp.TextContent).Text)
+		}
+	}
+	for _, args := range []map[string]any{
+		{"name": "DIO3", "line": 3.0},
+		{"name": "7", "line": 7.0},
+		{"name": "CS"},
+		{"name": "CS", "line": -1.0},
+		{"name": "CLK", "remove": true},
+	} {
+		if result, _ := s.handlePinDefine(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("define %v: expected error", args)
+		}
+	}
+
+	result, _ := s.handlePinList(context.Background(), makeReq(nil))
+	var got struct {
+		Pins []Pin `json:"pins"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []Pin{{"sda", 2}, {"RESET_N", 5}}
+	if !reflect.DeepEqual(got.Pins, want) {
+		t.Errorf("pins = %v, want %v", got.Pins, want)
+	}
+
+	if result, _ := s.handlePinDefine(context.Background(), makeReq(map[string]any{"name": "Reset_N", "remove": true})); result.IsError {
+		t.Fatalf("remove: %s", result.Content[0].(mcp.TextContent).Text)
+	}
+	if _, ok := s.pins.get("RESET_N"); ok {
+		t.Error("RESET_N still defined after remove")
+	}
+}
+
+func TestResolvePinArgs(t *testing.T) {
+	s, dev := newTestServer()
+	if err := s.SetConfig(&Config{Pins: map[string]int{"SDA": 0, "SCL": 1, "RESET_N": 5, "CLK": 3}}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	dev.logic.readback.Channels = []int{3, 5}
+
+	for _, tc := range []struct {
+		tool string
+		args map[string]any
+		want map[string]any
+	}{
+		{"discovery_static_set_state", map[string]any{"channel": "reset_n", "value": true},
+			map[string]any{"channel": 5.0, "value": true}},
+		{"discovery_static_set_state", map[string]any{"channel": 4.0}, map[string]any{"channel": 4.0}},
+		{"discovery_static_get_state", map[string]any{"channel": "DIO7"}, map[string]any{"channel": 7.0}},
+		{"discovery_i2c_open", map[string]any{"sda": "SDA", "scl": "SCL"}, map[string]any{"sda": 0.0, "scl": 1.0}},
+		{"discovery_pattern_rom", map[string]any{"inputs": []any{"SDA", 1.0}, "outputs": []any{"RESET_N"}},
+			map[string]any{"inputs": []any{0.0, 1.0}, "outputs": []any{5.0}}},
+		{"discovery_static_macro", map[string]any{"steps": []any{
+			map[string]any{"op": "set", "channel": "RESET_N", "value": false},
+			map[string]any{"op": "loop", "count": 2.0, "steps": []any{map[string]any{"op": "read", "channel": "SDA"}}},
+		}}, map[string]any{"steps": []any{
+			map[string]any{"op": "set", "channel": 5.0, "value": false},
+			map[string]any{"op": "loop", "count": 2.0, "steps": []any{map[string]any{"op": "read", "channel": 0.0}}},
+		}}},
+		// The quick measurement channel is a scope channel unless it checks
+		// a DIO pin.
+		{"discovery_quick_measure", map[string]any{"check": "dio", "channel": "RESET_N"},
+			map[string]any{"check": "dio", "channel": 5.0}},
+		{"discovery_quick_measure", map[string]any{"check": "voltage", "channel": "RESET_N"},
+			map[string]any{"check": "voltage", "channel": "RESET_N"}},
+		// Logic channel arguments take the channel that reads the pin.
+		{"discovery_logic_record", map[string]any{"channel": "RESET_N"}, map[string]any{"channel": 1.0}},
+		{"discovery_logic_record", map[string]any{"channel": 1.0}, map[string]any{"channel": 1.0}},
+		{"discovery_logic_open", map[string]any{"channels": []any{"SDA", "CLK"}, "clock_channel": "CLK"},
+			map[string]any{"channels": []any{0.0, 3.0}, "clock_channel": 1.0}},
+		{"discovery_wavegen_generate", map[string]any{"channel": "SDA"}, map[string]any{"channel": "SDA"}},
+	} {
+		got, err := s.resolvePinArgs(tc.tool, tc.args)
+		if err != nil {
+			t.Errorf("%s %v: %v", tc.tool, tc.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s %v: got %v, want %v", tc.tool, tc.args, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"discovery_static_set_state", map[string]any{"channel": "MISO"}, "unknown pin \"MISO\""},
+		{"discovery_static_macro", map[string]any{"steps": []any{map[string]any{"op": "read", "channel": "X"}}}, "steps[0].channel"},
+		{"discovery_logic_record", map[string]any{"channel": "SDA"}, "DIO 0 is not mapped"},
+	} {
+		_, err := s.resolvePinArgs(tc.tool, tc.args)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s %v: err = %v, want %q", tc.tool, tc.args, err, tc.want)
+		}
+	}
+}
+
+func TestResolvePins(t *testing.T) {
+	s, _ := newTestServer()
+	s.pins.define(Pin{Name: "RESET_N", Line: 5})
+
+	var got any
+	h := s.resolvePins(func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		got = req.Params.Arguments
+		return mcp.NewToolResultText("ok"), nil
+	})
+	req := makeReq(map[string]any{"channel": "RESET_N"})
+	req.Params.Name = "discovery_dio_toggle"
+	if result, _ := h(context.Background(), req); result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content[0].(mcp.TextContent).Text)
+	}
+	if want := map[string]any{"channel": 5.0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("handler got %v, want %v", got, want)
+	}
+
+	got = nil
+	req = makeReq(map[string]any{"channel": "RESET"})
+	req.Params.Name = "discovery_dio_toggle"
+	if result, _ := h(context.Background(), req); !result.IsError || got != nil {
+		t.Errorf("unknown pin reached the handler: %v", got)
+	}
+
+	prop := s.mcpServer.GetTool("discovery_dio_toggle").Tool.InputSchema.Properties["channel"].(map[string]any)
+	if !reflect.DeepEqual(prop["type"], []string{"number", "string"}) {
+		t.Errorf("channel schema type = %v, want number or string", prop["type"])
+	}
+}
+
+// TestPinArgsCoverTools checks that every argument described as a DIO line
+// or a logic channel accepts pin names.
+func TestPinArgsCoverTools(t *testing.T) {
+	s, _ := newTestServer()
+	line := regexp.MustCompile(`(?i)\b(DIO (line|channel|pin)|logic channel|chip select line)\b`)
+	for name, tool := range s.mcpServer.ListTools() {
+		for arg, p := range tool.Tool.InputSchema.Properties {
+			prop, _ := p.(map[string]any)
+			if _, marked := prop[pinArgKey]; marked {
+				t.Errorf("%s %s: mark left in the schema", name, arg)
+			}
+			d, _ := prop["description"].(string)
+			if prop["type"] == "string" || !line.MatchString(d) || name == "discovery_pin_define" {
+				continue
+			}
+			takesPin := func(key string) bool { return key == arg || strings.HasPrefix(key, arg+".") }
+			if !slices.ContainsFunc(s.pinArgs[name], takesPin) && !slices.ContainsFunc(s.logicPinArgs[name], takesPin) {
+				t.Errorf("%s %s takes a DIO line or logic channel but not pin names", name, arg)
+			}
+		}
+	}
+	for _, tc := range []struct{ tool, arg string }{
+		{"discovery_spi_read", "cs"},
+		{"discovery_spi_write", "cs"},
+		{"discovery_static_macro", "steps.channel"},
+	} {
+		if !slices.Contains(s.pinArgs[tc.tool], tc.arg) {
+			t.Errorf("%s %s does not take pin names", tc.tool, tc.arg)
+		}
+	}
+}
+
+func TestConfigPins(t *testing.T) {
+	for _, pins := range []map[string]int{
+		{"": 0},
+		{"DIO2": 2},
+		{"CS": -1},
+		{"cs": 1, "CS": 2},
+	} {
+		if err := (&Config{Pins: pins}).Validate(); err == nil {
+			t.Errorf("pins %v: expected error", pins)
+		}
+	}
+	if err := (&Config{Pins: map[string]int{"SDA": 0, "RESET_N": 5}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	s, _ := newTestServer()
+	if err := s.SetConfig(&Config{Pins: map[string]int{"DIO2": 2}}); err == nil {
+		t.Error("SetConfig: expected error for a pin named like a line")
+	}
+}
@@ -0,0 +1,369 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxCaptureAnalyses caps the pipeline results kept per capture; older ones
+// are dropped first.
+const maxCaptureAnalyses = 20
+
+// PipelineDef is a named capture analysis pipeline from the configuration.
+type PipelineDef struct {
+	// Name identifies the pipeline in discovery_capture_process.
+	Name string `json:"name"`
+	// Description tells what the pipeline is for.
+	Description string `json:"description,omitempty"`
+	// Stages are the stage objects, as accepted by the tool.
+	Stages []map[string]any `json:"stages"`
+}
+
+// pipelineState is the signal flowing through a pipeline.
+type pipelineState struct {
+	ctx context.Context
+	s   *DiscoveryMCPServer
+	// source is the capture the pipeline started from.
+	source string
+	data   []float64
+	// rate is the sample rate in Hz, 0 if the capture does not record it.
+	rate float64
+}
+
+// pipelineStage runs one parsed stage, transforming the state in place and
+// returning what it found for the results.
+type pipelineStage func(st *pipelineState) (map[string]interface{}, error)
+
+// pipelineStages builds a stage from its object. Each stage reads its own
+// parameters; adding an analysis means adding an entry here.
+var pipelineStages = map[string]func(args map[string]any) (pipelineStage, error){
+	"filter":  filterStage,
+	"decode":  decodeStage,
+	"measure": measureStage,
+	"export":  exportStage,
+}
+
+// parsedStage is a pipeline stage with the name it was given by.
+type parsedStage struct {
+	name string
+	run  pipelineStage
+}
+
+// parsePipeline builds the stages of a pipeline from their objects.
+func parsePipeline(stages []map[string]any) ([]parsedStage, error) {
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("a pipeline needs at least one stage")
+	}
+	out := make([]parsedStage, 0, len(stages))
+	for i, args := range stages {
+		name := getString(args, "stage", "")
+		build, ok := pipelineStages[name]
+		if !ok {
+			return nil, fmt.Errorf("stage %d: unknown stage %q (expected %s)", i, name, strings.Join(pipelineStageNames(), ", "))
+		}
+		run, err := build(args)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d (%s): %w", i, name, err)
+		}
+		out = append(out, parsedStage{name: name, run: run})
+	}
+	return out, nil
+}
+
+func pipelineStageNames() []string {
+	names := make([]string, 0, len(pipelineStages))
+	for name := range pipelineStages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// filterStage smooths or thins the signal: moving_average over window
+// samples, a single-pole lowpass at cutoff Hz, or decimate by factor.
+func filterStage(args map[string]any) (pipelineStage, error) {
+	switch kind := getString(args, "type", "moving_average"); kind {
+	case "moving_average":
+		window := getInt(args, "window", 5)
+		if window < 1 {
+			return nil, fmt.Errorf("window must be at least 1")
+		}
+		return func(st *pipelineState) (map[string]interface{}, error) {
+			out := make([]float64, len(st.data))
+			var sum float64
+			for i, v := range st.data {
+				sum += v
+				if i >= window {
+					sum -= st.data[i-window]
+				}
+				out[i] = sum / float64(min(i+1, window))
+			}
+			st.data = out
+			return map[string]interface{}{"type": kind, "window": window}, nil
+		}, nil
+	case "lowpass":
+		cutoff := getFloat(args, "cutoff", 0)
+		if cutoff <= 0 {
+			return nil, fmt.Errorf("lowpass needs a positive cutoff in Hz")
+		}
+		return func(st *pipelineState) (map[string]interface{}, error) {
+			if st.rate <= 0 {
+				return nil, fmt.Errorf("the capture has no sample_rate for a lowpass")
+			}
+			alpha := 1 / (1 + st.rate/(2*math.Pi*cutoff))
+			out := make([]float64, len(st.data))
+			for i, v := range st.data {
+				if i == 0 {
+					out[i] = v
+					continue
+				}
+				out[i] = out[i-1] + alpha*(v-out[i-1])
+			}
+			st.data = out
+			return map[string]interface{}{"type": kind, "cutoff": cutoff}, nil
+		}, nil
+	case "decimate":
+		factor := getInt(args, "factor", 0)
+		if factor < 2 {
+			return nil, fmt.Errorf("decimate needs a factor of at least 2")
+		}
+		return func(st *pipelineState) (map[string]interface{}, error) {
+			out := make([]float64, 0, len(st.data)/factor+1)
+			for i := 0; i < len(st.data); i += factor {
+				out = append(out, st.data[i])
+			}
+			st.data = out
+			st.rate /= float64(factor)
+			return map[string]interface{}{"type": kind, "factor": factor, "samples": len(out)}, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown filter type %q (expected moving_average, lowpass or decimate)", kind)
+	}
+}
+
+// decodeStage turns the signal into logic levels at threshold, by default
+// midway between its extremes, and reports the edges, frequency and duty
+// cycle. hysteresis is the band around the threshold the signal must cross,
+// by default a tenth of its span.
+func decodeStage(args map[string]any) (pipelineStage, error) {
+	_, hasThreshold := args["threshold"]
+	threshold := getFloat(args, "threshold", 0)
+	_, hasHysteresis := args["hysteresis"]
+	hysteresis := getFloat(args, "hysteresis", 0)
+	if hysteresis < 0 {
+		return nil, fmt.Errorf("hysteresis must not be negative")
+	}
+	return func(st *pipelineState) (map[string]interface{}, error) {
+		finite := finiteSamples(st.data)
+		lo, err := sampleStat(finite, "min")
+		if err != nil {
+			return nil, err
+		}
+		hi, _ := sampleStat(finite, "max")
+		level, band := threshold, hysteresis
+		if !hasThreshold {
+			level = (lo + hi) / 2
+		}
+		if !hasHysteresis {
+			band = (hi - lo) / 10
+		}
+		high := st.data[0] > level
+		var rising []int
+		falling, highSamples := 0, 0
+		for i, v := range st.data {
+			switch {
+			case !high && v > level+band/2:
+				high = true
+				rising = append(rising, i)
+			case high && v < level-band/2:
+				high = false
+				falling++
+			}
+			if high {
+				highSamples++
+			}
+		}
+		result := map[string]interface{}{
+			"threshold":     level,
+			"hysteresis":    band,
+			"rising_edges":  len(rising),
+			"falling_edges": falling,
+			"duty_cycle":    float64(highSamples) / float64(len(st.data)),
+		}
+		if len(rising) >= 2 && st.rate > 0 {
+			period := float64(rising[len(rising)-1]-rising[0]) / float64(len(rising)-1) / st.rate
+			result["frequency"] = 1 / period
+		}
+		if len(rising) > 0 {
+			result["first_rising_index"] = rising[0]
+		}
+		return result, nil
+	}, nil
+}
+
+// finiteSamples returns data without the NaN samples.
+func finiteSamples(data []float64) []float64 {
+	return slices.DeleteFunc(slices.Clone(data), math.IsNaN)
+}
+
+// measureStage reports statistics of the signal, by default all that
+// sampleStat knows.
+func measureStage(args map[string]any) (pipelineStage, error) {
+	stats := []string{"mean", "min", "max", "rms", "pp"}
+	if _, ok := args["stats"]; ok {
+		stats = parseTags(args["stats"])
+	}
+	for _, stat := range stats {
+		if _, err := sampleStat([]float64{0}, stat); err != nil {
+			return nil, err
+		}
+	}
+	return func(st *pipelineState) (map[string]interface{}, error) {
+		finite := finiteSamples(st.data)
+		result := map[string]interface{}{"samples": len(st.data)}
+		for _, stat := range stats {
+			v, err := sampleStat(finite, stat)
+			if err != nil {
+				return nil, err
+			}
+			result[stat] = v
+		}
+		return result, nil
+	}, nil
+}
+
+// exportStage stores the processed signal as a new capture.
+func exportStage(map[string]any) (pipelineStage, error) {
+	return func(st *pipelineState) (map[string]interface{}, error) {
+		name := newCaptureName("pipeline")
+		if err := st.s.storeCapture(st.ctx, name, map[string]interface{}{
+			"source":      st.source,
+			"sample_rate": st.rate,
+			"samples":     len(st.data),
+			"data":        st.data,
+		}); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"uri":      capturesURIPrefix + name,
+			"location": st.s.captures.Location(name),
+		}, nil
+	}, nil
+}
+
+// pipeline returns the stages of the named pipeline from the configuration.
+func (c *Config) pipeline(name string) ([]map[string]any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	for _, p := range c.Pipelines {
+		if p.Name == name {
+			return p.Stages, true
+		}
+	}
+	return nil, false
+}
+
+// loadCaptureArray reads one sample array of a stored capture with its
+// sample rate. Nulls, which stand for non-finite samples, become NaN.
+func (s *DiscoveryMCPServer) loadCaptureArray(ctx context.Context, name, key string) ([]float64, float64, error) {
+	raw, err := s.captures.Get(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	var capture map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &capture); err != nil {
+		return nil, 0, fmt.Errorf("capture %q is not a JSON object: %w", name, err)
+	}
+	var values []*float64
+	if err := json.Unmarshal(capture[key], &values); err != nil || values == nil {
+		return nil, 0, fmt.Errorf("capture %q has no sample array %q", name, key)
+	}
+	data := make([]float64, len(values))
+	for i, v := range values {
+		data[i] = math.NaN()
+		if v != nil {
+			data[i] = *v
+		}
+	}
+	var rate float64
+	_ = json.Unmarshal(capture["sample_rate"], &rate)
+	return data, rate, nil
+}
+
+func (s *DiscoveryMCPServer) handleCaptureProcess(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	name := captureName(getString(args, "capture", ""))
+	if err := validCaptureName(name); err != nil || name == captureIndexName {
+		return errResult(fmt.Errorf("invalid capture %q", getString(args, "capture", ""))), nil
+	}
+
+	pipelineName := getString(args, "pipeline", "")
+	var defs []map[string]any
+	switch raw, inline := args["stages"].([]any); {
+	case pipelineName != "" && inline:
+		return errResult(fmt.Errorf("give either pipeline or stages, not both")), nil
+	case pipelineName != "":
+		var ok bool
+		if defs, ok = s.config.pipeline(pipelineName); !ok {
+			return errResult(fmt.Errorf("no pipeline named %q in the configuration", pipelineName)), nil
+		}
+	case inline:
+		for i, item := range raw {
+			stage, ok := item.(map[string]any)
+			if !ok {
+				return errResult(fmt.Errorf("stage %d: must be an object", i)), nil
+			}
+			defs = append(defs, stage)
+		}
+		pipelineName = "inline"
+	default:
+		return errResult(fmt.Errorf("give a configured pipeline or a list of stages")), nil
+	}
+	stages, err := parsePipeline(defs)
+	if err != nil {
+		return errResult(fmt.Errorf("invalid pipeline: %w", err)), nil
+	}
+
+	array := getString(args, "array", "data")
+	data, rate, err := s.loadCaptureArray(ctx, name, array)
+	if err != nil {
+		return errResult(err), nil
+	}
+	if len(data) == 0 {
+		return errResult(fmt.Errorf("capture %q has no samples in %q", name, array)), nil
+	}
+	st := &pipelineState{ctx: ctx, s: s, source: name, data: data, rate: rate}
+	results := make([]map[string]interface{}, 0, len(stages))
+	for i, stage := range stages {
+		r, err := stage.run(st)
+		if err != nil {
+			return errResult(fmt.Errorf("stage %d (%s): %w", i, stage.name, err)), nil
+		}
+		r["stage"] = stage.name
+		results = append(results, r)
+	}
+
+	if err := s.annotations.analyzed(ctx, s.captures, name, captureAnalysis{
+		Pipeline: pipelineName,
+		Array:    array,
+		Results:  results,
+	}); err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(map[string]interface{}{
+		"capture":     capturesURIPrefix + name,
+		"pipeline":    pipelineName,
+		"array":       array,
+		"samples":     len(data),
+		"sample_rate": rate,
+		"stages":      results,
+	}), nil
+}
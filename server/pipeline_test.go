@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParsePipeline(t *testing.T) {
+	stages, err := parsePipeline([]map[string]any{
+		{"stage": "filter", "type": "lowpass", "cutoff": 1000.0},
+		{"stage": "measure", "stats": []any{"mean", "pp"}},
+	})
+	if err != nil || len(stages) != 2 || stages[1].name != "measure" {
+		t.Fatalf("parsePipeline = %v, %v", stages, err)
+	}
+	for _, bad := range [][]map[string]any{
+		nil,
+		{{"stage": "fft"}},
+		{{"stage": "filter", "type": "median"}},
+		{{"stage": "filter", "type": "decimate", "factor": 1.0}},
+		{{"stage": "measure", "stats": "mean, median"}},
+		{{"stage": "decode", "hysteresis": -1.0}},
+	} {
+		if _, err := parsePipeline(bad); err == nil {
+			t.Errorf("%v: expected error", bad)
+		}
+	}
+}
+
+func TestPipelineStages(t *testing.T) {
+	run := func(args map[string]any, st *pipelineState) map[string]interface{} {
+		t.Helper()
+		stages, err := parsePipeline([]map[string]any{args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := stages[0].run(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	st := &pipelineState{data: []float64{0, 3, 6, 3}, rate: 100}
+	run(map[string]any{"stage": "filter", "window": 2.0}, st)
+	if want := []float64{0, 1.5, 4.5, 4.5}; !floatsEqual(st.data, want) {
+		t.Errorf("moving average = %v, want %v", st.data, want)
+	}
+	run(map[string]any{"stage": "filter", "type": "decimate", "factor": 2.0}, st)
+	if !floatsEqual(st.data, []float64{0, 4.5}) || st.rate != 50 {
+		t.Errorf("decimated = %v at %g Hz", st.data, st.rate)
+	}
+
+	// A 10 Hz square wave at 100 Hz: five samples low, five high.
+	st = &pipelineState{rate: 100}
+	for i := range 40 {
+		st.data = append(st.data, float64(i/5%2))
+	}
+	r := run(map[string]any{"stage": "decode"}, st)
+	if r["rising_edges"] != 4 || r["falling_edges"] != 3 || r["frequency"] != 10.0 || r["duty_cycle"] != 0.5 {
+		t.Errorf("decode = %v", r)
+	}
+	st.data = append(st.data, math.NaN())
+	r = run(map[string]any{"stage": "measure", "stats": []any{"max", "mean"}}, st)
+	if r["max"] != 1.0 || r["mean"] != 0.5 || r["samples"] != 41 {
+		t.Errorf("measure = %v", r)
+	}
+
+	stages, _ := parsePipeline([]map[string]any{{"stage": "filter", "type": "lowpass", "cutoff": 10.0}})
+	if _, err := stages[0].run(&pipelineState{data: []float64{1}}); err == nil {
+		t.Error("expected error for a lowpass without sample rate")
+	}
+}
+
+func floatsEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-12 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHandleCaptureProcess(t *testing.T) {
+	s, _ := newTestServer()
+	ctx := context.Background()
+	if err := s.storeCapture(ctx, "scope-1.json", map[string]interface{}{
+		"sample_rate": 1000.0,
+		"data":        []float64{0, 1, 0, 1, 0, 1, 0, 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s.SetConfig(&Config{Pipelines: []PipelineDef{{
+		Name:   "levels",
+		Stages: []map[string]any{{"stage": "measure", "stats": []any{"min", "max"}}},
+	}}})
+
+	process := func(args map[string]any) (*mcp.CallToolResult, map[string]any) {
+		t.Helper()
+		result, err := s.handleCaptureProcess(ctx, makeReq(args))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got map[string]any
+		json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got)
+		return result, got
+	}
+	result, got := process(map[string]any{
+		"capture": "captures://scope-1.json",
+		"stages": []any{
+			map[string]any{"stage": "decode"},
+			map[string]any{"stage": "filter", "type": "decimate", "factor": 2.0},
+			map[string]any{"stage": "measure", "stats": "mean"},
+			map[string]any{"stage": "export"},
+		},
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	stages := got["stages"].([]any)
+	if len(stages) != 4 || stages[0].(map[string]any)["frequency"] != 500.0 || stages[2].(map[string]any)["mean"] != 0.0 {
+		t.Errorf("unexpected stages %v", stages)
+	}
+	exported := captureName(stages[3].(map[string]any)["uri"].(string))
+	if data, rate, err := s.loadCaptureArray(ctx, exported, "data"); err != nil || len(data) != 4 || rate != 500 {
+		t.Errorf("exported capture = %v at %g Hz, %v", data, rate, err)
+	}
+
+	if result, got = process(map[string]any{"capture": "scope-1.json", "pipeline": "levels"}); result.IsError || got["pipeline"] != "levels" {
+		t.Errorf("configured pipeline: %v", result.Content)
+	}
+	entries, err := s.annotations.search(ctx, s.captures, nil, "scope-1")
+	if err != nil || len(entries) != 1 || len(entries[0].Analyses) != 2 || entries[0].Analyses[1].Results[0]["max"] != 1.0 {
+		t.Errorf("analyses not attached to the capture: %+v", entries)
+	}
+
+	for _, args := range []map[string]any{
+		{"capture": "scope-1.json"},
+		{"capture": "scope-1.json", "pipeline": "missing"},
+		{"capture": "scope-1.json", "pipeline": "levels", "stages": []any{map[string]any{"stage": "measure"}}},
+		{"capture": "missing.json", "pipeline": "levels"},
+		{"capture": "scope-1.json", "pipeline": "levels", "array": "min"},
+		{"capture": "annotations.json", "pipeline": "levels"},
+	} {
+		if result, _ := process(args); !result.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
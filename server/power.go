@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// powerDataNotification is the method of the notifications that carry
+// streamed power profile samples to the client.
+const powerDataNotification = "notifications/discovery/power_data"
+
+// maxPowerSamples caps the samples of one power profile.
+const maxPowerSamples = 100000
+
+// powerChunk is the number of samples sent per notification when streaming.
+const powerChunk = 10
+
+// powerRail is an AnalogIO channel whose current can be profiled. current
+// and voltage index the nodes passed to ReadStatus; voltage is -1 if the
+// channel reports no voltage.
+type powerRail struct {
+	label            string
+	current, voltage int
+}
+
+// powerRails finds the channels that report a current, keeping only those
+// named in want by label or name when it is not empty. It returns the rails
+// with the status nodes to sample.
+func powerRails(channels []dwf.AnalogIOChannel, want []string) ([]powerRail, []dwf.AnalogIONodeRef, error) {
+	var rails []powerRail
+	var nodes []dwf.AnalogIONodeRef
+	found := map[string]bool{}
+	for _, ch := range channels {
+		if len(want) > 0 && !matchesRail(ch, want, found) {
+			continue
+		}
+		current, voltage := -1, -1
+		for _, n := range ch.Nodes {
+			if n.Status == nil {
+				continue
+			}
+			switch {
+			case strings.EqualFold(n.Name, "Current"):
+				current = n.Index
+			case strings.EqualFold(n.Name, "Voltage"):
+				voltage = n.Index
+			}
+		}
+		if current < 0 {
+			if len(want) > 0 {
+				return nil, nil, fmt.Errorf("%s reports no current", ch.Label)
+			}
+			continue
+		}
+		rail := powerRail{label: ch.Label, current: len(nodes), voltage: -1}
+		nodes = append(nodes, dwf.AnalogIONodeRef{Channel: ch.Index, Node: current})
+		if voltage >= 0 {
+			rail.voltage = len(nodes)
+			nodes = append(nodes, dwf.AnalogIONodeRef{Channel: ch.Index, Node: voltage})
+		}
+		rails = append(rails, rail)
+	}
+	for _, w := range want {
+		if !found[strings.ToLower(w)] {
+			return nil, nil, fmt.Errorf("no AnalogIO channel %q; discovery_analogio_list shows the channels", w)
+		}
+	}
+	if len(rails) == 0 {
+		return nil, nil, fmt.Errorf("the device reports no supply or USB current")
+	}
+	return rails, nodes, nil
+}
+
+// matchesRail reports whether ch is one of the wanted rails, marking the
+// names it matches in found.
+func matchesRail(ch dwf.AnalogIOChannel, want []string, found map[string]bool) bool {
+	match := false
+	for _, w := range want {
+		if strings.EqualFold(w, ch.Label) || strings.EqualFold(w, ch.Name) {
+			found[strings.ToLower(w)] = true
+			match = true
+		}
+	}
+	return match
+}
+
+// powerSample is one read of the profiled nodes, at time t seconds from the
+// start.
+type powerSample struct {
+	t      float64
+	values []float64
+}
+
+// samplePower reads nodes every interval until duration has passed, sending
+// each sample on the returned channel from a background goroutine. The
+// channel is closed at the end; a read error or the end of ctx is sent on
+// errc first.
+func samplePower(ctx context.Context, aio dwf.AnalogIO, nodes []dwf.AnalogIONodeRef, duration, interval time.Duration) (<-chan powerSample, <-chan error) {
+	samples := make(chan powerSample)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(samples)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		start := time.Now()
+		for {
+			values, err := aio.ReadStatus(nodes)
+			if err != nil {
+				errc <- err
+				return
+			}
+			elapsed := time.Since(start)
+			select {
+			case samples <- powerSample{t: elapsed.Seconds(), values: values}:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+			if elapsed+interval > duration {
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return samples, errc
+}
+
+// traceStats returns the average and peak of a trace and its integral over
+// times by the trapezoidal rule.
+func traceStats(times, values []float64) (avg, peak, integral float64) {
+	peak = math.Inf(-1)
+	for i, v := range values {
+		avg += v
+		peak = math.Max(peak, v)
+		if i > 0 {
+			integral += (v + values[i-1]) / 2 * (times[i] - times[i-1])
+		}
+	}
+	return avg / float64(len(values)), peak, integral
+}
+
+func (s *DiscoveryMCPServer) handlePowerProfile(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	duration := getFloat(args, "duration", 0)
+	if duration <= 0 || duration > 3600 {
+		return errResult(fmt.Errorf("duration must be between 0 and 3600 seconds, got %g", duration)), nil
+	}
+	interval := getFloat(args, "interval", 0.1)
+	if interval < 0.01 {
+		return errResult(fmt.Errorf("interval must be at least 0.01 seconds, got %g", interval)), nil
+	}
+	if duration/interval > maxPowerSamples {
+		return errResult(fmt.Errorf("%g s at %g s intervals exceeds %d samples; raise the interval", duration, interval, maxPowerSamples)), nil
+	}
+	aio := s.device.AnalogIO()
+	channels, err := aio.Channels()
+	if err != nil {
+		return errResult(err), nil
+	}
+	rails, nodes, err := powerRails(channels, parseTags(args["rails"]))
+	if err != nil {
+		return errResult(err), nil
+	}
+	stream := getBool(args, "stream", false)
+	store := getBool(args, "store", false)
+
+	srv := server.ServerFromContext(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	samples, errc := samplePower(ctx, aio, nodes,
+		time.Duration(duration*float64(time.Second)), time.Duration(interval*float64(time.Second)))
+	var times []float64
+	series := make([][]float64, len(nodes))
+	chunk := 0
+	flush := func(from int) {
+		if srv == nil || !stream || from >= len(times) {
+			return
+		}
+		data := map[string]interface{}{"chunk": chunk, "time": times[from:]}
+		for _, r := range rails {
+			data[r.label] = series[r.current][from:]
+		}
+		_ = srv.SendNotificationToClient(ctx, powerDataNotification, data)
+		chunk++
+	}
+	for sample := range samples {
+		times = append(times, sample.t)
+		for i, v := range sample.values {
+			series[i] = append(series[i], v)
+		}
+		if len(times)%powerChunk == 0 {
+			flush(len(times) - powerChunk)
+		}
+	}
+	select {
+	case err := <-errc:
+		return errResult(fmt.Errorf("power profile aborted after %d samples: %w", len(times), err)), nil
+	default:
+	}
+	flush(len(times) - len(times)%powerChunk)
+
+	railResults := make([]map[string]interface{}, 0, len(rails))
+	trace := map[string]interface{}{"time": times}
+	totalPower := make([]float64, len(times))
+	powered := 0
+	for _, r := range rails {
+		// Statistics are of magnitudes, so that a negative rail counts as a
+		// drawn current and power.
+		currents := series[r.current]
+		magnitudes := make([]float64, len(times))
+		for i, c := range currents {
+			magnitudes[i] = math.Abs(c)
+		}
+		avg, peak, _ := traceStats(times, magnitudes)
+		rail := map[string]interface{}{"rail": r.label, "avg_current": avg, "peak_current": peak}
+		railTrace := map[string]interface{}{"current": currents}
+		if r.voltage >= 0 {
+			power := make([]float64, len(times))
+			for i, c := range magnitudes {
+				power[i] = c * math.Abs(series[r.voltage][i])
+				totalPower[i] += power[i]
+			}
+			rail["avg_power"], rail["peak_power"], rail["energy"] = traceStats(times, power)
+			railTrace["voltage"] = series[r.voltage]
+			powered++
+		}
+		railResults = append(railResults, rail)
+		trace[r.label] = railTrace
+	}
+
+	result := map[string]interface{}{
+		"duration": duration,
+		"interval": interval,
+		"samples":  len(times),
+		"rails":    railResults,
+	}
+	if powered > 1 {
+		avg, peak, energy := traceStats(times, totalPower)
+		result["total"] = map[string]interface{}{"avg_power": avg, "peak_power": peak, "energy": energy}
+	}
+	if store {
+		name := newCaptureName("power")
+		trace["interval"], trace["samples"] = interval, len(times)
+		if err := s.storeCapture(ctx, name, trace); err != nil {
+			return errResult(err), nil
+		}
+		result["uri"] = capturesURIPrefix + name
+		result["location"] = s.captures.Location(name)
+	} else {
+		result["series"] = trace
+	}
+	if stream {
+		result["chunks"] = chunk
+	}
+	return jsonResult(result), nil
+}
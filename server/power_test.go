@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func testPowerChannels() []dwf.AnalogIOChannel {
+	status := &dwf.AnalogIORange{Min: 0, Max: 1, Steps: 4096}
+	return []dwf.AnalogIOChannel{
+		{Index: 0, Name: "Positive Supply", Label: "V+", Nodes: []dwf.AnalogIONode{
+			{Index: 0, Name: "Enable", Set: &dwf.AnalogIORange{Max: 1, Steps: 2}},
+			{Index: 1, Name: "Voltage", Units: "V", Status: status},
+			{Index: 2, Name: "Current", Units: "A", Status: status},
+		}},
+		{Index: 1, Name: "Negative Supply", Label: "V-", Nodes: []dwf.AnalogIONode{
+			{Index: 0, Name: "Voltage", Units: "V", Status: status},
+			{Index: 1, Name: "Current", Units: "A", Status: status},
+		}},
+		{Index: 2, Name: "System", Label: "Temp", Nodes: []dwf.AnalogIONode{
+			{Index: 0, Name: "Temp", Units: "C", Status: status},
+		}},
+	}
+}
+
+func TestPowerRails(t *testing.T) {
+	rails, nodes, err := powerRails(testPowerChannels(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rails) != 2 || rails[0].label != "V+" || rails[1].label != "V-" {
+		t.Fatalf("rails = %+v, want V+ and V-", rails)
+	}
+	if got := nodes[rails[0].current]; got != (dwf.AnalogIONodeRef{Channel: 0, Node: 2}) {
+		t.Errorf("V+ current node = %+v", got)
+	}
+	if got := nodes[rails[1].voltage]; got != (dwf.AnalogIONodeRef{Channel: 1, Node: 0}) {
+		t.Errorf("V- voltage node = %+v", got)
+	}
+
+	if rails, _, err := powerRails(testPowerChannels(), []string{"negative supply"}); err != nil || len(rails) != 1 || rails[0].label != "V-" {
+		t.Errorf("selecting by name: rails %+v, err %v", rails, err)
+	}
+	for _, want := range [][]string{{"Temp"}, {"USB"}} {
+		if _, _, err := powerRails(testPowerChannels(), want); err == nil {
+			t.Errorf("%v: expected error", want)
+		}
+	}
+	if _, _, err := powerRails(testPowerChannels()[2:], nil); err == nil {
+		t.Error("expected an error without current readings")
+	}
+}
+
+func TestTraceStats(t *testing.T) {
+	avg, peak, integral := traceStats([]float64{0, 1, 2}, []float64{1, 3, 2})
+	if avg != 2 || peak != 3 || integral != 4.5 {
+		t.Errorf("got avg %g, peak %g, integral %g; want 2, 3, 4.5", avg, peak, integral)
+	}
+}
+
+func TestHandlePowerProfile(t *testing.T) {
+	s, dev := newTestServer()
+	dev.analogIO.channels = testPowerChannels()
+	dev.analogIO.status = map[dwf.AnalogIONodeRef]float64{
+		{Channel: 0, Node: 1}: 5, {Channel: 0, Node: 2}: 0.1,
+		{Channel: 1, Node: 0}: -5, {Channel: 1, Node: 1}: -0.05,
+	}
+
+	result, _ := s.handlePowerProfile(context.Background(), makeReq(map[string]any{"duration": 0.05, "interval": 0.01}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content[0].(mcp.TextContent).Text)
+	}
+	var got struct {
+		Samples int                        `json:"samples"`
+		Rails   []map[string]any           `json:"rails"`
+		Total   map[string]float64         `json:"total"`
+		Series  map[string]json.RawMessage `json:"series"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Samples < 2 || got.Samples != dev.analogIO.statusCalls {
+		t.Errorf("samples = %d after %d reads", got.Samples, dev.analogIO.statusCalls)
+	}
+	if len(got.Rails) != 2 || got.Rails[0]["avg_power"] != 0.5 || got.Rails[1]["peak_current"] != 0.05 {
+		t.Errorf("unexpected rails: %v", got.Rails)
+	}
+	if math.Abs(got.Total["avg_power"]-0.75) > 1e-9 {
+		t.Errorf("total avg_power = %g, want 0.75", got.Total["avg_power"])
+	}
+	if _, ok := got.Series["V-"]; !ok {
+		t.Errorf("series lacks V-: %v", got.Series)
+	}
+
+	for _, tc := range []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{}, "duration"},
+		{map[string]any{"duration": 1.0, "interval": 0.001}, "interval"},
+		{map[string]any{"duration": 3600.0, "interval": 0.01}, "samples"},
+		{map[string]any{"duration": 1.0, "rails": []any{"VIO"}}, "no AnalogIO channel"},
+	} {
+		result, _ := s.handlePowerProfile(context.Background(), makeReq(tc.args))
+		if !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, tc.want) {
+			t.Errorf("%v: expected an error about %s, got %v", tc.args, tc.want, result.Content)
+		}
+	}
+
+	dev.analogIO.statusErr = errors.New("device lost")
+	result, _ = s.handlePowerProfile(context.Background(), makeReq(map[string]any{"duration": 0.05, "interval": 0.01}))
+	if !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "device lost") {
+		t.Errorf("expected the read error, got %v", result.Content)
+	}
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// logicPreset is a named combination of I/O voltage and pulls for a logic family.
+type logicPreset struct {
+	description string
+	// vio is the I/O supply voltage in Volts.
+	vio float64
+	// vil and vih are the input thresholds the family guarantees at vio.
+	vil, vih float64
+	pull     dwf.PullDirection
+	warning  string
+}
+
+// logicPresets lists the presets accepted by discovery_static_preset.
+var logicPresets = map[string]logicPreset{
+	"3v3-cmos": {
+		description: "3.3 V LVCMOS, unused inputs pulled down",
+		vio:         3.3, vil: 0.8, vih: 2.0,
+		pull: dwf.PullDown,
+	},
+	"1v8-cmos": {
+		description: "1.8 V LVCMOS, unused inputs pulled down",
+		vio:         1.8, vil: 0.63, vih: 1.17,
+		pull: dwf.PullDown,
+	},
+	"5v-ttl-tolerant-warning": {
+		description: "3.3 V outputs towards 5 V TTL logic, no pulls",
+		vio:         3.3, vil: 0.8, vih: 2.0,
+		pull: dwf.PullIdle,
+		warning: "Outputs swing to 3.3 V only: this meets TTL VIH (2.0 V) but not 5 V CMOS VIH (3.5 V). " +
+			"Never connect a 5 V driver to a DIO pin unless the device reference manual rates it 5 V tolerant; " +
+			"use a level shifter otherwise.",
+	},
+}
+
+func logicPresetNames() []string {
+	names := make([]string, 0, len(logicPresets))
+	for name := range logicPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parsePull(name string) (dwf.PullDirection, error) {
+	switch strings.ToLower(name) {
+	case "up":
+		return dwf.PullUp, nil
+	case "down":
+		return dwf.PullDown, nil
+	case "none":
+		return dwf.PullIdle, nil
+	}
+	return 0, fmt.Errorf("invalid pull %q: expected up, down or none", name)
+}
+
+func pullName(p dwf.PullDirection) string {
+	switch p {
+	case dwf.PullUp:
+		return "up"
+	case dwf.PullDown:
+		return "down"
+	}
+	return "none"
+}
+
+func (s *DiscoveryMCPServer) handleStaticPreset(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := getString(req.Params.Arguments, "preset", "")
+	preset, ok := logicPresets[name]
+	if !ok {
+		return errResult(fmt.Errorf("unknown preset %q: expected one of %s", name, strings.Join(logicPresetNames(), ", "))), nil
+	}
+	pull := preset.pull
+	if p := getString(req.Params.Arguments, "pull", ""); p != "" {
+		var err error
+		if pull, err = parsePull(p); err != nil {
+			return errResult(err), nil
+		}
+	}
+
+	static := s.device.Static()
+	levels, err := static.Levels()
+	if err != nil {
+		return errResult(err), nil
+	}
+	// Where the input threshold is adjustable, it goes midway between the
+	// family's VIL and VIH; elsewhere it follows VIO.
+	threshold := (preset.vil + preset.vih) / 2
+	setThreshold := levels.Threshold != nil
+	if setThreshold {
+		if err := checkLevel("threshold", threshold, levels.ThresholdRange); err != nil {
+			return errResult(err), nil
+		}
+	}
+	if err := static.SetVIO(preset.vio); err != nil {
+		return errResult(fmt.Errorf("setting VIO: %w", err)), nil
+	}
+	if setThreshold {
+		if err := static.SetThreshold(threshold); err != nil {
+			return errResult(fmt.Errorf("setting the threshold: %w", err)), nil
+		}
+	}
+	if err := static.SetPull(-1, pull); err != nil {
+		return errResult(fmt.Errorf("setting pulls: %w", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"preset":      name,
+		"description": preset.description,
+		"vio":         preset.vio,
+		"input_thresholds": map[string]interface{}{
+			"vil": preset.vil,
+			"vih": preset.vih,
+		},
+		"pull": pullName(pull),
+	}
+	if setThreshold {
+		result["threshold"] = threshold
+	}
+	if preset.warning != "" {
+		result["warning"] = preset.warning
+	}
+	return jsonResult(result), nil
+}
+
+// checkLevel rejects a VIO or threshold value outside the range the device
+// reports for it.
+func checkLevel(what string, v float64, r *dwf.AnalogIORange) error {
+	if r != nil && (v < r.Min || v > r.Max) {
+		return fmt.Errorf("%s must be between %g and %g V on this device, got %g", what, r.Min, r.Max, v)
+	}
+	return nil
+}
+
+func (s *DiscoveryMCPServer) handleStaticVIO(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	_, setVIO := args["vio"]
+	_, setThreshold := args["threshold"]
+	_, setDrive := args["drive"]
+	vio, threshold := getFloat(args, "vio", 0), getFloat(args, "threshold", 0)
+
+	static := s.device.Static()
+	levels, err := static.Levels()
+	if err != nil {
+		return errResult(err), nil
+	}
+	if levels.VIO == nil && levels.Threshold == nil && levels.Drive == nil {
+		return errResult(fmt.Errorf("neither VIO, the input threshold nor the drive current is adjustable on this device")), nil
+	}
+	if setVIO {
+		if levels.VIO == nil {
+			return errResult(fmt.Errorf("VIO is not adjustable on this device")), nil
+		}
+		if err := checkLevel("vio", vio, levels.VIORange); err != nil {
+			return errResult(err), nil
+		}
+	}
+	if setThreshold {
+		if levels.Threshold == nil {
+			return errResult(fmt.Errorf("the input threshold is not adjustable on this device; it follows VIO")), nil
+		}
+		if err := checkLevel("threshold", threshold, levels.ThresholdRange); err != nil {
+			return errResult(err), nil
+		}
+		limit := levels.VIO
+		if setVIO {
+			limit = &vio
+		}
+		if limit != nil && threshold >= *limit {
+			return errResult(fmt.Errorf("threshold %g V must be below VIO %g V", threshold, *limit)), nil
+		}
+	}
+	if setDrive && levels.Drive == nil {
+		return errResult(fmt.Errorf("the DIO drive current is not adjustable on this device")), nil
+	}
+
+	// The device checks the drive current against the strengths it
+	// supports before it writes anything, so the drive goes first: an
+	// unsupported value fails before VIO or the threshold change.
+	if setDrive {
+		if err := static.SetCurrent(getFloat(args, "drive", 0)); err != nil {
+			return errResult(fmt.Errorf("setting the drive current: %w", err)), nil
+		}
+	}
+	if setVIO {
+		if err := static.SetVIO(vio); err != nil {
+			return errResult(fmt.Errorf("setting VIO: %w", err)), nil
+		}
+	}
+	if setThreshold {
+		if err := static.SetThreshold(threshold); err != nil {
+			return errResult(fmt.Errorf("setting the threshold: %w", err)), nil
+		}
+	}
+	if setVIO || setThreshold || setDrive {
+		if levels, err = static.Levels(); err != nil {
+			return errResult(err), nil
+		}
+	}
+	return jsonResult(levels), nil
+}
+
+func (s *DiscoveryMCPServer) handleStaticSetCurrent(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	if _, ok := args["current"]; !ok {
+		return errResult(fmt.Errorf("current is required")), nil
+	}
+	current := getFloat(args, "current", 0)
+
+	// The device checks the drive current against the strengths it supports.
+	static := s.device.Static()
+	if err := static.SetCurrent(current); err != nil {
+		return errResult(fmt.Errorf("setting the drive current: %w", err)), nil
+	}
+	levels, err := static.Levels()
+	if err != nil {
+		return errResult(err), nil
+	}
+	return jsonResult(map[string]interface{}{
+		"requested": current,
+		"drive":     levels.Drive,
+	}), nil
+}
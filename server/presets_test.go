@@ -0,0 +1,217 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func TestHandleStaticPreset(t *testing.T) {
+	t.Run("1v8 cmos", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, err := s.handleStaticPreset(context.Background(), makeReq(map[string]any{
+			"preset": "1v8-cmos",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := strings.Join(dev.staticIO.calls, ","); got != "vio 1.8,pull -1 0" {
+			t.Errorf("calls = %q, want VIO 1.8 then pull-down on all lines", got)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, `"vih":1.17`) || strings.Contains(text, "warning") {
+			t.Errorf("unexpected result %q", text)
+		}
+	})
+
+	t.Run("adjustable threshold", func(t *testing.T) {
+		s, dev := newTestServer()
+		vio, threshold := 3.3, 1.65
+		dev.staticIO.levels = dwf.DigitalIOLevels{VIO: &vio, Threshold: &threshold, ThresholdRange: &dwf.AnalogIORange{Min: 0.5, Max: 3, Steps: 26}}
+		result, _ := s.handleStaticPreset(context.Background(), makeReq(map[string]any{
+			"preset": "3v3-cmos",
+		}))
+		if got := strings.Join(dev.staticIO.calls, ","); got != "vio 3.3,threshold 1.4,pull -1 0" {
+			t.Errorf("calls = %q, want VIO, the threshold midway between VIL and VIH, then pulls", got)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"threshold":1.4`) {
+			t.Errorf("expected the applied threshold in %q", text)
+		}
+	})
+
+	t.Run("5v warning with pull override", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, _ := s.handleStaticPreset(context.Background(), makeReq(map[string]any{
+			"preset": "5v-ttl-tolerant-warning",
+			"pull":   "up",
+		}))
+		if got := strings.Join(dev.staticIO.calls, ","); got != "vio 3.3,pull -1 1" {
+			t.Errorf("calls = %q, want VIO 3.3 then pull-up on all lines", got)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, `"warning"`) || !strings.Contains(text, `"pull":"up"`) {
+			t.Errorf("expected warning and pull override, got %q", text)
+		}
+	})
+
+	t.Run("unknown preset", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, _ := s.handleStaticPreset(context.Background(), makeReq(map[string]any{
+			"preset": "2v5-cmos",
+		}))
+		if !result.IsError {
+			t.Error("expected error result")
+		}
+		if len(dev.staticIO.calls) != 0 {
+			t.Errorf("expected no device calls, got %v", dev.staticIO.calls)
+		}
+	})
+
+	t.Run("vio not adjustable", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.staticIO.setVIOErr = errors.New("VIO is not adjustable on this device")
+		result, _ := s.handleStaticPreset(context.Background(), makeReq(map[string]any{
+			"preset": "3v3-cmos",
+		}))
+		if !result.IsError {
+			t.Error("expected error result")
+		}
+		if len(dev.staticIO.calls) != 1 {
+			t.Errorf("expected pulls to be left alone after VIO failure, got %v", dev.staticIO.calls)
+		}
+	})
+}
+
+func TestHandleStaticVIO(t *testing.T) {
+	digitalDiscovery := func() dwf.DigitalIOLevels {
+		vio := 3.3
+		return dwf.DigitalIOLevels{VIO: &vio, VIORange: &dwf.AnalogIORange{Min: 1.2, Max: 3.3, Steps: 22}}
+	}
+
+	t.Run("set vio", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.staticIO.levels = digitalDiscovery()
+		result, _ := s.handleStaticVIO(context.Background(), makeReq(map[string]any{"vio": 1.8}))
+		if result.IsError {
+			t.Fatalf("unexpected error: %s", result.Content[0].(mcp.TextContent).Text)
+		}
+		if got := strings.Join(dev.staticIO.calls, ","); got != "vio 1.8" {
+			t.Errorf("calls = %q, want vio 1.8", got)
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"vio":1.8`) {
+			t.Errorf("expected the new VIO in %q", text)
+		}
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.staticIO.levels = digitalDiscovery()
+		result, _ := s.handleStaticVIO(context.Background(), makeReq(nil))
+		if result.IsError || len(dev.staticIO.calls) != 0 {
+			t.Errorf("expected a plain read, got calls %v", dev.staticIO.calls)
+		}
+	})
+
+	for _, tc := range []struct {
+		name   string
+		levels dwf.DigitalIOLevels
+		args   map[string]any
+		want   string
+	}{
+		{"vio out of range", digitalDiscovery(), map[string]any{"vio": 5.0}, "between 1.2 and 3.3"},
+		{"threshold not adjustable", digitalDiscovery(), map[string]any{"threshold": 0.9}, "follows VIO"},
+		{"nothing adjustable", dwf.DigitalIOLevels{}, map[string]any{"vio": 1.8}, "neither"},
+		{"drive not adjustable", digitalDiscovery(), map[string]any{"vio": 1.8, "drive": 4.0}, "drive current is not adjustable"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, dev := newTestServer()
+			dev.staticIO.levels = tc.levels
+			result, _ := s.handleStaticVIO(context.Background(), makeReq(tc.args))
+			if !result.IsError {
+				t.Fatal("expected error result")
+			}
+			if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, tc.want) {
+				t.Errorf("got %q, want %q", text, tc.want)
+			}
+			if len(dev.staticIO.calls) != 0 {
+				t.Errorf("expected no device calls, got %v", dev.staticIO.calls)
+			}
+		})
+	}
+
+	t.Run("drive rejected by the device", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.staticIO.levels = digitalDiscovery()
+		drive := 8.0
+		dev.staticIO.levels.Drive = &drive
+		dev.staticIO.setCurrentErr = errors.New("drive current of 5 mA is not supported by the Digital Discovery; use one of 0, 2, 4, 6, 8, 12, 16 mA")
+		result, _ := s.handleStaticVIO(context.Background(), makeReq(map[string]any{"vio": 1.8, "drive": 5.0}))
+		if !result.IsError {
+			t.Fatal("expected error result")
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "use one of") {
+			t.Errorf("expected the allowed values in %q", text)
+		}
+		if got := strings.Join(dev.staticIO.calls, ","); got != "drive 5" {
+			t.Errorf("calls = %q, want VIO left alone", got)
+		}
+	})
+
+	t.Run("threshold below vio", func(t *testing.T) {
+		s, dev := newTestServer()
+		vio, threshold := 3.3, 1.65
+		dev.staticIO.levels = dwf.DigitalIOLevels{VIO: &vio, Threshold: &threshold}
+		result, _ := s.handleStaticVIO(context.Background(), makeReq(map[string]any{"vio": 1.8, "threshold": 2.0}))
+		if !result.IsError {
+			t.Error("expected a threshold above the new VIO to be rejected")
+		}
+		result, _ = s.handleStaticVIO(context.Background(), makeReq(map[string]any{"vio": 1.8, "threshold": 0.9}))
+		if result.IsError {
+			t.Fatalf("unexpected error: %s", result.Content[0].(mcp.TextContent).Text)
+		}
+		if got := strings.Join(dev.staticIO.calls, ","); got != "vio 1.8,threshold 0.9" {
+			t.Errorf("calls = %q, want vio then threshold", got)
+		}
+	})
+}
+
+func TestHandleStaticSetCurrent(t *testing.T) {
+	s, dev := newTestServer()
+	drive := 0.0
+	dev.staticIO.levels = dwf.DigitalIOLevels{Drive: &drive}
+	result, _ := s.handleStaticSetCurrent(context.Background(), makeReq(map[string]any{"current": 4.0}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.Content[0].(mcp.TextContent).Text)
+	}
+	if got := strings.Join(dev.staticIO.calls, ","); got != "drive 4" {
+		t.Errorf("calls = %q, want drive 4", got)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, `"drive":4`) {
+		t.Errorf("expected the new drive current in %q", text)
+	}
+
+	t.Run("missing current", func(t *testing.T) {
+		s, dev := newTestServer()
+		result, _ := s.handleStaticSetCurrent(context.Background(), makeReq(nil))
+		if !result.IsError || len(dev.staticIO.calls) != 0 {
+			t.Errorf("expected an error without device calls, got %v", dev.staticIO.calls)
+		}
+	})
+
+	t.Run("rejected by the device", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.staticIO.setCurrentErr = errors.New("drive current of 5 mA is not supported by the Digital Discovery; use one of 0, 2, 4, 6, 8, 12, 16 mA")
+		result, _ := s.handleStaticSetCurrent(context.Background(), makeReq(map[string]any{"current": 5.0}))
+		if !result.IsError {
+			t.Fatal("expected error result")
+		}
+		if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "use one of") {
+			t.Errorf("expected the allowed values in %q", text)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// ProbePoint bundles a physical connection with its measurement method and
+// scaling, so a test plan can refer to "VOUT" instead of "scope channel 1
+// behind a 10x probe".
+type ProbePoint struct {
+	// Name identifies the probe point, e.g. "VOUT".
+	Name string `json:"name"`
+	// Instrument is "scope" (default) or "dmm".
+	Instrument string `json:"instrument,omitempty"`
+	// Channel is the oscilloscope channel (1-based); unused for the DMM.
+	Channel int `json:"channel,omitempty"`
+	// Attenuation is the probe factor the reading is multiplied by, e.g. 10
+	// for a 10x probe. 0 means 1.
+	Attenuation float64 `json:"attenuation,omitempty"`
+	// Coupling is "dc" or "ac" and is applied before each scope reading;
+	// empty keeps the current setting.
+	Coupling string `json:"coupling,omitempty"`
+	// Shunt is the resistance in Ohms the channel measures across. When set
+	// the reading is converted to a current in Amperes.
+	Shunt float64 `json:"shunt,omitempty"`
+	// Measure is the scope statistic (sample, mean, min, max, rms, pp;
+	// default mean) or the DMM quantity (dc_voltage, ac_voltage, dc_current,
+	// ac_current, resistance, temperature; default dc_voltage).
+	Measure string `json:"measure,omitempty"`
+	// Description is a free-form note, e.g. "buck output after LC filter".
+	Description string `json:"description,omitempty"`
+}
+
+// dmmUnits gives the unit of each DMM probe quantity.
+var dmmUnits = map[string]string{
+	"ac_voltage":  "V",
+	"dc_voltage":  "V",
+	"ac_current":  "A",
+	"dc_current":  "A",
+	"resistance":  "Ohm",
+	"temperature": "C",
+}
+
+// normalize fills in defaults and checks the probe point for consistency.
+func (p *ProbePoint) normalize() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("probe name is required")
+	}
+	p.Instrument = strings.ToLower(p.Instrument)
+	p.Coupling = strings.ToLower(p.Coupling)
+	p.Measure = strings.ToLower(p.Measure)
+	if p.Attenuation == 0 {
+		p.Attenuation = 1
+	}
+	if p.Attenuation < 0 {
+		return fmt.Errorf("probe %q: attenuation must be positive", p.Name)
+	}
+	if p.Shunt < 0 {
+		return fmt.Errorf("probe %q: shunt must be positive", p.Name)
+	}
+	switch p.Instrument {
+	case "", "scope":
+		p.Instrument = "scope"
+		if p.Channel < 1 {
+			return fmt.Errorf("probe %q: scope channel is required", p.Name)
+		}
+		if p.Coupling != "" {
+			if _, err := parseCoupling(p.Coupling); err != nil {
+				return fmt.Errorf("probe %q: %w", p.Name, err)
+			}
+		}
+		if p.Measure == "" {
+			p.Measure = "mean"
+		}
+		if p.Measure != "sample" && !scopeWatchStats[p.Measure] {
+			return fmt.Errorf("probe %q: unknown statistic %q (expected sample, mean, min, max, rms or pp)", p.Name, p.Measure)
+		}
+	case "dmm":
+		if p.Coupling != "" || p.Shunt != 0 {
+			return fmt.Errorf("probe %q: coupling and shunt apply to scope probes only", p.Name)
+		}
+		if p.Measure == "" {
+			p.Measure = "dc_voltage"
+		}
+		if _, ok := dmmWatchModes[p.Measure]; !ok {
+			return fmt.Errorf("probe %q: unknown DMM quantity %q", p.Name, p.Measure)
+		}
+	default:
+		return fmt.Errorf("probe %q: unknown instrument %q (expected scope or dmm)", p.Name, p.Instrument)
+	}
+	return nil
+}
+
+// unit returns the unit of the scaled reading for a statistic or quantity.
+func (p *ProbePoint) unit(measure string) string {
+	if p.Instrument == "dmm" {
+		return dmmUnits[measure]
+	}
+	if p.Shunt > 0 {
+		return "A"
+	}
+	return "V"
+}
+
+// read takes one reading of a statistic or quantity and applies the probe
+// scaling.
+func (p *ProbePoint) read(ctx context.Context, dev dwf.DiscoveryDevice, measure string) (float64, error) {
+	if p.Instrument == "dmm" {
+		mode, ok := dmmWatchModes[measure]
+		if !ok {
+			return 0, fmt.Errorf("unknown DMM quantity %q", measure)
+		}
+		r, err := dev.DMM().Measure(mode, 0, false)
+		return r.Value * p.Attenuation, err
+	}
+
+	scope := dev.Scope()
+	if p.Coupling != "" {
+		coupling, _ := parseCoupling(p.Coupling)
+		if err := scope.SetCoupling(p.Channel, coupling); err != nil {
+			return 0, err
+		}
+	}
+	var v float64
+	var err error
+	if measure == "sample" {
+		v, err = scope.Measure(p.Channel)
+	} else if !scopeWatchStats[measure] {
+		return 0, fmt.Errorf("unknown statistic %q (expected sample, mean, min, max, rms or pp)", measure)
+	} else {
+		var data []float64
+		if data, err = scope.Record(ctx, p.Channel); err == nil {
+			v, err = sampleStat(data, measure)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	v *= p.Attenuation
+	if p.Shunt > 0 {
+		v /= p.Shunt
+	}
+	return v, nil
+}
+
+// probeSet holds the probe points from the configuration file and those
+// defined by the client.
+type probeSet struct {
+	mu     sync.Mutex
+	probes map[string]ProbePoint
+}
+
+func newProbeSet() *probeSet {
+	return &probeSet{probes: map[string]ProbePoint{}}
+}
+
+// define adds or replaces a probe point.
+func (ps *probeSet) define(p ProbePoint) error {
+	if err := p.normalize(); err != nil {
+		return err
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.probes[p.Name] = p
+	return nil
+}
+
+func (ps *probeSet) get(name string) (ProbePoint, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p, ok := ps.probes[name]
+	return p, ok
+}
+
+// list returns all probe points sorted by name.
+func (ps *probeSet) list() []ProbePoint {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	out := make([]ProbePoint, 0, len(ps.probes))
+	for _, p := range ps.probes {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (s *DiscoveryMCPServer) handleProbeDefine(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	p := ProbePoint{
+		Name:        getString(req.Params.Arguments, "name", ""),
+		Instrument:  getString(req.Params.Arguments, "instrument", "scope"),
+		Channel:     getInt(req.Params.Arguments, "channel", 0),
+		Attenuation: getFloat(req.Params.Arguments, "attenuation", 1),
+		Coupling:    getString(req.Params.Arguments, "coupling", ""),
+		Shunt:       getFloat(req.Params.Arguments, "shunt", 0),
+		Measure:     getString(req.Params.Arguments, "measure", ""),
+		Description: getString(req.Params.Arguments, "description", ""),
+	}
+	if err := s.probes.define(p); err != nil {
+		return errResult(err), nil
+	}
+	p, _ = s.probes.get(strings.TrimSpace(p.Name))
+	return jsonResult(p), nil
+}
+
+func (s *DiscoveryMCPServer) handleProbeList(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return jsonResult(map[string]interface{}{
+		"probes": s.probes.list(),
+	}), nil
+}
+
+func (s *DiscoveryMCPServer) handleProbeMeasure(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := getString(req.Params.Arguments, "name", "")
+	p, ok := s.probes.get(name)
+	if !ok {
+		return errResult(fmt.Errorf("no probe point named %q; define it with discovery_probe_define or in the config file", name)), nil
+	}
+	measure := strings.ToLower(getString(req.Params.Arguments, "measure", p.Measure))
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+	v, err := p.read(ctx, s.device, measure)
+	if err != nil {
+		return errResult(fmt.Errorf("probe %q: %w", name, acquisitionError(err))), nil
+	}
+	if p.Instrument == "scope" && measure != "sample" {
+		s.usage.captured("scope", 1)
+	}
+	result := map[string]interface{}{
+		"name":       p.Name,
+		"value":      v,
+		"unit":       p.unit(measure),
+		"measure":    measure,
+		"instrument": p.Instrument,
+	}
+	if p.Instrument == "scope" {
+		result["channel"] = p.Channel
+	}
+	return jsonResult(result), nil
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func TestProbePointNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		probe   ProbePoint
+		wantErr bool
+	}{
+		{"scope defaults", ProbePoint{Name: "VOUT", Channel: 1}, false},
+		{"dmm defaults", ProbePoint{Name: "VBAT", Instrument: "dmm"}, false},
+		{"missing name", ProbePoint{Channel: 1}, true},
+		{"missing channel", ProbePoint{Name: "VOUT"}, true},
+		{"bad statistic", ProbePoint{Name: "VOUT", Channel: 1, Measure: "median"}, true},
+		{"bad coupling", ProbePoint{Name: "VOUT", Channel: 1, Coupling: "gnd"}, true},
+		{"dmm shunt", ProbePoint{Name: "I", Instrument: "dmm", Shunt: 0.1}, true},
+		{"bad instrument", ProbePoint{Name: "X", Instrument: "logic"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.probe.normalize()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("normalize error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleProbeMeasure(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.recordData = []float64{0.01, 0.03}
+	dev.scope.measureVal = 0.5
+
+	for _, args := range []map[string]any{
+		{"name": "VOUT", "channel": float64(1), "attenuation": float64(10), "coupling": "dc"},
+		{"name": "IIN", "channel": float64(2), "shunt": 0.1},
+	} {
+		result, _ := s.handleProbeDefine(context.Background(), makeReq(args))
+		if result.IsError {
+			t.Fatalf("define %v: %v", args["name"], result.Content)
+		}
+	}
+
+	measure := func(args map[string]any) map[string]any {
+		t.Helper()
+		result, err := s.handleProbeMeasure(context.Background(), makeReq(args))
+		if err != nil || result.IsError {
+			t.Fatalf("measure %v: %v %v", args, err, result.Content)
+		}
+		var got map[string]any
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return got
+	}
+
+	got := measure(map[string]any{"name": "VOUT"})
+	if math.Abs(got["value"].(float64)-0.2) > 1e-9 || got["unit"] != "V" || got["measure"] != "mean" {
+		t.Errorf("VOUT = %v, want mean 0.02 V scaled by 10", got)
+	}
+	if dev.scope.coupling[1] != dwf.CouplingDC {
+		t.Errorf("coupling not applied: %v", dev.scope.coupling)
+	}
+
+	got = measure(map[string]any{"name": "IIN", "measure": "sample"})
+	if math.Abs(got["value"].(float64)-5) > 1e-9 || got["unit"] != "A" || got["channel"] != float64(2) {
+		t.Errorf("IIN = %v, want 0.5 V across 0.1 Ohm = 5 A", got)
+	}
+
+	result, _ := s.handleProbeMeasure(context.Background(), makeReq(map[string]any{"name": "VIN"}))
+	if !result.IsError {
+		t.Error("expected error for undefined probe point")
+	}
+}
+
+func TestConfigProbes(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `{"probes":[{"name":"VBAT","instrument":"dmm"}]}`))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	s, dev := newTestServer()
+	dev.dmm.measureVal = 3.7
+	if err := s.SetConfig(cfg); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+
+	result, _ := s.handleProbeMeasure(context.Background(), makeReq(map[string]any{"name": "VBAT"}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Value   float64 `json:"value"`
+		Measure string  `json:"measure"`
+	}
+	json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got)
+	if got.Value != 3.7 || got.Measure != "dc_voltage" {
+		t.Errorf("unexpected reading %+v", got)
+	}
+
+	if _, err := LoadConfig(writeConfig(t, `{"probes":[{"name":"A","channel":1},{"name":"A","channel":2}]}`)); err == nil {
+		t.Error("expected error for duplicate probe names")
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// quickScopeConfig is the oscilloscope setup of a quick voltage check when
+// the oscilloscope is not open: 10 ms at 100 kHz on the widest range, so
+// the mean rejects noise and a rail above 5 V does not clip.
+var quickScopeConfig = dwf.ScopeConfig{
+	SamplingFrequency: 100e3,
+	BufferSize:        1000,
+	AmplitudeRange:    50,
+}
+
+// quickVoltage reads the DC voltage of a scope channel. An open
+// oscilloscope is left as configured and read with a single sample;
+// otherwise it is set up for the reading and reset afterwards.
+func (s *DiscoveryMCPServer) quickVoltage(ctx context.Context, channel int, result map[string]interface{}) (float64, error) {
+	scope := s.device.Scope()
+	if scope.SampleRate() > 0 {
+		result["method"] = "sample"
+		return scope.Measure(channel)
+	}
+	if _, err := scope.Open(quickScopeConfig); err != nil {
+		return 0, err
+	}
+	defer scope.Close()
+	result["method"] = "mean"
+	result["restored"] = true
+	data, err := scope.Record(ctx, channel)
+	if err != nil {
+		return 0, acquisitionError(err)
+	}
+	s.usage.captured("scope", 1)
+	return sampleStat(data, "mean")
+}
+
+func (s *DiscoveryMCPServer) handleQuickMeasure(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	check := strings.ToLower(getString(req.Params.Arguments, "check", ""))
+	result := map[string]interface{}{"check": check}
+	var value float64
+	var unit string
+	var err error
+	switch check {
+	case "voltage":
+		ch := getInt(req.Params.Arguments, "channel", 1)
+		var cancel context.CancelFunc
+		if ctx, cancel, err = acquisitionContext(ctx, req.Params.Arguments, 1); err != nil {
+			return errResult(err), nil
+		}
+		defer cancel()
+		result["channel"] = ch
+		value, err = s.quickVoltage(ctx, ch, result)
+		unit = "V"
+	case "dio":
+		ch := getInt(req.Params.Arguments, "channel", 0)
+		var high bool
+		high, err = s.device.Static().GetState(ch)
+		result["channel"] = ch
+		result["level"] = "low"
+		if high {
+			value, result["level"] = 1, "high"
+		}
+	case "temperature":
+		value, err = s.device.Temperature()
+		unit = "°C"
+	default:
+		return errResult(fmt.Errorf("unknown check %q (expected voltage, dio or temperature)", check)), nil
+	}
+	if err != nil {
+		return errResult(fmt.Errorf("quick %s check: %w", check, err)), nil
+	}
+	result["value"] = value
+	if unit != "" {
+		result["unit"] = unit
+	}
+	return jsonResult(result), nil
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestHandleQuickMeasure(t *testing.T) {
+	s, dev := newTestServer()
+	measure := func(args map[string]any) map[string]any {
+		t.Helper()
+		result, _ := s.handleQuickMeasure(context.Background(), makeReq(args))
+		if result.IsError {
+			t.Fatalf("unexpected error: %v", result.Content)
+		}
+		var got map[string]any
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		return got
+	}
+
+	// A closed oscilloscope is configured for the reading and reset.
+	dev.scope.recordData = []float64{3.2, 3.4, 3.3}
+	got := measure(map[string]any{"check": "voltage", "channel": float64(2)})
+	if v := got["value"].(float64); v < 3.299 || v > 3.301 || got["method"] != "mean" || got["restored"] != true {
+		t.Errorf("unexpected closed-scope result %v", got)
+	}
+	if dev.scope.openCfg.AmplitudeRange != quickScopeConfig.AmplitudeRange || dev.scope.closed != 1 {
+		t.Errorf("scope opened with %+v and closed %d times", dev.scope.openCfg, dev.scope.closed)
+	}
+
+	// An open oscilloscope is read as configured and left open.
+	dev.scope.sampleRate = 1e6
+	dev.scope.measureVal = 1.8
+	got = measure(map[string]any{"check": "voltage"})
+	if got["value"] != 1.8 || got["method"] != "sample" || got["restored"] != nil || dev.scope.closed != 1 {
+		t.Errorf("unexpected open-scope result %v (closed %d times)", got, dev.scope.closed)
+	}
+
+	dev.staticIO.getStateVal = true
+	if got = measure(map[string]any{"check": "dio", "channel": float64(3)}); got["level"] != "high" || got["value"] != 1.0 {
+		t.Errorf("unexpected dio result %v", got)
+	}
+	dev.temperature = 41.5
+	if got = measure(map[string]any{"check": "temperature"}); got["value"] != 41.5 || got["unit"] != "°C" {
+		t.Errorf("unexpected temperature result %v", got)
+	}
+
+	if result, _ := s.handleQuickMeasure(context.Background(), makeReq(map[string]any{"check": "current"})); !result.IsError {
+		t.Error("expected error for unknown check")
+	}
+	dev.scope.sampleRate = 0
+	dev.scope.recordErr = errors.New("boom")
+	if result, _ := s.handleQuickMeasure(context.Background(), makeReq(map[string]any{"check": "voltage"})); !result.IsError || dev.scope.closed != 2 {
+		t.Errorf("expected error result and reset scope, closed %d times", dev.scope.closed)
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// maxRateProbes bounds the rates a max-rate search tries, in case the
+// resolution is too fine for the range.
+const maxRateProbes = 40
+
+// maskedErrors counts the bytes of got that differ from want in the bits
+// set in mask, including missing bytes. A nil mask compares all bits.
+func maskedErrors(want, mask, got []byte) int {
+	errs := max(len(want)-len(got), 0)
+	for i := range min(len(want), len(got)) {
+		m := byte(0xFF)
+		if mask != nil {
+			m = mask[i]
+		}
+		if (want[i]^got[i])&m != 0 {
+			errs++
+		}
+	}
+	return errs
+}
+
+// searchMaxRate finds the boundary between the rates at which run passes
+// reliably and those at which it does not, assuming that a transfer which
+// fails at some rate also fails at every higher one. It bisects [lo, hi]
+// geometrically until the rates are within a factor of 1+resolution and
+// returns the highest passing and lowest failing rate, 0 when there is
+// none, with the rates it tried in order. round adjusts a rate to one the
+// protocol can use.
+func searchMaxRate(ctx context.Context, lo, hi, resolution float64, runs int, round func(float64) float64, run func(rate float64) (int, error)) (pass, fail float64, tried []selfTestRate, err error) {
+	test := func(rate float64) (bool, error) {
+		r, err := runRate(ctx, rate, runs, run)
+		tried = append(tried, r)
+		return r.reliable(), err
+	}
+	ok, err := test(lo)
+	if err != nil || !ok {
+		return 0, lo, tried, err
+	}
+	if ok, err = test(hi); err != nil || ok {
+		return hi, 0, tried, err
+	}
+	pass, fail = lo, hi
+	for fail/pass > 1+resolution && len(tried) < maxRateProbes {
+		mid := round(math.Sqrt(pass * fail))
+		if mid <= pass || mid >= fail {
+			break
+		}
+		ok, err := test(mid)
+		if err != nil {
+			return pass, fail, tried, err
+		}
+		if ok {
+			pass = mid
+		} else {
+			fail = mid
+		}
+	}
+	return pass, fail, tried, nil
+}
+
+// parseHexArg decodes a hex string argument, ignoring spaces.
+func parseHexArg(args map[string]any, key string) ([]byte, error) {
+	data, err := hex.DecodeString(strings.ReplaceAll(getString(args, key, ""), " ", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex in %s: %w", key, err)
+	}
+	return data, nil
+}
+
+func (s *DiscoveryMCPServer) handleProtocolMaxRate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	protocol := strings.ToLower(getString(args, "protocol", ""))
+	runs := getInt(args, "repeats", 5)
+	if runs < 1 || runs > 100 {
+		return errResult(fmt.Errorf("repeats must be between 1 and 100")), nil
+	}
+	resolution := getFloat(args, "resolution", 0.05)
+	if resolution < 0.001 || resolution > 1 {
+		return errResult(fmt.Errorf("resolution must be between 0.001 and 1, got %g", resolution)), nil
+	}
+	data, err := parseHexArg(args, "data")
+	if err != nil {
+		return errResult(err), nil
+	}
+	expect, err := parseHexArg(args, "expect")
+	if err != nil {
+		return errResult(err), nil
+	}
+	if len(data) == 0 || len(expect) == 0 {
+		return errResult(fmt.Errorf("data and expect are required")), nil
+	}
+	var mask []byte
+	if _, ok := args["mask"]; ok {
+		if mask, err = parseHexArg(args, "mask"); err != nil {
+			return errResult(err), nil
+		}
+		if len(mask) != len(expect) {
+			return errResult(fmt.Errorf("mask must be as long as expect")), nil
+		}
+	}
+
+	var lo, hi float64
+	var run func(rate float64) (int, error)
+	var reset func() error
+	round := func(rate float64) float64 { return rate }
+	switch protocol {
+	case "spi":
+		cfg := dwf.SPIConfig{
+			CS:       getInt(args, "cs", 0),
+			SCK:      getInt(args, "sck", 1),
+			MOSI:     getInt(args, "mosi", 2),
+			MISO:     getInt(args, "miso", 3),
+			Mode:     getInt(args, "mode", 0),
+			MSBFirst: getBool(args, "msb_first", true),
+		}
+		if len(expect) > len(data) {
+			return errResult(fmt.Errorf("SPI expect cannot be longer than data, as one byte is clocked in per byte sent")), nil
+		}
+		lo, hi = getFloat(args, "min_rate", 100e3), getFloat(args, "max_rate", 50e6)
+		run = func(rate float64) (int, error) {
+			cfg.ClockFrequency = rate
+			spi := s.device.SPIProtocol()
+			if err := spi.Open(cfg); err != nil {
+				return 0, err
+			}
+			got, err := spi.Exchange(data, len(data), cfg.CS)
+			if err != nil {
+				return 0, err
+			}
+			return maskedErrors(expect, mask, got), nil
+		}
+		reset = s.device.SPIProtocol().Close
+	case "uart":
+		cfg := dwf.UARTConfig{
+			RX:       getInt(args, "rx", 0),
+			TX:       getInt(args, "tx", 1),
+			DataBits: 8,
+			StopBits: 1,
+		}
+		if cfg.RX == cfg.TX {
+			return errResult(fmt.Errorf("rx and tx must be different DIO lines")), nil
+		}
+		lo, hi = getFloat(args, "min_rate", 9600), getFloat(args, "max_rate", 3e6)
+		round = math.Round
+		run = func(rate float64) (int, error) {
+			cfg.BaudRate = int(rate)
+			got, err := s.uartTransfer(ctx, cfg, data, len(expect))
+			return maskedErrors(expect, mask, got), err
+		}
+		reset = s.device.UARTProtocol().Close
+	default:
+		return errResult(fmt.Errorf("unknown protocol %q (expected spi or uart)", protocol)), nil
+	}
+	if lo <= 0 || hi <= lo {
+		return errResult(fmt.Errorf("need 0 < min_rate < max_rate, got %g and %g", lo, hi)), nil
+	}
+
+	pass, fail, tried, err := searchMaxRate(ctx, lo, hi, resolution, runs, round, run)
+	if err != nil {
+		_ = reset()
+		return errResult(fmt.Errorf("search aborted: %w", err)), nil
+	}
+	if err := reset(); err != nil {
+		return errResult(err), nil
+	}
+	result := map[string]interface{}{
+		"protocol":         protocol,
+		"repeats":          runs,
+		"max_passing_rate": pass,
+		"min_failing_rate": fail,
+		"probes":           tried,
+	}
+	switch {
+	case pass == 0:
+		result["note"] = "the transaction failed at min_rate; check the wiring, the expected response and min_rate"
+	case fail == 0:
+		result["note"] = "the transaction passed at max_rate; the limit is higher"
+	default:
+		result["margin"] = fail/pass - 1
+	}
+	if i := slices.IndexFunc(tried, func(r selfTestRate) bool { return r.Error != "" }); i >= 0 {
+		result["instrument_error"] = tried[i].Error
+	}
+	return jsonResult(result), nil
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSearchMaxRate(t *testing.T) {
+	limit := 7.3e6
+	run := func(rate float64) (int, error) {
+		if rate > limit {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	identity := func(r float64) float64 { return r }
+	pass, fail, tried, err := searchMaxRate(context.Background(), 1e5, 5e7, 0.01, 2, identity, run)
+	if err != nil || pass > limit || fail <= limit || fail/pass > 1.01 {
+		t.Errorf("boundary %g..%g (%v), want around %g", pass, fail, err, limit)
+	}
+	if len(tried) > 20 || tried[0].Rate != 1e5 || tried[1].Rate != 5e7 {
+		t.Errorf("tried %d rates starting %v", len(tried), tried[:2])
+	}
+
+	if pass, fail, _, _ = searchMaxRate(context.Background(), 1e8, 2e8, 0.01, 2, identity, run); pass != 0 || fail != 1e8 {
+		t.Errorf("failing range gave %g..%g", pass, fail)
+	}
+	if pass, fail, _, _ = searchMaxRate(context.Background(), 1e5, 1e6, 0.01, 2, identity, run); pass != 1e6 || fail != 0 {
+		t.Errorf("passing range gave %g..%g", pass, fail)
+	}
+}
+
+func TestMaskedErrors(t *testing.T) {
+	if n := maskedErrors([]byte{0x00, 0xEF, 0x40}, []byte{0x00, 0xFF, 0xF0}, []byte{0x5A, 0xEF, 0x4F}); n != 0 {
+		t.Errorf("masked compare found %d errors", n)
+	}
+	if n := maskedErrors([]byte{0x01, 0x02}, nil, []byte{0x01}); n != 1 {
+		t.Errorf("short response gave %d errors, want 1", n)
+	}
+}
+
+func TestHandleProtocolMaxRate(t *testing.T) {
+	s, dev := newTestServer()
+	dev.spi.loopbackRate = 12e6
+	result, _ := s.handleProtocolMaxRate(context.Background(), makeReq(map[string]any{
+		"protocol": "spi", "data": "9F 00 00", "expect": "9F0000", "resolution": 0.02,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Pass   float64        `json:"max_passing_rate"`
+		Fail   float64        `json:"min_failing_rate"`
+		Probes []selfTestRate `json:"probes"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Pass > 12e6 || got.Fail <= 12e6 || got.Fail/got.Pass > 1.02 || got.Probes[0].Runs != 5 {
+		t.Errorf("unexpected result %+v", got)
+	}
+
+	dev.uart.loopbackRate = 115200
+	result, _ = s.handleProtocolMaxRate(context.Background(), makeReq(map[string]any{
+		"protocol": "uart", "data": "55AA", "expect": "55AA", "repeats": float64(1),
+	}))
+	json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got)
+	if result.IsError || got.Pass > 115200 || got.Fail <= 115200 || got.Pass != float64(int(got.Pass)) {
+		t.Errorf("unexpected UART result %+v", got)
+	}
+
+	for _, args := range []map[string]any{
+		{"protocol": "i2c", "data": "00", "expect": "00"},
+		{"protocol": "spi", "data": "zz", "expect": "00"},
+		{"protocol": "spi", "data": "00", "expect": "0000"},
+		{"protocol": "spi", "data": "00", "expect": "00", "mask": "FFFF"},
+		{"protocol": "spi", "data": "00", "expect": "00", "min_rate": 1e6, "max_rate": 1e5},
+	} {
+		if result, _ := s.handleProtocolMaxRate(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
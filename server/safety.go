@@ -0,0 +1,149 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// SafetyLimits caps the voltages the tools may apply, so that a mistaken
+// request is rejected before it reaches the device under test. A zero field
+// sets no limit.
+type SafetyLimits struct {
+	// WavegenAmplitude is the largest wavegen amplitude in Volts.
+	WavegenAmplitude float64 `json:"wavegen_amplitude,omitempty"`
+	// WavegenOffset is the largest wavegen offset in Volts, either polarity.
+	WavegenOffset float64 `json:"wavegen_offset,omitempty"`
+	// WavegenPeak is the largest voltage the wavegen may output, either
+	// polarity, counting offset, amplitude and modulation.
+	WavegenPeak float64 `json:"wavegen_peak,omitempty"`
+	// SupplyVoltage is the largest voltage of any supply rail, either
+	// polarity.
+	SupplyVoltage float64 `json:"supply_voltage,omitempty"`
+}
+
+// ParseSafetyLimits parses the --safety flag, a comma-separated list of
+// key=value pairs in Volts: wavegen_amplitude, wavegen_offset, wavegen_peak
+// and supply_voltage. For example:
+//
+//	wavegen_amplitude=2,wavegen_offset=1,supply_voltage=3.3
+func ParseSafetyLimits(spec string) (*SafetyLimits, error) {
+	l := &SafetyLimits{}
+	fields := map[string]*float64{
+		"wavegen_amplitude": &l.WavegenAmplitude,
+		"wavegen_offset":    &l.WavegenOffset,
+		"wavegen_peak":      &l.WavegenPeak,
+		"supply_voltage":    &l.SupplyVoltage,
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid safety limit %q: expected key=value", item)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		dst, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown safety limit %q", key)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", key, value)
+		}
+		*dst = v
+	}
+	return l, l.validate()
+}
+
+func (l *SafetyLimits) validate() error {
+	for _, f := range []struct {
+		name  string
+		value float64
+	}{
+		{"wavegen_amplitude", l.WavegenAmplitude},
+		{"wavegen_offset", l.WavegenOffset},
+		{"wavegen_peak", l.WavegenPeak},
+		{"supply_voltage", l.SupplyVoltage},
+	} {
+		if f.value < 0 || math.IsNaN(f.value) || math.IsInf(f.value, 0) {
+			return fmt.Errorf("%s must be a positive voltage, got %g", f.name, f.value)
+		}
+	}
+	return nil
+}
+
+// exceeds reports an error if v, in either polarity, is above a non-zero
+// limit.
+func exceeds(what string, v, limit float64) error {
+	if limit > 0 && math.Abs(v) > limit {
+		return fmt.Errorf("safety limit: %s of %g V exceeds the configured %g V", what, v, limit)
+	}
+	return nil
+}
+
+// checkWavegen rejects a wavegen output above the limits. peak is the
+// largest voltage the output reaches.
+func (l *SafetyLimits) checkWavegen(amplitude, offset, peak float64) error {
+	if l == nil {
+		return nil
+	}
+	if err := exceeds("amplitude", amplitude, l.WavegenAmplitude); err != nil {
+		return err
+	}
+	if err := exceeds("offset", offset, l.WavegenOffset); err != nil {
+		return err
+	}
+	return exceeds("peak output", peak, l.WavegenPeak)
+}
+
+// checkWavegenConfig rejects a generated waveform above the limits. AM can
+// raise the peak by its depth.
+func (l *SafetyLimits) checkWavegenConfig(cfg dwf.WavegenConfig) error {
+	amplitude := cfg.Amplitude
+	if cfg.Function == dwf.FuncDC {
+		amplitude = 0
+	}
+	peak := amplitude
+	if cfg.AM != nil {
+		peak *= 1 + cfg.AM.Depth/100
+	}
+	return l.checkWavegen(amplitude, cfg.Offset, math.Abs(cfg.Offset)+math.Abs(peak))
+}
+
+// checkSupplies rejects supply voltages above the limit. Rails that stay
+// off are not checked.
+func (l *SafetyLimits) checkSupplies(cfg dwf.SuppliesConfig) error {
+	if l == nil {
+		return nil
+	}
+	rails := []struct {
+		name    string
+		on      bool
+		voltage float64
+	}{
+		{"positive supply", cfg.PositiveState, cfg.PositiveVoltage},
+		{"negative supply", cfg.NegativeState, cfg.NegativeVoltage},
+		{"supply", cfg.State, cfg.Voltage},
+	}
+	for _, r := range rails {
+		if !r.on {
+			continue
+		}
+		if err := exceeds(r.name, r.voltage, l.SupplyVoltage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetSafetyLimits sets the voltage limits, replacing any safety section of
+// the configuration file.
+func (s *DiscoveryMCPServer) SetSafetyLimits(l *SafetyLimits) {
+	s.safety = l
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func TestParseSafetyLimits(t *testing.T) {
+	l, err := ParseSafetyLimits("wavegen_amplitude=2, wavegen_offset=1, wavegen_peak=2.5, supply_voltage=3.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := SafetyLimits{WavegenAmplitude: 2, WavegenOffset: 1, WavegenPeak: 2.5, SupplyVoltage: 3.3}
+	if *l != want {
+		t.Errorf("got %+v, want %+v", *l, want)
+	}
+
+	for _, bad := range []string{"wavegen_amplitude", "current=1", "supply_voltage=high", "wavegen_peak=-1"} {
+		if _, err := ParseSafetyLimits(bad); err == nil {
+			t.Errorf("ParseSafetyLimits(%q): expected error", bad)
+		}
+	}
+}
+
+func TestSafetyLimitsWavegen(t *testing.T) {
+	s, dev := newTestServer()
+	s.SetSafetyLimits(&SafetyLimits{WavegenAmplitude: 2, WavegenOffset: 1, WavegenPeak: 2.5})
+
+	for _, tc := range []struct {
+		args map[string]any
+		ok   bool
+	}{
+		{map[string]any{"amplitude": 2.0, "offset": 0.5}, true},
+		{map[string]any{"amplitude": 25.0}, false},
+		{map[string]any{"amplitude": 1.0, "offset": -1.5}, false},
+		{map[string]any{"amplitude": 2.0, "offset": 1.0}, false},
+		{map[string]any{"amplitude": 2.0, "am_frequency": 100.0, "am_depth": 50.0}, false},
+		{map[string]any{"function": float64(dwf.FuncDC), "amplitude": 5.0, "offset": 1.0}, true},
+	} {
+		args := map[string]any{"channel": float64(1), "function": float64(1)}
+		for k, v := range tc.args {
+			args[k] = v
+		}
+		dev.wavegen.generateCfg = dwf.WavegenConfig{}
+		result, _ := s.handleWavegenGenerate(context.Background(), makeReq(args))
+		if result.IsError == tc.ok {
+			t.Errorf("%v: IsError = %v, want %v", tc.args, result.IsError, !tc.ok)
+		}
+		if !tc.ok {
+			if dev.wavegen.generateCfg.Channel != 0 {
+				t.Errorf("%v: rejected request reached the device", tc.args)
+			}
+			if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "safety limit") {
+				t.Errorf("%v: expected a safety limit error, got %q", tc.args, text)
+			}
+		}
+	}
+
+	result, _ := s.handleWavegenExpression(context.Background(), makeReq(map[string]any{
+		"channel":    float64(1),
+		"expression": "3*sin(2*pi*x)",
+	}))
+	if !result.IsError {
+		t.Error("expression: expected the 3 V amplitude to be rejected")
+	}
+}
+
+func TestSafetyLimitsSupplies(t *testing.T) {
+	s, dev := newTestServer()
+	s.SetSafetyLimits(&SafetyLimits{SupplyVoltage: 5})
+
+	result, _ := s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{
+		"master_state":     true,
+		"positive_state":   true,
+		"positive_voltage": 5.0,
+		"negative_voltage": -12.0,
+	}))
+	if result.IsError {
+		t.Fatalf("a disabled rail should not be checked: %v", result.Content)
+	}
+	if dev.supply.switchCfg.PositiveVoltage != 5 {
+		t.Errorf("expected the supplies to be switched, got %+v", dev.supply.switchCfg)
+	}
+
+	dev.supply.switchCfg = dwf.SuppliesConfig{}
+	result, _ = s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{
+		"master_state":     true,
+		"negative_state":   true,
+		"negative_voltage": -12.0,
+	}))
+	if !result.IsError {
+		t.Error("expected -12 V to exceed the 5 V limit")
+	}
+	if dev.supply.switchCfg.NegativeState {
+		t.Error("rejected request reached the device")
+	}
+}
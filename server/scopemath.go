@@ -0,0 +1,329 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// mathFunctions are the functions a scope math or waveform expression may
+// call.
+var mathFunctions = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"log":   math.Log,
+	"log10": math.Log10,
+	"exp":   math.Exp,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"floor": math.Floor,
+	"sign": func(x float64) float64 {
+		switch {
+		case x > 0:
+			return 1
+		case x < 0:
+			return -1
+		}
+		return x
+	},
+}
+
+// mathExpr is a compiled scope math expression such as "C1-C2" or
+// "(C1-C2)/0.1*C2". Channels are referenced as C1, C2, ... (1-based).
+type mathExpr struct {
+	// channels lists the referenced channels in ascending order.
+	channels []int
+	// eval computes the expression from the values of one sample, indexed
+	// by channel number.
+	eval func(ch []float64) float64
+}
+
+// parseMathExpr compiles expr. It supports numbers, channel references,
+// + - * / ^, parentheses and the functions in mathFunctions.
+func parseMathExpr(expr string) (*mathExpr, error) {
+	p := &mathParser{src: expr, rest: expr}
+	eval, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if len(p.channels) == 0 {
+		return nil, fmt.Errorf("expression %q references no channel", expr)
+	}
+	slices.Sort(p.channels)
+	return &mathExpr{channels: slices.Compact(p.channels), eval: eval}, nil
+}
+
+// apply evaluates the expression for every sample of the channel traces,
+// which are given in the order of e.channels.
+func (e *mathExpr) apply(traces [][]float64) []float64 {
+	n := len(traces[0])
+	for _, t := range traces {
+		n = min(n, len(t))
+	}
+	values := make([]float64, e.channels[len(e.channels)-1]+1)
+	out := make([]float64, n)
+	for i := range out {
+		for j, ch := range e.channels {
+			values[ch] = traces[j][i]
+		}
+		out[i] = e.eval(values)
+	}
+	return out
+}
+
+// mathParser is a recursive descent parser over the tokens of an
+// expression; tok is the current token and pos its offset.
+type mathParser struct {
+	src      string
+	rest     string
+	tok      string
+	pos      int
+	channels []int
+	// vars maps variable names to their index in the values passed to the
+	// compiled expression. Channels can only be referenced without vars.
+	vars map[string]int
+}
+
+// parse compiles the whole expression.
+func (p *mathParser) parse() (func([]float64) float64, error) {
+	p.next()
+	eval, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, fmt.Errorf("unexpected %q at position %d of %q", p.tok, p.pos, p.src)
+	}
+	return eval, nil
+}
+
+func (p *mathParser) next() {
+	p.rest = strings.TrimLeftFunc(p.rest, unicode.IsSpace)
+	p.pos = len(p.src) - len(p.rest)
+	if p.rest == "" {
+		p.tok = ""
+		return
+	}
+	n := 1
+	c := rune(p.rest[0])
+	switch {
+	case unicode.IsDigit(c) || c == '.':
+		// Digits, a decimal point and an exponent with its sign, as in 1.5e-3.
+		for n = 0; n < len(p.rest); n++ {
+			r := p.rest[n]
+			if (r == 'e' || r == 'E') && n+1 < len(p.rest) && (p.rest[n+1] == '-' || p.rest[n+1] == '+') {
+				n++
+			} else if !(r >= '0' && r <= '9' || r == '.' || r == 'e' || r == 'E') {
+				break
+			}
+		}
+	case unicode.IsLetter(c):
+		n = strings.IndexFunc(p.rest, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	}
+	if n < 0 {
+		n = len(p.rest)
+	}
+	p.tok, p.rest = p.rest[:n], p.rest[n:]
+}
+
+func (p *mathParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s at position %d of %q", fmt.Sprintf(format, args...), p.pos, p.src)
+}
+
+// sum parses terms joined by + and -.
+func (p *mathParser) sum() (func([]float64) float64, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "+" || p.tok == "-" {
+		op := p.tok
+		p.next()
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "+" {
+			left = func(ch []float64) float64 { return l(ch) + right(ch) }
+		} else {
+			left = func(ch []float64) float64 { return l(ch) - right(ch) }
+		}
+	}
+	return left, nil
+}
+
+// product parses factors joined by * and /.
+func (p *mathParser) product() (func([]float64) float64, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "*" || p.tok == "/" {
+		op := p.tok
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == "*" {
+			left = func(ch []float64) float64 { return l(ch) * right(ch) }
+		} else {
+			left = func(ch []float64) float64 { return l(ch) / right(ch) }
+		}
+	}
+	return left, nil
+}
+
+// unary parses a signed power.
+func (p *mathParser) unary() (func([]float64) float64, error) {
+	switch p.tok {
+	case "-":
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(ch []float64) float64 { return -operand(ch) }, nil
+	case "+":
+		p.next()
+		return p.unary()
+	}
+	return p.power()
+}
+
+// power parses a primary raised to an optional, right-associative exponent.
+func (p *mathParser) power() (func([]float64) float64, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "^" {
+		return base, nil
+	}
+	p.next()
+	exp, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return func(ch []float64) float64 { return math.Pow(base(ch), exp(ch)) }, nil
+}
+
+// primary parses a number, a channel, a function call or a parenthesized
+// expression.
+func (p *mathParser) primary() (func([]float64) float64, error) {
+	tok := p.tok
+	switch {
+	case tok == "":
+		return nil, p.errorf("unexpected end")
+	case tok == "(":
+		p.next()
+		inner, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, p.errorf("missing )")
+		}
+		p.next()
+		return inner, nil
+	case unicode.IsDigit(rune(tok[0])) || tok[0] == '.':
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok)
+		}
+		p.next()
+		return func([]float64) float64 { return v }, nil
+	case unicode.IsLetter(rune(tok[0])):
+		name := strings.ToLower(tok)
+		if f, ok := mathFunctions[name]; ok {
+			p.next()
+			if p.tok != "(" {
+				return nil, p.errorf("expected ( after %s", name)
+			}
+			arg, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			return func(ch []float64) float64 { return f(arg(ch)) }, nil
+		}
+		if name == "pi" {
+			p.next()
+			return func([]float64) float64 { return math.Pi }, nil
+		}
+		if i, ok := p.vars[name]; ok {
+			p.next()
+			return func(v []float64) float64 { return v[i] }, nil
+		}
+		if n, err := strconv.Atoi(name[1:]); p.vars == nil && name[0] == 'c' && err == nil && n >= 1 {
+			p.channels = append(p.channels, n)
+			p.next()
+			return func(ch []float64) float64 { return ch[n] }, nil
+		}
+		return nil, p.errorf("unknown name %q", tok)
+	}
+	return nil, p.errorf("unexpected %q", tok)
+}
+
+func (s *DiscoveryMCPServer) handleScopeMath(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	source := getString(req.Params.Arguments, "expression", "")
+	expr, err := parseMathExpr(source)
+	if err != nil {
+		return errResult(err), nil
+	}
+	rb, err := s.device.Scope().Config()
+	if err != nil {
+		return errResult(err), nil
+	}
+	if last := expr.channels[len(expr.channels)-1]; last > len(rb.Channels) {
+		return errResult(fmt.Errorf("expression references C%d, but the oscilloscope has %d channels", last, len(rb.Channels))), nil
+	}
+	enc, err := parseSampleEncoding(req.Params.Arguments)
+	if err != nil {
+		return errResult(err), nil
+	}
+	ctx, cancel, err := acquisitionContext(ctx, req.Params.Arguments, 1)
+	if err != nil {
+		return errResult(err), nil
+	}
+	defer cancel()
+	traces, err := s.device.Scope().RecordChannels(ctx, expr.channels)
+	if err != nil {
+		return errResult(acquisitionError(err)), nil
+	}
+	s.usage.captured("scope", 1)
+
+	data := expr.apply(traces)
+	result := map[string]interface{}{
+		"expression": source,
+		"channels":   expr.channels,
+		"samples":    len(data),
+	}
+	if len(data) > 0 {
+		lo, hi, sum, sumSq := math.Inf(1), math.Inf(-1), 0.0, 0.0
+		for _, v := range data {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+			sum += v
+			sumSq += v * v
+		}
+		n := float64(len(data))
+		result["min"], result["max"] = lo, hi
+		result["mean"] = sum / n
+		result["rms"] = math.Sqrt(sumSq / n)
+	}
+	encodeSamples(result, "data", data, enc)
+	if getBool(req.Params.Arguments, "include_sources", false) {
+		for i, ch := range expr.channels {
+			encodeSamples(result, fmt.Sprintf("c%d", ch), traces[i], enc)
+		}
+	}
+	return jsonResult(timeAxis(result, s.device.Scope().Timing(), len(data), 1)), nil
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func TestParseMathExpr(t *testing.T) {
+	ch := []float64{0, 3, 2}
+	for _, tc := range []struct {
+		expr     string
+		want     float64
+		channels []int
+	}{
+		{"C1-C2", 1, []int{1, 2}},
+		{"c1 * c2 / 0.5", 12, []int{1, 2}},
+		{"-C2^2", -4, []int{2}},
+		{"2^3^2 * C1 / C1", 512, []int{1}},
+		{"(C1 + 1.5e-1) * 2", 6.3, []int{1}},
+		{"abs(C2 - C1) + sqrt(C2*8)", 5, []int{1, 2}},
+		{"C1 + C1 - C1", 3, []int{1}},
+	} {
+		e, err := parseMathExpr(tc.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tc.expr, err)
+			continue
+		}
+		if got := e.eval(ch); math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("%s = %g, want %g", tc.expr, got, tc.want)
+		}
+		if !slices.Equal(e.channels, tc.channels) {
+			t.Errorf("%s channels = %v, want %v", tc.expr, e.channels, tc.channels)
+		}
+	}
+	for _, bad := range []string{"", "2*3", "C1 +", "(C1", "C1 C2", "foo(C1)", "C0", "abs C1", "1..2*C1"} {
+		if _, err := parseMathExpr(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestHandleScopeMath(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.channelData = map[int][]float64{1: {1, 2, 3}, 2: {1, 1, 0}}
+	dev.scope.readback.Channels = make([]dwf.ScopeChannelReadback, 2)
+	result, _ := s.handleScopeMath(context.Background(), makeReq(map[string]any{
+		"expression":      "C1*C2",
+		"include_sources": true,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	var got struct {
+		Data []float64 `json:"data"`
+		C2   []float64 `json:"c2"`
+		Mean float64   `json:"mean"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !slices.Equal(got.Data, []float64{1, 2, 0}) || got.Mean != 1 || len(got.C2) != 3 {
+		t.Errorf("unexpected result %+v", got)
+	}
+
+	result, _ = s.handleScopeMath(context.Background(), makeReq(map[string]any{"expression": "C1/"}))
+	if !result.IsError {
+		t.Error("expected error for an invalid expression")
+	}
+	result, _ = s.handleScopeMath(context.Background(), makeReq(map[string]any{"expression": "C1-C3"}))
+	if !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "2 channels") {
+		t.Errorf("expected error for a missing channel, got %v", result.Content)
+	}
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// Default rates of the protocol self-tests, in baud for UART and Hz for the
+// SPI and I2C clocks.
+var (
+	selfTestUARTRates = []float64{9600, 115200, 460800, 1e6, 3e6}
+	selfTestSPIRates  = []float64{100e3, 1e6, 5e6, 10e6, 20e6}
+	selfTestI2CRates  = []float64{100e3, 400e3, 1e6}
+)
+
+// selfTestPattern returns n bytes that exercise every bit line: alternating
+// bits, all zeros, all ones and then a counting ramp.
+func selfTestPattern(n int) []byte {
+	head := []byte{0x55, 0xAA, 0x00, 0xFF}
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(i)
+		if i < len(head) {
+			out[i] = head[i]
+		}
+	}
+	return out
+}
+
+// byteErrors counts the bytes of got that differ from want, including
+// missing and extra bytes.
+func byteErrors(want, got []byte) int {
+	errs := max(len(want), len(got)) - min(len(want), len(got))
+	for i := range min(len(want), len(got)) {
+		if want[i] != got[i] {
+			errs++
+		}
+	}
+	return errs
+}
+
+// selfTestRate is the outcome of the self-test runs at one rate.
+type selfTestRate struct {
+	Rate       float64 `json:"rate"`
+	Runs       int     `json:"runs"`
+	Passed     int     `json:"passed"`
+	ByteErrors int     `json:"byte_errors"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// reliable reports whether every run at the rate passed.
+func (r selfTestRate) reliable() bool {
+	return r.Error == "" && r.Runs > 0 && r.Passed == r.Runs
+}
+
+// maxReliableRate returns the highest rate up to which every tested rate
+// passed all its runs, or 0 if the lowest did not. results are in
+// ascending rate order.
+func maxReliableRate(results []selfTestRate) float64 {
+	var best float64
+	for _, r := range results {
+		if !r.reliable() {
+			break
+		}
+		best = r.Rate
+	}
+	return best
+}
+
+// runRate repeats a transfer at one rate. run returns the number of wrong
+// bytes; an instrument error ends the runs and is recorded in the result.
+// Only the end of ctx is returned as an error.
+func runRate(ctx context.Context, rate float64, runs int, run func(rate float64) (int, error)) (selfTestRate, error) {
+	r := selfTestRate{Rate: rate}
+	for range runs {
+		if err := ctx.Err(); err != nil {
+			return r, err
+		}
+		errs, err := run(rate)
+		if err != nil {
+			r.Error = err.Error()
+			break
+		}
+		r.Runs++
+		r.ByteErrors += errs
+		if errs == 0 {
+			r.Passed++
+		}
+	}
+	return r, nil
+}
+
+// uartTransfer sends tx with cfg and returns what arrives on the RX line,
+// waiting until n bytes are in or the transfer should long have completed.
+func (s *DiscoveryMCPServer) uartTransfer(ctx context.Context, cfg dwf.UARTConfig, tx []byte, n int) ([]byte, error) {
+	uart := s.device.UARTProtocol()
+	if err := uart.Open(cfg); err != nil {
+		return nil, err
+	}
+	// Drop whatever arrived before the transfer.
+	_, _ = uart.Read()
+	if err := uart.Write(tx); err != nil {
+		return nil, err
+	}
+	// Ten bits per frame, plus a margin for the USB round trips.
+	airtime := time.Duration(float64(len(tx)+n) * 10 / float64(cfg.BaudRate) * float64(time.Second))
+	deadline := time.Now().Add(airtime + 100*time.Millisecond)
+	var got []byte
+	for len(got) < n && time.Now().Before(deadline) && ctx.Err() == nil {
+		data, err := uart.Read()
+		got = append(got, data...)
+		if err != nil {
+			// Parity errors and overflows count as corrupted bytes.
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return got, nil
+}
+
+func (s *DiscoveryMCPServer) handleProtocolSelfTest(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	protocol := strings.ToLower(getString(args, "protocol", ""))
+	runs := getInt(args, "repeats", 3)
+	if runs < 1 || runs > 100 {
+		return errResult(fmt.Errorf("repeats must be between 1 and 100")), nil
+	}
+	size := getInt(args, "bytes", 64)
+	if size < 1 || size > 1024 {
+		return errResult(fmt.Errorf("bytes must be between 1 and 1024")), nil
+	}
+	pattern := selfTestPattern(size)
+	rates, err := getFloatList(args, "rates")
+	if err != nil {
+		return errResult(err), nil
+	}
+
+	var wiring string
+	var run func(rate float64) (int, error)
+	var reset func() error
+	switch protocol {
+	case "uart":
+		rx, tx := getInt(args, "rx", 0), getInt(args, "tx", 1)
+		if rx == tx {
+			return errResult(fmt.Errorf("rx and tx must be different DIO lines")), nil
+		}
+		wiring = fmt.Sprintf("connect DIO %d (TX) to DIO %d (RX)", tx, rx)
+		if len(rates) == 0 {
+			rates = selfTestUARTRates
+		}
+		run = func(rate float64) (int, error) {
+			got, err := s.uartTransfer(ctx, dwf.UARTConfig{RX: rx, TX: tx, BaudRate: int(rate), DataBits: 8, StopBits: 1}, pattern, len(pattern))
+			return byteErrors(pattern, got), err
+		}
+		reset = s.device.UARTProtocol().Close
+	case "spi":
+		cfg := dwf.SPIConfig{
+			CS:       getInt(args, "cs", 0),
+			SCK:      getInt(args, "sck", 1),
+			MOSI:     getInt(args, "mosi", 2),
+			MISO:     getInt(args, "miso", 3),
+			MSBFirst: true,
+		}
+		if lines := []int{cfg.CS, cfg.SCK, cfg.MOSI, cfg.MISO}; len(slices.Compact(slices.Sorted(slices.Values(lines)))) != len(lines) {
+			return errResult(fmt.Errorf("cs, sck, mosi and miso must be different DIO lines")), nil
+		}
+		wiring = fmt.Sprintf("connect DIO %d (MOSI) to DIO %d (MISO)", cfg.MOSI, cfg.MISO)
+		if len(rates) == 0 {
+			rates = selfTestSPIRates
+		}
+		run = func(rate float64) (int, error) {
+			cfg.ClockFrequency = rate
+			spi := s.device.SPIProtocol()
+			if err := spi.Open(cfg); err != nil {
+				return 0, err
+			}
+			got, err := spi.Exchange(pattern, len(pattern), cfg.CS)
+			if err != nil {
+				return 0, err
+			}
+			return byteErrors(pattern, got), nil
+		}
+		reset = s.device.SPIProtocol().Close
+	case "i2c":
+		sda, scl := getInt(args, "sda", 0), getInt(args, "scl", 1)
+		addr := getInt(args, "address", 0)
+		if addr < 0x08 || addr > 0x77 {
+			return errResult(fmt.Errorf("the i2c self-test needs the 7-bit address (0x08-0x77) of a target on the bus")), nil
+		}
+		wiring = fmt.Sprintf("connect a target that acknowledges address 0x%02X to DIO %d (SDA) and DIO %d (SCL), with pull-ups on both lines", addr, sda, scl)
+		if len(rates) == 0 {
+			rates = selfTestI2CRates
+		}
+		// I2C cannot loop back, so each run checks that the target
+		// acknowledges its address among the scan results.
+		run = func(rate float64) (int, error) {
+			i2c := s.device.I2CProtocol()
+			if err := i2c.Open(dwf.I2CConfig{SDA: sda, SCL: scl, ClockRate: rate}); err != nil {
+				return 0, err
+			}
+			found, err := i2c.Scan()
+			if err != nil {
+				return 0, err
+			}
+			if slices.Contains(found, addr) {
+				return 0, nil
+			}
+			return 1, nil
+		}
+		reset = s.device.I2CProtocol().Close
+	default:
+		return errResult(fmt.Errorf("unknown protocol %q (expected uart, spi or i2c)", protocol)), nil
+	}
+	rates = slices.Sorted(slices.Values(rates))
+	if rates[0] <= 0 {
+		return errResult(fmt.Errorf("rates must be positive")), nil
+	}
+
+	results := make([]selfTestRate, 0, len(rates))
+	for _, rate := range rates {
+		r, err := runRate(ctx, rate, runs, run)
+		if err != nil {
+			_ = reset()
+			return errResult(fmt.Errorf("self-test aborted: %w", err)), nil
+		}
+		results = append(results, r)
+	}
+	if err := reset(); err != nil {
+		return errResult(err), nil
+	}
+
+	best := maxReliableRate(results)
+	result := map[string]interface{}{
+		"protocol":          protocol,
+		"wiring":            wiring,
+		"repeats":           runs,
+		"rates":             results,
+		"max_reliable_rate": best,
+	}
+	if protocol != "i2c" {
+		result["bytes"] = size
+	}
+	if best == 0 {
+		result["hint"] = "nothing passed at the lowest rate; check the wiring: " + wiring
+	}
+	return jsonResult(result), nil
+}
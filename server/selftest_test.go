@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSelfTestPattern(t *testing.T) {
+	p := selfTestPattern(6)
+	if want := []byte{0x55, 0xAA, 0x00, 0xFF, 4, 5}; string(p) != string(want) {
+		t.Errorf("pattern = %x, want %x", p, want)
+	}
+	if n := byteErrors(p, []byte{0x55, 0xAB, 0x00}); n != 4 {
+		t.Errorf("byteErrors = %d, want 4", n)
+	}
+}
+
+func TestHandleProtocolSelfTest(t *testing.T) {
+	s, dev := newTestServer()
+	selfTest := func(args map[string]any) (map[string]any, []selfTestRate) {
+		t.Helper()
+		result, _ := s.handleProtocolSelfTest(context.Background(), makeReq(args))
+		if result.IsError {
+			t.Fatalf("unexpected error: %v", result.Content)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		var got map[string]any
+		var rates struct {
+			Rates []selfTestRate `json:"rates"`
+		}
+		if err := json.Unmarshal([]byte(text), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		json.Unmarshal([]byte(text), &rates)
+		return got, rates.Rates
+	}
+
+	dev.uart.loopbackRate = 460800
+	got, rates := selfTest(map[string]any{"protocol": "uart", "repeats": float64(2), "bytes": float64(16)})
+	if got["max_reliable_rate"] != 460800.0 || len(rates) != len(selfTestUARTRates) || rates[3].ByteErrors != 2 || rates[0].Passed != 2 {
+		t.Errorf("unexpected UART result %v", got)
+	}
+	if !strings.Contains(got["wiring"].(string), "DIO 1 (TX) to DIO 0 (RX)") || got["hint"] != nil {
+		t.Errorf("unexpected wiring %v or hint %v", got["wiring"], got["hint"])
+	}
+
+	dev.spi.loopbackRate = 5e6
+	got, _ = selfTest(map[string]any{"protocol": "spi", "rates": []any{20e6, 1e6, 5e6}})
+	if got["max_reliable_rate"] != 5e6 {
+		t.Errorf("unexpected SPI result %v", got)
+	}
+	dev.spi.loopbackRate = 0
+	if got, _ = selfTest(map[string]any{"protocol": "spi"}); got["max_reliable_rate"] != 0.0 || got["hint"] == nil {
+		t.Errorf("expected a wiring hint without loopback, got %v", got)
+	}
+
+	dev.i2c.scanData = []int{0x50}
+	if got, _ = selfTest(map[string]any{"protocol": "i2c", "address": float64(0x50)}); got["max_reliable_rate"] != 1e6 {
+		t.Errorf("unexpected I2C result %v", got)
+	}
+
+	for _, args := range []map[string]any{
+		{"protocol": "can"},
+		{"protocol": "uart", "rx": float64(1), "tx": float64(1)},
+		{"protocol": "spi", "miso": float64(2)},
+		{"protocol": "i2c"},
+		{"protocol": "uart", "rates": []any{-1.0}},
+	} {
+		if result, _ := s.handleProtocolSelfTest(context.Background(), makeReq(args)); !result.IsError {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
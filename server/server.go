@@ -3,6 +3,8 @@
 package server
 
 import (
+	"sync"
+
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
@@ -11,8 +13,28 @@ import (
 
 // DiscoveryMCPServer wraps the MCP server and the Discovery device.
 type DiscoveryMCPServer struct {
-	mcpServer *server.MCPServer
-	device    dwf.DiscoveryDevice
+	mcpServer   *server.MCPServer
+	device      dwf.DiscoveryDevice
+	watches     *watchSet
+	usage       *usageTracker
+	captures    CaptureStore
+	annotations *captureIndex
+	probes      *probeSet
+	pins        *pinSet
+	format      *textFormat
+	configs     *deviceConfigs
+	config      *Config
+	expect      *DeviceExpectation
+	safety      *SafetyLimits
+	watchdog    *supplyWatchdog
+	// pinArgs and logicPinArgs list the arguments of each tool that take
+	// a DIO line or a logic channel, where a pin name is accepted instead.
+	pinArgs, logicPinArgs map[string][]string
+	// mu guards degraded, which the startup check and device_open write
+	// while other tool calls may read it.
+	mu sync.Mutex
+	// degraded lists why the hardware does not match the expectation.
+	degraded []string
 }
 
 // New creates and configures a new DiscoveryMCPServer with all tools registered.
@@ -24,16 +46,30 @@ func New() *DiscoveryMCPServer {
 // This is useful for testing with mock devices.
 func NewWithDevice(dev dwf.DiscoveryDevice) *DiscoveryMCPServer {
 	s := &DiscoveryMCPServer{
-		device: dev,
+		device:      dev,
+		watches:     newWatchSet(),
+		usage:       newUsageTracker(),
+		captures:    newMemoryStore(),
+		annotations: newCaptureIndex(),
+		probes:      newProbeSet(),
+		pins:        newPinSet(),
+		format:      newTextFormat(),
+		configs:     &deviceConfigs{},
 	}
+	s.watchdog = newSupplyWatchdog(s.supplyTimeout)
 
 	s.mcpServer = server.NewMCPServer(
 		"discovery-mcp",
 		"1.0.0",
 		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(false, false),
+		server.WithToolHandlerMiddleware(s.refreshWatchdog),
+		server.WithToolHandlerMiddleware(s.resolvePins),
 	)
 
 	s.registerTools()
+	s.allowPinNames()
+	s.registerResources()
 	return s
 }
 
@@ -55,12 +91,13 @@ func (s *DiscoveryMCPServer) registerTools() {
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_device_get_configs",
 		mcp.WithDescription("List available hardware configurations for a device without opening it"),
-		mcp.WithNumber("device_index", mcp.Description("Device index from enumeration"), mcp.Required()),
+		mcp.WithNumber("device_index", mcp.Description("Device index from enumeration")),
+		mcp.WithString("device", mcp.Description("Friendly device name from the --config rules, instead of device_index")),
 	), s.handleDeviceGetConfigs)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_device_open",
 		mcp.WithDescription("Open a connection to a Digilent Discovery device"),
-		mcp.WithString("device", mcp.Description("Device name (empty for first available): 'Analog Discovery 2', 'Digital Discovery', etc.")),
+		mcp.WithString("device", mcp.Description("Device to open (empty for first available): a product name such as 'Analog Discovery 2', a serial number, or a friendly name from the --config rules")),
 		mcp.WithNumber("config", mcp.Description("Device configuration index (0 for default)")),
 	), s.handleDeviceOpen)
 
@@ -72,6 +109,32 @@ func (s *DiscoveryMCPServer) registerTools() {
 		mcp.WithDescription("Read the board temperature in °C"),
 	), s.handleDeviceTemperature)
 
+	s.mcpServer.AddTool(mcp.NewTool("discovery_quick_measure",
+		mcp.WithDescription("One-shot check without opening an instrument first, e.g. 'is the 3V3 rail up?': the DC voltage of a scope channel, the level of a DIO pin or the board temperature. "+
+			"A closed oscilloscope is configured for the reading and reset afterwards; an open one is read as configured"),
+		mcp.WithString("check", mcp.Description("What to check: voltage, dio or temperature"), mcp.Required(), mcp.Enum("voltage", "dio", "temperature")),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based, default 1) for voltage, or DIO pin (default 0) for dio"), takesDIOLine()),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for the voltage acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleQuickMeasure)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_device_status",
+		mcp.WithDescription("Report the open device's FPGA configuration, hardware revision and whether it still responds"),
+	), s.handleDeviceStatus)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_device_reinitialize",
+		mcp.WithDescription("Recover a wedged device without replugging USB: reset all instruments, or with reprogram close and reopen it to reload the FPGA. Outputs and supplies are switched off"),
+		mcp.WithBoolean("reprogram", mcp.Description("Reload the FPGA configuration by reopening the device (default false: reset instruments only)")),
+	), s.handleDeviceReinitialize)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_usage_stats",
+		mcp.WithDescription("Report cumulative usage per device (opens, supply toggles and on-time, relay toggles, captures), persisted across server runs"),
+	), s.handleUsageStats)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_session_format",
+		mcp.WithDescription("Choose how numbers are written in text results and messages for the rest of the session: raw floats (default) or engineering notation with SI prefixes, e.g. 1.234 mV. Applies to every physical quantity; counts and ratios stay plain, and JSON fields always stay plain numbers. Without arguments, reports the current choice"),
+		mcp.WithString("numbers", mcp.Description("Number style: raw or engineering"), mcp.Enum("raw", "engineering")),
+	), s.handleSessionFormat)
+
 	// ---- Oscilloscope ----
 	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_open",
 		mcp.WithDescription("Initialize the oscilloscope"),
@@ -79,28 +142,150 @@ func (s *DiscoveryMCPServer) registerTools() {
 		mcp.WithNumber("buffer_size", mcp.Description("Buffer size in samples (0 = maximum)")),
 		mcp.WithNumber("offset_voltage", mcp.Description("Offset voltage in Volts")),
 		mcp.WithNumber("amplitude_range", mcp.Description("Amplitude range in Volts (e.g. 5 for ±5V)")),
-	), s.handleScopeOpen)
+		mcp.WithString("coupling", mcp.Description("Input coupling for all channels: dc or ac (default dc)")),
+		mcp.WithNumber("attenuation", mcp.Description("Probe attenuation for all channels, e.g. 10 for a 10x probe (default 1)")),
+		mcp.WithString("filter", mcp.Description("Acquisition filter: decimate, average or minmax (default decimate). Average reduces noise on slow signals")),
+		mcp.WithNumber("bandwidth", mcp.Description("Input bandwidth limit in Hz (0 = device default)")),
+		mcp.WithString("acquisition_mode", mcp.Description("How scope_record acquires: single (one triggered buffer, default), scan_shift (continuous roll mode, each record returns the latest samples), scan_screen (continuous sweep that wraps at the buffer end) or record (streams a record longer than the device buffer)"), mcp.Enum("single", "scan_shift", "scan_screen", "record")),
+		mcp.WithNumber("record_length", mcp.Description("Record duration in seconds for the record mode (default one buffer)")),
+	), s.requires(instrumentScope, s.handleScopeOpen))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_get_config",
+		mcp.WithDescription("Read back the oscilloscope configuration the device actually applied (sampling frequency, buffer size, per-channel range/offset/coupling and trigger), since requested values are coerced to what the hardware supports"),
+	), s.handleScopeGetConfig)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_measure",
 		mcp.WithDescription("Measure a single voltage from an oscilloscope channel"),
 		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
 	), s.handleScopeMeasure)
 
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_coupling",
+		mcp.WithDescription("Select AC or DC input coupling for an oscilloscope channel (AC blocks DC, e.g. for ripple measurements)"),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
+		mcp.WithString("coupling", mcp.Description("Input coupling: dc or ac"), mcp.Required()),
+	), s.handleScopeCoupling)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_attenuation",
+		mcp.WithDescription("Set the probe attenuation of an oscilloscope channel so samples are reported in probe-tip volts"),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
+		mcp.WithNumber("attenuation", mcp.Description("Attenuation factor: 1 for 1x, 10 for 10x, or any custom ratio"), mcp.Required()),
+	), s.handleScopeAttenuation)
+
 	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_trigger",
 		mcp.WithDescription("Configure the oscilloscope trigger"),
 		mcp.WithBoolean("enable", mcp.Description("Enable/disable trigger")),
 		mcp.WithNumber("source", mcp.Description("Trigger source (0=none, 2=analog_in, 3=digital_in, 11-14=external)")),
 		mcp.WithNumber("channel", mcp.Description("Trigger channel (1-based for analog)")),
 		mcp.WithNumber("timeout", mcp.Description("Auto-trigger timeout in seconds")),
-		mcp.WithBoolean("edge_rising", mcp.Description("Rising edge (true) or falling edge (false)")),
+		mcp.WithString("type", mcp.Description("Trigger type: edge (default), pulse (width-qualified, for runts and glitches) or window (signal enters or leaves a voltage window)"), mcp.Enum("edge", "pulse", "window")),
+		mcp.WithBoolean("edge_rising", mcp.Description("Rising edge (true) or falling edge (false); for pulse triggers a positive (true) or negative (false) pulse")),
+		mcp.WithNumber("length", mcp.Description("Pulse width in seconds compared by a pulse trigger")),
+		mcp.WithString("length_condition", mcp.Description("Pulse trigger condition: more (wider than length, default), less (glitches narrower than length) or timeout (fires once a pulse lasts length)"), mcp.Enum("more", "less", "timeout")),
 		mcp.WithNumber("level", mcp.Description("Trigger level in Volts")),
+		mcp.WithNumber("window_low", mcp.Description("Lower window bound in Volts, required for window triggers")),
+		mcp.WithNumber("window_high", mcp.Description("Upper window bound in Volts, required for window triggers")),
+		mcp.WithString("window_condition", mcp.Description("Window trigger condition: exit (signal leaves the window, default; catches rail excursions) or enter"), mcp.Enum("exit", "enter")),
+		mcp.WithNumber("holdoff", mcp.Description("Time in seconds after a trigger during which further edges are ignored, e.g. longer than a burst so each burst triggers on its first edge (default 0)")),
+		mcp.WithString("position", mcp.Description("Signal history to keep before the trigger event, in seconds or as a percentage of the buffer such as \"10%\" (default: keep the current position, which starts with the trigger in the middle of the buffer)")),
+		mcp.WithString("hysteresis", mcp.Description("Trigger hysteresis in Volts, or \"auto\" for 2% of the trigger channel's range; keeps noise near the level from double-triggering. 0 restores the device default (default: keep the current setting)")),
 	), s.handleScopeTrigger)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_record",
 		mcp.WithDescription("Record an analog signal buffer"),
 		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
+		mcp.WithNumber("averages", mcp.Description("Number of triggered acquisitions to average point-wise (default 1, max 1000)")),
+		mcp.WithBoolean("stddev", mcp.Description("Also return the per-point standard deviation when averaging (default false)")),
+		mcp.WithBoolean("peak_detect", mcp.Description("Return paired min/max arrays so glitches shorter than the sample period still show up (default false)")),
+		mcp.WithBoolean("noise", mcp.Description("Also return noise_min/noise_max arrays with the envelope of the raw ADC samples around the decimated data, to check signal integrity without raising the sample rate (default false)")),
+		mcp.WithNumber("preview_samples", mcp.Description("Return only a min/max envelope of this many points and store the full capture as a captures:// resource (default 0 = return everything)")),
+		mcp.WithString("encoding", mcp.Description("Sample array encoding: json (default), base64_f32 (little-endian float32) or base64_i16 (little-endian int16, value = raw * scale + offset to within scale/2; indices of non-finite samples in <array>_invalid)"), mcp.Enum("json", "base64_f32", "base64_i16")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
 	), s.handleScopeRecord)
 
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_record_xy",
+		mcp.WithDescription("Record two channels from the same trigger and return aligned x/y sample pairs for a Lissajous or I-V plot, with the phase shift, amplitude ratio and a fitted line"),
+		mcp.WithNumber("x_channel", mcp.Description("Oscilloscope channel plotted on the X axis (1-based, default 1)")),
+		mcp.WithNumber("y_channel", mcp.Description("Oscilloscope channel plotted on the Y axis (1-based, default 2)")),
+		mcp.WithString("encoding", mcp.Description("Sample array encoding: json (default), base64_f32 or base64_i16 (value = raw * <array>_scale + <array>_offset)"), mcp.Enum("json", "base64_f32", "base64_i16")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for the triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleScopeRecordXY)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_math",
+		mcp.WithDescription("Record the channels an expression references from one acquisition and return the computed trace, e.g. C1-C2 for differential probing or C1*C2/0.1 for instantaneous power through a 0.1 ohm shunt"),
+		mcp.WithString("expression", mcp.Description("Expression over channels C1, C2, ... up to the oscilloscope's channel count, with numbers, + - * / ^, parentheses, pi and the functions abs, sign, floor, sqrt, exp, log, log10, sin, cos and tan"), mcp.Required()),
+		mcp.WithBoolean("include_sources", mcp.Description("Also return the source channel traces as c1, c2, ... (default false)")),
+		mcp.WithString("encoding", mcp.Description("Sample array encoding: json (default), base64_f32 or base64_i16 (value = raw * <array>_scale + <array>_offset)"), mcp.Enum("json", "base64_f32", "base64_i16")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for the triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleScopeMath)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_record_raw",
+		mcp.WithDescription("Record a buffer as raw ADC codes with the factors that convert them to volts, for metrology-style work such as checking ADC linearity or averaging below one step. Needs the single acquisition mode. The SDK offers no dithering control"),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
+		mcp.WithString("encoding", mcp.Description("Code array encoding: json (default), base64_f32 or base64_i16 (value = raw * <array>_scale + <array>_offset)"), mcp.Enum("json", "base64_f32", "base64_i16")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for the triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleScopeRecordRaw)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_analyze",
+		mcp.WithDescription("Record a buffer and return standard measurements (min, max, peak-peak, mean, RMS, frequency, period, duty cycle, rise/fall time) instead of raw samples"),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleScopeAnalyze)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_stats",
+		mcp.WithDescription("Repeat a triggered acquisition count times and return the mean, min, max and standard deviation of each standard measurement (peak-peak, RMS, frequency, ...), to verify that a signal is stable"),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
+		mcp.WithNumber("count", mcp.Description("Number of acquisitions (default 10, max 1000)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleScopeStats)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_frequency",
+		mcp.WithDescription("Measure frequency and period on an analog channel like a bench counter: with the device's analog-in counter over a gate time where available, "+
+			"otherwise in software from one capture. Returns frequency, period and the edge count over the gate"),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
+		mcp.WithString("method", mcp.Description("auto (default: counter if the device has one), counter or software"), mcp.Enum("auto", "counter", "software")),
+		mcp.WithNumber("gate_time", mcp.Description("Counter gate time in seconds (default 1); software counting uses the capture length")),
+		mcp.WithNumber("level", mcp.Description("Counter threshold in Volts (default: the middle of the signal's swing in one capture)")),
+		mcp.WithNumber("hysteresis", mcp.Description("Counter hysteresis in Volts (default: device default)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for the triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleScopeFrequency)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_persistence",
+		mcp.WithDescription("Accumulate many triggered acquisitions into a persistence map (time x amplitude grid of how many acquisitions passed through each cell) "+
+			"and an amplitude histogram, and list the rarely visited cells and the acquisitions that visited them, to catch intermittent anomalies such as double pulses or metastability that single captures miss"),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
+		mcp.WithNumber("count", mcp.Description("Number of acquisitions (default 100, max 1000)")),
+		mcp.WithNumber("time_bins", mcp.Description("Columns of the map across the record (default 80, max 1000)")),
+		mcp.WithNumber("amplitude_bins", mcp.Description("Rows of the map and bins of the histogram (default 32, 2 to 256)")),
+		mcp.WithNumber("v_min", mcp.Description("Bottom of the amplitude range in Volts; give with v_max (default: the first acquisition's range with half its span to spare on each side)")),
+		mcp.WithNumber("v_max", mcp.Description("Top of the amplitude range in Volts; give with v_min")),
+		mcp.WithNumber("rare_fraction", mcp.Description("A cell is rare when at most this share of the acquisitions passed through it (default 0.05)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handlePersistence)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_fft",
+		mcp.WithDescription("Record a buffer and return its magnitude spectrum with the strongest peaks, e.g. to verify a generated waveform"),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
+		mcp.WithString("window", mcp.Description("Window function: hann (default), flattop (accurate amplitude) or rectangular"), mcp.Enum("hann", "flattop", "rectangular")),
+		mcp.WithNumber("peaks", mcp.Description("Maximum number of peaks to report (default 5)")),
+		mcp.WithNumber("bins", mcp.Description("Maximum number of spectrum points to return, reduced by peak-hold (default 256, 0 = peaks only)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleScopeFFT)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_timebase",
+		mcp.WithDescription("Record a known stable reference clock and measure the oscilloscope's own time base error (ppm) and jitter, so long time-interval measurements can be corrected or bounded"),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel the reference clock is connected to (1-based)"), mcp.Required()),
+		mcp.WithNumber("reference_frequency", mcp.Description("Nominal frequency of the reference clock in Hz"), mcp.Required()),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleScopeTimebase)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_stream",
+		mcp.WithDescription("Record a long analog signal in record mode, pushing sample chunks to the client as notifications while the acquisition runs"),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based)"), mcp.Required()),
+		mcp.WithNumber("duration", mcp.Description("Recording length in seconds"), mcp.Required()),
+		mcp.WithBoolean("store", mcp.Description("Save the whole recording to the capture store and return a captures:// resource URI (default false)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for the trigger on top of duration before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleScopeStream)
+
 	s.mcpServer.AddTool(mcp.NewTool("discovery_scope_close",
 		mcp.WithDescription("Reset the oscilloscope instrument"),
 	), s.handleScopeClose)
@@ -109,15 +294,52 @@ func (s *DiscoveryMCPServer) registerTools() {
 	s.mcpServer.AddTool(mcp.NewTool("discovery_wavegen_generate",
 		mcp.WithDescription("Generate an analog waveform"),
 		mcp.WithNumber("channel", mcp.Description("Wavegen channel (1 or 2)"), mcp.Required()),
-		mcp.WithNumber("function", mcp.Description("Wavegen: 0=DC,1=sine,2=square,3=triangle,4=ramp_up,5=ramp_down,6=noise,7=pulse,30=custom"), mcp.Required()),
+		mcp.WithNumber("function", mcp.Description("Wavegen: 0=DC,1=sine,2=square,3=triangle,4=ramp_up,5=ramp_down,6=noise,7=pulse,8=trapezium,9=sine_power,30=custom"), mcp.Required()),
 		mcp.WithNumber("offset", mcp.Description("DC offset in Volts")),
-		mcp.WithNumber("frequency", mcp.Description("Frequency in Hz")),
-		mcp.WithNumber("amplitude", mcp.Description("Amplitude in Volts")),
-		mcp.WithNumber("symmetry", mcp.Description("Symmetry in % (0-100)")),
+		mcp.WithNumber("frequency", mcp.Description("Frequency in Hz (default 1000)")),
+		mcp.WithNumber("amplitude", mcp.Description("Amplitude in Volts (default 1)")),
+		mcp.WithNumber("symmetry", mcp.Description("Symmetry in % (0-100, default 50): the duty cycle of square and pulse, the rising share of triangle and trapezium")),
+		mcp.WithNumber("phase", mcp.Description("Phase in degrees (0-360) at which the waveform starts, e.g. 90 for a cosine")),
+		mcp.WithNumber("power", mcp.Description("Shape of sine power (function 9) from -100 to 100 (default 0, a plain sine); it bends the sine further from its plain shape the larger it is")),
 		mcp.WithNumber("wait", mcp.Description("Wait time before start in seconds")),
 		mcp.WithNumber("run_time", mcp.Description("Run time in seconds (0 = infinite)")),
 		mcp.WithNumber("repeat", mcp.Description("Repeat count (0 = infinite)")),
-	), s.handleWavegenGenerate)
+		mcp.WithArray("custom_data", mcp.Description("One period of a custom waveform (function 30), scaled by amplitude. Values beyond ±1 are normalized with the amplitude raised to match, and a length the device does not accept is resampled; both are reported as a warning"), mcp.WithNumberItems()),
+		mcp.WithNumber("am_frequency", mcp.Description("Amplitude modulation frequency in Hz; enables AM")),
+		mcp.WithNumber("am_function", mcp.Description("AM modulating waveform, numbered as function (default 1 = sine)")),
+		mcp.WithNumber("am_depth", mcp.Description("AM modulation index in % of the amplitude (0-100, default 50)")),
+		mcp.WithNumber("fm_frequency", mcp.Description("Frequency modulation frequency in Hz; enables FM")),
+		mcp.WithNumber("fm_function", mcp.Description("FM modulating waveform, numbered as function (default 1 = sine)")),
+		mcp.WithNumber("fm_deviation", mcp.Description("FM frequency deviation in % of the frequency (0-100, default 10)")),
+		mcp.WithNumber("trigger_source", mcp.Description("Trigger that starts the output (0=none, start at once, default; 2=scope trigger, 3=digital_in, 11-14=external), e.g. to time a stimulus against a capture")),
+		mcp.WithString("trigger_slope", mcp.Description("Edge of the trigger source that starts the output (default rising)"), mcp.Enum("rising", "falling", "either")),
+	), s.requires(instrumentWavegen, s.handleWavegenGenerate))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_wavegen_expression",
+		mcp.WithDescription("Generate an arbitrary waveform from a math expression, evaluated server-side over one period into custom samples, "+
+			"e.g. '0.5*sin(2*pi*1000*t) + 0.1*sin(2*pi*10000*t)' at 1000 Hz. The expression gives volts; its peak becomes the amplitude"),
+		mcp.WithNumber("channel", mcp.Description("Wavegen channel (1 or 2)"), mcp.Required()),
+		mcp.WithString("expression", mcp.Description("Expression over t (seconds from the start of the period) and x (position in the period, 0 to 1) with numbers, + - * / ^, parentheses, pi and the functions sin, cos, tan, abs, sign, floor, sqrt, exp, log and log10"), mcp.Required()),
+		mcp.WithNumber("frequency", mcp.Description("Repetition rate of the period in Hz (default 1000)")),
+		mcp.WithNumber("samples", mcp.Description("Samples per period (default 1024, fitted to the device's limits)")),
+		mcp.WithNumber("offset", mcp.Description("DC offset in Volts added to the expression (default 0)")),
+		mcp.WithNumber("run_time", mcp.Description("Run time in seconds (0 = infinite)")),
+	), s.requires(instrumentWavegen, s.handleWavegenExpression))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_wavegen_play",
+		mcp.WithDescription("Play a waveform of any length once, e.g. an audio-style stimulus, streaming it to the device buffer as it drains. "+
+			"Samples are in Volts, from base64 data or a stored capture; the call returns when playback has finished"),
+		mcp.WithNumber("channel", mcp.Description("Wavegen channel (1 or 2)"), mcp.Required()),
+		mcp.WithString("data", mcp.Description("Samples in Volts as base64 (encoding base64_f32 or base64_i16)")),
+		mcp.WithString("encoding", mcp.Description("Encoding of data: base64_f32 (default) or base64_i16")),
+		mcp.WithNumber("data_scale", mcp.Description("base64_i16 scale: volts = raw * data_scale + data_offset (default 1)")),
+		mcp.WithNumber("data_offset", mcp.Description("base64_i16 offset in Volts (default 0)")),
+		mcp.WithString("capture", mcp.Description("Capture name or captures:// URI to play instead of data")),
+		mcp.WithString("array", mcp.Description("Sample array of the capture (default data)")),
+		mcp.WithNumber("sample_rate", mcp.Description("Output rate in samples per second; defaults to the capture's sample_rate")),
+		mcp.WithNumber("offset", mcp.Description("DC offset in Volts added to the samples (default 0)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds allowed beyond the playback duration (default 10)")),
+	), s.requires(instrumentWavegen, s.handleWavegenPlay))
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_wavegen_enable",
 		mcp.WithDescription("Enable a wavegen channel"),
@@ -129,6 +351,19 @@ func (s *DiscoveryMCPServer) registerTools() {
 		mcp.WithNumber("channel", mcp.Description("Channel (1-based)"), mcp.Required()),
 	), s.handleWavegenDisable)
 
+	s.mcpServer.AddTool(mcp.NewTool("discovery_wavegen_idle",
+		mcp.WithDescription("Set what a wavegen channel outputs while not running: before start, between runs and after stop. Use it when the device under test must not see the default glitch to 0 V"),
+		mcp.WithNumber("channel", mcp.Description("Channel (1-based)"), mcp.Required()),
+		mcp.WithString("idle", mcp.Description("disable: output off; offset: the configured offset voltage; initial: the first value of the waveform; hold: the last value output"), mcp.Enum("disable", "offset", "initial", "hold"), mcp.Required()),
+	), s.requires(instrumentWavegen, s.handleWavegenIdle))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_wavegen_master",
+		mcp.WithDescription("Slave a wavegen channel to another channel's state machine, so both start, run and repeat together in hardware without software timing races. "+
+			"Generate on the slave first; starting the master then starts both"),
+		mcp.WithNumber("channel", mcp.Description("Channel to slave (1-based, default 2)")),
+		mcp.WithNumber("master", mcp.Description("Channel it follows (1-based, default 1); 0 or the channel itself makes it independent again")),
+	), s.requires(instrumentWavegen, s.handleWavegenMaster))
+
 	s.mcpServer.AddTool(mcp.NewTool("discovery_wavegen_close",
 		mcp.WithDescription("Reset a wavegen channel"),
 		mcp.WithNumber("channel", mcp.Description("Channel (1-based)"), mcp.Required()),
@@ -147,22 +382,70 @@ func (s *DiscoveryMCPServer) registerTools() {
 		mcp.WithNumber("positive_current", mcp.Description("Positive current limit in A")),
 		mcp.WithNumber("negative_current", mcp.Description("Negative current limit in A")),
 		mcp.WithNumber("current", mcp.Description("Digital current limit in A")),
-	), s.handleSuppliesSwitch)
+		mcp.WithNumber("ramp_time", mcp.Description("Soft-start time in seconds (0-60, default 0): the rails being switched on rise from the voltage they read back to their voltage over this time, limiting inrush into capacitive loads; rails already on change at once. The call returns while the ramp continues")),
+		mcp.WithNumber("max_on_time", mcp.Description("Watchdog time in seconds (0 = none): the supplies switch off when no tool call arrives for this long, so an interrupted session does not leave the device under test powered. Every tool call restarts it")),
+	), s.requires(instrumentAnalogIO, s.handleSuppliesSwitch))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_supplies_get_config",
+		mcp.WithDescription("Read back the programmed master enable and the enable, set voltage and current limit of each supply rail, to confirm the state after discovery_supplies_switch calls, whose omitted fields default to off and 0"),
+	), s.requires(instrumentAnalogIO, s.handleSuppliesGetConfig))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_supplies_info",
+		mcp.WithDescription("List the programmable supply rails of the open device with the minimum, maximum and number of steps of each settable quantity (enable, voltage, current limit), to check a setting before discovery_supplies_switch"),
+	), s.requires(instrumentAnalogIO, s.handleSuppliesInfo))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_supplies_status",
+		mcp.WithDescription("Read the supply rails and their protection status: whether a rail is current limited or the device raised an over-current or thermal shutdown flag. "+
+			"With monitor, keep polling and send a warning log notification as soon as a protection trips"),
+		mcp.WithNumber("monitor", mcp.Description("Seconds to keep watching the protections (0-3600, default 0 = read once)")),
+		mcp.WithNumber("interval", mcp.Description("Seconds between reads while monitoring (default 0.5)")),
+	), s.requires(instrumentAnalogIO, s.handleSuppliesStatus))
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_supplies_close",
 		mcp.WithDescription("Reset the power supplies"),
 	), s.handleSuppliesClose)
 
+	// ---- AnalogIO ----
+	s.mcpServer.AddTool(mcp.NewTool("discovery_analogio_list",
+		mcp.WithDescription("List every AnalogIO channel of the open device with its nodes, units and the ranges that can be set and read. "+
+			"Besides the supplies these include device-specific settings and monitors such as VIO, USB power, temperature and buffers, which discovery_analogio_get and discovery_analogio_set access"),
+	), s.requires(instrumentAnalogIO, s.handleAnalogIOList))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_analogio_get",
+		mcp.WithDescription("Read an AnalogIO node: the value it is set to and the value the device reports"),
+		mcp.WithString("channel", mcp.Description("Channel as its 0-based index, name or label from discovery_analogio_list"), mcp.Required()),
+		mcp.WithString("node", mcp.Description("Node as its 0-based index or name, e.g. Voltage"), mcp.Required()),
+	), s.requires(instrumentAnalogIO, s.handleAnalogIOGet))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_analogio_set",
+		mcp.WithDescription("Set an AnalogIO node within its range and return the value the device applied. Voltage nodes are subject to the supply safety limit"),
+		mcp.WithString("channel", mcp.Description("Channel as its 0-based index, name or label from discovery_analogio_list"), mcp.Required()),
+		mcp.WithString("node", mcp.Description("Node as its 0-based index or name, e.g. Voltage"), mcp.Required()),
+		mcp.WithNumber("value", mcp.Description("Value in the node's units"), mcp.Required()),
+	), s.requires(instrumentAnalogIO, s.handleAnalogIOSet))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_power_profile",
+		mcp.WithDescription("Log the current drawn from the supplies and USB over time, to characterize the power consumption of the device under test. "+
+			"Samples every AnalogIO channel that reports a current, or the rails given, and returns the time series with average and peak current and power and the energy per rail. "+
+			"With stream, sample chunks are pushed to the client as notifications while the profile runs"),
+		mcp.WithNumber("duration", mcp.Description("Profile length in seconds (up to 3600)"), mcp.Required()),
+		mcp.WithNumber("interval", mcp.Description("Seconds between samples (default 0.1, at least 0.01)")),
+		mcp.WithArray("rails", mcp.Description("Channel labels or names to profile, such as V+ or USB (default: every channel that reports a current)"), mcp.WithStringItems()),
+		mcp.WithBoolean("stream", mcp.Description("Send notifications/discovery/power_data notifications with every 10 samples (default false)")),
+		mcp.WithBoolean("store", mcp.Description("Save the time series to the capture store and return a captures:// resource URI instead of the series (default false)")),
+	), s.requires(instrumentAnalogIO, s.handlePowerProfile))
+
 	// ---- DMM ----
 	s.mcpServer.AddTool(mcp.NewTool("discovery_dmm_open",
 		mcp.WithDescription("Initialize the digital multimeter"),
-	), s.handleDMMOpen)
+	), s.requires(instrumentAnalogIO, s.handleDMMOpen))
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_dmm_measure",
-		mcp.WithDescription("Measure with the DMM"),
-		mcp.WithNumber("mode", mcp.Description("Mode: 0=AC_V,1=DC_V,2=AC_I,3=DC_I,4=resistance,5=continuity,6=diode,7=temp"), mcp.Required()),
+		mcp.WithDescription("Measure with the DMM; returns the value with its unit and the range the device used"),
+		mcp.WithNumber("mode", mcp.Description("Mode: 0=AC_V,1=DC_V,2=AC_I,3=DC_I,4=resistance,5=continuity,6=diode,7=temp,8=AC_I low (mA input),9=DC_I low (mA input),10=AC_I high (10 A input),11=DC_I high (10 A input)"), mcp.Required()),
 		mcp.WithNumber("range", mcp.Description("Measurement range (0 = auto)")),
 		mcp.WithBoolean("high_impedance", mcp.Description("High impedance input (10GΩ) for DC voltage")),
+		mcp.WithNumber("samples", mcp.Description("Number of readings to take (1-1000, default 1); with more than one, returns their count, mean, min, max and standard deviation")),
 	), s.handleDMMMeasure)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_dmm_close",
@@ -174,25 +457,85 @@ func (s *DiscoveryMCPServer) registerTools() {
 		mcp.WithDescription("Initialize the logic analyzer"),
 		mcp.WithNumber("sampling_frequency", mcp.Description("Sampling frequency in Hz (default 100MHz)")),
 		mcp.WithNumber("buffer_size", mcp.Description("Buffer size (0 = maximum)")),
-	), s.handleLogicOpen)
+		mcp.WithNumber("threshold", mcp.Description("Input logic threshold in Volts on devices where it is adjustable, such as the Analog Discovery Pro 3X50 (0 = device default)")),
+		mcp.WithArray("channels", mcp.Description("DIO line for each logic channel: channel N of record, trigger and capture reads DIO line channels[N] (default channel N = DIO N). Up to 32 lines; DIO 24-39 of the Digital Discovery are reachable this way"), mcp.WithNumberItems(), takesDIOLine()),
+		mcp.WithNumber("sample_bits", mcp.Description("Sample width: 8, 16 or 32 bits (default: the narrowest that holds the mapped lines, 16 without channels)")),
+		mcp.WithNumber("clock_channel", mcp.Description("Sync mode: take one sample per edge of this logic channel instead of at sampling_frequency, e.g. the SCK of an SPI bus. The trigger is then unavailable and the samples have no time base"), takesLogicChannel()),
+		mcp.WithString("clock_edge", mcp.Description("Sync mode: clock edges that take a sample: rising, falling or either (default either, which keeps the clock's own edges in the capture for discovery_spi_monitor)"), mcp.Enum("rising", "falling", "either")),
+	), s.requires(instrumentLogic, s.handleLogicOpen))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_logic_get_config",
+		mcp.WithDescription("Read back the logic analyzer settings in effect: clock divider, achieved sample rate, buffer size and time, sample format and channel mapping, since the requested rate is rounded to a whole divider"),
+	), s.handleLogicGetConfig)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_logic_trigger",
 		mcp.WithDescription("Configure the logic analyzer trigger"),
 		mcp.WithBoolean("enable", mcp.Description("Enable/disable trigger")),
-		mcp.WithNumber("channel", mcp.Description("DIO line number")),
+		mcp.WithNumber("channel", mcp.Description("DIO line number"), takesLogicChannel()),
 		mcp.WithNumber("position", mcp.Description("Prefill size")),
 		mcp.WithNumber("timeout", mcp.Description("Auto-trigger timeout in seconds")),
 		mcp.WithBoolean("rising_edge", mcp.Description("Rising (true) or falling (false) edge")),
 		mcp.WithNumber("length_min", mcp.Description("Min trigger sequence duration in seconds")),
 		mcp.WithNumber("length_max", mcp.Description("Max trigger sequence duration in seconds")),
 		mcp.WithNumber("count", mcp.Description("Trigger event count")),
+		mcp.WithString("pattern", mcp.Description("Trigger while the channels selected by mask equal this value, e.g. \"0xA5\" (bit N = logic channel N); replaces the edge trigger")),
+		mcp.WithString("mask", mcp.Description("Channels the pattern applies to, e.g. \"0xFF\" for DIO 7:0 (default: the whole bytes the pattern spans)")),
+		mcp.WithString("mode", mcp.Description("Trigger mode: edge (default), pattern (default when pattern is given), glitch, which fires on a pulse on channel narrower than max_width, or a protocol event: i2c_start, i2c_stop (on sda and scl), uart_start (on rx) or spi_select (on cs)"), mcp.Enum("edge", "pattern", "glitch", "i2c_start", "i2c_stop", "uart_start", "spi_select")),
+		mcp.WithNumber("sda", mcp.Description("i2c_start and i2c_stop modes: logic channel of SDA"), takesLogicChannel()),
+		mcp.WithNumber("scl", mcp.Description("i2c_start and i2c_stop modes: logic channel of SCL"), takesLogicChannel()),
+		mcp.WithNumber("rx", mcp.Description("uart_start mode: logic channel of the UART line"), takesLogicChannel()),
+		mcp.WithNumber("cs", mcp.Description("spi_select mode: logic channel of the active-low chip select"), takesLogicChannel()),
+		mcp.WithString("polarity", mcp.Description("Glitch polarity: positive (a high pulse, default) or negative (a low pulse)"), mcp.Enum("positive", "negative")),
+		mcp.WithNumber("min_width", mcp.Description("Glitch mode: narrowest pulse in seconds to trigger on (default 0)")),
+		mcp.WithNumber("max_width", mcp.Description("Glitch mode: widest pulse in seconds to trigger on, e.g. 50e-9 for pulses under 50 ns")),
 	), s.handleLogicTrigger)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_logic_record",
 		mcp.WithDescription("Record digital signal from a DIO channel"),
-		mcp.WithNumber("channel", mcp.Description("DIO line number"), mcp.Required()),
+		mcp.WithNumber("channel", mcp.Description("DIO line number"), mcp.Required(), takesLogicChannel()),
+		mcp.WithString("encoding", mcp.Description("Sample array encoding: json (default), base64_f32 (little-endian float32) or base64_i16 (little-endian int16, value = raw * data_scale + data_offset)"), mcp.Enum("json", "base64_f32", "base64_i16")),
+		mcp.WithBoolean("rle", mcp.Description("Return [value, count] runs instead of the sample array, which is far shorter for mostly constant signals (default false)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
 	), s.handleLogicRecord)
 
+	s.mcpServer.AddTool(mcp.NewTool("discovery_logic_delta",
+		mcp.WithDescription("Capture all DIO lines and measure the delay from an edge on one line to the next edge on another (e.g. reset release to first SPI clock)"),
+		mcp.WithNumber("from_channel", mcp.Description("DIO line of the start event"), mcp.Required(), takesLogicChannel()),
+		mcp.WithString("from_edge", mcp.Description("Start edge: rising, falling or either (default rising)")),
+		mcp.WithNumber("to_channel", mcp.Description("DIO line of the end event; on the from_channel line, the next matching edge after the start"), mcp.Required(), takesLogicChannel()),
+		mcp.WithString("to_edge", mcp.Description("End edge: rising, falling or either (default rising)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleLogicDelta)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_logic_measure",
+		mcp.WithDescription("Capture all DIO lines and measure the pulse widths, period, frequency and duty cycle of one, and optionally its delay after edges on a reference line, so timing can be verified without counting samples"),
+		mcp.WithNumber("channel", mcp.Description("Logic channel to measure"), mcp.Required(), takesLogicChannel()),
+		mcp.WithNumber("ref_channel", mcp.Description("Reference channel: also measure the delay from each of its edges to the next edge on channel"), takesLogicChannel()),
+		mcp.WithString("ref_edge", mcp.Description("Reference edge: rising, falling or either (default rising)")),
+		mcp.WithString("edge", mcp.Description("Edge on channel that ends each delay: rising, falling or either (default rising)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleLogicMeasure)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_logic_count",
+		mcp.WithDescription("Count edges and measure frequency and period on a logic channel like a bench counter: with the device's digital-in counter over a gate time where available, "+
+			"otherwise in software from one capture. Open the logic analyzer first"),
+		mcp.WithNumber("channel", mcp.Description("Logic channel to count, 0 to 31"), mcp.Required(), takesLogicChannel()),
+		mcp.WithString("edge", mcp.Description("Edges to count: rising (default), falling or either"), mcp.Enum("rising", "falling", "either")),
+		mcp.WithString("method", mcp.Description("auto (default: counter if the device has one), counter or software"), mcp.Enum("auto", "counter", "software")),
+		mcp.WithNumber("gate_time", mcp.Description("Counter gate time in seconds (default 1). Software counting uses the capture length and rejects it")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for the triggered acquisition of software counting before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleLogicCount)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_logic_decode_uart",
+		mcp.WithDescription("Capture all DIO lines with the logic analyzer and decode the UART characters on one of them, with timestamps and parity/framing errors; nothing is driven. Open the logic analyzer at 4x the baud rate or more (and optionally trigger on the falling start bit) first"),
+		mcp.WithNumber("rx", mcp.Description("Logic channel carrying the UART signal"), mcp.Required(), takesLogicChannel()),
+		mcp.WithNumber("baud_rate", mcp.Description("Baud rate (default 9600)")),
+		mcp.WithNumber("parity", mcp.Description("Parity: 0=none (default), 1=odd, 2=even")),
+		mcp.WithNumber("data_bits", mcp.Description("Data bits, 5-9 (default 8)")),
+		mcp.WithNumber("stop_bits", mcp.Description("Stop bits, 1 or 2 (default 1)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleLogicDecodeUART)
+
 	s.mcpServer.AddTool(mcp.NewTool("discovery_logic_close",
 		mcp.WithDescription("Reset the logic analyzer"),
 	), s.handleLogicClose)
@@ -200,47 +543,155 @@ func (s *DiscoveryMCPServer) registerTools() {
 	// ---- Pattern Generator ----
 	s.mcpServer.AddTool(mcp.NewTool("discovery_pattern_generate",
 		mcp.WithDescription("Generate a digital pattern"),
-		mcp.WithNumber("channel", mcp.Description("DIO line number"), mcp.Required()),
+		mcp.WithNumber("channel", mcp.Description("DIO line number"), mcp.Required(), takesDIOLine()),
 		mcp.WithNumber("function", mcp.Description("Type: 0=pulse, 1=custom, 2=random"), mcp.Required()),
 		mcp.WithNumber("frequency", mcp.Description("Frequency in Hz"), mcp.Required()),
 		mcp.WithNumber("duty_cycle", mcp.Description("Duty cycle % (for pulse)")),
+		mcp.WithString("data", mcp.Description("Bit pattern for the custom function, sent in order at frequency bits per second, e.g. \"1011 0010\" (see data_format); limited by the DIO buffer of the device")),
+		mcp.WithString("data_format", mcp.Description("Format of data: bits (default, 0s and 1s), hex (4 bits per digit, MSB first) or base64 (8 bits per byte, MSB first)"), mcp.Enum("bits", "hex", "base64")),
+		mcp.WithNumber("prbs", mcp.Description("With function 2: instead of the device's unseeded random output, send the pseudo-random binary sequence of this order (7, 9, 11, 15, 20, 23 or 31), which is reproducible for bit-error-rate tests")),
+		mcp.WithString("seed", mcp.Description("PRBS starting state, e.g. \"0x5A\"; only its low prbs bits are used and must not all be zero (default all ones)")),
+		mcp.WithNumber("length", mcp.Description("PRBS bits to load (default one full period, or the channel's buffer if that is shorter)")),
+		mcp.WithString("output", mcp.Description("Drive type: push-pull (default), open-drain (drives low only, for I2C or wired-OR lines with a pull-up) or open-source (drives high only)"), mcp.Enum("push-pull", "open-drain", "open-source")),
 		mcp.WithNumber("wait", mcp.Description("Wait time in seconds")),
 		mcp.WithNumber("repeat", mcp.Description("Repeat count (0 = infinite)")),
 		mcp.WithNumber("run_time", mcp.Description("Run time in seconds (0=infinite, -1=auto)")),
-	), s.handlePatternGenerate)
+		mcp.WithString("idle_state", mcp.Description("Level of the line while the pattern is not running, e.g. before the trigger or after run_time: init (default, the pattern's initial level), low, high or z (high impedance)"), mcp.Enum("init", "low", "high", "z")),
+		mcp.WithNumber("trigger_source", mcp.Description("Trigger that starts each repeat (0=none, default; 2=scope trigger detector, 3=digital_in trigger detector, 4=scope start, 5=digital_in start, 7-10=wavegen channel 1-4 start, 11-14=external), e.g. to send the pattern once per external event")),
+		mcp.WithBoolean("trigger_edge_rising", mcp.Description("Trigger on the rising (true, default) or falling (false) edge; needs trigger_source")),
+	), s.requires(instrumentPattern, s.handlePatternGenerate))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_pattern_clock",
+		mcp.WithDescription("Start free-running clocks on several DIO lines together, each with its own frequency, duty cycle and phase, e.g. a clock and a strobe shifted by 90 degrees. Runs until discovery_pattern_disable or discovery_pattern_close"),
+		mcp.WithArray("clocks", mcp.Description("One object per clock: channel (DIO line, required), frequency in Hz (required), duty_cycle in % (default 50) and phase, the delay in degrees of its own period (default 0), e.g. [{\"channel\":0,\"frequency\":1e6},{\"channel\":1,\"frequency\":1e6,\"phase\":90}]"), mcp.Required(), mcp.Items(map[string]any{"type": "object"}), takesDIOLineIn("channel")),
+	), s.requires(instrumentPattern, s.handlePatternClock))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_pattern_rom",
+		mcp.WithDescription("Make the pattern generator act as glue logic: drive output DIO lines from a truth table addressed by input DIO lines, evaluated continuously in the device (e.g. an address decoder or a gate). Runs until discovery_pattern_disable or discovery_pattern_close"),
+		mcp.WithArray("inputs", mcp.Description("Input DIO lines; inputs[i] is bit i of the table index. They must be among the lowest DIO lines that address the device's tables"), mcp.Required(), mcp.WithNumberItems(), takesDIOLine()),
+		mcp.WithArray("outputs", mcp.Description("Output DIO lines; bit k of each table entry drives outputs[k]"), mcp.Required(), mcp.WithNumberItems(), takesDIOLine()),
+		mcp.WithArray("table", mcp.Description("Truth table: the output word for each input value 0 to 2^len(inputs)-1, as numbers or strings such as \"0b01\"; e.g. inputs [0,1], outputs [2] and table [0,0,0,1] make DIO2 = DIO0 AND DIO1"), mcp.Required()),
+	), s.requires(instrumentPattern, s.handlePatternROM))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_pattern_play",
+		mcp.WithDescription("Play a digital pattern of any length once on the lowest DIO lines, e.g. a multi-megabit stimulus file, streaming it to the device as it drains (play mode, Digital Discovery). "+
+			"Samples come from a stored capture or a file on the server host; the call returns when playback has finished"),
+		mcp.WithNumber("lines", mcp.Description("DIO lines driven from the first, and bits per sample: 1, 2, 4, 8 (default) or 16; bit k of a sample drives the k-th line")),
+		mcp.WithString("capture", mcp.Description("Capture name or captures:// URI whose array holds one whole-number sample per entry")),
+		mcp.WithString("array", mcp.Description("Sample array of the capture (default data)")),
+		mcp.WithString("file", mcp.Description("Path of a sample file on the server host, instead of a capture")),
+		mcp.WithString("format", mcp.Description("File format: binary (default) with the samples packed back to back, least significant bit first, or text with one number per sample (decimal, 0x or 0b)")),
+		mcp.WithNumber("sample_rate", mcp.Description("Output rate in samples per second; defaults to the capture's sample_rate")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds allowed beyond the playback duration, 0 or more (default 10)")),
+	), s.requires(instrumentPattern, s.handlePatternPlay))
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_pattern_set_duty",
+		mcp.WithDescription("Change the duty cycle of a running pulse channel (from discovery_pattern_generate, discovery_pattern_clock or discovery_dio_toggle) at its current frequency without restarting it, for closed-loop PWM control such as servos or LED dimming"),
+		mcp.WithNumber("channel", mcp.Description("DIO line number"), mcp.Required(), takesDIOLine()),
+		mcp.WithNumber("duty_cycle", mcp.Description("New duty cycle in %; the achieved value is rounded to a counter step and stays one step inside 0 and 100"), mcp.Required()),
+	), s.handlePatternSetDuty)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_dio_toggle",
+		mcp.WithDescription("Toggle a DIO line as a 50% square wave at a given rate, for a number of periods, a duration or until stopped (e.g. blink an LED); the clock divider is worked out automatically"),
+		mcp.WithNumber("channel", mcp.Description("DIO line number"), mcp.Required(), takesDIOLine()),
+		mcp.WithNumber("frequency", mcp.Description("Toggle rate in Hz: full high-low periods per second"), mcp.Required()),
+		mcp.WithNumber("count", mcp.Description("Number of periods to output (default: until stopped with discovery_pattern_disable)")),
+		mcp.WithNumber("duration", mcp.Description("Seconds to toggle for, instead of count")),
+		mcp.WithString("output", mcp.Description("Drive type: push-pull (default), open-drain (drives low only) or open-source (drives high only)"), mcp.Enum("push-pull", "open-drain", "open-source")),
+	), s.handleDIOToggle)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_pattern_enable",
 		mcp.WithDescription("Enable a digital output channel"),
-		mcp.WithNumber("channel", mcp.Description("DIO line number"), mcp.Required()),
+		mcp.WithNumber("channel", mcp.Description("DIO line number"), mcp.Required(), takesDIOLine()),
 	), s.handlePatternEnable)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_pattern_disable",
 		mcp.WithDescription("Disable a digital output channel"),
-		mcp.WithNumber("channel", mcp.Description("DIO line number"), mcp.Required()),
+		mcp.WithNumber("channel", mcp.Description("DIO line number"), mcp.Required(), takesDIOLine()),
 	), s.handlePatternDisable)
 
+	s.mcpServer.AddTool(mcp.NewTool("discovery_pattern_status",
+		mcp.WithDescription("Report whether the pattern generator is running, done or waiting for its trigger, how many repeats remain and which DIO lines are enabled; with wait, first wait for a finite pattern burst to finish"),
+		mcp.WithNumber("wait", mcp.Description("Seconds to wait for the pattern to reach the done state before reporting (default 0, report at once)")),
+	), s.handlePatternStatus)
+
 	s.mcpServer.AddTool(mcp.NewTool("discovery_pattern_close",
 		mcp.WithDescription("Reset the pattern generator"),
 	), s.handlePatternClose)
 
 	// ---- Static I/O ----
 	s.mcpServer.AddTool(mcp.NewTool("discovery_static_set_mode",
-		mcp.WithDescription("Set a DIO line as input or output"),
-		mcp.WithNumber("channel", mcp.Description("DIO channel number"), mcp.Required()),
+		mcp.WithDescription("Set a DIO line as input or output, and optionally its drive type. "+
+			"An open-drain line only drives low and is released to its pull-up for high, so pins can safely share a pulled-up bus such as I2C"),
+		mcp.WithNumber("channel", mcp.Description("DIO channel number"), mcp.Required(), takesDIOLine()),
 		mcp.WithBoolean("output", mcp.Description("true=output, false=input"), mcp.Required()),
+		mcp.WithString("output_type", mcp.Description("Drive type: push-pull, open-drain (drives low only) or open-source (drives high only), emulated by switching the output on and off (default: leave unchanged, push-pull at start)"),
+			mcp.Enum("push-pull", "open-drain", "open-source")),
 	), s.handleStaticSetMode)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_static_get_state",
 		mcp.WithDescription("Read the state of a DIO line"),
-		mcp.WithNumber("channel", mcp.Description("DIO channel number"), mcp.Required()),
+		mcp.WithNumber("channel", mcp.Description("DIO channel number"), mcp.Required(), takesDIOLine()),
 	), s.handleStaticGetState)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_static_set_state",
 		mcp.WithDescription("Set a DIO line HIGH or LOW"),
-		mcp.WithNumber("channel", mcp.Description("DIO channel number"), mcp.Required()),
+		mcp.WithNumber("channel", mcp.Description("DIO channel number"), mcp.Required(), takesDIOLine()),
 		mcp.WithBoolean("value", mcp.Description("true=HIGH, false=LOW"), mcp.Required()),
 	), s.handleStaticSetState)
 
+	s.mcpServer.AddTool(mcp.NewTool("discovery_static_read_mask",
+		mcp.WithDescription("Read the output enable, output and input state of all DIO lines in one call, as hex masks with bit 0 for the first DIO line (first_dio in the result), e.g. to read a parallel bus"),
+	), s.handleStaticReadMask)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_static_write_mask",
+		mcp.WithDescription("Set the direction and levels of several DIO lines at once from bit masks, bit 0 for the first DIO line, e.g. to drive a parallel bus with all bits changing together. "+
+			"Levels are written before directions, so lines turned into outputs start at the given level"),
+		mcp.WithString("output", mcp.Description("Levels to drive, e.g. \"0xA5\": 1=HIGH, 0=LOW for each selected line")),
+		mcp.WithString("output_enable", mcp.Description("Directions, e.g. \"0xFF\": 1=output, 0=input for each selected line")),
+		mcp.WithString("mask", mcp.Description("Lines to change, e.g. \"0x00FF\"; the others keep their settings (default all lines)")),
+	), s.handleStaticWriteMask)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_static_preset",
+		mcp.WithDescription("Configure I/O voltage (VIO), pulls and, where adjustable, the input threshold for a logic family in one call, so levels match the DUT"),
+		mcp.WithString("preset", mcp.Description("Logic family preset"), mcp.Required(), mcp.Enum(logicPresetNames()...)),
+		mcp.WithString("pull", mcp.Description("Override the preset pull for all DIO lines: up, down or none")),
+	), s.handleStaticPreset)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_static_vio",
+		mcp.WithDescription("Read or set the digital I/O supply voltage (VIO), the input logic threshold and the drive current, e.g. VIO 1.8 V to talk to 1.8 V targets. "+
+			"VIO is adjustable on the Digital Discovery (1.2-3.3 V) and the threshold on devices such as the Analog Discovery Pro; without parameters the tool only reads them"),
+		mcp.WithNumber("vio", mcp.Description("I/O supply voltage in Volts; output levels follow it")),
+		mcp.WithNumber("threshold", mcp.Description("Input logic threshold in Volts, below VIO")),
+		mcp.WithNumber("drive", mcp.Description("DIO drive current in mA: 2, 4, 6, 8, 12 or 16 on the Digital Discovery, 0 to follow VIO")),
+	), s.handleStaticVIO)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_static_set_current",
+		mcp.WithDescription("Set the DIO drive current, e.g. 4 mA to limit the current into a target that may be unpowered. "+
+			"Adjustable on the Digital Discovery; other devices report an error"),
+		mcp.WithNumber("current", mcp.Description("Drive current in mA: 2, 4, 6, 8, 12 or 16 on the Digital Discovery, 0 to follow VIO"), mcp.Required()),
+	), s.handleStaticSetCurrent)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_static_macro",
+		mcp.WithDescription("Run a short script of static I/O operations server-side, with microsecond delays between steps, "+
+			"so strobe sequences and simple bit-banged protocols need only one call. "+
+			`Each step is an object with "op": set {channel, value}, mode {channel, output}, read {channel}, wait {us}, or loop {count, steps}`),
+		mcp.WithArray("steps", mcp.Description("Ordered list of operations"), mcp.Required(), takesDIOLineIn("channel"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"op":      map[string]any{"type": "string", "enum": []string{"set", "mode", "read", "wait", "loop"}},
+					"channel": map[string]any{"type": "number", "description": "DIO channel number"},
+					"value":   map[string]any{"type": "boolean", "description": "set: true=HIGH, false=LOW"},
+					"output":  map[string]any{"type": "boolean", "description": "mode: true=output, false=input"},
+					"us":      map[string]any{"type": "number", "description": "wait: delay in microseconds"},
+					"count":   map[string]any{"type": "number", "description": "loop: number of repetitions"},
+					"steps":   map[string]any{"type": "array", "description": "loop: nested operations"},
+				},
+				"required": []string{"op"},
+			})),
+	), s.handleStaticMacro)
+
 	s.mcpServer.AddTool(mcp.NewTool("discovery_static_close",
 		mcp.WithDescription("Reset the static I/O"),
 	), s.handleStaticClose)
@@ -248,8 +699,8 @@ func (s *DiscoveryMCPServer) registerTools() {
 	// ---- UART ----
 	s.mcpServer.AddTool(mcp.NewTool("discovery_uart_open",
 		mcp.WithDescription("Initialize UART communication"),
-		mcp.WithNumber("rx", mcp.Description("DIO line for RX"), mcp.Required()),
-		mcp.WithNumber("tx", mcp.Description("DIO line for TX"), mcp.Required()),
+		mcp.WithNumber("rx", mcp.Description("DIO line for RX (-1 for transmit-only)"), mcp.Required(), takesDIOLine()),
+		mcp.WithNumber("tx", mcp.Description("DIO line for TX (-1 for receive-only, e.g. listening to a console)"), mcp.Required(), takesDIOLine()),
 		mcp.WithNumber("baud_rate", mcp.Description("Baud rate (default 9600)")),
 		mcp.WithNumber("parity", mcp.Description("Parity: 0=none, 1=odd, 2=even")),
 		mcp.WithNumber("data_bits", mcp.Description("Data bits (default 8)")),
@@ -272,10 +723,10 @@ func (s *DiscoveryMCPServer) registerTools() {
 	// ---- SPI ----
 	s.mcpServer.AddTool(mcp.NewTool("discovery_spi_open",
 		mcp.WithDescription("Initialize SPI communication"),
-		mcp.WithNumber("cs", mcp.Description("DIO line for chip select"), mcp.Required()),
-		mcp.WithNumber("sck", mcp.Description("DIO line for serial clock"), mcp.Required()),
-		mcp.WithNumber("miso", mcp.Description("DIO line for MISO (-1 to skip)")),
-		mcp.WithNumber("mosi", mcp.Description("DIO line for MOSI (-1 to skip)")),
+		mcp.WithNumber("cs", mcp.Description("DIO line for chip select"), mcp.Required(), takesDIOLine()),
+		mcp.WithNumber("sck", mcp.Description("DIO line for serial clock"), mcp.Required(), takesDIOLine()),
+		mcp.WithNumber("miso", mcp.Description("DIO line for MISO (-1 to skip)"), takesDIOLine()),
+		mcp.WithNumber("mosi", mcp.Description("DIO line for MOSI (-1 to skip)"), takesDIOLine()),
 		mcp.WithNumber("clock_frequency", mcp.Description("Clock frequency in Hz (default 1MHz)")),
 		mcp.WithNumber("mode", mcp.Description("SPI mode 0-3")),
 		mcp.WithBoolean("msb_first", mcp.Description("MSB first (true) or LSB first (false)")),
@@ -284,28 +735,47 @@ func (s *DiscoveryMCPServer) registerTools() {
 	s.mcpServer.AddTool(mcp.NewTool("discovery_spi_read",
 		mcp.WithDescription("Read data from SPI"),
 		mcp.WithNumber("count", mcp.Description("Number of bytes to read"), mcp.Required()),
-		mcp.WithNumber("cs", mcp.Description("Chip select line"), mcp.Required()),
+		mcp.WithNumber("cs", mcp.Description("Chip select line"), mcp.Required(), takesDIOLine()),
 	), s.handleSPIRead)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_spi_write",
 		mcp.WithDescription("Write data through SPI"),
 		mcp.WithString("data", mcp.Description("Data to send (hex string, e.g. 'FF01A2')"), mcp.Required()),
-		mcp.WithNumber("cs", mcp.Description("Chip select line"), mcp.Required()),
+		mcp.WithNumber("cs", mcp.Description("Chip select line"), mcp.Required(), takesDIOLine()),
 	), s.handleSPIWrite)
 
 	s.mcpServer.AddTool(mcp.NewTool("discovery_spi_close",
 		mcp.WithDescription("Reset the SPI interface"),
 	), s.handleSPIClose)
 
+	s.mcpServer.AddTool(mcp.NewTool("discovery_spi_monitor",
+		mcp.WithDescription("Passively capture SPI traffic between a host and its peripheral with the logic analyzer and decode both directions; nothing is driven. Open the logic analyzer (and optionally set a trigger, e.g. on CS) first"),
+		mcp.WithNumber("sck", mcp.Description("DIO line for the serial clock"), mcp.Required(), takesLogicChannel()),
+		mcp.WithNumber("mosi", mcp.Description("DIO line for MOSI (-1 to skip)"), takesLogicChannel()),
+		mcp.WithNumber("miso", mcp.Description("DIO line for MISO (-1 to skip)"), takesLogicChannel()),
+		mcp.WithNumber("cs", mcp.Description("DIO line for the active-low chip select (-1 = none, the whole capture is one transfer)"), takesLogicChannel()),
+		mcp.WithNumber("mode", mcp.Description("SPI mode (0-3, default 0)")),
+		mcp.WithBoolean("msb_first", mcp.Description("Bit order; true = MSB first (default)")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleSPIMonitor)
+
 	// ---- I2C ----
 	s.mcpServer.AddTool(mcp.NewTool("discovery_i2c_open",
 		mcp.WithDescription("Initialize I2C communication"),
-		mcp.WithNumber("sda", mcp.Description("DIO line for SDA"), mcp.Required()),
-		mcp.WithNumber("scl", mcp.Description("DIO line for SCL"), mcp.Required()),
+		mcp.WithNumber("sda", mcp.Description("DIO line for SDA"), mcp.Required(), takesDIOLine()),
+		mcp.WithNumber("scl", mcp.Description("DIO line for SCL"), mcp.Required(), takesDIOLine()),
 		mcp.WithNumber("clock_rate", mcp.Description("Clock rate in Hz (default 100kHz)")),
 		mcp.WithBoolean("stretching", mcp.Description("Enable clock stretching")),
 	), s.handleI2COpen)
 
+	s.mcpServer.AddTool(mcp.NewTool("discovery_i2c_recover",
+		mcp.WithDescription("Free a stuck I2C bus (target holding SDA low, e.g. after an interrupted transfer): clock SCL up to 9 times until SDA is released, generate a STOP and re-initialize I2C"),
+		mcp.WithNumber("sda", mcp.Description("DIO line for SDA"), mcp.Required(), takesDIOLine()),
+		mcp.WithNumber("scl", mcp.Description("DIO line for SCL"), mcp.Required(), takesDIOLine()),
+		mcp.WithNumber("clock_rate", mcp.Description("Clock rate in Hz for the recovery pulses and the re-initialized bus (default 100kHz)")),
+		mcp.WithBoolean("stretching", mcp.Description("Enable clock stretching after re-initialization")),
+	), s.handleI2CRecover)
+
 	s.mcpServer.AddTool(mcp.NewTool("discovery_i2c_scan",
 		mcp.WithDescription("Scan the I2C bus for connected devices (probes addresses 0x08-0x77)"),
 	), s.handleI2CScan)
@@ -325,4 +795,142 @@ func (s *DiscoveryMCPServer) registerTools() {
 	s.mcpServer.AddTool(mcp.NewTool("discovery_i2c_close",
 		mcp.WithDescription("Reset the I2C interface"),
 	), s.handleI2CClose)
+
+	// ---- Protocol self-tests ----
+	s.mcpServer.AddTool(mcp.NewTool("discovery_protocol_selftest",
+		mcp.WithDescription("Qualify cabling and fixtures before DUT work: run canned transfers at several rates and report the maximum reliable rate. "+
+			"UART and SPI need a loopback (TX to RX, MOSI to MISO) and check full-duplex data; I2C needs a target and checks that it acknowledges. "+
+			"The result states the wiring it expects. The protocol instrument is reset afterwards"),
+		mcp.WithString("protocol", mcp.Description("Protocol to test: uart, spi or i2c"), mcp.Required(), mcp.Enum("uart", "spi", "i2c")),
+		mcp.WithArray("rates", mcp.Description("Rates to test, in baud for UART and clock Hz for SPI and I2C (default: 9600 to 3M baud, 100 kHz to 20 MHz, 100 kHz to 1 MHz)"), mcp.WithNumberItems()),
+		mcp.WithNumber("repeats", mcp.Description("Transfers per rate (default 3, max 100)")),
+		mcp.WithNumber("bytes", mcp.Description("UART/SPI transfer length in bytes (default 64, max 1024)")),
+		mcp.WithNumber("rx", mcp.Description("UART RX DIO line (default 0)"), takesDIOLine()),
+		mcp.WithNumber("tx", mcp.Description("UART TX DIO line (default 1)"), takesDIOLine()),
+		mcp.WithNumber("cs", mcp.Description("SPI chip select DIO line (default 0)"), takesDIOLine()),
+		mcp.WithNumber("sck", mcp.Description("SPI clock DIO line (default 1)"), takesDIOLine()),
+		mcp.WithNumber("mosi", mcp.Description("SPI MOSI DIO line (default 2)"), takesDIOLine()),
+		mcp.WithNumber("miso", mcp.Description("SPI MISO DIO line (default 3)"), takesDIOLine()),
+		mcp.WithNumber("sda", mcp.Description("I2C SDA DIO line (default 0)"), takesDIOLine()),
+		mcp.WithNumber("scl", mcp.Description("I2C SCL DIO line (default 1)"), takesDIOLine()),
+		mcp.WithNumber("address", mcp.Description("7-bit address of the I2C target on the fixture, required for i2c")),
+	), s.handleProtocolSelfTest)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_protocol_max_rate",
+		mcp.WithDescription("Find the highest reliable SPI clock or UART baud rate against a DUT: repeat a verify transaction (send data, compare the response with expect under mask) "+
+			"and bisect between min_rate and max_rate to the passing/failing boundary. For UART the DUT must answer at every baud rate tried, e.g. with auto-baud. "+
+			"The protocol instrument is reset afterwards"),
+		mcp.WithString("protocol", mcp.Description("Protocol: spi or uart"), mcp.Required(), mcp.Enum("spi", "uart")),
+		mcp.WithString("data", mcp.Description("Verify transaction to send (hex string, e.g. '9F000000' to read a SPI flash JEDEC ID)"), mcp.Required()),
+		mcp.WithString("expect", mcp.Description("Expected response (hex). SPI compares the bytes clocked in during data, UART the bytes received after sending it"), mcp.Required()),
+		mcp.WithString("mask", mcp.Description("Bits of expect to compare (hex, as long as expect; default all), e.g. '00FFFFFF' to skip the byte clocked in with the command")),
+		mcp.WithNumber("min_rate", mcp.Description("Lowest rate, expected to pass (default 100 kHz for SPI, 9600 baud for UART)")),
+		mcp.WithNumber("max_rate", mcp.Description("Highest rate to try (default 50 MHz for SPI, 3M baud for UART)")),
+		mcp.WithNumber("resolution", mcp.Description("Stop when the failing rate is within this fraction above the passing one (default 0.05)")),
+		mcp.WithNumber("repeats", mcp.Description("Transactions per rate; a rate passes only if all do (default 5, max 100)")),
+		mcp.WithNumber("cs", mcp.Description("SPI chip select DIO line (default 0)"), takesDIOLine()),
+		mcp.WithNumber("sck", mcp.Description("SPI clock DIO line (default 1)"), takesDIOLine()),
+		mcp.WithNumber("mosi", mcp.Description("SPI MOSI DIO line (default 2)"), takesDIOLine()),
+		mcp.WithNumber("miso", mcp.Description("SPI MISO DIO line (default 3)"), takesDIOLine()),
+		mcp.WithNumber("mode", mcp.Description("SPI mode 0-3 (default 0)")),
+		mcp.WithBoolean("msb_first", mcp.Description("SPI bit order (default true)")),
+		mcp.WithNumber("rx", mcp.Description("UART RX DIO line (default 0)"), takesDIOLine()),
+		mcp.WithNumber("tx", mcp.Description("UART TX DIO line (default 1)"), takesDIOLine()),
+	), s.handleProtocolMaxRate)
+
+	// ---- Probe points ----
+	s.mcpServer.AddTool(mcp.NewTool("discovery_probe_define",
+		mcp.WithDescription("Define or replace a named probe point that bundles a connection with its measurement method and scaling, "+
+			"e.g. VOUT = scope channel 1 behind a 10x probe, or IIN = channel 2 across a 0.1 Ohm shunt. Later steps measure it by name"),
+		mcp.WithString("name", mcp.Description("Probe point name, e.g. 'VOUT'"), mcp.Required()),
+		mcp.WithString("instrument", mcp.Description("Instrument: scope (default) or dmm"), mcp.Enum("scope", "dmm")),
+		mcp.WithNumber("channel", mcp.Description("Oscilloscope channel (1-based), required for scope probe points")),
+		mcp.WithNumber("attenuation", mcp.Description("Probe factor the reading is multiplied by, e.g. 10 for a 10x probe (default 1)")),
+		mcp.WithString("coupling", mcp.Description("Input coupling applied before each reading: dc or ac (default: leave unchanged)"), mcp.Enum("dc", "ac")),
+		mcp.WithNumber("shunt", mcp.Description("Shunt resistance in Ohms the channel measures across; the reading is then reported as a current in Amperes")),
+		mcp.WithString("measure", mcp.Description("Default measurement: sample, mean, min, max, rms or pp for scope (default mean); "+
+			"dc_voltage, ac_voltage, dc_current, ac_current, resistance or temperature for dmm (default dc_voltage)")),
+		mcp.WithString("description", mcp.Description("Free-form note, e.g. 'buck output after LC filter'")),
+	), s.handleProbeDefine)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_probe_list",
+		mcp.WithDescription("List the defined probe points"),
+	), s.handleProbeList)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_probe_measure",
+		mcp.WithDescription("Measure a probe point by name, applying its coupling and scaling"),
+		mcp.WithString("name", mcp.Description("Probe point name"), mcp.Required()),
+		mcp.WithString("measure", mcp.Description("Override the probe point's default measurement")),
+		mcp.WithNumber("timeout", mcp.Description("Seconds to wait for each triggered acquisition before giving up (default 10, 0 = wait indefinitely)")),
+	), s.handleProbeMeasure)
+
+	// ---- Pins ----
+	s.mcpServer.AddTool(mcp.NewTool("discovery_pin_define",
+		mcp.WithDescription("Name a DIO line after the signal wired to it, e.g. SDA = DIO 0 or RESET_N = DIO 5. "+
+			"Every tool that takes a DIO line or logic channel then accepts the name, which avoids wiring mistakes"),
+		mcp.WithString("name", mcp.Description("Signal name, e.g. 'RESET_N'; names match without regard to case"), mcp.Required()),
+		mcp.WithNumber("line", mcp.Description("DIO line the signal is wired to (required unless remove is set)")),
+		mcp.WithBoolean("remove", mcp.Description("Remove the name instead of defining it (default false)")),
+	), s.handlePinDefine)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_pin_list",
+		mcp.WithDescription("List the defined pin names and their DIO lines"),
+	), s.handlePinList)
+
+	// ---- Watches ----
+	s.mcpServer.AddTool(mcp.NewTool("discovery_watch_add",
+		mcp.WithDescription("Define a named watch expression whose value is reported by the watches:// resource. "+
+			"Expressions: device.temperature, supplies.faults, scope.chN.{sample,mean,min,max,rms,pp}, dio.N, "+
+			"dmm.{dc_voltage,ac_voltage,dc_current,ac_current,resistance,temperature}"),
+		mcp.WithString("name", mcp.Description("Watch name (may be omitted when expression is written as 'name = expression')")),
+		mcp.WithString("expression", mcp.Description("Value to watch, e.g. 'scope.ch1.mean'"), mcp.Required()),
+		mcp.WithNumber("interval", mcp.Description("Seconds between re-evaluations; reads in between return the cached value (default 0 = every read)")),
+	), s.handleWatchAdd)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_watch_remove",
+		mcp.WithDescription("Remove a watch expression"),
+		mcp.WithString("name", mcp.Description("Watch name"), mcp.Required()),
+	), s.handleWatchRemove)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_watch_read",
+		mcp.WithDescription("Evaluate all watch expressions and return their values (same content as the watches:// resource)"),
+	), s.handleWatchRead)
+
+	// ---- Captures ----
+	s.mcpServer.AddTool(mcp.NewTool("discovery_capture_annotate",
+		mcp.WithDescription("Attach a free-text note and/or tags to a stored capture, e.g. to label data 'before rework' and 'after rework'"),
+		mcp.WithString("capture", mcp.Description("Capture name or captures:// URI returned by the tool that stored it"), mcp.Required()),
+		mcp.WithString("note", mcp.Description("Free-text note to append")),
+		mcp.WithArray("tags", mcp.Description("Tags to add"), mcp.WithStringItems()),
+	), s.handleCaptureAnnotate)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_capture_search",
+		mcp.WithDescription("List stored captures with their tags and notes, optionally filtered by tags and text"),
+		mcp.WithArray("tags", mcp.Description("Only captures carrying all of these tags"), mcp.WithStringItems()),
+		mcp.WithString("text", mcp.Description("Only captures whose name or notes contain this text (case-insensitive)")),
+	), s.handleCaptureSearch)
+
+	s.mcpServer.AddTool(mcp.NewTool("discovery_capture_process",
+		mcp.WithDescription("Run a stored capture through an analysis pipeline of named stages and attach the results to the capture, where discovery_capture_search lists them. "+
+			"Stages: filter (type moving_average with window, lowpass with cutoff Hz, or decimate with factor), decode (logic levels at threshold with hysteresis: edges, frequency, duty cycle), "+
+			"measure (stats: mean, min, max, rms, pp) and export (store the processed signal as a new capture)"),
+		mcp.WithString("capture", mcp.Description("Capture name or captures:// URI"), mcp.Required()),
+		mcp.WithString("pipeline", mcp.Description("Name of a pipeline from the --config file")),
+		mcp.WithArray("stages", mcp.Description("Pipeline stages in order, instead of pipeline, e.g. [{\"stage\":\"filter\",\"type\":\"lowpass\",\"cutoff\":1e4},{\"stage\":\"measure\"}]"), mcp.Items(map[string]any{"type": "object"})),
+		mcp.WithString("array", mcp.Description("Sample array of the capture to process (default data)")),
+	), s.handleCaptureProcess)
+}
+
+func (s *DiscoveryMCPServer) registerResources() {
+	// ---- Watches ----
+	s.mcpServer.AddResource(mcp.NewResource(watchesURI, "Watches",
+		mcp.WithResourceDescription("Current values of all watch expressions defined with discovery_watch_add"),
+		mcp.WithMIMEType("application/json"),
+	), s.handleWatchesResource)
+
+	// ---- Captures ----
+	s.mcpServer.AddResourceTemplate(mcp.NewResourceTemplate(capturesURIPrefix+"{name}", "Captures",
+		mcp.WithTemplateDescription("Recordings saved by tools called with store=true"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), s.handleCaptureResource)
 }
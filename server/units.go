@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// Number styles for values in text results and messages. Structured result
+// fields are always plain numbers.
+const (
+	// numbersRaw prints values as plain floats, e.g. "0.001234 V".
+	numbersRaw = "raw"
+	// numbersEngineering prints values with an SI prefix, e.g. "1.234 mV".
+	numbersEngineering = "engineering"
+)
+
+// siPrefixes maps powers of 1000 (from 10^-12) to SI prefixes.
+var siPrefixes = []string{"p", "n", "µ", "m", "", "k", "M", "G"}
+
+// dmmModeUnits is the unit of a DMM reading in each mode.
+var dmmModeUnits = map[dwf.DMMMode]string{
+	dwf.DMMModeACVoltage:     "V",
+	dwf.DMMModeDCVoltage:     "V",
+	dwf.DMMModeACCurrent:     "A",
+	dwf.DMMModeDCCurrent:     "A",
+	dwf.DMMModeResistance:    "Ω",
+	dwf.DMMModeContinuity:    "Ω",
+	dwf.DMMModeDiode:         "V",
+	dwf.DMMModeTemperature:   "°C",
+	dwf.DMMModeACLowCurrent:  "A",
+	dwf.DMMModeDCLowCurrent:  "A",
+	dwf.DMMModeACHighCurrent: "A",
+	dwf.DMMModeDCHighCurrent: "A",
+}
+
+// dmmModeNames are the names of the DMM modes in results.
+var dmmModeNames = map[dwf.DMMMode]string{
+	dwf.DMMModeACVoltage:     "ac_voltage",
+	dwf.DMMModeDCVoltage:     "dc_voltage",
+	dwf.DMMModeACCurrent:     "ac_current",
+	dwf.DMMModeDCCurrent:     "dc_current",
+	dwf.DMMModeResistance:    "resistance",
+	dwf.DMMModeContinuity:    "continuity",
+	dwf.DMMModeDiode:         "diode",
+	dwf.DMMModeTemperature:   "temperature",
+	dwf.DMMModeACLowCurrent:  "ac_low_current",
+	dwf.DMMModeDCLowCurrent:  "dc_low_current",
+	dwf.DMMModeACHighCurrent: "ac_high_current",
+	dwf.DMMModeDCHighCurrent: "dc_high_current",
+}
+
+// textFormat is the session's number style for text results.
+type textFormat struct {
+	mu    sync.Mutex
+	style string
+}
+
+func newTextFormat() *textFormat {
+	return &textFormat{style: numbersRaw}
+}
+
+func (f *textFormat) get() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.style
+}
+
+func (f *textFormat) set(style string) error {
+	style = strings.ToLower(style)
+	if style != numbersRaw && style != numbersEngineering {
+		return fmt.Errorf("invalid number style %q: expected raw or engineering", style)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.style = style
+	return nil
+}
+
+// quantity formats v in unit for a text result. The raw style uses
+// rawFormat, a format for v that includes the unit if the result always
+// showed one; the engineering style always appends unit.
+func (f *textFormat) quantity(v float64, unit, rawFormat string) string {
+	if f.get() == numbersRaw {
+		return fmt.Sprintf(rawFormat, v)
+	}
+	return engineering(v, unit)
+}
+
+// engineering writes v in unit to four significant digits with the SI
+// prefix that keeps the mantissa in [1, 1000), e.g. "1.234 mV" for
+// 0.0012341 V. Values outside the prefix range, zero and non-finite values
+// have no prefix.
+func engineering(v float64, unit string) string {
+	withUnit := func(num, prefix string) string {
+		return strings.TrimSpace(num + " " + prefix + unit)
+	}
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return withUnit(strconv.FormatFloat(v, 'g', -1, 64), "")
+	}
+	// Round first so that e.g. 999.96 becomes 1 k rather than 1000.
+	v, _ = strconv.ParseFloat(strconv.FormatFloat(v, 'g', 4, 64), 64)
+	exp := int(math.Floor(math.Log10(math.Abs(v)) / 3))
+	mantissa := v / math.Pow(1000, float64(exp))
+	// Log10 is inexact at powers of ten, e.g. 2.9999999999999996 for 1000.
+	if math.Abs(mantissa) >= 1000 {
+		exp++
+		mantissa /= 1000
+	}
+	idx := exp + 4
+	if idx < 0 || idx >= len(siPrefixes) {
+		return withUnit(strconv.FormatFloat(v, 'g', 4, 64), "")
+	}
+	return withUnit(strconv.FormatFloat(mantissa, 'g', 4, 64), siPrefixes[idx])
+}
+
+func (s *DiscoveryMCPServer) handleSessionFormat(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if style := getString(req.Params.Arguments, "numbers", ""); style != "" {
+		if err := s.format.set(style); err != nil {
+			return errResult(err), nil
+		}
+	}
+	return jsonResult(map[string]interface{}{
+		"numbers": s.format.get(),
+	}), nil
+}
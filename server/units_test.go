@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func TestEngineering(t *testing.T) {
+	for _, tc := range []struct {
+		v    float64
+		unit string
+		want string
+	}{
+		{0.0012341, "V", "1.234 mV"},
+		{-2.5e-6, "A", "-2.5 µA"},
+		{1000, "Hz", "1 kHz"},
+		{999.96, "Hz", "1 kHz"},
+		{3.3, "V", "3.3 V"},
+		{12.5e6, "", "12.5 M"},
+		{42, "", "42"},
+		{0, "V", "0 V"},
+		{1e-15, "F", "1e-15 F"},
+		{math.NaN(), "V", "NaN V"},
+	} {
+		if got := engineering(tc.v, tc.unit); got != tc.want {
+			t.Errorf("engineering(%g, %q) = %q, want %q", tc.v, tc.unit, got, tc.want)
+		}
+	}
+}
+
+func TestHandleSessionFormat(t *testing.T) {
+	s, dev := newTestServer()
+	dev.scope.measureVal = 0.0012345
+	text := func(result *mcp.CallToolResult) string { return result.Content[0].(mcp.TextContent).Text }
+
+	result, _ := s.handleScopeMeasure(context.Background(), makeReq(map[string]any{"channel": float64(1)}))
+	if got := text(result); got != "0.001234 V" && got != "0.001235 V" {
+		t.Errorf("raw measure = %q", got)
+	}
+
+	if result, _ := s.handleSessionFormat(context.Background(), makeReq(map[string]any{"numbers": "engineering"})); result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	result, _ = s.handleScopeMeasure(context.Background(), makeReq(map[string]any{"channel": float64(1)}))
+	if got := text(result); got != "1.234 mV" && got != "1.235 mV" {
+		t.Errorf("engineering measure = %q", got)
+	}
+	dev.dmm.measureVal = 4700
+	result, _ = s.handleDMMMeasure(context.Background(), makeReq(map[string]any{"mode": float64(dwf.DMMModeResistance)}))
+	if got := text(result); !strings.Contains(got, `"text":"4.7 kΩ"`) {
+		t.Errorf("engineering resistance = %q", got)
+	}
+	result, _ = s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{"master_state": true, "ramp_time": 0.25}))
+	if got := text(result); !strings.Contains(got, "ramps up over 250 ms") {
+		t.Errorf("engineering supplies message = %q", got)
+	}
+
+	if result, _ := s.handleSessionFormat(context.Background(), makeReq(map[string]any{"numbers": "roman"})); !result.IsError {
+		t.Error("expected error for an unknown style")
+	}
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// unknownDevice keys usage recorded before a device reported its serial.
+const unknownDevice = "unknown"
+
+// deviceUsage holds the cumulative counters of one physical device.
+type deviceUsage struct {
+	Opens           int64            `json:"opens"`
+	SupplyToggles   int64            `json:"supply_toggles"`
+	SupplyOnSeconds float64          `json:"supply_on_seconds"`
+	RelayToggles    int64            `json:"relay_toggles"`
+	Captures        map[string]int64 `json:"captures"`
+	FirstUsed       time.Time        `json:"first_used"`
+	LastUsed        time.Time        `json:"last_used"`
+}
+
+// usageTracker accumulates device wear counters and persists them to a JSON
+// file, so heavy automated use of shared instruments can be spotted across
+// server runs.
+type usageTracker struct {
+	mu      sync.Mutex
+	path    string
+	now     func() time.Time
+	devices map[string]*deviceUsage
+
+	// serial is the device the counters are currently attributed to.
+	serial string
+	// supplyOnSince is when the supplies were last switched on, zero if off.
+	supplyOnSince time.Time
+	// relays holds the last state of each relay-backed setting, so only
+	// actual changes are counted.
+	relays map[string]int
+}
+
+func newUsageTracker() *usageTracker {
+	return &usageTracker{
+		now:     time.Now,
+		devices: map[string]*deviceUsage{},
+		serial:  unknownDevice,
+		relays:  map[string]int{},
+	}
+}
+
+// DefaultUsagePath returns the default location of the usage statistics file.
+func DefaultUsagePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "discovery-mcp", "usage.json")
+}
+
+// load reads the counters stored at path and persists all further updates
+// there. A missing file starts empty statistics.
+func (u *usageTracker) load(path string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		devices := map[string]*deviceUsage{}
+		if err := json.Unmarshal(data, &devices); err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		u.devices = devices
+	}
+	u.path = path
+	return nil
+}
+
+// saveLocked writes the counters to the usage file; u.mu must be held.
+// Persisting is best-effort, so a read-only file never fails a tool call.
+func (u *usageTracker) saveLocked() {
+	if u.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(u.devices, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(u.path), 0o755); err != nil {
+		return
+	}
+	tmp := u.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, u.path)
+}
+
+// currentLocked returns the counters of the current device; u.mu must be held.
+func (u *usageTracker) currentLocked() *deviceUsage {
+	d, ok := u.devices[u.serial]
+	if !ok {
+		d = &deviceUsage{FirstUsed: u.now()}
+	}
+	if d.Captures == nil {
+		d.Captures = map[string]int64{}
+	}
+	u.devices[u.serial] = d
+	d.LastUsed = u.now()
+	return d
+}
+
+// stopSupplyLocked adds the running supply-on time; u.mu must be held.
+func (u *usageTracker) stopSupplyLocked(d *deviceUsage) {
+	if !u.supplyOnSince.IsZero() {
+		d.SupplyOnSeconds += u.now().Sub(u.supplyOnSince).Seconds()
+		u.supplyOnSince = time.Time{}
+	}
+}
+
+// opened attributes further usage to the device with the given serial.
+func (u *usageTracker) opened(serial string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if serial == "" {
+		serial = unknownDevice
+	}
+	u.serial = serial
+	u.relays = map[string]int{}
+	u.currentLocked().Opens++
+	u.saveLocked()
+}
+
+// closed stops the supply timer when the device is released.
+func (u *usageTracker) closed() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.stopSupplyLocked(u.currentLocked())
+	u.relays = map[string]int{}
+	u.saveLocked()
+}
+
+// supply records the master supply state, counting on/off transitions and
+// the time spent on.
+func (u *usageTracker) supply(on bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	d := u.currentLocked()
+	if on == !u.supplyOnSince.IsZero() {
+		return
+	}
+	d.SupplyToggles++
+	if on {
+		u.supplyOnSince = u.now()
+	} else {
+		u.stopSupplyLocked(d)
+	}
+	u.saveLocked()
+}
+
+// relay records the state of a relay-backed setting such as input coupling
+// or the DMM function; a toggle is counted whenever the state changes.
+func (u *usageTracker) relay(name string, state int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if last, ok := u.relays[name]; ok && last == state {
+		return
+	}
+	_, known := u.relays[name]
+	u.relays[name] = state
+	if !known {
+		// The power-on state is unknown, so the first setting is not a toggle.
+		return
+	}
+	u.currentLocked().RelayToggles++
+	u.saveLocked()
+}
+
+// captured counts n acquisitions of the given instrument.
+func (u *usageTracker) captured(instrument string, n int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.currentLocked().Captures[instrument] += int64(n)
+	u.saveLocked()
+}
+
+// file returns the path the counters are saved to, empty if kept in memory.
+func (u *usageTracker) file() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.path
+}
+
+// snapshot returns the counters of every known device, including the
+// running supply-on time, sorted by serial.
+func (u *usageTracker) snapshot() []map[string]interface{} {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	serials := make([]string, 0, len(u.devices))
+	for serial := range u.devices {
+		serials = append(serials, serial)
+	}
+	sort.Strings(serials)
+
+	out := make([]map[string]interface{}, 0, len(serials))
+	for _, serial := range serials {
+		d := u.devices[serial]
+		onSeconds := d.SupplyOnSeconds
+		supplyOn := serial == u.serial && !u.supplyOnSince.IsZero()
+		if supplyOn {
+			onSeconds += u.now().Sub(u.supplyOnSince).Seconds()
+		}
+		captures := map[string]int64{}
+		for k, v := range d.Captures {
+			captures[k] = v
+		}
+		out = append(out, map[string]interface{}{
+			"serial":            serial,
+			"current":           serial == u.serial,
+			"opens":             d.Opens,
+			"supply_toggles":    d.SupplyToggles,
+			"supply_on_seconds": onSeconds,
+			"supply_on":         supplyOn,
+			"relay_toggles":     d.RelayToggles,
+			"captures":          captures,
+			"first_used":        d.FirstUsed.Format(time.RFC3339),
+			"last_used":         d.LastUsed.Format(time.RFC3339),
+		})
+	}
+	return out
+}
+
+// LoadUsage reads persisted usage statistics from path and keeps saving them
+// there. An empty path keeps the statistics in memory only.
+func (s *DiscoveryMCPServer) LoadUsage(path string) error {
+	if path == "" {
+		return nil
+	}
+	return s.usage.load(path)
+}
+
+func (s *DiscoveryMCPServer) handleUsageStats(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	result := map[string]interface{}{
+		"devices": s.usage.snapshot(),
+	}
+	if path := s.usage.file(); path != "" {
+		result["file"] = path
+	}
+	return jsonResult(result), nil
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func TestUsageTrackerCounters(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	u := newUsageTracker()
+	u.now = func() time.Time { return clock }
+
+	u.opened("SN:1")
+	u.supply(true)
+	u.supply(true) // already on, not a toggle
+	clock = clock.Add(90 * time.Second)
+	u.supply(false)
+	u.supply(false)
+
+	u.relay("dmm.mode", 1) // initial state, not a toggle
+	u.relay("dmm.mode", 1)
+	u.relay("dmm.mode", 4)
+	u.relay("dmm.mode", 1)
+
+	u.captured("scope", 1)
+	u.captured("scope", 8)
+	u.captured("logic", 1)
+
+	d := u.devices["SN:1"]
+	if d.Opens != 1 {
+		t.Errorf("opens = %d, want 1", d.Opens)
+	}
+	if d.SupplyToggles != 2 {
+		t.Errorf("supply toggles = %d, want 2", d.SupplyToggles)
+	}
+	if d.SupplyOnSeconds != 90 {
+		t.Errorf("supply on seconds = %g, want 90", d.SupplyOnSeconds)
+	}
+	if d.RelayToggles != 2 {
+		t.Errorf("relay toggles = %d, want 2", d.RelayToggles)
+	}
+	if d.Captures["scope"] != 9 || d.Captures["logic"] != 1 {
+		t.Errorf("captures = %v", d.Captures)
+	}
+}
+
+func TestUsageTrackerRunningSupplyTime(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	u := newUsageTracker()
+	u.now = func() time.Time { return clock }
+
+	u.opened("SN:1")
+	u.supply(true)
+	clock = clock.Add(30 * time.Second)
+	snap := u.snapshot()
+	if len(snap) != 1 || snap[0]["supply_on_seconds"] != 30.0 || snap[0]["supply_on"] != true {
+		t.Errorf("unexpected snapshot %v", snap)
+	}
+
+	clock = clock.Add(10 * time.Second)
+	u.closed()
+	if got := u.devices["SN:1"].SupplyOnSeconds; got != 40 {
+		t.Errorf("supply on seconds after close = %g, want 40", got)
+	}
+}
+
+func TestUsageTrackerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "usage.json")
+
+	u := newUsageTracker()
+	if err := u.load(path); err != nil {
+		t.Fatalf("load of missing file: %v", err)
+	}
+	u.opened("SN:1")
+	u.captured("scope", 3)
+
+	reloaded := newUsageTracker()
+	if err := reloaded.load(path); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	d, ok := reloaded.devices["SN:1"]
+	if !ok {
+		t.Fatal("device missing after reload")
+	}
+	if d.Opens != 1 || d.Captures["scope"] != 3 {
+		t.Errorf("reloaded counters = %+v", d)
+	}
+}
+
+func TestHandleUsageStats(t *testing.T) {
+	s, dev := newTestServer()
+	dev.openInfo = &dwf.DeviceInfo{SerialNumber: "SN:ABC"}
+	dev.scope.recordData = []float64{0.1}
+
+	s.handleDeviceOpen(context.Background(), makeReq(map[string]any{}))
+	s.handleScopeRecord(context.Background(), makeReq(map[string]any{"channel": float64(1)}))
+	// A failed acquisition is not counted.
+	dev.scope.recordErr = errors.New("timeout")
+	s.handleScopeRecord(context.Background(), makeReq(map[string]any{"channel": float64(1)}))
+	s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{"master_state": true}))
+
+	result, err := s.handleUsageStats(context.Background(), makeReq(map[string]any{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got struct {
+		Devices []struct {
+			Serial        string           `json:"serial"`
+			Opens         int64            `json:"opens"`
+			SupplyToggles int64            `json:"supply_toggles"`
+			Captures      map[string]int64 `json:"captures"`
+		} `json:"devices"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got.Devices) != 1 {
+		t.Fatalf("devices = %+v, want one", got.Devices)
+	}
+	d := got.Devices[0]
+	if d.Serial != "SN:ABC" || d.Opens != 1 || d.SupplyToggles != 1 || d.Captures["scope"] != 1 {
+		t.Errorf("unexpected usage %+v", d)
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxSupplyOnTime bounds the max_on_time of discovery_supplies_switch.
+const maxSupplyOnTime = 24 * 3600
+
+// supplyWatchdog switches the supplies off when no tool call has refreshed
+// it for its time, so that an interrupted session does not leave the device
+// under test powered indefinitely.
+type supplyWatchdog struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	timeout time.Duration
+	// deadline is when the timer fires, zero while disarmed.
+	deadline time.Time
+	// expire switches the supplies off; it runs on the timer's goroutine.
+	expire func()
+}
+
+func newSupplyWatchdog(expire func()) *supplyWatchdog {
+	return &supplyWatchdog{expire: expire}
+}
+
+// arm starts the watchdog with the given time, replacing an armed one.
+func (w *supplyWatchdog) arm(timeout time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopLocked()
+	w.timeout = timeout
+	w.deadline = time.Now().Add(timeout)
+	var timer *time.Timer
+	timer = time.AfterFunc(timeout, func() {
+		w.mu.Lock()
+		// A refresh or disarm may have raced with the timer firing.
+		if w.timer != timer || time.Now().Before(w.deadline) {
+			w.mu.Unlock()
+			return
+		}
+		w.timer, w.deadline = nil, time.Time{}
+		w.mu.Unlock()
+		w.expire()
+	})
+	w.timer = timer
+}
+
+// disarm stops the watchdog, if armed.
+func (w *supplyWatchdog) disarm() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopLocked()
+}
+
+func (w *supplyWatchdog) stopLocked() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer, w.deadline = nil, time.Time{}
+}
+
+// refresh restarts the time of an armed watchdog.
+func (w *supplyWatchdog) refresh() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer == nil {
+		return
+	}
+	w.deadline = time.Now().Add(w.timeout)
+	w.timer.Reset(w.timeout)
+}
+
+// remaining returns the time until an armed watchdog switches the supplies
+// off.
+func (w *supplyWatchdog) remaining() (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer == nil {
+		return 0, false
+	}
+	return time.Until(w.deadline), true
+}
+
+// refreshWatchdog is the tool middleware that counts every tool call as a
+// sign of life, both when it starts and when it ends.
+func (s *DiscoveryMCPServer) refreshWatchdog(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		s.watchdog.refresh()
+		defer s.watchdog.refresh()
+		return next(ctx, req)
+	}
+}
+
+// supplyTimeout switches the supplies off when the watchdog expires and
+// warns the connected clients.
+func (s *DiscoveryMCPServer) supplyTimeout() {
+	msg := "no tool call within max_on_time: power supplies switched off"
+	if err := s.device.Supply().Close(); err != nil {
+		msg = fmt.Sprintf("no tool call within max_on_time, but switching the power supplies off failed: %v", err)
+	} else {
+		s.usage.supply(false)
+	}
+	if s.mcpServer != nil {
+		s.mcpServer.SendNotificationToAllClients(logNotification, map[string]any{
+			"level":  "warning",
+			"logger": "discovery_supplies",
+			"data":   msg,
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestSupplyWatchdog(t *testing.T) {
+	var fired atomic.Int32
+	w := newSupplyWatchdog(func() { fired.Add(1) })
+
+	w.arm(200 * time.Millisecond)
+	for range 4 {
+		time.Sleep(50 * time.Millisecond)
+		w.refresh()
+	}
+	if fired.Load() != 0 {
+		t.Fatal("watchdog fired although it was refreshed")
+	}
+	if left, ok := w.remaining(); !ok || left <= 0 || left > 200*time.Millisecond {
+		t.Errorf("remaining = %v, %v", left, ok)
+	}
+	time.Sleep(400 * time.Millisecond)
+	if fired.Load() != 1 {
+		t.Fatalf("fired %d times, want once", fired.Load())
+	}
+	if _, ok := w.remaining(); ok {
+		t.Error("expected the watchdog to be disarmed after firing")
+	}
+
+	w.arm(20 * time.Millisecond)
+	w.disarm()
+	time.Sleep(50 * time.Millisecond)
+	if fired.Load() != 1 {
+		t.Error("a disarmed watchdog fired")
+	}
+}
+
+func TestHandleSuppliesSwitchMaxOnTime(t *testing.T) {
+	s, dev := newTestServer()
+	on := map[string]any{"master_state": true, "positive_state": true, "positive_voltage": 3.3, "max_on_time": 0.2}
+	result, _ := s.handleSuppliesSwitch(context.Background(), makeReq(on))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "switch off after 0.2 s") {
+		t.Errorf("expected the auto-off in the message, got %q", text)
+	}
+
+	// Tool calls through the middleware keep the supplies on.
+	handler := s.refreshWatchdog(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	for range 4 {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = handler(context.Background(), makeReq(nil))
+	}
+	if _, ok := s.watchdog.remaining(); !ok {
+		t.Fatal("watchdog expired despite tool calls")
+	}
+	time.Sleep(400 * time.Millisecond)
+	if _, ok := s.watchdog.remaining(); ok {
+		t.Fatal("watchdog did not expire without tool calls")
+	}
+	s.usage.mu.Lock()
+	off := s.usage.supplyOnSince.IsZero()
+	s.usage.mu.Unlock()
+	if !off {
+		t.Error("expected the supplies to be recorded as off")
+	}
+
+	// Switching off or without max_on_time disarms it.
+	_, _ = s.handleSuppliesSwitch(context.Background(), makeReq(on))
+	_, _ = s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{"master_state": false}))
+	if _, ok := s.watchdog.remaining(); ok {
+		t.Error("expected switching off to disarm the watchdog")
+	}
+
+	result, _ = s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{"master_state": true, "max_on_time": -1.0}))
+	if !result.IsError {
+		t.Error("expected error for a negative max_on_time")
+	}
+
+	// A failed switch-off leaves the usage record on.
+	dev.supply.closeErr = errors.New("device gone")
+	_, _ = s.handleSuppliesSwitch(context.Background(), makeReq(map[string]any{"master_state": true}))
+	s.supplyTimeout()
+	s.usage.mu.Lock()
+	off = s.usage.supplyOnSince.IsZero()
+	s.usage.mu.Unlock()
+	if off {
+		t.Error("expected the supplies to stay recorded as on after a failed switch-off")
+	}
+}
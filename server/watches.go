@@ -0,0 +1,278 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// watchesURI is the resource that exposes all watch values in one read.
+const watchesURI = "watches://"
+
+// watchEval reads one value from the device.
+type watchEval func(ctx context.Context, dev dwf.DiscoveryDevice) (float64, error)
+
+// watch is a named expression together with its last evaluated value.
+type watch struct {
+	name     string
+	expr     string
+	interval time.Duration
+	eval     watchEval
+
+	value   float64
+	err     error
+	updated time.Time
+}
+
+// watchSet holds the watches defined by the client.
+type watchSet struct {
+	mu      sync.Mutex
+	watches map[string]*watch
+}
+
+func newWatchSet() *watchSet {
+	return &watchSet{watches: map[string]*watch{}}
+}
+
+// dmmWatchModes maps the DMM watch keywords to measurement modes.
+var dmmWatchModes = map[string]dwf.DMMMode{
+	"ac_voltage":  dwf.DMMModeACVoltage,
+	"dc_voltage":  dwf.DMMModeDCVoltage,
+	"ac_current":  dwf.DMMModeACCurrent,
+	"dc_current":  dwf.DMMModeDCCurrent,
+	"resistance":  dwf.DMMModeResistance,
+	"temperature": dwf.DMMModeTemperature,
+}
+
+// scopeWatchStats lists the statistics a scope watch can compute from a record.
+var scopeWatchStats = map[string]bool{
+	"mean": true, "min": true, "max": true, "rms": true, "pp": true,
+}
+
+// compileWatch parses a watch expression into an evaluation function.
+// Supported expressions:
+//
+//	device.temperature
+//	supplies.faults
+//	scope.chN.sample | mean | min | max | rms | pp
+//	dio.N
+//	dmm.dc_voltage | ac_voltage | dc_current | ac_current | resistance | temperature
+func compileWatch(expr string) (watchEval, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(expr)), ".")
+	switch {
+	case len(parts) == 2 && parts[0] == "device" && parts[1] == "temperature":
+		return func(ctx context.Context, dev dwf.DiscoveryDevice) (float64, error) {
+			return dev.Temperature()
+		}, nil
+
+	case len(parts) == 2 && parts[0] == "supplies" && parts[1] == "faults":
+		return func(ctx context.Context, dev dwf.DiscoveryDevice) (float64, error) {
+			st, err := dev.Supply().Status()
+			if err != nil {
+				return 0, err
+			}
+			return float64(len(supplyTrips(st))), nil
+		}, nil
+
+	case len(parts) == 3 && parts[0] == "scope" && strings.HasPrefix(parts[1], "ch"):
+		ch, err := strconv.Atoi(strings.TrimPrefix(parts[1], "ch"))
+		if err != nil || ch < 1 {
+			return nil, fmt.Errorf("invalid scope channel %q", parts[1])
+		}
+		stat := parts[2]
+		if stat == "sample" {
+			return func(ctx context.Context, dev dwf.DiscoveryDevice) (float64, error) {
+				return dev.Scope().Measure(ch)
+			}, nil
+		}
+		if !scopeWatchStats[stat] {
+			return nil, fmt.Errorf("unknown statistic %q (expected sample, mean, min, max, rms or pp)", stat)
+		}
+		return func(ctx context.Context, dev dwf.DiscoveryDevice) (float64, error) {
+			ctx, cancel := context.WithTimeout(ctx, time.Duration(defaultAcquisitionTimeout*float64(time.Second)))
+			defer cancel()
+			data, err := dev.Scope().Record(ctx, ch)
+			if err != nil {
+				return 0, err
+			}
+			return sampleStat(data, stat)
+		}, nil
+
+	case len(parts) == 2 && parts[0] == "dio":
+		ch, err := strconv.Atoi(parts[1])
+		if err != nil || ch < 0 {
+			return nil, fmt.Errorf("invalid DIO channel %q", parts[1])
+		}
+		return func(ctx context.Context, dev dwf.DiscoveryDevice) (float64, error) {
+			high, err := dev.Static().GetState(ch)
+			if err != nil {
+				return 0, err
+			}
+			if high {
+				return 1, nil
+			}
+			return 0, nil
+		}, nil
+
+	case len(parts) == 2 && parts[0] == "dmm":
+		mode, ok := dmmWatchModes[parts[1]]
+		if !ok {
+			return nil, fmt.Errorf("unknown DMM quantity %q", parts[1])
+		}
+		return func(ctx context.Context, dev dwf.DiscoveryDevice) (float64, error) {
+			r, err := dev.DMM().Measure(mode, 0, false)
+			return r.Value, err
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported watch expression %q", expr)
+}
+
+// sampleStat reduces a sample buffer to a single statistic.
+func sampleStat(data []float64, stat string) (float64, error) {
+	if len(data) == 0 {
+		return 0, fmt.Errorf("no samples")
+	}
+	minV, maxV := data[0], data[0]
+	var sum, sumSq float64
+	for _, v := range data {
+		minV = math.Min(minV, v)
+		maxV = math.Max(maxV, v)
+		sum += v
+		sumSq += v * v
+	}
+	n := float64(len(data))
+	switch stat {
+	case "mean":
+		return sum / n, nil
+	case "min":
+		return minV, nil
+	case "max":
+		return maxV, nil
+	case "rms":
+		return math.Sqrt(sumSq / n), nil
+	case "pp":
+		return maxV - minV, nil
+	}
+	return 0, fmt.Errorf("unknown statistic %q", stat)
+}
+
+// add defines or replaces a watch.
+func (ws *watchSet) add(name, expr string, interval time.Duration) error {
+	eval, err := compileWatch(expr)
+	if err != nil {
+		return err
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.watches[name] = &watch{name: name, expr: expr, interval: interval, eval: eval}
+	return nil
+}
+
+// remove deletes a watch and reports whether it existed.
+func (ws *watchSet) remove(name string) bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	_, ok := ws.watches[name]
+	delete(ws.watches, name)
+	return ok
+}
+
+// snapshot evaluates every watch that is due and returns all values, sorted
+// by name. Watches with an interval are re-read only once it has elapsed.
+func (ws *watchSet) snapshot(ctx context.Context, dev dwf.DiscoveryDevice) []map[string]interface{} {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	names := make([]string, 0, len(ws.watches))
+	for name := range ws.watches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	out := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		w := ws.watches[name]
+		if w.updated.IsZero() || now.Sub(w.updated) >= w.interval {
+			w.value, w.err = w.eval(ctx, dev)
+			w.updated = now
+		}
+		entry := map[string]interface{}{
+			"name":       w.name,
+			"expression": w.expr,
+			"updated":    w.updated.Format(time.RFC3339Nano),
+		}
+		if w.interval > 0 {
+			entry["interval"] = w.interval.Seconds()
+		}
+		if w.err != nil {
+			entry["error"] = w.err.Error()
+		} else {
+			entry["value"] = w.value
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+func (s *DiscoveryMCPServer) handleWatchAdd(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := strings.TrimSpace(getString(req.Params.Arguments, "name", ""))
+	expr := getString(req.Params.Arguments, "expression", "")
+	// Accept the "name = expression" shorthand.
+	if name == "" {
+		if lhs, rhs, ok := strings.Cut(expr, "="); ok {
+			name, expr = strings.TrimSpace(lhs), rhs
+		}
+	}
+	if name == "" {
+		return errResult(fmt.Errorf("watch name is required")), nil
+	}
+	interval := getFloat(req.Params.Arguments, "interval", 0)
+	if interval < 0 {
+		return errResult(fmt.Errorf("interval must not be negative")), nil
+	}
+	if err := s.watches.add(name, strings.TrimSpace(expr), time.Duration(interval*float64(time.Second))); err != nil {
+		return errResult(err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Watch %q added; read %s for its value", name, watchesURI)), nil
+}
+
+func (s *DiscoveryMCPServer) handleWatchRemove(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := getString(req.Params.Arguments, "name", "")
+	if !s.watches.remove(name) {
+		return errResult(fmt.Errorf("no watch named %q", name)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Watch %q removed", name)), nil
+}
+
+func (s *DiscoveryMCPServer) handleWatchRead(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return jsonResult(map[string]interface{}{
+		"watches": s.watches.snapshot(ctx, s.device),
+	}), nil
+}
+
+func (s *DiscoveryMCPServer) handleWatchesResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	data, err := json.Marshal(finiteValue(map[string]interface{}{
+		"watches": s.watches.snapshot(ctx, s.device),
+	}, "", map[string]bool{}))
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      req.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(data),
+		},
+	}, nil
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func readWatches(t *testing.T, s *DiscoveryMCPServer) []map[string]any {
+	t.Helper()
+	var req mcp.ReadResourceRequest
+	req.Params.URI = watchesURI
+	contents, err := s.handleWatchesResource(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out struct {
+		Watches []map[string]any `json:"watches"`
+	}
+	text := contents[0].(mcp.TextResourceContents).Text
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		t.Fatalf("invalid resource JSON %q: %v", text, err)
+	}
+	return out.Watches
+}
+
+func TestHandleWatchAdd(t *testing.T) {
+	t.Run("named", func(t *testing.T) {
+		s, _ := newTestServer()
+		result, err := s.handleWatchAdd(context.Background(), makeReq(map[string]any{
+			"name":       "temp",
+			"expression": "device.temperature",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, "temp") {
+			t.Errorf("expected watch name, got %q", text)
+		}
+	})
+
+	t.Run("shorthand", func(t *testing.T) {
+		s, _ := newTestServer()
+		result, _ := s.handleWatchAdd(context.Background(), makeReq(map[string]any{
+			"expression": "vbat = scope.ch1.mean",
+		}))
+		if result.IsError {
+			t.Fatalf("unexpected error result: %v", result.Content)
+		}
+		watches := readWatches(t, s)
+		if len(watches) != 1 || watches[0]["name"] != "vbat" {
+			t.Errorf("expected watch 'vbat', got %v", watches)
+		}
+	})
+
+	t.Run("invalid expressions", func(t *testing.T) {
+		for _, expr := range []string{"scope.ch0.mean", "scope.ch1.median", "dio.x", "dmm.power", "supply.v"} {
+			s, _ := newTestServer()
+			result, _ := s.handleWatchAdd(context.Background(), makeReq(map[string]any{
+				"name":       "w",
+				"expression": expr,
+			}))
+			if !result.IsError {
+				t.Errorf("expected error result for %q", expr)
+			}
+		}
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		s, _ := newTestServer()
+		result, _ := s.handleWatchAdd(context.Background(), makeReq(map[string]any{
+			"expression": "device.temperature",
+		}))
+		if !result.IsError {
+			t.Error("expected error result")
+		}
+	})
+}
+
+func TestWatchesResource(t *testing.T) {
+	t.Run("values and errors", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.recordData = []float64{1, 2, 3}
+		dev.staticIO.getStateVal = true
+		dev.dmm.measureErr = errors.New("dmm fail")
+		dev.supply.statuses = []dwf.SupplyStatus{{Faults: []string{"System Overtemp"}}}
+		for name, expr := range map[string]string{
+			"a_mean":   "scope.ch1.mean",
+			"b_pp":     "scope.ch1.pp",
+			"c_pin":    "dio.4",
+			"d_dmm":    "dmm.dc_voltage",
+			"e_faults": "supplies.faults",
+		} {
+			if err := s.watches.add(name, expr, 0); err != nil {
+				t.Fatalf("add %s: %v", name, err)
+			}
+		}
+		watches := readWatches(t, s)
+		if len(watches) != 5 {
+			t.Fatalf("expected 5 watches, got %v", watches)
+		}
+		if watches[0]["value"] != 2.0 || watches[1]["value"] != 2.0 || watches[2]["value"] != 1.0 || watches[4]["value"] != 1.0 {
+			t.Errorf("unexpected values: %v", watches)
+		}
+		if watches[3]["error"] != "dmm fail" {
+			t.Errorf("expected DMM error, got %v", watches[3])
+		}
+	})
+
+	t.Run("interval caches value", func(t *testing.T) {
+		s, dev := newTestServer()
+		dev.scope.measureVal = 1.5
+		if err := s.watches.add("v", "scope.ch1.sample", time.Minute); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		readWatches(t, s)
+		dev.scope.measureVal = 3.0
+		watches := readWatches(t, s)
+		if watches[0]["value"] != 1.5 {
+			t.Errorf("expected cached 1.5, got %v", watches[0]["value"])
+		}
+	})
+}
+
+func TestHandleWatchRemove(t *testing.T) {
+	s, _ := newTestServer()
+	_ = s.watches.add("temp", "device.temperature", 0)
+	result, _ := s.handleWatchRemove(context.Background(), makeReq(map[string]any{"name": "temp"}))
+	if result.IsError {
+		t.Fatalf("unexpected error result: %v", result.Content)
+	}
+	result, _ = s.handleWatchRemove(context.Background(), makeReq(map[string]any{"name": "temp"}))
+	if !result.IsError {
+		t.Error("expected error result when removing an unknown watch")
+	}
+}
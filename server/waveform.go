@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+// waveVars are the variables of a waveform expression: t is the time in
+// seconds from the start of the period and x the position in it, from 0 up
+// to 1.
+var waveVars = map[string]int{"t": 0, "x": 1}
+
+// evalWaveExpr samples one period of a waveform expression over t and x at
+// the given frequency, in volts.
+func evalWaveExpr(expr string, samples int, frequency float64) ([]float64, error) {
+	p := &mathParser{src: expr, rest: expr, vars: waveVars}
+	eval, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]float64, samples)
+	vars := make([]float64, len(waveVars))
+	for i := range out {
+		vars[1] = float64(i) / float64(samples)
+		vars[0] = vars[1] / frequency
+		out[i] = eval(vars)
+		if math.IsNaN(out[i]) || math.IsInf(out[i], 0) {
+			return nil, fmt.Errorf("%q is not finite at t=%g s", expr, vars[0])
+		}
+	}
+	return out, nil
+}
+
+// fitCustomWaveform prepares one period of a custom waveform for a wavegen
+// channel accepting limits samples. Samples beyond ±1 are divided by their
+// peak and the amplitude multiplied by it, which keeps the output voltage,
+// and a length outside limits is resampled to the nearest accepted one. It
+// returns the samples, the amplitude and a warning for each change, with
+// voltages written in the number style f.
+func fitCustomWaveform(data []float64, amplitude float64, limits dwf.Limits, f *textFormat) ([]float64, float64, []string, error) {
+	if len(data) == 0 {
+		return nil, 0, nil, fmt.Errorf("custom_data is empty")
+	}
+	var peak float64
+	for i, v := range data {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, 0, nil, fmt.Errorf("custom_data sample %d is not finite", i)
+		}
+		peak = math.Max(peak, math.Abs(v))
+	}
+
+	var warnings []string
+	out := data
+	if peak > 1 {
+		out = make([]float64, len(data))
+		for i, v := range data {
+			out[i] = v / peak
+		}
+		warnings = append(warnings, fmt.Sprintf("custom_data peaks at %g, outside the normalized range ±1; it was divided by %g and the amplitude raised from %s to %s to keep the output voltage.",
+			peak, peak, f.quantity(amplitude, "V", "%g V"), f.quantity(amplitude*peak, "V", "%g V")))
+		amplitude *= peak
+	}
+
+	n := len(out)
+	if limits.Max > 0 && float64(n) > limits.Max {
+		n = int(limits.Max)
+	}
+	if float64(n) < limits.Min {
+		n = int(limits.Min)
+	}
+	if n != len(out) {
+		warnings = append(warnings, fmt.Sprintf("The device accepts %g to %g custom samples; the %d samples were resampled to %d.", limits.Min, limits.Max, len(out), n))
+		out = resamplePeriodic(out, n)
+	}
+	return out, amplitude, warnings, nil
+}
+
+// resamplePeriodic resamples one period of a waveform to n points by linear
+// interpolation, wrapping from the last sample back to the first.
+func resamplePeriodic(data []float64, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		pos := float64(i) * float64(len(data)) / float64(n)
+		j := int(pos)
+		frac := pos - float64(j)
+		out[i] = data[j]*(1-frac) + data[(j+1)%len(data)]*frac
+	}
+	return out
+}
+
+// normalizeVolts scales samples in volts into the ±1 range the device plays,
+// returning them with the amplitude that restores the voltages, which is
+// their peak, and their extremes. All-zero data has amplitude 0.
+func normalizeVolts(data []float64) (norm []float64, amplitude, lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, v := range data {
+		lo, hi, amplitude = math.Min(lo, v), math.Max(hi, v), math.Max(amplitude, math.Abs(v))
+	}
+	norm = make([]float64, len(data))
+	if amplitude > 0 {
+		for i, v := range data {
+			norm[i] = v / amplitude
+		}
+	}
+	return norm, amplitude, lo, hi
+}
+
+func (s *DiscoveryMCPServer) handleWavegenExpression(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	cfg := dwf.WavegenConfig{
+		Channel:   getInt(args, "channel", 1),
+		Function:  dwf.FuncCustom,
+		Offset:    getFloat(args, "offset", 0),
+		Frequency: getFloat(args, "frequency", 1000),
+		Symmetry:  50,
+		RunTime:   getFloat(args, "run_time", 0),
+	}
+	if cfg.Frequency <= 0 {
+		return errResult(fmt.Errorf("frequency must be positive, got %g", cfg.Frequency)), nil
+	}
+	limits, err := s.device.Wavegen().DataLimits(cfg.Channel)
+	if err != nil {
+		return errResult(err), nil
+	}
+	var warnings []string
+	n := getInt(args, "samples", 1024)
+	if fit := int(math.Max(math.Min(float64(n), limits.Max), limits.Min)); limits.Max > 0 && fit != n {
+		warnings = append(warnings, fmt.Sprintf("The device accepts %g to %g custom samples; %d were used instead of %d.", limits.Min, limits.Max, fit, n))
+		n = fit
+	}
+	if n < 2 {
+		return errResult(fmt.Errorf("samples must be at least 2")), nil
+	}
+	source := getString(args, "expression", "")
+	data, err := evalWaveExpr(source, n, cfg.Frequency)
+	if err != nil {
+		return errResult(err), nil
+	}
+
+	var lo, hi float64
+	cfg.CustomData, cfg.Amplitude, lo, hi = normalizeVolts(data)
+	if cfg.Amplitude == 0 {
+		return errResult(fmt.Errorf("%q is 0 over the whole period; use offset for a DC level", source)), nil
+	}
+	if err := s.safety.checkWavegen(cfg.Amplitude, cfg.Offset, max(math.Abs(lo+cfg.Offset), math.Abs(hi+cfg.Offset))); err != nil {
+		return errResult(err), nil
+	}
+	st, err := s.device.Wavegen().Generate(cfg)
+	if err != nil {
+		return errResult(err), nil
+	}
+
+	result := wavegenResult(cfg, st, warnings)
+	result["expression"] = source
+	result["min"] = lo + cfg.Offset
+	result["max"] = hi + cfg.Offset
+	return jsonResult(result), nil
+}
+
+// maxPlaySamples bounds a streamed waveform, ten seconds at 1 MHz.
+const maxPlaySamples = 10_000_000
+
+func (s *DiscoveryMCPServer) handleWavegenPlay(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := argsMap(req.Params.Arguments)
+	ch := getInt(args, "channel", 1)
+	rate := getFloat(args, "sample_rate", 0)
+	text, hasData := args["data"].(string)
+	ref := getString(args, "capture", "")
+	var data []float64
+	var err error
+	switch {
+	case hasData && ref != "":
+		return errResult(fmt.Errorf("give either data or capture, not both")), nil
+	case hasData:
+		enc := strings.ToLower(getString(args, "encoding", encodingF32))
+		data, err = decodeSamples(text, enc, getFloat(args, "data_scale", 1), getFloat(args, "data_offset", 0))
+	case ref != "":
+		var captureRate float64
+		data, captureRate, err = s.loadCaptureArray(ctx, captureName(ref), getString(args, "array", "data"))
+		if _, ok := args["sample_rate"]; !ok {
+			rate = captureRate
+		}
+	default:
+		return errResult(fmt.Errorf("give the samples as base64 data or a capture")), nil
+	}
+	if err != nil {
+		return errResult(err), nil
+	}
+	if rate <= 0 {
+		return errResult(fmt.Errorf("sample_rate must be positive; captures without a sample_rate need it given")), nil
+	}
+	if len(data) == 0 || len(data) > maxPlaySamples {
+		return errResult(fmt.Errorf("need 1 to %d samples, got %d", maxPlaySamples, len(data))), nil
+	}
+	for i, v := range data {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errResult(fmt.Errorf("sample %d is not finite", i)), nil
+		}
+	}
+	norm, amplitude, lo, hi := normalizeVolts(data)
+	if amplitude == 0 {
+		return errResult(fmt.Errorf("all samples are 0; use offset for a DC level")), nil
+	}
+	offset := getFloat(args, "offset", 0)
+	if err := s.safety.checkWavegen(amplitude, offset, max(math.Abs(lo+offset), math.Abs(hi+offset))); err != nil {
+		return errResult(err), nil
+	}
+
+	duration := float64(len(data)) / rate
+	margin := getFloat(args, "timeout", 10)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration((duration+margin)*float64(time.Second)))
+	defer cancel()
+	stats, err := s.device.Wavegen().Play(ctx, dwf.PlayConfig{
+		Channel:    ch,
+		SampleRate: rate,
+		Amplitude:  amplitude,
+		Offset:     offset,
+		Data:       norm,
+	})
+	if err != nil {
+		return errResult(err), nil
+	}
+
+	result := map[string]interface{}{
+		"channel":     ch,
+		"samples":     stats.Samples,
+		"sample_rate": rate,
+		"duration":    duration,
+		"min":         lo + offset,
+		"max":         hi + offset,
+		"lost":        stats.Lost,
+		"corrupt":     stats.Corrupt,
+	}
+	if stats.Lost > 0 || stats.Corrupt > 0 {
+		result["warning"] = "The host could not keep the device buffer filled and the output has gaps; lower sample_rate or play shorter data."
+	}
+	return jsonResult(result), nil
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/molejar/discovery-mcp/dwf"
+)
+
+func TestFitCustomWaveform(t *testing.T) {
+	limits := dwf.Limits{Min: 4, Max: 8}
+
+	data, amp, warnings, err := fitCustomWaveform([]float64{0, 0.5, 0, -0.5}, 2, limits, newTextFormat())
+	if err != nil || amp != 2 || len(warnings) != 0 || len(data) != 4 {
+		t.Errorf("in-range waveform changed: %v %g %v %v", data, amp, warnings, err)
+	}
+
+	data, amp, warnings, err = fitCustomWaveform([]float64{0, 2, 0, -1}, 1.5, limits, newTextFormat())
+	if err != nil || amp != 3 || len(warnings) != 1 || !slices.Equal(data, []float64{0, 1, 0, -0.5}) {
+		t.Errorf("normalized waveform = %v, amplitude %g, warnings %v, err %v", data, amp, warnings, err)
+	}
+
+	data, _, warnings, _ = fitCustomWaveform([]float64{0, 1}, 1, limits, newTextFormat())
+	if len(warnings) != 1 || !slices.Equal(data, []float64{0, 0.5, 1, 0.5}) {
+		t.Errorf("upsampled waveform = %v, warnings %v", data, warnings)
+	}
+	data, _, _, _ = fitCustomWaveform(make([]float64, 100), 1, limits, newTextFormat())
+	if len(data) != 8 {
+		t.Errorf("downsampled to %d samples, want 8", len(data))
+	}
+
+	if _, _, _, err := fitCustomWaveform([]float64{0, math.NaN()}, 1, limits, newTextFormat()); err == nil {
+		t.Error("expected error for a non-finite sample")
+	}
+	if _, _, _, err := fitCustomWaveform(nil, 1, limits, newTextFormat()); err == nil {
+		t.Error("expected error for an empty waveform")
+	}
+}
+
+func TestHandleWavegenGenerateCustomData(t *testing.T) {
+	s, dev := newTestServer()
+	dev.wavegen.dataLimits = dwf.Limits{Min: 2, Max: 4}
+	result, _ := s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{
+		"channel":     float64(1),
+		"function":    float64(dwf.FuncCustom),
+		"amplitude":   1.0,
+		"custom_data": []any{0.0, 2.0, 0.0, -2.0, 0.0, 1.0},
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	cfg := dev.wavegen.generateCfg
+	if len(cfg.CustomData) != 4 || cfg.Amplitude != 2 {
+		t.Errorf("expected 4 normalized samples at 2 V, got %v at %g V", cfg.CustomData, cfg.Amplitude)
+	}
+	var got struct {
+		Samples int    `json:"custom_samples"`
+		Warning string `json:"warning"`
+	}
+	json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got)
+	if got.Samples != 4 || !strings.Contains(got.Warning, "resampled") || !strings.Contains(got.Warning, "normalized") {
+		t.Errorf("unexpected result %+v", got)
+	}
+
+	result, _ = s.handleWavegenGenerate(context.Background(), makeReq(map[string]any{
+		"channel":     float64(1),
+		"function":    float64(1),
+		"custom_data": []any{0.0, 1.0},
+	}))
+	if !result.IsError {
+		t.Error("expected error for custom_data with a built-in function")
+	}
+}
+
+func TestEvalWaveExpr(t *testing.T) {
+	data, err := evalWaveExpr("0.5*sin(2*pi*t*1000) + 0.1*sin(2*pi*t*3000)", 4, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0, 0.4, 0, -0.4}
+	for i := range want {
+		if math.Abs(data[i]-want[i]) > 1e-9 {
+			t.Fatalf("samples = %v, want %v", data, want)
+		}
+	}
+	if data, _ := evalWaveExpr("2*x - 1", 4, 50); !slices.Equal(data, []float64{-1, -0.5, 0, 0.5}) {
+		t.Errorf("ramp = %v", data)
+	}
+	for _, bad := range []string{"", "C1*t", "sin(t", "1/x", "y"} {
+		if _, err := evalWaveExpr(bad, 4, 1000); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestHandleWavegenExpression(t *testing.T) {
+	s, dev := newTestServer()
+	dev.wavegen.dataLimits = dwf.Limits{Min: 2, Max: 512}
+	result, _ := s.handleWavegenExpression(context.Background(), makeReq(map[string]any{
+		"channel":    float64(2),
+		"expression": "2*sign(sin(2*pi*x))",
+		"frequency":  500.0,
+		"offset":     1.0,
+		"samples":    float64(4096),
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", result.Content)
+	}
+	cfg := dev.wavegen.generateCfg
+	if cfg.Channel != 2 || cfg.Function != dwf.FuncCustom || cfg.Frequency != 500 || cfg.Amplitude != 2 || len(cfg.CustomData) != 512 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if cfg.CustomData[1] != 1 || cfg.CustomData[300] != -1 {
+		t.Errorf("samples not normalized: %v, %v", cfg.CustomData[1], cfg.CustomData[300])
+	}
+	var got struct {
+		Min     float64 `json:"min"`
+		Max     float64 `json:"max"`
+		Samples int     `json:"custom_samples"`
+		Warning string  `json:"warning"`
+	}
+	json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got)
+	if got.Min != -1 || got.Max != 3 || got.Samples != 512 || !strings.Contains(got.Warning, "512 were used") {
+		t.Errorf("unexpected result %+v", got)
+	}
+
+	result, _ = s.handleWavegenExpression(context.Background(), makeReq(map[string]any{
+		"channel":    float64(1),
+		"expression": "0*t",
+	}))
+	if !result.IsError {
+		t.Error("expected error for an expression that is always 0")
+	}
+}
+
+func TestHandleWavegenPlay(t *testing.T) {
+	s, dev := newTestServer()
+	result := map[string]interface{}{}
+	encodeSamples(result, "data", []float64{0, 1.5, 0, -3}, encodingF32)
+	res, _ := s.handleWavegenPlay(context.Background(), makeReq(map[string]any{
+		"channel":     float64(2),
+		"data":        result["data"],
+		"sample_rate": 48000.0,
+		"offset":      1.0,
+	}))
+	if res.IsError {
+		t.Fatalf("unexpected error: %v", res.Content)
+	}
+	cfg := dev.wavegen.playCfg
+	if cfg.Channel != 2 || cfg.SampleRate != 48000 || cfg.Amplitude != 3 || cfg.Offset != 1 || !slices.Equal(cfg.Data, []float64{0, 0.5, 0, -1}) {
+		t.Errorf("unexpected play config %+v", cfg)
+	}
+	var got struct {
+		Samples int     `json:"samples"`
+		Min     float64 `json:"min"`
+		Max     float64 `json:"max"`
+		Warning string  `json:"warning"`
+	}
+	json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &got)
+	if got.Samples != 4 || got.Min != -2 || got.Max != 2.5 || got.Warning != "" {
+		t.Errorf("unexpected result %+v", got)
+	}
+
+	// A stored capture plays at its own sample rate unless one is given.
+	if err := s.storeCapture(context.Background(), "scope-1.json", map[string]interface{}{
+		"sample_rate": 1e6,
+		"data":        []float64{0.5, -0.5},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	dev.wavegen.playStats = dwf.StreamStats{Lost: 10}
+	res, _ = s.handleWavegenPlay(context.Background(), makeReq(map[string]any{
+		"channel": float64(1),
+		"capture": "captures://scope-1.json",
+	}))
+	if res.IsError || dev.wavegen.playCfg.SampleRate != 1e6 || !strings.Contains(res.Content[0].(mcp.TextContent).Text, "gaps") {
+		t.Errorf("capture playback: %v at %g Hz", res.Content, dev.wavegen.playCfg.SampleRate)
+	}
+
+	for _, args := range []map[string]any{
+		{"channel": float64(1), "sample_rate": 1000.0},
+		{"channel": float64(1), "data": result["data"]},
+		{"channel": float64(1), "data": result["data"], "capture": "scope-1.json", "sample_rate": 1000.0},
+		{"channel": float64(1), "data": "AAAAAA==", "sample_rate": 1000.0},
+		{"channel": float64(1), "data": result["data"], "encoding": "json", "sample_rate": 1000.0},
+	} {
+		if res, _ := s.handleWavegenPlay(context.Background(), makeReq(args)); !res.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}